@@ -0,0 +1,383 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	batchAPIPath             = "/speechtotext/v3.2"
+	batchPollIntervalDefault = 10 * time.Second
+	batchRequestTimeout      = 30 * time.Second
+	// batchTimeToLive is how long Azure keeps transcriptions, should deleting
+	// them fail.
+	batchTimeToLive = "PT12H"
+	// batchTicksPerMs converts offsets and durations, in 100ns ticks.
+	batchTicksPerMs = 10000
+)
+
+type BatchTranscriberConfig struct {
+	SpeechKey    string
+	SpeechRegion string
+	// APIURL is the base URL of the REST API (e.g. of a private endpoint).
+	// It defaults to the public one of SpeechRegion.
+	APIURL string
+	// ContainerURL is the SAS URL of the blob container audio is uploaded
+	// to, so that the service can fetch it. The SAS needs the read, write
+	// and delete permissions.
+	ContainerURL string
+	// Locale is the language audio is transcribed in (e.g. en-US).
+	Locale string
+	// Proxy is the URL of the HTTP proxy requests go through, if any.
+	Proxy string
+	// PollInterval is how often the status of a transcription is checked.
+	PollInterval time.Duration
+}
+
+func (c BatchTranscriberConfig) IsValid() error {
+	if c.SpeechKey == "" {
+		return fmt.Errorf("invalid SpeechKey: should not be empty")
+	}
+
+	if c.SpeechRegion == "" && c.APIURL == "" {
+		return fmt.Errorf("invalid SpeechRegion: should not be empty")
+	}
+
+	if c.APIURL != "" {
+		if err := checkServiceURL(c.APIURL); err != nil {
+			return fmt.Errorf("invalid APIURL: %w", err)
+		}
+	}
+
+	if err := checkServiceURL(c.ContainerURL); err != nil {
+		return fmt.Errorf("invalid ContainerURL: %w", err)
+	}
+
+	if c.Locale == "" {
+		return fmt.Errorf("invalid Locale: should not be empty")
+	}
+
+	if c.Proxy != "" {
+		if _, err := parseProxy(c.Proxy); err != nil {
+			return fmt.Errorf("invalid Proxy: %w", err)
+		}
+	}
+
+	if c.PollInterval < 0 {
+		return fmt.Errorf("invalid PollInterval: should not be negative")
+	}
+
+	return nil
+}
+
+// BatchTranscriber transcribes whole files through the Batch Transcription
+// REST API. Unlike SpeechRecognizer, audio isn't streamed: it's uploaded to
+// blob storage and transcribed asynchronously, which is cheaper and more
+// reliable for long recordings.
+type BatchTranscriber struct {
+	cfg        BatchTranscriberConfig
+	apiURL     string
+	httpClient *http.Client
+}
+
+type batchTranscriptionRequest struct {
+	ContentURLs []string                     `json:"contentUrls"`
+	Locale      string                       `json:"locale"`
+	DisplayName string                       `json:"displayName"`
+	Properties  batchTranscriptionProperties `json:"properties"`
+}
+
+type batchTranscriptionProperties struct {
+	PunctuationMode     string `json:"punctuationMode"`
+	ProfanityFilterMode string `json:"profanityFilterMode"`
+	TimeToLive          string `json:"timeToLive"`
+}
+
+type batchTranscription struct {
+	Self   string `json:"self"`
+	Status string `json:"status"`
+	Links  struct {
+		Files string `json:"files"`
+	} `json:"links"`
+	Properties struct {
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"properties"`
+}
+
+type batchFiles struct {
+	Values []struct {
+		Kind  string `json:"kind"`
+		Links struct {
+			ContentURL string `json:"contentUrl"`
+		} `json:"links"`
+	} `json:"values"`
+}
+
+type batchResult struct {
+	RecognizedPhrases []struct {
+		OffsetInTicks   int64  `json:"offsetInTicks"`
+		DurationInTicks int64  `json:"durationInTicks"`
+		Locale          string `json:"locale"`
+		NBest           []struct {
+			Display    string  `json:"display"`
+			Confidence float64 `json:"confidence"`
+		} `json:"nBest"`
+	} `json:"recognizedPhrases"`
+}
+
+func NewBatchTranscriber(cfg BatchTranscriberConfig) (*BatchTranscriber, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = batchPollIntervalDefault
+	}
+
+	apiURL := cfg.APIURL
+	if apiURL == "" {
+		apiURL = fmt.Sprintf("https://%s.api.cognitive.microsoft.com", cfg.SpeechRegion)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &BatchTranscriber{
+		cfg:        cfg,
+		apiURL:     strings.TrimSuffix(apiURL, "/") + batchAPIPath,
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+// TranscribeFile transcribes the WAV file at filePath, uploaded under name,
+// returning the segments and the language they are in. The uploaded audio
+// and the transcription are deleted once done.
+func (b *BatchTranscriber) TranscribeFile(ctx context.Context, name, filePath string) ([]transcribe.Segment, string, error) {
+	blobURL, err := b.blobURL(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := b.uploadBlob(ctx, blobURL, filePath); err != nil {
+		return nil, "", fmt.Errorf("failed to upload audio: %w", err)
+	}
+	defer func() {
+		if err := b.do(context.Background(), http.MethodDelete, blobURL, nil, false, nil); err != nil {
+			slog.Error("failed to delete uploaded audio", slog.String("err", err.Error()), slog.String("name", name))
+		}
+	}()
+
+	var tr batchTranscription
+	if err := b.do(ctx, http.MethodPost, b.apiURL+"/transcriptions", batchTranscriptionRequest{
+		ContentURLs: []string{blobURL},
+		Locale:      b.cfg.Locale,
+		DisplayName: name,
+		Properties: batchTranscriptionProperties{
+			PunctuationMode:     "DictatedAndAutomatic",
+			ProfanityFilterMode: "None",
+			TimeToLive:          batchTimeToLive,
+		},
+	}, true, &tr); err != nil {
+		return nil, "", fmt.Errorf("failed to create transcription: %w", err)
+	}
+	defer func() {
+		if err := b.do(context.Background(), http.MethodDelete, tr.Self, nil, true, nil); err != nil {
+			slog.Error("failed to delete transcription", slog.String("err", err.Error()), slog.String("name", name))
+		}
+	}()
+
+	slog.Debug("batch transcription created", slog.String("name", name), slog.String("url", tr.Self))
+
+	ticker := time.NewTicker(b.cfg.PollInterval)
+	defer ticker.Stop()
+	for tr.Status != "Succeeded" {
+		if tr.Status == "Failed" {
+			if tr.Properties.Error != nil {
+				return nil, "", fmt.Errorf("transcription failed: %s: %s", tr.Properties.Error.Code, tr.Properties.Error.Message)
+			}
+			return nil, "", fmt.Errorf("transcription failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, "", fmt.Errorf("failed to wait for transcription: %w", ctx.Err())
+		case <-ticker.C:
+		}
+
+		if err := b.do(ctx, http.MethodGet, tr.Self, nil, true, &tr); err != nil {
+			return nil, "", fmt.Errorf("failed to get transcription: %w", err)
+		}
+	}
+
+	var files batchFiles
+	if err := b.do(ctx, http.MethodGet, tr.Links.Files, nil, true, &files); err != nil {
+		return nil, "", fmt.Errorf("failed to get transcription files: %w", err)
+	}
+
+	for _, f := range files.Values {
+		if f.Kind != "Transcription" {
+			continue
+		}
+
+		// Result URLs are pre-signed.
+		var res batchResult
+		if err := b.do(ctx, http.MethodGet, f.Links.ContentURL, nil, false, &res); err != nil {
+			return nil, "", fmt.Errorf("failed to get transcription result: %w", err)
+		}
+
+		segments, lang := res.segments()
+		slog.Info("batch transcription completed", slog.String("name", name), slog.Int("numSegments", len(segments)))
+
+		return segments, lang, nil
+	}
+
+	return nil, "", fmt.Errorf("transcription has no result")
+}
+
+// segments returns the best alternative of each recognized phrase.
+func (r batchResult) segments() ([]transcribe.Segment, string) {
+	var lang string
+	segments := make([]transcribe.Segment, 0, len(r.RecognizedPhrases))
+	for _, p := range r.RecognizedPhrases {
+		if len(p.NBest) == 0 || p.NBest[0].Display == "" {
+			continue
+		}
+
+		// Locales (e.g. en-US) are reduced to their language, as detected by
+		// the other APIs.
+		phraseLang, _, _ := strings.Cut(p.Locale, "-")
+		if lang == "" {
+			lang = phraseLang
+		}
+
+		segments = append(segments, transcribe.Segment{
+			Text:       p.NBest[0].Display,
+			StartTS:    p.OffsetInTicks / batchTicksPerMs,
+			EndTS:      (p.OffsetInTicks + p.DurationInTicks) / batchTicksPerMs,
+			Language:   phraseLang,
+			Confidence: p.NBest[0].Confidence,
+		})
+	}
+
+	return segments, lang
+}
+
+// blobURL returns the URL of the blob named name in the container.
+func (b *BatchTranscriber) blobURL(name string) (string, error) {
+	u, err := url.Parse(b.cfg.ContainerURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse container URL: %w", err)
+	}
+	u.Path = path.Join(u.Path, name)
+
+	return u.String(), nil
+}
+
+func (b *BatchTranscriber) uploadBlob(ctx context.Context, blobURL, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Uploads of long tracks can take a while so they aren't bound by
+	// batchRequestTimeout.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, blobURL, f)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "audio/wav")
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return checkBatchResponse(resp)
+}
+
+// do sends a request, with the JSON encoded body if not nil, and decodes
+// the JSON response in res if not nil. The subscription key is only sent to
+// the API, not to storage.
+func (b *BatchTranscriber) do(ctx context.Context, method, u string, body any, auth bool, res any) error {
+	ctx, cancelFn := context.WithTimeout(ctx, batchRequestTimeout)
+	defer cancelFn()
+
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		bodyReader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if auth {
+		req.Header.Set("Ocp-Apim-Subscription-Key", b.cfg.SpeechKey)
+	}
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkBatchResponse(resp); err != nil {
+		return err
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+func checkBatchResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
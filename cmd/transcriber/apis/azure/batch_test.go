@@ -0,0 +1,193 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+// batchServerMock fakes both the blob container and the batch transcription
+// API.
+type batchServerMock struct {
+	*httptest.Server
+
+	mut      sync.Mutex
+	failed   bool
+	polls    int
+	uploaded []byte
+	request  batchTranscriptionRequest
+	deleted  []string
+}
+
+func newBatchServerMock(t *testing.T, failed bool) *batchServerMock {
+	t.Helper()
+
+	m := &batchServerMock{failed: failed}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/audio/", func(w http.ResponseWriter, r *http.Request) {
+		m.mut.Lock()
+		defer m.mut.Unlock()
+
+		require.Equal(t, "sig=signature", r.URL.RawQuery)
+		require.Empty(t, r.Header.Get("Ocp-Apim-Subscription-Key"))
+
+		switch r.Method {
+		case http.MethodPut:
+			require.Equal(t, "BlockBlob", r.Header.Get("x-ms-blob-type"))
+			m.uploaded, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodDelete:
+			m.deleted = append(m.deleted, r.URL.Path)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	})
+
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions", func(w http.ResponseWriter, r *http.Request) {
+		m.mut.Lock()
+		defer m.mut.Unlock()
+
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "speechkey", r.Header.Get("Ocp-Apim-Subscription-Key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&m.request))
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"self": "%[1]s/speechtotext/v3.2/transcriptions/trid", "status": "NotStarted", "links": {"files": "%[1]s/speechtotext/v3.2/transcriptions/trid/files"}}`, m.URL)
+	})
+
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions/trid", func(w http.ResponseWriter, r *http.Request) {
+		m.mut.Lock()
+		defer m.mut.Unlock()
+
+		require.Equal(t, "speechkey", r.Header.Get("Ocp-Apim-Subscription-Key"))
+
+		if r.Method == http.MethodDelete {
+			m.deleted = append(m.deleted, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		m.polls++
+		status := "Running"
+		if m.polls > 1 && m.failed {
+			fmt.Fprintf(w, `{"self": "%s/speechtotext/v3.2/transcriptions/trid", "status": "Failed", "properties": {"error": {"code": "InvalidData", "message": "bad audio"}}}`, m.URL)
+			return
+		} else if m.polls > 1 {
+			status = "Succeeded"
+		}
+		fmt.Fprintf(w, `{"self": "%[1]s/speechtotext/v3.2/transcriptions/trid", "status": "%[2]s", "links": {"files": "%[1]s/speechtotext/v3.2/transcriptions/trid/files"}}`, m.URL, status)
+	})
+
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions/trid/files", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "speechkey", r.Header.Get("Ocp-Apim-Subscription-Key"))
+		fmt.Fprintf(w, `{"values": [{"kind": "TranscriptionReport", "links": {"contentUrl": "%[1]s/results/report.json"}}, {"kind": "Transcription", "links": {"contentUrl": "%[1]s/results/0.json"}}]}`, m.URL)
+	})
+
+	mux.HandleFunc("/results/0.json", func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("Ocp-Apim-Subscription-Key"))
+		fmt.Fprint(w, `{"recognizedPhrases": [
+			{"offsetInTicks": 5000000, "durationInTicks": 12000000, "locale": "en-US", "nBest": [{"display": "Hello there.", "confidence": 0.9}, {"display": "Hello they're."}]},
+			{"offsetInTicks": 25000000, "durationInTicks": 5000000, "locale": "en-US", "nBest": []},
+			{"offsetInTicks": 40000000, "durationInTicks": 8000000, "locale": "en-US", "nBest": [{"display": "How are you?", "confidence": 0.8}]}
+		]}`)
+	})
+
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+
+	return m
+}
+
+func TestBatchTranscriberConfigIsValid(t *testing.T) {
+	cfg := BatchTranscriberConfig{
+		SpeechKey:    "speechkey",
+		SpeechRegion: "eastus",
+		ContainerURL: "https://account.blob.core.windows.net/audio?sig=signature",
+		Locale:       "en-US",
+	}
+	require.NoError(t, cfg.IsValid())
+
+	invalid := cfg
+	invalid.ContainerURL = ""
+	require.EqualError(t, invalid.IsValid(), `invalid ContainerURL: unsupported scheme ""`)
+
+	invalid = cfg
+	invalid.Locale = ""
+	require.EqualError(t, invalid.IsValid(), "invalid Locale: should not be empty")
+
+	invalid = cfg
+	invalid.Proxy = "http://proxy.example.com"
+	require.EqualError(t, invalid.IsValid(), `invalid Proxy: port "" is not valid`)
+
+	noRegion := cfg
+	noRegion.SpeechRegion = ""
+	require.EqualError(t, noRegion.IsValid(), "invalid SpeechRegion: should not be empty")
+	noRegion.APIURL = "https://speech.example.com"
+	require.NoError(t, noRegion.IsValid())
+}
+
+func TestBatchTranscriberTranscribeFile(t *testing.T) {
+	audioPath := filepath.Join(t.TempDir(), "track.wav")
+	require.NoError(t, os.WriteFile(audioPath, []byte("audio data"), 0600))
+
+	newTranscriber := func(t *testing.T, srv *batchServerMock) *BatchTranscriber {
+		bt, err := NewBatchTranscriber(BatchTranscriberConfig{
+			SpeechKey:    "speechkey",
+			APIURL:       srv.URL,
+			ContainerURL: srv.URL + "/audio?sig=signature",
+			Locale:       "en-US",
+			PollInterval: 10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		return bt
+	}
+
+	t.Run("success", func(t *testing.T) {
+		srv := newBatchServerMock(t, false)
+
+		segments, lang, err := newTranscriber(t, srv).TranscribeFile(context.Background(), "call_track.wav", audioPath)
+		require.NoError(t, err)
+		require.Equal(t, "en", lang)
+		require.Equal(t, []transcribe.Segment{
+			{Text: "Hello there.", StartTS: 500, EndTS: 1700, Language: "en", Confidence: 0.9},
+			{Text: "How are you?", StartTS: 4000, EndTS: 4800, Language: "en", Confidence: 0.8},
+		}, segments)
+
+		require.Equal(t, []byte("audio data"), srv.uploaded)
+		require.Equal(t, []string{srv.URL + "/audio/call_track.wav?sig=signature"}, srv.request.ContentURLs)
+		require.Equal(t, "en-US", srv.request.Locale)
+		require.Equal(t, 2, srv.polls)
+		require.ElementsMatch(t, []string{"/audio/call_track.wav", "/speechtotext/v3.2/transcriptions/trid"}, srv.deleted)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		srv := newBatchServerMock(t, true)
+
+		_, _, err := newTranscriber(t, srv).TranscribeFile(context.Background(), "call_track.wav", audioPath)
+		require.EqualError(t, err, "transcription failed: InvalidData: bad audio")
+		require.ElementsMatch(t, []string{"/audio/call_track.wav", "/speechtotext/v3.2/transcriptions/trid"}, srv.deleted)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		srv := newBatchServerMock(t, false)
+
+		ctx, cancelFn := context.WithCancel(context.Background())
+		cancelFn()
+
+		_, _, err := newTranscriber(t, srv).TranscribeFile(ctx, "call_track.wav", audioPath)
+		require.Error(t, err)
+		require.Empty(t, srv.polls)
+	})
+}
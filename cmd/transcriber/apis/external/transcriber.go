@@ -0,0 +1,199 @@
+// Package external transcribes audio through a user-provided HTTP service,
+// so that any speech-to-text engine can be plugged in without changes to the
+// transcriber.
+//
+// Each chunk of speech is sent as a POST request to the configured URL:
+//
+//   - The body holds mono PCM audio, as little-endian 32-bit float samples
+//     in the range [-1, 1], with the Content-Type application/octet-stream.
+//   - The sample_rate query parameter holds the rate of the samples (16000).
+//   - The language query parameter, if set, holds the language to transcribe
+//     in (e.g. en). The service should detect it otherwise.
+//   - The Authorization header holds "Bearer <token>" if a token is set.
+//
+// The service replies with a 200 status and a JSON body such as:
+//
+//	{
+//	  "language": "en",
+//	  "segments": [
+//	    {"text": "Hello.", "start_ms": 0, "end_ms": 800, "confidence": 0.9}
+//	  ]
+//	}
+//
+// Timestamps are relative to the start of the chunk. The language and
+// confidence fields are optional.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	// SampleRate is the rate of the audio sent to the service.
+	SampleRate     = 16000
+	timeoutDefault = 5 * time.Minute
+	// maxErrorBodySize bounds how much of an error response is reported.
+	maxErrorBodySize = 512
+)
+
+type Config struct {
+	// URL is the endpoint audio is posted to.
+	URL string
+	// Token, if set, is sent as a bearer token.
+	Token string
+	// Language to transcribe in (defaults to autodetection).
+	Language string
+	// Timeout bounds each request. Defaults to five minutes.
+	Timeout time.Duration
+	// SocketPath, if set, is the unix socket requests are sent over, in
+	// which case the host of URL is only used in the Host header.
+	SocketPath string
+}
+
+func (c Config) IsValid() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid URL: unsupported scheme %q", u.Scheme)
+	}
+
+	if u.Host == "" {
+		return fmt.Errorf("invalid URL: host should not be empty")
+	}
+
+	if c.Timeout < 0 {
+		return fmt.Errorf("invalid Timeout: should not be negative")
+	}
+
+	return nil
+}
+
+type Transcriber struct {
+	cfg        Config
+	reqURL     string
+	httpClient *http.Client
+}
+
+type response struct {
+	Language string `json:"language"`
+	Segments []struct {
+		Text       string  `json:"text"`
+		StartMs    int64   `json:"start_ms"`
+		EndMs      int64   `json:"end_ms"`
+		Language   string  `json:"language"`
+		Confidence float64 `json:"confidence"`
+	} `json:"segments"`
+}
+
+func NewTranscriber(cfg Config) (*Transcriber, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = timeoutDefault
+	}
+
+	// Parameters are added to those the URL may already have.
+	u, _ := url.Parse(cfg.URL)
+	query := u.Query()
+	query.Set("sample_rate", strconv.Itoa(SampleRate))
+	if cfg.Language != "" {
+		query.Set("language", cfg.Language)
+	}
+	u.RawQuery = query.Encode()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.SocketPath != "" {
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", cfg.SocketPath)
+		}
+	}
+
+	return &Transcriber{
+		cfg:        cfg,
+		reqURL:     u.String(),
+		httpClient: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (t *Transcriber) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	data := make([]byte, 0, 4*len(samples))
+	for _, s := range samples {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(s))
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), t.cfg.Timeout)
+	defer cancelFn()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.reqURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if t.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.cfg.Token)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, "", fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var res response
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	segments := make([]transcribe.Segment, 0, len(res.Segments))
+	for _, s := range res.Segments {
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+
+		lang := s.Language
+		if lang == "" {
+			lang = res.Language
+		}
+
+		segments = append(segments, transcribe.Segment{
+			Text:       s.Text,
+			StartTS:    s.StartMs,
+			EndTS:      s.EndMs,
+			Language:   lang,
+			Confidence: s.Confidence,
+		})
+	}
+
+	return segments, res.Language, nil
+}
+
+func (t *Transcriber) Destroy() error {
+	t.httpClient.CloseIdleConnections()
+	return nil
+}
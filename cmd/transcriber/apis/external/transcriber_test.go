@@ -0,0 +1,135 @@
+package external
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigIsValid(t *testing.T) {
+	require.NoError(t, Config{URL: "http://localhost:8080/transcribe"}.IsValid())
+	require.EqualError(t, Config{}.IsValid(), `invalid URL: unsupported scheme ""`)
+	require.EqualError(t, Config{URL: "grpc://localhost:8080"}.IsValid(), `invalid URL: unsupported scheme "grpc"`)
+	require.EqualError(t, Config{URL: "http:///transcribe"}.IsValid(), "invalid URL: host should not be empty")
+	require.EqualError(t, Config{URL: "http://localhost", Timeout: -1}.IsValid(), "invalid Timeout: should not be negative")
+}
+
+func TestTranscribe(t *testing.T) {
+	samples := []float32{0, 0.5, -0.5, 1}
+
+	t.Run("success", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "/transcribe", r.URL.Path)
+			require.Equal(t, "v1", r.URL.Query().Get("model"))
+			require.Equal(t, "16000", r.URL.Query().Get("sample_rate"))
+			require.Equal(t, "it", r.URL.Query().Get("language"))
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			require.Equal(t, "application/octet-stream", r.Header.Get("Content-Type"))
+
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Len(t, data, 4*len(samples))
+			for i, s := range samples {
+				require.Equal(t, s, math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:])))
+			}
+
+			_, _ = w.Write([]byte(`{"language": "it", "segments": [
+				{"text": "Ciao.", "start_ms": 0, "end_ms": 800, "confidence": 0.9},
+				{"text": " ", "start_ms": 800, "end_ms": 900},
+				{"text": "Hello.", "start_ms": 1000, "end_ms": 1500, "language": "en"}
+			]}`))
+		}))
+		defer srv.Close()
+
+		tr, err := NewTranscriber(Config{URL: srv.URL + "/transcribe?model=v1", Token: "token", Language: "it"})
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		segments, lang, err := tr.Transcribe(samples)
+		require.NoError(t, err)
+		require.Equal(t, "it", lang)
+		require.Equal(t, []transcribe.Segment{
+			{Text: "Ciao.", StartTS: 0, EndTS: 800, Language: "it", Confidence: 0.9},
+			{Text: "Hello.", StartTS: 1000, EndTS: 1500, Language: "en"},
+		}, segments)
+	})
+
+	t.Run("auto-detection", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.False(t, r.URL.Query().Has("language"))
+			require.Empty(t, r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{"segments": []}`))
+		}))
+		defer srv.Close()
+
+		tr, err := NewTranscriber(Config{URL: srv.URL})
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		segments, lang, err := tr.Transcribe(samples)
+		require.NoError(t, err)
+		require.Empty(t, lang)
+		require.Empty(t, segments)
+	})
+
+	t.Run("unix socket", func(t *testing.T) {
+		socketPath := filepath.Join(t.TempDir(), "transcriber.sock")
+		l, err := net.Listen("unix", socketPath)
+		require.NoError(t, err)
+
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/transcribe", r.URL.Path)
+			_, _ = w.Write([]byte(`{"language": "en", "segments": [{"text": "Hello.", "start_ms": 0, "end_ms": 500}]}`))
+		}))
+		srv.Listener = l
+		srv.Start()
+		defer srv.Close()
+
+		tr, err := NewTranscriber(Config{URL: "http://transcriber/transcribe", SocketPath: socketPath})
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		segments, lang, err := tr.Transcribe(samples)
+		require.NoError(t, err)
+		require.Equal(t, "en", lang)
+		require.Equal(t, []transcribe.Segment{{Text: "Hello.", StartTS: 0, EndTS: 500, Language: "en"}}, segments)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		tr, err := NewTranscriber(Config{URL: srv.URL})
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		_, _, err = tr.Transcribe(samples)
+		require.EqualError(t, err, "request failed with status 503: model not loaded")
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(`not json`))
+		}))
+		defer srv.Close()
+
+		tr, err := NewTranscriber(Config{URL: srv.URL})
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		_, _, err = tr.Transcribe(samples)
+		require.ErrorContains(t, err, "failed to decode response")
+	})
+}
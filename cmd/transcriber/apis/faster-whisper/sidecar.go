@@ -0,0 +1,347 @@
+// Package fasterwhisper transcribes audio through a faster-whisper
+// (CTranslate2) sidecar process, spawned and supervised by the transcriber.
+//
+// The sidecar is started with the following environment variables set:
+//
+//   - FASTER_WHISPER_SOCKET: the unix socket to serve HTTP on.
+//   - FASTER_WHISPER_MODEL: the size of the model to load (e.g. medium).
+//   - FASTER_WHISPER_NUM_THREADS: the number of threads to use.
+//
+// It serves GET /health, replying with a 200 status once the model is
+// loaded, and POST /transcribe following the contract of the external
+// package.
+package fasterwhisper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	startTimeoutDefault        = 5 * time.Minute
+	healthCheckIntervalDefault = 10 * time.Second
+	maxRestartsDefault         = 5
+	// maxHealthCheckFailures is how many consecutive health checks can fail
+	// before the sidecar is restarted.
+	maxHealthCheckFailures = 3
+	healthCheckTimeout     = 5 * time.Second
+	readyPollInterval      = 100 * time.Millisecond
+	stopTimeout            = 10 * time.Second
+	// baseURL is the URL requests are made to, over the socket.
+	baseURL = "http://faster-whisper"
+)
+
+type SidecarConfig struct {
+	// Command is the executable of the sidecar, followed by its arguments.
+	Command []string
+	// SocketPath is the unix socket the sidecar serves on.
+	SocketPath string
+	// ModelSize is the size of the model to load.
+	ModelSize string
+	// NumThreads is the number of threads the sidecar should use.
+	NumThreads int
+	// StartTimeout bounds how long the sidecar can take to become healthy,
+	// which includes loading the model. Defaults to five minutes.
+	StartTimeout time.Duration
+	// HealthCheckInterval is how often a running sidecar is checked.
+	// Defaults to ten seconds.
+	HealthCheckInterval time.Duration
+	// MaxRestarts bounds how many times the sidecar is restarted after
+	// crashing or becoming unhealthy. Defaults to five.
+	MaxRestarts int
+}
+
+func (c SidecarConfig) IsValid() error {
+	if len(c.Command) == 0 || c.Command[0] == "" {
+		return fmt.Errorf("invalid Command: should not be empty")
+	}
+
+	if c.SocketPath == "" {
+		return fmt.Errorf("invalid SocketPath: should not be empty")
+	}
+
+	if c.ModelSize == "" {
+		return fmt.Errorf("invalid ModelSize: should not be empty")
+	}
+
+	if c.NumThreads < 1 {
+		return fmt.Errorf("invalid NumThreads: should be greater than zero")
+	}
+
+	if c.StartTimeout < 0 || c.HealthCheckInterval < 0 || c.MaxRestarts < 0 {
+		return fmt.Errorf("invalid timings: should not be negative")
+	}
+
+	return nil
+}
+
+// Sidecar manages the lifecycle of the faster-whisper process: it's spawned
+// on creation, health checked while running and restarted, up to
+// MaxRestarts times, should it crash or stop responding.
+type Sidecar struct {
+	cfg        SidecarConfig
+	httpClient *http.Client
+
+	mut sync.Mutex
+	// readyCh is closed once the current process is healthy. It's replaced
+	// on restart.
+	readyCh chan struct{}
+	// failedCh is closed, with err set, once the sidecar is given up on.
+	failedCh chan struct{}
+	err      error
+	restarts int
+
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+func NewSidecar(cfg SidecarConfig) (*Sidecar, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, fmt.Errorf("failed to validate config: %w", err)
+	}
+
+	if cfg.StartTimeout == 0 {
+		cfg.StartTimeout = startTimeoutDefault
+	}
+	if cfg.HealthCheckInterval == 0 {
+		cfg.HealthCheckInterval = healthCheckIntervalDefault
+	}
+	if cfg.MaxRestarts == 0 {
+		cfg.MaxRestarts = maxRestartsDefault
+	}
+
+	s := &Sidecar{
+		cfg: cfg,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var d net.Dialer
+					return d.DialContext(ctx, "unix", cfg.SocketPath)
+				},
+			},
+			Timeout: healthCheckTimeout,
+		},
+		readyCh:  make(chan struct{}),
+		failedCh: make(chan struct{}),
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+
+	cmd, exitCh, err := s.spawn()
+	if err != nil {
+		return nil, err
+	}
+
+	go s.supervise(cmd, exitCh)
+
+	return s, nil
+}
+
+// spawn starts a new sidecar process. The returned channel receives the
+// result of waiting on it.
+func (s *Sidecar) spawn() (*exec.Cmd, <-chan error, error) {
+	// A stale socket left by a crashed process would fail the bind.
+	if err := os.Remove(s.cfg.SocketPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("failed to remove socket: %w", err)
+	}
+
+	cmd := exec.Command(s.cfg.Command[0], s.cfg.Command[1:]...) //nolint:gosec
+	cmd.Env = append(os.Environ(),
+		"FASTER_WHISPER_SOCKET="+s.cfg.SocketPath,
+		"FASTER_WHISPER_MODEL="+s.cfg.ModelSize,
+		"FASTER_WHISPER_NUM_THREADS="+strconv.Itoa(s.cfg.NumThreads),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start sidecar: %w", err)
+	}
+
+	slog.Info("faster-whisper sidecar started", slog.Int("pid", cmd.Process.Pid))
+
+	exitCh := make(chan error, 1)
+	go func() {
+		exitCh <- cmd.Wait()
+	}()
+
+	return cmd, exitCh, nil
+}
+
+// supervise waits for the process to become healthy, monitors it and
+// restarts it when needed, until stopped.
+func (s *Sidecar) supervise(cmd *exec.Cmd, exitCh <-chan error) {
+	defer close(s.doneCh)
+
+	for {
+		err := s.monitor(cmd, exitCh)
+		if err == nil {
+			return
+		}
+
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		s.mut.Lock()
+		if s.restarts >= s.cfg.MaxRestarts {
+			s.err = fmt.Errorf("sidecar failed after %d restarts: %w", s.restarts, err)
+			close(s.failedCh)
+			s.mut.Unlock()
+			slog.Error("giving up on faster-whisper sidecar", slog.String("err", err.Error()))
+			return
+		}
+		s.restarts++
+		// Transcriptions wait for the new process.
+		select {
+		case <-s.readyCh:
+			s.readyCh = make(chan struct{})
+		default:
+		}
+		s.mut.Unlock()
+
+		slog.Warn("restarting faster-whisper sidecar", slog.String("err", err.Error()), slog.Int("restarts", s.restarts))
+
+		cmd, exitCh, err = s.spawn()
+		if err != nil {
+			// Counted as a restart so that a command failing to start
+			// isn't retried forever.
+			exitCh = closedExitCh(err)
+		}
+	}
+}
+
+// monitor returns nil once the sidecar is stopped, or the reason the
+// process should be restarted.
+func (s *Sidecar) monitor(cmd *exec.Cmd, exitCh <-chan error) error {
+	if cmd == nil {
+		return <-exitCh
+	}
+
+	startDeadline := time.After(s.cfg.StartTimeout)
+	poll := time.NewTicker(readyPollInterval)
+	defer poll.Stop()
+	ready := false
+	failures := 0
+
+	for {
+		select {
+		case <-s.stopCh:
+			s.terminate(cmd, exitCh)
+			return nil
+		case err := <-exitCh:
+			if err == nil {
+				err = fmt.Errorf("exited")
+			}
+			return fmt.Errorf("sidecar process: %w", err)
+		case <-startDeadline:
+			if !ready {
+				s.terminate(cmd, exitCh)
+				return fmt.Errorf("sidecar didn't become healthy within %v", s.cfg.StartTimeout)
+			}
+		case <-poll.C:
+			err := s.checkHealth()
+			if !ready {
+				if err == nil {
+					ready = true
+					poll.Reset(s.cfg.HealthCheckInterval)
+					s.mut.Lock()
+					close(s.readyCh)
+					s.mut.Unlock()
+					slog.Info("faster-whisper sidecar ready", slog.Int("pid", cmd.Process.Pid))
+				}
+				continue
+			}
+
+			if err == nil {
+				failures = 0
+				continue
+			}
+			failures++
+			slog.Warn("faster-whisper sidecar health check failed", slog.String("err", err.Error()), slog.Int("failures", failures))
+			if failures >= maxHealthCheckFailures {
+				s.terminate(cmd, exitCh)
+				return fmt.Errorf("sidecar is unhealthy: %w", err)
+			}
+		}
+	}
+}
+
+func (s *Sidecar) checkHealth() error {
+	resp, err := s.httpClient.Get(baseURL + "/health")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// terminate asks the process to exit, killing it if it doesn't in time.
+func (s *Sidecar) terminate(cmd *exec.Cmd, exitCh <-chan error) {
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		slog.Warn("failed to signal sidecar", slog.String("err", err.Error()))
+	}
+
+	select {
+	case <-exitCh:
+	case <-time.After(stopTimeout):
+		slog.Warn("faster-whisper sidecar didn't exit in time, killing it")
+		_ = cmd.Process.Kill()
+		<-exitCh
+	}
+}
+
+// waitReady blocks until the sidecar is healthy.
+func (s *Sidecar) waitReady() error {
+	s.mut.Lock()
+	readyCh := s.readyCh
+	s.mut.Unlock()
+
+	select {
+	case <-readyCh:
+		return nil
+	case <-s.failedCh:
+		return s.err
+	case <-s.doneCh:
+		return fmt.Errorf("sidecar is stopped")
+	case <-time.After(s.cfg.StartTimeout):
+		return fmt.Errorf("timed out waiting for sidecar")
+	}
+}
+
+// Stop terminates the sidecar process. It's safe to call more than once.
+func (s *Sidecar) Stop() {
+	s.mut.Lock()
+	select {
+	case <-s.stopCh:
+	default:
+		close(s.stopCh)
+	}
+	s.mut.Unlock()
+
+	<-s.doneCh
+	_ = os.Remove(s.cfg.SocketPath)
+}
+
+func closedExitCh(err error) <-chan error {
+	ch := make(chan error, 1)
+	ch <- err
+	return ch
+}
@@ -0,0 +1,161 @@
+package fasterwhisper
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain lets the test binary act as the sidecar, as set through
+// SIDECAR_TEST_MODE.
+func TestMain(m *testing.M) {
+	switch os.Getenv("SIDECAR_TEST_MODE") {
+	case "":
+		os.Exit(m.Run())
+	case "exit":
+		os.Exit(1)
+	case "serve":
+		serveTestSidecar(os.Getenv("SIDECAR_TEST_CRASH") == "true")
+	}
+}
+
+// serveTestSidecar replies to transcriptions with its PID, crashing after the
+// first one if crash is set.
+func serveTestSidecar(crash bool) {
+	l, err := net.Listen("unix", os.Getenv("FASTER_WHISPER_SOCKET"))
+	if err != nil {
+		os.Exit(2)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/transcribe", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"language": "en", "segments": [{"text": "%d %s %s %s", "start_ms": 0, "end_ms": 500}]}`,
+			os.Getpid(), os.Getenv("FASTER_WHISPER_MODEL"), os.Getenv("FASTER_WHISPER_NUM_THREADS"), r.URL.Query().Get("language"))
+		if crash {
+			w.(http.Flusher).Flush()
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				os.Exit(1)
+			}()
+		}
+	})
+
+	_ = http.Serve(l, mux)
+}
+
+func newTestSidecar(t *testing.T, mode string, crash bool) *Sidecar {
+	t.Helper()
+
+	t.Setenv("SIDECAR_TEST_MODE", mode)
+	t.Setenv("SIDECAR_TEST_CRASH", fmt.Sprint(crash))
+
+	s, err := NewSidecar(SidecarConfig{
+		Command:             []string{os.Args[0]},
+		SocketPath:          filepath.Join(t.TempDir(), "fw.sock"),
+		ModelSize:           "medium",
+		NumThreads:          2,
+		StartTimeout:        10 * time.Second,
+		HealthCheckInterval: 50 * time.Millisecond,
+		MaxRestarts:         2,
+	})
+	require.NoError(t, err)
+	t.Cleanup(s.Stop)
+
+	return s
+}
+
+func TestSidecarConfigIsValid(t *testing.T) {
+	cfg := SidecarConfig{
+		Command:    []string{"faster-whisper-server"},
+		SocketPath: "/tmp/fw.sock",
+		ModelSize:  "medium",
+		NumThreads: 1,
+	}
+	require.NoError(t, cfg.IsValid())
+
+	invalid := cfg
+	invalid.Command = nil
+	require.EqualError(t, invalid.IsValid(), "invalid Command: should not be empty")
+
+	invalid = cfg
+	invalid.SocketPath = ""
+	require.EqualError(t, invalid.IsValid(), "invalid SocketPath: should not be empty")
+
+	invalid = cfg
+	invalid.NumThreads = 0
+	require.EqualError(t, invalid.IsValid(), "invalid NumThreads: should be greater than zero")
+
+	invalid = cfg
+	invalid.MaxRestarts = -1
+	require.EqualError(t, invalid.IsValid(), "invalid timings: should not be negative")
+}
+
+func TestSidecar(t *testing.T) {
+	t.Run("transcribe", func(t *testing.T) {
+		s := newTestSidecar(t, "serve", false)
+
+		tr, err := s.NewTranscriber("it")
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		segments, lang, err := tr.Transcribe(make([]float32, 16000))
+		require.NoError(t, err)
+		require.Equal(t, "en", lang)
+		require.Len(t, segments, 1)
+		require.Regexp(t, `^\d+ medium 2 it$`, segments[0].Text)
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		s := newTestSidecar(t, "serve", true)
+
+		tr, err := s.NewTranscriber("")
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		segments, _, err := tr.Transcribe(make([]float32, 16000))
+		require.NoError(t, err)
+		first := segments[0].Text
+
+		// The crashed process gets replaced by a new one.
+		require.Eventually(t, func() bool {
+			segments, _, err := tr.Transcribe(make([]float32, 16000))
+			return err == nil && segments[0].Text != first
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+
+	t.Run("give up", func(t *testing.T) {
+		s := newTestSidecar(t, "exit", false)
+
+		tr, err := s.NewTranscriber("")
+		require.NoError(t, err)
+		defer tr.Destroy()
+
+		_, _, err = tr.Transcribe(make([]float32, 16000))
+		require.ErrorContains(t, err, "sidecar failed after 2 restarts")
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		s := newTestSidecar(t, "serve", false)
+		require.NoError(t, s.waitReady())
+
+		s.Stop()
+		s.Stop()
+		_, err := os.Stat(s.cfg.SocketPath)
+		require.True(t, os.IsNotExist(err))
+
+		tr, err := s.NewTranscriber("")
+		require.NoError(t, err)
+		defer tr.Destroy()
+		_, _, err = tr.Transcribe(make([]float32, 16000))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,45 @@
+package fasterwhisper
+
+import (
+	"fmt"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/external"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// Transcriber sends audio to the sidecar. Any number of them can share a
+// sidecar.
+type Transcriber struct {
+	sidecar *Sidecar
+	tr      *external.Transcriber
+}
+
+// NewTranscriber returns a transcriber for the given language (auto-detected
+// if empty).
+func (s *Sidecar) NewTranscriber(language string) (*Transcriber, error) {
+	tr, err := external.NewTranscriber(external.Config{
+		URL:        baseURL + "/transcribe",
+		Language:   language,
+		SocketPath: s.cfg.SocketPath,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Transcriber{
+		sidecar: s,
+		tr:      tr,
+	}, nil
+}
+
+func (t *Transcriber) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	if err := t.sidecar.waitReady(); err != nil {
+		return nil, "", fmt.Errorf("sidecar is not ready: %w", err)
+	}
+
+	return t.tr.Transcribe(samples)
+}
+
+func (t *Transcriber) Destroy() error {
+	return t.tr.Destroy()
+}
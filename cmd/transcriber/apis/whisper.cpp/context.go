@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"regexp"
 	"runtime"
 	"unsafe"
 
@@ -32,6 +33,10 @@ type Config struct {
 	Language string
 	// Whether or not to generate a single segment (default false).
 	SingleSegment bool
+	// Whether or not to suppress non-speech tokens (e.g. "[Music]", "♪").
+	SuppressNonSpeechTokens bool
+	// Tokens matching this regular expression are suppressed during decoding.
+	SuppressRegex string
 }
 
 func (c Config) IsValid() error {
@@ -51,6 +56,12 @@ func (c Config) IsValid() error {
 		return fmt.Errorf("invalid NumThreads: should be in the range [1, %d]", numCPU)
 	}
 
+	if c.SuppressRegex != "" {
+		if _, err := regexp.Compile(c.SuppressRegex); err != nil {
+			return fmt.Errorf("invalid SuppressRegex: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -59,6 +70,8 @@ type Context struct {
 	ctx     *C.struct_whisper_context
 	cparams C.struct_whisper_context_params
 	params  C.struct_whisper_full_params
+	// resetContext makes the next transcription ignore past text.
+	resetContext bool
 }
 
 func NewContext(cfg Config) (*Context, error) {
@@ -93,6 +106,10 @@ func NewContext(cfg Config) (*Context, error) {
 	c.params.language = C.CString(c.cfg.Language)
 	c.params.single_segment = C.bool(c.cfg.SingleSegment)
 	c.params.print_progress = C.bool(c.cfg.PrintProgress)
+	c.params.suppress_non_speech_tokens = C.bool(c.cfg.SuppressNonSpeechTokens)
+	if c.cfg.SuppressRegex != "" {
+		c.params.suppress_regex = C.CString(c.cfg.SuppressRegex)
+	}
 
 	return &c, nil
 }
@@ -103,16 +120,44 @@ func (c *Context) Destroy() error {
 	}
 	C.whisper_free(c.ctx)
 	C.free(unsafe.Pointer(c.params.language))
+	if c.params.suppress_regex != nil {
+		C.free(unsafe.Pointer(c.params.suppress_regex))
+	}
+	c.SetPrompt("")
 	c.ctx = nil
 	return nil
 }
 
+// SetPrompt sets the text following transcriptions are conditioned on (e.g.
+// what the same speaker said before). An empty prompt clears it.
+func (c *Context) SetPrompt(prompt string) {
+	if c.params.initial_prompt != nil {
+		C.free(unsafe.Pointer(c.params.initial_prompt))
+		c.params.initial_prompt = nil
+	}
+	if prompt != "" {
+		c.params.initial_prompt = C.CString(prompt)
+	}
+}
+
+// ResetContext makes the next transcription ignore the text transcribed so
+// far, as whisper.cpp otherwise keeps conditioning on it between calls.
+func (c *Context) ResetContext() {
+	c.resetContext = true
+}
+
 func (c *Context) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
 	if len(samples) == 0 {
 		return nil, "", fmt.Errorf("samples should not be empty")
 	}
 
-	ret := C.whisper_full(c.ctx, c.params, (*C.float)(&samples[0]), C.int(len(samples)))
+	params := c.params
+	if c.resetContext {
+		params.no_context = C.bool(true)
+		c.resetContext = false
+	}
+
+	ret := C.whisper_full(c.ctx, params, (*C.float)(&samples[0]), C.int(len(samples)))
 	if ret != 0 {
 		return nil, "", fmt.Errorf("whisper_full failed with code %d", ret)
 	}
@@ -125,7 +170,29 @@ func (c *Context) Transcribe(samples []float32) ([]transcribe.Segment, string, e
 		segments[i].Text = C.GoString(C.whisper_full_get_segment_text(c.ctx, C.int(i)))
 		segments[i].StartTS = int64(C.whisper_full_get_segment_t0(c.ctx, C.int(i))) * 10
 		segments[i].EndTS = int64(C.whisper_full_get_segment_t1(c.ctx, C.int(i))) * 10
+		segments[i].Confidence = c.segmentConfidence(i)
 	}
 
 	return segments, lang, nil
 }
+
+// segmentConfidence returns the average probability of the text tokens of the
+// given segment, leaving out special ones (e.g. timestamps).
+func (c *Context) segmentConfidence(i int) float64 {
+	eot := C.whisper_token_eot(c.ctx)
+	var sum float64
+	var n int
+	for j := 0; j < int(C.whisper_full_n_tokens(c.ctx, C.int(i))); j++ {
+		if C.whisper_full_get_token_id(c.ctx, C.int(i), C.int(j)) >= eot {
+			continue
+		}
+		sum += float64(C.whisper_full_get_token_p(c.ctx, C.int(i), C.int(j)))
+		n++
+	}
+
+	if n == 0 {
+		return 0
+	}
+
+	return sum / float64(n)
+}
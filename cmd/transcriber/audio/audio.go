@@ -0,0 +1,197 @@
+// Package audio decodes recorded tracks into mono PCM samples, streaming them
+// in chunks of bounded length so that memory usage doesn't grow with the
+// length of the recording. Gaps in the audio split the stream, each chunk
+// carrying its start time.
+package audio
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// InputSampleRate is the sample rate of recorded Opus audio.
+	InputSampleRate = 48000
+	// SampleRate is the sample rate of decoded audio, as required by Whisper.
+	SampleRate = 16000
+	// FrameSizeMs is the duration of an Opus frame, as sent over WebRTC.
+	FrameSizeMs = 20
+	// FrameSize is the number of decoded samples in a frame.
+	FrameSize = FrameSizeMs * SampleRate / 1000
+	// GapThreshold is the length of missing audio after which the stream is
+	// split rather than filled in.
+	GapThreshold = time.Second
+
+	inputSamplesPerMs = InputSampleRate / 1000
+	inputFrameSize    = FrameSizeMs * inputSamplesPerMs
+	samplesPerMs      = SampleRate / 1000
+
+	// cutSearchSamples is how far back from the end of a full chunk the
+	// quietest frame to split it at is searched for, so that words don't get
+	// cut in half (ten seconds).
+	cutSearchSamples = 10 * SampleRate
+)
+
+// Chunk is a bounded portion of decoded audio.
+type Chunk struct {
+	PCM []float32
+	// StartTS is the time of the first sample, in milliseconds since the
+	// start of the track.
+	StartTS int64
+	// Cont is set when the chunk directly follows the previous one, which
+	// was split for being too long, as opposed to following a gap in the
+	// audio.
+	Cont bool
+}
+
+// Chunker splits a stream of decoded samples into chunks of at most
+// maxSamples.
+type Chunker struct {
+	maxSamples int
+	curr       Chunk
+	ready      []Chunk
+}
+
+// MaxSamples returns the length past which chunks are split.
+func (c *Chunker) MaxSamples() int {
+	return c.maxSamples
+}
+
+// Push appends samples to the current chunk, splitting it once full.
+func (c *Chunker) Push(pcm []float32) {
+	c.curr.PCM = append(c.curr.PCM, pcm...)
+	for len(c.curr.PCM) >= c.maxSamples {
+		cut := quietestCut(c.curr.PCM[:c.maxSamples])
+		rest := make([]float32, len(c.curr.PCM)-cut, max(len(c.curr.PCM)-cut, c.maxSamples))
+		copy(rest, c.curr.PCM[cut:])
+
+		c.ready = append(c.ready, Chunk{
+			PCM:     c.curr.PCM[:cut:cut],
+			StartTS: c.curr.StartTS,
+			Cont:    c.curr.Cont,
+		})
+		c.curr = Chunk{
+			PCM:     rest,
+			StartTS: c.curr.StartTS + int64(cut/samplesPerMs),
+			Cont:    true,
+		}
+	}
+}
+
+// Gap ends the current chunk, the following samples starting at startTS.
+func (c *Chunker) Gap(startTS int64) {
+	c.flush()
+	c.curr.StartTS = startTS
+}
+
+// flush ends the current chunk.
+func (c *Chunker) flush() {
+	if len(c.curr.PCM) > 0 {
+		c.ready = append(c.ready, c.curr)
+	}
+	c.curr = Chunk{}
+}
+
+// quietestCut returns the offset of the frame with the least energy within
+// the last cutSearchSamples of pcm, or its last quarter if shorter. Offsets
+// are aligned to frames so that chunks keep whole milliseconds.
+func quietestCut(pcm []float32) int {
+	from := max(len(pcm)-cutSearchSamples, len(pcm)*3/4)
+	from -= from % FrameSize
+
+	cut := len(pcm) - len(pcm)%FrameSize
+	minEnergy := math.Inf(1)
+	for off := from; off+FrameSize <= len(pcm); off += FrameSize {
+		var energy float64
+		for _, s := range pcm[off : off+FrameSize] {
+			energy += float64(s) * float64(s)
+		}
+		if energy < minEnergy && off > 0 {
+			minEnergy = energy
+			cut = off
+		}
+	}
+
+	return cut
+}
+
+// Source produces decoded samples.
+type Source interface {
+	// Decode passes the next portion of decoded audio to c, returning io.EOF
+	// once there's none left.
+	Decode(c *Chunker) error
+	Close() error
+}
+
+// Decoder decodes a source incrementally, in chunks of bounded length.
+type Decoder struct {
+	src     Source
+	chunker Chunker
+	eof     bool
+}
+
+// NewDecoder returns a decoder splitting the audio of src into chunks of at
+// most maxSamples.
+func NewDecoder(src Source, maxSamples int) *Decoder {
+	return &Decoder{
+		src: src,
+		chunker: Chunker{
+			maxSamples: maxSamples,
+		},
+	}
+}
+
+// Next returns the next chunk of audio, or io.EOF once the source is fully
+// decoded.
+func (d *Decoder) Next() (Chunk, error) {
+	for len(d.chunker.ready) == 0 {
+		if d.eof {
+			return Chunk{}, io.EOF
+		}
+
+		if err := d.src.Decode(&d.chunker); errors.Is(err, io.EOF) {
+			d.chunker.flush()
+			d.eof = true
+		} else if err != nil {
+			return Chunk{}, err
+		}
+	}
+
+	chunk := d.chunker.ready[0]
+	d.chunker.ready = d.chunker.ready[1:]
+
+	return chunk, nil
+}
+
+// Close closes the source.
+func (d *Decoder) Close() error {
+	return d.src.Close()
+}
+
+// pcmSource passes along already decoded samples.
+type pcmSource struct {
+	pcm []float32
+}
+
+// NewPCMSource returns a source for already decoded samples, at SampleRate.
+func NewPCMSource(pcm []float32) Source {
+	return &pcmSource{pcm: pcm}
+}
+
+func (s *pcmSource) Decode(c *Chunker) error {
+	if len(s.pcm) == 0 {
+		return io.EOF
+	}
+
+	n := min(len(s.pcm), c.maxSamples)
+	c.Push(s.pcm[:n])
+	s.pcm = s.pcm[n:]
+
+	return nil
+}
+
+func (s *pcmSource) Close() error {
+	return nil
+}
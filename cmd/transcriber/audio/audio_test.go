@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func genTone(amp float32, samples int) []float32 {
+	pcm := make([]float32, samples)
+	for i := range pcm {
+		pcm[i] = amp * float32(math.Sin(2*math.Pi*440*float64(i)/SampleRate))
+	}
+	return pcm
+}
+
+// decodeAll returns all the chunks decoded from src.
+func decodeAll(t *testing.T, src Source, maxSamples int) []Chunk {
+	t.Helper()
+
+	dec := NewDecoder(src, maxSamples)
+	defer func() {
+		require.NoError(t, dec.Close())
+	}()
+
+	var chunks []Chunk
+	for {
+		chunk, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestChunker(t *testing.T) {
+	t.Run("split at quietest frame", func(t *testing.T) {
+		c := Chunker{maxSamples: 100 * FrameSize}
+
+		pcm := genTone(0.5, 250*FrameSize)
+		// Quiet frames, the second one being within the last quarter of the
+		// first chunk.
+		clear(pcm[10*FrameSize : 11*FrameSize])
+		clear(pcm[90*FrameSize : 91*FrameSize])
+		clear(pcm[185*FrameSize : 186*FrameSize])
+		c.Push(pcm)
+		c.flush()
+
+		require.Len(t, c.ready, 3)
+		require.Len(t, c.ready[0].PCM, 90*FrameSize)
+		require.Zero(t, c.ready[0].StartTS)
+		require.False(t, c.ready[0].Cont)
+
+		require.Len(t, c.ready[1].PCM, 95*FrameSize)
+		require.Equal(t, int64(90*FrameSizeMs), c.ready[1].StartTS)
+		require.True(t, c.ready[1].Cont)
+
+		require.Len(t, c.ready[2].PCM, 65*FrameSize)
+		require.Equal(t, int64(185*FrameSizeMs), c.ready[2].StartTS)
+		require.True(t, c.ready[2].Cont)
+
+		var joined []float32
+		for _, chunk := range c.ready {
+			joined = append(joined, chunk.PCM...)
+		}
+		require.Equal(t, pcm, joined)
+	})
+
+	t.Run("gaps", func(t *testing.T) {
+		c := Chunker{maxSamples: 100 * FrameSize}
+
+		c.Push(genTone(0.5, 10*FrameSize))
+		c.Gap(5000)
+		c.Gap(6000)
+		c.Push(genTone(0.5, 20*FrameSize))
+		c.flush()
+
+		require.Len(t, c.ready, 2)
+		require.Zero(t, c.ready[0].StartTS)
+		require.Len(t, c.ready[0].PCM, 10*FrameSize)
+		require.Equal(t, int64(6000), c.ready[1].StartTS)
+		require.Len(t, c.ready[1].PCM, 20*FrameSize)
+		require.False(t, c.ready[1].Cont)
+	})
+}
+
+func TestDecoder(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		require.Empty(t, decodeAll(t, NewPCMSource(nil), 100*FrameSize))
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		pcm := genTone(0.5, 250*FrameSize)
+
+		chunks := decodeAll(t, NewPCMSource(pcm), 100*FrameSize)
+		require.Greater(t, len(chunks), 2)
+
+		var joined []float32
+		for i, chunk := range chunks {
+			require.LessOrEqual(t, len(chunk.PCM), 100*FrameSize)
+			require.Equal(t, int64(len(joined)/samplesPerMs), chunk.StartTS)
+			require.Equal(t, i > 0, chunk.Cont)
+			joined = append(joined, chunk.PCM...)
+		}
+		require.Equal(t, pcm, joined)
+	})
+
+	t.Run("end of stream", func(t *testing.T) {
+		dec := NewDecoder(NewPCMSource(genTone(0.5, 10*FrameSize)), 100*FrameSize)
+		defer dec.Close()
+
+		chunk, err := dec.Next()
+		require.NoError(t, err)
+		require.Len(t, chunk.PCM, 10*FrameSize)
+
+		_, err = dec.Next()
+		require.Equal(t, io.EOF, err)
+		_, err = dec.Next()
+		require.Equal(t, io.EOF, err)
+	})
+}
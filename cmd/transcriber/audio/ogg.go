@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/ogg"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/opus"
+)
+
+// OGGSource decodes the Opus audio of an OGG file, accounting for gaps and
+// lost packets.
+type OGGSource struct {
+	logger   *slog.Logger
+	in       io.Closer
+	reader   *ogg.Reader
+	dec      OpusDecoder
+	destroy  func() error
+	channels int
+	pcmBuf   []float32
+	frameBuf []float32
+
+	prevGP          uint64
+	inDTX           bool
+	concealedFrames int
+}
+
+// NewOGGSource opens the OGG file at path for decoding. Errors met while
+// decoding, which are skipped over, are logged through logger.
+func NewOGGSource(path string, logger *slog.Logger) (*OGGSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open track file: %w", err)
+	}
+
+	src, err := newOGGSource(f, logger)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	opusDec, err := opus.NewDecoder(SampleRate, src.channels)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+	}
+	src.dec = opusDec
+	src.destroy = opusDec.Destroy
+
+	return src, nil
+}
+
+// newOGGSource reads the OGG headers from in, leaving the decoder to be set.
+func newOGGSource(in io.ReadCloser, logger *slog.Logger) (*OGGSource, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	oggReader, oggHdr, err := ogg.NewReaderWith(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new ogg reader: %w", err)
+	}
+
+	// Only single stream (mono or stereo) files are supported.
+	channels := int(oggHdr.Channels)
+	if oggHdr.ChannelMap != 0 || channels < 1 || channels > 2 {
+		return nil, fmt.Errorf("unsupported number of channels: %d", channels)
+	}
+
+	return &OGGSource{
+		logger:   logger,
+		in:       in,
+		reader:   oggReader,
+		channels: channels,
+		pcmBuf:   make([]float32, FrameSize*channels),
+	}, nil
+}
+
+// Decode decodes the next page. Pages failing to parse, e.g. corrupted ones,
+// are skipped and their audio is concealed as if lost.
+func (s *OGGSource) Decode(c *Chunker) error {
+	var lost int
+	data, hdr, err := s.reader.ParseNextPage()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		s.logger.Error("failed to parse ogg page", slog.String("err", err.Error()))
+		return nil
+	}
+
+	// Ignoring first page which only contains metadata.
+	if hdr.GranulePosition == 0 {
+		return nil
+	}
+
+	if hdr.GranulePosition > s.prevGP+inputFrameSize {
+		gap := time.Duration((hdr.GranulePosition-s.prevGP)/inputSamplesPerMs) * time.Millisecond
+		s.logger.Debug("gap in audio samples", slog.Duration("gap", gap))
+		// If there's enough of a gap in the audio (GapThreshold) we split and
+		// update the start time accordingly.
+		if gap > GapThreshold {
+			c.Gap(int64(hdr.GranulePosition) / inputSamplesPerMs)
+		} else if s.inDTX {
+			// The sender paused transmitting during silence (DTX), which
+			// is kept as such rather than concealed.
+			silence := int((hdr.GranulePosition-s.prevGP)/inputFrameSize) - 1
+			c.Push(make([]float32, silence*FrameSize))
+		} else if s.prevGP > 0 {
+			// Smaller holes are likely caused by lost packets.
+			lost = int((hdr.GranulePosition-s.prevGP)/inputFrameSize) - 1
+		}
+	}
+	s.prevGP = hdr.GranulePosition
+
+	// DTX markers stand for silence, decoding them would produce
+	// comfort noise instead.
+	s.inDTX = IsOpusDTX(data)
+	if s.inDTX {
+		c.Push(make([]float32, FrameSize))
+		return nil
+	}
+
+	var concealed int
+	s.frameBuf, concealed, err = DecodeWithLoss(s.dec, s.channels, data, lost, s.pcmBuf, s.frameBuf[:0])
+	if err != nil {
+		s.logger.Error("failed to decode audio data",
+			slog.String("err", err.Error()),
+			slog.Any("data", data))
+	}
+	s.concealedFrames += concealed
+	c.Push(s.frameBuf)
+
+	return nil
+}
+
+// Close releases the decoder and closes the file.
+func (s *OGGSource) Close() error {
+	if s.concealedFrames > 0 {
+		s.logger.Debug("concealed lost audio frames", slog.Int("count", s.concealedFrames))
+	}
+
+	if err := s.destroy(); err != nil {
+		s.logger.Error("failed to destroy decoder", slog.String("err", err.Error()))
+	}
+
+	return s.in.Close()
+}
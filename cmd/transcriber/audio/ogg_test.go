@@ -0,0 +1,205 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/ogg"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/require"
+)
+
+type testPacket struct {
+	ts      uint32
+	payload []byte
+}
+
+// genTestPackets returns n consecutive packets, the first one having
+// timestamp ts. Payloads are unique so that they can be looked up in the
+// resulting file.
+func genTestPackets(ts uint32, n int) []testPacket {
+	pkts := make([]testPacket, n)
+	for i := range pkts {
+		pkts[i] = testPacket{
+			ts:      ts + uint32(i*inputFrameSize),
+			payload: []byte{0x78, 0xaa, byte(ts >> 8), byte(i)},
+		}
+	}
+	return pkts
+}
+
+func writeTestOGG(t *testing.T, pkts []testPacket) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := ogg.NewWith(&buf, InputSampleRate, 1)
+	require.NoError(t, err)
+
+	for _, pkt := range pkts {
+		require.NoError(t, w.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Timestamp: pkt.ts},
+			Payload: pkt.payload,
+		}, 0))
+	}
+
+	return buf.Bytes()
+}
+
+func newTestOGGSource(t *testing.T, data []byte) (*OGGSource, *opusDecoderMock) {
+	t.Helper()
+
+	src, err := newOGGSource(io.NopCloser(bytes.NewReader(data)), nil)
+	require.NoError(t, err)
+
+	dec := &opusDecoderMock{}
+	src.dec = dec
+	src.destroy = func() error { return nil }
+
+	return src, dec
+}
+
+func countCalls(calls []string, name string) int {
+	var n int
+	for _, c := range calls {
+		if c == name {
+			n++
+		}
+	}
+	return n
+}
+
+func TestOGGSource(t *testing.T) {
+	t.Run("continuous", func(t *testing.T) {
+		src, dec := newTestOGGSource(t, writeTestOGG(t, genTestPackets(0, 50)))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		require.Zero(t, chunks[0].StartTS)
+		require.Len(t, chunks[0].PCM, 50*FrameSize)
+		require.Equal(t, 50, countCalls(dec.calls, "decode"))
+		require.Len(t, dec.calls, 50)
+	})
+
+	t.Run("timestamp wraparound", func(t *testing.T) {
+		pkts := genTestPackets(math.MaxUint32-10*inputFrameSize+1, 50)
+		require.Less(t, pkts[49].ts, pkts[0].ts)
+
+		src, dec := newTestOGGSource(t, writeTestOGG(t, pkts))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		require.Zero(t, chunks[0].StartTS)
+		require.Len(t, chunks[0].PCM, 50*FrameSize)
+		require.Len(t, dec.calls, 50)
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		secondTS := uint32(10*inputFrameSize + 3*InputSampleRate)
+		pkts := append(genTestPackets(0, 10), genTestPackets(secondTS, 10)...)
+
+		src, _ := newTestOGGSource(t, writeTestOGG(t, pkts))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 2)
+		require.Zero(t, chunks[0].StartTS)
+		require.Len(t, chunks[0].PCM, 10*FrameSize)
+		// Granule positions start at one.
+		require.Equal(t, int64(secondTS+1)/inputSamplesPerMs, chunks[1].StartTS)
+		require.Len(t, chunks[1].PCM, 10*FrameSize)
+		require.False(t, chunks[1].Cont)
+	})
+
+	t.Run("gap splitting long audio", func(t *testing.T) {
+		secondTS := uint32(150*inputFrameSize + 2*InputSampleRate)
+		pkts := append(genTestPackets(0, 150), genTestPackets(secondTS, 10)...)
+
+		src, _ := newTestOGGSource(t, writeTestOGG(t, pkts))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 3)
+		require.False(t, chunks[0].Cont)
+		require.True(t, chunks[1].Cont)
+		require.Equal(t, int64(len(chunks[0].PCM)/samplesPerMs), chunks[1].StartTS)
+		require.Len(t, append(chunks[0].PCM, chunks[1].PCM...), 150*FrameSize)
+		require.False(t, chunks[2].Cont)
+		require.Equal(t, int64(secondTS+1)/inputSamplesPerMs, chunks[2].StartTS)
+	})
+
+	t.Run("lost packets", func(t *testing.T) {
+		pkts := genTestPackets(0, 20)
+		pkts = append(pkts[:5], pkts[8:]...)
+
+		src, dec := newTestOGGSource(t, writeTestOGG(t, pkts))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		// Lost frames are filled in so that timing is preserved.
+		require.Len(t, chunks[0].PCM, 20*FrameSize)
+		require.Equal(t, 2, countCalls(dec.calls, "plc"))
+		require.Equal(t, 1, countCalls(dec.calls, "fec"))
+		require.Equal(t, 0.25, float64(chunks[0].PCM[5*FrameSize]))
+		require.Equal(t, 0.5, float64(chunks[0].PCM[7*FrameSize]))
+	})
+
+	t.Run("dtx", func(t *testing.T) {
+		pkts := genTestPackets(0, 20)
+		pkts[5].payload = []byte{0x78}
+		pkts = append(pkts[:6], pkts[15:]...)
+
+		src, dec := newTestOGGSource(t, writeTestOGG(t, pkts))
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		require.Len(t, chunks[0].PCM, 20*FrameSize)
+		// Silence isn't concealed, nor decoded into comfort noise.
+		require.Len(t, dec.calls, 10)
+		require.Equal(t, 10, countCalls(dec.calls, "decode"))
+		require.Equal(t, make([]float32, 10*FrameSize), chunks[0].PCM[5*FrameSize:15*FrameSize])
+	})
+
+	t.Run("corrupt page", func(t *testing.T) {
+		pkts := genTestPackets(0, 20)
+		data := writeTestOGG(t, pkts)
+
+		idx := bytes.Index(data, pkts[10].payload)
+		require.Positive(t, idx)
+		data[idx+len(pkts[10].payload)-1] ^= 0xff
+
+		src, dec := newTestOGGSource(t, data)
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		// The page is skipped, its audio concealed as if lost.
+		require.Len(t, chunks[0].PCM, 20*FrameSize)
+		require.Equal(t, 1, countCalls(dec.calls, "fec"))
+		require.Equal(t, 19, countCalls(dec.calls, "decode"))
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		data := writeTestOGG(t, genTestPackets(0, 20))
+
+		src, dec := newTestOGGSource(t, data[:len(data)-2])
+
+		chunks := decodeAll(t, src, 100*FrameSize)
+		require.Len(t, chunks, 1)
+		require.Len(t, chunks[0].PCM, 19*FrameSize)
+		require.Len(t, dec.calls, 19)
+	})
+
+	t.Run("unsupported channels", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := ogg.NewWith(&buf, InputSampleRate, 3)
+		require.NoError(t, err)
+
+		_, err = newOGGSource(io.NopCloser(&buf), nil)
+		require.EqualError(t, err, "unsupported number of channels: 3")
+	})
+
+	t.Run("invalid file", func(t *testing.T) {
+		_, err := newOGGSource(io.NopCloser(bytes.NewReader([]byte("not an ogg file, not at all"))), nil)
+		require.Error(t, err)
+	})
+}
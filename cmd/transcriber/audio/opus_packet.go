@@ -0,0 +1,46 @@
+package audio
+
+const (
+	// opusTOCCodeMask masks the frame count code of the table-of-contents
+	// byte opening every Opus packet (RFC 6716, section 3.1).
+	opusTOCCodeMask = 0x3
+	// opusTOCStereoFlag is set in the table-of-contents byte of stereo
+	// packets.
+	opusTOCStereoFlag = 0x4
+)
+
+// IsOpusDTX returns whether the given Opus payload is a discontinuous
+// transmission (DTX) packet. During silence senders using DTX stop sending
+// audio, only emitting a packet holding nothing but the TOC byte (a single
+// frame of zero length) every now and then. Decoding it would only produce
+// comfort noise.
+func IsOpusDTX(payload []byte) bool {
+	return len(payload) == 1 && payload[0]&opusTOCCodeMask == 0
+}
+
+// OpusPacketChannels returns the number of channels coded in the given Opus
+// payload.
+func OpusPacketChannels(payload []byte) int {
+	if len(payload) > 0 && payload[0]&opusTOCStereoFlag != 0 {
+		return 2
+	}
+	return 1
+}
+
+// downmix averages the given interleaved samples into mono, appending the
+// result to pcm.
+func downmix(pcm, samples []float32, channels int) []float32 {
+	if channels == 1 {
+		return append(pcm, samples...)
+	}
+
+	for i := 0; i+channels <= len(samples); i += channels {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += samples[i+c]
+		}
+		pcm = append(pcm, sum/float32(channels))
+	}
+
+	return pcm
+}
@@ -0,0 +1,39 @@
+package audio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsOpusDTX(t *testing.T) {
+	tcs := []struct {
+		name     string
+		payload  []byte
+		expected bool
+	}{
+		{"empty", nil, false},
+		{"toc only", []byte{0x78}, true},
+		{"toc only, two frames", []byte{0x79}, false},
+		{"audio", []byte{0x78, 0x01, 0x02}, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, IsOpusDTX(tc.payload))
+		})
+	}
+}
+
+func TestOpusPacketChannels(t *testing.T) {
+	require.Equal(t, 1, OpusPacketChannels(nil))
+	require.Equal(t, 1, OpusPacketChannels([]byte{0x78, 0x01}))
+	require.Equal(t, 2, OpusPacketChannels([]byte{0x7c, 0x01}))
+}
+
+func TestDownmix(t *testing.T) {
+	require.Equal(t, []float32{0.5, 1}, downmix([]float32{0.5}, []float32{1}, 1))
+	require.Equal(t, []float32{0.5, 0.5, -0.25}, downmix([]float32{0.5}, []float32{1, 0, -0.5, 0}, 2))
+	// A trailing partial frame is ignored.
+	require.Equal(t, []float32{0.5}, downmix(nil, []float32{1, 0, 1}, 2))
+}
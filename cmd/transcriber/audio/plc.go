@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"fmt"
+)
+
+// MaxConcealedFrames is the longest loss, in frames, that gets concealed.
+// Longer holes are more likely to be the sender pausing than packet loss and
+// concealing them would only produce artifacts.
+const MaxConcealedFrames = 5
+
+// OpusDecoder decodes Opus packets, as implemented by opus.Decoder.
+type OpusDecoder interface {
+	Decode(data []byte, samples []float32) (int, error)
+	DecodeFEC(data []byte, samples []float32) (int, error)
+	DecodeLost(samples []float32) (int, error)
+}
+
+// DecodeWithLoss decodes data appending the samples to pcm. When frames were
+// lost right before data, they are first filled in so that the audio isn't
+// left with holes: the last one is recovered from the forward error
+// correction information data may carry while the others are concealed.
+// Samples decoded from multi-channel streams are downmixed to mono, buf
+// needing to fit a frame's worth of interleaved samples. The number of filled
+// in frames is returned along with the samples.
+func DecodeWithLoss(dec OpusDecoder, channels int, data []byte, lost int, buf, pcm []float32) ([]float32, int, error) {
+	var concealed int
+	if lost > 0 && lost <= MaxConcealedFrames {
+		for i := 0; i < lost; i++ {
+			var n int
+			var err error
+			if i == lost-1 {
+				n, err = dec.DecodeFEC(data, buf)
+			} else {
+				n, err = dec.DecodeLost(buf)
+			}
+			if err != nil {
+				return pcm, concealed, fmt.Errorf("failed to conceal lost frame: %w", err)
+			}
+			pcm = downmix(pcm, buf[:n*channels], channels)
+			concealed++
+		}
+	}
+
+	n, err := dec.Decode(data, buf)
+	if err != nil {
+		return pcm, concealed, err
+	}
+
+	return downmix(pcm, buf[:n*channels], channels), concealed, nil
+}
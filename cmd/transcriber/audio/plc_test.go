@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type opusDecoderMock struct {
+	calls []string
+}
+
+func (d *opusDecoderMock) fill(samples []float32, val float32) int {
+	for i := range samples {
+		samples[i] = val
+	}
+	return len(samples)
+}
+
+func (d *opusDecoderMock) Decode(_ []byte, samples []float32) (int, error) {
+	d.calls = append(d.calls, "decode")
+	return d.fill(samples, 1), nil
+}
+
+func (d *opusDecoderMock) DecodeFEC(_ []byte, samples []float32) (int, error) {
+	d.calls = append(d.calls, "fec")
+	return d.fill(samples, 0.5), nil
+}
+
+func (d *opusDecoderMock) DecodeLost(samples []float32) (int, error) {
+	d.calls = append(d.calls, "plc")
+	return d.fill(samples, 0.25), nil
+}
+
+func TestDecodeWithLoss(t *testing.T) {
+	data := []byte{0x45}
+
+	tcs := []struct {
+		name          string
+		lost          int
+		expectedCalls []string
+		expectedPCM   []float32
+	}{
+		{
+			name:          "no loss",
+			lost:          0,
+			expectedCalls: []string{"decode"},
+			expectedPCM:   []float32{1, 1},
+		},
+		{
+			name:          "single loss",
+			lost:          1,
+			expectedCalls: []string{"fec", "decode"},
+			expectedPCM:   []float32{0.5, 0.5, 1, 1},
+		},
+		{
+			name:          "multiple losses",
+			lost:          3,
+			expectedCalls: []string{"plc", "plc", "fec", "decode"},
+			expectedPCM:   []float32{0.25, 0.25, 0.25, 0.25, 0.5, 0.5, 1, 1},
+		},
+		{
+			name:          "too long",
+			lost:          MaxConcealedFrames + 1,
+			expectedCalls: []string{"decode"},
+			expectedPCM:   []float32{1, 1},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			dec := &opusDecoderMock{}
+			pcm, concealed, err := DecodeWithLoss(dec, 1, data, tc.lost, make([]float32, 2), nil)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedCalls, dec.calls)
+			require.Equal(t, tc.expectedPCM, pcm)
+			require.Equal(t, len(tc.expectedCalls)-1, concealed)
+		})
+	}
+}
+
+// stereoOpusDecoderMock outputs interleaved samples, the left channel being
+// silent.
+type stereoOpusDecoderMock struct {
+	opusDecoderMock
+}
+
+func (d *stereoOpusDecoderMock) Decode(_ []byte, samples []float32) (int, error) {
+	for i := 0; i < len(samples); i += 2 {
+		samples[i], samples[i+1] = 0, 1
+	}
+	return len(samples) / 2, nil
+}
+
+func TestDecodeWithLossStereo(t *testing.T) {
+	dec := &stereoOpusDecoderMock{}
+	pcm, _, err := DecodeWithLoss(dec, 2, []byte{0x7c}, 0, make([]float32, 4), nil)
+	require.NoError(t, err)
+	require.Equal(t, []float32{0.5, 0.5}, pcm)
+}
+
+type failingOpusDecoderMock struct {
+	opusDecoderMock
+}
+
+func (d *failingOpusDecoderMock) DecodeLost(_ []float32) (int, error) {
+	return 0, fmt.Errorf("decode failed with code -1")
+}
+
+func TestDecodeWithLossFailure(t *testing.T) {
+	dec := &failingOpusDecoderMock{}
+	pcm, concealed, err := DecodeWithLoss(dec, 1, []byte{0x45}, 2, make([]float32, 2), []float32{1})
+	require.EqualError(t, err, "failed to conceal lost frame: decode failed with code -1")
+	require.Zero(t, concealed)
+	require.Equal(t, []float32{1}, pcm)
+}
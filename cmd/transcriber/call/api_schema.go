@@ -0,0 +1,84 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// pluginAPIVersion is the version of the plugin API the request and response
+// types below follow. Fields may be added to responses without bumping it,
+// as unknown ones are ignored, but not removed or changed.
+const pluginAPIVersion = 1
+
+// apiResponse is a response body from the API, checked for the fields the
+// transcriber relies on once decoded.
+type apiResponse interface {
+	// missingField returns the name of the first required field that's
+	// missing (or empty) in the response, if any.
+	missingField() string
+}
+
+// apiSchemaError is a response that doesn't match the expected schema,
+// typically because the plugin is older or newer than the transcriber
+// supports. Retrying won't help.
+type apiSchemaError struct {
+	Endpoint string
+	Field    string
+	err      error
+}
+
+func (e *apiSchemaError) Error() string {
+	hint := fmt.Sprintf("the plugin may be incompatible with API version %d used by the transcriber", pluginAPIVersion)
+	if e.err != nil {
+		if e.Field != "" {
+			return fmt.Sprintf("unexpected response from %s: invalid field %q: %s; %s", e.Endpoint, e.Field, e.err, hint)
+		}
+		return fmt.Sprintf("unexpected response from %s: %s; %s", e.Endpoint, e.err, hint)
+	}
+	return fmt.Sprintf("unexpected response from %s: missing field %q; %s", e.Endpoint, e.Field, hint)
+}
+
+func (e *apiSchemaError) Unwrap() error {
+	return e.err
+}
+
+// decodeAPIResponse decodes the body of a response from endpoint into v.
+func decodeAPIResponse(endpoint string, body io.Reader, v apiResponse) error {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &apiSchemaError{Endpoint: endpoint, Field: typeErr.Field, err: err}
+		}
+		return &apiSchemaError{Endpoint: endpoint, err: err}
+	}
+
+	if field := v.missingField(); field != "" {
+		return &apiSchemaError{Endpoint: endpoint, Field: field}
+	}
+
+	return nil
+}
+
+// filenameResponse is returned by the plugin's /filename endpoint.
+type filenameResponse struct {
+	Filename string `json:"filename"`
+}
+
+func (r *filenameResponse) missingField() string {
+	if r.Filename == "" {
+		return "filename"
+	}
+	return ""
+}
+
+func (p *sessionProfile) missingField() string {
+	if p.Id == "" {
+		return "id"
+	}
+	if p.Username == "" {
+		return "username"
+	}
+	return ""
+}
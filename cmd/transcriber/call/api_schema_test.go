@@ -0,0 +1,85 @@
+package call
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeAPIResponse(t *testing.T) {
+	t.Run("additive changes", func(t *testing.T) {
+		var r filenameResponse
+		err := decodeAPIResponse("/filename", strings.NewReader(`{"filename": "call", "extension": "vtt"}`), &r)
+		require.NoError(t, err)
+		require.Equal(t, "call", r.Filename)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		var r filenameResponse
+		err := decodeAPIResponse("/filename", strings.NewReader(`{"name": "call"}`), &r)
+		var schemaErr *apiSchemaError
+		require.True(t, errors.As(err, &schemaErr))
+		require.Equal(t, "/filename", schemaErr.Endpoint)
+		require.Equal(t, "filename", schemaErr.Field)
+		require.EqualError(t, err, `unexpected response from /filename: missing field "filename"; the plugin may be incompatible with API version 1 used by the transcriber`)
+	})
+
+	t.Run("type change", func(t *testing.T) {
+		var p sessionProfile
+		err := decodeAPIResponse("/profile", strings.NewReader(`{"id": "userID", "username": "user", "transcription_opt_out": "yes"}`), &p)
+		var schemaErr *apiSchemaError
+		require.True(t, errors.As(err, &schemaErr))
+		require.Equal(t, "transcription_opt_out", schemaErr.Field)
+		require.ErrorContains(t, err, `invalid field "transcription_opt_out"`)
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		var p sessionProfile
+		err := decodeAPIResponse("/profile", strings.NewReader(`<html>`), &p)
+		var schemaErr *apiSchemaError
+		require.True(t, errors.As(err, &schemaErr))
+		require.Empty(t, schemaErr.Field)
+	})
+
+	t.Run("not retried", func(t *testing.T) {
+		p := retryPolicy{
+			MaxAttempts: 3,
+			InitialWait: time.Millisecond,
+		}
+
+		var attempts int
+		err := retry(context.Background(), "test", p, func(_ context.Context) error {
+			attempts++
+			return fmt.Errorf("failed: %w", &apiSchemaError{Endpoint: "/filename", Field: "filename"})
+		})
+		require.Error(t, err)
+		require.Equal(t, 1, attempts)
+	})
+}
+
+func TestGetFilenameForCallSchemaDrift(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+		"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename", "", "").
+		Return(&http.Response{
+			Body: io.NopCloser(strings.NewReader(`{"file_name": "Call"}`)),
+		}, nil).Once()
+
+	_, err := tr.getFilenameForCall()
+	require.ErrorContains(t, err, `unexpected response from /bot/calls/{callID}/filename: missing field "filename"`)
+}
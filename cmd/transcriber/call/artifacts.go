@@ -0,0 +1,184 @@
+package call
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	artifactRequestTimeout = 30 * time.Second
+)
+
+type artifactType string
+
+const (
+	artifactTypeLogs        artifactType = "logs"
+	artifactTypeMetrics     artifactType = "metrics"
+	artifactTypeDiagnostics artifactType = "diagnostics"
+)
+
+// artifact is a job output, other than the transcription itself, delivered
+// to the offloader so that it's reachable alongside the job.
+type artifact struct {
+	Type        artifactType
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type jobDiagnostics struct {
+	Health       healthResponse `json:"health"`
+	Participants []participant  `json:"participants"`
+}
+
+// artifactsClient pushes artifacts to the offloader's job artifacts endpoint.
+type artifactsClient struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newArtifactsClient(artifactsURL string) *artifactsClient {
+	return &artifactsClient{
+		url:        artifactsURL,
+		httpClient: &http.Client{},
+	}
+}
+
+func (c *artifactsClient) push(a artifact) error {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("type", string(a.Type))
+	q.Set("name", a.Name)
+	u.RawQuery = q.Encode()
+
+	err = retry(context.Background(), "pushArtifact", defaultRetryPolicy, func(ctx context.Context) error {
+		ctx, cancelFn := context.WithTimeout(ctx, artifactRequestTimeout)
+		defer cancelFn()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(a.Data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", a.ContentType)
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (t *Transcriber) logFilePath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s.log", t.cfg.TranscriptionID))
+}
+
+// OpenLogFile opens the file logs should be copied to so that they can be
+// pushed as a job artifact. It's only needed if ArtifactsURL is set.
+func (t *Transcriber) OpenLogFile() (*os.File, error) {
+	return os.OpenFile(t.logFilePath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+}
+
+func (t *Transcriber) getJobArtifacts() ([]artifact, error) {
+	var artifacts []artifact
+
+	logs, err := os.ReadFile(t.logFilePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read log file: %w", err)
+	} else if err == nil {
+		artifacts = append(artifacts, artifact{
+			Type:        artifactTypeLogs,
+			Name:        filepath.Base(t.logFilePath()),
+			ContentType: "text/plain",
+			Data:        logs,
+		})
+	}
+
+	metrics, err := json.Marshal(t.getRuntimeStats())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metrics: %w", err)
+	}
+	artifacts = append(artifacts, artifact{
+		Type:        artifactTypeMetrics,
+		Name:        fmt.Sprintf("%s-metrics.json", t.cfg.TranscriptionID),
+		ContentType: "application/json",
+		Data:        metrics,
+	})
+
+	liveTranscript, err := os.ReadFile(t.captionsArchivePath())
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read captions archive: %w", err)
+	} else if err == nil {
+		artifacts = append(artifacts, artifact{
+			Type:        artifactTypeLiveTranscript,
+			Name:        fmt.Sprintf("%s-live-transcript.jsonl", t.cfg.TranscriptionID),
+			ContentType: "application/x-ndjson",
+			Data:        liveTranscript,
+		})
+	}
+
+	t.participantsMut.Lock()
+	diagnostics, err := json.Marshal(jobDiagnostics{
+		Health:       t.getHealth(),
+		Participants: t.participants,
+	})
+	t.participantsMut.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal diagnostics: %w", err)
+	}
+	artifacts = append(artifacts, artifact{
+		Type:        artifactTypeDiagnostics,
+		Name:        fmt.Sprintf("%s-diagnostics.json", t.cfg.TranscriptionID),
+		ContentType: "application/json",
+		Data:        diagnostics,
+	})
+
+	return artifacts, nil
+}
+
+// pushJobArtifacts delivers logs, metrics and diagnostics to the offloader,
+// if configured. This is best effort and never fails the job.
+func (t *Transcriber) pushJobArtifacts() {
+	if t.cfg.Publish.ArtifactsURL == "" {
+		return
+	}
+
+	artifacts, err := t.getJobArtifacts()
+	if err != nil {
+		slog.Error("failed to get job artifacts", slog.String("err", err.Error()))
+		return
+	}
+
+	client := newArtifactsClient(t.cfg.Publish.ArtifactsURL)
+	for _, a := range artifacts {
+		if err := client.push(a); err != nil {
+			slog.Error("failed to push job artifact",
+				slog.String("name", a.Name),
+				slog.String("err", err.Error()))
+			continue
+		}
+		slog.Debug("job artifact pushed", slog.String("name", a.Name))
+	}
+}
@@ -0,0 +1,139 @@
+package call
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushJobArtifacts(t *testing.T) {
+	type pushedArtifact struct {
+		typ         string
+		name        string
+		contentType string
+		data        []byte
+	}
+
+	var pushed []pushedArtifact
+	var failing bool
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "/jobs/67t5u6cmtfbb7jug739d43xa9e/artifacts", r.URL.Path)
+
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		pushed = append(pushed, pushedArtifact{
+			typ:         r.URL.Query().Get("type"),
+			name:        r.URL.Query().Get("name"),
+			contentType: r.Header.Get("Content-Type"),
+			data:        data,
+		})
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	tr := setupTranscriberForTest(t)
+
+	t.Run("not configured", func(t *testing.T) {
+		tr.pushJobArtifacts()
+		require.Empty(t, pushed)
+	})
+
+	tr.cfg.Publish.ArtifactsURL = ts.URL + "/jobs/67t5u6cmtfbb7jug739d43xa9e/artifacts"
+
+	t.Run("no log file", func(t *testing.T) {
+		defer func() {
+			pushed = nil
+		}()
+
+		tr.pushJobArtifacts()
+		require.Len(t, pushed, 2)
+		require.Equal(t, "metrics", pushed[0].typ)
+		require.Equal(t, "67t5u6cmtfbb7jug739d43xa9e-metrics.json", pushed[0].name)
+		require.Equal(t, "application/json", pushed[0].contentType)
+		var stats runtimeStats
+		require.NoError(t, json.Unmarshal(pushed[0].data, &stats))
+		require.Equal(t, StateConnecting, stats.State)
+
+		require.Equal(t, "diagnostics", pushed[1].typ)
+		require.Equal(t, "67t5u6cmtfbb7jug739d43xa9e-diagnostics.json", pushed[1].name)
+		var diagnostics jobDiagnostics
+		require.NoError(t, json.Unmarshal(pushed[1].data, &diagnostics))
+		require.Equal(t, StateConnecting, diagnostics.Health.State)
+	})
+
+	t.Run("with log file", func(t *testing.T) {
+		defer func() {
+			pushed = nil
+		}()
+
+		logFile, err := tr.OpenLogFile()
+		require.NoError(t, err)
+		_, err = logFile.WriteString("some log line\n")
+		require.NoError(t, err)
+		require.NoError(t, logFile.Close())
+		defer os.Remove(tr.logFilePath())
+
+		tr.pushJobArtifacts()
+		require.Len(t, pushed, 3)
+		require.Equal(t, pushedArtifact{
+			typ:         "logs",
+			name:        "67t5u6cmtfbb7jug739d43xa9e.log",
+			contentType: "text/plain",
+			data:        []byte("some log line\n"),
+		}, pushed[0])
+	})
+
+	t.Run("with live captions archive", func(t *testing.T) {
+		defer func() {
+			pushed = nil
+		}()
+
+		tr.archiveCaption(trackContext{trackID: "trackID"}, captionMsg{
+			CaptionMsg: public.CaptionMsg{SessionID: "sessionID", Text: "Hello"},
+		})
+		tr.closeCaptionsFiles()
+		defer os.Remove(tr.captionsArchivePath())
+
+		tr.pushJobArtifacts()
+		require.Len(t, pushed, 3)
+		require.Equal(t, "live_transcript", pushed[1].typ)
+		require.Equal(t, "67t5u6cmtfbb7jug739d43xa9e-live-transcript.jsonl", pushed[1].name)
+		require.Equal(t, "application/x-ndjson", pushed[1].contentType)
+		var caption recordedCaption
+		require.NoError(t, json.Unmarshal(pushed[1].data, &caption))
+		require.Equal(t, "Hello", caption.Text)
+		require.Equal(t, "sessionID", caption.SessionID)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		failing = true
+		defer func() {
+			failing = false
+		}()
+
+		origAttempts := maxAPIRetryAttempts
+		maxAPIRetryAttempts = 1
+		defer func() {
+			maxAPIRetryAttempts = origAttempts
+		}()
+
+		err := newArtifactsClient(tr.cfg.Publish.ArtifactsURL).push(artifact{
+			Type: artifactTypeMetrics,
+			Name: "metrics.json",
+		})
+		require.EqualError(t, err, "maximum attempts reached : unexpected status code 500")
+	})
+}
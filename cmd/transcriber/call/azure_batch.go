@@ -0,0 +1,175 @@
+package call
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/azure"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	azureBatchLocaleDefault = "en-US"
+	// azureBatchPaddingMs is the silence put between speech samples so that
+	// recognized phrases don't run across them.
+	azureBatchPaddingMs = 500
+	// azureBatchTimeout bounds the transcription of a track, which is queued
+	// by the service and so can take a while.
+	azureBatchTimeout = 4 * time.Hour
+)
+
+// azureBatchEnabled returns whether the speech of tracks is transcribed at
+// once through the Azure batch API rather than streamed sample by sample.
+func (t *Transcriber) azureBatchEnabled(api config.TranscribeAPI) bool {
+	enabled, _ := t.cfg.Engine.TranscribeAPIOptions["AZURE_BATCH"].(bool)
+	return api == config.TranscribeAPIAzure && enabled
+}
+
+func (t *Transcriber) azureBatchTranscriberConfig() azure.BatchTranscriberConfig {
+	opts := t.cfg.Engine.TranscribeAPIOptions
+	srCfg := t.azureSpeechRecognizerConfig("")
+	apiURL, _ := opts["AZURE_BATCH_API_URL"].(string)
+	containerURL, _ := opts["AZURE_BATCH_CONTAINER_SAS_URL"].(string)
+	locale, _ := opts["AZURE_BATCH_LOCALE"].(string)
+	if locale == "" {
+		locale = azureBatchLocaleDefault
+	}
+
+	return azure.BatchTranscriberConfig{
+		SpeechKey:    srCfg.SpeechKey,
+		SpeechRegion: srCfg.SpeechRegion,
+		APIURL:       apiURL,
+		ContainerURL: containerURL,
+		Locale:       locale,
+		Proxy:        srCfg.Proxy,
+	}
+}
+
+// azureBatchTrack gathers the speech detected in a track into a WAV file, to
+// be transcribed at once. Silence between speech samples is cut down to
+// azureBatchPaddingMs so that it isn't uploaded, nor billed.
+type azureBatchTrack struct {
+	file     *os.File
+	dataSize int64
+	// tm maps timestamps of the file back to the track.
+	tm transcribe.TimeMap
+}
+
+func newAzureBatchTrack() (*azureBatchTrack, error) {
+	f, err := os.CreateTemp(getDataDir(), "azure_batch_*.wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch file: %w", err)
+	}
+
+	// The header is written again, with the actual sizes, once done.
+	if _, err := f.Write(pcm16WAVHeader(0)); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, fmt.Errorf("failed to write batch file: %w", err)
+	}
+
+	return &azureBatchTrack{file: f}, nil
+}
+
+// add appends the speech samples to the file.
+func (b *azureBatchTrack) add(ts trackTimedSamples) error {
+	var padding int
+	if b.dataSize > 0 {
+		padding = azureBatchPaddingMs * trackOutAudioSamplesPerMs
+	}
+
+	size := int64(2 * (padding + len(ts.pcm)))
+	if wavHeaderSize+b.dataSize+size > math.MaxUint32 {
+		return fmt.Errorf("batch file is too large")
+	}
+
+	data := make([]byte, 2*padding, size)
+	for _, s := range ts.pcm {
+		data = binary.LittleEndian.AppendUint16(data, uint16(int16(max(-1, min(1, s))*math.MaxInt16)))
+	}
+
+	if _, err := b.file.Write(data); err != nil {
+		return fmt.Errorf("failed to write batch file: %w", err)
+	}
+
+	b.tm = append(b.tm, transcribe.TimeSpan{
+		StartMs:         (b.dataSize/2 + int64(padding)) / trackOutAudioSamplesPerMs,
+		OriginalStartMs: ts.startTS,
+	})
+	b.dataSize += size
+
+	return nil
+}
+
+// finish completes the file, which is ready to be uploaded.
+func (b *azureBatchTrack) finish() error {
+	if _, err := b.file.WriteAt(pcm16WAVHeader(uint32(b.dataSize)), 0); err != nil {
+		return fmt.Errorf("failed to write batch file: %w", err)
+	}
+
+	if err := b.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync batch file: %w", err)
+	}
+
+	return nil
+}
+
+func (b *azureBatchTrack) remove() {
+	b.file.Close()
+	if err := os.Remove(b.file.Name()); err != nil {
+		slog.Error("failed to remove batch file", slog.String("err", err.Error()))
+	}
+}
+
+// transcribeAzureBatch transcribes the speech gathered in b through the Azure
+// batch API and appends the resulting segments to trackTr.
+func (t *Transcriber) transcribeAzureBatch(ctx trackContext, b *azureBatchTrack, trackTr *transcribe.TrackTranscription) error {
+	if len(b.tm) == 0 {
+		return nil
+	}
+
+	if err := b.finish(); err != nil {
+		return err
+	}
+
+	bt, err := azure.NewBatchTranscriber(t.azureBatchTranscriberConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create batch transcriber: %w", err)
+	}
+
+	c, cancelFn := context.WithTimeout(context.Background(), azureBatchTimeout)
+	defer cancelFn()
+
+	slog.Debug("transcribing track through Azure batch API",
+		slog.Int64("sizeBytes", wavHeaderSize+b.dataSize),
+		slog.String("trackID", ctx.trackID))
+
+	name := fmt.Sprintf("%s_%s.wav", t.cfg.TranscriptionID, ctx.trackID)
+	segments, lang, err := bt.TranscribeFile(c, name, b.file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to transcribe track: %w", err)
+	}
+
+	if lang != "" && trackTr.Language == "" {
+		trackTr.Language = lang
+	}
+
+	for _, s := range transcribe.FilterSegments(segments, t.outputFilterRE) {
+		// Durations are kept since phrases can run over the padding.
+		dur := s.EndTS - s.StartTS
+		s.StartTS = b.tm.ToOriginal(s.StartTS) + ctx.startTS
+		s.EndTS = s.StartTS + dur
+		if !trimPreRoll(&s) {
+			continue
+		}
+		trackTr.Segments = append(trackTr.Segments, s)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package call
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAzureBatchTrack(t *testing.T) {
+	setupTranscriberForTest(t)
+
+	b, err := newAzureBatchTrack()
+	require.NoError(t, err)
+
+	first := genTone(0.5, trackOutAudioRate)
+	second := genTone(0.25, trackOutAudioRate/2)
+	require.NoError(t, b.add(trackTimedSamples{pcm: first, startTS: 1000}))
+	require.NoError(t, b.add(trackTimedSamples{pcm: second, startTS: 5000}))
+	require.NoError(t, b.finish())
+
+	require.Equal(t, transcribe.TimeMap{
+		{StartMs: 0, OriginalStartMs: 1000},
+		{StartMs: 1000 + azureBatchPaddingMs, OriginalStartMs: 5000},
+	}, b.tm)
+
+	pcm, err := decodeWAV(b.file.Name())
+	require.NoError(t, err)
+	require.Len(t, pcm, len(first)+azureBatchPaddingMs*trackOutAudioSamplesPerMs+len(second))
+	require.InDeltaSlice(t, first, pcm[:len(first)], 0.001)
+	require.Equal(t, make([]float32, azureBatchPaddingMs*trackOutAudioSamplesPerMs), pcm[len(first):len(pcm)-len(second)])
+	require.InDeltaSlice(t, second, pcm[len(pcm)-len(second):], 0.001)
+
+	b.remove()
+	_, err = os.Stat(b.file.Name())
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestTranscribeAzureBatch(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	mux.HandleFunc("/audio/", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/audio/67t5u6cmtfbb7jug739d43xa9e_trackID.wav", r.URL.Path)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"self": "%[1]s/speechtotext/v3.2/transcriptions/trid", "status": "Succeeded", "links": {"files": "%[1]s/speechtotext/v3.2/transcriptions/trid/files"}}`, srv.URL)
+	})
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions/trid", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/speechtotext/v3.2/transcriptions/trid/files", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"values": [{"kind": "Transcription", "links": {"contentUrl": "%s/results/0.json"}}]}`, srv.URL)
+	})
+	mux.HandleFunc("/results/0.json", func(w http.ResponseWriter, _ *http.Request) {
+		// Phrases in the first sample, and in the second one after the
+		// padding.
+		fmt.Fprint(w, `{"recognizedPhrases": [
+			{"offsetInTicks": 2000000, "durationInTicks": 6000000, "locale": "it-IT", "nBest": [{"display": "Ciao."}]},
+			{"offsetInTicks": 16000000, "durationInTicks": 3000000, "locale": "it-IT", "nBest": [{"display": "Come va?"}]}
+		]}`)
+	})
+
+	tr.cfg.Engine.TranscribeAPI = config.TranscribeAPIAzure
+	tr.cfg.Engine.TranscribeAPIOptions = map[string]any{
+		"AZURE_SPEECH_KEY":              "speechkey",
+		"AZURE_BATCH":                   true,
+		"AZURE_BATCH_API_URL":           srv.URL,
+		"AZURE_BATCH_CONTAINER_SAS_URL": srv.URL + "/audio?sig=signature",
+	}
+	require.True(t, tr.azureBatchEnabled(config.TranscribeAPIAzure))
+	require.False(t, tr.azureBatchEnabled(config.TranscribeAPIWhisperCPP))
+	require.Equal(t, azureBatchLocaleDefault, tr.azureBatchTranscriberConfig().Locale)
+
+	b, err := newAzureBatchTrack()
+	require.NoError(t, err)
+	defer b.remove()
+
+	var trackTr transcribe.TrackTranscription
+	ctx := trackContext{trackID: "trackID", startTS: 10000}
+
+	t.Run("no speech", func(t *testing.T) {
+		require.NoError(t, tr.transcribeAzureBatch(ctx, b, &trackTr))
+		require.Empty(t, trackTr.Segments)
+	})
+
+	t.Run("speech", func(t *testing.T) {
+		require.NoError(t, b.add(trackTimedSamples{pcm: genTone(0.5, trackOutAudioRate), startTS: 1000}))
+		require.NoError(t, b.add(trackTimedSamples{pcm: genTone(0.5, trackOutAudioRate), startTS: 5000}))
+
+		require.NoError(t, tr.transcribeAzureBatch(ctx, b, &trackTr))
+		require.Equal(t, "it", trackTr.Language)
+		require.Equal(t, []transcribe.Segment{
+			{Text: "Ciao.", StartTS: 11200, EndTS: 11800, Language: "it"},
+			{Text: "Come va?", StartTS: 15100, EndTS: 15400, Language: "it"},
+		}, trackTr.Segments)
+	})
+}
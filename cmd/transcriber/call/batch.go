@@ -0,0 +1,211 @@
+package call
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+// BatchJob is a recording to transcribe as part of a batch.
+type BatchJob struct {
+	// Name identifies the job in the batch and names its output directory.
+	Name string `json:"name"`
+	// PostID is the post the transcription belongs to, reported along with
+	// the job's status so that results can be matched to calls.
+	PostID string `json:"post_id,omitempty"`
+	// Tracks are given as in the transcribe-file command,
+	// path[:speaker[:offset]].
+	Tracks []string `json:"tracks"`
+	// Config overrides settings of the base config for this job only, using
+	// the same flat keys as the plugin (e.g. "model_size").
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// BatchManifest lists the jobs of a batch.
+type BatchManifest struct {
+	Jobs []BatchJob `json:"jobs"`
+}
+
+type BatchJobState string
+
+const (
+	BatchJobStatePending BatchJobState = "pending"
+	BatchJobStateRunning BatchJobState = "running"
+	BatchJobStateDone    BatchJobState = "done"
+	BatchJobStateFailed  BatchJobState = "failed"
+)
+
+// BatchJobStatus is the outcome of a batch job.
+type BatchJobStatus struct {
+	Name       string        `json:"name"`
+	PostID     string        `json:"post_id,omitempty"`
+	State      BatchJobState `json:"state"`
+	Error      string        `json:"error,omitempty"`
+	Files      []string      `json:"files,omitempty"`
+	DurationMs int64         `json:"duration_ms,omitempty"`
+}
+
+// BatchOptions controls how a batch is run.
+type BatchOptions struct {
+	// OutputDir holds a directory per job, named after it.
+	OutputDir string
+	// StatusPath is where the statuses of all jobs are saved, updated as
+	// jobs progress. Jobs already done according to an existing status
+	// file are skipped so that an interrupted batch can be resumed.
+	StatusPath string
+	// Parallel is the number of jobs run at once.
+	Parallel int
+}
+
+// transcribeFilesFn is overridden in tests.
+var transcribeFilesFn = TranscribeFiles
+
+// LoadBatchManifest reads and validates a batch manifest.
+func LoadBatchManifest(path string) (BatchManifest, error) {
+	var manifest BatchManifest
+	if err := readJSONFile(path, &manifest); err != nil {
+		return manifest, err
+	}
+
+	if len(manifest.Jobs) == 0 {
+		return manifest, fmt.Errorf("no jobs in manifest")
+	}
+
+	names := make(map[string]bool, len(manifest.Jobs))
+	for i, job := range manifest.Jobs {
+		if job.Name == "" || job.Name == "." || job.Name == ".." || job.Name != sanitizeFilename(job.Name) {
+			return manifest, fmt.Errorf("job %d: invalid name %q", i, job.Name)
+		}
+		if names[job.Name] {
+			return manifest, fmt.Errorf("job %d: duplicate name %q", i, job.Name)
+		}
+		names[job.Name] = true
+
+		if len(job.Tracks) == 0 {
+			return manifest, fmt.Errorf("job %q: no tracks", job.Name)
+		}
+		for _, arg := range job.Tracks {
+			if _, err := ParseOfflineTrack(arg); err != nil {
+				return manifest, fmt.Errorf("job %q: invalid track %q: %w", job.Name, arg, err)
+			}
+		}
+	}
+
+	return manifest, nil
+}
+
+// batchJobConfig returns the base config with the job's overrides applied.
+func batchJobConfig(base config.CallTranscriberConfig, job BatchJob) config.CallTranscriberConfig {
+	if len(job.Config) == 0 {
+		return base
+	}
+
+	m := base.ToMap()
+	maps.Copy(m, job.Config)
+
+	var cfg config.CallTranscriberConfig
+	cfg.FromMap(m)
+	cfg.SetDefaults()
+
+	return cfg
+}
+
+// RunBatch transcribes the jobs of the manifest, Parallel at a time, and
+// returns their statuses. A failed job doesn't stop the batch.
+func RunBatch(base config.CallTranscriberConfig, manifest BatchManifest, opts BatchOptions) ([]BatchJobStatus, error) {
+	statuses := make([]BatchJobStatus, len(manifest.Jobs))
+	for i, job := range manifest.Jobs {
+		statuses[i] = BatchJobStatus{
+			Name:   job.Name,
+			PostID: job.PostID,
+			State:  BatchJobStatePending,
+		}
+	}
+
+	// Resuming a previous run of the batch.
+	var prevStatuses []BatchJobStatus
+	if err := readJSONFile(opts.StatusPath, &prevStatuses); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, prev := range prevStatuses {
+		for i := range statuses {
+			if statuses[i].Name == prev.Name && prev.State == BatchJobStateDone {
+				statuses[i] = prev
+			}
+		}
+	}
+
+	var mut sync.Mutex
+	setStatus := func(i int, status BatchJobStatus) {
+		mut.Lock()
+		defer mut.Unlock()
+		statuses[i] = status
+		if err := writeJSONFile(opts.StatusPath, statuses); err != nil {
+			slog.Error("failed to save batch status", slog.String("err", err.Error()))
+		}
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(1, opts.Parallel))
+	for i, job := range manifest.Jobs {
+		if statuses[i].State == BatchJobStateDone {
+			slog.Info("batch job already done, skipping", slog.String("name", job.Name))
+			continue
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, job BatchJob) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			status := BatchJobStatus{
+				Name:   job.Name,
+				PostID: job.PostID,
+				State:  BatchJobStateRunning,
+			}
+			setStatus(i, status)
+
+			slog.Info("running batch job", slog.String("name", job.Name), slog.String("postID", job.PostID))
+
+			start := time.Now()
+			files, err := runBatchJob(base, job, opts.OutputDir)
+			status.DurationMs = time.Since(start).Milliseconds()
+			if err != nil {
+				slog.Error("batch job failed", slog.String("name", job.Name), slog.String("err", err.Error()))
+				status.State = BatchJobStateFailed
+				status.Error = err.Error()
+			} else {
+				slog.Info("batch job done", slog.String("name", job.Name), slog.Int64("durationMs", status.DurationMs))
+				status.State = BatchJobStateDone
+				status.Files = files
+			}
+			setStatus(i, status)
+		}(i, job)
+	}
+	wg.Wait()
+
+	return statuses, nil
+}
+
+func runBatchJob(base config.CallTranscriberConfig, job BatchJob, outDir string) ([]string, error) {
+	tracks := make([]OfflineTrack, 0, len(job.Tracks))
+	for _, arg := range job.Tracks {
+		track, err := ParseOfflineTrack(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid track %q: %w", arg, err)
+		}
+		tracks = append(tracks, track)
+	}
+
+	return transcribeFilesFn(batchJobConfig(base, job), tracks, filepath.Join(outDir, job.Name), "transcription")
+}
@@ -0,0 +1,128 @@
+package call
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadBatchManifest(t *testing.T) {
+	dir := t.TempDir()
+	writeManifest := func(t *testing.T, data string) string {
+		t.Helper()
+		path := filepath.Join(dir, "manifest.json")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		return path
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		manifest, err := LoadBatchManifest(writeManifest(t, `{"jobs": [
+			{"name": "call1", "post_id": "udzdsg7dwidbzcidx5khrf8nee", "tracks": ["a.ogg:Alice", "b.ogg:Bob:1m"], "config": {"model_size": "small"}}
+		]}`))
+		require.NoError(t, err)
+		require.Equal(t, BatchManifest{
+			Jobs: []BatchJob{
+				{
+					Name:   "call1",
+					PostID: "udzdsg7dwidbzcidx5khrf8nee",
+					Tracks: []string{"a.ogg:Alice", "b.ogg:Bob:1m"},
+					Config: map[string]any{"model_size": "small"},
+				},
+			},
+		}, manifest)
+	})
+
+	for data, expectedError := range map[string]string{
+		`{"jobs": []}`: "no jobs in manifest",
+		`{"jobs": [{"name": "a/b", "tracks": ["a.ogg"]}]}`:                                   `job 0: invalid name "a/b"`,
+		`{"jobs": [{"name": "..", "tracks": ["a.ogg"]}]}`:                                    `job 0: invalid name ".."`,
+		`{"jobs": [{"name": "a", "tracks": ["a.ogg"]}, {"name": "a", "tracks": ["b.ogg"]}]}`: `job 1: duplicate name "a"`,
+		`{"jobs": [{"name": "a"}]}`:                                                          `job "a": no tracks`,
+		`{"jobs": [{"name": "a", "tracks": ["a.ogg:A:x"]}]}`:                                 `job "a": invalid track "a.ogg:A:x": failed to parse offset: time: invalid duration "x"`,
+	} {
+		_, err := LoadBatchManifest(writeManifest(t, data))
+		require.EqualError(t, err, expectedError, data)
+	}
+}
+
+func TestBatchJobConfig(t *testing.T) {
+	var base config.CallTranscriberConfig
+	base.Engine.NumThreads = 2
+	base.SetDefaults()
+
+	require.Equal(t, base, batchJobConfig(base, BatchJob{}))
+
+	cfg := batchJobConfig(base, BatchJob{
+		Config: map[string]any{
+			"model_size":  "small",
+			"num_threads": float64(4),
+		},
+	})
+	require.Equal(t, config.ModelSize(config.ModelSizeSmall), cfg.Engine.ModelSize)
+	require.Equal(t, 4, cfg.Engine.NumThreads)
+	require.Equal(t, base.Output, cfg.Output)
+}
+
+func TestRunBatch(t *testing.T) {
+	defer func(fn func(config.CallTranscriberConfig, []OfflineTrack, string, string) ([]string, error)) {
+		transcribeFilesFn = fn
+	}(transcribeFilesFn)
+
+	var mut sync.Mutex
+	var ran []string
+	transcribeFilesFn = func(cfg config.CallTranscriberConfig, tracks []OfflineTrack, outDir, fname string) ([]string, error) {
+		mut.Lock()
+		ran = append(ran, filepath.Base(outDir))
+		mut.Unlock()
+		if tracks[0].Path == "broken.ogg" {
+			return nil, fmt.Errorf("failed to decode")
+		}
+		return []string{filepath.Join(outDir, fname+".vtt")}, nil
+	}
+
+	dir := t.TempDir()
+	opts := BatchOptions{
+		OutputDir:  dir,
+		StatusPath: filepath.Join(dir, "batch_status.json"),
+		Parallel:   2,
+	}
+	manifest := BatchManifest{
+		Jobs: []BatchJob{
+			{Name: "call1", PostID: "post1", Tracks: []string{"a.ogg"}},
+			{Name: "call2", PostID: "post2", Tracks: []string{"broken.ogg"}},
+			{Name: "call3", PostID: "post3", Tracks: []string{"c.ogg"}},
+		},
+	}
+
+	statuses, err := RunBatch(config.CallTranscriberConfig{}, manifest, opts)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	require.ElementsMatch(t, []string{"call1", "call2", "call3"}, ran)
+
+	require.Equal(t, BatchJobStateDone, statuses[0].State)
+	require.Equal(t, "post1", statuses[0].PostID)
+	require.Equal(t, []string{filepath.Join(dir, "call1", "transcription.vtt")}, statuses[0].Files)
+	require.Equal(t, BatchJobStateFailed, statuses[1].State)
+	require.Equal(t, "failed to decode", statuses[1].Error)
+	require.Equal(t, BatchJobStateDone, statuses[2].State)
+
+	var saved []BatchJobStatus
+	require.NoError(t, readJSONFile(opts.StatusPath, &saved))
+	require.Equal(t, statuses, saved)
+
+	t.Run("resume", func(t *testing.T) {
+		ran = nil
+		statuses, err := RunBatch(config.CallTranscriberConfig{}, manifest, opts)
+		require.NoError(t, err)
+		// Only the failed job is run again.
+		require.Equal(t, []string{"call2"}, ran)
+		require.Equal(t, BatchJobStateDone, statuses[0].State)
+		require.Equal(t, BatchJobStateFailed, statuses[1].State)
+	})
+}
@@ -0,0 +1,59 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const (
+	// captionAckTimeout bounds how long we wait for the server to acknowledge
+	// a caption. Captions are only useful if timely so this is kept short.
+	captionAckTimeout = 2 * time.Second
+	// captionMaxSendAttempts is the number of times a caption is sent before
+	// giving up on it, i.e. it's resent once.
+	captionMaxSendAttempts = 2
+)
+
+// sendCaptionAcked delivers the caption through the plugin's bot API, which
+// responds once the caption has been broadcast. The WebSocket client doesn't
+// surface replies to sent messages, hence the HTTP path. A caption that isn't
+// acknowledged is resent once, then counted as lost.
+func (t *Transcriber) sendCaptionAcked(msg captionMsg) error {
+	payload, err := json.Marshal(&msg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal caption: %w", err)
+	}
+
+	apiURL := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/captions", t.apiURL, pluginID, t.cfg.CallID)
+	for i := 0; i < captionMaxSendAttempts; i++ {
+		if i > 0 {
+			slog.Warn("caption not acknowledged, resending",
+				slog.String("err", err.Error()),
+				slog.String("sessionID", msg.SessionID))
+		}
+
+		err = func() error {
+			ctx, cancelFn := context.WithTimeout(context.Background(), captionAckTimeout)
+			defer cancelFn()
+			resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL, payload, "")
+			if err != nil {
+				err = newAPIError(resp, err)
+				return fmt.Errorf("request failed: %w", err)
+			}
+			defer resp.Body.Close()
+			return nil
+		}()
+		if err == nil {
+			t.captionsAcked.Add(1)
+			return nil
+		}
+	}
+
+	t.captionsUnacked.Add(1)
+
+	return fmt.Errorf("caption not acknowledged: %w", err)
+}
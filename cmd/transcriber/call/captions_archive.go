@@ -0,0 +1,133 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	artifactTypeLiveTranscript artifactType = "live_transcript"
+)
+
+// captionsArchiveMaxSize caps the captions archive so that a long call can't
+// fill the data volume. Captions sent past it aren't archived.
+const captionsArchiveMaxSize = 64 * 1024 * 1024
+
+// recordedCaption is a caption persisted in the data directory, either
+// rather than broadcast in shadow mode, or once sent in the captions archive.
+type recordedCaption struct {
+	captionMsg
+	TrackID string `json:"track_id"`
+	// Timestamp is when the caption was sent, in milliseconds since the
+	// epoch.
+	Timestamp int64 `json:"timestamp"`
+	// OffsetMs is the time since the start of the recording.
+	OffsetMs int64 `json:"offset_ms"`
+}
+
+// captionsFile appends captions, as JSON lines, to a file opened on the
+// first write. Writes past maxSize, if set, are dropped.
+type captionsFile struct {
+	mut     sync.Mutex
+	maxSize int64
+	file    *os.File
+	size    int64
+	full    bool
+}
+
+func (f *captionsFile) write(path string, caption recordedCaption) error {
+	data, err := json.Marshal(caption)
+	if err != nil {
+		return fmt.Errorf("failed to marshal caption: %w", err)
+	}
+	data = append(data, '\n')
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.file == nil {
+		f.file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open captions file: %w", err)
+		}
+		// The file is appended to if the job was restarted.
+		if info, err := f.file.Stat(); err == nil {
+			f.size = info.Size()
+		}
+	}
+
+	if f.full {
+		return nil
+	}
+	if f.maxSize > 0 && f.size+int64(len(data)) > f.maxSize {
+		// Reported once, following captions are silently dropped.
+		f.full = true
+		return fmt.Errorf("captions file is full")
+	}
+
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write caption: %w", err)
+	}
+
+	return nil
+}
+
+// close closes the file, if open.
+func (f *captionsFile) close() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if f.file == nil {
+		return
+	}
+
+	if err := f.file.Close(); err != nil {
+		slog.Error("failed to close captions file", slog.String("err", err.Error()))
+	}
+	f.file = nil
+}
+
+func (t *Transcriber) shadowCaptionsPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_captions.jsonl", t.cfg.TranscriptionID))
+}
+
+func (t *Transcriber) captionsArchivePath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_live_captions.jsonl", t.cfg.TranscriptionID))
+}
+
+func (t *Transcriber) newRecordedCaption(ctx trackContext, msg captionMsg) recordedCaption {
+	var offsetMs int64
+	if startTime := t.startTime.Load(); startTime != nil {
+		offsetMs = time.Since(*startTime).Milliseconds()
+	}
+
+	return recordedCaption{
+		captionMsg: msg,
+		TrackID:    ctx.trackID,
+		Timestamp:  time.Now().UnixMilli(),
+		OffsetMs:   offsetMs,
+	}
+}
+
+// archiveCaption appends a caption sent to clients to the captions archive.
+// This is best effort, a failure is only logged.
+func (t *Transcriber) archiveCaption(ctx trackContext, msg captionMsg) {
+	if err := t.captionsArchive.write(t.captionsArchivePath(), t.newRecordedCaption(ctx, msg)); err != nil {
+		slog.Error("failed to archive caption",
+			slog.String("err", err.Error()),
+			slog.String("trackID", ctx.trackID))
+	}
+}
+
+// closeCaptionsFiles closes the shadow captions and archive files, if open.
+func (t *Transcriber) closeCaptionsFiles() {
+	t.shadowCaptions.close()
+	t.captionsArchive.close()
+}
@@ -0,0 +1,87 @@
+package call
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// asyncCaptionsTrailingSilenceMs is the silence pushed after each window so
+// that streaming recognizers finalize the utterance right away.
+const asyncCaptionsTrailingSilenceMs = 600
+
+// asyncCaptionsResultTimeout is how long a window's result is waited for
+// before giving up on captioning it.
+var asyncCaptionsResultTimeout = 3 * time.Second
+
+// asyncTranscriber is implemented by streaming APIs (e.g. Azure) which
+// continuously transcribe the audio pushed to them.
+type asyncTranscriber interface {
+	TranscribeAsync(samplesCh <-chan []float32) (<-chan transcribe.Segment, error)
+	Destroy() error
+}
+
+// asyncCaptionsTranscriber adapts a streaming API to the live captions
+// windows. All windows are pushed to the same recognition session, avoiding
+// the cost of setting up a new one every time.
+type asyncCaptionsTranscriber struct {
+	at         asyncTranscriber
+	samplesCh  chan []float32
+	segmentsCh <-chan transcribe.Segment
+}
+
+func newAsyncCaptionsTranscriber(at asyncTranscriber) (*asyncCaptionsTranscriber, error) {
+	samplesCh := make(chan []float32, 2)
+	segmentsCh, err := at.TranscribeAsync(samplesCh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transcribing: %w", err)
+	}
+
+	return &asyncCaptionsTranscriber{
+		at:         at,
+		samplesCh:  samplesCh,
+		segmentsCh: segmentsCh,
+	}, nil
+}
+
+func (c *asyncCaptionsTranscriber) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	// Results arriving after their window timed out are stale by now.
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-c.segmentsCh:
+			if !ok {
+				return nil, "", fmt.Errorf("transcription stopped")
+			}
+		default:
+			drained = true
+		}
+	}
+
+	timeoutCh := time.After(asyncCaptionsResultTimeout)
+	for _, pcm := range [][]float32{samples, make([]float32, asyncCaptionsTrailingSilenceMs*trackOutAudioSamplesPerMs)} {
+		select {
+		case c.samplesCh <- pcm:
+		case <-timeoutCh:
+			return nil, "", fmt.Errorf("timed out pushing samples")
+		}
+	}
+
+	select {
+	case segment, ok := <-c.segmentsCh:
+		if !ok {
+			return nil, "", fmt.Errorf("transcription stopped")
+		}
+		return []transcribe.Segment{segment}, "", nil
+	case <-timeoutCh:
+		// Nothing was recognized (e.g. noise), the window goes uncaptioned.
+		return nil, "", nil
+	}
+}
+
+func (c *asyncCaptionsTranscriber) Destroy() error {
+	close(c.samplesCh)
+	for range c.segmentsCh {
+	}
+	return c.at.Destroy()
+}
@@ -0,0 +1,71 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+// asyncTranscriberMock recognizes "speech" in any pushed samples that aren't
+// silent, emitting a segment per non silent push.
+type asyncTranscriberMock struct {
+	pushed    int
+	destroyed bool
+}
+
+func (m *asyncTranscriberMock) TranscribeAsync(samplesCh <-chan []float32) (<-chan transcribe.Segment, error) {
+	segmentsCh := make(chan transcribe.Segment, 1)
+	go func() {
+		defer close(segmentsCh)
+		for samples := range samplesCh {
+			m.pushed++
+			if len(samples) > 0 && samples[0] != 0 {
+				segmentsCh <- transcribe.Segment{Text: "Hello"}
+			}
+		}
+	}()
+	return segmentsCh, nil
+}
+
+func (m *asyncTranscriberMock) Destroy() error {
+	m.destroyed = true
+	return nil
+}
+
+func TestAsyncCaptionsTranscriber(t *testing.T) {
+	defer func(timeout time.Duration) {
+		asyncCaptionsResultTimeout = timeout
+	}(asyncCaptionsResultTimeout)
+	asyncCaptionsResultTimeout = 100 * time.Millisecond
+
+	m := &asyncTranscriberMock{}
+	ct, err := newAsyncCaptionsTranscriber(m)
+	require.NoError(t, err)
+
+	speech := make([]float32, trackOutAudioRate)
+	for i := range speech {
+		speech[i] = 0.5
+	}
+
+	t.Run("recognized", func(t *testing.T) {
+		segments, _, err := ct.Transcribe(speech)
+		require.NoError(t, err)
+		require.Equal(t, []transcribe.Segment{{Text: "Hello"}}, segments)
+	})
+
+	t.Run("nothing recognized", func(t *testing.T) {
+		segments, _, err := ct.Transcribe(make([]float32, trackOutAudioRate))
+		require.NoError(t, err)
+		require.Empty(t, segments)
+	})
+
+	t.Run("destroy", func(t *testing.T) {
+		require.NoError(t, ct.Destroy())
+		require.True(t, m.destroyed)
+		// Every window is followed by trailing silence.
+		require.Equal(t, 4, m.pushed)
+	})
+}
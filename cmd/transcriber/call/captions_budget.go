@@ -0,0 +1,67 @@
+package call
+
+import (
+	"sync"
+)
+
+// captionsWindowsBudget bounds the memory taken by the live captions windows
+// of all tracks combined. The per-track pressure valve alone doesn't prevent
+// large calls from exhausting memory.
+type captionsWindowsBudget struct {
+	mut sync.Mutex
+	// limit is the budget in bytes. Zero or less means unbounded.
+	limit   int64
+	sizes   map[string]int64
+	total   int64
+	trimmed uint64
+}
+
+func newCaptionsWindowsBudget(limit int64) *captionsWindowsBudget {
+	return &captionsWindowsBudget{
+		limit: limit,
+		sizes: make(map[string]int64),
+	}
+}
+
+// update records the current size, in bytes, of the track's window and
+// returns the size the window should be trimmed to. While over budget,
+// windows larger than an even share of the budget are trimmed down to it so
+// that tracks with a lot of buffered audio give up memory first.
+func (b *captionsWindowsBudget) update(trackID string, size int64) int64 {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.total += size - b.sizes[trackID]
+	b.sizes[trackID] = size
+
+	if b.limit <= 0 || b.total <= b.limit {
+		return size
+	}
+
+	share := b.limit / int64(len(b.sizes))
+	if size <= share {
+		return size
+	}
+
+	b.total -= size - share
+	b.sizes[trackID] = share
+	b.trimmed++
+
+	return share
+}
+
+// remove releases the budget taken by the track's window.
+func (b *captionsWindowsBudget) remove(trackID string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.total -= b.sizes[trackID]
+	delete(b.sizes, trackID)
+}
+
+// get returns the bytes currently taken by all windows along with the number
+// of times a window was trimmed to stay within budget.
+func (b *captionsWindowsBudget) get() (total int64, trimmed uint64) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.total, b.trimmed
+}
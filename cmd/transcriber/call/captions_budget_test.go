@@ -0,0 +1,56 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptionsWindowsBudget(t *testing.T) {
+	t.Run("unbounded", func(t *testing.T) {
+		b := newCaptionsWindowsBudget(0)
+		require.Equal(t, int64(1000), b.update("trackA", 1000))
+		require.Equal(t, int64(2000), b.update("trackB", 2000))
+		total, trimmed := b.get()
+		require.Equal(t, int64(3000), total)
+		require.Zero(t, trimmed)
+	})
+
+	t.Run("within budget", func(t *testing.T) {
+		b := newCaptionsWindowsBudget(1000)
+		require.Equal(t, int64(600), b.update("trackA", 600))
+		require.Equal(t, int64(400), b.update("trackB", 400))
+		require.Equal(t, int64(300), b.update("trackA", 300))
+		total, _ := b.get()
+		require.Equal(t, int64(700), total)
+	})
+
+	t.Run("over budget", func(t *testing.T) {
+		b := newCaptionsWindowsBudget(1000)
+		require.Equal(t, int64(200), b.update("trackA", 200))
+		require.Equal(t, int64(700), b.update("trackB", 700))
+
+		// The small window is left alone.
+		require.Equal(t, int64(300), b.update("trackC", 300))
+		total, trimmed := b.get()
+		require.Equal(t, int64(1200), total)
+		require.Zero(t, trimmed)
+
+		// The larger one gets trimmed to an even share.
+		require.Equal(t, int64(333), b.update("trackB", 700))
+		total, trimmed = b.get()
+		require.Equal(t, int64(833), total)
+		require.Equal(t, uint64(1), trimmed)
+
+		// Once back within budget, windows can grow again.
+		require.Equal(t, int64(450), b.update("trackC", 450))
+		require.Equal(t, int64(333), b.update("trackC", 600))
+		total, trimmed = b.get()
+		require.Equal(t, int64(866), total)
+		require.Equal(t, uint64(2), trimmed)
+
+		b.remove("trackB")
+		total, _ = b.get()
+		require.Equal(t, int64(533), total)
+	})
+}
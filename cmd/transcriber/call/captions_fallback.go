@@ -0,0 +1,220 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// captionsFallbackThreshold is the number of consecutive WebSocket send
+	// failures after which captions are delivered over HTTP instead.
+	captionsFallbackThreshold = 3
+	// captionsFallbackInterval is how often captions queued for HTTP delivery
+	// are posted, in a single batch.
+	captionsFallbackInterval = time.Second
+	// captionsFallbackProbeInterval is how often, while falling back, a
+	// caption is sent over WebSocket again to check whether it recovered.
+	captionsFallbackProbeInterval = 10 * time.Second
+	// captionsFallbackMaxPending caps the captions waiting to be posted.
+	// Older ones are dropped first as they are stale by then.
+	captionsFallbackMaxPending = 100
+	// captionsFallbackTimeout bounds the posting of a batch.
+	captionsFallbackTimeout = 5 * time.Second
+)
+
+// captionsFallback keeps live captions flowing when the WebSocket connection
+// is under pressure: after repeated send failures captions are queued and
+// posted in batches to the plugin, slightly delayed, until a WebSocket send
+// succeeds again.
+type captionsFallback struct {
+	mut      sync.Mutex
+	failures int
+	active   bool
+	// probedAt is when a WebSocket send was last attempted while active, as
+	// a monotonic clock reading.
+	probedAt time.Duration
+	pending  []captionMsg
+	dropped  int
+
+	// flushMut serializes flushes so that batches are posted in order.
+	flushMut sync.Mutex
+}
+
+type captionsFallbackRequest struct {
+	Captions []captionMsg `json:"captions"`
+}
+
+// useHTTP returns whether the caption should be queued rather than sent
+// over WebSocket.
+func (f *captionsFallback) useHTTP(now time.Duration) bool {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if !f.active {
+		return false
+	}
+
+	if now-f.probedAt < captionsFallbackProbeInterval {
+		return true
+	}
+	f.probedAt = now
+
+	return false
+}
+
+// report records the outcome of a WebSocket send, returning whether the
+// fallback is active.
+func (f *captionsFallback) report(err error, now time.Duration) bool {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if err == nil {
+		if f.active {
+			slog.Info("live captions WebSocket delivery recovered, leaving HTTP fallback")
+		}
+		f.failures = 0
+		f.active = false
+		return false
+	}
+
+	f.failures++
+	if !f.active && f.failures >= captionsFallbackThreshold {
+		slog.Warn("live captions WebSocket delivery failing, falling back to HTTP",
+			slog.Int("failures", f.failures),
+			slog.String("err", err.Error()))
+		f.active = true
+		f.probedAt = now
+	}
+
+	return f.active
+}
+
+// queue adds captions to be posted, dropping the oldest ones past
+// captionsFallbackMaxPending.
+func (f *captionsFallback) queue(msgs ...captionMsg) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.pending = append(f.pending, msgs...)
+	if n := len(f.pending) - captionsFallbackMaxPending; n > 0 {
+		f.pending = f.pending[n:]
+		f.dropped += n
+	}
+}
+
+// requeue puts back a batch that failed to be posted ahead of the captions
+// queued in the meantime.
+func (f *captionsFallback) requeue(msgs []captionMsg) {
+	f.mut.Lock()
+	pending := f.pending
+	f.pending = nil
+	f.mut.Unlock()
+
+	f.queue(append(msgs, pending...)...)
+}
+
+func (f *captionsFallback) take() []captionMsg {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	pending := f.pending
+	f.pending = nil
+
+	return pending
+}
+
+// sendCaptionWS sends the caption over WebSocket, falling back to HTTP if
+// that keeps failing.
+func (t *Transcriber) sendCaptionWS(msg captionMsg) error {
+	return t.sendCaptionWithFallback(msg, func(msg captionMsg) error {
+		return t.client.Load().SendWS(wsEvCaption, msg, false)
+	})
+}
+
+func (t *Transcriber) sendCaptionWithFallback(msg captionMsg, sendWS func(msg captionMsg) error) error {
+	now := t.monoNow()
+	if t.captionsFallback.useHTTP(now) {
+		t.captionsFallback.queue(msg)
+		return nil
+	}
+
+	// Captions queued before probing go out first so that clients receive
+	// them in order.
+	t.flushCaptionsFallback()
+
+	err := sendWS(msg)
+	if t.captionsFallback.report(err, now) {
+		t.captionsFallback.queue(msg)
+		return nil
+	}
+
+	return err
+}
+
+// flushCaptionsFallback posts the queued captions, if any. A batch failing
+// to be posted is kept for the next flush.
+func (t *Transcriber) flushCaptionsFallback() {
+	t.captionsFallback.flushMut.Lock()
+	defer t.captionsFallback.flushMut.Unlock()
+
+	msgs := t.captionsFallback.take()
+	if len(msgs) == 0 {
+		return
+	}
+
+	if err := t.postCaptions(msgs); err != nil {
+		slog.Error("failed to post captions", slog.String("err", err.Error()), slog.Int("count", len(msgs)))
+		t.captionsFallback.requeue(msgs)
+	}
+}
+
+// postCaptions delivers a batch of captions through the plugin's bot API,
+// which broadcasts them to clients.
+func (t *Transcriber) postCaptions(msgs []captionMsg) error {
+	payload, err := json.Marshal(captionsFallbackRequest{Captions: msgs})
+	if err != nil {
+		return fmt.Errorf("failed to marshal captions: %w", err)
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), captionsFallbackTimeout)
+	defer cancelFn()
+
+	apiURL := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/captions/batch", t.apiURL, pluginID, t.cfg.CallID)
+	resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL, payload, "")
+	if err != nil {
+		err = newAPIError(resp, err)
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return nil
+}
+
+// monitorCaptionsFallback periodically posts the captions queued while
+// falling back to HTTP.
+func (t *Transcriber) monitorCaptionsFallback() {
+	ticker := time.NewTicker(captionsFallbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.flushCaptionsFallback()
+		case <-t.doneCh:
+			t.flushCaptionsFallback()
+
+			t.captionsFallback.mut.Lock()
+			dropped := t.captionsFallback.dropped
+			t.captionsFallback.mut.Unlock()
+			if dropped > 0 {
+				slog.Warn("live captions dropped while falling back to HTTP", slog.Int("count", dropped))
+			}
+			return
+		}
+	}
+}
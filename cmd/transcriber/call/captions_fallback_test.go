@@ -0,0 +1,110 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptionsFallback(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	var now time.Duration
+	tr.monoNow = func() time.Duration {
+		return now
+	}
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	var posted [][]string
+	batchURL := "http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/captions/batch"
+	mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, batchURL, mock.Anything, "").
+		Return(nil, fmt.Errorf("timeout")).Once()
+	mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, batchURL, mock.Anything, "").
+		Run(func(args mock.Arguments) {
+			var req captionsFallbackRequest
+			require.NoError(t, json.Unmarshal(args.Get(3).([]byte), &req))
+			var texts []string
+			for _, c := range req.Captions {
+				texts = append(texts, c.Text)
+			}
+			posted = append(posted, texts)
+		}).
+		Return(&http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil)
+
+	wsErr := fmt.Errorf("ws send failed")
+	var sentWS []string
+	sendWS := func(msg captionMsg) error {
+		if wsErr != nil {
+			return wsErr
+		}
+		sentWS = append(sentWS, msg.Text)
+		return nil
+	}
+
+	send := func(text string) error {
+		return tr.sendCaptionWithFallback(captionMsg{
+			CaptionMsg: public.CaptionMsg{
+				SessionID: "sessionID",
+				Text:      text,
+			},
+		}, sendWS)
+	}
+
+	// Failures are reported until the threshold is reached.
+	for i := 1; i < captionsFallbackThreshold; i++ {
+		require.ErrorIs(t, send(fmt.Sprintf("lost%d", i)), wsErr)
+	}
+	require.NoError(t, send("c1"))
+	require.True(t, tr.captionsFallback.active)
+
+	// While falling back, captions are queued without trying WebSocket.
+	wsErr = nil
+	now += time.Second
+	require.NoError(t, send("c2"))
+	require.Empty(t, sentWS)
+
+	// A failed batch is kept for the next flush.
+	tr.flushCaptionsFallback()
+	require.Empty(t, posted)
+	require.NoError(t, send("c3"))
+	tr.flushCaptionsFallback()
+	require.Equal(t, [][]string{{"c1", "c2", "c3"}}, posted)
+
+	// Once WebSocket is probed successfully, queued captions are posted first.
+	require.NoError(t, send("c4"))
+	now += captionsFallbackProbeInterval
+	require.NoError(t, send("c5"))
+	require.Equal(t, [][]string{{"c1", "c2", "c3"}, {"c4"}}, posted)
+	require.Equal(t, []string{"c5"}, sentWS)
+	require.False(t, tr.captionsFallback.active)
+
+	require.NoError(t, send("c6"))
+	require.Equal(t, []string{"c5", "c6"}, sentWS)
+}
+
+func TestCaptionsFallbackMaxPending(t *testing.T) {
+	var f captionsFallback
+	for i := 0; i < captionsFallbackMaxPending+10; i++ {
+		f.queue(captionMsg{CaptionMsg: public.CaptionMsg{Text: fmt.Sprintf("c%d", i)}})
+	}
+
+	pending := f.take()
+	require.Len(t, pending, captionsFallbackMaxPending)
+	require.Equal(t, "c10", pending[0].Text)
+	require.Equal(t, 10, f.dropped)
+	require.Empty(t, f.take())
+}
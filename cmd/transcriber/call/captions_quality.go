@@ -0,0 +1,89 @@
+package call
+
+import (
+	"sync"
+	"time"
+)
+
+// captionsQualityStats summarizes the live captions experience over a call.
+type captionsQualityStats struct {
+	// CaptionsSent is the number of captions delivered to clients (or
+	// persisted in shadow mode).
+	CaptionsSent uint64 `json:"captions_sent"`
+	// LatencyAvgMs and LatencyMaxMs measure the time from a window being
+	// queued for transcription to its caption being sent.
+	LatencyAvgMs int64 `json:"latency_avg_ms"`
+	LatencyMaxMs int64 `json:"latency_max_ms"`
+	// WindowsDropped counts the audio windows discarded, either under
+	// pressure or because the transcription queue was full.
+	WindowsDropped uint64 `json:"windows_dropped"`
+	// SessionsSuppressed is the number of sessions which had at least one
+	// window dropped, meaning part of their speech was never captioned.
+	SessionsSuppressed int `json:"sessions_suppressed"`
+	// BacklogMs is the total time tracks spent waiting on the transcribers
+	// past their regular tick.
+	BacklogMs int64 `json:"backlog_ms"`
+}
+
+// captionsQuality accumulates live captions stats across all tracks.
+type captionsQuality struct {
+	mut            sync.Mutex
+	sent           uint64
+	latencySum     time.Duration
+	latencyMax     time.Duration
+	windowsDropped uint64
+	suppressed     map[string]bool
+	backlog        time.Duration
+}
+
+func (q *captionsQuality) addSent(latency time.Duration) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	q.sent++
+	q.latencySum += latency
+	q.latencyMax = max(q.latencyMax, latency)
+}
+
+func (q *captionsQuality) addDroppedWindow(sessionID string) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	q.windowsDropped++
+	if q.suppressed == nil {
+		q.suppressed = make(map[string]bool)
+	}
+	q.suppressed[sessionID] = true
+}
+
+func (q *captionsQuality) addBacklog(d time.Duration) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	q.backlog += d
+}
+
+func (q *captionsQuality) get() captionsQualityStats {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	stats := captionsQualityStats{
+		CaptionsSent:       q.sent,
+		LatencyMaxMs:       q.latencyMax.Milliseconds(),
+		WindowsDropped:     q.windowsDropped,
+		SessionsSuppressed: len(q.suppressed),
+		BacklogMs:          q.backlog.Milliseconds(),
+	}
+	if q.sent > 0 {
+		stats.LatencyAvgMs = (q.latencySum / time.Duration(q.sent)).Milliseconds()
+	}
+
+	return stats
+}
+
+// getCaptionsQualityStats returns the live captions stats for the call, or
+// nil if live captions are off.
+func (t *Transcriber) getCaptionsQualityStats() *captionsQualityStats {
+	if !t.cfg.LiveCaptions.On {
+		return nil
+	}
+	stats := t.captionsQuality.get()
+	return &stats
+}
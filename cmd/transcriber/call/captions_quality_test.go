@@ -0,0 +1,44 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptionsQuality(t *testing.T) {
+	var q captionsQuality
+	require.Equal(t, captionsQualityStats{}, q.get())
+
+	q.addSent(100 * time.Millisecond)
+	q.addSent(300 * time.Millisecond)
+	q.addDroppedWindow("sessionA")
+	q.addDroppedWindow("sessionA")
+	q.addDroppedWindow("sessionB")
+	q.addBacklog(2 * time.Second)
+
+	require.Equal(t, captionsQualityStats{
+		CaptionsSent:       2,
+		LatencyAvgMs:       200,
+		LatencyMaxMs:       300,
+		WindowsDropped:     3,
+		SessionsSuppressed: 2,
+		BacklogMs:          2000,
+	}, q.get())
+
+	t.Run("runtime stats", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.LiveCaptions.On = false
+		require.Nil(t, tr.getCaptionsQualityStats())
+		require.Nil(t, tr.getRuntimeStats().Captions)
+
+		tr.cfg.LiveCaptions.On = true
+		tr.captionsQuality.addSent(time.Second)
+		require.Equal(t, &captionsQualityStats{
+			CaptionsSent: 1,
+			LatencyAvgMs: 1000,
+			LatencyMaxMs: 1000,
+		}, tr.getRuntimeStats().Captions)
+	})
+}
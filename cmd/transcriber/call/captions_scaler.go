@@ -0,0 +1,187 @@
+package call
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const (
+	// captionsScaleInterval is how often the live captions load is evaluated.
+	captionsScaleInterval = 10 * time.Second
+	// captionsScaleBackAfter is the number of consecutive intervals without
+	// any pressure after which a previous scaling step is undone.
+	captionsScaleBackAfter = 6
+)
+
+// captionsModelSizes are the models live captions can run on, from the most
+// to the least accurate (and demanding).
+var captionsModelSizes = []config.ModelSize{
+	config.ModelSizeLarge,
+	config.ModelSizeMedium,
+	config.ModelSizeSmall,
+	config.ModelSizeBase,
+	config.ModelSizeTiny,
+}
+
+type captionsScaleAction int
+
+const (
+	captionsScaleNone captionsScaleAction = iota
+	captionsScaleAddTranscriber
+	captionsScaleRemoveTranscriber
+	captionsScaleDownsizeModel
+	captionsScaleRestoreModel
+)
+
+// captionsScaler adjusts the live captions transcribers to the load. Under
+// pressure (windows dropped or tracks waiting on the transcribers), more
+// transcribers are started, up to what the CPUs allow, after which the model
+// is downsized. Once the load has been handled for a while the last step is
+// undone, restoring the model first.
+type captionsScaler struct {
+	mut sync.Mutex
+	// stops holds a channel per running transcriber, closed to stop it.
+	stops []chan struct{}
+	// minTranscribers is the number of transcribers configured, which is
+	// never scaled below.
+	minTranscribers int
+	maxTranscribers int
+	// modelSizes are the available models, from the configured one down.
+	modelSizes []config.ModelSize
+	level      int
+	idle       int
+
+	lastDropped uint64
+	lastBacklog time.Duration
+}
+
+func newCaptionsScaler(cfg config.LiveCaptionsConfig, modelsDir string) *captionsScaler {
+	s := &captionsScaler{
+		minTranscribers: cfg.NumTranscribers,
+		maxTranscribers: max(cfg.NumTranscribers, runtime.NumCPU()/max(1, cfg.NumThreadsPerTranscriber)),
+		modelSizes:      []config.ModelSize{cfg.ModelSize},
+	}
+
+	// Only downsizing to models that are available.
+	if idx := slices.Index(captionsModelSizes, cfg.ModelSize); cfg.AutoScale && idx >= 0 {
+		for _, size := range captionsModelSizes[idx+1:] {
+			if _, err := os.Stat(filepath.Join(modelsDir, fmt.Sprintf("ggml-%s.bin", size))); err == nil {
+				s.modelSizes = append(s.modelSizes, size)
+			}
+		}
+	}
+
+	return s
+}
+
+// modelSize returns the model live captions should currently run on.
+func (s *captionsScaler) modelSize() config.ModelSize {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.modelSizes[s.level]
+}
+
+// evaluate returns the action to take given the total windows dropped and
+// backlog accumulated so far.
+func (s *captionsScaler) evaluate(dropped uint64, backlog time.Duration) captionsScaleAction {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	pressure := dropped > s.lastDropped || backlog > s.lastBacklog
+	s.lastDropped = dropped
+	s.lastBacklog = backlog
+
+	if pressure {
+		s.idle = 0
+		if len(s.stops) < s.maxTranscribers {
+			return captionsScaleAddTranscriber
+		}
+		if s.level < len(s.modelSizes)-1 {
+			s.level++
+			return captionsScaleDownsizeModel
+		}
+		return captionsScaleNone
+	}
+
+	s.idle++
+	if s.idle < captionsScaleBackAfter {
+		return captionsScaleNone
+	}
+	s.idle = 0
+
+	if s.level > 0 {
+		s.level--
+		return captionsScaleRestoreModel
+	}
+	if len(s.stops) > s.minTranscribers {
+		return captionsScaleRemoveTranscriber
+	}
+
+	return captionsScaleNone
+}
+
+// addTranscriber registers a new transcriber, returning its number and the
+// channel closed to stop it.
+func (s *captionsScaler) addTranscriber() (int, chan struct{}) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	stopCh := make(chan struct{})
+	s.stops = append(s.stops, stopCh)
+	return len(s.stops) - 1, stopCh
+}
+
+// removeTranscriber stops the most recently added transcriber.
+func (s *captionsScaler) removeTranscriber() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if len(s.stops) == 0 {
+		return
+	}
+	close(s.stops[len(s.stops)-1])
+	s.stops = s.stops[:len(s.stops)-1]
+}
+
+func (t *Transcriber) startCaptionsTranscriber() {
+	num, stopCh := t.captionsScaler.addTranscriber()
+	t.captionsPoolWg.Add(1)
+	go t.handleTranscriptionRequests(num, stopCh)
+}
+
+// scaleLiveCaptions periodically adjusts the live captions transcribers to
+// the load until the pool is closed.
+func (t *Transcriber) scaleLiveCaptions() {
+	defer t.captionsPoolWg.Done()
+
+	ticker := time.NewTicker(captionsScaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			stats := t.captionsQuality.get()
+			action := t.captionsScaler.evaluate(stats.WindowsDropped, time.Duration(stats.BacklogMs)*time.Millisecond)
+			switch action {
+			case captionsScaleAddTranscriber:
+				slog.Info("live captions falling behind, adding a transcriber")
+				t.startCaptionsTranscriber()
+			case captionsScaleRemoveTranscriber:
+				slog.Info("live captions keeping up, removing a transcriber")
+				t.captionsScaler.removeTranscriber()
+			case captionsScaleDownsizeModel, captionsScaleRestoreModel:
+				// Transcribers switch model once done with their current request.
+				slog.Info("live captions switching model",
+					slog.String("modelSize", string(t.captionsScaler.modelSize())))
+			}
+		case <-t.captionsPoolDoneCh:
+			return
+		}
+	}
+}
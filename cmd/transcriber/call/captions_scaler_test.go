@@ -0,0 +1,106 @@
+package call
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptionsScaler(t *testing.T) {
+	modelsDir := t.TempDir()
+	for _, size := range []config.ModelSize{config.ModelSizeBase, config.ModelSizeTiny} {
+		require.NoError(t, os.WriteFile(filepath.Join(modelsDir, "ggml-"+string(size)+".bin"), nil, 0600))
+	}
+
+	cfg := config.LiveCaptionsConfig{
+		ModelSize:                config.ModelSizeSmall,
+		NumTranscribers:          1,
+		NumThreadsPerTranscriber: runtime.NumCPU(),
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		s := newCaptionsScaler(cfg, modelsDir)
+		require.Equal(t, []config.ModelSize{config.ModelSizeSmall}, s.modelSizes)
+	})
+
+	cfg.AutoScale = true
+	s := newCaptionsScaler(cfg, modelsDir)
+	// Only downsizing to the models available.
+	require.Equal(t, []config.ModelSize{config.ModelSizeSmall, config.ModelSizeBase, config.ModelSizeTiny}, s.modelSizes)
+	require.Equal(t, 1, s.maxTranscribers)
+	s.addTranscriber()
+
+	var dropped uint64
+	var backlog time.Duration
+
+	t.Run("scale down under pressure", func(t *testing.T) {
+		require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+
+		// No more transcribers can run, the model is downsized instead.
+		dropped++
+		require.Equal(t, captionsScaleDownsizeModel, s.evaluate(dropped, backlog))
+		require.Equal(t, config.ModelSize(config.ModelSizeBase), s.modelSize())
+
+		backlog += 2 * time.Second
+		require.Equal(t, captionsScaleDownsizeModel, s.evaluate(dropped, backlog))
+		require.Equal(t, config.ModelSizeTiny, s.modelSize())
+
+		dropped++
+		require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		require.Equal(t, config.ModelSizeTiny, s.modelSize())
+	})
+
+	t.Run("scale back once keeping up", func(t *testing.T) {
+		for i := 0; i < captionsScaleBackAfter-1; i++ {
+			require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		}
+		require.Equal(t, captionsScaleRestoreModel, s.evaluate(dropped, backlog))
+		require.Equal(t, config.ModelSize(config.ModelSizeBase), s.modelSize())
+
+		// Any pressure resets the count.
+		for i := 0; i < captionsScaleBackAfter-1; i++ {
+			require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		}
+		dropped++
+		require.Equal(t, captionsScaleDownsizeModel, s.evaluate(dropped, backlog))
+		require.Equal(t, config.ModelSizeTiny, s.modelSize())
+	})
+
+	t.Run("transcribers", func(t *testing.T) {
+		s := newCaptionsScaler(cfg, modelsDir)
+		s.maxTranscribers = 2
+		s.addTranscriber()
+
+		dropped++
+		require.Equal(t, captionsScaleAddTranscriber, s.evaluate(dropped, backlog))
+		_, stopCh := s.addTranscriber()
+
+		dropped++
+		require.Equal(t, captionsScaleDownsizeModel, s.evaluate(dropped, backlog))
+
+		// The model is restored before transcribers are removed.
+		for i := 0; i < captionsScaleBackAfter-1; i++ {
+			require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		}
+		require.Equal(t, captionsScaleRestoreModel, s.evaluate(dropped, backlog))
+		for i := 0; i < captionsScaleBackAfter-1; i++ {
+			require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		}
+		require.Equal(t, captionsScaleRemoveTranscriber, s.evaluate(dropped, backlog))
+		s.removeTranscriber()
+		require.Len(t, s.stops, 1)
+		_, ok := <-stopCh
+		require.False(t, ok)
+
+		// Never below the configured transcribers.
+		for i := 0; i < captionsScaleBackAfter; i++ {
+			require.Equal(t, captionsScaleNone, s.evaluate(dropped, backlog))
+		}
+	})
+}
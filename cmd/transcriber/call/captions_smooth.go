@@ -0,0 +1,147 @@
+package call
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+// captionsDedupExpiry is how long a caption is remembered for
+// deduplication. Past that, the same text is assumed to be said again.
+const captionsDedupExpiry = 10 * time.Second
+
+// captionsBatch is the set of captions, one per language, transcribed from a
+// window of a track.
+type captionsBatch struct {
+	captions      []captionText
+	newAudioLenMs int
+	partial       bool
+}
+
+// captionsSmoother reduces the captions sent for a track. Since windows are
+// transcribed again as they grow, consecutive captions often repeat the same
+// text: those (near-)identical to the previous one are suppressed. Sends are
+// also spaced by at least minInterval, the latest captions being held until
+// then.
+type captionsSmoother struct {
+	minInterval time.Duration
+	maxDistance float64
+
+	held        *captionsBatch
+	sent        bool
+	lastSentAt  time.Duration
+	lastPartial bool
+	// lastTexts holds the normalized text last sent in each language.
+	lastTexts map[string]string
+	// suppressed counts the captions suppressed as repeats.
+	suppressed int
+}
+
+func newCaptionsSmoother(cfg config.LiveCaptionsConfig) *captionsSmoother {
+	return &captionsSmoother{
+		minInterval: time.Duration(cfg.MinIntervalMs) * time.Millisecond,
+		maxDistance: cfg.DedupMaxDistance,
+		lastTexts:   make(map[string]string),
+	}
+}
+
+// next queues the batch, if any, and returns the captions to send now, or
+// nil if there are none. Queued captions replace those still held. With
+// force set, held captions are returned regardless of the time since the
+// last send (e.g. because the window is over).
+func (s *captionsSmoother) next(b *captionsBatch, now time.Duration, force bool) *captionsBatch {
+	if b != nil {
+		if s.held != nil {
+			b.newAudioLenMs += s.held.newAudioLenMs
+		}
+		s.held = b
+	}
+
+	if s.held == nil || (!force && s.sent && now-s.lastSentAt < s.minInterval) {
+		return nil
+	}
+
+	held := s.held
+	s.held = nil
+
+	out := &captionsBatch{
+		newAudioLenMs: held.newAudioLenMs,
+		partial:       held.partial,
+	}
+	for _, caption := range held.captions {
+		if s.isRepeat(caption, held.partial, now) {
+			s.suppressed++
+			continue
+		}
+		out.captions = append(out.captions, caption)
+	}
+	if len(out.captions) == 0 {
+		return nil
+	}
+
+	s.sent = true
+	s.lastSentAt = now
+	s.lastPartial = out.partial
+	for _, caption := range out.captions {
+		s.lastTexts[caption.language] = normalizeCaptionText(caption.text)
+	}
+
+	return out
+}
+
+// isRepeat returns whether the caption repeats the last one sent in its
+// language. A final caption repeating a partial one isn't, since clients
+// wait for it to settle the text.
+func (s *captionsSmoother) isRepeat(caption captionText, partial bool, now time.Duration) bool {
+	if s.maxDistance < 0 || !s.sent || now-s.lastSentAt > captionsDedupExpiry {
+		return false
+	}
+	if s.lastPartial && !partial {
+		return false
+	}
+
+	last, ok := s.lastTexts[caption.language]
+	if !ok {
+		return false
+	}
+
+	text := normalizeCaptionText(caption.text)
+	maxLen := max(len([]rune(last)), len([]rune(text)))
+
+	return editDistance(last, text) <= int(s.maxDistance*float64(maxLen))
+}
+
+// normalizeCaptionText lowercases the text and strips its punctuation so that
+// captions differing only in those compare equal.
+func normalizeCaptionText(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	return strings.Join(words, " ")
+}
+
+// editDistance returns the Levenshtein distance between a and b, in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,101 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditDistance(t *testing.T) {
+	require.Equal(t, 0, editDistance("", ""))
+	require.Equal(t, 3, editDistance("", "abc"))
+	require.Equal(t, 0, editDistance("hello", "hello"))
+	require.Equal(t, 3, editDistance("kitten", "sitting"))
+	require.Equal(t, 1, editDistance("héllo", "hello"))
+}
+
+func TestNormalizeCaptionText(t *testing.T) {
+	require.Equal(t, "hello there how are you", normalizeCaptionText("  Hello there!  How are you? "))
+	require.Equal(t, "", normalizeCaptionText("..."))
+}
+
+func TestCaptionsSmoother(t *testing.T) {
+	batch := func(text string, partial bool) *captionsBatch {
+		return &captionsBatch{
+			captions:      []captionText{{language: "en", text: text}},
+			newAudioLenMs: 1000,
+			partial:       partial,
+		}
+	}
+
+	t.Run("repeats", func(t *testing.T) {
+		s := newCaptionsSmoother(config.LiveCaptionsConfig{DedupMaxDistance: 0.1})
+
+		require.NotNil(t, s.next(batch("Hello there, how are you?", false), 0, false))
+		require.Nil(t, s.next(batch("hello there how are you", false), time.Second, false))
+		// One character off.
+		require.Nil(t, s.next(batch("Hello there, how are you!?", false), 2*time.Second, false))
+		require.NotNil(t, s.next(batch("Hello there, how are you doing?", false), 3*time.Second, false))
+		require.Equal(t, 2, s.suppressed)
+
+		// Said again after a while.
+		require.NotNil(t, s.next(batch("Hello there, how are you doing?", false), 3*time.Second+captionsDedupExpiry+1, false))
+	})
+
+	t.Run("final after partial", func(t *testing.T) {
+		s := newCaptionsSmoother(config.LiveCaptionsConfig{DedupMaxDistance: 0.1})
+
+		require.NotNil(t, s.next(batch("Hello there", true), 0, false))
+		require.Nil(t, s.next(batch("Hello there", true), time.Second, false))
+		b := s.next(batch("Hello there", false), 2*time.Second, true)
+		require.NotNil(t, b)
+		require.False(t, b.partial)
+	})
+
+	t.Run("per language", func(t *testing.T) {
+		s := newCaptionsSmoother(config.LiveCaptionsConfig{DedupMaxDistance: 0.1})
+
+		require.NotNil(t, s.next(&captionsBatch{captions: []captionText{
+			{language: "en", text: "Good morning"},
+			{language: "fr", text: "Bonjour"},
+		}}, 0, false))
+		b := s.next(&captionsBatch{captions: []captionText{
+			{language: "en", text: "Good morning everyone"},
+			{language: "fr", text: "Bonjour"},
+		}}, time.Second, false)
+		require.Equal(t, []captionText{{language: "en", text: "Good morning everyone"}}, b.captions)
+	})
+
+	t.Run("dedup disabled", func(t *testing.T) {
+		s := newCaptionsSmoother(config.LiveCaptionsConfig{DedupMaxDistance: -1})
+
+		require.NotNil(t, s.next(batch("Hello", false), 0, false))
+		require.NotNil(t, s.next(batch("Hello", false), time.Second, false))
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		s := newCaptionsSmoother(config.LiveCaptionsConfig{MinIntervalMs: 3000, DedupMaxDistance: 0.1})
+
+		require.NotNil(t, s.next(batch("One", true), 0, false))
+		require.Nil(t, s.next(batch("One two", true), time.Second, false))
+		require.Nil(t, s.next(batch("One two three", true), 2*time.Second, false))
+		require.Nil(t, s.next(nil, 2500*time.Millisecond, false))
+
+		// The latest held captions are sent once due.
+		b := s.next(nil, 3*time.Second, false)
+		require.NotNil(t, b)
+		require.Equal(t, "One two three", b.captions[0].text)
+		require.Equal(t, 2000, b.newAudioLenMs)
+		require.Nil(t, s.next(nil, 4*time.Second, false))
+
+		// Forced when the window ends.
+		require.Nil(t, s.next(batch("One two three four", true), 4*time.Second, false))
+		b = s.next(batch("One two three four", false), 4*time.Second, true)
+		require.NotNil(t, b)
+		require.False(t, b.partial)
+		require.Equal(t, 2000, b.newAudioLenMs)
+	})
+}
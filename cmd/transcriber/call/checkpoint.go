@@ -0,0 +1,196 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/voiceprint"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// checkpoint holds the post-processing state persisted to the data volume so
+// that a job restarted after a crash (e.g. OOM-killed container) can resume
+// from the last finished track instead of re-transcribing everything.
+type checkpoint struct {
+	APIs         []config.TranscribeAPI `json:"apis"`
+	Participants []participant          `json:"participants"`
+	Tracks       []trackCheckpoint      `json:"tracks"`
+	// Truncated is the reason capturing was stopped early, if any.
+	Truncated truncationReason `json:"truncated,omitempty"`
+}
+
+type trackCheckpoint struct {
+	TrackID   string `json:"track_id"`
+	SessionID string `json:"session_id"`
+	// Filename is relative to the data directory so that the checkpoint
+	// survives the volume being mounted elsewhere.
+	Filename   string      `json:"filename"`
+	StartTS    int64       `json:"start_ts"`
+	User       *model.User `json:"user"`
+	ColorIndex int         `json:"color_index"`
+	AudioDurMs int64       `json:"audio_dur_ms"`
+
+	// The following are only set once the track has been transcribed.
+	Done           bool                            `json:"done"`
+	Transcriptions []transcribe.TrackTranscription `json:"transcriptions,omitempty"`
+	SpeechDurMs    int64                           `json:"speech_dur_ms,omitempty"`
+	Voiceprint     *voiceprint.Stats               `json:"voiceprint,omitempty"`
+	VADSegments    []vadSegment                    `json:"vad_segments,omitempty"`
+}
+
+func (tc trackCheckpoint) trackContext() trackContext {
+	return trackContext{
+		trackID:    tc.TrackID,
+		sessionID:  tc.SessionID,
+		filename:   filepath.Join(getDataDir(), tc.Filename),
+		startTS:    tc.StartTS,
+		user:       tc.User,
+		colorIndex: tc.ColorIndex,
+		audioDur:   time.Duration(tc.AudioDurMs) * time.Millisecond,
+	}
+}
+
+func (t *Transcriber) checkpointPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_checkpoint.json", t.cfg.TranscriptionID))
+}
+
+// newCheckpoint creates the checkpoint for the given tracks. Results from the
+// checkpoint the job was resumed from, if any, are carried over as long as
+// they were produced by the same APIs.
+func (t *Transcriber) newCheckpoint(apis []config.TranscribeAPI, ctxs []trackContext) *checkpoint {
+	t.participantsMut.Lock()
+	cp := &checkpoint{
+		APIs:         apis,
+		Participants: slices.Clone(t.participants),
+		Tracks:       make([]trackCheckpoint, len(ctxs)),
+	}
+	t.participantsMut.Unlock()
+
+	if reason := t.truncated.Load(); reason != nil {
+		cp.Truncated = *reason
+	}
+
+	results := make(map[string]trackCheckpoint)
+	if t.resumedCheckpoint != nil && slices.Equal(t.resumedCheckpoint.APIs, apis) {
+		for _, tc := range t.resumedCheckpoint.Tracks {
+			if tc.Done && len(tc.Transcriptions) == len(apis) {
+				results[tc.TrackID] = tc
+			}
+		}
+	}
+
+	for i, ctx := range ctxs {
+		if tc, ok := results[ctx.trackID]; ok {
+			cp.Tracks[i] = tc
+			continue
+		}
+
+		cp.Tracks[i] = trackCheckpoint{
+			TrackID:    ctx.trackID,
+			SessionID:  ctx.sessionID,
+			Filename:   filepath.Base(ctx.filename),
+			StartTS:    ctx.startTS,
+			User:       ctx.user,
+			ColorIndex: ctx.colorIndex,
+			AudioDurMs: ctx.audioDur.Milliseconds(),
+		}
+	}
+
+	return cp
+}
+
+// saveCheckpoint atomically writes the checkpoint to disk so that a crash
+// mid-write can't leave a corrupted file behind.
+func (t *Transcriber) saveCheckpoint(cp *checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	path := t.checkpointPath()
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename checkpoint: %w", err)
+	}
+
+	return nil
+}
+
+// loadCheckpoint returns the persisted checkpoint or nil if there's none.
+func (t *Transcriber) loadCheckpoint() (*checkpoint, error) {
+	data, err := os.ReadFile(t.checkpointPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+	}
+
+	return &cp, nil
+}
+
+func (t *Transcriber) removeCheckpoint() {
+	if err := os.Remove(t.checkpointPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Error("failed to remove checkpoint", slog.String("err", err.Error()))
+	}
+}
+
+// Resume checks whether a previous run of this job crashed during
+// post-processing and, if so, starts post-processing the saved tracks
+// straight away, skipping the ones that were already transcribed. It returns
+// true if the job was resumed, in which case Start must not be called.
+func (t *Transcriber) Resume() (bool, error) {
+	cp, err := t.loadCheckpoint()
+	if err != nil {
+		return false, err
+	}
+	if cp == nil {
+		return false, nil
+	}
+
+	if len(cp.Tracks) > maxTracksContexes {
+		return false, fmt.Errorf("too many tracks in checkpoint: %d", len(cp.Tracks))
+	}
+
+	slog.Info("resuming post-processing from checkpoint", slog.Int("numTracks", len(cp.Tracks)))
+
+	t.resumedCheckpoint = cp
+	t.startPostProcessing(cp)
+
+	return true, nil
+}
+
+// startPostProcessing restores the capture state saved in cp and starts
+// post-processing its tracks.
+func (t *Transcriber) startPostProcessing(cp *checkpoint) {
+	t.participantsMut.Lock()
+	t.participants = cp.Participants
+	t.participantsMut.Unlock()
+
+	if cp.Truncated != "" {
+		t.truncated.Store(&cp.Truncated)
+	}
+
+	for _, tc := range cp.Tracks {
+		t.trackCtxs <- tc.trackContext()
+	}
+
+	go t.done()
+}
@@ -0,0 +1,188 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	apis := []config.TranscribeAPI{config.TranscribeAPIWhisperCPP}
+	ctxs := []trackContext{
+		{
+			trackID:    "trackA",
+			sessionID:  "sessionA",
+			filename:   filepath.Join(getDataDir(), "userA_trackA.ogg"),
+			startTS:    1000,
+			user:       &model.User{Id: "userA", Username: "usera"},
+			colorIndex: 1,
+			audioDur:   5 * time.Second,
+		},
+		{
+			trackID:   "trackB",
+			sessionID: "sessionB",
+			filename:  filepath.Join(getDataDir(), "userB_trackB.ogg"),
+			user:      &model.User{Id: "userB", Username: "userb"},
+			audioDur:  time.Second,
+		},
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		cp, err := tr.loadCheckpoint()
+		require.NoError(t, err)
+		require.Nil(t, cp)
+
+		resumed, err := tr.Resume()
+		require.NoError(t, err)
+		require.False(t, resumed)
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		cp := tr.newCheckpoint(apis, ctxs)
+		require.Len(t, cp.Tracks, 2)
+		require.Equal(t, "userA_trackA.ogg", cp.Tracks[0].Filename)
+		require.False(t, cp.Tracks[0].Done)
+
+		cp.Tracks[0].Done = true
+		cp.Tracks[0].SpeechDurMs = 4000
+		cp.Tracks[0].Transcriptions = []transcribe.TrackTranscription{
+			{
+				Speaker: "usera",
+				Segments: []transcribe.Segment{
+					{Text: "Hello", StartTS: 1000, EndTS: 2000},
+				},
+			},
+		}
+		require.NoError(t, tr.saveCheckpoint(cp))
+
+		loaded, err := tr.loadCheckpoint()
+		require.NoError(t, err)
+		require.Equal(t, cp, loaded)
+		require.Equal(t, ctxs[0], loaded.Tracks[0].trackContext())
+		require.Equal(t, ctxs[1], loaded.Tracks[1].trackContext())
+
+		tr.removeCheckpoint()
+		_, err = os.Stat(tr.checkpointPath())
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("results are carried over", func(t *testing.T) {
+		tr.resumedCheckpoint = tr.newCheckpoint(apis, ctxs)
+		defer func() {
+			tr.resumedCheckpoint = nil
+		}()
+		tr.resumedCheckpoint.Tracks[1].Done = true
+		tr.resumedCheckpoint.Tracks[1].Transcriptions = make([]transcribe.TrackTranscription, 1)
+
+		cp := tr.newCheckpoint(apis, ctxs)
+		require.False(t, cp.Tracks[0].Done)
+		require.True(t, cp.Tracks[1].Done)
+
+		// Results produced by different APIs can't be used.
+		cp = tr.newCheckpoint([]config.TranscribeAPI{config.TranscribeAPIAzure}, ctxs)
+		require.False(t, cp.Tracks[0].Done)
+		require.False(t, cp.Tracks[1].Done)
+	})
+}
+
+func TestResume(t *testing.T) {
+	var uploadedFilenames []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/jobs/67t5u6cmtfbb7jug739d43xa9e/status":
+			w.WriteHeader(200)
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename":
+			w.WriteHeader(200)
+			fmt.Fprintln(w, `{"filename": "Call_Test"}`)
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads":
+			var us model.UploadSession
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&us))
+			uploadedFilenames = append(uploadedFilenames, us.Filename)
+			us.Id = "jpanyqdipffrpmxxst3kzdjaah"
+			w.WriteHeader(200)
+			require.NoError(t, json.NewEncoder(w).Encode(&us))
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads/jpanyqdipffrpmxxst3kzdjaah":
+			w.WriteHeader(200)
+			require.NoError(t, json.NewEncoder(w).Encode(&model.FileInfo{}))
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/transcriptions":
+			w.WriteHeader(200)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	// All the tracks were transcribed before the crash so no track file
+	// (or model) is needed to complete the job.
+	require.NoError(t, tr.saveCheckpoint(&checkpoint{
+		APIs: []config.TranscribeAPI{config.TranscribeAPIWhisperCPP},
+		Tracks: []trackCheckpoint{
+			{
+				TrackID:     "trackA",
+				SessionID:   "sessionA",
+				Filename:    "userA_trackA.ogg",
+				User:        &model.User{Id: "userA", Username: "usera"},
+				AudioDurMs:  5000,
+				Done:        true,
+				SpeechDurMs: 4000,
+				Transcriptions: []transcribe.TrackTranscription{
+					{
+						Speaker: "usera",
+						Segments: []transcribe.Segment{
+							{Text: "Hello", StartTS: 1000, EndTS: 2000},
+						},
+					},
+				},
+			},
+		},
+	}))
+
+	resumed, err := tr.Resume()
+	require.NoError(t, err)
+	require.True(t, resumed)
+
+	select {
+	case <-tr.Done():
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for job to complete")
+	}
+	require.NoError(t, tr.Err())
+	require.Equal(t, StateDone, tr.State())
+
+	require.Equal(t, []string{"Call_Test.vtt", "Call_Test.txt"}, uploadedFilenames)
+
+	_, err = os.Stat(tr.checkpointPath())
+	require.True(t, os.IsNotExist(err))
+}
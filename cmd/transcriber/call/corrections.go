@@ -0,0 +1,203 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	artifactTypeCorrections artifactType = "corrections"
+)
+
+// appliedCorrection is a user correction along with the text it replaced.
+type appliedCorrection struct {
+	config.Correction
+	Original  string `json:"original"`
+	AppliedAt int64  `json:"applied_at"`
+}
+
+// correctionsRecord accumulates the corrections applied to the job's
+// transcription so that they can be used to assess (or tune) its quality.
+type correctionsRecord struct {
+	Corrections []appliedCorrection `json:"corrections"`
+}
+
+// transcriptionRecordPath is where the last published transcription is kept
+// so that corrections can be applied to it once the job has completed.
+func (t *Transcriber) transcriptionRecordPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_transcription.json", t.cfg.TranscriptionID))
+}
+
+func (t *Transcriber) correctionsRecordPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_corrections.json", t.cfg.TranscriptionID))
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
+
+func (t *Transcriber) saveTranscriptionRecord(tr transcribe.Transcription) error {
+	return writeJSONFile(t.transcriptionRecordPath(), tr)
+}
+
+// loadTranscriptionRecord returns the last transcription published for the
+// job or nil if it never published.
+func (t *Transcriber) loadTranscriptionRecord() (transcribe.Transcription, error) {
+	var tr transcribe.Transcription
+	if err := readJSONFile(t.transcriptionRecordPath(), &tr); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// applyCorrections returns a copy of the transcription with the given
+// corrections applied. Every correction must match a segment.
+func applyCorrections(tr transcribe.Transcription, corrections []config.Correction) (transcribe.Transcription, []appliedCorrection, error) {
+	corrected := make(transcribe.Transcription, len(tr))
+	for i, trackTr := range tr {
+		corrected[i] = trackTr
+		corrected[i].Segments = append([]transcribe.Segment(nil), trackTr.Segments...)
+	}
+
+	type segmentKey struct {
+		track, segment int
+	}
+	removed := make(map[segmentKey]bool)
+
+	applied := make([]appliedCorrection, 0, len(corrections))
+	for i, c := range corrections {
+		found := false
+		for j := range corrected {
+			if corrected[j].Speaker != c.Speaker {
+				continue
+			}
+			for k := range corrected[j].Segments {
+				seg := &corrected[j].Segments[k]
+				if seg.StartTS != c.StartMs {
+					continue
+				}
+				applied = append(applied, appliedCorrection{
+					Correction: c,
+					Original:   seg.Text,
+				})
+				seg.Text = c.Text
+				removed[segmentKey{j, k}] = c.Text == ""
+				found = true
+				break
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("correction %d: no segment of %q starting at %dms", i, c.Speaker, c.StartMs)
+		}
+	}
+
+	for j := range corrected {
+		segments := corrected[j].Segments[:0]
+		for k, seg := range corrected[j].Segments {
+			if !removed[segmentKey{j, k}] {
+				segments = append(segments, seg)
+			}
+		}
+		corrected[j].Segments = segments
+	}
+
+	return corrected, applied, nil
+}
+
+// ApplyCorrections applies the user corrections set through Corrections to
+// the transcription previously published by the job, without joining the
+// call. Corrections are saved in the data directory and pushed as an
+// artifact, if configured. With CorrectionsRegenerate the corrected
+// transcription is also published, replacing the previous files. Like
+// ReTranscribeRange, it runs synchronously.
+func (t *Transcriber) ApplyCorrections() error {
+	corrections, err := config.ParseCorrections(t.cfg.Publish.Corrections)
+	if err != nil {
+		return fmt.Errorf("failed to parse corrections: %w", err)
+	}
+
+	tr, err := t.loadTranscriptionRecord()
+	if err != nil {
+		return err
+	}
+	if tr == nil {
+		return fmt.Errorf("no published transcription found in %s", getDataDir())
+	}
+
+	corrected, applied, err := applyCorrections(tr, corrections)
+	if err != nil {
+		return fmt.Errorf("failed to apply corrections: %w", err)
+	}
+
+	slog.Info("applying corrections",
+		slog.Int("numCorrections", len(applied)),
+		slog.Bool("regenerate", t.cfg.Publish.CorrectionsRegenerate))
+
+	var rec correctionsRecord
+	if err := readJSONFile(t.correctionsRecordPath(), &rec); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	now := time.Now().UnixMilli()
+	for i := range applied {
+		applied[i].AppliedAt = now
+	}
+	rec.Corrections = append(rec.Corrections, applied...)
+	if err := writeJSONFile(t.correctionsRecordPath(), &rec); err != nil {
+		return err
+	}
+
+	if t.cfg.Publish.ArtifactsURL != "" {
+		data, err := os.ReadFile(t.correctionsRecordPath())
+		if err != nil {
+			return fmt.Errorf("failed to read corrections: %w", err)
+		}
+		// Best effort, the corrections are kept in the data directory anyway.
+		if err := newArtifactsClient(t.cfg.Publish.ArtifactsURL).push(artifact{
+			Type:        artifactTypeCorrections,
+			Name:        filepath.Base(t.correctionsRecordPath()),
+			ContentType: "application/json",
+			Data:        data,
+		}); err != nil {
+			slog.Error("failed to push corrections artifact", slog.String("err", err.Error()))
+		}
+	}
+
+	if t.cfg.Publish.CorrectionsRegenerate {
+		if err := t.publishTranscription(corrected); err != nil {
+			return fmt.Errorf("failed to publish corrected transcription: %w", err)
+		}
+	}
+
+	if err := t.ReportJobDone(nil); err != nil {
+		slog.Error("failed to report job done", slog.String("err", err.Error()))
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package call
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyCorrections(t *testing.T) {
+	tr := transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello", StartTS: 0, EndTS: 1000},
+				{Text: "Thanks for joining", StartTS: 1000, EndTS: 2000},
+			},
+		},
+		{
+			Speaker: "Bob",
+			Segments: []transcribe.Segment{
+				{Text: "Hi", StartTS: 500, EndTS: 900},
+				{Text: "you", StartTS: 3000, EndTS: 3500},
+			},
+		},
+	}
+
+	t.Run("applied", func(t *testing.T) {
+		corrected, applied, err := applyCorrections(tr, []config.Correction{
+			{Speaker: "Alice", StartMs: 1000, Text: "Thanks for joining, Bob"},
+			{Speaker: "Bob", StartMs: 3000, Text: ""},
+		})
+		require.NoError(t, err)
+		require.Equal(t, transcribe.Transcription{
+			{
+				Speaker: "Alice",
+				Segments: []transcribe.Segment{
+					{Text: "Hello", StartTS: 0, EndTS: 1000},
+					{Text: "Thanks for joining, Bob", StartTS: 1000, EndTS: 2000},
+				},
+			},
+			{
+				Speaker: "Bob",
+				Segments: []transcribe.Segment{
+					{Text: "Hi", StartTS: 500, EndTS: 900},
+				},
+			},
+		}, corrected)
+		require.Equal(t, []appliedCorrection{
+			{Correction: config.Correction{Speaker: "Alice", StartMs: 1000, Text: "Thanks for joining, Bob"}, Original: "Thanks for joining"},
+			{Correction: config.Correction{Speaker: "Bob", StartMs: 3000, Text: ""}, Original: "you"},
+		}, applied)
+
+		// The original transcription is left untouched.
+		require.Equal(t, "Thanks for joining", tr[0].Segments[1].Text)
+		require.Len(t, tr[1].Segments, 2)
+	})
+
+	t.Run("no matching segment", func(t *testing.T) {
+		_, _, err := applyCorrections(tr, []config.Correction{
+			{Speaker: "Bob", StartMs: 1000, Text: "Hello"},
+		})
+		require.EqualError(t, err, `correction 0: no segment of "Bob" starting at 1000ms`)
+	})
+}
+
+func TestApplyCorrectionsJob(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename" {
+			fmt.Fprintln(w, `{"filename": "Call_Test"}`)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
+		Publish: config.PublishConfig{
+			DryRun:      true,
+			Corrections: `[{"speaker":"Alice","start_ms":0,"text":"Hello everyone"}]`,
+		},
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+
+	t.Run("nothing published", func(t *testing.T) {
+		err := tr.ApplyCorrections()
+		require.EqualError(t, err, "no published transcription found in "+getDataDir())
+	})
+
+	require.NoError(t, tr.saveTranscriptionRecord(transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello every one", StartTS: 0, EndTS: 1000},
+			},
+		},
+	}))
+
+	t.Run("saved only", func(t *testing.T) {
+		require.NoError(t, tr.ApplyCorrections())
+
+		var rec correctionsRecord
+		require.NoError(t, readJSONFile(tr.correctionsRecordPath(), &rec))
+		require.Len(t, rec.Corrections, 1)
+		require.Equal(t, "Hello every one", rec.Corrections[0].Original)
+		require.NotZero(t, rec.Corrections[0].AppliedAt)
+
+		_, err := os.Stat(filepath.Join(getDataDir(), "Call_Test.vtt"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("regenerated", func(t *testing.T) {
+		tr.cfg.Publish.CorrectionsRegenerate = true
+		require.NoError(t, tr.ApplyCorrections())
+
+		// Corrections accumulate across runs.
+		var rec correctionsRecord
+		require.NoError(t, readJSONFile(tr.correctionsRecordPath(), &rec))
+		require.Len(t, rec.Corrections, 2)
+
+		data, err := os.ReadFile(filepath.Join(getDataDir(), "Call_Test.txt"))
+		require.NoError(t, err)
+		require.Contains(t, string(data), "Hello everyone")
+
+		saved, err := tr.loadTranscriptionRecord()
+		require.NoError(t, err)
+		require.Equal(t, "Hello everyone", saved[0].Segments[0].Text)
+	})
+}
@@ -0,0 +1,157 @@
+package call
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// captionsWindowStats is a snapshot of the live captions processing state for
+// a track, useful to diagnose window pressure.
+type captionsWindowStats struct {
+	TrackID   string `json:"track_id"`
+	SessionID string `json:"session_id"`
+	// WindowMs is the amount of audio currently buffered in the window.
+	WindowMs int `json:"window_ms"`
+	// PendingPkts is the number of audio packets waiting to be decoded.
+	PendingPkts int `json:"pending_pkts"`
+	// DroppedWindows counts how many times the window was dropped due to pressure.
+	DroppedWindows int `json:"dropped_windows"`
+	// DroppedTicks counts how many ticks passed while waiting for the transcriber.
+	DroppedTicks int `json:"dropped_ticks"`
+	// UpdatedAgoMs is the time elapsed since the stats were last updated.
+	UpdatedAgoMs int64 `json:"updated_ago_ms"`
+
+	updatedAt time.Duration
+}
+
+type runtimeStats struct {
+	NumGoroutines int    `json:"num_goroutines"`
+	NumCPU        int    `json:"num_cpu"`
+	HeapAlloc     uint64 `json:"heap_alloc"`
+	HeapInuse     uint64 `json:"heap_inuse"`
+	Sys           uint64 `json:"sys"`
+	NumGC         uint32 `json:"num_gc"`
+	State         State  `json:"state"`
+	TracksQueued  int    `json:"tracks_queued"`
+	CaptionsQueue int    `json:"captions_queue"`
+	// CaptionsQueueSize is the capacity of the captions queue. Requests are
+	// dropped once it's full, as counted by CaptionsQueueFull.
+	CaptionsQueueSize      int    `json:"captions_queue_size"`
+	CaptionsQueueFull      uint64 `json:"captions_queue_full"`
+	CaptionsQueueWaitAvgMs int64  `json:"captions_queue_wait_avg_ms"`
+	CaptionsQueueWaitMaxMs int64  `json:"captions_queue_wait_max_ms"`
+	DuplicatePackets       uint64 `json:"duplicate_packets"`
+	LowDiskSpace           bool   `json:"low_disk_space"`
+	CaptionsAcked          uint64 `json:"captions_acked"`
+	CaptionsUnacked        uint64 `json:"captions_unacked"`
+	// CaptionsWindowsBytes is the memory taken by the live captions windows
+	// of all tracks, bounded by CaptionsWindowsBudget.
+	CaptionsWindowsBytes   int64  `json:"captions_windows_bytes"`
+	CaptionsWindowsBudget  int64  `json:"captions_windows_budget"`
+	CaptionsWindowsTrimmed uint64 `json:"captions_windows_trimmed"`
+	// Captions summarizes the live captions quality, if they are on.
+	Captions *captionsQualityStats `json:"captions,omitempty"`
+}
+
+func (t *Transcriber) updateCaptionsWindowStats(stats captionsWindowStats) {
+	t.captionsStatsMut.Lock()
+	defer t.captionsStatsMut.Unlock()
+	stats.updatedAt = t.monoNow()
+	t.captionsStats[stats.TrackID] = stats
+}
+
+func (t *Transcriber) removeCaptionsWindowStats(trackID string) {
+	t.captionsStatsMut.Lock()
+	defer t.captionsStatsMut.Unlock()
+	delete(t.captionsStats, trackID)
+}
+
+func (t *Transcriber) getCaptionsWindowStats() []captionsWindowStats {
+	t.captionsStatsMut.Lock()
+	defer t.captionsStatsMut.Unlock()
+
+	now := t.monoNow()
+	stats := make([]captionsWindowStats, 0, len(t.captionsStats))
+	for _, s := range t.captionsStats {
+		s.UpdatedAgoMs = (now - s.updatedAt).Milliseconds()
+		stats = append(stats, s)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].TrackID < stats[j].TrackID
+	})
+
+	return stats
+}
+
+func (t *Transcriber) getRuntimeStats() runtimeStats {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	queueFull, queueWaitAvg, queueWaitMax := t.captionsQueueStats.get()
+	windowsBytes, windowsTrimmed := t.captionsBudget.get()
+	return runtimeStats{
+		NumGoroutines:          runtime.NumGoroutine(),
+		NumCPU:                 runtime.NumCPU(),
+		HeapAlloc:              ms.HeapAlloc,
+		HeapInuse:              ms.HeapInuse,
+		Sys:                    ms.Sys,
+		NumGC:                  ms.NumGC,
+		State:                  t.State(),
+		TracksQueued:           len(t.trackCtxs),
+		CaptionsQueue:          len(t.captionsPoolQueueCh),
+		CaptionsQueueSize:      cap(t.captionsPoolQueueCh),
+		CaptionsQueueFull:      queueFull,
+		CaptionsQueueWaitAvgMs: queueWaitAvg.Milliseconds(),
+		CaptionsQueueWaitMaxMs: queueWaitMax.Milliseconds(),
+		DuplicatePackets:       t.duplicatePkts.Load(),
+		LowDiskSpace:           t.lowDiskSpace.Load(),
+		CaptionsAcked:          t.captionsAcked.Load(),
+		CaptionsUnacked:        t.captionsUnacked.Load(),
+		CaptionsWindowsBytes:   windowsBytes,
+		CaptionsWindowsBudget:  t.captionsBudget.limit,
+		CaptionsWindowsTrimmed: windowsTrimmed,
+		Captions:               t.getCaptionsQualityStats(),
+	}
+}
+
+func writeDebugResponse(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Error("failed to write debug response", slog.String("err", err.Error()))
+	}
+}
+
+// DebugHandler returns an HTTP handler exposing diagnostics:
+//
+//   - /debug/pprof/: the standard pprof endpoints (e.g. /debug/pprof/goroutine?debug=2
+//     for a full goroutine dump).
+//   - /debug/runtime: runtime and queue stats.
+//   - /debug/captions: a snapshot of the live captions window per track.
+//
+// It must only be served on a private address.
+func (t *Transcriber) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/debug/runtime", func(w http.ResponseWriter, _ *http.Request) {
+		writeDebugResponse(w, t.getRuntimeStats())
+	})
+
+	mux.HandleFunc("/debug/captions", func(w http.ResponseWriter, _ *http.Request) {
+		writeDebugResponse(w, t.getCaptionsWindowStats())
+	})
+
+	return mux
+}
@@ -0,0 +1,90 @@
+package call
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	var now time.Duration
+	tr.monoNow = func() time.Duration {
+		return now
+	}
+
+	handler := tr.DebugHandler()
+
+	t.Run("captions", func(t *testing.T) {
+		tr.updateCaptionsWindowStats(captionsWindowStats{
+			TrackID:        "trackB",
+			SessionID:      "sessionB",
+			WindowMs:       4000,
+			DroppedWindows: 1,
+		})
+		tr.updateCaptionsWindowStats(captionsWindowStats{
+			TrackID:     "trackA",
+			SessionID:   "sessionA",
+			WindowMs:    2000,
+			PendingPkts: 10,
+		})
+		now += time.Second
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/captions", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats []captionsWindowStats
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+		require.Equal(t, []captionsWindowStats{
+			{
+				TrackID:      "trackA",
+				SessionID:    "sessionA",
+				WindowMs:     2000,
+				PendingPkts:  10,
+				UpdatedAgoMs: 1000,
+			},
+			{
+				TrackID:        "trackB",
+				SessionID:      "sessionB",
+				WindowMs:       4000,
+				DroppedWindows: 1,
+				UpdatedAgoMs:   1000,
+			},
+		}, stats)
+
+		tr.removeCaptionsWindowStats("trackA")
+		tr.removeCaptionsWindowStats("trackB")
+		require.Empty(t, tr.getCaptionsWindowStats())
+	})
+
+	t.Run("runtime", func(t *testing.T) {
+		tr.captionsQueueStats.addServed(500 * time.Millisecond)
+		tr.captionsQueueStats.addFull()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var stats runtimeStats
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&stats))
+		require.NotZero(t, stats.NumGoroutines)
+		require.Equal(t, StateConnecting, stats.State)
+		require.Equal(t, 1, stats.CaptionsQueueSize)
+		require.Equal(t, uint64(1), stats.CaptionsQueueFull)
+		require.Equal(t, int64(500), stats.CaptionsQueueWaitAvgMs)
+		require.Equal(t, int64(500), stats.CaptionsQueueWaitMaxMs)
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), "goroutine profile")
+	})
+}
@@ -0,0 +1,92 @@
+package call
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/audio"
+)
+
+// trackDecodeChunkSamples bounds the samples decoded, and processed, at once
+// so that memory usage doesn't grow with the length of the track (two
+// minutes).
+var trackDecodeChunkSamples = 2 * 60 * trackOutAudioRate
+
+// audioChunk is a bounded portion of decoded track audio.
+type audioChunk struct {
+	trackTimedSamples
+	// cont is set when the chunk directly follows the previous one, which was
+	// split for being too long, as opposed to following a gap in the audio.
+	cont bool
+}
+
+// trackAudioDecoder decodes a track incrementally, in chunks of at most
+// trackDecodeChunkSamples.
+type trackAudioDecoder struct {
+	*audio.Decoder
+}
+
+func newTrackAudioDecoder(src audio.Source) *trackAudioDecoder {
+	return &trackAudioDecoder{audio.NewDecoder(src, trackDecodeChunkSamples)}
+}
+
+// Next returns the next chunk of audio, or io.EOF once the track is fully
+// decoded.
+func (d *trackAudioDecoder) Next() (audioChunk, error) {
+	chunk, err := d.Decoder.Next()
+	if err != nil {
+		return audioChunk{}, err
+	}
+
+	return audioChunk{
+		trackTimedSamples: trackTimedSamples{
+			pcm:     chunk.PCM,
+			startTS: chunk.StartTS,
+		},
+		cont: chunk.Cont,
+	}, nil
+}
+
+// newAudioDecoder opens the track file for decoding. Tracks are OGG files
+// but WAV files, and other formats through ffmpeg, are also accepted to
+// support transcribing pre-recorded audio.
+func (ctx trackContext) newAudioDecoder() (*trackAudioDecoder, error) {
+	if strings.EqualFold(filepath.Ext(ctx.filename), ".wav") {
+		// WAV files need resampling as a whole so they are decoded at once.
+		pcm, err := decodeWAV(ctx.filename)
+		if errors.Is(err, errUnsupportedWAVEncoding) {
+			slog.Debug("falling back to ffmpeg for WAV file",
+				slog.String("err", err.Error()),
+				slog.String("trackID", ctx.trackID))
+			src, err := newFFmpegSource(ctx.filename)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode WAV file: %w", err)
+			}
+			return newTrackAudioDecoder(src), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode WAV file: %w", err)
+		}
+		return newTrackAudioDecoder(audio.NewPCMSource(pcm)), nil
+	}
+
+	if isFFmpegFormat(ctx.filename) {
+		src, err := newFFmpegSource(ctx.filename)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s file: %w", filepath.Ext(ctx.filename), err)
+		}
+		return newTrackAudioDecoder(src), nil
+	}
+
+	src, err := audio.NewOGGSource(ctx.filename, slog.With(slog.String("trackID", ctx.trackID)))
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("decoding track", slog.String("trackID", ctx.trackID))
+
+	return newTrackAudioDecoder(src), nil
+}
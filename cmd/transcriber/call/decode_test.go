@@ -0,0 +1,201 @@
+package call
+
+import (
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/loudness"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/require"
+)
+
+// decodeTrackAudio decodes the whole track, joining chunks that directly
+// follow each other.
+func decodeTrackAudio(ctx trackContext) ([]trackTimedSamples, error) {
+	dec, err := ctx.newAudioDecoder()
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	var samples []trackTimedSamples
+	for {
+		chunk, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			return samples, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if chunk.cont && len(samples) > 0 {
+			samples[len(samples)-1].pcm = append(samples[len(samples)-1].pcm, chunk.pcm...)
+			continue
+		}
+		samples = append(samples, chunk.trackTimedSamples)
+	}
+}
+
+func genTone(amp float32, samples int) []float32 {
+	pcm := make([]float32, samples)
+	for i := range pcm {
+		pcm[i] = amp * float32(math.Sin(2*math.Pi*440*float64(i)/trackOutAudioRate))
+	}
+	return pcm
+}
+
+type speechTranscriberMock struct {
+	lens  []int
+	peaks []float64
+}
+
+func (m *speechTranscriberMock) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	m.lens = append(m.lens, len(samples))
+	var peak float64
+	for _, s := range samples {
+		peak = max(peak, math.Abs(float64(s)))
+	}
+	m.peaks = append(m.peaks, peak)
+	return []transcribe.Segment{{Text: "speech", EndTS: int64(len(samples) / trackOutAudioSamplesPerMs)}}, "en", nil
+}
+
+func (m *speechTranscriberMock) Destroy() error {
+	return nil
+}
+
+func TestTranscribeTrackChunked(t *testing.T) {
+	defer func(samples int) {
+		trackDecodeChunkSamples = samples
+	}(trackDecodeChunkSamples)
+
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Engine.VAD.Engine = config.VADEngineWebRTC
+	tr.cfg.Engine.DisableLoudnessNormalization = true
+
+	var mock speechTranscriberMock
+	var created int
+	tr.trPool = newTranscriberPool(0, func(_ transcriberKey) (transcribe.Transcriber, error) {
+		created++
+		return &mock, nil
+	})
+
+	// Five seconds of continuous tone after a second of silence.
+	pcm := append(make([]float32, trackOutAudioRate), genTone(0.5, 5*trackOutAudioRate)...)
+	samples := make([]int16, len(pcm))
+	for i, s := range pcm {
+		samples[i] = int16(s * math.MaxInt16)
+	}
+	path := filepath.Join(t.TempDir(), "track.wav")
+	writeTestWAV(t, path, trackOutAudioRate, 1, samples)
+
+	tctx := trackContext{
+		trackID:  "trackID",
+		filename: path,
+		startTS:  1000,
+		user:     &model.User{Username: "testuser"},
+	}
+
+	t.Run("speech spanning chunks", func(t *testing.T) {
+		mock.lens = nil
+		trackDecodeChunkSamples = 4 * trackOutAudioRate
+
+		trackTr, dur, err := tr.transcribeTrack(tctx)
+		require.NoError(t, err)
+
+		// Speech carried over to the next chunk is transcribed at once.
+		require.Len(t, mock.lens, 1)
+		require.Greater(t, mock.lens[0], trackDecodeChunkSamples)
+		require.Equal(t, time.Duration(mock.lens[0]/trackOutAudioSamplesPerMs)*time.Millisecond, dur)
+
+		require.Len(t, trackTr.Segments, 1)
+		require.InDelta(t, 2000, trackTr.Segments[0].StartTS, 100)
+		require.InDelta(t, 7000, trackTr.Segments[0].EndTS, 200)
+	})
+
+	t.Run("long speech", func(t *testing.T) {
+		mock.lens = nil
+		created = 0
+		trackDecodeChunkSamples = 2 * trackOutAudioRate
+
+		trackTr, _, err := tr.transcribeTrack(tctx)
+		require.NoError(t, err)
+
+		// Speech isn't carried over indefinitely so that memory stays bounded.
+		require.Greater(t, len(mock.lens), 1)
+		var total int
+		for _, n := range mock.lens {
+			require.LessOrEqual(t, n, 2*trackDecodeChunkSamples)
+			total += n
+		}
+		require.InDelta(t, 5*trackOutAudioRate, total, 0.1*trackOutAudioRate)
+		require.Len(t, trackTr.Segments, len(mock.lens))
+
+		// The transcriber is created once for the whole track.
+		require.Equal(t, 1, created)
+	})
+
+	t.Run("pre-roll", func(t *testing.T) {
+		trackDecodeChunkSamples = 8 * trackOutAudioRate
+		preRollCtx := tctx
+
+		// Audio buffered before the recording start has a negative offset.
+		// Speech running into the start is clipped to it.
+		preRollCtx.startTS = -2000
+		trackTr, _, err := tr.transcribeTrack(preRollCtx)
+		require.NoError(t, err)
+		require.Len(t, trackTr.Segments, 1)
+		require.Zero(t, trackTr.Segments[0].StartTS)
+		require.InDelta(t, 4000, trackTr.Segments[0].EndTS, 200)
+
+		// Speech ending before the start is left out.
+		preRollCtx.startTS = -7000
+		trackTr, _, err = tr.transcribeTrack(preRollCtx)
+		require.NoError(t, err)
+		require.Empty(t, trackTr.Segments)
+	})
+	t.Run("loudness normalization", func(t *testing.T) {
+		tr.cfg.Engine.DisableLoudnessNormalization = false
+		defer func() {
+			tr.cfg.Engine.DisableLoudnessNormalization = true
+		}()
+
+		// A quiet speaker.
+		pcm := append(make([]float32, trackOutAudioRate), genTone(0.02, 5*trackOutAudioRate)...)
+		samples := make([]int16, len(pcm))
+		for i, s := range pcm {
+			samples[i] = int16(s * math.MaxInt16)
+			pcm[i] = float32(samples[i]) / math.MaxInt16
+		}
+		quietCtx := tctx
+		quietCtx.filename = filepath.Join(t.TempDir(), "quiet.wav")
+		writeTestWAV(t, quietCtx.filename, trackOutAudioRate, 1, samples)
+
+		// With the track fitting in a chunk, it's normalized as a whole.
+		trackDecodeChunkSamples = 8 * trackOutAudioRate
+		mock.lens = nil
+		mock.peaks = nil
+		_, _, err := tr.transcribeTrack(quietCtx)
+		require.NoError(t, err)
+		require.Len(t, mock.peaks, 1)
+		gain := math.Pow(10, loudness.Normalize(pcm)/20)
+		require.Greater(t, gain, 5.0)
+		require.InDelta(t, 0.02*gain, mock.peaks[0], 0.01)
+
+		// Otherwise chunks are normalized as they are read.
+		trackDecodeChunkSamples = 2 * trackOutAudioRate
+		mock.lens = nil
+		mock.peaks = nil
+		_, _, err = tr.transcribeTrack(quietCtx)
+		require.NoError(t, err)
+		require.Greater(t, len(mock.peaks), 1)
+		for _, peak := range mock.peaks {
+			require.Greater(t, peak, 0.1)
+		}
+	})
+}
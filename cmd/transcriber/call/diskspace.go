@@ -0,0 +1,94 @@
+package call
+
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+)
+
+const (
+	diskSpaceCheckInterval = 5 * time.Second
+	// The free space reserved by default is kept well above what's needed to
+	// write the output files so that the job can still be published once
+	// we stop writing track data.
+	minFreeDiskSpaceMBDefault = 256
+
+	metricDiskSpaceLow public.MetricName = "disk_space_low"
+)
+
+func getMinFreeDiskSpace() uint64 {
+	if val := os.Getenv("MIN_FREE_DISK_SPACE_MB"); val != "" {
+		if mb, err := strconv.ParseUint(val, 10, 64); err == nil {
+			return mb * 1024 * 1024
+		}
+		slog.Error("invalid MIN_FREE_DISK_SPACE_MB value, using default", slog.String("value", val))
+	}
+	return minFreeDiskSpaceMBDefault * 1024 * 1024
+}
+
+// getFreeDiskSpace returns the space available to unprivileged users on the
+// filesystem holding dir.
+func getFreeDiskSpace(dir string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// checkDiskSpace updates the low disk space flag, which makes live tracks
+// stop writing audio data until enough space is available again.
+func (t *Transcriber) checkDiskSpace() {
+	free, err := t.freeDiskSpace(getDataDir())
+	if err != nil {
+		slog.Error("failed to get free disk space", slog.String("err", err.Error()))
+		return
+	}
+
+	minFree := getMinFreeDiskSpace()
+	low := free < minFree
+	if low == t.lowDiskSpace.Swap(low) {
+		return
+	}
+
+	if !low {
+		slog.Info("disk space available again, resuming writing track data",
+			slog.Uint64("free", free))
+		return
+	}
+
+	slog.Warn("low disk space, pausing writing track data",
+		slog.Uint64("free", free),
+		slog.Uint64("minFree", minFree))
+
+	if err := t.client.Load().SendWS(wsEvMetric, public.MetricMsg{
+		MetricName: metricDiskSpaceLow,
+	}, false); err != nil {
+		slog.Error("failed to send wsEvMetric metricDiskSpaceLow", slog.String("err", err.Error()))
+	}
+}
+
+// monitorDiskSpace periodically checks the free space in the data directory
+// for as long as tracks are being recorded.
+func (t *Transcriber) monitorDiskSpace() {
+	t.checkDiskSpace()
+
+	ticker := time.NewTicker(diskSpaceCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if state := t.State(); state != StateConnecting && state != StateRecording {
+				return
+			}
+			t.checkDiskSpace()
+		case <-t.doneCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMinFreeDiskSpace(t *testing.T) {
+	require.Equal(t, uint64(minFreeDiskSpaceMBDefault*1024*1024), getMinFreeDiskSpace())
+
+	t.Setenv("MIN_FREE_DISK_SPACE_MB", "10")
+	require.Equal(t, uint64(10*1024*1024), getMinFreeDiskSpace())
+
+	t.Setenv("MIN_FREE_DISK_SPACE_MB", "invalid")
+	require.Equal(t, uint64(minFreeDiskSpaceMBDefault*1024*1024), getMinFreeDiskSpace())
+}
+
+func TestGetFreeDiskSpace(t *testing.T) {
+	free, err := getFreeDiskSpace(t.TempDir())
+	require.NoError(t, err)
+	require.NotZero(t, free)
+
+	_, err = getFreeDiskSpace("/not/existing")
+	require.Error(t, err)
+}
+
+func TestCheckDiskSpace(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	t.Setenv("MIN_FREE_DISK_SPACE_MB", "10")
+
+	var free uint64
+	tr.freeDiskSpace = func(_ string) (uint64, error) {
+		return free, nil
+	}
+
+	// Space becoming available again resumes writing.
+	tr.lowDiskSpace.Store(true)
+	free = 11 * 1024 * 1024
+	tr.checkDiskSpace()
+	require.False(t, tr.lowDiskSpace.Load())
+
+	tr.checkDiskSpace()
+	require.False(t, tr.lowDiskSpace.Load())
+}
@@ -0,0 +1,58 @@
+package call
+
+import (
+	"fmt"
+	"path/filepath"
+
+	fasterwhisper "github.com/mattermost/calls-transcriber/cmd/transcriber/apis/faster-whisper"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const fasterWhisperSocketName = "faster-whisper.sock"
+
+// fasterWhisperSidecar returns the faster-whisper sidecar, spawning it on
+// first use. It's shared by all the track and live captions transcribers.
+func (t *Transcriber) fasterWhisperSidecar() (*fasterwhisper.Sidecar, error) {
+	t.fwSidecarMut.Lock()
+	defer t.fwSidecarMut.Unlock()
+
+	if t.fwSidecar != nil {
+		return t.fwSidecar, nil
+	}
+
+	// Already validated.
+	command, _ := config.StringListOption(t.cfg.Engine.TranscribeAPIOptions["FASTER_WHISPER_COMMAND"])
+	s, err := fasterwhisper.NewSidecar(fasterwhisper.SidecarConfig{
+		Command:    command,
+		SocketPath: filepath.Join(getDataDir(), fasterWhisperSocketName),
+		ModelSize:  string(t.cfg.Engine.ModelSize),
+		NumThreads: t.cfg.Engine.NumThreads,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create faster-whisper sidecar: %w", err)
+	}
+	t.fwSidecar = s
+
+	return s, nil
+}
+
+func (t *Transcriber) newFasterWhisperTranscriber(language string) (*fasterwhisper.Transcriber, error) {
+	s, err := t.fasterWhisperSidecar()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.NewTranscriber(language)
+}
+
+// stopFasterWhisperSidecar stops the sidecar, if running, once no more
+// transcribers need it.
+func (t *Transcriber) stopFasterWhisperSidecar() {
+	t.fwSidecarMut.Lock()
+	defer t.fwSidecarMut.Unlock()
+
+	if t.fwSidecar != nil {
+		t.fwSidecar.Stop()
+		t.fwSidecar = nil
+	}
+}
@@ -0,0 +1,133 @@
+package call
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/audio"
+)
+
+const ffmpegPathDefault = "ffmpeg"
+
+// ffmpegFormatExts are the formats decoded through ffmpeg, which include
+// the audio-only containers produced by calls-recorder as well as common
+// audio exports.
+var ffmpegFormatExts = map[string]bool{
+	".mka":  true,
+	".mkv":  true,
+	".webm": true,
+	".mp4":  true,
+	".m4a":  true,
+	".mp3":  true,
+	".aac":  true,
+	".flac": true,
+}
+
+// getFFmpegPath returns the ffmpeg binary to run. It's not shipped with the
+// transcriber image so it can be pointed to through FFMPEG_PATH.
+func getFFmpegPath() string {
+	if p := os.Getenv("FFMPEG_PATH"); p != "" {
+		return p
+	}
+	return ffmpegPathDefault
+}
+
+func isFFmpegFormat(path string) bool {
+	return ffmpegFormatExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// ffmpegSource decodes the first audio stream of a file through ffmpeg, as
+// mono PCM samples resampled to trackOutAudioRate.
+type ffmpegSource struct {
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	stdout *bufio.Reader
+	buf    []byte
+	done   bool
+}
+
+func newFFmpegSource(path string) (*ffmpegSource, error) {
+	cmd := exec.Command(getFFmpegPath(),
+		"-nostdin", "-hide_banner", "-loglevel", "error",
+		"-i", path,
+		"-map", "0:a:0",
+		"-ac", strconv.Itoa(trackAudioChannels),
+		"-ar", strconv.Itoa(trackOutAudioRate),
+		"-f", "f32le",
+		"pipe:1")
+
+	src := &ffmpegSource{
+		cmd: cmd,
+		// A second worth of samples is read at a time.
+		buf: make([]byte, 4*trackOutAudioRate),
+	}
+	cmd.Stderr = &src.stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ffmpeg output: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ffmpeg: %w", err)
+	}
+	src.stdout = bufio.NewReader(stdout)
+
+	return src, nil
+}
+
+// Decode reads the raw little-endian float32 samples output by ffmpeg. A
+// trailing partial sample is ignored.
+func (s *ffmpegSource) Decode(c *audio.Chunker) error {
+	n, readErr := io.ReadFull(s.stdout, s.buf)
+
+	pcm := make([]float32, n/4)
+	for i := range pcm {
+		pcm[i] = math.Float32frombits(binary.LittleEndian.Uint32(s.buf[i*4:]))
+	}
+	c.Push(pcm)
+
+	if readErr == nil {
+		return nil
+	}
+
+	s.done = true
+	if err := s.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(s.stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg failed: %w", err)
+	}
+
+	if !errors.Is(readErr, io.EOF) && !errors.Is(readErr, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("failed to read ffmpeg output: %w", readErr)
+	}
+
+	return io.EOF
+}
+
+// Close stops ffmpeg if it's still running, e.g. when decoding is
+// interrupted.
+func (s *ffmpegSource) Close() error {
+	if s.done {
+		return nil
+	}
+	s.done = true
+
+	if err := s.cmd.Process.Kill(); err != nil {
+		return fmt.Errorf("failed to stop ffmpeg: %w", err)
+	}
+	_ = s.cmd.Wait()
+
+	return nil
+}
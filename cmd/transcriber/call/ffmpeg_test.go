@@ -0,0 +1,86 @@
+package call
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setupFakeFFmpeg installs a script standing in for ffmpeg which records its
+// arguments and outputs the given samples.
+func setupFakeFFmpeg(t *testing.T, samples []float32, exitCode int) (argsPath string) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	var buf bytes.Buffer
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, samples))
+	pcmPath := filepath.Join(dir, "out.f32")
+	require.NoError(t, os.WriteFile(pcmPath, buf.Bytes(), 0600))
+
+	argsPath = filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"echo \"$@\" > " + argsPath + "\n" +
+		"cat " + pcmPath + "\n"
+	if exitCode != 0 {
+		script += "echo 'Invalid data found when processing input' >&2\n"
+	}
+	script += "exit " + strconv.Itoa(exitCode) + "\n"
+
+	ffmpegPath := filepath.Join(dir, "ffmpeg")
+	require.NoError(t, os.WriteFile(ffmpegPath, []byte(script), 0700))
+	t.Setenv("FFMPEG_PATH", ffmpegPath)
+
+	return argsPath
+}
+
+func TestDecodeWithFFmpeg(t *testing.T) {
+	samples := []float32{0, 0.25, -0.5, 1}
+
+	t.Run("format detection", func(t *testing.T) {
+		for _, path := range []string{"rec.mka", "rec.MP4", "/recs/call.m4a", "rec.webm", "export.mp3", "export.flac"} {
+			require.True(t, isFFmpegFormat(path), path)
+		}
+		for _, path := range []string{"track.ogg", "track.wav", "track"} {
+			require.False(t, isFFmpegFormat(path), path)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		argsPath := setupFakeFFmpeg(t, samples, 0)
+
+		ctx := trackContext{
+			trackID:  "trackID",
+			filename: "/recs/call.mka",
+		}
+		decoded, err := decodeTrackAudio(ctx)
+		require.NoError(t, err)
+		require.Len(t, decoded, 1)
+		require.Equal(t, samples, decoded[0].pcm)
+
+		args, err := os.ReadFile(argsPath)
+		require.NoError(t, err)
+		require.Equal(t, "-nostdin -hide_banner -loglevel error -i /recs/call.mka -map 0:a:0 -ac 1 -ar 16000 -f f32le pipe:1",
+			strings.TrimSpace(string(args)))
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		setupFakeFFmpeg(t, nil, 1)
+
+		_, err := decodeTrackAudio(trackContext{filename: "/recs/call.mp4"})
+		require.EqualError(t, err, "ffmpeg failed: exit status 1: Invalid data found when processing input")
+	})
+
+	t.Run("missing binary", func(t *testing.T) {
+		t.Setenv("FFMPEG_PATH", filepath.Join(t.TempDir(), "ffmpeg"))
+
+		_, err := decodeTrackAudio(trackContext{filename: "/recs/call.mp4"})
+		require.ErrorContains(t, err, "failed to run ffmpeg")
+	})
+}
@@ -0,0 +1,130 @@
+package call
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// filenameFormatPlaceholder is kept in the names files are written under,
+// and replaced by their format (extension) once written.
+const filenameFormatPlaceholder = "{format}"
+
+// filenamePlaceholderRE matches placeholders along with the separator
+// following them, if any.
+var filenamePlaceholderRE = regexp.MustCompile(`\{[a-z]+\}[-_.]?`)
+
+// filenameTemplateValues are what the placeholders of a filename template
+// expand to for a transcription output.
+type filenameTemplateValues struct {
+	filename string
+	channel  string
+	date     time.Time
+	lang     string
+	label    string
+	job      string
+}
+
+// renderFilenameTemplate returns the name files of an output are written
+// under. Placeholders with empty values are dropped along with the
+// separator following them.
+func renderFilenameTemplate(tmpl string, v filenameTemplateValues) string {
+	values := map[string]string{
+		"{filename}": v.filename,
+		"{channel}":  sanitizeFilename(v.channel),
+		"{date}":     v.date.UTC().Format(time.DateOnly),
+		"{lang}":     sanitizeFilename(v.lang),
+		"{label}":    sanitizeFilename(v.label),
+		"{job}":      sanitizeFilename(v.job),
+	}
+
+	name := filenamePlaceholderRE.ReplaceAllStringFunc(tmpl, func(match string) string {
+		placeholder := match[:strings.IndexByte(match, '}')+1]
+		value, ok := values[placeholder]
+		if !ok {
+			return match
+		} else if value == "" {
+			return ""
+		}
+		return value + match[len(placeholder):]
+	})
+
+	return strings.TrimRight(name, "-_.")
+}
+
+// uniqueFilename returns name, or name suffixed with a counter if already
+// taken, marking the result as taken.
+func uniqueFilename(name string, taken map[string]bool) string {
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// expandFormatPlaceholder renames the files at the given paths, replacing
+// the format placeholder in their name with their extension, and returns
+// the new paths.
+func expandFormatPlaceholder(paths []string) ([]string, error) {
+	renamed := make([]string, 0, len(paths))
+	for _, path := range paths {
+		base := filepath.Base(path)
+		if !strings.Contains(base, filenameFormatPlaceholder) {
+			renamed = append(renamed, path)
+			continue
+		}
+
+		format := strings.TrimPrefix(filepath.Ext(base), ".")
+		newPath := filepath.Join(filepath.Dir(path), strings.ReplaceAll(base, filenameFormatPlaceholder, format))
+		if err := os.Rename(path, newPath); err != nil {
+			return nil, fmt.Errorf("failed to rename file: %w", err)
+		}
+		renamed = append(renamed, newPath)
+	}
+
+	return renamed, nil
+}
+
+// outputFilename returns the name the files of out are written under, as
+// set through the filename template if any, unique among the outputs.
+func (t *Transcriber) outputFilename(fname string, out transcriptionOutput, taken map[string]bool) string {
+	if t.cfg.Output.FilenameTemplate == "" {
+		name := fname
+		if out.label != "" {
+			name += "-" + sanitizeFilename(out.label)
+		}
+		if out.language != "" {
+			name += "-" + sanitizeFilename(out.language)
+		}
+		return uniqueFilename(name, taken)
+	}
+
+	date := time.Now()
+	if startTime := t.startTime.Load(); startTime != nil {
+		date = *startTime
+	}
+
+	lang := out.language
+	if lang == "" {
+		lang = out.tr.Language()
+	}
+
+	name := renderFilenameTemplate(t.cfg.Output.FilenameTemplate, filenameTemplateValues{
+		filename: fname,
+		channel:  t.cfg.CallID,
+		date:     date,
+		lang:     lang,
+		label:    out.label,
+		job:      t.cfg.TranscriptionID,
+	})
+	// Only the format left, files would be named after their extension.
+	if strings.Trim(strings.ReplaceAll(name, filenameFormatPlaceholder, ""), "-_.") == "" {
+		name = fname
+	}
+
+	return uniqueFilename(name, taken)
+}
@@ -0,0 +1,109 @@
+package call
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderFilenameTemplate(t *testing.T) {
+	values := filenameTemplateValues{
+		filename: "Call_Town_Square_2024-01-02",
+		channel:  "8w8jorhr7j83uqr6y1st894hqe",
+		date:     time.Date(2024, 1, 2, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*3600)),
+		lang:     "en",
+		job:      "on5yfih5etn5m8rfdidamc1oxa",
+	}
+
+	tcs := []struct {
+		name     string
+		tmpl     string
+		expected string
+	}{
+		{"all set", "{channel}-{date}-{lang}-{format}", "8w8jorhr7j83uqr6y1st894hqe-2024-01-03-en-{format}"},
+		{"filename", "{filename}_{job}", "Call_Town_Square_2024-01-02_on5yfih5etn5m8rfdidamc1oxa"},
+		{"empty value", "{channel}-{label}-{lang}", "8w8jorhr7j83uqr6y1st894hqe-en"},
+		{"empty last value", "{channel}.{lang}.{label}", "8w8jorhr7j83uqr6y1st894hqe.en"},
+		{"literals", "transcript-{date}", "transcript-2024-01-03"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, renderFilenameTemplate(tc.tmpl, values))
+		})
+	}
+
+	t.Run("values are sanitized", func(t *testing.T) {
+		v := values
+		v.label = "openai/whisper"
+		v.filename = "{lang}"
+		require.Equal(t, "{lang}-openai_whisper", renderFilenameTemplate("{filename}-{label}", v))
+	})
+}
+
+func TestUniqueFilename(t *testing.T) {
+	taken := make(map[string]bool)
+	require.Equal(t, "name", uniqueFilename("name", taken))
+	require.Equal(t, "name-2", uniqueFilename("name", taken))
+	require.Equal(t, "name-3", uniqueFilename("name", taken))
+	require.Equal(t, "other", uniqueFilename("other", taken))
+}
+
+func TestExpandFormatPlaceholder(t *testing.T) {
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"call-{format}.vtt", "call-{format}.txt", "call-{format}-participants.json", "call.ass"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0600))
+		paths = append(paths, path)
+	}
+
+	renamed, err := expandFormatPlaceholder(paths)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "call-vtt.vtt"),
+		filepath.Join(dir, "call-txt.txt"),
+		filepath.Join(dir, "call-json-participants.json"),
+		filepath.Join(dir, "call.ass"),
+	}, renamed)
+
+	data, err := os.ReadFile(renamed[0])
+	require.NoError(t, err)
+	require.Equal(t, "call-{format}.vtt", string(data))
+}
+
+func TestOutputFilename(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	startTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	tr.startTime.Store(&startTime)
+
+	out := transcriptionOutput{tr: transcribe.Transcription{{Language: "fr"}}}
+
+	t.Run("default", func(t *testing.T) {
+		taken := make(map[string]bool)
+		require.Equal(t, "call", tr.outputFilename("call", out, taken))
+		require.Equal(t, "call-whisper.cpp-it", tr.outputFilename("call", transcriptionOutput{label: "whisper.cpp", language: "it"}, taken))
+	})
+
+	t.Run("template", func(t *testing.T) {
+		tr.cfg.Output.FilenameTemplate = "{channel}-{date}-{lang}-{format}"
+		defer func() { tr.cfg.Output.FilenameTemplate = "" }()
+
+		taken := make(map[string]bool)
+		require.Equal(t, "8w8jorhr7j83uqr6y1st894hqe-2024-01-02-fr-{format}", tr.outputFilename("call", out, taken))
+		// Outputs in the same language get told apart.
+		require.Equal(t, "8w8jorhr7j83uqr6y1st894hqe-2024-01-02-fr-{format}-2", tr.outputFilename("call", out, taken))
+	})
+
+	t.Run("template without values", func(t *testing.T) {
+		tr.cfg.Output.FilenameTemplate = "{label}-{format}"
+		defer func() { tr.cfg.Output.FilenameTemplate = "" }()
+
+		require.Equal(t, "call", tr.outputFilename("call", out, make(map[string]bool)))
+	})
+}
@@ -0,0 +1,113 @@
+package call
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// State describes the current phase of the transcribing job.
+type State string
+
+const (
+	// StateConnecting is set until the recording has started.
+	StateConnecting State = "connecting"
+	// StateRecording is set while live tracks are being saved.
+	StateRecording State = "recording"
+	// StatePostProcessing is set while the saved tracks are being transcribed
+	// and the results published.
+	StatePostProcessing State = "post-processing"
+	// StateDone is set once the job has completed.
+	StateDone State = "done"
+	// StateFailed is set if the job failed.
+	StateFailed State = "failed"
+)
+
+const (
+	// If no progress is made during post-processing for longer than this the
+	// job is considered stuck. Transcribing a single speech chunk should never
+	// take this long.
+	stuckThreshold = 10 * time.Minute
+)
+
+type healthResponse struct {
+	State          State `json:"state"`
+	Stuck          bool  `json:"stuck"`
+	LastProgressMs int64 `json:"last_progress_ms"`
+}
+
+func (t *Transcriber) setState(state State) {
+	slog.Debug("transcriber state change", slog.String("state", string(state)))
+	t.state.Store(state)
+	t.reportProgress()
+}
+
+// State returns the current state of the transcriber.
+func (t *Transcriber) State() State {
+	state, _ := t.state.Load().(State)
+	return state
+}
+
+// reportProgress records that the job is moving forward. It's used to
+// detect a stuck post-processing phase.
+func (t *Transcriber) reportProgress() {
+	t.lastProgressAt.Store(int64(t.monoNow()))
+}
+
+// sinceLastProgress returns the time elapsed since progress was last reported.
+func (t *Transcriber) sinceLastProgress() time.Duration {
+	return t.monoNow() - time.Duration(t.lastProgressAt.Load())
+}
+
+func (t *Transcriber) getHealth() healthResponse {
+	state := t.State()
+	sinceProgress := t.sinceLastProgress()
+	return healthResponse{
+		State:          state,
+		Stuck:          state == StatePostProcessing && sinceProgress > stuckThreshold,
+		LastProgressMs: sinceProgress.Milliseconds(),
+	}
+}
+
+func writeHealthResponse(w http.ResponseWriter, code int, res healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		slog.Error("failed to write health response", slog.String("err", err.Error()))
+	}
+}
+
+// HealthHandler returns an HTTP handler serving the following endpoints:
+//
+//   - /healthz: liveness. Fails if the job has failed or is stuck.
+//   - /readyz: readiness. Succeeds only once the recording has started and
+//     for as long as the job is making progress.
+//
+// Both endpoints respond with the current state so that callers can tell
+// the different phases apart.
+func (t *Transcriber) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		res := t.getHealth()
+		if res.State == StateFailed || res.Stuck {
+			writeHealthResponse(w, http.StatusServiceUnavailable, res)
+			return
+		}
+		writeHealthResponse(w, http.StatusOK, res)
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		res := t.getHealth()
+		switch {
+		case res.Stuck:
+		case res.State == StateRecording, res.State == StatePostProcessing, res.State == StateDone:
+			writeHealthResponse(w, http.StatusOK, res)
+			return
+		}
+		writeHealthResponse(w, http.StatusServiceUnavailable, res)
+	})
+
+	return mux
+}
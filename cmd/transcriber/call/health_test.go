@@ -0,0 +1,75 @@
+package call
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	var now time.Duration
+	tr.monoNow = func() time.Duration {
+		return now
+	}
+
+	handler := tr.HealthHandler()
+
+	check := func(t *testing.T, path string, expectedCode int, expected healthResponse) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, expectedCode, w.Code)
+
+		var res healthResponse
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&res))
+		require.Equal(t, expected, res)
+	}
+
+	t.Run("connecting", func(t *testing.T) {
+		tr.setState(StateConnecting)
+		check(t, "/healthz", http.StatusOK, healthResponse{State: StateConnecting})
+		check(t, "/readyz", http.StatusServiceUnavailable, healthResponse{State: StateConnecting})
+	})
+
+	t.Run("recording", func(t *testing.T) {
+		tr.setState(StateRecording)
+		// No audio for a long time is fine while recording (e.g. everyone muted).
+		now += 2 * stuckThreshold
+		check(t, "/healthz", http.StatusOK, healthResponse{State: StateRecording, LastProgressMs: (2 * stuckThreshold).Milliseconds()})
+		check(t, "/readyz", http.StatusOK, healthResponse{State: StateRecording, LastProgressMs: (2 * stuckThreshold).Milliseconds()})
+	})
+
+	t.Run("post-processing", func(t *testing.T) {
+		tr.setState(StatePostProcessing)
+		now += time.Minute
+		check(t, "/healthz", http.StatusOK, healthResponse{State: StatePostProcessing, LastProgressMs: time.Minute.Milliseconds()})
+		check(t, "/readyz", http.StatusOK, healthResponse{State: StatePostProcessing, LastProgressMs: time.Minute.Milliseconds()})
+	})
+
+	t.Run("stuck", func(t *testing.T) {
+		now += stuckThreshold
+		expected := healthResponse{
+			State:          StatePostProcessing,
+			Stuck:          true,
+			LastProgressMs: (stuckThreshold + time.Minute).Milliseconds(),
+		}
+		check(t, "/healthz", http.StatusServiceUnavailable, expected)
+		check(t, "/readyz", http.StatusServiceUnavailable, expected)
+
+		tr.reportProgress()
+		check(t, "/healthz", http.StatusOK, healthResponse{State: StatePostProcessing})
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		tr.setState(StateFailed)
+		check(t, "/healthz", http.StatusServiceUnavailable, healthResponse{State: StateFailed})
+		check(t, "/readyz", http.StatusServiceUnavailable, healthResponse{State: StateFailed})
+	})
+}
@@ -0,0 +1,132 @@
+package call
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// jitterBufferMaxPackets bounds the number of packets held by a jitterBuffer
+// regardless of its window (e.g. on a burst of packets following a loss).
+const jitterBufferMaxPackets = 64
+
+type jitterPacket struct {
+	pkt *rtp.Packet
+	// arrival is the monotonic clock reading at which the packet was received.
+	arrival time.Duration
+}
+
+// jitterBuffer reorders the packets of a track by sequence number. Packets
+// following a hole in the sequence are held for up to window (measured on
+// arrival times) giving late packets a chance to fill it. Packets arriving
+// after their position has already been released are passed through as is,
+// leaving it to the caller to decide what to do with them.
+//
+// It's not safe for concurrent use.
+type jitterBuffer struct {
+	window  time.Duration
+	pkts    []jitterPacket
+	out     []jitterPacket
+	nextSeq uint16
+	started bool
+	// reordered counts the packets that were released in a different
+	// order than received.
+	reordered int
+}
+
+func newJitterBuffer(window time.Duration) *jitterBuffer {
+	return &jitterBuffer{
+		window: window,
+	}
+}
+
+// seqDiff returns the distance from b to a accounting for wrap around.
+func seqDiff(a, b uint16) int {
+	return int(int16(a - b))
+}
+
+// push adds a packet to the buffer returning the packets, if any, that are
+// ready to be processed in order. The returned slice is only valid until the
+// next call.
+func (jb *jitterBuffer) push(pkt *rtp.Packet, arrival time.Duration) []jitterPacket {
+	jb.out = jb.out[:0]
+	jp := jitterPacket{pkt: pkt, arrival: arrival}
+
+	if jb.window <= 0 {
+		return append(jb.out, jp)
+	}
+
+	if !jb.started {
+		jb.started = true
+		jb.nextSeq = pkt.SequenceNumber
+	}
+
+	// Too late to be reordered (or a duplicate of a released packet).
+	if seqDiff(pkt.SequenceNumber, jb.nextSeq) < 0 {
+		return append(jb.out, jp)
+	}
+
+	// The common case: in order with nothing pending.
+	if len(jb.pkts) == 0 && pkt.SequenceNumber == jb.nextSeq {
+		jb.nextSeq++
+		return append(jb.out, jp)
+	}
+
+	// Insert keeping the buffer sorted by sequence number. Duplicates are
+	// kept after the original.
+	idx := sort.Search(len(jb.pkts), func(i int) bool {
+		return seqDiff(jb.pkts[i].pkt.SequenceNumber, pkt.SequenceNumber) > 0
+	})
+	if idx < len(jb.pkts) {
+		jb.reordered++
+	}
+	jb.pkts = append(jb.pkts, jitterPacket{})
+	copy(jb.pkts[idx+1:], jb.pkts[idx:])
+	jb.pkts[idx] = jp
+
+	jb.release(arrival)
+
+	return jb.out
+}
+
+// release moves to out the packets that are either next in sequence or have
+// waited longer than the window.
+func (jb *jitterBuffer) release(now time.Duration) {
+	var n int
+	for n < len(jb.pkts) {
+		head := jb.pkts[n]
+		diff := seqDiff(head.pkt.SequenceNumber, jb.nextSeq)
+		expired := now-jb.oldestArrival(n) >= jb.window || len(jb.pkts)-n > jitterBufferMaxPackets
+		if diff > 0 && !expired {
+			break
+		}
+		if diff >= 0 {
+			jb.nextSeq = head.pkt.SequenceNumber + 1
+		}
+		jb.out = append(jb.out, head)
+		n++
+	}
+
+	jb.pkts = jb.pkts[:copy(jb.pkts, jb.pkts[n:])]
+}
+
+// oldestArrival returns the earliest arrival time of the packets held from
+// the given index on.
+func (jb *jitterBuffer) oldestArrival(from int) time.Duration {
+	oldest := jb.pkts[from].arrival
+	for _, jp := range jb.pkts[from+1:] {
+		oldest = min(oldest, jp.arrival)
+	}
+	return oldest
+}
+
+// flush returns all the packets still held in the buffer.
+func (jb *jitterBuffer) flush() []jitterPacket {
+	jb.out = append(jb.out[:0], jb.pkts...)
+	jb.pkts = jb.pkts[:0]
+	if len(jb.out) > 0 {
+		jb.nextSeq = jb.out[len(jb.out)-1].pkt.SequenceNumber + 1
+	}
+	return jb.out
+}
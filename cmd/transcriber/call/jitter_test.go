@@ -0,0 +1,142 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJitterBuffer(t *testing.T) {
+	type input struct {
+		seq     uint16
+		arrival time.Duration
+	}
+
+	seqs := func(pkts []jitterPacket) []uint16 {
+		var out []uint16
+		for _, jp := range pkts {
+			out = append(out, jp.pkt.SequenceNumber)
+		}
+		return out
+	}
+
+	run := func(jb *jitterBuffer, inputs []input) []uint16 {
+		var out []uint16
+		for _, in := range inputs {
+			out = append(out, seqs(jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: in.seq}}, in.arrival))...)
+		}
+		return append(out, seqs(jb.flush())...)
+	}
+
+	tcs := []struct {
+		name      string
+		window    time.Duration
+		inputs    []input
+		expected  []uint16
+		reordered int
+	}{
+		{
+			name:   "in order",
+			window: 100 * time.Millisecond,
+			inputs: []input{
+				{1, 20 * time.Millisecond},
+				{2, 40 * time.Millisecond},
+				{3, 60 * time.Millisecond},
+			},
+			expected: []uint16{1, 2, 3},
+		},
+		{
+			name:   "reordered within window",
+			window: 100 * time.Millisecond,
+			inputs: []input{
+				{1, 20 * time.Millisecond},
+				{3, 40 * time.Millisecond},
+				{4, 60 * time.Millisecond},
+				{2, 80 * time.Millisecond},
+				{5, 100 * time.Millisecond},
+			},
+			expected:  []uint16{1, 2, 3, 4, 5},
+			reordered: 1,
+		},
+		{
+			name:   "loss released after window",
+			window: 60 * time.Millisecond,
+			inputs: []input{
+				{1, 20 * time.Millisecond},
+				{3, 40 * time.Millisecond},
+				{4, 60 * time.Millisecond},
+				{5, 80 * time.Millisecond},
+				{6, 100 * time.Millisecond},
+				// Too late, passed through as is.
+				{2, 120 * time.Millisecond},
+			},
+			expected: []uint16{1, 3, 4, 5, 6, 2},
+		},
+		{
+			name:   "wrap around",
+			window: 100 * time.Millisecond,
+			inputs: []input{
+				{65534, 20 * time.Millisecond},
+				{0, 40 * time.Millisecond},
+				{65535, 60 * time.Millisecond},
+				{1, 80 * time.Millisecond},
+			},
+			expected:  []uint16{65534, 65535, 0, 1},
+			reordered: 1,
+		},
+		{
+			name:   "duplicates",
+			window: 100 * time.Millisecond,
+			inputs: []input{
+				{1, 20 * time.Millisecond},
+				{1, 30 * time.Millisecond},
+				{3, 40 * time.Millisecond},
+				{3, 50 * time.Millisecond},
+				{2, 60 * time.Millisecond},
+			},
+			expected:  []uint16{1, 1, 2, 3, 3},
+			reordered: 1,
+		},
+		{
+			name:   "disabled",
+			window: 0,
+			inputs: []input{
+				{1, 20 * time.Millisecond},
+				{3, 40 * time.Millisecond},
+				{2, 60 * time.Millisecond},
+			},
+			expected: []uint16{1, 3, 2},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			jb := newJitterBuffer(tc.window)
+			require.Equal(t, tc.expected, run(jb, tc.inputs))
+			require.Equal(t, tc.reordered, jb.reordered)
+		})
+	}
+
+	t.Run("bounded", func(t *testing.T) {
+		jb := newJitterBuffer(time.Hour)
+		push := func(seq uint16) []uint16 {
+			return seqs(jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: seq}}, 0))
+		}
+
+		// Packet 1 is lost and never arrives.
+		require.Equal(t, []uint16{0}, push(0))
+		for i := 0; i < jitterBufferMaxPackets; i++ {
+			require.Empty(t, push(uint16(i+2)))
+		}
+		require.Len(t, jb.pkts, jitterBufferMaxPackets)
+
+		// Going over the limit gives up on the missing packet.
+		out := push(jitterBufferMaxPackets + 2)
+		require.Len(t, out, jitterBufferMaxPackets+1)
+		require.Equal(t, uint16(2), out[0])
+		require.Empty(t, jb.pkts)
+	})
+}
@@ -3,13 +3,75 @@ package call
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/mattermost/mattermost-plugin-calls/server/public"
 )
 
-func (t *Transcriber) postJobStatus(status public.JobStatus) error {
+const (
+	// jobStatusTypeProgress is reported while post-processing.
+	jobStatusTypeProgress public.JobStatusType = "progress"
+	// jobStatusTypeDone is reported once the transcription has been published.
+	jobStatusTypeDone public.JobStatusType = "done"
+)
+
+// jobStatus extends the job status sent to the plugin with optional metadata.
+type jobStatus struct {
+	public.JobStatus
+	Progress *jobProgress `json:"progress,omitempty"`
+	Metadata *jobMetadata `json:"metadata,omitempty"`
+}
+
+type jobProgress struct {
+	// Percent is the overall post-processing completion, in the range [0, 100].
+	Percent float64 `json:"percent"`
+	// Stage is a human readable description of the current step
+	// (e.g. "transcribing 3/7 tracks").
+	Stage string `json:"stage"`
+}
+
+type jobMetadata struct {
+	// Speakers lists the audio coverage for every session observed on the call.
+	Speakers []speakerStats `json:"speakers"`
+	// Captions summarizes the live captions quality, if they were on.
+	Captions *captionsQualityStats `json:"captions,omitempty"`
+}
+
+// speakerStats reports how much audio was captured for a session and how much
+// of it was detected as speech. A session with no captured audio likely
+// points to a client whose audio never reached the transcriber.
+type speakerStats struct {
+	SessionID     string  `json:"session_id"`
+	UserID        string  `json:"user_id"`
+	Speaker       string  `json:"speaker"`
+	AudioSeconds  float64 `json:"audio_seconds"`
+	SpeechSeconds float64 `json:"speech_seconds"`
+}
+
+// getSpeakerStats returns the stats for every observed participant given the
+// per-session captured audio and speech durations.
+func (t *Transcriber) getSpeakerStats(audioDurs, speechDurs map[string]time.Duration) []speakerStats {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	stats := make([]speakerStats, 0, len(t.participants))
+	for _, p := range t.participants {
+		stats = append(stats, speakerStats{
+			SessionID:     p.SessionID,
+			UserID:        p.UserID,
+			Speaker:       p.Speaker,
+			AudioSeconds:  audioDurs[p.SessionID].Seconds(),
+			SpeechSeconds: speechDurs[p.SessionID].Seconds(),
+		})
+	}
+
+	return stats
+}
+
+func (t *Transcriber) postJobStatus(status jobStatus) error {
 	apiURL := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/jobs/%s/status",
 		t.apiURL, pluginID, t.cfg.CallID, t.cfg.TranscriptionID)
 
@@ -22,6 +84,7 @@ func (t *Transcriber) postJobStatus(status public.JobStatus) error {
 	defer cancelCtx()
 	resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL, payload, "")
 	if err != nil {
+		err = newAPIError(resp, err)
 		return fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
@@ -31,16 +94,48 @@ func (t *Transcriber) postJobStatus(status public.JobStatus) error {
 }
 
 func (t *Transcriber) ReportJobFailure(errMsg string) error {
-	return t.postJobStatus(public.JobStatus{
-		JobType: public.JobTypeTranscribing,
-		Status:  public.JobStatusTypeFailed,
-		Error:   errMsg,
+	t.notifyCompletion(t.recordingOffsetMs(), errors.New(errMsg))
+
+	return t.postJobStatus(jobStatus{
+		JobStatus: public.JobStatus{
+			JobType: public.JobTypeTranscribing,
+			Status:  public.JobStatusTypeFailed,
+			Error:   errMsg,
+		},
 	})
 }
 
 func (t *Transcriber) ReportJobStarted() error {
-	return t.postJobStatus(public.JobStatus{
-		JobType: public.JobTypeTranscribing,
-		Status:  public.JobStatusTypeStarted,
+	return t.postJobStatus(jobStatus{
+		JobStatus: public.JobStatus{
+			JobType: public.JobTypeTranscribing,
+			Status:  public.JobStatusTypeStarted,
+		},
+	})
+}
+
+func (t *Transcriber) ReportJobProgress(percent float64, stage string) error {
+	return t.postJobStatus(jobStatus{
+		JobStatus: public.JobStatus{
+			JobType: public.JobTypeTranscribing,
+			Status:  jobStatusTypeProgress,
+		},
+		Progress: &jobProgress{
+			Percent: min(100, max(0, percent)),
+			Stage:   stage,
+		},
+	})
+}
+
+func (t *Transcriber) ReportJobDone(speakers []speakerStats) error {
+	return t.postJobStatus(jobStatus{
+		JobStatus: public.JobStatus{
+			JobType: public.JobTypeTranscribing,
+			Status:  jobStatusTypeDone,
+		},
+		Metadata: &jobMetadata{
+			Speakers: speakers,
+			Captions: t.getCaptionsQualityStats(),
+		},
 	})
 }
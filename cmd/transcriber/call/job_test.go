@@ -6,10 +6,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
 	"github.com/mattermost/mattermost-plugin-calls/server/public"
 
+	"github.com/mattermost/mattermost/server/public/model"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,7 +57,7 @@ func TestReportJobFailure(t *testing.T) {
 			},
 		}
 		err := tr.ReportJobFailure("")
-		require.EqualError(t, err, "request failed: server error")
+		require.EqualError(t, err, "request failed: server error (status 400)")
 	})
 
 	t.Run("success", func(t *testing.T) {
@@ -94,3 +97,112 @@ func TestReportJobFailure(t *testing.T) {
 		require.Equal(t, "some error", errMsg)
 	})
 }
+
+func TestReportJobDone(t *testing.T) {
+	var status jobStatus
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/jobs/67t5u6cmtfbb7jug739d43xa9e/status" {
+			w.WriteHeader(404)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			w.WriteHeader(400)
+			fmt.Fprintf(w, `{"message": %q}`, err.Error())
+			return
+		}
+
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	tr.addParticipant("sessionA", &model.User{Id: "userA", Username: "usera"})
+	tr.addParticipant("sessionB", &model.User{Id: "userB", Username: "userb"})
+
+	err = tr.ReportJobDone(tr.getSpeakerStats(map[string]time.Duration{
+		"sessionA": 90 * time.Second,
+	}, map[string]time.Duration{
+		"sessionA": 45 * time.Second,
+	}))
+	require.NoError(t, err)
+
+	require.Equal(t, jobStatus{
+		JobStatus: public.JobStatus{
+			JobType: public.JobTypeTranscribing,
+			Status:  jobStatusTypeDone,
+		},
+		Metadata: &jobMetadata{
+			Speakers: []speakerStats{
+				{
+					SessionID:     "sessionA",
+					UserID:        "userA",
+					Speaker:       "usera",
+					AudioSeconds:  90,
+					SpeechSeconds: 45,
+				},
+				{
+					SessionID: "sessionB",
+					UserID:    "userB",
+					Speaker:   "userb",
+				},
+			},
+		},
+	}, status)
+}
+
+func TestReportJobProgress(t *testing.T) {
+	var status jobStatus
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/jobs/67t5u6cmtfbb7jug739d43xa9e/status" {
+			w.WriteHeader(404)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			w.WriteHeader(400)
+			fmt.Fprintf(w, `{"message": %q}`, err.Error())
+			return
+		}
+
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	err = tr.ReportJobProgress(3.0/7.0*100, "transcribing 4/7 tracks")
+	require.NoError(t, err)
+	require.Equal(t, public.JobStatusType("progress"), status.Status)
+	require.EqualValues(t, public.JobTypeTranscribing, status.JobType)
+	require.NotNil(t, status.Progress)
+	require.InDelta(t, 42.857, status.Progress.Percent, 0.001)
+	require.Equal(t, "transcribing 4/7 tracks", status.Progress.Stage)
+	require.Nil(t, status.Metadata)
+
+	// Out of range values are clamped.
+	err = tr.ReportJobProgress(120, "publishing")
+	require.NoError(t, err)
+	require.Equal(t, float64(100), status.Progress.Percent)
+}
@@ -0,0 +1,61 @@
+package call
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type truncationReason string
+
+const (
+	truncationReasonMaxCallDuration truncationReason = "maximum call duration reached"
+	truncationReasonMaxTrackSize    truncationReason = "maximum track size reached"
+)
+
+// truncate stops capturing the call because one of the configured limits
+// was exceeded. Closing the client kicks off post-processing, so whatever
+// was captured up to this point still gets published.
+func (t *Transcriber) truncate(reason truncationReason) {
+	if !t.truncated.CompareAndSwap(nil, &reason) {
+		return
+	}
+
+	slog.Warn("limit exceeded, stopping capture", slog.String("reason", string(reason)))
+
+	t.closing.Store(true)
+	go t.client.Load().Close()
+}
+
+// getTruncationNote returns the annotation for the published transcription
+// if capturing was stopped early, or an empty string otherwise.
+func (t *Transcriber) getTruncationNote() string {
+	reason := t.truncated.Load()
+	if reason == nil {
+		return ""
+	}
+	return fmt.Sprintf("Transcription truncated: %s.", *reason)
+}
+
+// monitorCallDuration truncates the call once the configured maximum
+// duration, counted from the start of the recording, is reached.
+func (t *Transcriber) monitorCallDuration() {
+	maxDur := t.cfg.Capture.MaxCallDuration
+	if maxDur <= 0 {
+		return
+	}
+
+	var elapsed time.Duration
+	if startTime := t.startTime.Load(); startTime != nil {
+		elapsed = time.Since(*startTime)
+	}
+
+	timer := time.NewTimer(max(0, maxDur-elapsed))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		t.truncate(truncationReasonMaxCallDuration)
+	case <-t.doneCh:
+	}
+}
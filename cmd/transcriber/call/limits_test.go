@@ -0,0 +1,135 @@
+package call
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncate(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	require.Empty(t, tr.getTruncationNote())
+
+	tr.truncate(truncationReasonMaxTrackSize)
+	require.True(t, tr.closing.Load())
+	require.Equal(t, "Transcription truncated: maximum track size reached.", tr.getTruncationNote())
+
+	// The first reason sticks.
+	tr.truncate(truncationReasonMaxCallDuration)
+	require.Equal(t, "Transcription truncated: maximum track size reached.", tr.getTruncationNote())
+}
+
+func TestMonitorCallDuration(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.monitorCallDuration()
+		require.Nil(t, tr.truncated.Load())
+	})
+
+	t.Run("exceeded", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Capture.MaxCallDuration = time.Minute
+		// The duration is counted from the start of the recording.
+		tr.startTime.Store(newTimeP(time.Now().Add(-time.Minute)))
+		tr.monitorCallDuration()
+		require.Equal(t, truncationReasonMaxCallDuration, *tr.truncated.Load())
+	})
+
+	t.Run("done", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Capture.MaxCallDuration = time.Hour
+		tr.startTime.Store(newTimeP(time.Now()))
+		close(tr.doneCh)
+		tr.monitorCallDuration()
+		require.Nil(t, tr.truncated.Load())
+	})
+}
+
+func TestMaxTrackSize(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Capture.MaxTrackSizeBytes = 1024
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+		"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+		Return(&http.Response{
+			Body: io.NopCloser(strings.NewReader(`{"id": "userID", "username": "testuser"}`)),
+		}, nil).Once()
+
+	// The track never ends on its own.
+	var seq uint16
+	track := &trackRemoteMock{
+		id: "trackID",
+		readRTP: func() (*rtp.Packet, interceptor.Attributes, error) {
+			seq++
+			return &rtp.Packet{
+				Header: rtp.Header{
+					SequenceNumber: seq,
+					Timestamp:      uint32(seq) * trackInFrameSize,
+				},
+				Payload: []byte{0x45, 0x45, 0x45, 0x45},
+			}, nil, nil
+		},
+	}
+
+	tr.liveTracksWg.Add(1)
+	tr.startTime.Store(newTimeP(time.Now()))
+	tr.processLiveTrack(track, "sessionID")
+
+	require.Equal(t, truncationReasonMaxTrackSize, *tr.truncated.Load())
+
+	info, err := os.Stat(filepath.Join(getDataDir(), "userID_trackID.ogg"))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, info.Size(), int64(1024))
+	require.Less(t, info.Size(), int64(1100))
+
+	// What was captured so far is still processed.
+	close(tr.trackCtxs)
+	ctx := <-tr.trackCtxs
+	require.Equal(t, "trackID", ctx.trackID)
+}
+
+func TestWriteTranscriptionFilesNote(t *testing.T) {
+	cfg := setupTranscriberForTest(t).cfg.Output
+
+	tr := transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{
+					Text:    "Hello",
+					StartTS: 0,
+					EndTS:   1000,
+				},
+			},
+		},
+	}
+
+	paths, err := writeTranscriptionFiles(getDataDir(), "call", tr, cfg.Format.Formats(), cfg.Options, nil, "Transcription truncated: maximum call duration reached.")
+	require.NoError(t, err)
+	require.Len(t, paths, 2)
+
+	vtt, err := os.ReadFile(paths[0])
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(vtt), "Hello\n\nNOTE Transcription truncated: maximum call duration reached.\n"))
+
+	text, err := os.ReadFile(paths[1])
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(text), "Hello\n\nTranscription truncated: maximum call duration reached.\n"))
+}
@@ -3,46 +3,182 @@ package call
 import (
 	"errors"
 	"fmt"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/azure"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/external"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/whisper.cpp"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/audio"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/denoise"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/opus"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
 	"github.com/mattermost/mattermost-plugin-calls/server/public"
 	"github.com/streamer45/silero-vad-go/speech"
 	"log/slog"
 	"path/filepath"
+	"sync"
 	"time"
 )
 
 const (
+	// transcriberQueueChBuffer is the minimum size of the caption requests
+	// queue, used if LiveCaptionsQueueSize isn't set.
 	transcriberQueueChBuffer = 1
 	tickRate                 = 2 * time.Second
+	partialTickRate          = time.Second // captions are sent sooner when partial ones are enabled
 	maxWindowSize            = 8 * time.Second
 	windowPressureLimitSec   = 12                                                           // at this point cut the audio down to prevent a death spiral
 	pktPayloadChBuffer       = trackInAudioRate / trackInFrameSize * windowPressureLimitSec // hard drop after windowPressureLimitSec seconds of audio backing up
 	removeWindowAfterSilence = 3 * time.Second
 
 	// VAD settings
-	vadWindowSizeInSamples  = 512 // 30 ms
-	vadThreshold            = 0.5
-	vadMinSilenceDurationMs = 150
-	vadSpeechPadMs          = 60
-	minSpeechLengthSamples  = 330 * trackOutAudioSamplesPerMs // padding (120) + 210 of detected speech
+	vadWindowSizeInSamples = 512                             // 30 ms
+	minSpeechLengthSamples = 330 * trackOutAudioSamplesPerMs // padding (120) + 210 of detected speech
 )
 
+// captionPackage is a request to transcribe a track's window. Every track
+// waits for its request to be served before queuing another so a single
+// speaker can't monopolize the transcribers.
 type captionPackage struct {
 	pcm   []float32
-	retCh chan string
+	retCh chan []captionText
+	// queuedAt is the monotonic time at which the package was queued.
+	queuedAt time.Duration
 }
 
-func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsCh <-chan []byte) {
-	opusDec, err := opus.NewDecoder(trackOutAudioRate, trackAudioChannels)
-	if err != nil {
-		slog.Error("processLiveCaptionsForTrack: failed to create opus decoder for live captions",
-			slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
-		return
+// captionsQueueStats measures the back-pressure on the live captions
+// transcribers.
+type captionsQueueStats struct {
+	mut sync.Mutex
+	// full counts the requests dropped because the queue was full.
+	full uint64
+	// served counts the requests picked up by a transcriber.
+	served  uint64
+	waitSum time.Duration
+	waitMax time.Duration
+}
+
+func (s *captionsQueueStats) addFull() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.full++
+}
+
+func (s *captionsQueueStats) addServed(wait time.Duration) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.served++
+	s.waitSum += wait
+	s.waitMax = max(s.waitMax, wait)
+}
+
+// get returns the number of dropped requests along with the average and
+// maximum time requests waited in the queue.
+func (s *captionsQueueStats) get() (full uint64, waitAvg, waitMax time.Duration) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.served > 0 {
+		waitAvg = s.waitSum / time.Duration(s.served)
+	}
+	return s.full, waitAvg, s.waitMax
+}
+
+// captionText is the text of a caption in one of the configured languages.
+type captionText struct {
+	language   string
+	text       string
+	confidence float64
+}
+
+// captionMsg extends public.CaptionMsg with rendering hints for clients.
+type captionMsg struct {
+	public.CaptionMsg
+	ColorIndex int `json:"color_index"`
+	// Language is the language of the caption, so that clients can pick their
+	// preferred one when the call is captioned in several.
+	Language string `json:"language,omitempty"`
+	// Partial marks a provisional caption, to be replaced by the following
+	// one for the same session.
+	Partial bool `json:"partial,omitempty"`
+	// Confidence is the average probability, in the range (0, 1], of the
+	// caption's tokens so that clients can tone down or hide captions likely
+	// transcribed from noise. It's omitted if unknown.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// sendCaption broadcasts the caption to clients, archiving it if enabled. In
+// shadow mode captions are instead appended to a file in the data directory
+// so that caption quality and load can be evaluated without exposing them to
+// users.
+func (t *Transcriber) sendCaption(ctx trackContext, msg captionMsg) error {
+	if !t.cfg.LiveCaptions.Shadow {
+		var err error
+		if t.cfg.LiveCaptions.Ack {
+			err = t.sendCaptionAcked(msg)
+		} else {
+			err = t.sendCaptionWS(msg)
+		}
+		if err == nil && t.cfg.LiveCaptions.Archive {
+			t.archiveCaption(ctx, msg)
+		}
+		return err
+	}
+
+	slog.Debug("shadow caption",
+		slog.String("trackID", ctx.trackID),
+		slog.Int("textLen", len(msg.Text)),
+		slog.Float64("newAudioLenMs", msg.NewAudioLenMs))
+
+	if err := t.shadowCaptions.write(t.shadowCaptionsPath(), t.newRecordedCaption(ctx, msg)); err != nil {
+		return fmt.Errorf("failed to persist shadow caption: %w", err)
+	}
+
+	return nil
+}
+
+// sendCaptions sends the captions transcribed from a window of the track,
+// one per language, returning whether any was sent.
+func (t *Transcriber) sendCaptions(ctx trackContext, captions []captionText, newAudioLenMs int, partial bool) bool {
+	var sent bool
+	for _, caption := range captions {
+		if err := t.sendCaption(ctx, captionMsg{
+			CaptionMsg: public.CaptionMsg{
+				SessionID:     ctx.sessionID,
+				Text:          caption.text,
+				NewAudioLenMs: float64(newAudioLenMs),
+			},
+			ColorIndex: ctx.colorIndex,
+			Language:   caption.language,
+			Partial:    partial,
+			Confidence: caption.confidence,
+		}); err != nil {
+			slog.Error("processLiveCaptionsForTrack: error sending ws captions",
+				slog.String("err", err.Error()),
+				slog.String("language", caption.language),
+				slog.String("trackID", ctx.trackID))
+		} else {
+			sent = true
+		}
 	}
+	return sent
+}
+
+// captionsPkt is an audio packet passed on for live captioning.
+type captionsPkt struct {
+	payload []byte
+	// lost is the number of packets missing right before this one.
+	lost int
+}
+
+func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsCh <-chan captionsPkt) {
+	// The decoder is created on the first packet, which tells the number of
+	// channels of the track.
+	var opusDec *opus.Decoder
+	var channels int
+	var pcmBuf []float32
 	defer func() {
+		if opusDec == nil {
+			return
+		}
 		if err := opusDec.Destroy(); err != nil {
 			slog.Error("processLiveCaptionsForTrack: failed to destroy decoder", slog.String("err", err.Error()),
 				slog.String("trackID", ctx.trackID))
@@ -50,44 +186,48 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 	}()
 
 	// Setup the VAD
-	sd, err := speech.NewDetector(speech.DetectorConfig{
-		ModelPath:  filepath.Join(getModelsDir(), "silero_vad.onnx"),
-		SampleRate: trackOutAudioRate,
-
-		Threshold:            vadThreshold,
-		MinSilenceDurationMs: vadMinSilenceDurationMs,
-		SpeechPadMs:          vadSpeechPadMs,
-	})
+	sd, err := newSpeechDetector(t.cfg.LiveCaptions.VAD)
 	if err != nil {
 		slog.Error("processLiveCaptionsForTrack: failed to create speech detector",
 			slog.String("err", err.Error()))
 	}
 	defer func() {
-		if err := sd.Destroy(); err != nil {
-			slog.Error("processLiveCaptionsForTrack: failed to destroy speech detector", slog.String("err", err.Error()))
+		if sd != nil {
+			if err := sd.Destroy(); err != nil {
+				slog.Error("processLiveCaptionsForTrack: failed to destroy speech detector", slog.String("err", err.Error()))
+			}
 		}
 		slog.Debug("processLiveCaptionsForTrack: finished processing live captions",
 			slog.String("trackID", ctx.trackID))
 	}()
 
-	pcmBuf := make([]float32, trackOutFrameSize)
-
 	// readTrackPktPayloads drains the pktPayloadsCh (audio data from the track) and converts it to PCM.
 	readTrackPktPayloads := func(window []float32) ([]float32, error) {
 		for {
 			select {
-			case payload, ok := <-pktPayloadsCh:
+			case pkt, ok := <-pktPayloadsCh:
 				if !ok {
 					// Exit on channel close
 					return nil, errors.New("closed")
 				}
-				n, err := opusDec.Decode(payload, pcmBuf)
+				if opusDec == nil {
+					var err error
+					channels = audio.OpusPacketChannels(pkt.payload)
+					opusDec, err = opus.NewDecoder(trackOutAudioRate, channels)
+					if err != nil {
+						slog.Error("processLiveCaptionsForTrack: failed to create opus decoder for live captions",
+							slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+						return nil, err
+					}
+					pcmBuf = make([]float32, trackOutFrameSize*channels)
+				}
+				var err error
+				window, _, err = audio.DecodeWithLoss(opusDec, channels, pkt.payload, pkt.lost, pcmBuf, window)
 				if err != nil {
 					slog.Error("failed to decode audio data for live captions",
 						slog.String("err", err.Error()),
 						slog.String("trackID", ctx.trackID))
 				}
-				window = append(window, pcmBuf[:n]...)
 			default:
 				// Done draining
 				return window, nil
@@ -100,9 +240,49 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 	prevTranscribedPos := 0
 	prevWindowLen := 0
 	var prevAudioAt time.Time
+	var droppedWindows, droppedTicks int
+
+	// With partial captions enabled, the captions of the window are sent as
+	// provisional until its speech ends, at which point the last ones are sent
+	// again as final.
+	rate := tickRate
+	if t.cfg.LiveCaptions.Partial {
+		rate = partialTickRate
+	}
+	//
+	// Captions go through the smoother, which suppresses repeats and holds
+	// them back when sent too often. Held captions are sent once due, on the
+	// following ticks, or when the window ends.
+	smoother := newCaptionsSmoother(t.cfg.LiveCaptions)
+	sendBatch := func(b *captionsBatch) bool {
+		if b == nil || !t.sendCaptions(ctx, b.captions, b.newAudioLenMs, b.partial) {
+			return false
+		}
+		t.trackStats.addCaption(ctx.trackID)
+		return true
+	}
+	var pending []captionText
+	finalizeCaptions := func() {
+		var b *captionsBatch
+		if len(pending) > 0 {
+			b = &captionsBatch{captions: pending}
+			pending = nil
+		}
+		sendBatch(smoother.next(b, t.monoNow(), true))
+	}
+	defer func() {
+		finalizeCaptions()
+		if smoother.suppressed > 0 {
+			slog.Debug("processLiveCaptionsForTrack: suppressed repeated captions",
+				slog.Int("count", smoother.suppressed),
+				slog.String("trackID", ctx.trackID))
+		}
+	}()
 
-	ticker := time.NewTicker(tickRate)
+	ticker := time.NewTicker(rate)
 	defer ticker.Stop()
+	defer t.removeCaptionsWindowStats(ctx.trackID)
+	defer t.captionsBudget.remove(ctx.trackID)
 
 	// Algorithm summary:
 	// - Get a cleaned version of the voice (with zeroes where no voice is detected)
@@ -117,13 +297,36 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 	// - finish and wait for next `tick`
 
 	for range ticker.C {
+		sendBatch(smoother.next(nil, t.monoNow(), false))
+
 		// empty the waiting pktPayloadsCh
 		window, err = readTrackPktPayloads(window)
 		if err != nil {
-			// exit on close
+			// exit on close, or if audio can't be decoded
 			return
 		}
 
+		// Windows of all tracks share a memory budget. When over it, the
+		// oldest audio is dropped first.
+		if allowed := int(t.captionsBudget.update(ctx.trackID, int64(len(window))*4) / 4); allowed < len(window) {
+			cut := len(window) - allowed
+			slog.Debug("processLiveCaptionsForTrack: trimming window to stay within memory budget",
+				slog.Int("cutMs", cut/trackOutAudioSamplesPerMs),
+				slog.String("trackID", ctx.trackID))
+			window = append(window[:0], window[cut:]...)
+			prevTranscribedPos = max(0, prevTranscribedPos-cut)
+			prevWindowLen = max(0, prevWindowLen-cut)
+		}
+
+		t.updateCaptionsWindowStats(captionsWindowStats{
+			TrackID:        ctx.trackID,
+			SessionID:      ctx.sessionID,
+			WindowMs:       len(window) / trackOutAudioSamplesPerMs,
+			PendingPkts:    len(pktPayloadsCh),
+			DroppedWindows: droppedWindows,
+			DroppedTicks:   droppedTicks,
+		})
+
 		// track how long we were waiting until consuming the next batch of audio data, as a measure
 		// of the pressure on the transcription process
 		newAudioLenMs := (len(window) - prevWindowLen) / trackOutAudioSamplesPerMs
@@ -137,6 +340,7 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 		if len(window) == prevWindowLen {
 			// And clear the window if we haven't had new data (window is stale, don't re-transcribe)
 			if time.Since(prevAudioAt) > removeWindowAfterSilence {
+				finalizeCaptions()
 				window = window[:0]
 				prevWindowLen = 0
 				prevTranscribedPos = 0
@@ -150,10 +354,13 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 		// can finish it all in time, and it will never be able to recover. This happens especially when
 		// number of calls * threads per call > numCPUs. We need to be able to relieve the pressure.
 		if len(window) >= windowPressureLimitSamples {
+			finalizeCaptions()
+			droppedWindows++
+			t.captionsQuality.addDroppedWindow(ctx.sessionID)
 			window = window[:0]
 			prevWindowLen = 0
 			prevTranscribedPos = 0
-			if err := t.client.SendWS(wsEvMetric, public.MetricMsg{
+			if err := t.client.Load().SendWS(wsEvMetric, public.MetricMsg{
 				SessionID:  ctx.sessionID,
 				MetricName: public.MetricLiveCaptionsWindowDropped,
 			}, false); err != nil {
@@ -167,7 +374,19 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 		prevAudioAt = time.Now()
 		prevWindowLen = len(window)
 
-		vadSegments, err := sd.Detect(window)
+		// The window is kept as received, noise being removed every time
+		// from the whole of it so that it's estimated on enough audio.
+		audio := window
+		if t.cfg.Engine.Denoise {
+			audio = denoise.Reduce(window)
+		}
+
+		if sd == nil {
+			// Captions can't be generated without detecting speech first.
+			continue
+		}
+
+		vadSegments, err := sd.Detect(audio)
 		if err != nil {
 			slog.Error("processLiveCaptionsForTrack: vad failed", slog.String("err", err.Error()))
 			continue
@@ -183,14 +402,15 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 		}
 
 		// Prepare the vad segments and the audio for transcription.
-		segments := convertToSegmentSamples(vadSegments, len(window))
+		segments := convertToSegmentSamples(vadSegments, len(audio))
 		segments = removeShortSpeeches(segments)
-		cleaned := cleanAudio(window, segments)
+		cleaned := cleanAudio(audio, segments)
 
 		// Before sending off data to be transcribed, check if new data is silence.
 		// If it is silence, don't send it off.
 		newDataIsSilence, windowFinished := checkSilence(segments, prevTranscribedPos)
 		if windowFinished {
+			finalizeCaptions()
 			window = window[:0]
 			prevTranscribedPos = 0
 			prevWindowLen = 0
@@ -202,16 +422,19 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 
 		// Track our new position and send off data for transcription.
 		prevTranscribedPos = len(cleaned)
-		transcribedCh := make(chan string)
+		transcribedCh := make(chan []captionText)
 		pkg := captionPackage{
-			pcm:   cleaned,
-			retCh: transcribedCh,
+			pcm:      cleaned,
+			retCh:    transcribedCh,
+			queuedAt: t.monoNow(),
 		}
 		select {
 		case t.captionsPoolQueueCh <- pkg:
 			break
 		default:
-			if err := t.client.SendWS(wsEvMetric, public.MetricMsg{
+			t.captionsQueueStats.addFull()
+			t.captionsQuality.addDroppedWindow(ctx.sessionID)
+			if err := t.client.Load().SendWS(wsEvMetric, public.MetricMsg{
 				SessionID:  ctx.sessionID,
 				MetricName: public.MetricLiveCaptionsTranscriberBufFull,
 			}, false); err != nil {
@@ -223,32 +446,41 @@ func (t *Transcriber) processLiveCaptionsForTrack(ctx trackContext, pktPayloadsC
 		}
 
 		// While audio is being transcribed, we need to cut down the window if it's > maxWindowSize.
+		// The captions of a window that got cut are final since the audio
+		// they cover won't be transcribed again.
+		cutLen := len(window)
 		window, prevTranscribedPos = cutWindowToSize(ctx.trackID, window, segments, prevTranscribedPos)
 		prevWindowLen = len(window)
+		windowCut := len(window) < cutLen
 
 		// Use a for loop and a select so that we can drop ticks waiting for the transcriber.
 		for {
 			select {
 			case <-ticker.C:
+				droppedTicks++
+				t.captionsQuality.addBacklog(rate)
 				slog.Debug("processLiveCaptionsForTrack: dropped a tick waiting for the transcriber",
 					slog.String("trackID", ctx.trackID))
 				continue
-			case text := <-transcribedCh:
-				if len(text) == 0 {
+			case captions := <-transcribedCh:
+				if len(captions) == 0 {
 					// Either transcribedCh was closed above (captionQueueCh full), or audio transcription failed.
 					// Note: this appears to happen when the transcriber fails to decode a block of audio.
 					// Usually the probability returned for the language is very low, which makes sense.
 					slog.Debug("processLiveCaptionsForTrack: received empty text, ignoring.")
 					break
 				}
-				if err := t.client.SendWS(wsEvCaption, public.CaptionMsg{
-					SessionID:     ctx.sessionID,
-					Text:          text,
-					NewAudioLenMs: float64(newAudioLenMs),
-				}, false); err != nil {
-					slog.Error("processLiveCaptionsForTrack: error sending ws captions",
-						slog.String("err", err.Error()),
-						slog.String("trackID", ctx.trackID))
+				partial := t.cfg.LiveCaptions.Partial && !windowCut
+				pending = nil
+				if partial {
+					pending = captions
+				}
+				if sendBatch(smoother.next(&captionsBatch{
+					captions:      captions,
+					newAudioLenMs: newAudioLenMs,
+					partial:       partial,
+				}, t.monoNow(), false)) {
+					t.captionsQuality.addSent(t.monoNow() - pkg.queuedAt)
 				}
 			}
 
@@ -396,70 +628,143 @@ func cutWindowToSize(trackID string, window []float32, segments []segmentSamples
 }
 
 func (t *Transcriber) startTranscriberPool() {
-	for i := 0; i < t.cfg.LiveCaptionsNumTranscribers; i++ {
+	t.captionsScaler = newCaptionsScaler(t.cfg.LiveCaptions, getModelsDir())
+	for i := 0; i < t.cfg.LiveCaptions.NumTranscribers; i++ {
+		t.startCaptionsTranscriber()
+	}
+
+	if t.cfg.LiveCaptions.AutoScale {
 		t.captionsPoolWg.Add(1)
-		go t.handleTranscriptionRequests(i)
+		go t.scaleLiveCaptions()
 	}
 }
 
-func (t *Transcriber) handleTranscriptionRequests(num int) {
+func (t *Transcriber) handleTranscriptionRequests(num int, stopCh <-chan struct{}) {
 	slog.Debug(fmt.Sprintf("live captions, handleTranscriptionRequests: starting transcriber #%d", num))
 
-	transcriber, err := t.newLiveCaptionsTranscriber()
-	if err != nil {
-		slog.Error("live captions, handleTranscriptionRequests: failed to create transcriber",
-			slog.String("err", err.Error()))
-		t.captionsPoolWg.Done()
-		return
+	// Every language gets its own transcriber since whisper contexts are bound
+	// to the language they output.
+	var transcribers []captionsTranscriber
+	destroyTranscribers := func() {
+		for _, ct := range transcribers {
+			if err := ct.Destroy(); err != nil {
+				slog.Error("live captions, handleTranscriptionRequests: failed to destroy transcriber",
+					slog.String("err", err.Error()),
+					slog.String("language", ct.language))
+			}
+		}
+		transcribers = nil
 	}
 	defer func() {
-		err := transcriber.Destroy()
-		if err != nil {
-			slog.Error("live captions, handleTranscriptionRequests: failed to destroy transcriber",
-				slog.String("err", err.Error()))
-		}
+		destroyTranscribers()
 		t.captionsPoolWg.Done()
 	}()
 
+	// The model can change during the call (see captionsScaler), in which
+	// case transcribers are recreated before serving the next request.
+	var modelSize config.ModelSize
 	for {
+		if size := t.captionsScaler.modelSize(); size != modelSize {
+			destroyTranscribers()
+			modelSize = size
+			for _, language := range t.cfg.LiveCaptions.Languages() {
+				transcriber, err := t.newLiveCaptionsTranscriber(modelSize, language)
+				if err != nil {
+					slog.Error("live captions, handleTranscriptionRequests: failed to create transcriber",
+						slog.String("err", err.Error()),
+						slog.String("modelSize", string(modelSize)),
+						slog.String("language", language))
+					return
+				}
+				transcribers = append(transcribers, captionsTranscriber{Transcriber: transcriber, language: language})
+			}
+		}
+
 		select {
 		case <-t.captionsPoolDoneCh:
 			slog.Debug(fmt.Sprintf("live captions, handleTranscriptionRequests: closing transcriber #%d", num))
 			return
+		case <-stopCh:
+			slog.Debug(fmt.Sprintf("live captions, handleTranscriptionRequests: stopping transcriber #%d", num))
+			return
 		case packet := <-t.captionsPoolQueueCh:
-			transcribed, _, err := transcriber.Transcribe(packet.pcm)
+			t.captionsQueueStats.addServed(t.monoNow() - packet.queuedAt)
+
+			captions, err := t.transcribeCaptions(transcribers, packet.pcm)
 			if err != nil {
 				slog.Error("live captions, handleTranscriptionRequests: failed to transcribe audio samples",
 					slog.String("err", err.Error()))
-				packet.retCh <- ""
+				packet.retCh <- nil
 				return
 			}
+			packet.retCh <- captions
+		}
+	}
+}
 
-			if len(transcribed) == 0 {
-				packet.retCh <- ""
-			} else {
-				packet.retCh <- transcribed[0].Text
-			}
+// captionsTranscriber is a live captions transcriber along with the language
+// it outputs.
+type captionsTranscriber struct {
+	transcribe.Transcriber
+	language string
+}
+
+// transcribeCaptions transcribes the audio in each of the transcribers'
+// languages, skipping those yielding no text.
+func (t *Transcriber) transcribeCaptions(transcribers []captionsTranscriber, pcm []float32) ([]captionText, error) {
+	captions := make([]captionText, 0, len(transcribers))
+	for _, ct := range transcribers {
+		transcribed, _, err := ct.Transcribe(pcm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcribe in %q: %w", ct.language, err)
+		}
+
+		transcribed = transcribe.FilterSegments(transcribed, t.outputFilterRE)
+		if len(transcribed) > 0 {
+			captions = append(captions, captionText{
+				language:   ct.language,
+				text:       transcribed[0].Text,
+				confidence: transcribed[0].Confidence,
+			})
 		}
 	}
+
+	return captions, nil
 }
 
-func (t *Transcriber) newLiveCaptionsTranscriber() (transcribe.Transcriber, error) {
-	switch t.cfg.TranscribeAPI {
+func (t *Transcriber) newLiveCaptionsTranscriber(modelSize config.ModelSize, language string) (transcribe.Transcriber, error) {
+	switch t.cfg.Engine.TranscribeAPI {
 	case config.TranscribeAPIAzure:
-		// Only supporting WhisperCPP live captions for the time being.
-		fallthrough
+		// Captions are transcribed by the service, no model involved.
+		sr, err := azure.NewSpeechRecognizer(t.azureSpeechRecognizerConfig(language))
+		if err != nil {
+			return nil, err
+		}
+		ct, err := newAsyncCaptionsTranscriber(sr)
+		if err != nil {
+			_ = sr.Destroy()
+			return nil, err
+		}
+		return ct, nil
+	case config.TranscribeAPIExternal:
+		return external.NewTranscriber(t.externalTranscriberConfig(language))
+	case config.TranscribeAPIFasterWhisper:
+		return t.newFasterWhisperTranscriber(language)
 	case config.TranscribeAPIWhisperCPP:
+		suppressNonSpeechTokens, _ := t.cfg.Engine.TranscribeAPIOptions["WHISPER_SUPPRESS_NON_SPEECH_TOKENS"].(bool)
+		suppressRegex, _ := t.cfg.Engine.TranscribeAPIOptions["WHISPER_SUPPRESS_REGEX"].(string)
 		return whisper.NewContext(whisper.Config{
-			ModelFile:     filepath.Join(getModelsDir(), fmt.Sprintf("ggml-%s.bin", string(t.cfg.LiveCaptionsModelSize))),
-			NumThreads:    t.cfg.LiveCaptionsNumThreadsPerTranscriber,
-			NoContext:     true, // do not use previous translations as context for next translation: https://github.com/ggerganov/whisper.cpp/pull/141#issuecomment-1321225563
-			AudioContext:  512,  // a bit more than 10seconds: https://github.com/ggerganov/whisper.cpp/pull/141#issuecomment-1321230379
-			PrintProgress: false,
-			Language:      t.cfg.LiveCaptionsLanguage,
-			SingleSegment: true,
+			ModelFile:               filepath.Join(getModelsDir(), fmt.Sprintf("ggml-%s.bin", string(modelSize))),
+			NumThreads:              t.cfg.LiveCaptions.NumThreadsPerTranscriber,
+			NoContext:               true, // do not use previous translations as context for next translation: https://github.com/ggerganov/whisper.cpp/pull/141#issuecomment-1321225563
+			AudioContext:            512,  // a bit more than 10seconds: https://github.com/ggerganov/whisper.cpp/pull/141#issuecomment-1321230379
+			PrintProgress:           false,
+			Language:                language,
+			SingleSegment:           true,
+			SuppressNonSpeechTokens: suppressNonSpeechTokens,
+			SuppressRegex:           suppressRegex,
 		})
 	default:
-		return nil, fmt.Errorf("transcribe API %q not implemented", t.cfg.TranscribeAPI)
+		return nil, fmt.Errorf("transcribe API %q not implemented", t.cfg.Engine.TranscribeAPI)
 	}
 }
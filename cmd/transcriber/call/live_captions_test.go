@@ -0,0 +1,298 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCaptionShadow(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.LiveCaptions.On = true
+	tr.cfg.LiveCaptions.Shadow = true
+	tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+
+	ctx := trackContext{
+		trackID:    "trackID",
+		sessionID:  "sessionID",
+		colorIndex: 2,
+	}
+
+	for _, text := range []string{"Hello", "world"} {
+		err := tr.sendCaption(ctx, captionMsg{
+			CaptionMsg: public.CaptionMsg{
+				SessionID:     ctx.sessionID,
+				Text:          text,
+				NewAudioLenMs: 2000,
+			},
+			ColorIndex: ctx.colorIndex,
+		})
+		require.NoError(t, err)
+	}
+	tr.closeCaptionsFiles()
+
+	data, err := os.ReadFile(filepath.Join(getDataDir(), tr.cfg.TranscriptionID+"_captions.jsonl"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	for i, text := range []string{"Hello", "world"} {
+		var caption recordedCaption
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &caption))
+		require.Equal(t, "trackID", caption.TrackID)
+		require.Equal(t, "sessionID", caption.SessionID)
+		require.Equal(t, text, caption.Text)
+		require.Equal(t, 2, caption.ColorIndex)
+		require.Equal(t, float64(2000), caption.NewAudioLenMs)
+		require.GreaterOrEqual(t, caption.OffsetMs, int64(1000))
+	}
+}
+
+func TestSendCaptionArchive(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.LiveCaptions.On = true
+	tr.cfg.LiveCaptions.Ack = true
+	tr.cfg.LiveCaptions.Archive = true
+	tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	captionsURL := "http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/captions"
+	mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, mock.Anything, "").
+		Return(&http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil).Twice()
+
+	mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, mock.Anything, "").
+		Return(nil, fmt.Errorf("timeout")).Times(captionMaxSendAttempts)
+
+	ctx := trackContext{
+		trackID:   "trackID",
+		sessionID: "sessionID",
+	}
+	for _, text := range []string{"Hello", "world"} {
+		require.NoError(t, tr.sendCaption(ctx, captionMsg{
+			CaptionMsg: public.CaptionMsg{
+				SessionID: ctx.sessionID,
+				Text:      text,
+			},
+		}))
+	}
+	// Captions failing to be sent aren't archived.
+	require.Error(t, tr.sendCaption(ctx, captionMsg{
+		CaptionMsg: public.CaptionMsg{
+			SessionID: ctx.sessionID,
+			Text:      "lost",
+		},
+	}))
+	tr.closeCaptionsFiles()
+
+	data, err := os.ReadFile(tr.captionsArchivePath())
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	for i, text := range []string{"Hello", "world"} {
+		var caption recordedCaption
+		require.NoError(t, json.Unmarshal([]byte(lines[i]), &caption))
+		require.Equal(t, "trackID", caption.TrackID)
+		require.Equal(t, "sessionID", caption.SessionID)
+		require.Equal(t, text, caption.Text)
+		require.GreaterOrEqual(t, caption.OffsetMs, int64(1000))
+		require.InDelta(t, time.Now().UnixMilli(), caption.Timestamp, float64(time.Minute.Milliseconds()))
+	}
+}
+
+func TestCaptionsFileMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captions.jsonl")
+	f := captionsFile{maxSize: 200}
+	defer f.close()
+
+	caption := recordedCaption{captionMsg: captionMsg{CaptionMsg: public.CaptionMsg{Text: "Hello"}}}
+	require.NoError(t, f.write(path, caption))
+	require.EqualError(t, f.write(path, caption), "captions file is full")
+	require.NoError(t, f.write(path, caption))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(data), "\n"))
+}
+
+func TestSendCaptionAcked(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.LiveCaptions.On = true
+	tr.cfg.LiveCaptions.Ack = true
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	ctx := trackContext{
+		trackID:   "trackID",
+		sessionID: "sessionID",
+	}
+	msg := captionMsg{
+		CaptionMsg: public.CaptionMsg{
+			SessionID: ctx.sessionID,
+			Text:      "Hello",
+		},
+	}
+	payload, err := json.Marshal(&msg)
+	require.NoError(t, err)
+
+	captionsURL := "http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/captions"
+
+	t.Run("acknowledged", func(t *testing.T) {
+		mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, payload, "").
+			Return(&http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil).Once()
+
+		require.NoError(t, tr.sendCaption(ctx, msg))
+		require.Equal(t, uint64(1), tr.captionsAcked.Load())
+		require.Zero(t, tr.captionsUnacked.Load())
+	})
+
+	t.Run("acknowledged after resend", func(t *testing.T) {
+		mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, payload, "").
+			Return(nil, fmt.Errorf("timeout")).Once()
+		mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, payload, "").
+			Return(&http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil).Once()
+
+		require.NoError(t, tr.sendCaption(ctx, msg))
+		require.Equal(t, uint64(2), tr.captionsAcked.Load())
+		require.Zero(t, tr.captionsUnacked.Load())
+	})
+
+	t.Run("never acknowledged", func(t *testing.T) {
+		mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, captionsURL, payload, "").
+			Return(nil, fmt.Errorf("timeout")).Twice()
+
+		err := tr.sendCaption(ctx, msg)
+		require.EqualError(t, err, "caption not acknowledged: request failed: timeout")
+		require.Equal(t, uint64(2), tr.captionsAcked.Load())
+		require.Equal(t, uint64(1), tr.captionsUnacked.Load())
+		require.Equal(t, uint64(1), tr.getRuntimeStats().CaptionsUnacked)
+	})
+}
+
+func TestCaptionsQueueStats(t *testing.T) {
+	var s captionsQueueStats
+
+	full, waitAvg, waitMax := s.get()
+	require.Zero(t, full)
+	require.Zero(t, waitAvg)
+	require.Zero(t, waitMax)
+
+	s.addServed(100 * time.Millisecond)
+	s.addServed(300 * time.Millisecond)
+	s.addFull()
+
+	full, waitAvg, waitMax = s.get()
+	require.Equal(t, uint64(1), full)
+	require.Equal(t, 200*time.Millisecond, waitAvg)
+	require.Equal(t, 300*time.Millisecond, waitMax)
+}
+
+type captionsTranscriberMock struct {
+	text       string
+	confidence float64
+	err        error
+}
+
+func (m *captionsTranscriberMock) Transcribe(_ []float32) ([]transcribe.Segment, string, error) {
+	if m.err != nil || m.text == "" {
+		return nil, "", m.err
+	}
+	return []transcribe.Segment{{Text: m.text, Confidence: m.confidence}}, "", nil
+}
+
+func (m *captionsTranscriberMock) Destroy() error {
+	return nil
+}
+
+func TestTranscribeCaptions(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	t.Run("multiple languages", func(t *testing.T) {
+		captions, err := tr.transcribeCaptions([]captionsTranscriber{
+			{Transcriber: &captionsTranscriberMock{text: "Hello", confidence: 0.9}, language: "en"},
+			{Transcriber: &captionsTranscriberMock{}, language: "de"},
+			{Transcriber: &captionsTranscriberMock{text: "Hola", confidence: 0.6}, language: "es"},
+		}, make([]float32, trackOutAudioRate))
+		require.NoError(t, err)
+		require.Equal(t, []captionText{
+			{language: "en", text: "Hello", confidence: 0.9},
+			{language: "es", text: "Hola", confidence: 0.6},
+		}, captions)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		captions, err := tr.transcribeCaptions([]captionsTranscriber{
+			{Transcriber: &captionsTranscriberMock{text: "Hello"}, language: "en"},
+			{Transcriber: &captionsTranscriberMock{err: fmt.Errorf("failed")}, language: "es"},
+		}, make([]float32, trackOutAudioRate))
+		require.EqualError(t, err, `failed to transcribe in "es": failed`)
+		require.Nil(t, captions)
+	})
+}
+
+func TestSendCaptionsPartial(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.LiveCaptions.On = true
+	tr.cfg.LiveCaptions.Shadow = true
+	tr.cfg.LiveCaptions.Partial = true
+
+	ctx := trackContext{
+		trackID:   "trackID",
+		sessionID: "sessionID",
+	}
+
+	captions := []captionText{
+		{language: "en", text: "Hello", confidence: 0.8},
+		{language: "es", text: "Hola"},
+	}
+	require.True(t, tr.sendCaptions(ctx, captions, 1000, true))
+	require.True(t, tr.sendCaptions(ctx, captions, 0, false))
+	tr.closeCaptionsFiles()
+
+	data, err := os.ReadFile(filepath.Join(getDataDir(), tr.cfg.TranscriptionID+"_captions.jsonl"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+
+	for i, line := range lines {
+		var caption map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &caption))
+		require.Equal(t, captions[i%2].text, caption["text"])
+		require.Equal(t, captions[i%2].language, caption["language"])
+		if conf := captions[i%2].confidence; conf > 0 {
+			require.Equal(t, conf, caption["confidence"])
+		} else {
+			require.NotContains(t, caption, "confidence")
+		}
+		if i < 2 {
+			require.Equal(t, true, caption["partial"])
+		} else {
+			// Final captions omit the field so that clients unaware of
+			// partial captions behave as before.
+			require.NotContains(t, caption, "partial")
+		}
+	}
+}
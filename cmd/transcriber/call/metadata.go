@@ -0,0 +1,43 @@
+package call
+
+import (
+	"fmt"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// modelName describes the model a transcription API runs, including its
+// size for the APIs running Whisper locally.
+func modelName(api config.TranscribeAPI, size config.ModelSize) string {
+	switch api {
+	case config.TranscribeAPIWhisperCPP, config.TranscribeAPIFasterWhisper:
+		return fmt.Sprintf("%s (%s)", api, size)
+	default:
+		return string(api)
+	}
+}
+
+// transcriptionMetadata returns the metadata written along with the files of
+// out when the metadata header is enabled.
+func (t *Transcriber) transcriptionMetadata(out transcriptionOutput) *transcribe.Metadata {
+	// In comparison mode outputs are labeled with the API that produced them.
+	api := t.cfg.Engine.TranscribeAPI
+	if out.label != "" {
+		api = config.TranscribeAPI(out.label)
+	}
+
+	lang := out.language
+	if lang == "" {
+		lang = out.tr.Language()
+	}
+
+	return &transcribe.Metadata{
+		Channel:      t.cfg.CallID,
+		StartTime:    t.startTime.Load(),
+		EndTime:      t.endTime.Load(),
+		Participants: out.tr.Speakers(),
+		Model:        modelName(api, t.cfg.Engine.ModelSize),
+		Language:     lang,
+	}
+}
@@ -0,0 +1,52 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptionMetadata(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Engine.TranscribeAPI = config.TranscribeAPIWhisperCPP
+	tr.cfg.Engine.ModelSize = config.ModelSizeMedium
+
+	out := transcriptionOutput{tr: transcribe.Transcription{
+		{Speaker: "Bob", Language: "fr"},
+		{Speaker: "Alice", Language: "fr"},
+	}}
+
+	t.Run("call not started", func(t *testing.T) {
+		require.Equal(t, &transcribe.Metadata{
+			Channel:      "8w8jorhr7j83uqr6y1st894hqe",
+			Participants: []string{"Bob", "Alice"},
+			Model:        "whisper.cpp (medium)",
+			Language:     "fr",
+		}, tr.transcriptionMetadata(out))
+	})
+
+	t.Run("call ended", func(t *testing.T) {
+		startTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+		endTime := startTime.Add(time.Hour)
+		tr.startTime.Store(&startTime)
+		tr.endTime.Store(&endTime)
+
+		meta := tr.transcriptionMetadata(out)
+		require.Equal(t, &startTime, meta.StartTime)
+		require.Equal(t, &endTime, meta.EndTime)
+	})
+
+	t.Run("comparison output", func(t *testing.T) {
+		meta := tr.transcriptionMetadata(transcriptionOutput{
+			tr:       out.tr,
+			label:    config.TranscribeAPIAzure,
+			language: "it",
+		})
+		require.Equal(t, "azure", meta.Model)
+		require.Equal(t, "it", meta.Language)
+	})
+}
@@ -0,0 +1,151 @@
+package call
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	dialTimeout    = 10 * time.Second
+	dnsDialTimeout = 5 * time.Second
+	dnsDefaultPort = "53"
+)
+
+// netDialer establishes the connections to the Mattermost installation,
+// honoring the configured IP family and DNS resolvers so that failures in
+// dual-stack environments can be told apart.
+type netDialer struct {
+	cfg      config.NetworkConfig
+	dialer   *net.Dialer
+	resolver *net.Resolver
+}
+
+func newNetDialer(cfg config.NetworkConfig) *netDialer {
+	d := &netDialer{
+		cfg:      cfg,
+		resolver: net.DefaultResolver,
+	}
+
+	if len(cfg.DNSServers) > 0 {
+		var next atomic.Uint32
+		d.resolver = &net.Resolver{
+			PreferGo: true,
+			// Queries are spread across the configured servers.
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				server := cfg.DNSServers[int(next.Add(1)-1)%len(cfg.DNSServers)]
+				if _, _, err := net.SplitHostPort(server); err != nil {
+					server = net.JoinHostPort(server, dnsDefaultPort)
+				}
+				dialer := net.Dialer{Timeout: dnsDialTimeout}
+				return dialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	d.dialer = &net.Dialer{
+		Timeout:  dialTimeout,
+		Resolver: d.resolver,
+	}
+
+	return d
+}
+
+// network returns the network to dial, restricted to the configured family.
+func (d *netDialer) network(network string) string {
+	if network != "tcp" {
+		return network
+	}
+
+	switch d.cfg.IPFamily {
+	case config.IPFamilyIPv4:
+		return "tcp4"
+	case config.IPFamilyIPv6:
+		return "tcp6"
+	default:
+		return network
+	}
+}
+
+func (d *netDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	network = d.network(network)
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s over %s: %w", addr, network, err)
+	}
+
+	slog.Debug("connected",
+		slog.String("addr", addr),
+		slog.String("network", network),
+		slog.String("remote_addr", conn.RemoteAddr().String()))
+
+	return conn, nil
+}
+
+// resolve returns the addresses host resolves to for the configured family.
+func (d *netDialer) resolve(ctx context.Context, host string) ([]net.IP, error) {
+	network := "ip"
+	switch d.cfg.IPFamily {
+	case config.IPFamilyIPv4:
+		network = "ip4"
+	case config.IPFamilyIPv6:
+		network = "ip6"
+	}
+
+	return d.resolver.LookupIP(ctx, network, host)
+}
+
+// logResolvedEndpoints logs the addresses the site URL resolves to, which is
+// where both the API and rtcd signaling connections are made.
+func (d *netDialer) logResolvedEndpoints(siteURL string) {
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		slog.Error("failed to parse site URL", slog.String("err", err.Error()))
+		return
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancelFn()
+
+	ips, err := d.resolve(ctx, u.Hostname())
+	if err != nil {
+		slog.Error("failed to resolve site URL host",
+			slog.String("host", u.Hostname()),
+			slog.String("ip_family", string(d.cfg.IPFamily)),
+			slog.String("err", err.Error()))
+		return
+	}
+
+	addrs := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		addrs = append(addrs, ip.String())
+	}
+
+	slog.Info("resolved site URL host",
+		slog.String("host", u.Hostname()),
+		slog.String("ip_family", string(d.cfg.IPFamily)),
+		slog.Any("addrs", addrs))
+}
+
+// httpClient returns a client for API requests going through the dialer.
+func (d *netDialer) httpClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = d.DialContext
+	return &http.Client{Transport: transport}
+}
+
+// setWebSocketDialer makes the rtcd signaling connection go through the
+// dialer. The rtcd client doesn't expose its own so the default WebSocket one
+// is overridden, which is fine since there's a single transcriber per process.
+func setWebSocketDialer(d *netDialer) {
+	websocket.DefaultDialer.NetDialContext = d.DialContext
+}
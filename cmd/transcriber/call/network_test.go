@@ -0,0 +1,110 @@
+package call
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetDialerNetwork(t *testing.T) {
+	tcs := []struct {
+		name     string
+		family   config.IPFamily
+		network  string
+		expected string
+	}{
+		{
+			name:     "any",
+			network:  "tcp",
+			expected: "tcp",
+		},
+		{
+			name:     "ipv4",
+			family:   config.IPFamilyIPv4,
+			network:  "tcp",
+			expected: "tcp4",
+		},
+		{
+			name:     "ipv6",
+			family:   config.IPFamilyIPv6,
+			network:  "tcp",
+			expected: "tcp6",
+		},
+		{
+			name:     "explicit network",
+			family:   config.IPFamilyIPv6,
+			network:  "tcp4",
+			expected: "tcp4",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newNetDialer(config.NetworkConfig{IPFamily: tc.family})
+			require.Equal(t, tc.expected, d.network(tc.network))
+		})
+	}
+}
+
+func TestNetDialerDialContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer ts.Close()
+	addr := ts.Listener.Addr().String()
+
+	t.Run("ipv4", func(t *testing.T) {
+		d := newNetDialer(config.NetworkConfig{IPFamily: config.IPFamilyIPv4})
+		conn, err := d.DialContext(context.Background(), "tcp", addr)
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		resp, err := d.httpClient().Get(ts.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("ipv6", func(t *testing.T) {
+		// The test server only listens on IPv4.
+		d := newNetDialer(config.NetworkConfig{IPFamily: config.IPFamilyIPv6})
+		_, err := d.DialContext(context.Background(), "tcp", addr)
+		require.ErrorContains(t, err, "failed to connect to "+addr+" over tcp6")
+	})
+}
+
+func TestNetDialerDNSServers(t *testing.T) {
+	d := newNetDialer(config.NetworkConfig{
+		DNSServers: []string{"127.0.0.2", "127.0.0.3:5353"},
+	})
+	require.NotEqual(t, net.DefaultResolver, d.resolver)
+	require.Equal(t, d.resolver, d.dialer.Resolver)
+
+	// Queries alternate between the configured servers, using the default
+	// port if none is given.
+	for _, expected := range []string{"127.0.0.2:53", "127.0.0.3:5353", "127.0.0.2:53"} {
+		conn, err := d.resolver.Dial(context.Background(), "udp", "ignored:53")
+		require.NoError(t, err)
+		require.Equal(t, expected, conn.RemoteAddr().String())
+		require.NoError(t, conn.Close())
+	}
+
+	t.Run("ip literal", func(t *testing.T) {
+		ips, err := d.resolve(context.Background(), "127.0.0.1")
+		require.NoError(t, err)
+		require.Len(t, ips, 1)
+		require.Equal(t, "127.0.0.1", ips[0].String())
+	})
+
+	t.Run("family mismatch", func(t *testing.T) {
+		d := newNetDialer(config.NetworkConfig{IPFamily: config.IPFamilyIPv6})
+		_, err := d.resolve(context.Background(), "127.0.0.1")
+		require.Error(t, err)
+	})
+}
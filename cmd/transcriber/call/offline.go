@@ -0,0 +1,207 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// OfflineTrack is a pre-recorded audio file holding the voice of a single
+// speaker. OGG/Opus and WAV files are decoded natively while other formats
+// (e.g. MP3, M4A or the MKA/MP4 containers from calls-recorder) are decoded
+// through ffmpeg. Audio is downmixed to mono and resampled as needed.
+type OfflineTrack struct {
+	Path string
+	// Speaker defaults to the file name, without extension.
+	Speaker string
+	// Offset is when the track starts relative to the beginning of the call.
+	Offset time.Duration
+}
+
+// ParseOfflineTrack parses a track argument in the form
+// path[:speaker[:offset]], where offset is a duration (e.g. "1m30s").
+func ParseOfflineTrack(arg string) (OfflineTrack, error) {
+	parts := strings.SplitN(arg, ":", 3)
+
+	track := OfflineTrack{
+		Path: parts[0],
+	}
+	if track.Path == "" {
+		return track, fmt.Errorf("path should not be empty")
+	}
+
+	if len(parts) > 1 {
+		track.Speaker = strings.TrimSpace(parts[1])
+	}
+	if track.Speaker == "" {
+		track.Speaker = strings.TrimSuffix(filepath.Base(track.Path), filepath.Ext(track.Path))
+	}
+
+	if len(parts) > 2 {
+		offset, err := time.ParseDuration(parts[2])
+		if err != nil {
+			return track, fmt.Errorf("failed to parse offset: %w", err)
+		}
+		if offset < 0 {
+			return track, fmt.Errorf("offset should not be negative")
+		}
+		track.Offset = offset
+	}
+
+	return track, nil
+}
+
+// TranscribeFiles runs the same speech detection and transcription pipeline
+// used for calls over pre-recorded tracks, writing the resulting files
+// (in the configured formats, and JSON) named after fname in outDir. No Mattermost
+// installation is needed, only the engine and output settings of cfg are
+// used. The paths of the written files are returned.
+func TranscribeFiles(cfg config.CallTranscriberConfig, tracks []OfflineTrack, outDir, fname string) ([]string, error) {
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no tracks to transcribe")
+	}
+	if err := cfg.Engine.IsValid(); err != nil {
+		return nil, fmt.Errorf("invalid engine config: %w", err)
+	}
+	if err := cfg.Output.IsValid(); err != nil {
+		return nil, fmt.Errorf("invalid output config: %w", err)
+	}
+
+	t := &Transcriber{
+		cfg:           cfg,
+		speakerColors: make(map[string]int),
+		monoNow:       newMonotonicClock(),
+	}
+	t.trPool = newTranscriberPool(transcriberPoolMaxSize, t.newTrackTranscriber)
+	t.resultCache = t.newResultCache()
+	defer t.trPool.close()
+	defer t.stopFasterWhisperSidecar()
+
+	if re, _ := cfg.Engine.TranscribeAPIOptions["OUTPUT_FILTER_REGEX"].(string); re != "" {
+		// Already validated above.
+		t.outputFilterRE = regexp.MustCompile(re)
+	}
+
+	var tr transcribe.Transcription
+	ctxs := make([]trackContext, 0, len(tracks))
+	for i, track := range tracks {
+		slog.Info("transcribing file",
+			slog.String("path", track.Path),
+			slog.String("speaker", track.Speaker),
+			slog.Duration("offset", track.Offset))
+
+		if _, err := os.Stat(track.Path); err != nil {
+			return nil, fmt.Errorf("failed to stat track file: %w", err)
+		}
+
+		ctx := trackContext{
+			trackID:    fmt.Sprintf("file-%d", i),
+			sessionID:  track.Speaker,
+			filename:   track.Path,
+			startTS:    track.Offset.Milliseconds(),
+			user:       &model.User{Username: track.Speaker},
+			colorIndex: t.getSpeakerColorIndex(track.Speaker),
+		}
+
+		trackTr, _, err := t.transcribeTrack(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transcribe %s: %w", track.Path, err)
+		}
+		ctxs = append(ctxs, ctx)
+
+		if len(trackTr.Segments) > 0 {
+			tr = append(tr, trackTr)
+		}
+	}
+
+	if len(tr) == 0 {
+		slog.Warn("nothing to do, empty transcription")
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(outDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var tm transcribe.TimeMap
+	if cfg.Output.TrimSilenceMs > 0 {
+		tr, tm = tr.TrimSilences(int64(cfg.Output.TrimSilenceMs))
+	}
+
+	var meta *transcribe.Metadata
+	if cfg.Output.MetadataHeader {
+		meta = t.transcriptionMetadata(transcriptionOutput{tr: tr})
+	}
+
+	// The JSON file is always written, for further processing.
+	formats := cfg.Output.Format.Formats()
+	if !slices.Contains(formats, config.OutputFormatJSON) {
+		formats = append(formats, config.OutputFormatJSON)
+	}
+
+	paths, err := writeTranscriptionFiles(outDir, fname, tr, formats, cfg.Output.Options, meta, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Output.VADSegments {
+		vadPath, err := writeVADSegmentsFile(outDir, fname, t.getVADSegments(ctxs))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, vadPath)
+	}
+
+	if tm != nil {
+		tmPath, err := writeTimeMapFile(outDir, fname, cfg.Output.TrimSilenceMs, tm)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, tmPath)
+	}
+
+	return paths, nil
+}
+
+// transcriptionJSON is the JSON transcription when the metadata header is
+// enabled.
+type transcriptionJSON struct {
+	Metadata *transcribe.Metadata      `json:"metadata"`
+	Segments []transcribe.NamedSegment `json:"segments"`
+}
+
+// writeTranscriptionJSON saves the interleaved segments of the transcription
+// as JSON, for further processing by other tools. If meta is set, the
+// segments are saved along with it as an object instead.
+func writeTranscriptionJSON(dir, fname string, tr transcribe.Transcription, meta *transcribe.Metadata) (string, error) {
+	var v any = tr.Interleave()
+	if meta != nil {
+		v = transcriptionJSON{
+			Metadata: meta,
+			Segments: tr.Interleave(),
+		}
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal transcription: %w", err)
+	}
+
+	path := filepath.Join(dir, fname+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write JSON file: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,317 @@
+package call
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOfflineTrack(t *testing.T) {
+	tcs := []struct {
+		name          string
+		arg           string
+		expectedTrack OfflineTrack
+		expectedErr   string
+	}{
+		{
+			name:        "empty",
+			arg:         "",
+			expectedErr: "path should not be empty",
+		},
+		{
+			name: "path only",
+			arg:  "/recs/alice.ogg",
+			expectedTrack: OfflineTrack{
+				Path:    "/recs/alice.ogg",
+				Speaker: "alice",
+			},
+		},
+		{
+			name: "speaker",
+			arg:  "/recs/track1.wav:Alice Smith",
+			expectedTrack: OfflineTrack{
+				Path:    "/recs/track1.wav",
+				Speaker: "Alice Smith",
+			},
+		},
+		{
+			name: "empty speaker with offset",
+			arg:  "/recs/bob.ogg::1m30s",
+			expectedTrack: OfflineTrack{
+				Path:    "/recs/bob.ogg",
+				Speaker: "bob",
+				Offset:  90 * time.Second,
+			},
+		},
+		{
+			name:        "invalid offset",
+			arg:         "/recs/bob.ogg:Bob:soon",
+			expectedErr: "failed to parse offset: time: invalid duration \"soon\"",
+		},
+		{
+			name:        "negative offset",
+			arg:         "/recs/bob.ogg:Bob:-1s",
+			expectedErr: "offset should not be negative",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			track, err := ParseOfflineTrack(tc.arg)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedTrack, track)
+		})
+	}
+}
+
+func writeTestWAV(t *testing.T, path string, sampleRate, channels int, samples []int16) {
+	t.Helper()
+
+	var data bytes.Buffer
+	for _, s := range samples {
+		require.NoError(t, binary.Write(&data, binary.LittleEndian, s))
+	}
+
+	var fmtChunk bytes.Buffer
+	for _, v := range []any{
+		uint16(wavFormatPCM),
+		uint16(channels),
+		uint32(sampleRate),
+		uint32(sampleRate * channels * 2),
+		uint16(channels * 2),
+		uint16(16),
+	} {
+		require.NoError(t, binary.Write(&fmtChunk, binary.LittleEndian, v))
+	}
+
+	writeTestWAVChunks(t, path, fmtChunk.Bytes(), data.Bytes())
+}
+
+func writeTestWAVChunks(t *testing.T, path string, fmtChunk, data []byte) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(4+8+len(fmtChunk)+8+4+8+len(data))))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(len(fmtChunk))))
+	buf.Write(fmtChunk)
+	// An unrelated chunk with odd size, which should be skipped along with
+	// its padding.
+	buf.WriteString("LIST")
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(3)))
+	buf.Write([]byte{1, 2, 3, 0})
+	buf.WriteString("data")
+	require.NoError(t, binary.Write(&buf, binary.LittleEndian, uint32(len(data))))
+	buf.Write(data)
+
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0600))
+}
+
+func TestDecodeWAV(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.wav")
+		require.NoError(t, os.WriteFile(path, []byte("not a wav file"), 0600))
+		_, err := decodeWAV(path)
+		require.EqualError(t, err, "not a WAV file")
+	})
+
+	t.Run("mono 16KHz", func(t *testing.T) {
+		path := filepath.Join(dir, "mono.wav")
+		writeTestWAV(t, path, 16000, 1, []int16{0, 16384, -16384, 32767})
+		pcm, err := decodeWAV(path)
+		require.NoError(t, err)
+		require.Equal(t, []float32{0, 0.5, -0.5, 32767.0 / 32768}, pcm)
+	})
+
+	t.Run("stereo 48KHz", func(t *testing.T) {
+		path := filepath.Join(dir, "stereo.wav")
+		samples := make([]int16, 48000*2)
+		for i := 0; i < len(samples); i += 2 {
+			samples[i] = 16384
+			samples[i+1] = 0
+		}
+		writeTestWAV(t, path, 48000, 2, samples)
+		pcm, err := decodeWAV(path)
+		require.NoError(t, err)
+		require.Len(t, pcm, trackOutAudioRate)
+		for _, s := range pcm {
+			require.Equal(t, float32(0.25), s)
+		}
+	})
+}
+
+func TestDecodeWAVData(t *testing.T) {
+	tcs := []struct {
+		name        string
+		format      wavFormat
+		data        []byte
+		expected    []float32
+		expectedErr string
+	}{
+		{
+			name:     "8-bit",
+			format:   wavFormat{audioFormat: wavFormatPCM, channels: 1, sampleRate: 8000, bitsPerSample: 8},
+			data:     []byte{128, 192, 64, 0},
+			expected: []float32{0, 0.5, -0.5, -1},
+		},
+		{
+			name:     "24-bit",
+			format:   wavFormat{audioFormat: wavFormatPCM, channels: 1, sampleRate: 16000, bitsPerSample: 24},
+			data:     []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0xc0, 0x00, 0x00, 0x80},
+			expected: []float32{0, 0.5, -0.5, -1},
+		},
+		{
+			name:     "32-bit",
+			format:   wavFormat{audioFormat: wavFormatPCM, channels: 1, sampleRate: 16000, bitsPerSample: 32},
+			data:     []byte{0x00, 0x00, 0x00, 0x40, 0x00, 0x00, 0x00, 0xc0},
+			expected: []float32{0.5, -0.5},
+		},
+		{
+			name:     "64-bit float stereo",
+			format:   wavFormat{audioFormat: wavFormatFloat, channels: 2, sampleRate: 16000, bitsPerSample: 64},
+			data:     binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, math.Float64bits(0.5)), math.Float64bits(0)),
+			expected: []float32{0.25},
+		},
+		{
+			name:        "A-law",
+			format:      wavFormat{audioFormat: 6, channels: 1, sampleRate: 8000, bitsPerSample: 8},
+			data:        []byte{0xd5},
+			expectedErr: "unsupported WAV encoding (format=6, bits=8)",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			samples, err := decodeWAVData(bytes.NewReader(tc.data), tc.format)
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+				require.ErrorIs(t, err, errUnsupportedWAVEncoding)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, samples)
+		})
+	}
+}
+
+func TestDecodeAudioWAV(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("extensible", func(t *testing.T) {
+		fmtChunk := make([]byte, 40)
+		binary.LittleEndian.PutUint16(fmtChunk[0:2], wavFormatExtensible)
+		binary.LittleEndian.PutUint16(fmtChunk[2:4], 1)
+		binary.LittleEndian.PutUint32(fmtChunk[4:8], 16000)
+		binary.LittleEndian.PutUint16(fmtChunk[14:16], 32)
+		binary.LittleEndian.PutUint16(fmtChunk[16:18], 22)
+		binary.LittleEndian.PutUint16(fmtChunk[24:26], wavFormatFloat)
+
+		path := filepath.Join(dir, "extensible.wav")
+		writeTestWAVChunks(t, path, fmtChunk, binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.5)))
+
+		samples, err := decodeTrackAudio(trackContext{filename: path})
+		require.NoError(t, err)
+		require.Equal(t, []trackTimedSamples{{pcm: []float32{0.5}}}, samples)
+	})
+
+	t.Run("ffmpeg fallback", func(t *testing.T) {
+		argsPath := setupFakeFFmpeg(t, []float32{0.25}, 0)
+
+		fmtChunk := make([]byte, 16)
+		binary.LittleEndian.PutUint16(fmtChunk[0:2], 6)
+		binary.LittleEndian.PutUint16(fmtChunk[2:4], 1)
+		binary.LittleEndian.PutUint32(fmtChunk[4:8], 8000)
+		binary.LittleEndian.PutUint16(fmtChunk[14:16], 8)
+
+		path := filepath.Join(dir, "alaw.wav")
+		writeTestWAVChunks(t, path, fmtChunk, []byte{0xd5})
+
+		samples, err := decodeTrackAudio(trackContext{filename: path})
+		require.NoError(t, err)
+		require.Equal(t, []trackTimedSamples{{pcm: []float32{0.25}}}, samples)
+
+		args, err := os.ReadFile(argsPath)
+		require.NoError(t, err)
+		require.Contains(t, string(args), "-i "+path)
+	})
+}
+
+func TestWriteTranscriptionJSON(t *testing.T) {
+	tr := transcribe.Transcription{
+		{
+			Speaker:    "Bob",
+			ColorIndex: 1,
+			Segments: []transcribe.Segment{
+				{Text: "Hi Alice", StartTS: 2000, EndTS: 3000},
+			},
+		},
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello", StartTS: 0, EndTS: 1000},
+			},
+		},
+	}
+
+	t.Run("segments", func(t *testing.T) {
+		path, err := writeTranscriptionJSON(t.TempDir(), "call", tr, nil)
+		require.NoError(t, err)
+		require.Equal(t, "call.json", filepath.Base(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var segments []transcribe.NamedSegment
+		require.NoError(t, json.Unmarshal(data, &segments))
+		require.Equal(t, tr.Interleave(), segments)
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		meta := &transcribe.Metadata{
+			Participants: tr.Speakers(),
+			Model:        "whisper.cpp (medium)",
+			Language:     "en",
+		}
+		path, err := writeTranscriptionJSON(t.TempDir(), "call", tr, meta)
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var out transcriptionJSON
+		require.NoError(t, json.Unmarshal(data, &out))
+		require.Equal(t, meta, out.Metadata)
+		require.Equal(t, tr.Interleave(), out.Segments)
+	})
+}
+
+func TestTranscribeFiles(t *testing.T) {
+	var cfg config.CallTranscriberConfig
+	cfg.SetDefaults()
+
+	t.Run("no tracks", func(t *testing.T) {
+		_, err := TranscribeFiles(cfg, nil, t.TempDir(), "call")
+		require.EqualError(t, err, "no tracks to transcribe")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := TranscribeFiles(cfg, []OfflineTrack{{Path: "missing.ogg", Speaker: "Alice"}}, t.TempDir(), "call")
+		require.ErrorContains(t, err, "failed to stat track file")
+	})
+}
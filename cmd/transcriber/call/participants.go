@@ -0,0 +1,183 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/voiceprint"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+// participant is an entry of the participants JSON artifact. Sessions whose
+// tracks never produced any audio (e.g. never unmuted) are still listed, with
+// zero speech duration, so that the transcript carries an explicit record of
+// everyone observed on the call.
+type participant struct {
+	SessionID        string `json:"session_id"`
+	UserID           string `json:"user_id"`
+	Username         string `json:"username"`
+	Speaker          string `json:"speaker"`
+	SpeechDurationMs int64  `json:"speech_duration_ms"`
+	Segments         int    `json:"segments"`
+	// SpeakerEmbedding is an opaque voice-print computed from all of the
+	// session's speech. It's only set if speaker embeddings are enabled and
+	// enough speech was detected.
+	SpeakerEmbedding string `json:"speaker_embedding,omitempty"`
+	// TranscriptionOptOut is set if the participant declined being
+	// transcribed, in which case none of their speech was captured.
+	TranscriptionOptOut bool `json:"transcription_opt_out,omitempty"`
+	// Bot is set if the participant is a bot account (e.g. an integration).
+	Bot bool `json:"bot,omitempty"`
+}
+
+// botSpeakerLabel tags the name of bot participants in transcriptions.
+const botSpeakerLabel = "[bot]"
+
+// getSpeakerName returns the name the given user is attributed speech under.
+func (t *Transcriber) getSpeakerName(user *model.User) string {
+	name := user.GetDisplayName(model.ShowFullName)
+	if user.IsBot && t.cfg.Capture.BotTracks == config.BotTracksLabel {
+		name += " " + botSpeakerLabel
+	}
+	return name
+}
+
+// trackVoiceprint holds the voice-print stats computed from a track's speech.
+type trackVoiceprint struct {
+	sessionID string
+	stats     *voiceprint.Stats
+}
+
+type participantsArtifact struct {
+	Participants []participant `json:"participants"`
+}
+
+// addParticipant records that the given session was observed on the call.
+// Participants are kept in order of appearance.
+func (t *Transcriber) addParticipant(sessionID string, user *model.User) {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	for _, p := range t.participants {
+		if p.SessionID == sessionID {
+			return
+		}
+	}
+
+	t.participants = append(t.participants, participant{
+		SessionID: sessionID,
+		UserID:    user.Id,
+		Username:  user.Username,
+		Speaker:   t.getSpeakerName(user),
+		Bot:       user.IsBot,
+	})
+}
+
+// setParticipantOptOut records that the given session declined being
+// transcribed.
+func (t *Transcriber) setParticipantOptOut(sessionID string) {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	for i := range t.participants {
+		if t.participants[i].SessionID == sessionID {
+			t.participants[i].TranscriptionOptOut = true
+		}
+	}
+}
+
+// getOptOutNote returns the annotation for the published transcription
+// listing the participants who declined being transcribed, or an empty
+// string if none did.
+func (t *Transcriber) getOptOutNote() string {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	var speakers []string
+	for _, p := range t.participants {
+		if p.TranscriptionOptOut && !slices.Contains(speakers, p.Speaker) {
+			speakers = append(speakers, p.Speaker)
+		}
+	}
+
+	if len(speakers) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("Not transcribed at their request: %s.", strings.Join(speakers, ", "))
+}
+
+// getParticipants returns the list of observed participants with speech
+// stats computed from the given per-session track transcriptions.
+func (t *Transcriber) getParticipants(sessionTrs map[string][]transcribe.TrackTranscription) []participant {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	participants := make([]participant, len(t.participants))
+	for i, p := range t.participants {
+		for _, trackTr := range sessionTrs[p.SessionID] {
+			for _, s := range trackTr.Segments {
+				p.SpeechDurationMs += s.EndTS - s.StartTS
+				p.Segments++
+			}
+		}
+
+		// Sessions can have multiple tracks (e.g. after reconnecting), all of
+		// which contribute to the same voice-print.
+		var stats voiceprint.Stats
+		for _, vp := range t.voiceprints {
+			if vp.sessionID == p.SessionID {
+				stats.Merge(vp.stats)
+			}
+		}
+		p.SpeakerEmbedding = stats.Encode()
+
+		participants[i] = p
+	}
+
+	return participants
+}
+
+// setTrackVoiceprint records the voice-print stats computed from the given
+// track's speech.
+func (t *Transcriber) setTrackVoiceprint(trackID, sessionID string, stats *voiceprint.Stats) {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+
+	if t.voiceprints == nil {
+		t.voiceprints = make(map[string]trackVoiceprint)
+	}
+	t.voiceprints[trackID] = trackVoiceprint{sessionID: sessionID, stats: stats}
+}
+
+// getTrackVoiceprint returns the voice-print stats of the given track, if
+// computed.
+func (t *Transcriber) getTrackVoiceprint(trackID string) *voiceprint.Stats {
+	t.participantsMut.Lock()
+	defer t.participantsMut.Unlock()
+	return t.voiceprints[trackID].stats
+}
+
+// writeParticipantsFile saves the participants JSON artifact in the data
+// directory and returns its path.
+func writeParticipantsFile(fname string, participants []participant) (string, error) {
+	path := filepath.Join(getDataDir(), fname+"-participants.json")
+
+	data, err := json.MarshalIndent(participantsArtifact{Participants: participants}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode participants: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write participants file: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,157 @@
+package call
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/voiceprint"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParticipants(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	tr.addParticipant("sessionA", &model.User{Id: "userA", Username: "usera", FirstName: "User", LastName: "A"})
+	tr.addParticipant("sessionB", &model.User{Id: "userB", Username: "userb"})
+	// Sessions are only recorded once.
+	tr.addParticipant("sessionA", &model.User{Id: "userA", Username: "usera"})
+
+	participants := tr.getParticipants(map[string][]transcribe.TrackTranscription{
+		"sessionA": {
+			{
+				Speaker: "User A",
+				Segments: []transcribe.Segment{
+					{StartTS: 0, EndTS: 1000, Text: "A1"},
+					{StartTS: 2000, EndTS: 2500, Text: "A2"},
+				},
+			},
+			{
+				Speaker: "User A",
+				Segments: []transcribe.Segment{
+					{StartTS: 4000, EndTS: 5000, Text: "A3"},
+				},
+			},
+		},
+	})
+
+	expected := []participant{
+		{
+			SessionID:        "sessionA",
+			UserID:           "userA",
+			Username:         "usera",
+			Speaker:          "User A",
+			SpeechDurationMs: 2500,
+			Segments:         3,
+		},
+		{
+			SessionID: "sessionB",
+			UserID:    "userB",
+			Username:  "userb",
+			Speaker:   "userb",
+		},
+	}
+	require.Equal(t, expected, participants)
+
+	path, err := writeParticipantsFile("Call_Test", participants)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(getDataDir(), "Call_Test-participants.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var artifact participantsArtifact
+	require.NoError(t, json.Unmarshal(data, &artifact))
+	require.Equal(t, expected, artifact.Participants)
+}
+
+func TestSpeakerName(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	user := &model.User{Id: "userA", Username: "usera", FirstName: "User", LastName: "A"}
+	bot := &model.User{Id: "botA", Username: "recorder", FirstName: "Recorder", IsBot: true}
+
+	require.Equal(t, "User A", tr.getSpeakerName(user))
+	require.Equal(t, "Recorder [bot]", tr.getSpeakerName(bot))
+
+	tr.addParticipant("sessionA", bot)
+	participants := tr.getParticipants(nil)
+	require.Equal(t, []participant{
+		{
+			SessionID: "sessionA",
+			UserID:    "botA",
+			Username:  "recorder",
+			Speaker:   "Recorder [bot]",
+			Bot:       true,
+		},
+	}, participants)
+
+	tr.cfg.Capture.BotTracks = config.BotTracksKeep
+	require.Equal(t, "Recorder", tr.getSpeakerName(bot))
+}
+
+func TestParticipantsSpeakerEmbedding(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	tr.addParticipant("sessionA", &model.User{Id: "userA", Username: "usera"})
+	tr.addParticipant("sessionB", &model.User{Id: "userB", Username: "userb"})
+
+	rnd := rand.New(rand.NewSource(1))
+	genStats := func(dur int) *voiceprint.Stats {
+		pcm := make([]float32, dur*trackOutAudioRate)
+		for i := range pcm {
+			pcm[i] = float32(0.1 * rnd.NormFloat64())
+		}
+		stats := &voiceprint.Stats{}
+		stats.Add(pcm)
+		return stats
+	}
+
+	// Neither track has enough speech on its own, but combined they do.
+	trackA1, trackA2 := genStats(1), genStats(1)
+	tr.setTrackVoiceprint("trackA1", "sessionA", trackA1)
+	tr.setTrackVoiceprint("trackA2", "sessionA", trackA2)
+	require.Empty(t, trackA1.Encode())
+	require.Equal(t, trackA1, tr.getTrackVoiceprint("trackA1"))
+	require.Nil(t, tr.getTrackVoiceprint("trackB"))
+
+	tr.setTrackVoiceprint("trackB", "sessionB", genStats(1))
+
+	var combined voiceprint.Stats
+	combined.Merge(trackA1)
+	combined.Merge(trackA2)
+
+	participants := tr.getParticipants(nil)
+	require.Len(t, participants, 2)
+	require.NotEmpty(t, participants[0].SpeakerEmbedding)
+	require.Equal(t, combined.Encode(), participants[0].SpeakerEmbedding)
+	require.Empty(t, participants[1].SpeakerEmbedding)
+}
+
+func TestOptOutNote(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	require.Empty(t, tr.getOptOutNote())
+
+	tr.addParticipant("sessionA", &model.User{Id: "userA", Username: "usera", FirstName: "User", LastName: "A"})
+	tr.addParticipant("sessionB", &model.User{Id: "userB", Username: "userb"})
+	tr.addParticipant("sessionC", &model.User{Id: "userC", Username: "userc"})
+	tr.setParticipantOptOut("sessionA")
+	tr.setParticipantOptOut("sessionC")
+
+	require.Equal(t, "Not transcribed at their request: User A, userc.", tr.getOptOutNote())
+
+	reason := truncationReasonMaxTrackSize
+	tr.truncated.Store(&reason)
+	require.Equal(t, "Transcription truncated: maximum track size reached. Not transcribed at their request: User A, userc.", tr.getTranscriptionNote())
+
+	participants := tr.getParticipants(nil)
+	require.True(t, participants[0].TranscriptionOptOut)
+	require.False(t, participants[1].TranscriptionOptOut)
+}
@@ -0,0 +1,210 @@
+package call
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	postProcessTimeout = 2 * time.Minute
+	// postProcessMaxResponseSize bounds the transcript a hook can return.
+	postProcessMaxResponseSize = 64 << 20
+	// postProcessMaxStderrSize bounds how much of a failing command's output
+	// is logged.
+	postProcessMaxStderrSize = 4 << 10
+)
+
+// postProcessPayload is the transcript handed to the post-processing hook.
+// The hook is expected to return the same structure, with its tracks
+// modified as needed (e.g. redacted).
+type postProcessPayload struct {
+	CallID          string `json:"call_id"`
+	PostID          string `json:"post_id"`
+	TranscriptionID string `json:"transcription_id"`
+	// Label identifies the transcription when more than one is published
+	// for the call (e.g. in comparison mode).
+	Label  string             `json:"label,omitempty"`
+	Tracks []postProcessTrack `json:"tracks"`
+}
+
+type postProcessTrack struct {
+	Speaker    string               `json:"speaker"`
+	Language   string               `json:"language,omitempty"`
+	ColorIndex int                  `json:"color_index"`
+	Segments   []postProcessSegment `json:"segments"`
+}
+
+type postProcessSegment struct {
+	Text       string  `json:"text"`
+	StartTS    int64   `json:"start_ts"`
+	EndTS      int64   `json:"end_ts"`
+	Language   string  `json:"language,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+func newPostProcessTracks(tr transcribe.Transcription) []postProcessTrack {
+	tracks := make([]postProcessTrack, len(tr))
+	for i, trackTr := range tr {
+		tracks[i] = postProcessTrack{
+			Speaker:    trackTr.Speaker,
+			Language:   trackTr.Language,
+			ColorIndex: trackTr.ColorIndex,
+			Segments:   make([]postProcessSegment, len(trackTr.Segments)),
+		}
+		for j, s := range trackTr.Segments {
+			tracks[i].Segments[j] = postProcessSegment(s)
+		}
+	}
+	return tracks
+}
+
+func postProcessTracksToTranscription(tracks []postProcessTrack) (transcribe.Transcription, error) {
+	tr := make(transcribe.Transcription, len(tracks))
+	for i, track := range tracks {
+		tr[i] = transcribe.TrackTranscription{
+			Speaker:    track.Speaker,
+			Language:   track.Language,
+			ColorIndex: track.ColorIndex,
+			Segments:   make([]transcribe.Segment, len(track.Segments)),
+		}
+		for j, s := range track.Segments {
+			if s.StartTS < 0 || s.EndTS < s.StartTS {
+				return nil, fmt.Errorf("invalid segment timestamps %d-%d", s.StartTS, s.EndTS)
+			}
+			tr[i].Segments[j] = transcribe.Segment(s)
+		}
+	}
+	return tr, nil
+}
+
+// postProcessOutputs runs the configured post-processing hook, if any, over
+// every output, replacing their transcription with the one returned. Any
+// failure fails the job rather than publishing a transcription the hook
+// didn't get to process (e.g. unredacted).
+func (t *Transcriber) postProcessOutputs(outputs []transcriptionOutput) error {
+	var run func(ctx context.Context, data []byte) ([]byte, error)
+	switch {
+	case t.cfg.Publish.PostProcessCommand != "":
+		run = t.runPostProcessCommand
+	case t.cfg.Publish.PostProcessWebhookURL != "":
+		run = t.postPostProcessWebhook
+	default:
+		return nil
+	}
+
+	for i, out := range outputs {
+		data, err := json.Marshal(postProcessPayload{
+			CallID:          t.cfg.CallID,
+			PostID:          t.cfg.PostID,
+			TranscriptionID: t.cfg.TranscriptionID,
+			Label:           out.label,
+			Tracks:          newPostProcessTracks(out.tr),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %w", err)
+		}
+
+		ctx, cancelCtx := context.WithTimeout(context.Background(), postProcessTimeout)
+		data, err = run(ctx, data)
+		cancelCtx()
+		if err != nil {
+			return err
+		}
+
+		var res postProcessPayload
+		if err := json.Unmarshal(data, &res); err != nil {
+			return fmt.Errorf("failed to unmarshal hook response: %w", err)
+		}
+
+		outputs[i].tr, err = postProcessTracksToTranscription(res.Tracks)
+		if err != nil {
+			return fmt.Errorf("invalid hook response: %w", err)
+		}
+	}
+
+	slog.Debug("transcription post-processed", slog.Int("outputs", len(outputs)))
+
+	return nil
+}
+
+// runPostProcessCommand runs the post-processing command, passing the
+// payload on its standard input and returning its standard output. The
+// command doesn't inherit the job's environment since it holds credentials.
+func (t *Transcriber) runPostProcessCommand(ctx context.Context, data []byte) ([]byte, error) {
+	args := strings.Fields(t.cfg.Publish.PostProcessCommand)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "DATA_DIR=" + getDataDir()}
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		errOut := stderr.String()
+		if len(errOut) > postProcessMaxStderrSize {
+			errOut = errOut[:postProcessMaxStderrSize]
+		}
+		slog.Error("post-processing command failed", slog.String("stderr", errOut))
+		return nil, fmt.Errorf("post-processing command failed: %w", err)
+	}
+
+	if stdout.Len() > postProcessMaxResponseSize {
+		return nil, fmt.Errorf("post-processing command output is too large")
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// postPostProcessWebhook sends the payload to the post-processing webhook,
+// signed as the completion webhook ones, and returns the response body.
+func (t *Transcriber) postPostProcessWebhook(ctx context.Context, data []byte) ([]byte, error) {
+	signature := signWebhookPayload(t.cfg.Publish.PostProcessWebhookSecret, data)
+
+	var body []byte
+	err := retry(ctx, "postPostProcessWebhook", defaultRetryPolicy, func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Publish.PostProcessWebhookURL, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhookSignatureHeader, signature)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		body, err = io.ReadAll(io.LimitReader(resp.Body, postProcessMaxResponseSize+1))
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if len(body) > postProcessMaxResponseSize {
+			return fmt.Errorf("response is too large")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("post-processing webhook failed: %w", err)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,174 @@
+package call
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newPostProcessTestOutputs() []transcriptionOutput {
+	return []transcriptionOutput{
+		{
+			tr: transcribe.Transcription{
+				{
+					Speaker:    "User A",
+					Language:   "en",
+					ColorIndex: 1,
+					Segments: []transcribe.Segment{
+						{Text: "my card number is 1234", StartTS: 0, EndTS: 2000},
+						{Text: "thanks", StartTS: 3000, EndTS: 3500},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestPostProcessOutputs(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		outputs := newPostProcessTestOutputs()
+		require.NoError(t, tr.postProcessOutputs(outputs))
+		require.Equal(t, newPostProcessTestOutputs(), outputs)
+	})
+
+	t.Run("command", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		script := filepath.Join(t.TempDir(), "redact.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nsed 's/1234/[redacted]/g'\n"), 0700))
+		tr.cfg.Publish.PostProcessCommand = script
+
+		outputs := newPostProcessTestOutputs()
+		require.NoError(t, tr.postProcessOutputs(outputs))
+
+		expected := newPostProcessTestOutputs()
+		expected[0].tr[0].Segments[0].Text = "my card number is [redacted]"
+		require.Equal(t, expected, outputs)
+	})
+
+	t.Run("command failure", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		script := filepath.Join(t.TempDir(), "fail.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho failed >&2\nexit 1\n"), 0700))
+		tr.cfg.Publish.PostProcessCommand = script
+
+		outputs := newPostProcessTestOutputs()
+		err := tr.postProcessOutputs(outputs)
+		require.EqualError(t, err, "post-processing command failed: exit status 1")
+		require.Equal(t, newPostProcessTestOutputs(), outputs)
+	})
+
+	t.Run("command environment", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		t.Setenv("AUTH_TOKEN", "secret")
+
+		// The token would end up in the speaker name if it was leaked to the
+		// command.
+		script := filepath.Join(t.TempDir(), "env.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nsed \"s/User A/User A$AUTH_TOKEN/\"\n"), 0700))
+		tr.cfg.Publish.PostProcessCommand = script
+
+		outputs := newPostProcessTestOutputs()
+		require.NoError(t, tr.postProcessOutputs(outputs))
+		require.Equal(t, "User A", outputs[0].tr[0].Speaker)
+	})
+
+	t.Run("webhook", func(t *testing.T) {
+		var payloads []postProcessPayload
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.Equal(t, signWebhookPayload("secret", data), r.Header.Get(webhookSignatureHeader))
+
+			var p postProcessPayload
+			require.NoError(t, json.Unmarshal(data, &p))
+			payloads = append(payloads, p)
+
+			res := p
+			res.Tracks = []postProcessTrack{p.Tracks[0]}
+			res.Tracks[0].Segments = p.Tracks[0].Segments[1:]
+			require.NoError(t, json.NewEncoder(w).Encode(res))
+		}))
+		defer ts.Close()
+
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Publish.PostProcessWebhookURL = ts.URL
+		tr.cfg.Publish.PostProcessWebhookSecret = "secret"
+
+		outputs := newPostProcessTestOutputs()
+		outputs[0].label = "whisper.cpp"
+		require.NoError(t, tr.postProcessOutputs(outputs))
+
+		require.Len(t, payloads, 1)
+		require.Equal(t, postProcessPayload{
+			CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+			PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+			TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+			Label:           "whisper.cpp",
+			Tracks: []postProcessTrack{
+				{
+					Speaker:    "User A",
+					Language:   "en",
+					ColorIndex: 1,
+					Segments: []postProcessSegment{
+						{Text: "my card number is 1234", StartTS: 0, EndTS: 2000},
+						{Text: "thanks", StartTS: 3000, EndTS: 3500},
+					},
+				},
+			},
+		}, payloads[0])
+
+		require.Equal(t, []transcribe.Segment{
+			{Text: "thanks", StartTS: 3000, EndTS: 3500},
+		}, outputs[0].tr[0].Segments)
+	})
+
+	t.Run("webhook failure", func(t *testing.T) {
+		defer func(attempts int) {
+			maxAPIRetryAttempts = attempts
+		}(maxAPIRetryAttempts)
+		maxAPIRetryAttempts = 1
+
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer ts.Close()
+
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Publish.PostProcessWebhookURL = ts.URL
+		tr.cfg.Publish.PostProcessWebhookSecret = "secret"
+
+		err := tr.postProcessOutputs(newPostProcessTestOutputs())
+		require.ErrorContains(t, err, "post-processing webhook failed")
+		require.ErrorContains(t, err, "unexpected status code 500")
+	})
+
+	t.Run("invalid response", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		for name, body := range map[string]string{
+			"malformed":          `{"tracks": [`,
+			"invalid timestamps": `{"tracks": [{"speaker": "User A", "segments": [{"text": "hi", "start_ts": 2000, "end_ts": 1000}]}]}`,
+		} {
+			script := filepath.Join(t.TempDir(), "invalid.sh")
+			require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat > /dev/null\necho '"+body+"'\n"), 0700))
+			tr.cfg.Publish.PostProcessCommand = script
+
+			err := tr.postProcessOutputs(newPostProcessTestOutputs())
+			require.ErrorContains(t, err, "hook response", name)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package call
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+// promptSetter is implemented by transcribers that can condition a
+// transcription on preceding text.
+type promptSetter interface {
+	SetPrompt(prompt string)
+}
+
+// promptCharsPerToken is the approximate number of characters per token for
+// scripts separated by spaces.
+const promptCharsPerToken = 4
+
+// unspacedScripts are the scripts written without spaces between words, for
+// which every character is counted as a token.
+var unspacedScripts = []*unicode.RangeTable{
+	unicode.Han,
+	unicode.Hiragana,
+	unicode.Katakana,
+	unicode.Thai,
+	unicode.Lao,
+	unicode.Khmer,
+	unicode.Myanmar,
+}
+
+type promptPiece struct {
+	text   string
+	tokens int
+	// space is whether the piece is separated by a space from the previous one.
+	space bool
+}
+
+// rollingPrompt holds the last tokens transcribed for a track so that the
+// following speech is transcribed in continuity with it. Bounding it keeps
+// the model from drifting on (or repeating) stale context.
+//
+// As the tokenizer isn't available here, tokens are estimated from the text:
+// one per character for scripts written without spaces (e.g. Chinese,
+// Japanese) and one per promptCharsPerToken characters otherwise.
+type rollingPrompt struct {
+	maxTokens int
+	tokens    int
+	pieces    []promptPiece
+}
+
+func newRollingPrompt(maxTokens int) *rollingPrompt {
+	return &rollingPrompt{
+		maxTokens: maxTokens,
+	}
+}
+
+func (p *rollingPrompt) enabled() bool {
+	return p != nil && p.maxTokens > 0
+}
+
+// add appends text to the prompt, dropping the oldest pieces past maxTokens.
+func (p *rollingPrompt) add(text string) {
+	if !p.enabled() {
+		return
+	}
+
+	for _, word := range strings.Fields(text) {
+		p.addWord(word)
+	}
+
+	var i int
+	for ; i < len(p.pieces) && p.tokens > p.maxTokens; i++ {
+		p.tokens -= p.pieces[i].tokens
+	}
+	if i > 0 {
+		// Copying so that the backing array doesn't keep growing.
+		p.pieces = append([]promptPiece(nil), p.pieces[i:]...)
+	}
+}
+
+// addWord splits word into pieces that can be dropped on their own, so that
+// text without spaces is bounded too.
+func (p *rollingPrompt) addWord(word string) {
+	space := true
+	var run []rune
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		p.push(promptPiece{
+			text:   string(run),
+			tokens: (len(run) + promptCharsPerToken - 1) / promptCharsPerToken,
+			space:  space,
+		})
+		space = false
+		run = run[:0]
+	}
+
+	for _, r := range word {
+		if !unicode.In(r, unspacedScripts...) {
+			run = append(run, r)
+			continue
+		}
+		flush()
+		p.push(promptPiece{text: string(r), tokens: 1, space: space})
+		space = false
+	}
+	flush()
+}
+
+func (p *rollingPrompt) push(piece promptPiece) {
+	p.pieces = append(p.pieces, piece)
+	p.tokens += piece.tokens
+}
+
+func (p *rollingPrompt) String() string {
+	if p == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, piece := range p.pieces {
+		if i > 0 && piece.space {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(piece.text)
+	}
+	return sb.String()
+}
+
+// rollingPromptTokens returns the number of tokens to carry over between the
+// speech chunks of a track, as set through WHISPER_ROLLING_PROMPT_TOKENS.
+func (t *Transcriber) rollingPromptTokens(api config.TranscribeAPI) int {
+	if api != config.TranscribeAPIWhisperCPP {
+		return 0
+	}
+
+	switch tokens := t.cfg.Engine.TranscribeAPIOptions["WHISPER_ROLLING_PROMPT_TOKENS"].(type) {
+	case float64:
+		return int(tokens)
+	case int:
+		return tokens
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,140 @@
+package call
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingPrompt(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		p := newRollingPrompt(0)
+		p.add("some text")
+		require.Empty(t, p.String())
+
+		var nilPrompt *rollingPrompt
+		require.False(t, nilPrompt.enabled())
+		require.Empty(t, nilPrompt.String())
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		p := newRollingPrompt(5)
+		p.add(" Hello  there. ")
+		require.Equal(t, "Hello there.", p.String())
+		require.Equal(t, 4, p.tokens)
+
+		p.add("How are you doing?")
+		require.Equal(t, "How are you doing?", p.String())
+		require.Equal(t, 5, p.tokens)
+
+		p.add("Fine.")
+		require.Equal(t, "you doing? Fine.", p.String())
+		require.Equal(t, 5, p.tokens)
+		require.Len(t, p.pieces, 3)
+
+		for i := 0; i < 100; i++ {
+			p.add("More words keep coming.")
+			require.LessOrEqual(t, p.tokens, 5)
+		}
+		require.Equal(t, "words keep coming.", p.String())
+	})
+
+	t.Run("unspaced script", func(t *testing.T) {
+		p := newRollingPrompt(4)
+		p.add("今天天气很好。")
+		require.Equal(t, "气很好。", p.String())
+		require.Equal(t, 4, p.tokens)
+
+		p.add("OK 谢谢")
+		require.Equal(t, "。 OK 谢谢", p.String())
+		require.Equal(t, 4, p.tokens)
+	})
+}
+
+type promptTranscriberMock struct {
+	speechTranscriberMock
+	prompt  string
+	prompts []string
+	n       int
+}
+
+func (m *promptTranscriberMock) SetPrompt(prompt string) {
+	m.prompt = prompt
+}
+
+func (m *promptTranscriberMock) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	m.prompts = append(m.prompts, m.prompt)
+	m.n++
+	return []transcribe.Segment{{Text: fmt.Sprintf("chunk %d.", m.n)}}, "en", nil
+}
+
+func TestTranscribeTrackRollingPrompt(t *testing.T) {
+	defer func(samples int) {
+		trackDecodeChunkSamples = samples
+	}(trackDecodeChunkSamples)
+	trackDecodeChunkSamples = trackOutAudioRate
+
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Engine.TranscribeAPI = config.TranscribeAPIWhisperCPP
+	tr.cfg.Engine.VAD.Engine = config.VADEngineWebRTC
+	tr.cfg.Engine.DisableLoudnessNormalization = true
+
+	var mock promptTranscriberMock
+	tr.trPool = newTranscriberPool(1, func(_ transcriberKey) (transcribe.Transcriber, error) {
+		return &mock, nil
+	})
+
+	pcm := genTone(0.5, 5*trackOutAudioRate)
+	samples := make([]int16, len(pcm))
+	for i, s := range pcm {
+		samples[i] = int16(s * math.MaxInt16)
+	}
+	path := filepath.Join(t.TempDir(), "track.wav")
+	writeTestWAV(t, path, trackOutAudioRate, 1, samples)
+
+	tctx := trackContext{
+		trackID:  "trackID",
+		filename: path,
+		user:     &model.User{Username: "testuser"},
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		_, _, err := tr.transcribeTrack(tctx)
+		require.NoError(t, err)
+		require.Greater(t, len(mock.prompts), 2)
+		for _, p := range mock.prompts {
+			require.Empty(t, p)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		mock.prompts = nil
+		mock.n = 0
+		tr.cfg.Engine.TranscribeAPIOptions = map[string]any{
+			"WHISPER_ROLLING_PROMPT_TOKENS": 6.0,
+		}
+
+		_, _, err := tr.transcribeTrack(tctx)
+		require.NoError(t, err)
+		require.Greater(t, len(mock.prompts), 2)
+		require.Empty(t, mock.prompts[0])
+		require.Equal(t, "chunk 1.", mock.prompts[1])
+		require.Equal(t, "chunk 1. chunk 2.", mock.prompts[2])
+		for i := 3; i < len(mock.prompts); i++ {
+			require.Equal(t, fmt.Sprintf("chunk %d. chunk %d.", i-1, i), mock.prompts[i])
+		}
+
+		// The prompt doesn't leak to other tracks through the pool.
+		pooled, err := tr.trPool.get(tr.trackTranscriberKey(config.TranscribeAPIWhisperCPP))
+		require.NoError(t, err)
+		require.Same(t, &mock, pooled)
+		require.Empty(t, mock.prompt)
+	})
+}
@@ -0,0 +1,155 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DataJob summarizes the state of a job found in a data directory.
+type DataJob struct {
+	TranscriptionID string
+	// NumTracks is the number of tracks captured by the job.
+	NumTracks int
+	// NumTranscribed is the number of tracks whose transcription is saved in
+	// the checkpoint, ready to be published.
+	NumTranscribed int
+	// Pending is set when the job left a checkpoint behind, meaning it never
+	// completed publishing.
+	Pending bool
+	// PublishedVersion is the number of times the job published, zero if
+	// it never did.
+	PublishedVersion int
+}
+
+// CanPublish returns whether the job can be published from its data alone.
+func (j DataJob) CanPublish() bool {
+	return j.Pending && j.NumTracks > 0 && j.NumTranscribed == j.NumTracks
+}
+
+// ListDataDir returns the jobs found in the given data directory, sorted by
+// transcription ID.
+func ListDataDir(dir string) ([]DataJob, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	jobs := make(map[string]*DataJob)
+	getJob := func(trID string) *DataJob {
+		if jobs[trID] == nil {
+			jobs[trID] = &DataJob{TranscriptionID: trID}
+		}
+		return jobs[trID]
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		path := filepath.Join(dir, name)
+		switch {
+		case strings.HasSuffix(name, "_checkpoint.json"):
+			var cp checkpoint
+			if err := readJSONFile(path, &cp); err != nil {
+				return nil, err
+			}
+			job := getJob(strings.TrimSuffix(name, "_checkpoint.json"))
+			job.Pending = true
+			job.NumTracks = len(cp.Tracks)
+			job.NumTranscribed = 0
+			for _, tc := range cp.Tracks {
+				if tc.Done && len(tc.Transcriptions) == len(cp.APIs) {
+					job.NumTranscribed++
+				}
+			}
+		case strings.HasSuffix(name, "_tracks.json"):
+			var rec checkpoint
+			if err := readJSONFile(path, &rec); err != nil {
+				return nil, err
+			}
+			// The checkpoint, if any, is more up to date.
+			if job := getJob(strings.TrimSuffix(name, "_tracks.json")); !job.Pending {
+				job.NumTracks = len(rec.Tracks)
+			}
+		case strings.HasSuffix(name, "_published.json"):
+			var rec publishedRecord
+			if err := readJSONFile(path, &rec); err != nil {
+				return nil, err
+			}
+			getJob(strings.TrimSuffix(name, "_published.json")).PublishedVersion = rec.Version
+		}
+	}
+
+	list := make([]DataJob, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, *job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].TranscriptionID < list[j].TranscriptionID
+	})
+
+	return list, nil
+}
+
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// PublishFromData publishes the transcription saved in the checkpoint of a
+// job that got to transcribe all of its tracks but failed to publish (e.g.
+// uploads failing). Nothing gets transcribed, the APIs which produced the
+// saved results are used regardless of the configured ones. Start must not
+// be called afterwards.
+func (t *Transcriber) PublishFromData() error {
+	cp, err := t.loadCheckpoint()
+	if err != nil {
+		return err
+	}
+	if cp == nil {
+		return fmt.Errorf("no checkpoint found in %s, nothing to publish", getDataDir())
+	}
+
+	if len(cp.Tracks) > maxTracksContexes {
+		return fmt.Errorf("too many tracks in checkpoint: %d", len(cp.Tracks))
+	}
+
+	if len(cp.APIs) == 0 || len(cp.APIs) > 2 {
+		return fmt.Errorf("unexpected number of APIs in checkpoint: %d", len(cp.APIs))
+	}
+
+	var transcribed int
+	for _, tc := range cp.Tracks {
+		if tc.Done && len(tc.Transcriptions) == len(cp.APIs) {
+			transcribed++
+		}
+	}
+	if transcribed < len(cp.Tracks) {
+		return fmt.Errorf("only %d out of %d tracks were transcribed, re-transcribing is needed", transcribed, len(cp.Tracks))
+	}
+
+	// Saved results are only reused if produced by the same APIs.
+	t.cfg.Engine.TranscribeAPI = cp.APIs[0]
+	t.cfg.Engine.TranscribeAPICompare = ""
+	if len(cp.APIs) > 1 {
+		t.cfg.Engine.TranscribeAPICompare = cp.APIs[1]
+	}
+
+	slog.Info("publishing from data",
+		slog.Int("numTracks", len(cp.Tracks)),
+		slog.Any("apis", cp.APIs))
+
+	t.resumedCheckpoint = cp
+	t.startPostProcessing(cp)
+
+	return nil
+}
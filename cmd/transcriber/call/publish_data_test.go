@@ -0,0 +1,124 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDataDir(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	t.Run("empty", func(t *testing.T) {
+		jobs, err := ListDataDir(getDataDir())
+		require.NoError(t, err)
+		require.Empty(t, jobs)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		_, err := ListDataDir("/does/not/exist")
+		require.ErrorContains(t, err, "failed to read data directory")
+	})
+
+	cp := &checkpoint{
+		APIs: []config.TranscribeAPI{config.TranscribeAPIWhisperCPP},
+		Tracks: []trackCheckpoint{
+			{
+				TrackID:  "trackA",
+				Filename: "userA_trackA.ogg",
+				User:     &model.User{Id: "userA", Username: "usera"},
+				Done:     true,
+				Transcriptions: []transcribe.TrackTranscription{
+					{Speaker: "usera", Segments: []transcribe.Segment{{Text: "Hello"}}},
+				},
+			},
+			{
+				TrackID:  "trackB",
+				Filename: "userB_trackB.ogg",
+				User:     &model.User{Id: "userB", Username: "userb"},
+			},
+		},
+	}
+
+	t.Run("pending job", func(t *testing.T) {
+		require.NoError(t, tr.saveCheckpoint(cp))
+		require.NoError(t, tr.saveTracksRecord(cp))
+
+		jobs, err := ListDataDir(getDataDir())
+		require.NoError(t, err)
+		require.Equal(t, []DataJob{
+			{
+				TranscriptionID: tr.cfg.TranscriptionID,
+				NumTracks:       2,
+				NumTranscribed:  1,
+				Pending:         true,
+			},
+		}, jobs)
+		require.False(t, jobs[0].CanPublish())
+
+		cp.Tracks[1].Done = true
+		cp.Tracks[1].Transcriptions = make([]transcribe.TrackTranscription, 1)
+		require.NoError(t, tr.saveCheckpoint(cp))
+
+		jobs, err = ListDataDir(getDataDir())
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		require.Equal(t, 2, jobs[0].NumTranscribed)
+		require.True(t, jobs[0].CanPublish())
+	})
+
+	t.Run("published job", func(t *testing.T) {
+		tr.removeCheckpoint()
+		require.NoError(t, tr.savePublishedRecord(publishedRecord{Version: 2}))
+
+		jobs, err := ListDataDir(getDataDir())
+		require.NoError(t, err)
+		require.Equal(t, []DataJob{
+			{
+				TranscriptionID:  tr.cfg.TranscriptionID,
+				NumTracks:        2,
+				PublishedVersion: 2,
+			},
+		}, jobs)
+		require.False(t, jobs[0].CanPublish())
+	})
+}
+
+func TestPublishFromData(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	t.Run("no checkpoint", func(t *testing.T) {
+		err := tr.PublishFromData()
+		require.ErrorContains(t, err, "no checkpoint found")
+	})
+
+	t.Run("not transcribed", func(t *testing.T) {
+		require.NoError(t, tr.saveCheckpoint(&checkpoint{
+			APIs: []config.TranscribeAPI{config.TranscribeAPIWhisperCPP},
+			Tracks: []trackCheckpoint{
+				{TrackID: "trackA", Filename: "userA_trackA.ogg", Done: true, Transcriptions: make([]transcribe.TrackTranscription, 1)},
+				{TrackID: "trackB", Filename: "userB_trackB.ogg"},
+			},
+		}))
+		defer tr.removeCheckpoint()
+
+		err := tr.PublishFromData()
+		require.EqualError(t, err, "only 1 out of 2 tracks were transcribed, re-transcribing is needed")
+		require.Nil(t, tr.resumedCheckpoint)
+	})
+
+	t.Run("missing APIs", func(t *testing.T) {
+		require.NoError(t, tr.saveCheckpoint(&checkpoint{
+			Tracks: []trackCheckpoint{{TrackID: "trackA", Filename: "userA_trackA.ogg"}},
+		}))
+		defer tr.removeCheckpoint()
+
+		err := tr.PublishFromData()
+		require.EqualError(t, err, "unexpected number of APIs in checkpoint: 0")
+	})
+}
@@ -0,0 +1,158 @@
+package call
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattermost/rtcd/client"
+)
+
+const (
+	maxReconnectAttempts     = 5
+	reconnectAttemptWaitTime = 2 * time.Second
+)
+
+// reconnectGap is a period of time, relative to the recording start, during
+// which the transcriber was disconnected from the call.
+type reconnectGap struct {
+	StartMs int64
+	EndMs   int64
+}
+
+func (g reconnectGap) String() string {
+	return fmt.Sprintf("RECONNECT_GAP=%d-%d", g.StartMs, g.EndMs)
+}
+
+func (t *Transcriber) newClient() (*client.Client, error) {
+	return client.New(client.Config{
+		SiteURL:   t.cfg.SiteURL,
+		AuthToken: t.cfg.AuthToken,
+		ChannelID: t.cfg.CallID,
+		JobID:     t.cfg.TranscriptionID,
+	})
+}
+
+func (t *Transcriber) registerClientHandlers(c *client.Client) {
+	for ev, h := range t.clientHandlers {
+		c.On(ev, h)
+	}
+}
+
+// handleClientClose gets called whenever the client gets closed. Unless we
+// closed it on purpose, a drop mid-call is retried so that transient
+// network issues don't truncate the transcription.
+func (t *Transcriber) handleClientClose(_ any) error {
+	if !t.closing.Load() && t.State() == StateRecording {
+		slog.Warn("client connection dropped, reconnecting")
+		go t.reconnect()
+		return nil
+	}
+
+	go t.done()
+	return nil
+}
+
+// recordingOffsetMs returns the time elapsed since the recording started.
+func (t *Transcriber) recordingOffsetMs() int64 {
+	startTime := t.startTime.Load()
+	if startTime == nil {
+		return 0
+	}
+	return time.Since(*startTime).Milliseconds()
+}
+
+// reconnect connects a new client, with bounded retries. Tracks are
+// resubscribed by the new client and processed as usual, with the
+// disconnected period annotated in their files. If all attempts fail
+// the job moves on to post-processing what was saved so far.
+func (t *Transcriber) reconnect() {
+	disconnectedAt := t.recordingOffsetMs()
+
+	var err error
+	for i := 0; i < maxReconnectAttempts; i++ {
+		if i > 0 {
+			slog.Error("failed to reconnect",
+				slog.String("err", err.Error()),
+				slog.Duration("reattempt_time", reconnectAttemptWaitTime))
+		}
+		time.Sleep(reconnectAttemptWaitTime)
+
+		if t.closing.Load() {
+			break
+		}
+
+		slog.Info("reconnecting", slog.Int("attempt", i+1))
+
+		var c *client.Client
+		c, err = t.newClient()
+		if err != nil {
+			err = fmt.Errorf("failed to create client: %w", err)
+			continue
+		}
+		t.registerClientHandlers(c)
+		if err = c.Connect(); err != nil {
+			err = fmt.Errorf("failed to connect: %w", err)
+			continue
+		}
+
+		t.client.Store(c)
+		gap := reconnectGap{
+			StartMs: disconnectedAt,
+			EndMs:   t.recordingOffsetMs(),
+		}
+		t.addReconnectGap(gap)
+		slog.Info("reconnected", slog.String("gap", gap.String()))
+
+		// We may have been stopped while reconnecting, in which case the old
+		// client got closed instead. Closing the new one completes the job.
+		if t.closing.Load() {
+			if err := c.Close(); err != nil {
+				slog.Error("failed to close client", slog.String("err", err.Error()))
+			}
+		}
+
+		return
+	}
+
+	if err != nil {
+		slog.Error("failed to reconnect, giving up", slog.String("err", err.Error()))
+	}
+
+	t.done()
+}
+
+func (t *Transcriber) addReconnectGap(gap reconnectGap) {
+	t.reconnectGapsMut.Lock()
+	defer t.reconnectGapsMut.Unlock()
+	t.reconnectGaps = append(t.reconnectGaps, gap)
+}
+
+// getReconnectGapComments returns the reconnect gaps so far as OGG user
+// comments to annotate newly created track files with.
+func (t *Transcriber) getReconnectGapComments() []string {
+	t.reconnectGapsMut.Lock()
+	defer t.reconnectGapsMut.Unlock()
+
+	var comments []string
+	for _, gap := range t.reconnectGaps {
+		comments = append(comments, gap.String())
+	}
+	return comments
+}
+
+// getTrackFilename returns the path of the file to save the track to. Track
+// IDs may be reused when tracks are resubscribed after reconnecting so we make
+// sure not to overwrite a previously saved file.
+func getTrackFilename(userID, trackID string) string {
+	filename := filepath.Join(getDataDir(), fmt.Sprintf("%s_%s.ogg", userID, trackID))
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filename); errors.Is(err, os.ErrNotExist) {
+			return filename
+		}
+		filename = filepath.Join(getDataDir(), fmt.Sprintf("%s_%s_%d.ogg", userID, trackID, i))
+	}
+}
@@ -0,0 +1,101 @@
+package call
+
+import (
+	"encoding/binary"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/ogg"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTrackFilename(t *testing.T) {
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	filename := getTrackFilename("userID", "trackID")
+	require.Equal(t, filepath.Join(getDataDir(), "userID_trackID.ogg"), filename)
+	require.NoError(t, os.WriteFile(filename, nil, 0600))
+
+	filename = getTrackFilename("userID", "trackID")
+	require.Equal(t, filepath.Join(getDataDir(), "userID_trackID_1.ogg"), filename)
+	require.NoError(t, os.WriteFile(filename, nil, 0600))
+
+	require.Equal(t, filepath.Join(getDataDir(), "userID_trackID_2.ogg"), getTrackFilename("userID", "trackID"))
+}
+
+func TestReconnectGapAnnotation(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+		"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+		Return(&http.Response{
+			Body: io.NopCloser(strings.NewReader(`{"id": "userID", "username": "testuser"}`)),
+		}, nil).Once()
+
+	tr.addReconnectGap(reconnectGap{StartMs: 45000, EndMs: 49000})
+	tr.addReconnectGap(reconnectGap{StartMs: 120000, EndMs: 121500})
+
+	var done bool
+	track := &trackRemoteMock{
+		id: "trackID",
+		readRTP: func() (*rtp.Packet, interceptor.Attributes, error) {
+			if done {
+				return nil, nil, io.EOF
+			}
+			done = true
+			return &rtp.Packet{
+				Header: rtp.Header{
+					Timestamp: 960,
+				},
+				Payload: []byte{0x45},
+			}, nil, nil
+		},
+	}
+
+	tr.liveTracksWg.Add(1)
+	tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+	tr.processLiveTrack(track, "sessionID")
+
+	trackFile, err := os.Open(filepath.Join(getDataDir(), "userID_trackID.ogg"))
+	require.NoError(t, err)
+	defer trackFile.Close()
+
+	oggReader, _, err := ogg.NewReaderWith(trackFile)
+	require.NoError(t, err)
+
+	payload, _, err := oggReader.ParseNextPage()
+	require.NoError(t, err)
+	require.Equal(t, "OpusTags", string(payload[:8]))
+
+	vendorLen := binary.LittleEndian.Uint32(payload[8:])
+	offset := 12 + vendorLen
+	numComments := binary.LittleEndian.Uint32(payload[offset:])
+	offset += 4
+
+	var comments []string
+	for i := 0; i < int(numComments); i++ {
+		commentLen := binary.LittleEndian.Uint32(payload[offset:])
+		comments = append(comments, string(payload[offset+4:offset+4+commentLen]))
+		offset += 4 + commentLen
+	}
+	require.Equal(t, []string{"RECONNECT_GAP=45000-49000", "RECONNECT_GAP=120000-121500"}, comments)
+
+	// Audio follows as usual.
+	_, hdr, err := oggReader.ParseNextPage()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), hdr.GranulePosition)
+}
@@ -0,0 +1,59 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// publishedRecord keeps track of what was published for the job so that,
+// should the job be re-run (e.g. after fixing a backend issue), the
+// previously published files get replaced rather than duplicated.
+type publishedRecord struct {
+	// Version is incremented every time the job publishes.
+	Version int      `json:"version"`
+	FileIDs []string `json:"file_ids"`
+}
+
+func (t *Transcriber) publishedRecordPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_published.json", t.cfg.TranscriptionID))
+}
+
+// loadPublishedRecord returns the record of a previous run of the job or
+// nil if it never published.
+func (t *Transcriber) loadPublishedRecord() (*publishedRecord, error) {
+	data, err := os.ReadFile(t.publishedRecordPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read published record: %w", err)
+	}
+
+	var rec publishedRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal published record: %w", err)
+	}
+
+	return &rec, nil
+}
+
+func (t *Transcriber) savePublishedRecord(rec publishedRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal published record: %w", err)
+	}
+
+	path := t.publishedRecordPath()
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write published record: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename published record: %w", err)
+	}
+
+	return nil
+}
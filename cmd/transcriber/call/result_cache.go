@@ -0,0 +1,180 @@
+package call
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	// resultCacheVersion is part of the cache keys so that changes to the
+	// cached data invalidate previous entries.
+	resultCacheVersion = 1
+	resultCacheDirName = "cache"
+)
+
+type cachedResult struct {
+	Segments []transcribe.Segment `json:"segments"`
+	Language string               `json:"language"`
+}
+
+// resultCache stores the transcriptions of speech chunks, so that re-runs
+// of a job (e.g. after a crash, or to re-transcribe it) skip the chunks
+// already transcribed.
+type resultCache interface {
+	// get returns the result cached under key, if any.
+	get(key string) (cachedResult, bool, error)
+	put(key string, res cachedResult) error
+}
+
+// diskResultCache keeps results as files in a directory.
+type diskResultCache struct {
+	dir string
+}
+
+func (c diskResultCache) get(key string) (cachedResult, bool, error) {
+	data, err := os.ReadFile(filepath.Join(c.dir, key+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return cachedResult{}, false, nil
+	} else if err != nil {
+		return cachedResult{}, false, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var res cachedResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return cachedResult{}, false, fmt.Errorf("failed to unmarshal cached result: %w", err)
+	}
+
+	return res, true, nil
+}
+
+func (c diskResultCache) put(key string, res cachedResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Written through a temporary file so that a crash can't leave a
+	// partial entry behind.
+	f, err := os.CreateTemp(c.dir, key+"_*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close cache file: %w", err)
+	}
+
+	return os.Rename(f.Name(), filepath.Join(c.dir, key+".json"))
+}
+
+// s3ResultCache keeps results as objects in the S3 bucket transcriptions
+// are published to.
+type s3ResultCache struct {
+	client *s3Client
+}
+
+func (c s3ResultCache) objectKey(key string) string {
+	return path.Join(c.client.cfg.Prefix, resultCacheDirName, key+".json")
+}
+
+func (c s3ResultCache) get(key string) (cachedResult, bool, error) {
+	data, err := c.client.getObject(c.objectKey(key))
+	if errors.Is(err, errS3NotFound) {
+		return cachedResult{}, false, nil
+	} else if err != nil {
+		return cachedResult{}, false, fmt.Errorf("failed to get cache object: %w", err)
+	}
+
+	var res cachedResult
+	if err := json.Unmarshal(data, &res); err != nil {
+		return cachedResult{}, false, fmt.Errorf("failed to unmarshal cached result: %w", err)
+	}
+
+	return res, true, nil
+}
+
+func (c s3ResultCache) put(key string, res cachedResult) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+
+	return c.client.putObject(c.objectKey(key), data)
+}
+
+// newResultCache returns the configured result cache, or nil if disabled.
+func (t *Transcriber) newResultCache() resultCache {
+	switch t.cfg.Engine.ResultCache {
+	case config.ResultCacheDisk:
+		return diskResultCache{dir: filepath.Join(getDataDir(), resultCacheDirName)}
+	case config.ResultCacheS3:
+		return s3ResultCache{client: newS3Client(t.cfg.Publish.S3)}
+	default:
+		return nil
+	}
+}
+
+// resultCacheKey hashes the audio along with the settings that affect how
+// it's transcribed.
+func (t *Transcriber) resultCacheKey(key transcriberKey, pcm []float32, prompt string) string {
+	// Map keys are sorted when marshaling so options hash consistently.
+	opts, _ := json.Marshal(t.cfg.Engine.TranscribeAPIOptions)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n%s\n%s\n%s\n%s\n", resultCacheVersion, key.api, key.modelSize, key.language, opts, prompt)
+	buf := make([]byte, 0, 4*len(pcm))
+	for _, s := range pcm {
+		buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(s))
+	}
+	h.Write(buf)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// transcribeCached transcribes pcm through tr, unless the result is already
+// cached. Cache failures are logged but don't fail the transcription.
+func (t *Transcriber) transcribeCached(key transcriberKey, tr transcribe.Transcriber, pcm []float32, prompt string) ([]transcribe.Segment, string, error) {
+	if t.resultCache == nil {
+		return tr.Transcribe(pcm)
+	}
+
+	cacheKey := t.resultCacheKey(key, pcm, prompt)
+	if res, ok, err := t.resultCache.get(cacheKey); err != nil {
+		slog.Warn("failed to get cached result", slog.String("err", err.Error()), slog.String("key", cacheKey))
+	} else if ok {
+		slog.Debug("using cached result", slog.String("key", cacheKey))
+		return res.Segments, res.Language, nil
+	}
+
+	segments, lang, err := tr.Transcribe(pcm)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := t.resultCache.put(cacheKey, cachedResult{Segments: segments, Language: lang}); err != nil {
+		slog.Warn("failed to cache result", slog.String("err", err.Error()), slog.String("key", cacheKey))
+	}
+
+	return segments, lang, nil
+}
@@ -0,0 +1,154 @@
+package call
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingTranscriber struct {
+	calls int
+}
+
+func (c *countingTranscriber) Transcribe(samples []float32) ([]transcribe.Segment, string, error) {
+	c.calls++
+	return []transcribe.Segment{{Text: "Hello.", StartTS: 0, EndTS: int64(len(samples) / trackOutAudioSamplesPerMs)}}, "en", nil
+}
+
+func (c *countingTranscriber) Destroy() error {
+	return nil
+}
+
+func TestResultCacheKey(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	key := transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeTiny}
+	pcm := genTone(0.5, trackOutAudioRate)
+
+	k := tr.resultCacheKey(key, pcm, "")
+	require.Len(t, k, 64)
+	require.Equal(t, k, tr.resultCacheKey(key, pcm, ""))
+
+	require.NotEqual(t, k, tr.resultCacheKey(key, genTone(0.25, trackOutAudioRate), ""))
+	require.NotEqual(t, k, tr.resultCacheKey(key, pcm, "previous words"))
+	require.NotEqual(t, k, tr.resultCacheKey(transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeMedium}, pcm, ""))
+
+	tr.cfg.Engine.TranscribeAPIOptions = map[string]any{"WHISPER_SUPPRESS_NON_SPEECH_TOKENS": true}
+	require.NotEqual(t, k, tr.resultCacheKey(key, pcm, ""))
+}
+
+func TestTranscribeCached(t *testing.T) {
+	key := transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeTiny}
+	pcm := genTone(0.5, trackOutAudioRate)
+	expected := []transcribe.Segment{{Text: "Hello.", StartTS: 0, EndTS: 1000}}
+
+	t.Run("disabled", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		require.Nil(t, tr.newResultCache())
+
+		ct := &countingTranscriber{}
+		for i := 0; i < 2; i++ {
+			_, _, err := tr.transcribeCached(key, ct, pcm, "")
+			require.NoError(t, err)
+		}
+		require.Equal(t, 2, ct.calls)
+	})
+
+	t.Run("disk", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Engine.ResultCache = config.ResultCacheDisk
+		tr.resultCache = tr.newResultCache()
+
+		ct := &countingTranscriber{}
+		for i := 0; i < 2; i++ {
+			segments, lang, err := tr.transcribeCached(key, ct, pcm, "")
+			require.NoError(t, err)
+			require.Equal(t, expected, segments)
+			require.Equal(t, "en", lang)
+		}
+		require.Equal(t, 1, ct.calls)
+
+		files, err := filepath.Glob(filepath.Join(getDataDir(), resultCacheDirName, "*"))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+
+		// A different prompt is a cache miss.
+		_, _, err = tr.transcribeCached(key, ct, pcm, "previous words")
+		require.NoError(t, err)
+		require.Equal(t, 2, ct.calls)
+	})
+
+	t.Run("s3", func(t *testing.T) {
+		var mut sync.Mutex
+		objects := make(map[string][]byte)
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mut.Lock()
+			defer mut.Unlock()
+
+			require.NotEmpty(t, r.Header.Get("Authorization"))
+
+			switch r.Method {
+			case http.MethodPut:
+				objects[r.URL.Path], _ = io.ReadAll(r.Body)
+			case http.MethodGet:
+				data, ok := objects[r.URL.Path]
+				if !ok {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				_, _ = w.Write(data)
+			}
+		}))
+		defer ts.Close()
+
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Engine.ResultCache = config.ResultCacheS3
+		tr.cfg.Publish.S3 = config.S3Config{
+			Endpoint:        ts.URL,
+			Bucket:          "transcripts",
+			Region:          "us-east-1",
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret",
+			Prefix:          "calls",
+		}
+		tr.resultCache = tr.newResultCache()
+
+		ct := &countingTranscriber{}
+		for i := 0; i < 2; i++ {
+			segments, lang, err := tr.transcribeCached(key, ct, pcm, "")
+			require.NoError(t, err)
+			require.Equal(t, expected, segments)
+			require.Equal(t, "en", lang)
+		}
+		require.Equal(t, 1, ct.calls)
+		require.Contains(t, objects, "/transcripts/calls/cache/"+tr.resultCacheKey(key, pcm, "")+".json")
+	})
+
+	t.Run("cache failure", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer ts.Close()
+
+		tr := setupTranscriberForTest(t)
+		tr.resultCache = s3ResultCache{client: newS3Client(config.S3Config{
+			Endpoint: ts.URL,
+			Bucket:   "transcripts",
+			Region:   "us-east-1",
+		})}
+
+		ct := &countingTranscriber{}
+		segments, _, err := tr.transcribeCached(key, ct, pcm, "")
+		require.NoError(t, err)
+		require.Equal(t, expected, segments)
+		require.Equal(t, 1, ct.calls)
+	})
+}
@@ -0,0 +1,106 @@
+package call
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// tracksRecordPath is where the tracks captured for the job are listed.
+// Unlike the checkpoint, the record is kept once the job completes so that
+// it can be re-run with ReTranscribeFromData.
+func (t *Transcriber) tracksRecordPath() string {
+	return filepath.Join(getDataDir(), fmt.Sprintf("%s_tracks.json", t.cfg.TranscriptionID))
+}
+
+// saveTracksRecord persists the tracks of the given checkpoint, leaving out
+// any transcription result.
+func (t *Transcriber) saveTracksRecord(cp *checkpoint) error {
+	rec := checkpoint{
+		Participants: cp.Participants,
+		Tracks:       make([]trackCheckpoint, len(cp.Tracks)),
+		Truncated:    cp.Truncated,
+	}
+	for i, tc := range cp.Tracks {
+		rec.Tracks[i] = trackCheckpoint{
+			TrackID:    tc.TrackID,
+			SessionID:  tc.SessionID,
+			Filename:   tc.Filename,
+			StartTS:    tc.StartTS,
+			User:       tc.User,
+			ColorIndex: tc.ColorIndex,
+			AudioDurMs: tc.AudioDurMs,
+		}
+	}
+
+	data, err := json.Marshal(&rec)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tracks record: %w", err)
+	}
+
+	path := t.tracksRecordPath()
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write tracks record: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to rename tracks record: %w", err)
+	}
+
+	return nil
+}
+
+// loadTracksRecord returns the tracks previously captured for the job or nil
+// if there's no record of them.
+func (t *Transcriber) loadTracksRecord() (*checkpoint, error) {
+	data, err := os.ReadFile(t.tracksRecordPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read tracks record: %w", err)
+	}
+
+	var rec checkpoint
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tracks record: %w", err)
+	}
+
+	return &rec, nil
+}
+
+// ReTranscribe starts post-processing the tracks captured by a previous run
+// of the job, without joining the call. Any transcription previously
+// published for the job gets replaced. Start must not be called afterwards.
+func (t *Transcriber) ReTranscribe() error {
+	rec, err := t.loadTracksRecord()
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("no captured tracks found in %s", getDataDir())
+	}
+
+	if len(rec.Tracks) > maxTracksContexes {
+		return fmt.Errorf("too many tracks in record: %d", len(rec.Tracks))
+	}
+
+	for _, tc := range rec.Tracks {
+		if _, err := os.Stat(tc.trackContext().filename); err != nil {
+			return fmt.Errorf("failed to find track file: %w", err)
+		}
+	}
+
+	// Results from a crashed run shouldn't be reused since the point is to
+	// transcribe everything again.
+	t.removeCheckpoint()
+
+	slog.Info("re-transcribing captured tracks", slog.Int("numTracks", len(rec.Tracks)))
+
+	t.startPostProcessing(rec)
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// transcriptionPatch carries the result of re-transcribing a range of a
+// track. Consumers replace the segments of the track's speaker falling
+// within the range with the patch ones, which may be none if no speech was
+// detected.
+type transcriptionPatch struct {
+	JobID         string               `json:"job_id"`
+	PostID        string               `json:"post_id"`
+	TrackID       string               `json:"track_id"`
+	SessionID     string               `json:"session_id"`
+	Speaker       string               `json:"speaker"`
+	StartMs       int64                `json:"start_ms"`
+	EndMs         int64                `json:"end_ms"`
+	TranscribeAPI config.TranscribeAPI `json:"transcribe_api"`
+	ModelSize     config.ModelSize     `json:"model_size"`
+	Segments      []transcribe.Segment `json:"segments"`
+}
+
+// clipTimedSamples returns the portions of the given samples falling within
+// [startMs, endMs), relative to the start of the track.
+func clipTimedSamples(samples []trackTimedSamples, startMs, endMs int64) []trackTimedSamples {
+	var clipped []trackTimedSamples
+	for _, ts := range samples {
+		endTS := ts.startTS + int64(len(ts.pcm)/trackOutAudioSamplesPerMs)
+		if endTS <= startMs || ts.startTS >= endMs {
+			continue
+		}
+
+		from := max(0, startMs-ts.startTS) * trackOutAudioSamplesPerMs
+		to := min(int64(len(ts.pcm)), (endMs-ts.startTS)*trackOutAudioSamplesPerMs)
+		clipped = append(clipped, trackTimedSamples{
+			pcm:     ts.pcm[from:to],
+			startTS: ts.startTS + from/trackOutAudioSamplesPerMs,
+		})
+	}
+	return clipped
+}
+
+// ReTranscribeRange transcribes again the range of a previously captured
+// track set through ReTranscribeRange, without joining the call, and
+// publishes the result as a patch of the existing transcription. This allows
+// fixing poorly transcribed portions (e.g. with a larger model) without
+// redoing the whole call. Unlike ReTranscribe, it runs synchronously.
+func (t *Transcriber) ReTranscribeRange() error {
+	r, err := config.ParseTranscribeRange(t.cfg.Capture.ReTranscribeRange)
+	if err != nil {
+		return fmt.Errorf("failed to parse range: %w", err)
+	}
+
+	rec, err := t.loadTracksRecord()
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("no captured tracks found in %s", getDataDir())
+	}
+
+	var ctx *trackContext
+	for _, tc := range rec.Tracks {
+		if tc.TrackID == r.TrackID {
+			trackCtx := tc.trackContext()
+			ctx = &trackCtx
+			break
+		}
+	}
+	if ctx == nil {
+		return fmt.Errorf("track %q not found in record", r.TrackID)
+	}
+
+	if _, err := os.Stat(ctx.filename); err != nil {
+		return fmt.Errorf("failed to find track file: %w", err)
+	}
+	ctx.clip = &r
+
+	slog.Info("re-transcribing track range",
+		slog.String("trackID", r.TrackID),
+		slog.Int64("startMs", r.StartMs),
+		slog.Int64("endMs", r.EndMs),
+		slog.String("modelSize", string(t.cfg.Engine.ModelSize)))
+
+	defer t.trPool.close()
+	defer t.stopFasterWhisperSidecar()
+	trackTrs, _, err := t.transcribeTrackWithAPIs(*ctx, []config.TranscribeAPI{t.cfg.Engine.TranscribeAPI})
+	if err != nil {
+		return fmt.Errorf("failed to transcribe track: %w", err)
+	}
+
+	if err := t.publishTranscriptionPatch(transcriptionPatch{
+		JobID:         t.cfg.TranscriptionID,
+		PostID:        t.cfg.PostID,
+		TrackID:       ctx.trackID,
+		SessionID:     ctx.sessionID,
+		Speaker:       trackTrs[0].Speaker,
+		StartMs:       r.StartMs,
+		EndMs:         r.EndMs,
+		TranscribeAPI: t.cfg.Engine.TranscribeAPI,
+		ModelSize:     t.cfg.Engine.ModelSize,
+		Segments:      trackTrs[0].Segments,
+	}); err != nil {
+		return fmt.Errorf("failed to publish transcription patch: %w", err)
+	}
+
+	if err := t.ReportJobDone(nil); err != nil {
+		slog.Error("failed to report job done", slog.String("err", err.Error()))
+	}
+
+	return nil
+}
+
+// publishTranscriptionPatch saves the patch in the data directory and sends
+// it to the plugin.
+func (t *Transcriber) publishTranscriptionPatch(patch transcriptionPatch) error {
+	data, err := json.Marshal(&patch)
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	path := filepath.Join(getDataDir(), fmt.Sprintf("%s_patch_%d-%d.json", t.cfg.TranscriptionID, patch.StartMs, patch.EndMs))
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+
+	if t.cfg.Publish.DryRun {
+		slog.Info("dry run, skipping publishing", slog.String("file", path))
+		return nil
+	}
+
+	apiURL := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/transcriptions/patches", t.apiURL, pluginID, t.cfg.CallID)
+	return retry(context.Background(), "publishTranscriptionPatch", defaultRetryPolicy, func(ctx context.Context) error {
+		ctx, cancelCtx := context.WithTimeout(ctx, httpRequestTimeout)
+		defer cancelCtx()
+		resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL, data, "")
+		if err != nil {
+			err = newAPIError(resp, err)
+			return err
+		}
+		defer resp.Body.Close()
+		return nil
+	})
+}
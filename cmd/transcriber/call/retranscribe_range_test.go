@@ -0,0 +1,135 @@
+package call
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClipTimedSamples(t *testing.T) {
+	samples := []trackTimedSamples{
+		{pcm: make([]float32, 1000*trackOutAudioSamplesPerMs), startTS: 0},
+		{pcm: make([]float32, 1000*trackOutAudioSamplesPerMs), startTS: 3000},
+	}
+
+	t.Run("within a chunk", func(t *testing.T) {
+		clipped := clipTimedSamples(samples, 200, 700)
+		require.Len(t, clipped, 1)
+		require.Equal(t, int64(200), clipped[0].startTS)
+		require.Len(t, clipped[0].pcm, 500*trackOutAudioSamplesPerMs)
+	})
+
+	t.Run("across chunks", func(t *testing.T) {
+		clipped := clipTimedSamples(samples, 500, 3500)
+		require.Len(t, clipped, 2)
+		require.Equal(t, int64(500), clipped[0].startTS)
+		require.Len(t, clipped[0].pcm, 500*trackOutAudioSamplesPerMs)
+		require.Equal(t, int64(3000), clipped[1].startTS)
+		require.Len(t, clipped[1].pcm, 500*trackOutAudioSamplesPerMs)
+	})
+
+	t.Run("gap", func(t *testing.T) {
+		require.Empty(t, clipTimedSamples(samples, 1000, 3000))
+	})
+
+	t.Run("past the end", func(t *testing.T) {
+		clipped := clipTimedSamples(samples, 3800, 10000)
+		require.Len(t, clipped, 1)
+		require.Equal(t, int64(3800), clipped[0].startTS)
+		require.Len(t, clipped[0].pcm, 200*trackOutAudioSamplesPerMs)
+	})
+}
+
+func TestReTranscribeRange(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Capture.ReTranscribeRange = "trackB:1000-5000"
+
+	t.Run("missing record", func(t *testing.T) {
+		err := tr.ReTranscribeRange()
+		require.EqualError(t, err, "no captured tracks found in "+getDataDir())
+	})
+
+	ctxs := []trackContext{
+		{
+			trackID:   "trackA",
+			sessionID: "sessionA",
+			filename:  filepath.Join(getDataDir(), "userA_trackA.ogg"),
+			user:      &model.User{Id: "userA", Username: "usera"},
+			audioDur:  5 * time.Second,
+		},
+	}
+	cp := tr.newCheckpoint([]config.TranscribeAPI{config.TranscribeAPIWhisperCPP}, ctxs)
+	require.NoError(t, tr.saveTracksRecord(cp))
+
+	t.Run("unknown track", func(t *testing.T) {
+		err := tr.ReTranscribeRange()
+		require.EqualError(t, err, `track "trackB" not found in record`)
+	})
+
+	t.Run("missing track file", func(t *testing.T) {
+		tr.cfg.Capture.ReTranscribeRange = "trackA:1000-5000"
+		err := tr.ReTranscribeRange()
+		require.ErrorContains(t, err, "failed to find track file")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestPublishTranscriptionPatch(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	mockClient := &mocks.MockAPIClient{}
+	tr.apiClient = mockClient
+	defer mockClient.AssertExpectations(t)
+
+	patch := transcriptionPatch{
+		JobID:         tr.cfg.TranscriptionID,
+		PostID:        tr.cfg.PostID,
+		TrackID:       "trackA",
+		SessionID:     "sessionA",
+		Speaker:       "User A",
+		StartMs:       1000,
+		EndMs:         5000,
+		TranscribeAPI: config.TranscribeAPIWhisperCPP,
+		ModelSize:     config.ModelSizeMedium,
+		Segments: []transcribe.Segment{
+			{StartTS: 1200, EndTS: 2400, Text: "Hello"},
+		},
+	}
+	payload, err := json.Marshal(&patch)
+	require.NoError(t, err)
+
+	path := filepath.Join(getDataDir(), tr.cfg.TranscriptionID+"_patch_1000-5000.json")
+
+	t.Run("dry run", func(t *testing.T) {
+		tr.cfg.Publish.DryRun = true
+		defer func() { tr.cfg.Publish.DryRun = false }()
+
+		require.NoError(t, tr.publishTranscriptionPatch(patch))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, payload, data)
+	})
+
+	t.Run("published", func(t *testing.T) {
+		patchesURL := "http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/transcriptions/patches"
+		mockClient.On("DoAPIRequestBytes", mock.Anything, http.MethodPost, patchesURL, payload, "").
+			Return(&http.Response{Body: io.NopCloser(strings.NewReader(""))}, nil).Once()
+
+		require.NoError(t, tr.publishTranscriptionPatch(patch))
+	})
+}
@@ -0,0 +1,77 @@
+package call
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracksRecord(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	ctxs := []trackContext{
+		{
+			trackID:    "trackA",
+			sessionID:  "sessionA",
+			filename:   filepath.Join(getDataDir(), "userA_trackA.ogg"),
+			startTS:    1000,
+			user:       &model.User{Id: "userA", Username: "usera"},
+			colorIndex: 1,
+			audioDur:   5 * time.Second,
+		},
+	}
+
+	t.Run("missing", func(t *testing.T) {
+		rec, err := tr.loadTracksRecord()
+		require.NoError(t, err)
+		require.Nil(t, rec)
+
+		err = tr.ReTranscribe()
+		require.EqualError(t, err, "no captured tracks found in "+getDataDir())
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		cp := tr.newCheckpoint([]config.TranscribeAPI{config.TranscribeAPIWhisperCPP}, ctxs)
+		cp.Truncated = truncationReasonMaxCallDuration
+		cp.Tracks[0].Done = true
+		cp.Tracks[0].SpeechDurMs = 4000
+		cp.Tracks[0].Transcriptions = []transcribe.TrackTranscription{
+			{
+				Speaker: "usera",
+				Segments: []transcribe.Segment{
+					{Text: "Hello", StartTS: 1000, EndTS: 2000},
+				},
+			},
+		}
+		require.NoError(t, tr.saveTracksRecord(cp))
+
+		rec, err := tr.loadTracksRecord()
+		require.NoError(t, err)
+		require.Empty(t, rec.APIs)
+		require.Equal(t, truncationReasonMaxCallDuration, rec.Truncated)
+		require.Len(t, rec.Tracks, 1)
+		require.False(t, rec.Tracks[0].Done)
+		require.Empty(t, rec.Tracks[0].Transcriptions)
+		require.Zero(t, rec.Tracks[0].SpeechDurMs)
+		require.Equal(t, ctxs[0], rec.Tracks[0].trackContext())
+
+		// The record survives the job completing.
+		tr.removeCheckpoint()
+		_, err = os.Stat(tr.tracksRecordPath())
+		require.NoError(t, err)
+	})
+
+	t.Run("missing track file", func(t *testing.T) {
+		err := tr.ReTranscribe()
+		require.ErrorContains(t, err, "failed to find track file")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
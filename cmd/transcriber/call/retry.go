@@ -0,0 +1,184 @@
+package call
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxRetryAfterWait caps the wait requested by the server through a
+// Retry-After header so that a misbehaving proxy can't stall the job.
+const maxRetryAfterWait = 2 * time.Minute
+
+// retryPolicy configures how a failing request to a given endpoint is
+// retried.
+type retryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// If zero, maxAPIRetryAttempts is used.
+	MaxAttempts int
+	// InitialWait is the time waited before the first retry. It doubles on
+	// every following retry, up to MaxWait.
+	InitialWait time.Duration
+	MaxWait     time.Duration
+	// Jitter is the fraction, in the range [0, 1], by which every wait is
+	// randomly shortened or lengthened so that jobs failing at the same time
+	// (e.g. a server restart) don't retry in lockstep.
+	Jitter float64
+}
+
+var (
+	// defaultRetryPolicy applies to requests without specific needs.
+	defaultRetryPolicy = retryPolicy{
+		InitialWait: 2 * time.Second,
+		MaxWait:     30 * time.Second,
+		Jitter:      0.2,
+	}
+	// getUserRetryPolicy retries sooner since a track isn't read until the
+	// profile of its participant is fetched.
+	getUserRetryPolicy = retryPolicy{
+		InitialWait: time.Second,
+		MaxWait:     8 * time.Second,
+		Jitter:      0.2,
+	}
+)
+
+func (p retryPolicy) maxAttempts() int {
+	if p.MaxAttempts > 0 {
+		return p.MaxAttempts
+	}
+	return maxAPIRetryAttempts
+}
+
+// wait returns the time to wait before the given retry, starting from 1.
+func (p retryPolicy) wait(retry int) time.Duration {
+	wait := p.InitialWait
+	for i := 1; i < retry && wait < p.MaxWait; i++ {
+		wait *= 2
+	}
+	if p.MaxWait > 0 {
+		wait = min(wait, p.MaxWait)
+	}
+
+	if p.Jitter > 0 {
+		wait += time.Duration((rand.Float64()*2 - 1) * p.Jitter * float64(wait))
+	}
+
+	return wait
+}
+
+// apiError is a failed request to the Mattermost API, classified by status
+// code so that retrying can be skipped when it's bound to fail again.
+type apiError struct {
+	StatusCode int
+	// RetryAfter is the wait requested by the server, if any.
+	RetryAfter time.Duration
+	err        error
+}
+
+// newAPIError classifies the error returned for a request to the API. Errors
+// not coming with a response (e.g. timeouts) are returned as they are.
+func newAPIError(resp *http.Response, err error) error {
+	if resp == nil || resp.StatusCode < 300 {
+		return err
+	}
+
+	// The client parses the response body into err, an AppError. The status
+	// is taken from the response as, if the body wasn't one (e.g. a proxy
+	// error page), the AppError reports a 500 regardless.
+	return &apiError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		err:        err,
+	}
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s (status %d)", e.err, e.StatusCode)
+}
+
+func (e *apiError) Unwrap() error {
+	return e.err
+}
+
+// permanent returns whether the request is bound to fail again. Client errors
+// (e.g. 401 unauthorized or 413 too large) are, except for timeouts and rate
+// limiting.
+func (e *apiError) permanent() bool {
+	switch e.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// parseRetryAfter parses the value of a Retry-After header, either a number of
+// seconds or a date.
+func parseRetryAfter(val string, now time.Time) time.Duration {
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		return max(0, time.Duration(secs)*time.Second)
+	}
+	if date, err := http.ParseTime(val); err == nil {
+		return max(0, date.Sub(now))
+	}
+	return 0
+}
+
+// retry calls fn until it succeeds or the policy's attempts are exhausted, in
+// which case the last error is returned, wrapped so that it tells why it
+// stopped. Waiting stops early if ctx is done. Failed API requests (see
+// newAPIError) aren't retried if the failure is permanent, and are retried no
+// sooner than the server asked for. Neither are responses not matching the
+// expected schema (see apiSchemaError).
+func retry(ctx context.Context, name string, p retryPolicy, fn func(ctx context.Context) error) error {
+	var err error
+	for i := 0; i < p.maxAttempts(); i++ {
+		if i > 0 {
+			wait := p.wait(i)
+			var apiErr *apiError
+			if errors.As(err, &apiErr) {
+				wait = max(wait, min(apiErr.RetryAfter, maxRetryAfterWait))
+			}
+			slog.Error(name+" failed",
+				slog.String("err", err.Error()),
+				slog.Int("attempt", i),
+				slog.Duration("reattempt_time", wait))
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w, last error: %w", ctx.Err(), err)
+			}
+		}
+
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+
+		var schemaErr *apiSchemaError
+		if errors.As(err, &schemaErr) {
+			slog.Error(name+" failed on an unexpected response, not retrying",
+				slog.String("err", err.Error()))
+			return fmt.Errorf("permanent failure: %w", err)
+		}
+
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.permanent() {
+			slog.Error(name+" failed permanently, not retrying",
+				slog.String("err", err.Error()),
+				slog.Int("status", apiErr.StatusCode))
+			return fmt.Errorf("permanent failure: %w", err)
+		}
+	}
+
+	return fmt.Errorf("maximum attempts reached : %w", err)
+}
@@ -0,0 +1,164 @@
+package call
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyWait(t *testing.T) {
+	t.Run("exponential", func(t *testing.T) {
+		p := retryPolicy{
+			InitialWait: time.Second,
+			MaxWait:     5 * time.Second,
+		}
+		require.Equal(t, time.Second, p.wait(1))
+		require.Equal(t, 2*time.Second, p.wait(2))
+		require.Equal(t, 4*time.Second, p.wait(3))
+		require.Equal(t, 5*time.Second, p.wait(4))
+		require.Equal(t, 5*time.Second, p.wait(100))
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		p := retryPolicy{
+			InitialWait: time.Second,
+			MaxWait:     5 * time.Second,
+			Jitter:      0.2,
+		}
+		for i := 0; i < 100; i++ {
+			wait := p.wait(2)
+			require.GreaterOrEqual(t, wait, 1600*time.Millisecond)
+			require.LessOrEqual(t, wait, 2400*time.Millisecond)
+		}
+	})
+
+	t.Run("max attempts", func(t *testing.T) {
+		require.Equal(t, maxAPIRetryAttempts, retryPolicy{}.maxAttempts())
+		require.Equal(t, 3, retryPolicy{MaxAttempts: 3}.maxAttempts())
+	})
+}
+
+func TestRetry(t *testing.T) {
+	p := retryPolicy{
+		MaxAttempts: 3,
+		InitialWait: time.Millisecond,
+		MaxWait:     10 * time.Millisecond,
+	}
+
+	t.Run("success after failures", func(t *testing.T) {
+		var attempts int
+		err := retry(context.Background(), "test", p, func(_ context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return fmt.Errorf("attempt %d failed", attempts)
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		var attempts int
+		err := retry(context.Background(), "test", p, func(_ context.Context) error {
+			attempts++
+			return fmt.Errorf("attempt %d failed", attempts)
+		})
+		require.EqualError(t, err, "maximum attempts reached : attempt 3 failed")
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var attempts int
+		err := retry(ctx, "test", retryPolicy{MaxAttempts: 3, InitialWait: time.Hour}, func(_ context.Context) error {
+			attempts++
+			cancel()
+			return fmt.Errorf("attempt %d failed", attempts)
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualError(t, err, "context canceled, last error: attempt 1 failed")
+		require.Equal(t, 1, attempts)
+	})
+	t.Run("permanent API error", func(t *testing.T) {
+		var attempts int
+		err := retry(context.Background(), "test", p, func(_ context.Context) error {
+			attempts++
+			return newAPIError(&http.Response{StatusCode: http.StatusRequestEntityTooLarge},
+				model.NewAppError("uploadData", "api.file.too_large", nil, "", http.StatusRequestEntityTooLarge))
+		})
+		require.EqualError(t, err, "permanent failure: uploadData: api.file.too_large (status 413)")
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		var attempts int
+		start := time.Now()
+		err := retry(context.Background(), "test", p, func(_ context.Context) error {
+			attempts++
+			if attempts > 1 {
+				return nil
+			}
+			return newAPIError(&http.Response{
+				StatusCode: http.StatusTooManyRequests,
+				Header:     http.Header{"Retry-After": []string{"1"}},
+			}, model.NewAppError("rateLimit", "api.context.rate_limited", nil, "", http.StatusTooManyRequests))
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, attempts)
+		require.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+}
+
+func TestNewAPIError(t *testing.T) {
+	t.Run("no response", func(t *testing.T) {
+		err := fmt.Errorf("connection refused")
+		require.Equal(t, err, newAPIError(nil, err))
+	})
+
+	t.Run("classification", func(t *testing.T) {
+		for status, permanent := range map[int]bool{
+			http.StatusBadRequest:            true,
+			http.StatusUnauthorized:          true,
+			http.StatusForbidden:             true,
+			http.StatusNotFound:              true,
+			http.StatusRequestTimeout:        false,
+			http.StatusRequestEntityTooLarge: true,
+			http.StatusTooManyRequests:       false,
+			http.StatusInternalServerError:   false,
+			http.StatusBadGateway:            false,
+			http.StatusServiceUnavailable:    false,
+		} {
+			err := newAPIError(&http.Response{StatusCode: status}, fmt.Errorf("failed"))
+			apiErr, ok := err.(*apiError)
+			require.True(t, ok)
+			require.Equal(t, status, apiErr.StatusCode)
+			require.Equal(t, permanent, apiErr.permanent(), status)
+		}
+	})
+
+	t.Run("body not an AppError", func(t *testing.T) {
+		appErr := model.AppErrorFromJSON(strings.NewReader("<html>Bad Gateway</html>"))
+		err := newAPIError(&http.Response{StatusCode: http.StatusBadGateway}, appErr)
+		require.ErrorIs(t, err, appErr)
+		require.Contains(t, err.Error(), "(status 502)")
+		require.Contains(t, err.Error(), "<html>Bad Gateway</html>")
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.Zero(t, parseRetryAfter("", now))
+	require.Zero(t, parseRetryAfter("soon", now))
+	require.Zero(t, parseRetryAfter("-5", now))
+	require.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	require.Equal(t, 90*time.Second, parseRetryAfter("Fri, 01 Mar 2024 12:01:30 GMT", now))
+	require.Zero(t, parseRetryAfter("Fri, 01 Mar 2024 11:59:00 GMT", now))
+}
@@ -0,0 +1,184 @@
+package call
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+)
+
+// rtpCaptureMagic identifies (and versions) the files written by
+// rtpCaptureWriter.
+const rtpCaptureMagic = "RTPCAP01"
+
+// maxRTPCapturePacketSize is an upper bound on the size of a recorded packet
+// to avoid allocating arbitrary amounts of memory on corrupted files.
+const maxRTPCapturePacketSize = 1 << 16
+
+// getRTPCaptureFilename returns the path of the RTP capture for the given
+// track file.
+func getRTPCaptureFilename(trackFilename string) string {
+	return strings.TrimSuffix(trackFilename, ".ogg") + ".rtp"
+}
+
+// rtpCaptureWriter records the raw RTP packets of a track to disk. Each
+// packet is stored along with its arrival time (a monotonic clock reading)
+// so that the exact sequence, including gaps, duplicates and out of order
+// packets, can later be replayed through processLiveTrack.
+//
+// The file starts with rtpCaptureMagic followed by records made of the
+// arrival time in nanoseconds (uint64), the packet size (uint32) and the
+// marshaled packet, all big-endian.
+type rtpCaptureWriter struct {
+	f *os.File
+	w *bufio.Writer
+}
+
+func newRTPCaptureWriter(filename string) (*rtpCaptureWriter, error) {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create capture file: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	if _, err := w.WriteString(rtpCaptureMagic); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write capture header: %w", err)
+	}
+
+	return &rtpCaptureWriter{
+		f: f,
+		w: w,
+	}, nil
+}
+
+func (cw *rtpCaptureWriter) WritePacket(arrival time.Duration, pkt *rtp.Packet) error {
+	data, err := pkt.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal packet: %w", err)
+	}
+
+	var hdr [12]byte
+	binary.BigEndian.PutUint64(hdr[0:8], uint64(arrival))
+	binary.BigEndian.PutUint32(hdr[8:12], uint32(len(data)))
+	if _, err := cw.w.Write(hdr[:]); err != nil {
+		return fmt.Errorf("failed to write packet header: %w", err)
+	}
+	if _, err := cw.w.Write(data); err != nil {
+		return fmt.Errorf("failed to write packet: %w", err)
+	}
+
+	return nil
+}
+
+func (cw *rtpCaptureWriter) Close() error {
+	if err := cw.w.Flush(); err != nil {
+		cw.f.Close()
+		return fmt.Errorf("failed to flush capture file: %w", err)
+	}
+	return cw.f.Close()
+}
+
+// rtpCapturedPacket is a packet read back from an RTP capture.
+type rtpCapturedPacket struct {
+	// Arrival is the monotonic clock reading at which the packet was received.
+	Arrival time.Duration
+	Packet  *rtp.Packet
+}
+
+// readRTPCapture loads all the packets recorded in the given capture file.
+// A truncated trailing record (e.g. the process was killed mid-write) is
+// ignored.
+func readRTPCapture(filename string) ([]rtpCapturedPacket, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture file: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	magic := make([]byte, len(rtpCaptureMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != rtpCaptureMagic {
+		return nil, fmt.Errorf("invalid capture file")
+	}
+
+	var pkts []rtpCapturedPacket
+	for {
+		var hdr [12]byte
+		if _, err := io.ReadFull(r, hdr[:]); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return pkts, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read packet header: %w", err)
+		}
+
+		size := binary.BigEndian.Uint32(hdr[8:12])
+		if size > maxRTPCapturePacketSize {
+			return nil, fmt.Errorf("invalid packet size: %d", size)
+		}
+
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return pkts, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read packet: %w", err)
+		}
+
+		var pkt rtp.Packet
+		if err := pkt.Unmarshal(data); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal packet: %w", err)
+		}
+
+		pkts = append(pkts, rtpCapturedPacket{
+			Arrival: time.Duration(binary.BigEndian.Uint64(hdr[0:8])),
+			Packet:  &pkt,
+		})
+	}
+}
+
+// rtpReplayTrack implements trackRemote by serving previously captured
+// packets. Rather than sleeping between packets it exposes a virtual clock
+// (Now) which advances to each packet's arrival time as it's read, so that
+// replays are fast and deterministic when used as Transcriber.monoNow.
+type rtpReplayTrack struct {
+	id   string
+	pkts []rtpCapturedPacket
+	idx  int
+	now  atomic.Int64
+}
+
+func newRTPReplayTrack(trackID string, pkts []rtpCapturedPacket) *rtpReplayTrack {
+	return &rtpReplayTrack{
+		id:   trackID,
+		pkts: pkts,
+	}
+}
+
+func (rt *rtpReplayTrack) ID() string {
+	return rt.id
+}
+
+func (rt *rtpReplayTrack) ReadRTP() (*rtp.Packet, interceptor.Attributes, error) {
+	if rt.idx >= len(rt.pkts) {
+		return nil, nil, io.EOF
+	}
+
+	pkt := rt.pkts[rt.idx]
+	rt.idx++
+	rt.now.Store(int64(pkt.Arrival))
+
+	return pkt.Packet, nil, nil
+}
+
+// Now returns the arrival time of the last packet read.
+func (rt *rtpReplayTrack) Now() time.Duration {
+	return time.Duration(rt.now.Load())
+}
@@ -0,0 +1,212 @@
+package call
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/ogg"
+
+	mocks "github.com/mattermost/calls-transcriber/cmd/transcriber/mocks/github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRTPCapture(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "userID_trackID.rtp")
+
+	pkts := []rtpCapturedPacket{
+		{
+			Arrival: 20 * time.Millisecond,
+			Packet: &rtp.Packet{
+				Header:  rtp.Header{Version: 2, SequenceNumber: 1, Timestamp: 960},
+				Payload: []byte{0x45, 0x45},
+			},
+		},
+		{
+			Arrival: 2040 * time.Millisecond,
+			Packet: &rtp.Packet{
+				Header:  rtp.Header{Version: 2, SequenceNumber: 2, Timestamp: 1920, Marker: true},
+				Payload: []byte{},
+			},
+		},
+	}
+
+	w, err := newRTPCaptureWriter(filename)
+	require.NoError(t, err)
+	for _, pkt := range pkts {
+		require.NoError(t, w.WritePacket(pkt.Arrival, pkt.Packet))
+	}
+	require.NoError(t, w.Close())
+
+	t.Run("read", func(t *testing.T) {
+		got, err := readRTPCapture(filename)
+		require.NoError(t, err)
+		require.Len(t, got, len(pkts))
+		for i := range pkts {
+			require.Equal(t, pkts[i].Arrival, got[i].Arrival)
+			require.Equal(t, pkts[i].Packet.SequenceNumber, got[i].Packet.SequenceNumber)
+			require.Equal(t, pkts[i].Packet.Timestamp, got[i].Packet.Timestamp)
+			require.Equal(t, pkts[i].Packet.Marker, got[i].Packet.Marker)
+			require.Equal(t, len(pkts[i].Packet.Payload), len(got[i].Packet.Payload))
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		truncated := filepath.Join(dir, "truncated.rtp")
+		require.NoError(t, os.WriteFile(truncated, data[:len(data)-3], 0600))
+
+		got, err := readRTPCapture(truncated)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		invalid := filepath.Join(dir, "invalid.rtp")
+		require.NoError(t, os.WriteFile(invalid, []byte("OggS"), 0600))
+
+		_, err := readRTPCapture(invalid)
+		require.EqualError(t, err, "invalid capture file")
+	})
+
+	t.Run("replay track", func(t *testing.T) {
+		track := newRTPReplayTrack("trackID", pkts)
+		require.Equal(t, "trackID", track.ID())
+		require.Zero(t, track.Now())
+
+		pkt, _, err := track.ReadRTP()
+		require.NoError(t, err)
+		require.Equal(t, uint16(1), pkt.SequenceNumber)
+		require.Equal(t, 20*time.Millisecond, track.Now())
+
+		_, _, err = track.ReadRTP()
+		require.NoError(t, err)
+		require.Equal(t, 2040*time.Millisecond, track.Now())
+
+		_, _, err = track.ReadRTP()
+		require.Equal(t, io.EOF, err)
+	})
+}
+
+func TestRTPCaptureReplay(t *testing.T) {
+	runTrack := func(t *testing.T, tr *Transcriber, track trackRemote) []uint64 {
+		t.Helper()
+
+		mockClient := &mocks.MockAPIClient{}
+		tr.apiClient = mockClient
+		defer mockClient.AssertExpectations(t)
+
+		mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+			"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+			Return(&http.Response{
+				Body: io.NopCloser(strings.NewReader(`{"id": "userID", "username": "testuser"}`)),
+			}, nil).Once()
+
+		tr.liveTracksWg.Add(1)
+		tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+		tr.processLiveTrack(track, "sessionID")
+		close(tr.trackCtxs)
+		require.Len(t, tr.trackCtxs, 1)
+
+		trackFile, err := os.Open(filepath.Join(getDataDir(), "userID_trackID.ogg"))
+		require.NoError(t, err)
+		defer trackFile.Close()
+
+		oggReader, _, err := ogg.NewReaderWith(trackFile)
+		require.NoError(t, err)
+
+		// Metadata
+		_, _, err = oggReader.ParseNextPage()
+		require.NoError(t, err)
+
+		var granules []uint64
+		for {
+			_, hdr, err := oggReader.ParseNextPage()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			granules = append(granules, hdr.GranulePosition)
+		}
+
+		return granules
+	}
+
+	// A sequence exercising the synchronization logic: a receive gap with
+	// no RTP timestamp jump, a duplicate, an out of order packet, an empty
+	// payload and a timestamp wrap around.
+	type livePkt struct {
+		arrival time.Duration
+		seq     uint16
+		ts      uint32
+		payload []byte
+	}
+	livePkts := []livePkt{
+		{20 * time.Millisecond, 1, 4294964000, []byte{0x45}},
+		{40 * time.Millisecond, 2, 4294964960, []byte{0x45}},
+		{2060 * time.Millisecond, 3, 4294965920, []byte{0x45}},
+		{2080 * time.Millisecond, 3, 4294965920, []byte{0x45}},
+		{2100 * time.Millisecond, 5, 4294966880, []byte{0x45}},
+		{2120 * time.Millisecond, 4, 4294966000, []byte{0x45}},
+		{2140 * time.Millisecond, 6, 544, []byte{}},
+		{2160 * time.Millisecond, 7, 544, []byte{0x45}},
+		{2180 * time.Millisecond, 8, 1504, []byte{0x45}},
+	}
+
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Capture.RecordRTP = true
+
+	var now time.Duration
+	tr.monoNow = func() time.Duration {
+		return now
+	}
+
+	var i int
+	liveGranules := runTrack(t, tr, &trackRemoteMock{
+		id: "trackID",
+		readRTP: func() (*rtp.Packet, interceptor.Attributes, error) {
+			if i >= len(livePkts) {
+				return nil, nil, io.EOF
+			}
+			defer func() { i++ }()
+			now = livePkts[i].arrival
+			return &rtp.Packet{
+				Header: rtp.Header{
+					Version:        2,
+					SequenceNumber: livePkts[i].seq,
+					Timestamp:      livePkts[i].ts,
+				},
+				Payload: livePkts[i].payload,
+			}, nil, nil
+		},
+	})
+	require.NotEmpty(t, liveGranules)
+
+	// Every packet is recorded, including the ones which weren't saved.
+	captured, err := readRTPCapture(filepath.Join(getDataDir(), "userID_trackID.rtp"))
+	require.NoError(t, err)
+	require.Len(t, captured, len(livePkts))
+	for i, pkt := range captured {
+		require.Equal(t, livePkts[i].arrival, pkt.Arrival)
+		require.Equal(t, livePkts[i].seq, pkt.Packet.SequenceNumber)
+		require.Equal(t, livePkts[i].ts, pkt.Packet.Timestamp)
+	}
+
+	// Replaying the capture on a fresh transcriber must produce the exact same
+	// track file.
+	tr = setupTranscriberForTest(t)
+	replay := newRTPReplayTrack("trackID", captured)
+	tr.monoNow = replay.Now
+	require.Equal(t, liveGranules, runTrack(t, tr, replay))
+}
@@ -0,0 +1,203 @@
+package call
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const (
+	s3RequestTimeout = 30 * time.Second
+	s3SigningAlgo    = "AWS4-HMAC-SHA256"
+	s3AMZDateFormat  = "20060102T150405Z"
+	s3ShortDateFmt   = "20060102"
+)
+
+// errS3NotFound is returned when getting an object that doesn't exist.
+var errS3NotFound = errors.New("object not found")
+
+// objectStorageInfo is sent to the plugin in place of file IDs when
+// transcription files are uploaded to object storage. Keys are indexed the
+// same as the transcriptions they belong to.
+type objectStorageInfo struct {
+	Bucket string     `json:"bucket"`
+	Keys   [][]string `json:"keys"`
+}
+
+// s3Client is a minimal client for S3-compatible services, only supporting
+// what's needed to upload and get objects signed with AWS Signature Version
+// 4.
+type s3Client struct {
+	cfg        config.S3Config
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+func newS3Client(cfg config.S3Config) *s3Client {
+	return &s3Client{
+		cfg:        cfg,
+		httpClient: &http.Client{},
+		now:        time.Now,
+	}
+}
+
+// objectKey returns the key the file at the given path is stored under.
+func (c *s3Client) objectKey(jobID, filePath string) string {
+	return path.Join(c.cfg.Prefix, jobID, filepath.Base(filePath))
+}
+
+// uploadFile uploads the file at the given path. Uploading to an existing key
+// overwrites it so re-runs replace the previously published objects.
+func (c *s3Client) uploadFile(key, filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return c.putObject(key, data)
+}
+
+func (c *s3Client) putObject(key string, data []byte) error {
+	_, err := c.do(http.MethodPut, key, data)
+	return err
+}
+
+// getObject returns the content of the object stored under key, or
+// errS3NotFound if there's none.
+func (c *s3Client) getObject(key string) ([]byte, error) {
+	return c.do(http.MethodGet, key, nil)
+}
+
+// do sends a signed request for the object stored under key, returning the
+// response body.
+func (c *s3Client) do(method, key string, data []byte) ([]byte, error) {
+	u, err := url.Parse(c.cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint: %w", err)
+	}
+	u.Path = path.Join("/", u.Path, c.cfg.Bucket, key)
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancelFn()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", contentTypeForFile(key))
+	}
+	c.sign(req, data)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return nil, errS3NotFound
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, nil
+}
+
+// sign adds the AWS Signature Version 4 authorization headers to req.
+func (c *s3Client) sign(req *http.Request, payload []byte) {
+	now := c.now().UTC()
+	amzDate := now.Format(s3AMZDateFormat)
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"content-type", "host", "x-amz-content-sha256", "x-amz-date"}
+	headerValues := map[string]string{
+		"content-type":         req.Header.Get("Content-Type"),
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	// Only headers present in the request can be signed.
+	if headerValues["content-type"] == "" {
+		signedHeaders = signedHeaders[1:]
+	}
+	var canonicalHeaders strings.Builder
+	for _, h := range signedHeaders {
+		fmt.Fprintf(&canonicalHeaders, "%s:%s\n", h, strings.TrimSpace(headerValues[h]))
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(s3ShortDateFmt), c.cfg.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		s3SigningAlgo,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.cfg.SecretAccessKey), now.Format(s3ShortDateFmt))
+	key = hmacSHA256(key, c.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgo, c.cfg.AccessKeyID, scope, strings.Join(signedHeaders, ";"), signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func contentTypeForFile(name string) string {
+	switch filepath.Ext(name) {
+	case ".vtt":
+		return "text/vtt"
+	case ".txt":
+		return "text/plain"
+	case ".json":
+		return "application/json"
+	default:
+		return "application/octet-stream"
+	}
+}
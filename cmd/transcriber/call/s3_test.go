@@ -0,0 +1,142 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3ClientUploadFile(t *testing.T) {
+	var reqs []*http.Request
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		reqs = append(reqs, r)
+		bodies = append(bodies, string(body))
+
+		if r.URL.Path == "/transcripts/forbidden/file.vtt" {
+			w.WriteHeader(403)
+			fmt.Fprintln(w, "AccessDenied")
+			return
+		}
+	}))
+	defer ts.Close()
+
+	c := newS3Client(config.S3Config{
+		Endpoint:        ts.URL,
+		Bucket:          "transcripts",
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Prefix:          "calls",
+	})
+	c.now = func() time.Time {
+		return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+
+	f, err := os.CreateTemp("", "file.vtt")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("WEBVTT\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	t.Run("success", func(t *testing.T) {
+		key := c.objectKey("jobID", "/tmp/data/file.vtt")
+		require.Equal(t, "calls/jobID/file.vtt", key)
+
+		err := c.uploadFile(key, f.Name())
+		require.NoError(t, err)
+
+		require.Len(t, reqs, 1)
+		req := reqs[0]
+		require.Equal(t, http.MethodPut, req.Method)
+		require.Equal(t, "/transcripts/calls/jobID/file.vtt", req.URL.Path)
+		require.Equal(t, "WEBVTT\n", bodies[0])
+		require.Equal(t, "text/vtt", req.Header.Get("Content-Type"))
+		require.Equal(t, "20240102T030405Z", req.Header.Get("X-Amz-Date"))
+		require.Equal(t, sha256Hex([]byte("WEBVTT\n")), req.Header.Get("X-Amz-Content-Sha256"))
+		require.Regexp(t, regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=AKID/20240102/us-east-1/s3/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature=[0-9a-f]{64}$`),
+			req.Header.Get("Authorization"))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := c.uploadFile("key", filepath.Join(os.TempDir(), "missing.vtt"))
+		require.ErrorContains(t, err, "failed to read file")
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		err := c.uploadFile("forbidden/file.vtt", f.Name())
+		require.EqualError(t, err, "unexpected status code 403: AccessDenied")
+	})
+}
+
+func TestPublishTranscriptionsS3(t *testing.T) {
+	var objectKeys []string
+	var jobInfo transcribingJobInfo
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename":
+			fmt.Fprintln(w, `{"filename": "Call_Test"}`)
+		case r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/transcriptions":
+			err := json.NewDecoder(r.Body).Decode(&jobInfo)
+			require.NoError(t, err)
+		case r.Method == http.MethodPut:
+			objectKeys = append(objectKeys, r.URL.Path)
+		default:
+			// Nothing should go through the upload API.
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	tr := setupTranscriberForTest(t)
+	tr.cfg.SiteURL = ts.URL
+	tr.apiURL = ts.URL
+	tr.apiClient = model.NewAPIv4Client(ts.URL)
+	tr.cfg.Publish.S3 = config.S3Config{
+		Endpoint:        ts.URL,
+		Bucket:          "transcripts",
+		Region:          "us-east-1",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+	}
+	defer os.Remove(tr.publishedRecordPath())
+
+	err := tr.publishTranscriptions([]transcriptionOutput{{tr: transcribe.Transcription{}}})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{
+		"/transcripts/67t5u6cmtfbb7jug739d43xa9e/Call_Test.vtt",
+		"/transcripts/67t5u6cmtfbb7jug739d43xa9e/Call_Test.txt",
+	}, objectKeys)
+
+	require.Len(t, jobInfo.Transcriptions, 1)
+	require.Empty(t, jobInfo.Transcriptions[0].FileIDs)
+	require.Equal(t, &objectStorageInfo{
+		Bucket: "transcripts",
+		Keys: [][]string{
+			{"67t5u6cmtfbb7jug739d43xa9e/Call_Test.vtt", "67t5u6cmtfbb7jug739d43xa9e/Call_Test.txt"},
+		},
+	}, jobInfo.ObjectStorage)
+
+	res := tr.published.Load()
+	require.NotNil(t, res)
+	require.Empty(t, res.FileIDs)
+	require.Equal(t, []string{"67t5u6cmtfbb7jug739d43xa9e/Call_Test.vtt", "67t5u6cmtfbb7jug739d43xa9e/Call_Test.txt"}, res.ObjectKeys)
+}
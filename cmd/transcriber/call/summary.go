@@ -0,0 +1,79 @@
+package call
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	aiPluginID = "mattermost-ai"
+	// Summaries are generated by an LLM so the request can take considerably
+	// longer than regular API calls.
+	summaryRequestTimeout = 2 * time.Minute
+)
+
+type summaryRequest struct {
+	CallID     string `json:"call_id"`
+	PostID     string `json:"post_id"`
+	Language   string `json:"language"`
+	Transcript string `json:"transcript"`
+}
+
+// summaryTranscript renders the transcription as compacted text, which is what
+// gets sent to the AI plugin to be summarized.
+func (t *Transcriber) summaryTranscript(tr transcribe.Transcription) (string, error) {
+	opts := t.cfg.Output.Options.Text
+	if opts.CompactOptions.IsEmpty() {
+		opts.SetDefaults()
+	}
+
+	var b strings.Builder
+	if err := tr.Text(&b, opts, nil); err != nil {
+		return "", fmt.Errorf("failed to render text: %w", err)
+	}
+
+	return b.String(), nil
+}
+
+// generateSummary sends the transcript to the AI plugin which generates a
+// meeting summary, along with action items, and attaches it to the call post.
+func (t *Transcriber) generateSummary(tr transcribe.Transcription) error {
+	text, err := t.summaryTranscript(tr)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(summaryRequest{
+		CallID:     t.cfg.CallID,
+		PostID:     t.cfg.PostID,
+		Language:   tr.Language(),
+		Transcript: text,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/plugins/%s/post/%s/summarize_transcription", t.cfg.SiteURL, aiPluginID, t.cfg.PostID)
+
+	err = retry(context.Background(), "generateSummary", defaultRetryPolicy, func(ctx context.Context) error {
+		ctx, cancelFn := context.WithTimeout(ctx, summaryRequestTimeout)
+		defer cancelFn()
+
+		resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, url, payload, "")
+		if err != nil {
+			err = newAPIError(resp, err)
+			return fmt.Errorf("failed to request summary: %w", err)
+		}
+		defer resp.Body.Close()
+
+		return nil
+	})
+
+	return err
+}
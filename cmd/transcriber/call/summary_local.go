@@ -0,0 +1,131 @@
+package call
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+const (
+	llamaCLIPathDefault = "llama-cli"
+	// summaryLocalTimeout bounds the generation, which runs on CPU and so can
+	// take a while for long calls.
+	summaryLocalTimeout = 15 * time.Minute
+	// summaryLocalContextSize is the context window requested from the model,
+	// in tokens. The transcript is cut to fit in it along with the summary.
+	summaryLocalContextSize = 8192
+	// summaryLocalMaxTokens caps the length of the summary.
+	summaryLocalMaxTokens = 1024
+	// summaryLocalMaxTranscriptSize caps the transcript passed to the model,
+	// in bytes, assuming about four bytes per token.
+	summaryLocalMaxTranscriptSize = 4 * (summaryLocalContextSize - summaryLocalMaxTokens - 512)
+	// summaryLocalMaxStderrSize caps the error output logged on failure.
+	summaryLocalMaxStderrSize = 4096
+)
+
+// getLlamaCLIPath returns the llama.cpp CLI binary used to run the local
+// summary model. It's not shipped with the transcriber image so it can be
+// pointed to through LLAMA_CLI_PATH.
+func getLlamaCLIPath() string {
+	if p := os.Getenv("LLAMA_CLI_PATH"); p != "" {
+		return p
+	}
+	return llamaCLIPathDefault
+}
+
+// summaryPrompt returns the instructions given to the local model along with
+// the transcript to summarize.
+func summaryPrompt(language, transcript string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Below is the transcript of a meeting (language: %s). ", language)
+	b.WriteString("Write a concise summary of it, in the same language, as Markdown with two sections: ")
+	b.WriteString("\"Summary\", a few sentences covering the main topics and decisions, and ")
+	b.WriteString("\"Action items\", a bullet list of follow-ups along with who is responsible for them. ")
+	b.WriteString("Only use information from the transcript.\n\n")
+	b.WriteString("Transcript:\n")
+	b.WriteString(transcript)
+	b.WriteString("\n\nSummary:\n")
+	return b.String()
+}
+
+// generateLocalSummary generates a meeting summary, in Markdown, through a
+// local model so that no transcript leaves the job.
+func (t *Transcriber) generateLocalSummary(tr transcribe.Transcription) (string, error) {
+	text, err := t.summaryTranscript(tr)
+	if err != nil {
+		return "", err
+	}
+
+	if len(text) > summaryLocalMaxTranscriptSize {
+		slog.Warn("transcript too long for the local summary model, cutting it",
+			slog.Int("size", len(text)),
+			slog.Int("maxSize", summaryLocalMaxTranscriptSize))
+		text = strings.ToValidUTF8(text[:summaryLocalMaxTranscriptSize], "")
+	}
+
+	// The prompt is passed through a file as it can be larger than what
+	// command line arguments allow.
+	promptFile, err := os.CreateTemp(getDataDir(), "summary_prompt_*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create prompt file: %w", err)
+	}
+	defer os.Remove(promptFile.Name())
+
+	_, err = promptFile.WriteString(summaryPrompt(tr.Language(), text))
+	if closeErr := promptFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write prompt file: %w", err)
+	}
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), summaryLocalTimeout)
+	defer cancelFn()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, getLlamaCLIPath(),
+		"-m", filepath.Join(getModelsDir(), t.cfg.Publish.SummaryModel),
+		"-f", promptFile.Name(),
+		"-c", strconv.Itoa(summaryLocalContextSize),
+		"-n", strconv.Itoa(summaryLocalMaxTokens),
+		"-t", strconv.Itoa(t.cfg.Engine.NumThreads),
+		"--temp", "0.2",
+		"--no-display-prompt",
+		"-no-cnv")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		errOut := stderr.String()
+		if len(errOut) > summaryLocalMaxStderrSize {
+			errOut = errOut[len(errOut)-summaryLocalMaxStderrSize:]
+		}
+		slog.Error("local summary model failed", slog.String("stderr", errOut))
+		return "", fmt.Errorf("failed to run local summary model: %w", err)
+	}
+
+	summary := strings.TrimSpace(stdout.String())
+	if summary == "" {
+		return "", fmt.Errorf("local summary model returned an empty summary")
+	}
+
+	return summary, nil
+}
+
+// writeSummaryFile saves the summary in dir and returns its path.
+func writeSummaryFile(dir, fname, summary string) (string, error) {
+	path := filepath.Join(dir, fname+"-summary.md")
+	if err := os.WriteFile(path, []byte(summary+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write summary file: %w", err)
+	}
+	return path, nil
+}
@@ -0,0 +1,85 @@
+package call
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateLocalSummary(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+	tr.cfg.Publish.GenerateSummary = true
+	tr.cfg.Publish.SummaryBackend = config.SummaryBackendLocal
+	tr.cfg.Publish.SummaryModel = "summary.gguf"
+	t.Setenv("MODELS_DIR", "/models")
+
+	trs := transcribe.Transcription{
+		{
+			Speaker:  "Alice",
+			Language: "en",
+			Segments: []transcribe.Segment{
+				{StartTS: 0, EndTS: 2000, Text: "Bob, can you send the report by Friday?"},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args")
+	promptPath := filepath.Join(dir, "prompt")
+	llamaPath := filepath.Join(dir, "llama-cli")
+
+	t.Setenv("LLAMA_CLI_PATH", llamaPath)
+
+	t.Run("success", func(t *testing.T) {
+		// The prompt file is passed after -f.
+		script := "#!/bin/sh\n" +
+			"echo \"$@\" > " + argsPath + "\n" +
+			"while [ \"$1\" != \"-f\" ]; do shift; done\n" +
+			"cp \"$2\" " + promptPath + "\n" +
+			"echo '## Summary'\n" +
+			"echo 'Report due Friday.'\n"
+		require.NoError(t, os.WriteFile(llamaPath, []byte(script), 0700))
+
+		summary, err := tr.generateLocalSummary(trs)
+		require.NoError(t, err)
+		require.Equal(t, "## Summary\nReport due Friday.", summary)
+
+		args, err := os.ReadFile(argsPath)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(args), "-m /models/summary.gguf -f "))
+
+		prompt, err := os.ReadFile(promptPath)
+		require.NoError(t, err)
+		require.Contains(t, string(prompt), "(language: en)")
+		require.Contains(t, string(prompt), "Bob, can you send the report by Friday?")
+
+		// The prompt file is removed once done.
+		matches, err := filepath.Glob(filepath.Join(getDataDir(), "summary_prompt_*"))
+		require.NoError(t, err)
+		require.Empty(t, matches)
+
+		path, err := writeSummaryFile(dir, "Call_Test", summary)
+		require.NoError(t, err)
+		require.Equal(t, filepath.Join(dir, "Call_Test-summary.md"), path)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(llamaPath, []byte("#!/bin/sh\necho 'failed to load model' >&2\nexit 1\n"), 0700))
+
+		_, err := tr.generateLocalSummary(trs)
+		require.ErrorContains(t, err, "failed to run local summary model")
+	})
+
+	t.Run("empty summary", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(llamaPath, []byte("#!/bin/sh\necho\n"), 0700))
+
+		_, err := tr.generateLocalSummary(trs)
+		require.EqualError(t, err, "local summary model returned an empty summary")
+	})
+}
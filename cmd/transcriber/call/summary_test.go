@@ -0,0 +1,103 @@
+package call
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSummary(t *testing.T) {
+	middlewares := []middleware{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, mw := range middlewares {
+			if mw(w, r) {
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
+		Publish: config.PublishConfig{
+			GenerateSummary: true,
+		},
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	maxAttempts := maxAPIRetryAttempts
+	maxAPIRetryAttempts = 1
+	defer func() {
+		maxAPIRetryAttempts = maxAttempts
+	}()
+
+	trs := transcribe.Transcription{
+		transcribe.TrackTranscription{
+			Speaker:  "SpeakerA",
+			Language: "en",
+			Segments: []transcribe.Segment{
+				{
+					StartTS: 0,
+					EndTS:   1000,
+					Text:    "Hello",
+				},
+				{
+					StartTS: 1000,
+					EndTS:   2000,
+					Text:    "world",
+				},
+			},
+		},
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		err := tr.generateSummary(trs)
+		require.ErrorContains(t, err, "permanent failure: failed to request summary")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		var req summaryRequest
+		middlewares = []middleware{
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/mattermost-ai/post/udzdsg7dwidbzcidx5khrf8nee/summarize_transcription" && r.Method == http.MethodPost {
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+						w.WriteHeader(400)
+						return true
+					}
+					w.WriteHeader(200)
+					return true
+				}
+
+				return false
+			},
+		}
+
+		err := tr.generateSummary(trs)
+		require.NoError(t, err)
+		require.Equal(t, summaryRequest{
+			CallID:     "8w8jorhr7j83uqr6y1st894hqe",
+			PostID:     "udzdsg7dwidbzcidx5khrf8nee",
+			Language:   "en",
+			Transcript: "00:00:00 -> 00:00:02\nSpeakerA\nHello world\n",
+		}, req)
+	})
+}
@@ -0,0 +1,46 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// timeMapArtifact is the JSON artifact published along with transcription
+// files in which silences were trimmed, to map their timestamps back to the
+// recording.
+type timeMapArtifact struct {
+	ThresholdMs int                `json:"threshold_ms"`
+	Spans       transcribe.TimeMap `json:"spans"`
+}
+
+// trimOutputsSilences shortens the long silences of every output, attaching
+// the resulting time map to it.
+func trimOutputsSilences(outputs []transcriptionOutput, thresholdMs int) []transcriptionOutput {
+	trimmedOutputs := make([]transcriptionOutput, 0, len(outputs))
+	for _, out := range outputs {
+		out.tr, out.timeMap = out.tr.TrimSilences(int64(thresholdMs))
+		trimmedOutputs = append(trimmedOutputs, out)
+	}
+	return trimmedOutputs
+}
+
+// writeTimeMapFile saves the time map JSON artifact in dir and returns its
+// path.
+func writeTimeMapFile(dir, fname string, thresholdMs int, tm transcribe.TimeMap) (string, error) {
+	path := filepath.Join(dir, fname+"-timemap.json")
+
+	data, err := json.MarshalIndent(timeMapArtifact{ThresholdMs: thresholdMs, Spans: tm}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode time map: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write time map file: %w", err)
+	}
+
+	return path, nil
+}
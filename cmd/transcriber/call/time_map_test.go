@@ -0,0 +1,51 @@
+package call
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimOutputsSilences(t *testing.T) {
+	tr := transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello", StartTS: 1000, EndTS: 2000},
+				{Text: "Anyone?", StartTS: 600000, EndTS: 601000},
+			},
+		},
+	}
+	outputs := []transcriptionOutput{{tr: tr, label: "whisper.cpp"}}
+
+	trimmed := trimOutputsSilences(outputs, 5000)
+	require.Len(t, trimmed, 1)
+	require.Equal(t, "whisper.cpp", trimmed[0].label)
+	require.Equal(t, int64(7000), trimmed[0].tr[0].Segments[1].StartTS)
+	require.Equal(t, transcribe.TimeMap{
+		{StartMs: 0, OriginalStartMs: 0},
+		{StartMs: 7000, OriginalStartMs: 600000},
+	}, trimmed[0].timeMap)
+
+	// The original outputs are left untouched.
+	require.Nil(t, outputs[0].timeMap)
+	require.Equal(t, int64(600000), outputs[0].tr[0].Segments[1].StartTS)
+
+	dir := t.TempDir()
+	path, err := writeTimeMapFile(dir, "Call_Test", 5000, trimmed[0].timeMap)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "Call_Test-timemap.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var artifact timeMapArtifact
+	require.NoError(t, json.Unmarshal(data, &artifact))
+	require.Equal(t, 5000, artifact.ThresholdMs)
+	require.Equal(t, trimmed[0].timeMap, artifact.Spans)
+}
@@ -0,0 +1,154 @@
+package call
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// trackStatsInterval is how often the capture stats of live tracks are sent
+// to clients.
+const trackStatsInterval = 30 * time.Second
+
+// trackStatsMsg summarizes the capture of live tracks over the last
+// interval, so that clients (e.g. the admin console) can display the health
+// of the job during the call.
+type trackStatsMsg struct {
+	IntervalMs int64             `json:"interval_ms"`
+	Tracks     []trackStatsEntry `json:"tracks"`
+}
+
+type trackStatsEntry struct {
+	TrackID   string `json:"track_id"`
+	SessionID string `json:"session_id"`
+	// PacketsPerSec is the rate of audio packets received.
+	PacketsPerSec float64 `json:"packets_per_sec"`
+	// LossPct is the share of packets never received, as told from gaps in
+	// sequence numbers.
+	LossPct          float64 `json:"loss_pct"`
+	DuplicatePackets uint64  `json:"duplicate_packets"`
+	// CaptionsPerMin is the rate of live captions sent for the track.
+	CaptionsPerMin float64 `json:"captions_per_min"`
+}
+
+// trackCounters counts the events of a live track since the last stats
+// were taken.
+type trackCounters struct {
+	sessionID  string
+	received   atomic.Uint64
+	lost       atomic.Uint64
+	duplicates atomic.Uint64
+	captions   atomic.Uint64
+}
+
+// trackStatsRegistry holds the counters of the live tracks.
+type trackStatsRegistry struct {
+	mut    sync.Mutex
+	tracks map[string]*trackCounters
+	// lastAt is when stats were last taken, as a monotonic clock reading.
+	lastAt time.Duration
+}
+
+func (r *trackStatsRegistry) add(trackID, sessionID string) *trackCounters {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.tracks == nil {
+		r.tracks = make(map[string]*trackCounters)
+	}
+	c := &trackCounters{sessionID: sessionID}
+	r.tracks[trackID] = c
+
+	return c
+}
+
+func (r *trackStatsRegistry) remove(trackID string) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	delete(r.tracks, trackID)
+}
+
+// addCaption counts a caption sent for the track, if still live.
+func (r *trackStatsRegistry) addCaption(trackID string) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if c := r.tracks[trackID]; c != nil {
+		c.captions.Add(1)
+	}
+}
+
+// snapshot returns the stats of the live tracks since the previous call and
+// resets their counters.
+func (r *trackStatsRegistry) snapshot(now time.Duration) trackStatsMsg {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	interval := now - r.lastAt
+	r.lastAt = now
+
+	msg := trackStatsMsg{
+		IntervalMs: interval.Milliseconds(),
+		Tracks:     make([]trackStatsEntry, 0, len(r.tracks)),
+	}
+	if interval <= 0 {
+		return msg
+	}
+
+	for trackID, c := range r.tracks {
+		received := c.received.Swap(0)
+		lost := c.lost.Swap(0)
+
+		entry := trackStatsEntry{
+			TrackID:          trackID,
+			SessionID:        c.sessionID,
+			PacketsPerSec:    float64(received) / interval.Seconds(),
+			DuplicatePackets: c.duplicates.Swap(0),
+			CaptionsPerMin:   float64(c.captions.Swap(0)) / interval.Minutes(),
+		}
+		if total := received + lost; total > 0 {
+			entry.LossPct = 100 * float64(lost) / float64(total)
+		}
+		msg.Tracks = append(msg.Tracks, entry)
+	}
+
+	sort.Slice(msg.Tracks, func(i, j int) bool {
+		return msg.Tracks[i].TrackID < msg.Tracks[j].TrackID
+	})
+
+	return msg
+}
+
+func (t *Transcriber) sendTrackStats() {
+	msg := t.trackStats.snapshot(t.monoNow())
+	if len(msg.Tracks) == 0 {
+		return
+	}
+
+	if err := t.client.Load().SendWS(wsEvTrackStats, msg, false); err != nil {
+		slog.Error("failed to send wsEvTrackStats", slog.String("err", err.Error()))
+	}
+}
+
+// monitorTrackStats periodically sends the capture stats of live tracks for
+// as long as they are being recorded.
+func (t *Transcriber) monitorTrackStats() {
+	// Stats start from now.
+	t.trackStats.snapshot(t.monoNow())
+
+	ticker := time.NewTicker(trackStatsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if state := t.State(); state != StateConnecting && state != StateRecording {
+				return
+			}
+			t.sendTrackStats()
+		case <-t.doneCh:
+			return
+		}
+	}
+}
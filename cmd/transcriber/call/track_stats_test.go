@@ -0,0 +1,60 @@
+package call
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackStatsRegistry(t *testing.T) {
+	var r trackStatsRegistry
+	r.snapshot(10 * time.Second)
+
+	a := r.add("trackA", "sessionA")
+	b := r.add("trackB", "sessionB")
+
+	a.received.Add(1470)
+	a.lost.Add(30)
+	a.duplicates.Add(2)
+	r.addCaption("trackA")
+	r.addCaption("trackA")
+	r.addCaption("trackA")
+	b.received.Add(300)
+	// Captions for tracks no longer live are ignored.
+	r.addCaption("trackC")
+
+	require.Equal(t, trackStatsMsg{
+		IntervalMs: 30000,
+		Tracks: []trackStatsEntry{
+			{
+				TrackID:          "trackA",
+				SessionID:        "sessionA",
+				PacketsPerSec:    49,
+				LossPct:          2,
+				DuplicatePackets: 2,
+				CaptionsPerMin:   6,
+			},
+			{
+				TrackID:       "trackB",
+				SessionID:     "sessionB",
+				PacketsPerSec: 10,
+			},
+		},
+	}, r.snapshot(40*time.Second))
+
+	// Counters are reset after each snapshot.
+	r.remove("trackB")
+	require.Equal(t, trackStatsMsg{
+		IntervalMs: 30000,
+		Tracks: []trackStatsEntry{
+			{
+				TrackID:   "trackA",
+				SessionID: "sessionA",
+			},
+		},
+	}, r.snapshot(70*time.Second))
+
+	r.remove("trackA")
+	require.Empty(t, r.snapshot(100*time.Second).Tracks)
+}
@@ -7,28 +7,29 @@ import (
 	"io"
 	"log/slog"
 	"math"
-	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/azure"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/external"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/apis/whisper.cpp"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/audio"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/denoise"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/loudness"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/ogg"
-	"github.com/mattermost/calls-transcriber/cmd/transcriber/opus"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/voiceprint"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/rtcd/client"
 
-	"github.com/streamer45/silero-vad-go/speech"
-
 	"github.com/pion/webrtc/v3"
 )
 
 const (
 	trackInAudioRate          = 48000                                            // Default sample rate for Opus
-	trackAudioChannels        = 1                                                // Only mono supported for now
+	trackAudioChannels        = 1                                                // Audio is downmixed to mono for transcription
 	trackOutAudioRate         = 16000                                            // 16KHz is what Whisper requires
 	trackInAudioSamplesPerMs  = trackInAudioRate / 1000                          // Number of audio samples per ms
 	trackOutAudioSamplesPerMs = trackOutAudioRate / 1000                         // Number of audio samples per ms
@@ -37,17 +38,50 @@ const (
 	trackOutFrameSize         = trackAudioFrameSizeMs * trackOutAudioRate / 1000 // The output frame size in samples
 	audioGapThreshold         = time.Second                                      // The amount of time after which we detect a gap in the audio track.
 	rtpTSWrapAroundThreshold  = trackInAudioRate                                 // The threshold to detect if the RTP timestamp has wrapped around (one second worth of samples).
+	speakerColorsNum          = 8                                                // The number of distinct speaker colors before indexes wrap around.
+	rtpHistorySize            = 64                                               // The number of recent RTP packets remembered per track to detect duplicates.
 
 	dataDir   = "/data"
 	modelsDir = "/models"
 )
 
 type trackContext struct {
-	trackID   string
-	sessionID string
-	filename  string
-	startTS   int64
-	user      *model.User
+	trackID    string
+	sessionID  string
+	filename   string
+	startTS    int64
+	user       *model.User
+	colorIndex int
+	// audioDur is the duration of the audio captured for the track.
+	audioDur time.Duration
+	// clip, if set, restricts transcribing to the given range of the track.
+	clip *config.TranscribeRange
+}
+
+// rtpHistory remembers the most recent RTP packets of a track so that
+// retransmitted or duplicated packets can be detected.
+type rtpHistory struct {
+	pkts [rtpHistorySize]struct {
+		seq uint16
+		ts  uint32
+	}
+	n int
+}
+
+// isDuplicate records the packet and returns whether an exact duplicate
+// (same sequence number and timestamp) was recently seen.
+func (h *rtpHistory) isDuplicate(seq uint16, ts uint32) bool {
+	for i := 0; i < min(h.n, len(h.pkts)); i++ {
+		if h.pkts[i].seq == seq && h.pkts[i].ts == ts {
+			return true
+		}
+	}
+
+	h.pkts[h.n%len(h.pkts)].seq = seq
+	h.pkts[h.n%len(h.pkts)].ts = ts
+	h.n++
+
+	return false
 }
 
 // handleTrack gets called whenever a new WebRTC track is received (e.g. someone unmuted
@@ -79,6 +113,15 @@ func (t *Transcriber) handleTrack(ctx any) error {
 	return nil
 }
 
+// discardTrack reads the track until it ends, dropping its packets.
+func discardTrack(track trackRemote) {
+	for {
+		if _, _, err := track.ReadRTP(); err != nil {
+			return
+		}
+	}
+}
+
 // processLiveTrack saves the content of a voice track to a file for later processing.
 // This involves muxing the raw Opus packets into a OGG file with the
 // timings adjusted to account for any potential gaps due to mute/unmute sequences.
@@ -88,16 +131,51 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 		sessionID: sessionID,
 	}
 
-	user, err := t.getUserForSession(ctx.sessionID)
+	profile, err := t.getSessionProfile(ctx.sessionID)
 	if err != nil {
 		slog.Error("failed to get user for session", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
 		return
 	}
+	user := &profile.User
 	ctx.user = user
-	ctx.filename = filepath.Join(getDataDir(), fmt.Sprintf("%s_%s.ogg", user.Id, track.ID()))
+	ctx.filename = getTrackFilename(user.Id, track.ID())
+	ctx.colorIndex = t.getSpeakerColorIndex(sessionID)
+	t.addParticipant(sessionID, user)
+
+	// Participants who declined being transcribed are neither recorded nor
+	// captioned. Their packets are still read so that they don't pile up.
+	if profile.TranscriptionOptOut {
+		slog.Info("participant opted out of transcription, ignoring track",
+			slog.String("sessionID", sessionID),
+			slog.String("trackID", ctx.trackID))
+		t.setParticipantOptOut(sessionID)
+		discardTrack(track)
+		t.liveTracksWg.Done()
+		return
+	}
+
+	if user.IsBot && t.cfg.Capture.BotTracks == config.BotTracksExclude {
+		slog.Info("participant is a bot, ignoring track",
+			slog.String("sessionID", sessionID),
+			slog.String("trackID", ctx.trackID))
+		discardTrack(track)
+		t.liveTracksWg.Done()
+		return
+	}
 
-	var prevArrivalTime time.Time
+	stats := t.trackStats.add(ctx.trackID, sessionID)
+	defer t.trackStats.remove(ctx.trackID)
+
+	// prevArrivalTime is a monotonic clock reading (see Transcriber.monoNow).
+	var prevArrivalTime time.Duration
 	var prevRTPTimestamp uint32
+	var prevSeq uint16
+	var hasAudio bool
+	var history rtpHistory
+	var duplicatePkts int
+	var skippedPkts int
+	var dtxPkts int
+	var inDTX bool
 
 	slog.Debug("processing voice track",
 		slog.String("username", user.Username),
@@ -107,8 +185,27 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 	defer func() {
 		slog.Debug("exiting reading loop for track", slog.String("trackID", ctx.trackID))
 
+		if skippedPkts > 0 {
+			slog.Warn("packets not saved due to low disk space",
+				slog.Int("count", skippedPkts),
+				slog.String("trackID", ctx.trackID))
+		}
+
+		if dtxPkts > 0 {
+			slog.Debug("DTX packets received for track",
+				slog.Int("count", dtxPkts),
+				slog.String("trackID", ctx.trackID))
+		}
+
+		if duplicatePkts > 0 {
+			slog.Info("duplicate packets received for track",
+				slog.Int("count", duplicatePkts),
+				slog.String("policy", string(t.cfg.Capture.DuplicatePackets)),
+				slog.String("trackID", ctx.trackID))
+		}
+
 		// Only send the track context if we processed at least one audio packet.
-		if !prevArrivalTime.IsZero() {
+		if hasAudio {
 			select {
 			case t.trackCtxs <- ctx:
 			default:
@@ -121,18 +218,36 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 		t.liveTracksWg.Done()
 	}()
 
-	oggWriter, err := ogg.NewWriter(ctx.filename, trackInAudioRate, trackAudioChannels)
+	// Tracks created after a reconnection carry the disconnected periods
+	// in their files.
+	oggWriter, err := ogg.NewWriterWithComments(ctx.filename, trackInAudioRate, trackAudioChannels, t.getReconnectGapComments())
 	if err != nil {
 		slog.Error("failed to created ogg writer", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
 		return
 	}
 	defer oggWriter.Close()
 
+	// Raw packets are recorded before any processing so that the track can
+	// be replayed exactly as it was received.
+	var captureWriter *rtpCaptureWriter
+	if t.cfg.Capture.RecordRTP {
+		captureWriter, err = newRTPCaptureWriter(getRTPCaptureFilename(ctx.filename))
+		if err != nil {
+			slog.Error("failed to create rtp capture writer", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+		} else {
+			defer func() {
+				if err := captureWriter.Close(); err != nil {
+					slog.Error("failed to close rtp capture writer", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+				}
+			}()
+		}
+	}
+
 	// Live captioning:
 	// pktPayloadCh is used to send the rtp audio data to the processLiveCaptionsForTrack goroutine
-	var pktPayloadCh chan []byte
-	if t.cfg.LiveCaptionsOn {
-		pktPayloadCh = make(chan []byte, pktPayloadChBuffer)
+	var pktPayloadCh chan captionsPkt
+	if t.cfg.LiveCaptions.On {
+		pktPayloadCh = make(chan captionsPkt, pktPayloadChBuffer)
 		defer func() {
 			close(pktPayloadCh)
 		}()
@@ -140,20 +255,86 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 		go t.processLiveCaptionsForTrack(ctx, pktPayloadCh)
 	}
 
+	// Packets are passed through a reordering buffer so that the ones
+	// arriving shortly after their successors can still be written in the
+	// right position.
+	jitter := newJitterBuffer(time.Duration(t.cfg.Capture.ReorderBufferMs) * time.Millisecond)
+	defer func() {
+		if jitter.reordered > 0 {
+			slog.Debug("packets reordered for track",
+				slog.Int("count", jitter.reordered),
+				slog.String("trackID", ctx.trackID))
+		}
+	}()
+	var ready []jitterPacket
+	var readDone bool
+
+	// Audio received before the recording started is optionally kept, up to
+	// PreStartBufferMs, and processed once the start time is known.
+	preStartBuffer := time.Duration(t.cfg.Capture.PreStartBufferMs) * time.Millisecond
+	var preStart []jitterPacket
+	// captionsFrom is the arrival time from which packets are live captioned,
+	// buffered ones being too late for it.
+	var captionsFrom time.Duration
+
 	// Read track audio:
 	for {
-		pkt, _, readErr := track.ReadRTP()
-		if readErr != nil {
-			if !errors.Is(readErr, io.EOF) {
-				slog.Error("failed to read RTP packet for track",
-					slog.String("err", readErr.Error()),
-					slog.String("trackID", ctx.trackID))
+		if len(ready) == 0 {
+			if readDone {
+				return
 			}
-			return
+
+			pkt, _, readErr := track.ReadRTP()
+			if readErr != nil {
+				if !errors.Is(readErr, io.EOF) {
+					slog.Error("failed to read RTP packet for track",
+						slog.String("err", readErr.Error()),
+						slog.String("trackID", ctx.trackID))
+				}
+				// Whatever is left in the buffer still needs to be written.
+				readDone = true
+				ready = jitter.flush()
+				continue
+			}
+
+			arrival := t.monoNow()
+			if captureWriter != nil {
+				if err := captureWriter.WritePacket(arrival, pkt); err != nil {
+					slog.Error("failed to record RTP packet", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+				}
+			}
+
+			ready = jitter.push(pkt, arrival)
+			continue
 		}
 
+		pkt, now := ready[0].pkt, ready[0].arrival
+		ready = ready[1:]
+
 		// We start processing audio samples only when the recording process has successfully started.
 		if t.startTime.Load() == nil {
+			if preStartBuffer > 0 {
+				preStart = append(preStart, jitterPacket{pkt: pkt, arrival: now})
+				if maxPkts := int(preStartBuffer / (trackAudioFrameSizeMs * time.Millisecond)); len(preStart) > maxPkts {
+					preStart = preStart[len(preStart)-maxPkts:]
+				}
+			}
+			continue
+		}
+
+		if len(preStart) > 0 {
+			// Packets received too long before the start don't fit in the
+			// buffered time either.
+			startedAt := t.monoNow() - time.Since(*t.startTime.Load())
+			captionsFrom = now
+			for len(preStart) > 0 && preStart[0].arrival < startedAt-preStartBuffer {
+				preStart = preStart[1:]
+			}
+			slog.Debug("processing audio received before the recording start",
+				slog.Int("pkts", len(preStart)),
+				slog.String("trackID", ctx.trackID))
+			ready = append(append(preStart, jitterPacket{pkt: pkt, arrival: now}), ready...)
+			preStart = nil
 			continue
 		}
 
@@ -163,10 +344,22 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 		if len(pkt.Payload) == 0 {
 			continue
 		}
+		stats.received.Add(1)
+
+		// Retransmitted/duplicated packets would otherwise be written twice,
+		// causing audible glitches and repeated words.
+		if history.isDuplicate(pkt.SequenceNumber, pkt.Timestamp) {
+			duplicatePkts++
+			t.duplicatePkts.Add(1)
+			stats.duplicates.Add(1)
+			if t.cfg.Capture.DuplicatePackets != config.DuplicatePacketsKeep {
+				continue
+			}
+		}
 
-		// We ignore out of order packets as they would cause synchronization
-		// issues. In the future we may want to reorder them but that requires us to keep
-		// buffers and complicate the whole process.
+		// We ignore out of order packets that the jitter buffer couldn't
+		// reorder (e.g. arrived too late) as they would cause synchronization
+		// issues.
 		if pkt.Timestamp < prevRTPTimestamp {
 			slog.Debug("out of order packet",
 				slog.Int("diff", int(pkt.Timestamp)-int(prevRTPTimestamp)),
@@ -188,13 +381,49 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 			slog.Debug("ts wrap around detected", slog.String("trackID", ctx.trackID))
 		}
 
+		// Only the first DTX packet of a silent period is saved, as a marker
+		// for the gap that follows, so that mostly muted participants don't
+		// produce large files full of comfort noise.
+		dtx := audio.IsOpusDTX(pkt.Payload)
+		if dtx {
+			dtxPkts++
+			if !hasAudio || inDTX {
+				if hasAudio {
+					// DTX packets still take sequence numbers so they
+					// mustn't be mistaken for lost ones.
+					prevSeq = pkt.SequenceNumber
+				}
+				continue
+			}
+		}
+		inDTX = dtx
+
 		var gap uint64
-		if prevArrivalTime.IsZero() {
-			ctx.startTS = time.Since(*t.startTime.Load()).Milliseconds()
+		if !hasAudio {
+			// A start time in the future can only be the result of clock skew
+			// between instances so we clamp the offset to zero.
+			ctx.startTS = max(0, time.Since(*t.startTime.Load()).Milliseconds())
+			if preStartBuffer > 0 {
+				// The offset is taken at arrival as the packet may have been
+				// buffered, in which case it's negative as the audio precedes
+				// the recording start. Such pre-roll is trimmed when
+				// transcribing.
+				offset := time.Since(*t.startTime.Load()) - (t.monoNow() - now)
+				ctx.startTS = max(-preStartBuffer.Milliseconds(), offset.Milliseconds())
+			}
 			slog.Debug("start offset for track",
 				slog.Duration("offset", time.Duration(ctx.startTS)*time.Millisecond),
 				slog.String("trackID", ctx.trackID))
-		} else if receiveGap := time.Since(prevArrivalTime); receiveGap > audioGapThreshold {
+
+			// Some clients (e.g. sharing system audio) send stereo, which
+			// can only be told from the packets themselves.
+			if channels := audio.OpusPacketChannels(pkt.Payload); channels != trackAudioChannels {
+				slog.Debug("multi-channel track", slog.Int("channels", channels), slog.String("trackID", ctx.trackID))
+				if err := oggWriter.SetChannelCount(uint16(channels)); err != nil {
+					slog.Error("failed to set track channel count", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+				}
+			}
+		} else if receiveGap := now - prevArrivalTime; receiveGap > audioGapThreshold {
 			// If the last received audio packet was more than a audioGapThreshold
 			// ago we may need to fix the RTP timestamp as some clients (e.g. Firefox) will
 			// simply resume from where they left.
@@ -220,20 +449,48 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 			}
 		}
 
-		prevArrivalTime = time.Now()
-		prevRTPTimestamp = pkt.Timestamp
+		// Packets lost along the way are concealed when decoding for live
+		// captions.
+		var lostPkts int
+		if hasAudio {
+			lostPkts = max(0, seqDiff(pkt.SequenceNumber, prevSeq)-1)
+			stats.lost.Add(uint64(lostPkts))
+		}
 
-		if err := oggWriter.WriteRTP(pkt, gap); err != nil {
+		hasAudio = true
+		prevArrivalTime = now
+		prevRTPTimestamp = pkt.Timestamp
+		prevSeq = pkt.SequenceNumber
+
+		if t.lowDiskSpace.Load() {
+			// Rather than failing with write errors we stop saving audio
+			// until space is available again so that what was captured so far
+			// can still be transcribed and published. Since the RTP timestamp
+			// keeps advancing the skipped time is accounted for on resume.
+			skippedPkts++
+		} else if err := oggWriter.WriteRTP(pkt, gap); err != nil {
 			slog.Error("failed to write RTP packet",
 				slog.String("err", err.Error()),
 				slog.String("trackID", ctx.trackID))
+		} else {
+			ctx.audioDur += trackAudioFrameSizeMs * time.Millisecond
 		}
 
-		if t.cfg.LiveCaptionsOn {
+		if maxSize := t.cfg.Capture.MaxTrackSizeBytes; maxSize > 0 && oggWriter.Size() >= maxSize {
+			slog.Warn("maximum track size reached",
+				slog.Int64("size", oggWriter.Size()),
+				slog.String("trackID", ctx.trackID))
+			t.truncate(truncationReasonMaxTrackSize)
+			return
+		}
+
+		// Silence isn't worth captioning and would only cause spurious
+		// speech detections.
+		if t.cfg.LiveCaptions.On && !dtx && now >= captionsFrom {
 			select {
-			case pktPayloadCh <- pkt.Payload:
+			case pktPayloadCh <- captionsPkt{payload: pkt.Payload, lost: lostPkts}:
 			default:
-				if err := t.client.SendWS(wsEvMetric, public.MetricMsg{
+				if err := t.client.Load().SendWS(wsEvMetric, public.MetricMsg{
 					SessionID:  ctx.sessionID,
 					MetricName: public.MetricLiveCaptionsPktPayloadChBufFull,
 				}, false); err != nil {
@@ -251,34 +508,119 @@ func (t *Transcriber) processLiveTrack(track trackRemote, sessionID string) {
 func (t *Transcriber) handleClose() error {
 	slog.Debug("handleClose")
 
+	t.endTime.Store(newTimeP(time.Now()))
+
 	t.liveTracksWg.Wait()
 	close(t.trackCtxs)
+	defer t.trPool.close()
+	defer t.stopFasterWhisperSidecar()
 
 	t.captionsPoolWg.Wait()
+	t.closeCaptionsFiles()
 
 	slog.Debug("live tracks processing done, starting post processing")
+	t.setState(StatePostProcessing)
 	start := time.Now()
 
+	apis := []config.TranscribeAPI{t.cfg.Engine.TranscribeAPI}
+	if t.cfg.Engine.TranscribeAPICompare != "" {
+		slog.Info("comparison mode enabled",
+			slog.String("api", string(t.cfg.Engine.TranscribeAPI)),
+			slog.String("compareAPI", string(t.cfg.Engine.TranscribeAPICompare)))
+		apis = append(apis, t.cfg.Engine.TranscribeAPICompare)
+	}
+
 	var samplesDur time.Duration
-	var tr transcribe.Transcription
+	audioDurs := make(map[string]time.Duration)
+	speechDurs := make(map[string]time.Duration)
+	trs := make([]transcribe.Transcription, len(apis))
+	sessionTrs := make([]map[string][]transcribe.TrackTranscription, len(apis))
+	for i := range sessionTrs {
+		sessionTrs[i] = make(map[string][]transcribe.TrackTranscription)
+	}
+	// The channel is closed at this point so we can collect all the tracks
+	// left to process.
+	ctxs := make([]trackContext, 0, len(t.trackCtxs))
 	for ctx := range t.trackCtxs {
+		ctxs = append(ctxs, ctx)
+	}
+
+	// Checkpointing is best effort, failing to save it should only affect
+	// our ability to resume after a crash or to re-transcribe later on.
+	cp := t.newCheckpoint(apis, ctxs)
+	if err := t.saveCheckpoint(cp); err != nil {
+		slog.Error("failed to save checkpoint", slog.String("err", err.Error()))
+	}
+	if err := t.saveTracksRecord(cp); err != nil {
+		slog.Error("failed to save tracks record", slog.String("err", err.Error()))
+	}
+
+	numTracks := len(ctxs)
+	for trackIdx, ctx := range ctxs {
 		slog.Debug("post processing track", slog.String("trackID", ctx.trackID))
+		t.reportProgress()
 
-		trackTr, dur, err := t.transcribeTrack(ctx)
-		if err != nil {
-			slog.Error("failed to transcribe track", slog.String("trackID", ctx.trackID), slog.String("err", err.Error()))
-			return fmt.Errorf("failed to transcribe track: %w", err)
+		// Progress reporting is best effort, it shouldn't fail the job.
+		if err := t.ReportJobProgress(float64(trackIdx)/float64(numTracks)*100,
+			fmt.Sprintf("transcribing %d/%d tracks", trackIdx+1, numTracks)); err != nil {
+			slog.Error("failed to report job progress", slog.String("err", err.Error()))
+		}
+
+		var trackTrs []transcribe.TrackTranscription
+		var dur time.Duration
+		if tc := cp.Tracks[trackIdx]; tc.Done {
+			slog.Debug("track already transcribed, using checkpoint", slog.String("trackID", ctx.trackID))
+			trackTrs = tc.Transcriptions
+			dur = time.Duration(tc.SpeechDurMs) * time.Millisecond
+			if tc.Voiceprint != nil {
+				t.setTrackVoiceprint(ctx.trackID, ctx.sessionID, tc.Voiceprint)
+			}
+			if tc.VADSegments != nil {
+				t.setTrackVADSegments(ctx.trackID, tc.VADSegments)
+			}
+		} else {
+			var err error
+			trackTrs, dur, err = t.transcribeTrackWithAPIs(ctx, apis)
+			if err != nil {
+				slog.Error("failed to transcribe track", slog.String("trackID", ctx.trackID), slog.String("err", err.Error()))
+				return fmt.Errorf("failed to transcribe track: %w", err)
+			}
+
+			cp.Tracks[trackIdx].Done = true
+			cp.Tracks[trackIdx].Transcriptions = trackTrs
+			cp.Tracks[trackIdx].SpeechDurMs = dur.Milliseconds()
+			cp.Tracks[trackIdx].Voiceprint = t.getTrackVoiceprint(ctx.trackID)
+			cp.Tracks[trackIdx].VADSegments = t.getTrackVADSegments(ctx.trackID)
+			if err := t.saveCheckpoint(cp); err != nil {
+				slog.Error("failed to save checkpoint", slog.String("err", err.Error()))
+			}
 		}
 
 		samplesDur += dur
+		audioDurs[ctx.sessionID] += ctx.audioDur
+		speechDurs[ctx.sessionID] += dur
+
+		for i, trackTr := range trackTrs {
+			if len(trackTr.Segments) > 0 {
+				trs[i] = append(trs[i], trackTr)
+				sessionTrs[i][ctx.sessionID] = append(sessionTrs[i][ctx.sessionID], trackTr)
+			}
+		}
+	}
 
-		if len(trackTr.Segments) > 0 {
-			tr = append(tr, trackTr)
+	// Coverage stats are reported even if the transcription turned out empty,
+	// as that's when they are the most useful.
+	reportDone := func() {
+		if err := t.ReportJobDone(t.getSpeakerStats(audioDurs, speechDurs)); err != nil {
+			slog.Error("failed to report job done", slog.String("err", err.Error()))
 		}
 	}
 
-	if len(tr) == 0 {
+	outputs := t.newTranscriptionOutputs(apis, trs, sessionTrs)
+	if len(outputs) == 0 {
 		slog.Warn("nothing to do, empty transcription")
+		t.removeCheckpoint()
+		reportDone()
 		return nil
 	}
 
@@ -286,15 +628,80 @@ func (t *Transcriber) handleClose() error {
 	slog.Debug(fmt.Sprintf("transcription process completed for all tracks: transcribed %v of audio in %v, %0.2fx",
 		samplesDur, dur, samplesDur.Seconds()/dur.Seconds()))
 
-	if err := t.publishTranscription(tr); err != nil {
+	// Speech detection is shared by all APIs so its segments only need
+	// publishing once.
+	if t.cfg.Output.VADSegments {
+		outputs[0].vadSegments = t.getVADSegments(ctxs)
+	}
+
+	// Post-processing happens before anything gets rendered so that all
+	// formats (and the summary) are generated from the hook's result.
+	if err := t.postProcessOutputs(outputs); err != nil {
+		return fmt.Errorf("failed to post-process transcription: %w", err)
+	}
+
+	// The local summary is published along with the transcription so it's
+	// generated beforehand. As with the AI plugin, failing to summarize
+	// shouldn't fail the job.
+	if t.cfg.Publish.GenerateSummary && t.cfg.Publish.SummaryBackend == config.SummaryBackendLocal {
+		if err := t.ReportJobProgress(100, "summarizing"); err != nil {
+			slog.Error("failed to report job progress", slog.String("err", err.Error()))
+		}
+
+		if summary, err := t.generateLocalSummary(outputs[0].tr); err != nil {
+			slog.Error("failed to generate local summary", slog.String("err", err.Error()))
+		} else {
+			outputs[0].summary = summary
+			slog.Debug("local summary generated successfully")
+		}
+	}
+
+	if err := t.ReportJobProgress(100, "publishing"); err != nil {
+		slog.Error("failed to report job progress", slog.String("err", err.Error()))
+	}
+
+	if err := t.publishTranscriptions(outputs); err != nil {
 		return fmt.Errorf("failed to publish transcription: %w", err)
 	}
 
 	slog.Debug("transcription published successfully")
 
+	// Once published there's nothing left to resume.
+	t.removeCheckpoint()
+
+	if t.cfg.Publish.GenerateSummary && t.cfg.Publish.SummaryBackend != config.SummaryBackendLocal && !t.cfg.Publish.DryRun {
+		// A failure to summarize shouldn't fail the job since the transcription
+		// has already been published at this point.
+		if err := t.generateSummary(outputs[0].tr); err != nil {
+			slog.Error("failed to generate summary", slog.String("err", err.Error()))
+		} else {
+			slog.Debug("summary generated successfully")
+		}
+	}
+
+	reportDone()
+
 	return nil
 }
 
+// getSpeakerColorIndex returns the color index for the given session.
+// Indexes are assigned in order of appearance so that the same session keeps
+// its color across tracks (e.g. after reconnecting), both for live captions
+// and in the final transcription.
+func (t *Transcriber) getSpeakerColorIndex(sessionID string) int {
+	t.speakerColorsMut.Lock()
+	defer t.speakerColorsMut.Unlock()
+
+	if idx, ok := t.speakerColors[sessionID]; ok {
+		return idx
+	}
+
+	idx := len(t.speakerColors) % speakerColorsNum
+	t.speakerColors[sessionID] = idx
+
+	return idx
+}
+
 // trackTimedSamples is used to account for potential gaps in
 // voice tracks due to mute/unmute sequences. Each spoken segment
 // will have a relative time offset (startTS).
@@ -303,236 +710,464 @@ type trackTimedSamples struct {
 	startTS int64
 }
 
-// decodeAudio reads a track OGG file and decodes its audio into raw PCM samples
-// for later processing.
-func (ctx trackContext) decodeAudio() ([]trackTimedSamples, error) {
-	trackFile, err := os.Open(ctx.filename)
-	defer trackFile.Close()
+// transcribeTrack feeds track's raw audio samples to a transcription engine (e.g. whisper)
+// and outputs a transcription.
+func (t *Transcriber) transcribeTrack(ctx trackContext) (transcribe.TrackTranscription, time.Duration, error) {
+	trackTrs, dur, err := t.transcribeTrackWithAPIs(ctx, []config.TranscribeAPI{t.cfg.Engine.TranscribeAPI})
+	return trackTrs[0], dur, err
+}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to open track file: %w", err)
+// transcribeTrackWithAPIs is like transcribeTrack but runs the detected speech
+// through each of the given transcription APIs, so that decoding and speech
+// detection only happen once per track. The returned transcriptions are in the
+// same order as apis.
+func (t *Transcriber) transcribeTrackWithAPIs(ctx trackContext, apis []config.TranscribeAPI) ([]transcribe.TrackTranscription, time.Duration, error) {
+	trackTrs := make([]transcribe.TrackTranscription, len(apis))
+	for i := range trackTrs {
+		trackTrs[i].Speaker = t.getSpeakerName(ctx.user)
+		trackTrs[i].ColorIndex = ctx.colorIndex
 	}
 
-	oggReader, _, err := ogg.NewReaderWith(trackFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create new ogg reader: %w", err)
+	// Loudness is normalized, once noise is removed, so that quiet speakers
+	// are detected and transcribed as well as loud ones. So that tracks are
+	// decoded only once, it's measured as audio is read: each chunk is
+	// normalized by the loudness of the track up to the chunk's end, which
+	// for tracks fitting in a single chunk is the whole track.
+	var meter *loudness.Meter
+	if !t.cfg.Engine.DisableLoudnessNormalization {
+		meter = &loudness.Meter{}
+		defer func() {
+			slog.Debug("loudness normalized",
+				slog.Float64("gainDB", meter.Gain()),
+				slog.String("trackID", ctx.trackID))
+		}()
 	}
 
-	opusDec, err := opus.NewDecoder(trackOutAudioRate, trackAudioChannels)
+	sd, err := newSpeechDetector(t.cfg.Engine.VAD)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create opus decoder: %w", err)
+		return trackTrs, 0, fmt.Errorf("failed to ceate speech detector: %w", err)
 	}
 	defer func() {
-		if err := opusDec.Destroy(); err != nil {
-			slog.Error("failed to destroy decoder",
-				slog.String("err", err.Error()),
-				slog.String("trackID", ctx.trackID))
+		if err := sd.Destroy(); err != nil {
+			slog.Error("failed to destroy speech detector", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
 		}
 	}()
 
-	slog.Debug("decoding track", slog.String("trackID", ctx.trackID))
+	var stats *voiceprint.Stats
+	if t.cfg.Output.SpeakerEmbeddings {
+		stats = &voiceprint.Stats{}
+	}
 
-	pcmBuf := make([]float32, trackOutFrameSize)
-	// TODO: consider pre-calculating track duration to minimize memory waste.
-	samples := make([]trackTimedSamples, 1)
+	var vadSegments []vadSegment
 
-	var prevGP uint64
-	for {
-		data, hdr, err := oggReader.ParseNextPage()
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
-			slog.Error("failed to parse ogg page",
-				slog.String("err", err.Error()),
-				slog.String("trackID", ctx.trackID))
+	// Each API carries its own prompt as transcriptions differ between them.
+	prompts := make([]*rollingPrompt, len(apis))
+	for i, api := range apis {
+		prompts[i] = newRollingPrompt(t.rollingPromptTokens(api))
+	}
+
+	// With the Azure batch API speech is gathered and transcribed at once,
+	// after the whole track is read.
+	batches := make([]*azureBatchTrack, len(apis))
+	for i, api := range apis {
+		if !t.azureBatchEnabled(api) {
 			continue
 		}
+		batches[i], err = newAzureBatchTrack()
+		if err != nil {
+			return trackTrs, 0, err
+		}
+		defer batches[i].remove()
+	}
 
-		// Ignoring first page which only contains metadata.
-		if hdr.GranulePosition == 0 {
-			continue
+	// Transcribers are created on the first speech detected and kept for the
+	// whole track, rather than for every chunk.
+	keys := make([]transcriberKey, len(apis))
+	for i, api := range apis {
+		keys[i] = t.trackTranscriberKey(api)
+	}
+	transcribers := make([]transcribe.Transcriber, len(apis))
+	defer func() {
+		for i, transcriber := range transcribers {
+			if transcriber == nil {
+				continue
+			}
+			if err := t.trPool.put(keys[i], transcriber); err != nil {
+				slog.Error("failed to release track transcriber",
+					slog.String("err", err.Error()),
+					slog.String("api", string(apis[i])),
+					slog.String("trackID", ctx.trackID))
+			}
 		}
+	}()
 
-		if hdr.GranulePosition > prevGP+trackInFrameSize {
-			gap := time.Duration((hdr.GranulePosition-prevGP)/trackInAudioSamplesPerMs) * time.Millisecond
-			slog.Debug("gap in audio samples", slog.Duration("gap", gap))
-			// If there's enough of a gap in the audio (audioGapThreshold) we split and
-			// update the start time accordingly.
-			if gap > audioGapThreshold {
-				samples = append(samples, trackTimedSamples{
-					startTS: int64(hdr.GranulePosition) / trackInAudioSamplesPerMs,
+	// Speech is transcribed as soon as it's detected so that only the chunk
+	// being processed is held in memory.
+	var totalDur time.Duration
+	var speechSamplesNum int
+	transcribeSpeech := func(ts trackTimedSamples) error {
+		speechSamplesNum++
+		totalDur += time.Duration(len(ts.pcm)/trackOutAudioSamplesPerMs) * time.Millisecond
+		if stats != nil {
+			stats.Add(ts.pcm)
+		}
+		if t.cfg.Output.VADSegments {
+			startMs := ctx.startTS + ts.startTS
+			endMs := startMs + int64(len(ts.pcm)/trackOutAudioSamplesPerMs)
+			// Pre-roll is trimmed as for transcribed segments.
+			if endMs > 0 {
+				vadSegments = append(vadSegments, vadSegment{
+					StartMs: max(0, startMs),
+					EndMs:   endMs,
 				})
 			}
 		}
-		prevGP = hdr.GranulePosition
+		for i := range apis {
+			if batches[i] != nil {
+				t.reportProgress()
+				if err := batches[i].add(ts); err != nil {
+					return err
+				}
+				continue
+			}
+			if transcribers[i] == nil {
+				transcriber, err := t.trPool.get(keys[i])
+				if err != nil {
+					return fmt.Errorf("failed to create track transcriber: %w", err)
+				}
+				transcribers[i] = transcriber
+			}
+			if err := t.transcribeSpeechSamples(ctx, keys[i], transcribers[i], []trackTimedSamples{ts}, prompts[i], &trackTrs[i]); err != nil {
+				// A failing transcriber isn't given back to the pool.
+				if err := transcribers[i].Destroy(); err != nil {
+					slog.Error("failed to destroy track transcriber", slog.String("err", err.Error()))
+				}
+				transcribers[i] = nil
+				return err
+			}
+		}
+		return nil
+	}
 
-		n, err := opusDec.Decode(data, pcmBuf)
-		if err != nil {
-			slog.Error("failed to decode audio data",
-				slog.String("err", err.Error()),
-				slog.Any("data", data),
-				slog.String("trackID", ctx.trackID))
+	// Speech still going on at the end of a chunk is carried over to the next
+	// one, if it directly follows, so that it's detected and transcribed as a
+	// whole.
+	var carry *trackTimedSamples
+	if err := t.readTrackAudio(ctx, func(chunk audioChunk) error {
+		if meter != nil {
+			meter.Add(chunk.pcm)
+			if gainDB := meter.Gain(); gainDB != 0 {
+				loudness.Apply(chunk.pcm, gainDB)
+			}
 		}
 
-		samples[len(samples)-1].pcm = append(samples[len(samples)-1].pcm, pcmBuf[:n]...)
-	}
+		ts := chunk.trackTimedSamples
+		if carry != nil {
+			if chunk.cont {
+				ts = trackTimedSamples{
+					pcm:     append(carry.pcm, chunk.pcm...),
+					startTS: carry.startTS,
+				}
+			} else if err := transcribeSpeech(*carry); err != nil {
+				return err
+			}
+			carry = nil
+		}
 
-	return samples, nil
-}
+		speechSamples, open := t.detectSpeech(ctx, sd, ts)
+		if open != nil && len(open.pcm) < trackDecodeChunkSamples {
+			// Copying so that the rest of the chunk can be released.
+			carry = &trackTimedSamples{
+				pcm:     append(make([]float32, 0, len(open.pcm)+trackDecodeChunkSamples), open.pcm...),
+				startTS: open.startTS,
+			}
+		} else if open != nil {
+			speechSamples = append(speechSamples, *open)
+		}
 
-// transcribeTrack feeds track's raw audio samples to a transcription engine (e.g. whisper)
-// and outputs a transcription.
-func (t *Transcriber) transcribeTrack(ctx trackContext) (transcribe.TrackTranscription, time.Duration, error) {
-	trackTr := transcribe.TrackTranscription{
-		Speaker: ctx.user.GetDisplayName(model.ShowFullName),
+		for _, ts := range speechSamples {
+			if err := transcribeSpeech(ts); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return trackTrs, 0, err
 	}
 
-	samples, err := ctx.decodeAudio()
-	if err != nil {
-		return trackTr, 0, fmt.Errorf("failed to decode audio samples: %w", err)
+	if carry != nil {
+		if err := transcribeSpeech(*carry); err != nil {
+			return trackTrs, 0, err
+		}
+	}
+
+	for i, b := range batches {
+		if b == nil {
+			continue
+		}
+		if err := t.transcribeAzureBatch(ctx, b, &trackTrs[i]); err != nil {
+			return trackTrs, 0, err
+		}
 	}
 
-	slog.Debug("decoding done", slog.Any("samplesLen", len(samples)))
+	slog.Debug("speech detection done", slog.Int("speechSamples", speechSamplesNum))
 
-	transcriber, err := t.newTrackTranscriber()
-	if err != nil {
-		return trackTr, 0, fmt.Errorf("failed to create track transcriber: %w", err)
+	if stats != nil {
+		t.setTrackVoiceprint(ctx.trackID, ctx.sessionID, stats)
+	}
+	if t.cfg.Output.VADSegments {
+		t.setTrackVADSegments(ctx.trackID, vadSegments)
 	}
 
-	sd, err := speech.NewDetector(speech.DetectorConfig{
-		ModelPath:   filepath.Join(getModelsDir(), "silero_vad.onnx"),
-		SampleRate:  trackOutAudioRate,
-		Threshold:   0.5,
-		SpeechPadMs: 100,
+	return trackTrs, totalDur, nil
+}
 
-		// 2 seconds of silence is a good threshold that allows us not to split speech portions excessively
-		// which in turn will improve the transcribing performance as there will be less overhead.
-		MinSilenceDurationMs: 2000,
-	})
+// readTrackAudio decodes the track's audio, passing it to fn chunk by chunk
+// once clipped to the requested range and denoised.
+func (t *Transcriber) readTrackAudio(ctx trackContext, fn func(chunk audioChunk) error) error {
+	dec, err := ctx.newAudioDecoder()
 	if err != nil {
-		return trackTr, 0, fmt.Errorf("failed to ceate speech detector: %w", err)
+		return fmt.Errorf("failed to decode audio samples: %w", err)
 	}
 	defer func() {
-		if err := sd.Destroy(); err != nil {
-			slog.Error("failed to destroy speech detector", slog.String("err", err.Error()), slog.String("trackID", ctx.trackID))
+		if err := dec.Close(); err != nil {
+			slog.Error("failed to close audio decoder",
+				slog.String("err", err.Error()),
+				slog.String("trackID", ctx.trackID))
 		}
 	}()
 
-	// Before transcribing, we feed the samples to a speech detector and adjust
-	// the timestamps in accordance to when the speech begins/ends. This is
-	// to account for any potential silence that Whisper wouldn't recognize with
-	// much accuracy.
-	// TODO: consider deprecating this logic if we get accurate word level timestamps
-	// (https://github.com/ggerganov/whisper.cpp/issues/375).
+	for {
+		chunk, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to decode audio samples: %w", err)
+		}
+
+		if ctx.clip != nil {
+			// The range is relative to the call while samples are relative to
+			// the track.
+			clipped := clipTimedSamples([]trackTimedSamples{chunk.trackTimedSamples}, ctx.clip.StartMs-ctx.startTS, ctx.clip.EndMs-ctx.startTS)
+			if len(clipped) == 0 {
+				continue
+			}
+			chunk.trackTimedSamples = clipped[0]
+		}
 
-	var speechSamples []trackTimedSamples
-	for _, ts := range samples {
-		if len(ts.pcm) == 0 {
+		if len(chunk.pcm) == 0 {
 			slog.Warn("unexpected empty audio samples",
 				slog.String("trackID", ctx.trackID))
 			continue
 		}
 
-		// We need to reset the speech detector's state from one chunk of samples
-		// to the next.
-		if err := sd.Reset(); err != nil {
-			slog.Error("failed to reset speech detector",
-				slog.String("err", err.Error()),
-				slog.String("trackID", ctx.trackID))
+		// Background noise is removed first as it can otherwise be detected as
+		// speech, and whisper tends to hallucinate on it.
+		if t.cfg.Engine.Denoise {
+			chunk.pcm = denoise.Reduce(chunk.pcm)
 		}
 
-		segments, err := sd.Detect(ts.pcm)
-		if err != nil {
-			slog.Warn("failed to detect speech",
-				slog.String("err", err.Error()),
-				slog.String("trackID", ctx.trackID))
-
-			// As a fallback in case of failure, we keep the original samples.
-			speechSamples = append(speechSamples, ts)
-			continue
+		if err := fn(chunk); err != nil {
+			return err
 		}
-		slog.Debug("speech detection done", slog.Any("segments", segments))
+	}
 
-		for _, seg := range segments {
-			// Both SpeechStartAt and SpeechEndAt are in seconds.
-			// We simply multiply by the audio sampling rate to find out
-			// the index of the sample where speech starts/ends.
-			startSampleOff := int(seg.SpeechStartAt * trackOutAudioRate)
-			endSampleOff := int(seg.SpeechEndAt * trackOutAudioRate)
+	return nil
+}
 
-			if startSampleOff >= len(ts.pcm) {
-				slog.Error("invalid startSampleOff",
-					slog.Int("startSampleOff", startSampleOff),
-					slog.String("trackID", ctx.trackID))
-				continue
-			}
+// detectSpeech returns the portions of the given samples holding speech.
+// Speech going on up to the end of the samples is returned separately as
+// open.
+//
+// Before transcribing, we feed the samples to a speech detector and adjust
+// the timestamps in accordance to when the speech begins/ends. This is
+// to account for any potential silence that Whisper wouldn't recognize with
+// much accuracy.
+// TODO: consider deprecating this logic if we get accurate word level timestamps
+// (https://github.com/ggerganov/whisper.cpp/issues/375).
+func (t *Transcriber) detectSpeech(ctx trackContext, sd speechDetector, ts trackTimedSamples) (speechSamples []trackTimedSamples, open *trackTimedSamples) {
+	// We need to reset the speech detector's state from one chunk of samples
+	// to the next.
+	if err := sd.Reset(); err != nil {
+		slog.Error("failed to reset speech detector",
+			slog.String("err", err.Error()),
+			slog.String("trackID", ctx.trackID))
+	}
 
-			var speechPCM []float32
-			if endSampleOff > startSampleOff {
-				speechPCM = ts.pcm[startSampleOff:endSampleOff]
-			} else {
-				speechPCM = ts.pcm[startSampleOff:]
-			}
+	segments, err := sd.Detect(ts.pcm)
+	if err != nil {
+		slog.Warn("failed to detect speech",
+			slog.String("err", err.Error()),
+			slog.String("trackID", ctx.trackID))
 
-			speechSamples = append(speechSamples, trackTimedSamples{
-				pcm: speechPCM,
-				// Multiplying as our timestamps are in milliseconds.
-				startTS: ts.startTS + int64(seg.SpeechStartAt*1000),
-			})
+		// As a fallback in case of failure, we keep the original samples.
+		return []trackTimedSamples{ts}, nil
+	}
+	slog.Debug("speech detection done", slog.Any("segments", segments))
+
+	for _, seg := range segments {
+		// Both SpeechStartAt and SpeechEndAt are in seconds.
+		// We simply multiply by the audio sampling rate to find out
+		// the index of the sample where speech starts/ends.
+		startSampleOff := int(seg.SpeechStartAt * trackOutAudioRate)
+		endSampleOff := int(seg.SpeechEndAt * trackOutAudioRate)
+
+		if startSampleOff >= len(ts.pcm) {
+			slog.Error("invalid startSampleOff",
+				slog.Int("startSampleOff", startSampleOff),
+				slog.String("trackID", ctx.trackID))
+			continue
+		}
+
+		speech := trackTimedSamples{
+			// Multiplying as our timestamps are in milliseconds.
+			startTS: ts.startTS + int64(seg.SpeechStartAt*1000),
+		}
+		if endSampleOff > startSampleOff {
+			speech.pcm = ts.pcm[startSampleOff:min(endSampleOff, len(ts.pcm))]
+		} else {
+			speech.pcm = ts.pcm[startSampleOff:]
+			open = &speech
+			continue
 		}
+
+		speechSamples = append(speechSamples, speech)
 	}
 
-	slog.Debug("speech detection done", slog.Any("speechSamples", len(speechSamples)))
+	return speechSamples, open
+}
+
+// transcribeSpeechSamples transcribes the given speech samples through the
+// transcriber of the requested API and appends the resulting segments to
+// trackTr. If a prompt is given, and supported, each transcription is
+// conditioned on it and the resulting text added to it.
+func (t *Transcriber) transcribeSpeechSamples(ctx trackContext, key transcriberKey, transcriber transcribe.Transcriber, speechSamples []trackTimedSamples, prompt *rollingPrompt, trackTr *transcribe.TrackTranscription) error {
+	ps, _ := transcriber.(promptSetter)
+	if !prompt.enabled() {
+		ps = nil
+	}
 
-	var totalDur time.Duration
 	for _, ts := range speechSamples {
-		segments, lang, err := transcriber.Transcribe(ts.pcm)
+		t.reportProgress()
+
+		var promptText string
+		if ps != nil {
+			promptText = prompt.String()
+			ps.SetPrompt(promptText)
+		}
+
+		segments, lang, err := t.transcribeCached(key, transcriber, ts.pcm, promptText)
 		if err != nil {
 			slog.Error("failed to transcribe audio samples",
 				slog.String("err", err.Error()),
+				slog.String("api", string(key.api)),
 				slog.String("trackID", ctx.trackID))
-			return trackTr, 0, fmt.Errorf("failed to transcribe audio samples: %w", err)
+			return fmt.Errorf("failed to transcribe audio samples: %w", err)
 		}
 
 		if lang != "" && trackTr.Language == "" {
 			trackTr.Language = lang
 		}
 
-		samplesDur := time.Duration(len(ts.pcm)/trackOutAudioSamplesPerMs) * time.Millisecond
-		totalDur += samplesDur
-
-		for _, s := range segments {
+		for _, s := range transcribe.FilterSegments(segments, t.outputFilterRE) {
+			if s.Language == "" {
+				s.Language = lang
+			}
 			s.StartTS += ts.startTS + ctx.startTS
 			s.EndTS += ts.startTS + ctx.startTS
+			if !trimPreRoll(&s) {
+				continue
+			}
 			trackTr.Segments = append(trackTr.Segments, s)
+			if ps != nil {
+				prompt.add(s.Text)
+			}
 		}
 	}
 
-	if err := transcriber.Destroy(); err != nil {
-		return trackTr, 0, fmt.Errorf("failed to destroy track transcriber: %w", err)
+	return nil
+}
+
+// trimPreRoll clips s to the recording, as audio buffered before it started
+// (see PreStartBufferMs) has negative timestamps. It returns false if s lies
+// entirely before the start.
+func trimPreRoll(s *transcribe.Segment) bool {
+	if s.StartTS < 0 && s.EndTS <= 0 {
+		return false
 	}
+	s.StartTS = max(0, s.StartTS)
+	return true
+}
 
-	return trackTr, totalDur, nil
+// trackTranscriberKey returns the key of the transcriber used for tracks with
+// the given API. Tracks are currently transcribed with the engine model and
+// automatic language detection.
+func (t *Transcriber) trackTranscriberKey(api config.TranscribeAPI) transcriberKey {
+	return transcriberKey{api: api, modelSize: t.cfg.Engine.ModelSize}
 }
 
-func (t *Transcriber) newTrackTranscriber() (transcribe.Transcriber, error) {
-	switch t.cfg.TranscribeAPI {
+func (t *Transcriber) newTrackTranscriber(key transcriberKey) (transcribe.Transcriber, error) {
+	switch key.api {
 	case config.TranscribeAPIWhisperCPP:
+		suppressNonSpeechTokens, _ := t.cfg.Engine.TranscribeAPIOptions["WHISPER_SUPPRESS_NON_SPEECH_TOKENS"].(bool)
+		suppressRegex, _ := t.cfg.Engine.TranscribeAPIOptions["WHISPER_SUPPRESS_REGEX"].(string)
 		return whisper.NewContext(whisper.Config{
-			ModelFile:     filepath.Join(getModelsDir(), fmt.Sprintf("ggml-%s.bin", string(t.cfg.ModelSize))),
-			NumThreads:    t.cfg.NumThreads,
-			PrintProgress: true,
+			ModelFile:               filepath.Join(getModelsDir(), fmt.Sprintf("ggml-%s.bin", string(key.modelSize))),
+			NumThreads:              t.cfg.Engine.NumThreads,
+			Language:                key.language,
+			PrintProgress:           true,
+			SuppressNonSpeechTokens: suppressNonSpeechTokens,
+			SuppressRegex:           suppressRegex,
+			// With a rolling prompt the context carried over is explicit and
+			// bounded to the track.
+			NoContext: t.rollingPromptTokens(key.api) > 0,
 		})
 	case config.TranscribeAPIAzure:
-		speechKey, _ := t.cfg.TranscribeAPIOptions["AZURE_SPEECH_KEY"].(string)
-		speechRegion, _ := t.cfg.TranscribeAPIOptions["AZURE_SPEECH_REGION"].(string)
-		return azure.NewSpeechRecognizer(azure.SpeechRecognizerConfig{
-			SpeechKey:    speechKey,
-			SpeechRegion: speechRegion,
-			DataDir:      getDataDir(),
-		})
+		return azure.NewSpeechRecognizer(t.azureSpeechRecognizerConfig(key.language))
+	case config.TranscribeAPIExternal:
+		return external.NewTranscriber(t.externalTranscriberConfig(key.language))
+	case config.TranscribeAPIFasterWhisper:
+		return t.newFasterWhisperTranscriber(key.language)
 	default:
-		return nil, fmt.Errorf("transcribe API %q not implemented", t.cfg.TranscribeAPI)
+		return nil, fmt.Errorf("transcribe API %q not implemented", key.api)
+	}
+}
+
+// externalTranscriberConfig returns the config of the external API, as set
+// in the transcribe API options, for the given language (auto-detected if
+// empty).
+func (t *Transcriber) externalTranscriberConfig(language string) external.Config {
+	apiURL, _ := t.cfg.Engine.TranscribeAPIOptions["EXTERNAL_API_URL"].(string)
+	token, _ := t.cfg.Engine.TranscribeAPIOptions["EXTERNAL_API_TOKEN"].(string)
+
+	return external.Config{
+		URL:      apiURL,
+		Token:    token,
+		Language: language,
+	}
+}
+
+// azureSpeechRecognizerConfig returns the Azure recognizer config, as set in
+// the transcribe API options, for the given language (auto-detected if
+// empty).
+func (t *Transcriber) azureSpeechRecognizerConfig(language string) azure.SpeechRecognizerConfig {
+	opts := t.cfg.Engine.TranscribeAPIOptions
+	speechKey, _ := opts["AZURE_SPEECH_KEY"].(string)
+	speechRegion, _ := opts["AZURE_SPEECH_REGION"].(string)
+	endpoint, _ := opts["AZURE_SPEECH_ENDPOINT"].(string)
+	host, _ := opts["AZURE_SPEECH_HOST"].(string)
+	proxy, _ := opts["AZURE_SPEECH_PROXY"].(string)
+	phraseList, _ := config.StringListOption(opts["AZURE_PHRASE_LIST"])
+
+	return azure.SpeechRecognizerConfig{
+		SpeechKey:    speechKey,
+		SpeechRegion: speechRegion,
+		Endpoint:     endpoint,
+		Host:         host,
+		Proxy:        proxy,
+		Language:     language,
+		DataDir:      getDataDir(),
+		PhraseList:   phraseList,
 	}
 }
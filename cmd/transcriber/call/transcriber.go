@@ -6,10 +6,12 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"regexp"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	fasterwhisper "github.com/mattermost/calls-transcriber/cmd/transcriber/apis/faster-whisper"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
 
 	"github.com/mattermost/mattermost/server/public/model"
@@ -20,6 +22,7 @@ const (
 	pluginID          = "com.mattermost.calls"
 	wsEvCaption       = "custom_" + pluginID + "_caption"
 	wsEvMetric        = "custom_" + pluginID + "_metric"
+	wsEvTrackStats    = "custom_" + pluginID + "_track_stats"
 	maxTracksContexes = 256
 )
 
@@ -32,9 +35,31 @@ type APIClient interface {
 type Transcriber struct {
 	cfg config.CallTranscriberConfig
 
-	client    *client.Client
+	// client is replaced when reconnecting after a connection drop.
+	client    atomic.Pointer[client.Client]
 	apiClient APIClient
 	apiURL    string
+	dialer    *netDialer
+	trPool    *transcriberPool
+	// resultCache, if set, holds the results of the speech chunks already
+	// transcribed.
+	resultCache resultCache
+
+	fwSidecarMut sync.Mutex
+	fwSidecar    *fasterwhisper.Sidecar
+
+	// published is set once the transcription has been successfully posted.
+	published      atomic.Pointer[publishResult]
+	completionOnce sync.Once
+
+	// clientHandlers are the handlers registered on every new client.
+	clientHandlers map[client.EventType]client.EventHandler
+	// closing is set when the client is closed on purpose, as opposed to the
+	// connection dropping.
+	closing atomic.Bool
+
+	reconnectGapsMut sync.Mutex
+	reconnectGaps    []reconnectGap
 
 	errCh        chan error
 	doneCh       chan struct{}
@@ -42,10 +67,64 @@ type Transcriber struct {
 	liveTracksWg sync.WaitGroup
 	trackCtxs    chan trackContext
 	startTime    atomic.Pointer[time.Time]
+	// endTime is set once the call has ended.
+	endTime atomic.Pointer[time.Time]
+	// monoNow returns the time elapsed since the transcriber was created as
+	// measured by the monotonic clock. It's used for any interval measurement
+	// (e.g. gap detection) so that wall clock steps can't affect it.
+	monoNow func() time.Duration
+
+	state          atomic.Value
+	lastProgressAt atomic.Int64
+	// lowDiskSpace is set while the data directory is short on free space.
+	lowDiskSpace  atomic.Bool
+	freeDiskSpace func(dir string) (uint64, error)
+
+	// duplicatePkts counts the duplicated RTP packets received across all tracks.
+	duplicatePkts atomic.Uint64
+	// captionsAcked and captionsUnacked count the live captions the server
+	// did or did not acknowledge, when acknowledgments are enabled.
+	captionsAcked   atomic.Uint64
+	captionsUnacked atomic.Uint64
+
+	// truncated is set to the reason capturing was stopped early, if a
+	// configured limit was exceeded.
+	truncated atomic.Pointer[truncationReason]
+
+	// outputFilterRE, if set, removes matching text from transcribed segments.
+	outputFilterRE *regexp.Regexp
+
+	speakerColorsMut sync.Mutex
+	speakerColors    map[string]int
+
+	participantsMut sync.Mutex
+	participants    []participant
+	voiceprints     map[string]trackVoiceprint
+
+	vadSegmentsMut sync.Mutex
+	vadSegments    map[string][]vadSegment
 
 	captionsPoolQueueCh chan captionPackage
 	captionsPoolWg      sync.WaitGroup
 	captionsPoolDoneCh  chan struct{}
+	captionsQueueStats  captionsQueueStats
+	captionsQuality     captionsQuality
+	captionsBudget      *captionsWindowsBudget
+	captionsScaler      *captionsScaler
+
+	captionsStatsMut sync.Mutex
+	captionsStats    map[string]captionsWindowStats
+
+	trackStats trackStatsRegistry
+
+	shadowCaptions  captionsFile
+	captionsArchive captionsFile
+
+	captionsFallback captionsFallback
+
+	// resumedCheckpoint is set if the job was resumed after a crash during
+	// post-processing.
+	resumedCheckpoint *checkpoint
 }
 
 func NewTranscriber(cfg config.CallTranscriberConfig) (t *Transcriber, retErr error) {
@@ -57,10 +136,16 @@ func NewTranscriber(cfg config.CallTranscriberConfig) (t *Transcriber, retErr er
 	apiClient.SetToken(cfg.AuthToken)
 
 	t = &Transcriber{
-		cfg:       cfg,
-		apiClient: apiClient,
-		apiURL:    apiClient.URL,
+		cfg:           cfg,
+		apiClient:     apiClient,
+		apiURL:        apiClient.URL,
+		speakerColors: make(map[string]int),
+		monoNow:       newMonotonicClock(),
+		freeDiskSpace: getFreeDiskSpace,
+		captionsStats: make(map[string]captionsWindowStats),
 	}
+	t.captionsArchive.maxSize = captionsArchiveMaxSize
+	t.setState(StateConnecting)
 
 	defer func() {
 		if retErr != nil && t != nil {
@@ -75,22 +160,30 @@ func NewTranscriber(cfg config.CallTranscriberConfig) (t *Transcriber, retErr er
 		return t, err
 	}
 
-	rtcdClient, err := client.New(client.Config{
-		SiteURL:   cfg.SiteURL,
-		AuthToken: cfg.AuthToken,
-		ChannelID: cfg.CallID,
-		JobID:     cfg.TranscriptionID,
-	})
+	t.dialer = newNetDialer(cfg.Network)
+	apiClient.HTTPClient = t.dialer.httpClient()
+	setWebSocketDialer(t.dialer)
+	t.trPool = newTranscriberPool(transcriberPoolMaxSize, t.newTrackTranscriber)
+	t.resultCache = t.newResultCache()
+
+	if re, _ := cfg.Engine.TranscribeAPIOptions["OUTPUT_FILTER_REGEX"].(string); re != "" {
+		// Already validated above.
+		t.outputFilterRE = regexp.MustCompile(re)
+	}
+
+	rtcdClient, err := t.newClient()
 	if err != nil {
 		return t, err
 	}
 
-	t.client = rtcdClient
+	t.client.Store(rtcdClient)
+	t.clientHandlers = make(map[client.EventType]client.EventHandler)
 	t.errCh = make(chan error, 1)
 	t.doneCh = make(chan struct{})
 	t.trackCtxs = make(chan trackContext, maxTracksContexes)
-	t.captionsPoolQueueCh = make(chan captionPackage, transcriberQueueChBuffer)
+	t.captionsPoolQueueCh = make(chan captionPackage, max(cfg.LiveCaptions.QueueSize, transcriberQueueChBuffer))
 	t.captionsPoolDoneCh = make(chan struct{})
+	t.captionsBudget = newCaptionsWindowsBudget(int64(cfg.LiveCaptions.WindowsBudgetMB) << 20)
 
 	return
 }
@@ -98,7 +191,7 @@ func NewTranscriber(cfg config.CallTranscriberConfig) (t *Transcriber, retErr er
 func (t *Transcriber) Start(ctx context.Context) error {
 	var connectOnce sync.Once
 	connectedCh := make(chan struct{})
-	t.client.On(client.RTCConnectEvent, func(_ any) error {
+	t.clientHandlers[client.RTCConnectEvent] = func(_ any) error {
 		slog.Debug("transcoder RTC client connected")
 
 		connectOnce.Do(func() {
@@ -106,16 +199,13 @@ func (t *Transcriber) Start(ctx context.Context) error {
 		})
 
 		return nil
-	})
-	t.client.On(client.RTCTrackEvent, t.handleTrack)
-	t.client.On(client.CloseEvent, func(_ any) error {
-		go t.done()
-		return nil
-	})
+	}
+	t.clientHandlers[client.RTCTrackEvent] = t.handleTrack
+	t.clientHandlers[client.CloseEvent] = t.handleClientClose
 
 	var startOnce sync.Once
 	startedCh := make(chan struct{})
-	t.client.On(client.WSCallRecordingState, func(ctx any) error {
+	t.clientHandlers[client.WSCallRecordingState] = func(ctx any) error {
 		if recState, ok := ctx.(client.CallJobState); ok && recState.StartAt > 0 {
 			slog.Debug("received call recording state", slog.Any("jobState", recState))
 
@@ -128,14 +218,17 @@ func (t *Transcriber) Start(ctx context.Context) error {
 				// We are coupling transcribing with recording. This means that we
 				// won't start unless a recording is on going.
 				slog.Debug("updating startAt to be in sync with recording", slog.Int64("startAt", recState.StartAt))
-				t.startTime.Store(newTimeP(time.UnixMilli(recState.StartAt)))
+				// The wall clock is only read once here to find out how long ago the
+				// recording started. The stored value carries a monotonic reading so
+				// that later measurements against it are immune to clock steps.
+				t.startTime.Store(newTimeP(time.Now().Add(-time.Since(time.UnixMilli(recState.StartAt)))))
 				close(startedCh)
 			})
 		}
 		return nil
-	})
+	}
 
-	t.client.On(client.WSJobStopEvent, func(ctx any) error {
+	t.clientHandlers[client.WSJobStopEvent] = func(ctx any) error {
 		jobID, _ := ctx.(string)
 		if jobID == "" {
 			return fmt.Errorf("unexpected empty jobID")
@@ -143,13 +236,18 @@ func (t *Transcriber) Start(ctx context.Context) error {
 
 		if jobID == t.cfg.TranscriptionID {
 			slog.Info("received job stop event, exiting")
-			go t.client.Close()
+			t.closing.Store(true)
+			go t.client.Load().Close()
 		}
 
 		return nil
-	})
+	}
 
-	if err := t.client.Connect(); err != nil {
+	t.dialer.logResolvedEndpoints(t.cfg.SiteURL)
+
+	rtcdClient := t.client.Load()
+	t.registerClientHandlers(rtcdClient)
+	if err := rtcdClient.Connect(); err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 
@@ -159,12 +257,12 @@ func (t *Transcriber) Start(ctx context.Context) error {
 		return ctx.Err()
 	}
 
-	if t.cfg.LiveCaptionsOn {
+	if t.cfg.LiveCaptions.On {
 		slog.Debug("LiveCaptionsOn is true; startingTranscriberPool starting transcriber pool.",
-			slog.String("LiveCaptionsModelSize", string(t.cfg.LiveCaptionsModelSize)),
-			slog.Int("LiveCaptionsNumTranscribers", t.cfg.LiveCaptionsNumTranscribers),
-			slog.Int("LiveCaptionsNumThreadsPerTranscriber", t.cfg.LiveCaptionsNumThreadsPerTranscriber),
-			slog.String("LiveCaptionsLanguage", t.cfg.LiveCaptionsLanguage))
+			slog.String("LiveCaptionsModelSize", string(t.cfg.LiveCaptions.ModelSize)),
+			slog.Int("LiveCaptionsNumTranscribers", t.cfg.LiveCaptions.NumTranscribers),
+			slog.Int("LiveCaptionsNumThreadsPerTranscriber", t.cfg.LiveCaptions.NumThreadsPerTranscriber),
+			slog.String("LiveCaptionsLanguage", t.cfg.LiveCaptions.Language))
 		go t.startTranscriberPool()
 	}
 
@@ -173,6 +271,13 @@ func (t *Transcriber) Start(ctx context.Context) error {
 		if err := t.ReportJobStarted(); err != nil {
 			return fmt.Errorf("failed to report job started status: %w", err)
 		}
+		t.setState(StateRecording)
+		go t.monitorDiskSpace()
+		go t.monitorTrackStats()
+		go t.monitorCallDuration()
+		if t.cfg.LiveCaptions.On && !t.cfg.LiveCaptions.Shadow && !t.cfg.LiveCaptions.Ack {
+			go t.monitorCaptionsFallback()
+		}
 	case <-ctx.Done():
 		return ctx.Err()
 	}
@@ -181,7 +286,8 @@ func (t *Transcriber) Start(ctx context.Context) error {
 }
 
 func (t *Transcriber) Stop(ctx context.Context) error {
-	if err := t.client.Close(); err != nil {
+	t.closing.Store(true)
+	if err := t.client.Load().Close(); err != nil {
 		slog.Error("failed to close client on stop", slog.String("err", err.Error()))
 	}
 
@@ -209,7 +315,16 @@ func (t *Transcriber) Err() error {
 func (t *Transcriber) done() {
 	t.doneOnce.Do(func() {
 		close(t.captionsPoolDoneCh)
-		t.errCh <- t.handleClose()
+		callDurMs := t.recordingOffsetMs()
+		err := t.handleClose()
+		if err != nil {
+			t.setState(StateFailed)
+		} else {
+			t.setState(StateDone)
+		}
+		t.pushJobArtifacts()
+		t.notifyCompletion(callDurMs, err)
+		t.errCh <- err
 		close(t.doneCh)
 	})
 }
@@ -0,0 +1,128 @@
+package call
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// transcriberPoolMaxSize is the maximum number of idle transcribers kept
+// around. Every whisper.cpp context holds a full copy of the model in memory
+// so this is kept small.
+const transcriberPoolMaxSize = 2
+
+// transcriberKey identifies interchangeable track transcribers.
+type transcriberKey struct {
+	api       config.TranscribeAPI
+	modelSize config.ModelSize
+	// language is empty when automatically detected.
+	language string
+}
+
+// contextResetter is implemented by transcribers that carry decoding context
+// between transcriptions (e.g. whisper.cpp conditioning on past text).
+type contextResetter interface {
+	ResetContext()
+}
+
+type pooledTranscriber struct {
+	key transcriberKey
+	tr  transcribe.Transcriber
+}
+
+// transcriberPool caches track transcribers so that a model isn't reloaded
+// for every track. Transcribers are keyed by model and language so that
+// mixed workloads reuse the right context, with the least recently used one
+// evicted once the pool is full. Only whisper.cpp contexts are pooled since
+// they are the ones expensive to create.
+type transcriberPool struct {
+	mut     sync.Mutex
+	maxSize int
+	newFn   func(key transcriberKey) (transcribe.Transcriber, error)
+	// idle holds the transcribers not in use, the most recently used last.
+	idle []pooledTranscriber
+}
+
+func newTranscriberPool(maxSize int, newFn func(key transcriberKey) (transcribe.Transcriber, error)) *transcriberPool {
+	return &transcriberPool{
+		maxSize: maxSize,
+		newFn:   newFn,
+	}
+}
+
+func isPoolable(key transcriberKey) bool {
+	return key.api == config.TranscribeAPIWhisperCPP
+}
+
+// get returns an idle transcriber matching key, or a new one if none is
+// available. It should be given back through put once done.
+func (p *transcriberPool) get(key transcriberKey) (transcribe.Transcriber, error) {
+	p.mut.Lock()
+	for i := len(p.idle) - 1; i >= 0; i-- {
+		if p.idle[i].key != key {
+			continue
+		}
+		tr := p.idle[i].tr
+		p.idle = append(p.idle[:i], p.idle[i+1:]...)
+		p.mut.Unlock()
+
+		// What was transcribed for the previous track (i.e. another speaker)
+		// shouldn't condition this one.
+		if cr, ok := tr.(contextResetter); ok {
+			cr.ResetContext()
+		}
+		if ps, ok := tr.(promptSetter); ok {
+			ps.SetPrompt("")
+		}
+
+		return tr, nil
+	}
+	p.mut.Unlock()
+
+	return p.newFn(key)
+}
+
+// put returns a transcriber to the pool. If the pool is full, the least
+// recently used transcriber gets destroyed.
+func (p *transcriberPool) put(key transcriberKey, tr transcribe.Transcriber) error {
+	if !isPoolable(key) || p.maxSize <= 0 {
+		return tr.Destroy()
+	}
+
+	p.mut.Lock()
+	p.idle = append(p.idle, pooledTranscriber{key: key, tr: tr})
+	var evicted []pooledTranscriber
+	if n := len(p.idle) - p.maxSize; n > 0 {
+		evicted = append(evicted, p.idle[:n]...)
+		p.idle = append([]pooledTranscriber(nil), p.idle[n:]...)
+	}
+	p.mut.Unlock()
+
+	for _, e := range evicted {
+		slog.Debug("evicting transcriber from pool",
+			slog.String("api", string(e.key.api)),
+			slog.String("modelSize", string(e.key.modelSize)),
+			slog.String("language", e.key.language))
+		if err := e.tr.Destroy(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// close destroys all idle transcribers.
+func (p *transcriberPool) close() {
+	p.mut.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mut.Unlock()
+
+	for _, e := range idle {
+		if err := e.tr.Destroy(); err != nil {
+			slog.Error("failed to destroy pooled transcriber", slog.String("err", err.Error()))
+		}
+	}
+}
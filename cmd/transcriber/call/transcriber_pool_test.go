@@ -0,0 +1,139 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTranscriber struct {
+	key       transcriberKey
+	destroyed bool
+	resets    int
+	prompt    string
+}
+
+func (f *fakeTranscriber) Transcribe(_ []float32) ([]transcribe.Segment, string, error) {
+	return nil, f.key.language, nil
+}
+
+func (f *fakeTranscriber) ResetContext() {
+	f.resets++
+}
+
+func (f *fakeTranscriber) SetPrompt(prompt string) {
+	f.prompt = prompt
+}
+
+func (f *fakeTranscriber) Destroy() error {
+	f.destroyed = true
+	return nil
+}
+
+func TestTranscriberPool(t *testing.T) {
+	var created []*fakeTranscriber
+	newPool := func(maxSize int) *transcriberPool {
+		created = nil
+		return newTranscriberPool(maxSize, func(key transcriberKey) (transcribe.Transcriber, error) {
+			tr := &fakeTranscriber{key: key}
+			created = append(created, tr)
+			return tr, nil
+		})
+	}
+
+	enKey := transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeTiny, language: "en"}
+	itKey := transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeTiny, language: "it"}
+	baseKey := transcriberKey{api: config.TranscribeAPIWhisperCPP, modelSize: config.ModelSizeBase}
+
+	t.Run("same key", func(t *testing.T) {
+		p := newPool(2)
+
+		tr, err := p.get(enKey)
+		require.NoError(t, err)
+		require.Zero(t, created[0].resets)
+		created[0].SetPrompt("previous track")
+		require.NoError(t, p.put(enKey, tr))
+
+		tr2, err := p.get(enKey)
+		require.NoError(t, err)
+		require.Same(t, tr, tr2)
+		require.Len(t, created, 1)
+
+		// State from the previous track is dropped.
+		require.Equal(t, 1, created[0].resets)
+		require.Empty(t, created[0].prompt)
+
+		tr3, err := p.get(enKey)
+		require.NoError(t, err)
+		require.NotSame(t, tr, tr3)
+		require.Len(t, created, 2)
+	})
+
+	t.Run("different keys", func(t *testing.T) {
+		p := newPool(2)
+
+		tr, err := p.get(enKey)
+		require.NoError(t, err)
+		require.NoError(t, p.put(enKey, tr))
+
+		tr2, err := p.get(itKey)
+		require.NoError(t, err)
+		require.NotSame(t, tr, tr2)
+		require.Len(t, created, 2)
+		require.Equal(t, itKey, created[1].key)
+	})
+
+	t.Run("lru eviction across keys", func(t *testing.T) {
+		p := newPool(2)
+
+		for _, key := range []transcriberKey{enKey, itKey} {
+			tr, err := p.get(key)
+			require.NoError(t, err)
+			require.NoError(t, p.put(key, tr))
+		}
+
+		// Using en makes it the most recently used.
+		tr, err := p.get(enKey)
+		require.NoError(t, err)
+		require.NoError(t, p.put(enKey, tr))
+
+		tr, err = p.get(baseKey)
+		require.NoError(t, err)
+		require.NoError(t, p.put(baseKey, tr))
+
+		require.Len(t, created, 3)
+		require.False(t, created[0].destroyed)
+		require.True(t, created[1].destroyed)
+		require.False(t, created[2].destroyed)
+
+		// The evicted language needs a new context while the others are reused.
+		tr, err = p.get(enKey)
+		require.NoError(t, err)
+		require.Same(t, created[0], tr)
+		require.NoError(t, p.put(enKey, tr))
+		tr, err = p.get(itKey)
+		require.NoError(t, err)
+		require.Len(t, created, 4)
+		require.NoError(t, p.put(itKey, tr))
+
+		p.close()
+		for _, tr := range created {
+			require.True(t, tr.destroyed)
+		}
+		require.Empty(t, p.idle)
+	})
+
+	t.Run("not poolable", func(t *testing.T) {
+		p := newPool(2)
+
+		key := transcriberKey{api: config.TranscribeAPIAzure}
+		tr, err := p.get(key)
+		require.NoError(t, err)
+		require.NoError(t, p.put(key, tr))
+		require.True(t, created[0].destroyed)
+		require.Empty(t, p.idle)
+	})
+}
@@ -40,8 +40,10 @@ func setupTranscriberForTest(t *testing.T) *Transcriber {
 		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
 		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
-		NumThreads:      1,
-		ModelSize:       config.ModelSizeTiny,
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
 	}
 	cfg.SetDefaults()
 	tr, err := NewTranscriber(cfg)
@@ -116,6 +118,52 @@ func (t *trackRemoteMock) ReadRTP() (*rtp.Packet, interceptor.Attributes, error)
 }
 
 func TestProcessLiveTrack(t *testing.T) {
+	setupTrack := func(t *testing.T, tr *Transcriber, readRTP func() (*rtp.Packet, interceptor.Attributes, error)) *trackRemoteMock {
+		t.Helper()
+
+		mockClient := &mocks.MockAPIClient{}
+		tr.apiClient = mockClient
+		t.Cleanup(func() { mockClient.AssertExpectations(t) })
+
+		mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+			"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+			Return(&http.Response{
+				Body: io.NopCloser(strings.NewReader(`{"id": "userID", "username": "testuser"}`)),
+			}, nil).Once()
+
+		return &trackRemoteMock{
+			id:      "trackID",
+			readRTP: readRTP,
+		}
+	}
+
+	readGranules := func(t *testing.T) []uint64 {
+		t.Helper()
+
+		trackFile, err := os.Open(filepath.Join(getDataDir(), "userID_trackID.ogg"))
+		require.NoError(t, err)
+		defer trackFile.Close()
+
+		oggReader, _, err := ogg.NewReaderWith(trackFile)
+		require.NoError(t, err)
+
+		// Metadata
+		_, _, err = oggReader.ParseNextPage()
+		require.NoError(t, err)
+
+		var granules []uint64
+		for {
+			_, hdr, err := oggReader.ParseNextPage()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			granules = append(granules, hdr.GranulePosition)
+		}
+
+		return granules
+	}
+
 	t.Run("synchronization", func(t *testing.T) {
 		t.Run("empty payloads", func(t *testing.T) {
 			tr := setupTranscriberForTest(t)
@@ -430,7 +478,452 @@ func TestProcessLiveTrack(t *testing.T) {
 		})
 	})
 
-	t.Run("should reattempt getUserForSession on failure", func(t *testing.T) {
+	t.Run("clock jumps", func(t *testing.T) {
+		pkts := []*rtp.Packet{
+			{
+				Header: rtp.Header{
+					Timestamp: 960,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 1920,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 2880,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 3840,
+				},
+				Payload: []byte{0x45},
+			},
+		}
+
+		t.Run("wall clock steps are ignored", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+
+			// The monotonic clock advances steadily by one frame per packet. The wall
+			// clock stepping in between (e.g. an NTP adjustment) must not be seen as a gap.
+			var now time.Duration
+			tr.monoNow = func() time.Duration {
+				return now
+			}
+
+			var i int
+			track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+				if i >= len(pkts) {
+					return nil, nil, io.EOF
+				}
+				defer func() { i++ }()
+				now += trackAudioFrameSizeMs * time.Millisecond
+				return pkts[i], nil, nil
+			})
+
+			tr.liveTracksWg.Add(1)
+			// Stripping the monotonic reading simulates a start time that was
+			// received from a host whose wall clock is ahead of ours.
+			tr.startTime.Store(newTimeP(time.Now().Add(time.Hour).Round(0)))
+			tr.processLiveTrack(track, "sessionID")
+			close(tr.trackCtxs)
+			require.Len(t, tr.trackCtxs, 1)
+
+			ctx := <-tr.trackCtxs
+			require.Zero(t, ctx.startTS)
+			require.Equal(t, []uint64{1, 961, 1921, 2881}, readGranules(t))
+		})
+
+		t.Run("monotonic gaps are detected", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+
+			var now time.Duration
+			tr.monoNow = func() time.Duration {
+				return now
+			}
+
+			var i int
+			track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+				if i >= len(pkts) {
+					return nil, nil, io.EOF
+				}
+				defer func() { i++ }()
+				now += trackAudioFrameSizeMs * time.Millisecond
+				if i == 2 {
+					// Two seconds of silence (e.g. mute) with no RTP timestamp jump.
+					now += 2 * time.Second
+				}
+				return pkts[i], nil, nil
+			})
+
+			tr.liveTracksWg.Add(1)
+			tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+			tr.processLiveTrack(track, "sessionID")
+			close(tr.trackCtxs)
+			require.Len(t, tr.trackCtxs, 1)
+
+			ctx := <-tr.trackCtxs
+			require.GreaterOrEqual(t, ctx.startTS, int64(1000))
+			// The measured receive gap (2020ms) replaces the RTP timestamp increment.
+			gap := uint64(2020 / trackAudioFrameSizeMs * trackInFrameSize)
+			require.Equal(t, []uint64{1, 961, 961 + gap, 1921 + gap}, readGranules(t))
+		})
+	})
+
+	t.Run("reordered packets", func(t *testing.T) {
+		pkts := []*rtp.Packet{
+			{
+				Header: rtp.Header{
+					SequenceNumber: 1,
+					Timestamp:      960,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					SequenceNumber: 3,
+					Timestamp:      2880,
+				},
+				Payload: []byte{0x45},
+			},
+			// Late
+			{
+				Header: rtp.Header{
+					SequenceNumber: 2,
+					Timestamp:      1920,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					SequenceNumber: 4,
+					Timestamp:      3840,
+				},
+				Payload: []byte{0x45},
+			},
+		}
+
+		processTrack := func(t *testing.T, tr *Transcriber) {
+			t.Helper()
+
+			var now time.Duration
+			tr.monoNow = func() time.Duration {
+				return now
+			}
+
+			var i int
+			track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+				if i >= len(pkts) {
+					return nil, nil, io.EOF
+				}
+				defer func() { i++ }()
+				now += trackAudioFrameSizeMs * time.Millisecond
+				return pkts[i], nil, nil
+			})
+
+			tr.liveTracksWg.Add(1)
+			tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+			tr.processLiveTrack(track, "sessionID")
+			close(tr.trackCtxs)
+			require.Len(t, tr.trackCtxs, 1)
+		}
+
+		t.Run("reorder buffer", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+			processTrack(t, tr)
+			require.Equal(t, []uint64{1, 961, 1921, 2881}, readGranules(t))
+		})
+
+		t.Run("disabled", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+			tr.cfg.Capture.ReorderBufferMs = -1
+			processTrack(t, tr)
+			require.Equal(t, []uint64{1, 1921, 2881}, readGranules(t))
+		})
+	})
+
+	t.Run("stereo", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		pkts := []*rtp.Packet{
+			{Header: rtp.Header{SequenceNumber: 1, Timestamp: 960}, Payload: []byte{0x7c, 0x45, 0x45}},
+			{Header: rtp.Header{SequenceNumber: 2, Timestamp: 1920}, Payload: []byte{0x7c, 0x45, 0x45}},
+		}
+
+		var i int
+		track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+			if i >= len(pkts) {
+				return nil, nil, io.EOF
+			}
+			defer func() { i++ }()
+			return pkts[i], nil, nil
+		})
+
+		tr.liveTracksWg.Add(1)
+		tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+		tr.processLiveTrack(track, "sessionID")
+		close(tr.trackCtxs)
+		require.Len(t, tr.trackCtxs, 1)
+
+		trackFile, err := os.Open(filepath.Join(getDataDir(), "userID_trackID.ogg"))
+		require.NoError(t, err)
+		defer trackFile.Close()
+
+		// Checksums are verified so the rewritten header must be valid.
+		_, hdr, err := ogg.NewReaderWith(trackFile)
+		require.NoError(t, err)
+		require.Equal(t, uint8(2), hdr.Channels)
+		require.Equal(t, []uint64{1, 961}, readGranules(t))
+	})
+
+	t.Run("dtx packets", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		audio := []byte{0x45}
+		dtx := []byte{0x78}
+		pkts := []*rtp.Packet{
+			// Nothing to mark before audio starts.
+			{Header: rtp.Header{SequenceNumber: 1, Timestamp: 0}, Payload: dtx},
+			{Header: rtp.Header{SequenceNumber: 2, Timestamp: 960}, Payload: audio},
+			{Header: rtp.Header{SequenceNumber: 3, Timestamp: 1920}, Payload: dtx},
+			{Header: rtp.Header{SequenceNumber: 4, Timestamp: 2880}, Payload: dtx},
+			{Header: rtp.Header{SequenceNumber: 5, Timestamp: 3840}, Payload: dtx},
+			{Header: rtp.Header{SequenceNumber: 6, Timestamp: 4800}, Payload: audio},
+		}
+
+		var now time.Duration
+		tr.monoNow = func() time.Duration {
+			return now
+		}
+
+		var i int
+		track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+			if i >= len(pkts) {
+				return nil, nil, io.EOF
+			}
+			defer func() { i++ }()
+			now += trackAudioFrameSizeMs * time.Millisecond
+			return pkts[i], nil, nil
+		})
+
+		tr.liveTracksWg.Add(1)
+		tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+		tr.processLiveTrack(track, "sessionID")
+		close(tr.trackCtxs)
+		require.Len(t, tr.trackCtxs, 1)
+
+		// Only the first DTX packet of the silent period is saved while the
+		// gap is preserved.
+		require.Equal(t, []uint64{1, 961, 3841}, readGranules(t))
+		ctx := <-tr.trackCtxs
+		require.Equal(t, 60*time.Millisecond, ctx.audioDur)
+	})
+
+	t.Run("duplicate packets", func(t *testing.T) {
+		pkts := []*rtp.Packet{
+			{
+				Header: rtp.Header{
+					SequenceNumber: 1,
+					Timestamp:      960,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					SequenceNumber: 2,
+					Timestamp:      1920,
+				},
+				Payload: []byte{0x45},
+			},
+			// Retransmission
+			{
+				Header: rtp.Header{
+					SequenceNumber: 2,
+					Timestamp:      1920,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					SequenceNumber: 3,
+					Timestamp:      2880,
+				},
+				Payload: []byte{0x45},
+			},
+		}
+
+		processTrack := func(t *testing.T, tr *Transcriber) {
+			t.Helper()
+
+			var now time.Duration
+			tr.monoNow = func() time.Duration {
+				return now
+			}
+
+			var i int
+			track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+				if i >= len(pkts) {
+					return nil, nil, io.EOF
+				}
+				defer func() { i++ }()
+				now += trackAudioFrameSizeMs * time.Millisecond
+				return pkts[i], nil, nil
+			})
+
+			tr.liveTracksWg.Add(1)
+			tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+			tr.processLiveTrack(track, "sessionID")
+			close(tr.trackCtxs)
+			require.Len(t, tr.trackCtxs, 1)
+		}
+
+		t.Run("drop", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+			processTrack(t, tr)
+			require.Equal(t, []uint64{1, 961, 1921}, readGranules(t))
+			require.Equal(t, uint64(1), tr.duplicatePkts.Load())
+		})
+
+		t.Run("keep", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+			tr.cfg.Capture.DuplicatePackets = config.DuplicatePacketsKeep
+			processTrack(t, tr)
+			require.Equal(t, []uint64{1, 961, 961, 1921}, readGranules(t))
+			require.Equal(t, uint64(1), tr.duplicatePkts.Load())
+		})
+	})
+
+	t.Run("low disk space", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+
+		pkts := []*rtp.Packet{
+			{
+				Header: rtp.Header{
+					Timestamp: 960,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 1920,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 2880,
+				},
+				Payload: []byte{0x45},
+			},
+			{
+				Header: rtp.Header{
+					Timestamp: 3840,
+				},
+				Payload: []byte{0x45},
+			},
+		}
+
+		var now time.Duration
+		tr.monoNow = func() time.Duration {
+			return now
+		}
+
+		var i int
+		track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+			if i >= len(pkts) {
+				return nil, nil, io.EOF
+			}
+			defer func() { i++ }()
+			now += trackAudioFrameSizeMs * time.Millisecond
+			// Only the third packet arrives while short on space.
+			tr.lowDiskSpace.Store(i == 2)
+			return pkts[i], nil, nil
+		})
+
+		tr.liveTracksWg.Add(1)
+		tr.startTime.Store(newTimeP(time.Now().Add(-time.Second)))
+		tr.processLiveTrack(track, "sessionID")
+		close(tr.trackCtxs)
+		require.Len(t, tr.trackCtxs, 1)
+
+		ctx := <-tr.trackCtxs
+		require.Equal(t, 3*trackAudioFrameSizeMs*time.Millisecond, ctx.audioDur)
+		// The skipped packet is accounted for when writing resumes.
+		require.Equal(t, []uint64{1, 961, 2881}, readGranules(t))
+	})
+
+	t.Run("pre-start buffer", func(t *testing.T) {
+		pkts := make([]*rtp.Packet, 6)
+		for i := range pkts {
+			pkts[i] = &rtp.Packet{
+				Header: rtp.Header{
+					SequenceNumber: uint16(i),
+					Timestamp:      uint32(i+1) * 960,
+				},
+				Payload: []byte{0x45},
+			}
+		}
+
+		run := func(t *testing.T, tr *Transcriber) trackContext {
+			t.Helper()
+
+			var now time.Duration
+			tr.monoNow = func() time.Duration {
+				return now
+			}
+
+			var i int
+			track := setupTrack(t, tr, func() (*rtp.Packet, interceptor.Attributes, error) {
+				if i >= len(pkts) {
+					return nil, nil, io.EOF
+				}
+				defer func() { i++ }()
+				now += trackAudioFrameSizeMs * time.Millisecond
+				// The recording starts with the fifth packet.
+				if i == 4 {
+					tr.startTime.Store(newTimeP(time.Now()))
+				}
+				return pkts[i], nil, nil
+			})
+
+			tr.liveTracksWg.Add(1)
+			tr.processLiveTrack(track, "sessionID")
+			close(tr.trackCtxs)
+			require.Len(t, tr.trackCtxs, 1)
+
+			return <-tr.trackCtxs
+		}
+
+		t.Run("disabled", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+
+			ctx := run(t, tr)
+			require.Equal(t, 2*trackAudioFrameSizeMs*time.Millisecond, ctx.audioDur)
+			require.Equal(t, []uint64{1, 961}, readGranules(t))
+			// The first packet written arrived with the start.
+			require.InDelta(t, 0, ctx.startTS, 5)
+		})
+
+		t.Run("enabled", func(t *testing.T) {
+			tr := setupTranscriberForTest(t)
+			tr.cfg.Capture.PreStartBufferMs = 3 * trackAudioFrameSizeMs
+
+			ctx := run(t, tr)
+			// Only the last three packets before the start fit in the buffer.
+			require.Equal(t, 5*trackAudioFrameSizeMs*time.Millisecond, ctx.audioDur)
+			require.Equal(t, []uint64{1, 961, 1921, 2881, 3841}, readGranules(t))
+			// Timestamps stay relative to the recording start, the first
+			// buffered packet having arrived a whole buffer before it.
+			require.InDelta(t, -3*trackAudioFrameSizeMs, ctx.startTS, 5)
+		})
+	})
+
+	t.Run("should reattempt getSessionProfile on failure", func(t *testing.T) {
 		tr := setupTranscriberForTest(t)
 
 		mockClient := &mocks.MockAPIClient{}
@@ -496,6 +989,90 @@ func TestProcessLiveTrack(t *testing.T) {
 		require.Len(t, tr.trackCtxs, 1)
 	})
 
+	t.Run("should ignore tracks of participants who opted out", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.LiveCaptions.On = true
+
+		mockClient := &mocks.MockAPIClient{}
+		tr.apiClient = mockClient
+
+		defer mockClient.AssertExpectations(t)
+
+		mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+			"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+			Return(&http.Response{
+				Body: io.NopCloser(strings.NewReader(`{"id": "userID", "username": "testuser", "transcription_opt_out": true}`)),
+			}, nil).Once()
+
+		var reads int
+		track := &trackRemoteMock{
+			id: "trackID",
+			readRTP: func() (*rtp.Packet, interceptor.Attributes, error) {
+				if reads >= 3 {
+					return nil, nil, io.EOF
+				}
+				reads++
+				return &rtp.Packet{Header: rtp.Header{Timestamp: uint32(reads * 960)}, Payload: []byte{0x45, 0x45, 0x45}}, nil, nil
+			},
+		}
+
+		tr.liveTracksWg.Add(1)
+		tr.processLiveTrack(track, "sessionID")
+		tr.liveTracksWg.Wait()
+
+		// The track was read through but nothing was saved.
+		require.Equal(t, 3, reads)
+		close(tr.trackCtxs)
+		require.Empty(t, tr.trackCtxs)
+		_, err := os.Stat(getTrackFilename("userID", "trackID"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		require.Equal(t, "Not transcribed at their request: testuser.", tr.getTranscriptionNote())
+	})
+
+	t.Run("should ignore tracks of bots if excluded", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Capture.BotTracks = config.BotTracksExclude
+
+		mockClient := &mocks.MockAPIClient{}
+		tr.apiClient = mockClient
+
+		defer mockClient.AssertExpectations(t)
+
+		mockClient.On("DoAPIRequest", mock.Anything, http.MethodGet,
+			"http://localhost:8065/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile", "", "").
+			Return(&http.Response{
+				Body: io.NopCloser(strings.NewReader(`{"id": "botID", "username": "recorder", "is_bot": true}`)),
+			}, nil).Once()
+
+		var reads int
+		track := &trackRemoteMock{
+			id: "trackID",
+			readRTP: func() (*rtp.Packet, interceptor.Attributes, error) {
+				if reads >= 3 {
+					return nil, nil, io.EOF
+				}
+				reads++
+				return &rtp.Packet{Header: rtp.Header{Timestamp: uint32(reads * 960)}, Payload: []byte{0x45, 0x45, 0x45}}, nil, nil
+			},
+		}
+
+		tr.liveTracksWg.Add(1)
+		tr.processLiveTrack(track, "sessionID")
+		tr.liveTracksWg.Wait()
+
+		require.Equal(t, 3, reads)
+		close(tr.trackCtxs)
+		require.Empty(t, tr.trackCtxs)
+		_, err := os.Stat(getTrackFilename("botID", "trackID"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		// The bot is still listed among participants.
+		participants := tr.getParticipants(nil)
+		require.Len(t, participants, 1)
+		require.True(t, participants[0].Bot)
+	})
+
 	t.Run("should not queue contexes with no samples", func(t *testing.T) {
 		tr := setupTranscriberForTest(t)
 
@@ -562,3 +1139,19 @@ func TestProcessLiveTrack(t *testing.T) {
 		require.Empty(t, tr.trackCtxs)
 	})
 }
+
+func TestGetSpeakerColorIndex(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	require.Equal(t, 0, tr.getSpeakerColorIndex("sessionA"))
+	require.Equal(t, 1, tr.getSpeakerColorIndex("sessionB"))
+	require.Equal(t, 0, tr.getSpeakerColorIndex("sessionA"))
+
+	for i := 2; i < speakerColorsNum; i++ {
+		require.Equal(t, i, tr.getSpeakerColorIndex(fmt.Sprintf("session%d", i)))
+	}
+
+	// Wrapping around after all colors have been assigned.
+	require.Equal(t, 0, tr.getSpeakerColorIndex("sessionC"))
+	require.Equal(t, 1, tr.getSpeakerColorIndex("sessionB"))
+}
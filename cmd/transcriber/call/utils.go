@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
 
 	"github.com/mattermost/mattermost-plugin-calls/server/public"
@@ -19,10 +22,8 @@ import (
 )
 
 const (
-	httpRequestTimeout          = 5 * time.Second
-	httpUploadTimeout           = 10 * time.Second
-	uploadRetryAttemptWaitTime  = 5 * time.Second
-	getUserRetryAttemptWaitTime = time.Second
+	httpRequestTimeout = 5 * time.Second
+	httpUploadTimeout  = 10 * time.Second
 )
 
 var (
@@ -30,40 +31,48 @@ var (
 	maxAPIRetryAttempts    = 5
 )
 
-func (t *Transcriber) getUserForSession(sessionID string) (*model.User, error) {
-	getUser := func() (*model.User, error) {
-		ctx, cancelFn := context.WithTimeout(context.Background(), httpRequestTimeout)
+// sessionProfile is what the plugin returns about a call participant: the
+// session's user along with session properties.
+type sessionProfile struct {
+	model.User
+	// TranscriptionOptOut is set if the participant declined being
+	// transcribed, in which case their audio must not be recorded nor
+	// captioned.
+	TranscriptionOptOut bool `json:"transcription_opt_out"`
+}
+
+func (t *Transcriber) getSessionProfile(sessionID string) (*sessionProfile, error) {
+	getProfile := func(ctx context.Context) (*sessionProfile, error) {
+		ctx, cancelFn := context.WithTimeout(ctx, httpRequestTimeout)
 		defer cancelFn()
 
 		url := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/sessions/%s/profile", t.cfg.SiteURL, pluginID, t.cfg.CallID, sessionID)
 		resp, err := t.apiClient.DoAPIRequest(ctx, http.MethodGet, url, "", "")
 		if err != nil {
+			err = newAPIError(resp, err)
 			return nil, fmt.Errorf("failed to fetch user profile: %w", err)
 		}
 		defer resp.Body.Close()
 
-		var user *model.User
-		if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		var profile sessionProfile
+		if err := decodeAPIResponse("/bot/calls/{callID}/sessions/{sessionID}/profile", resp.Body, &profile); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal user profile: %w", err)
 		}
 
-		return user, nil
+		return &profile, nil
 	}
 
-	for i := 0; i < maxAPIRetryAttempts; i++ {
-		user, err := getUser()
-		if err == nil {
-			return user, nil
-		}
-
-		slog.Error("getUserForSession failed",
-			slog.String("err", err.Error()),
-			slog.Duration("reattempt_time", getUserRetryAttemptWaitTime))
-
-		time.Sleep(getUserRetryAttemptWaitTime)
+	var profile *sessionProfile
+	err := retry(context.Background(), "getSessionProfile", getUserRetryPolicy, func(ctx context.Context) error {
+		var err error
+		profile, err = getProfile(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user for call: %w", err)
 	}
 
-	return nil, fmt.Errorf("failed to get user for call: max attempts reached")
+	return profile, nil
 }
 
 func getDataDir() string {
@@ -80,219 +89,502 @@ func getModelsDir() string {
 	return modelsDir
 }
 
-func (t *Transcriber) publishTranscription(tr transcribe.Transcription) (err error) {
-	var fname string
-	for i := 0; i < maxAPIRetryAttempts; i++ {
-		if i > 0 {
-			slog.Error("getFilenameForCall failed",
-				slog.String("err", err.Error()),
-				slog.Duration("reattempt_time", uploadRetryAttemptWaitTime))
-			time.Sleep(uploadRetryAttemptWaitTime)
-		}
+// transcriptionOutput is a transcription to be published along with a label
+// identifying it among the other transcriptions for the same call (e.g. the
+// transcription API that produced it). The label is empty for the main
+// transcription.
+type transcriptionOutput struct {
+	tr    transcribe.Transcription
+	label string
+	// language is set when the output holds a single language out of a
+	// multilingual transcription.
+	language string
+	// participants, if set, are published as an additional JSON artifact.
+	participants []participant
+	// vadSegments, if set, are published as an additional JSON artifact.
+	vadSegments []trackVADSegments
+	// timeMap, if set, maps the timestamps of the output, in which silences
+	// were trimmed, back to the recording. It's published as an additional
+	// JSON artifact.
+	timeMap transcribe.TimeMap
+	// summary, if set, is the meeting summary generated locally, published
+	// as an additional Markdown file.
+	summary string
+}
 
-		fname, err = t.getFilenameForCall()
-		if err == nil {
-			break
+// newTranscriptionOutputs returns the outputs to publish for the
+// transcriptions produced by each API, leaving out the empty ones so that in
+// comparison mode an API producing nothing doesn't prevent the others from
+// being published.
+func (t *Transcriber) newTranscriptionOutputs(apis []config.TranscribeAPI, trs []transcribe.Transcription, sessionTrs []map[string][]transcribe.TrackTranscription) []transcriptionOutput {
+	var outputs []transcriptionOutput
+	for i, api := range apis {
+		if len(trs[i]) == 0 {
+			continue
+		}
+		out := transcriptionOutput{tr: trs[i]}
+		if len(apis) > 1 {
+			// In comparison mode every transcription is labeled with the API
+			// that produced it so that they can be told apart once published.
+			out.label = string(api)
 		}
+		if t.cfg.Output.IncludeSilentParticipants || t.cfg.Output.SpeakerEmbeddings {
+			out.participants = t.getParticipants(sessionTrs[i])
+		}
+		outputs = append(outputs, out)
 	}
-	if err != nil {
-		return fmt.Errorf("failed to get filename for call: %w", err)
+	return outputs
+}
+
+// splitOutputsByLanguage splits every multilingual output into one output per
+// language so that clients can offer a language selector.
+func splitOutputsByLanguage(outputs []transcriptionOutput) []transcriptionOutput {
+	var splitOutputs []transcriptionOutput
+	for _, out := range outputs {
+		splits := out.tr.SplitByLanguage()
+		if len(splits) < 2 {
+			splitOutputs = append(splitOutputs, out)
+			continue
+		}
+
+		for i, split := range splits {
+			splitOut := transcriptionOutput{
+				tr:       split,
+				label:    out.label,
+				language: split.Language(),
+				timeMap:  out.timeMap,
+			}
+			// The participants and VAD segments artifacts, as well as the
+			// summary, are about the call as a whole so they only need
+			// publishing once.
+			if i == 0 {
+				splitOut.participants = out.participants
+				splitOut.vadSegments = out.vadSegments
+				splitOut.summary = out.summary
+			}
+			splitOutputs = append(splitOutputs, splitOut)
+		}
 	}
+	return splitOutputs
+}
 
-	var vttFile *os.File
-	var textFile *os.File
-	openFiles := func() error {
-		vttFile, err = os.OpenFile(filepath.Join(getDataDir(), fname+".vtt"), os.O_RDWR|os.O_CREATE, 0600)
-		if err != nil {
-			return fmt.Errorf("failed to open output file: %w", err)
+// transcribingJobInfo extends the job info sent to the plugin with
+// additional metadata about the call.
+type transcribingJobInfo struct {
+	public.TranscribingJobInfo
+	// Keywords are the most relevant terms for the call and its speakers,
+	// meant to be rendered as searchable tags.
+	Keywords *transcribe.Keywords `json:"keywords,omitempty"`
+	// Languages lists the languages spoken in the call along with their
+	// proportion of the total speech.
+	Languages []transcribe.LanguageShare `json:"languages,omitempty"`
+	// Truncated is set if capturing was stopped early because a configured
+	// limit was exceeded.
+	Truncated bool `json:"truncated,omitempty"`
+	// Version is greater than one when the job is re-run, in which case
+	// ReplacesFileIDs holds the files previously published for it which the
+	// plugin should remove from the post.
+	Version         int      `json:"version,omitempty"`
+	ReplacesFileIDs []string `json:"replaces_file_ids,omitempty"`
+	// ObjectStorage is set when the files were uploaded to object storage
+	// rather than attached through the upload API.
+	ObjectStorage *objectStorageInfo `json:"object_storage,omitempty"`
+}
+
+func (t *Transcriber) publishTranscription(tr transcribe.Transcription) error {
+	return t.publishTranscriptions([]transcriptionOutput{{tr: tr}})
+}
+
+// writeTranscriptionFiles renders the transcription in the given formats, and
+// as ASS subtitles if enabled, and saves them in dir, returning the paths of
+// the written files. If set, meta is written at the top of the files and note
+// is appended to them (e.g. to flag them as truncated).
+func writeTranscriptionFiles(dir, fname string, tr transcribe.Transcription, formats []config.OutputFormat, opts config.OutputOptions, meta *transcribe.Metadata, note string) ([]string, error) {
+	var paths []string
+	for _, format := range formats {
+		if format == config.OutputFormatJSON {
+			path, err := writeTranscriptionJSON(dir, fname, tr, meta)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+			continue
 		}
 
-		textFile, err = os.OpenFile(filepath.Join(getDataDir(), fname+".txt"), os.O_RDWR|os.O_CREATE, 0600)
+		path := filepath.Join(dir, fname+"."+string(format))
+		err := writeOutputFile(path, outputFormatNames[format], func(w io.Writer) error {
+			switch format {
+			case config.OutputFormatVTT:
+				if err := tr.WebVTT(w, opts.WebVTT, meta); err != nil {
+					return err
+				}
+				if note != "" {
+					_, err := fmt.Fprintf(w, "\nNOTE %s\n", note)
+					return err
+				}
+			case config.OutputFormatTXT:
+				if err := tr.Text(w, opts.Text, meta); err != nil {
+					return err
+				}
+				if note != "" {
+					_, err := fmt.Fprintf(w, "\n%s\n", note)
+					return err
+				}
+			case config.OutputFormatSRT:
+				return tr.SRT(w)
+			case config.OutputFormatTTML:
+				return tr.TTML(w)
+			case config.OutputFormatCSV:
+				return tr.CSV(w)
+			default:
+				return fmt.Errorf("unsupported format %q", format)
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to open output file: %w", err)
+			return nil, err
 		}
+		paths = append(paths, path)
+	}
 
-		return nil
+	if opts.ASS.Enabled {
+		assPath := filepath.Join(dir, fname+".ass")
+		if err := writeOutputFile(assPath, "ASS", tr.ASS); err != nil {
+			return nil, err
+		}
+		paths = append(paths, assPath)
 	}
 
-	if err := openFiles(); err != nil {
-		return err
+	return paths, nil
+}
+
+// outputFormatNames are the names of the formats used in errors.
+var outputFormatNames = map[config.OutputFormat]string{
+	config.OutputFormatVTT:  "WebVTT",
+	config.OutputFormatTXT:  "text",
+	config.OutputFormatSRT:  "SRT",
+	config.OutputFormatTTML: "TTML",
+	config.OutputFormatCSV:  "CSV",
+}
+
+// writeOutputFile creates, or truncates, the file at path and writes it
+// through write. The name of the format is used in errors.
+func writeOutputFile(path, name string, write func(w io.Writer) error) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
 	}
-	defer vttFile.Close()
-	defer textFile.Close()
+	defer file.Close()
 
-	if err := tr.WebVTT(vttFile, t.cfg.OutputOptions.WebVTT); err != nil {
-		return fmt.Errorf("failed to write WebVTT file: %w", err)
+	if err := write(file); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", name, err)
 	}
 
-	if err := tr.Text(textFile, t.cfg.OutputOptions.Text); err != nil {
-		return fmt.Errorf("failed to write text file: %w", err)
+	return nil
+}
+
+// uploadFile uploads the file at the given path through the plugin's bot API
+// and returns the ID of the resulting file.
+func (t *Transcriber) uploadFile(apiURL, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
 	}
+	defer file.Close()
 
-	if _, err := vttFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file: %w", err)
 	}
 
-	if _, err := textFile.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
+	us := &model.UploadSession{
+		ChannelId: t.cfg.CallID,
+		Filename:  filepath.Base(path),
+		FileSize:  info.Size(),
 	}
 
-	vttInfo, err := vttFile.Stat()
+	payload, err := json.Marshal(us)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return "", fmt.Errorf("failed to encode payload: %w", err)
 	}
 
-	textInfo, err := textFile.Stat()
+	ctx, cancelCtx := context.WithTimeout(context.Background(), httpRequestTimeout)
+	defer cancelCtx()
+	resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL+"/uploads", payload, "")
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		err = newAPIError(resp, err)
+		slog.Error("failed to create upload", slog.String("err", err.Error()))
+		return "", err
 	}
+	defer resp.Body.Close()
 
-	apiURL := fmt.Sprintf("%s/plugins/%s/bot", t.apiURL, pluginID)
+	if err := json.NewDecoder(resp.Body).Decode(&us); err != nil {
+		slog.Error("failed to decode response body", slog.String("err", err.Error()))
+		return "", err
+	}
 
-	var lastErr error
-	for i := 0; i < maxAPIRetryAttempts; i++ {
-		if i > 0 {
-			slog.Error("publishTranscription failed", slog.Duration("reattempt_time", uploadRetryAttemptWaitTime))
-			time.Sleep(uploadRetryAttemptWaitTime)
-			if err := openFiles(); err != nil {
-				return fmt.Errorf("failed to open files: %w", err)
-			}
-			defer vttFile.Close()
-			defer textFile.Close()
+	uploadCtx, cancelUploadCtx := context.WithTimeout(context.Background(), httpUploadTimeout)
+	defer cancelUploadCtx()
+	resp, err = t.apiClient.DoAPIRequestReader(uploadCtx, http.MethodPost, apiURL+"/uploads/"+us.Id, file, nil)
+	if err != nil {
+		err = newAPIError(resp, err)
+		slog.Error("failed to upload data", slog.String("err", err.Error()))
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var fi model.FileInfo
+	if err := json.NewDecoder(resp.Body).Decode(&fi); err != nil {
+		slog.Error("failed to decode response body", slog.String("err", err.Error()))
+		return "", err
+	}
+
+	return fi.Id, nil
+}
+
+// getTranscriptionNote returns the annotations for the published
+// transcription (e.g. truncation, participants who opted out), if any.
+func (t *Transcriber) getTranscriptionNote() string {
+	var notes []string
+	for _, note := range []string{t.getTruncationNote(), t.getOptOutNote()} {
+		if note != "" {
+			notes = append(notes, note)
 		}
+	}
+	return strings.Join(notes, " ")
+}
 
-		// VTT format upload
-		us := &model.UploadSession{
-			ChannelId: t.cfg.CallID,
-			Filename:  filepath.Base(vttFile.Name()),
-			FileSize:  vttInfo.Size(),
+func (t *Transcriber) publishTranscriptions(outputs []transcriptionOutput) (err error) {
+	var fname string
+	err = retry(context.Background(), "getFilenameForCall", defaultRetryPolicy, func(_ context.Context) error {
+		var err error
+		fname, err = t.getFilenameForCall()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get filename for call: %w", err)
+	}
+
+	var languages []transcribe.LanguageShare
+	if len(outputs) > 0 {
+		languages = outputs[0].tr.Languages()
+	}
+
+	var keywords *transcribe.Keywords
+	if t.cfg.Output.ExtractKeywords && len(outputs) > 0 {
+		kw := outputs[0].tr.Keywords(transcribe.KeywordsMaxDefault)
+		keywords = &kw
+	}
+
+	// The main transcription is kept so that user corrections can later be
+	// applied to it (see ApplyCorrections).
+	if len(outputs) > 0 {
+		if err := t.saveTranscriptionRecord(outputs[0].tr); err != nil {
+			slog.Error("failed to save transcription record", slog.String("err", err.Error()))
 		}
+	}
 
-		payload, err := json.Marshal(us)
-		if err != nil {
-			return fmt.Errorf("failed to encode payload: %w", err)
+	// Trimming only affects the published files, the record above keeps the
+	// recording timestamps.
+	if t.cfg.Output.TrimSilenceMs > 0 {
+		outputs = trimOutputsSilences(outputs, t.cfg.Output.TrimSilenceMs)
+	}
+
+	if t.cfg.Output.SplitByLanguage {
+		outputs = splitOutputsByLanguage(outputs)
+	}
+
+	filePaths := make([][]string, len(outputs))
+	takenNames := make(map[string]bool, len(outputs))
+	for i, out := range outputs {
+		name := t.outputFilename(fname, out, takenNames)
+
+		var meta *transcribe.Metadata
+		if t.cfg.Output.MetadataHeader {
+			meta = t.transcriptionMetadata(out)
 		}
 
-		ctx, cancelCtx := context.WithTimeout(context.Background(), httpRequestTimeout)
-		defer cancelCtx()
-		resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL+"/uploads", payload, "")
+		filePaths[i], err = writeTranscriptionFiles(getDataDir(), name, out.tr, t.cfg.Output.Format.Formats(), t.cfg.Output.Options, meta, t.getTranscriptionNote())
 		if err != nil {
-			slog.Error("failed to create upload", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+			return err
 		}
-		defer resp.Body.Close()
-		cancelCtx()
 
-		if err := json.NewDecoder(resp.Body).Decode(&us); err != nil {
-			slog.Error("failed to decode response body", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+		if out.participants != nil {
+			path, err := writeParticipantsFile(name, out.participants)
+			if err != nil {
+				return err
+			}
+			filePaths[i] = append(filePaths[i], path)
 		}
 
-		ctx, cancelCtx = context.WithTimeout(context.Background(), httpUploadTimeout)
-		defer cancelCtx()
-		resp, err = t.apiClient.DoAPIRequestReader(ctx, http.MethodPost, apiURL+"/uploads/"+us.Id, vttFile, nil)
-		if err != nil {
-			slog.Error("failed to upload data", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+		if out.vadSegments != nil {
+			path, err := writeVADSegmentsFile(getDataDir(), name, out.vadSegments)
+			if err != nil {
+				return err
+			}
+			filePaths[i] = append(filePaths[i], path)
 		}
-		defer resp.Body.Close()
-		cancelCtx()
 
-		var vttFi model.FileInfo
-		if err := json.NewDecoder(resp.Body).Decode(&vttFi); err != nil {
-			slog.Error("failed to decode response body", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+		if out.summary != "" {
+			path, err := writeSummaryFile(getDataDir(), name, out.summary)
+			if err != nil {
+				return err
+			}
+			filePaths[i] = append(filePaths[i], path)
 		}
 
-		// text format upload
-		us = &model.UploadSession{
-			ChannelId: t.cfg.CallID,
-			Filename:  filepath.Base(textFile.Name()),
-			FileSize:  textInfo.Size(),
+		if out.timeMap != nil {
+			path, err := writeTimeMapFile(getDataDir(), name, t.cfg.Output.TrimSilenceMs, out.timeMap)
+			if err != nil {
+				return err
+			}
+			filePaths[i] = append(filePaths[i], path)
 		}
 
-		payload, err = json.Marshal(us)
+		filePaths[i], err = expandFormatPlaceholder(filePaths[i])
 		if err != nil {
-			return fmt.Errorf("failed to encode payload: %w", err)
+			return err
 		}
+	}
 
-		ctx, cancelCtx = context.WithTimeout(context.Background(), httpRequestTimeout)
-		defer cancelCtx()
-		resp, err = t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, apiURL+"/uploads", payload, "")
-		if err != nil {
-			slog.Error("failed to create upload", slog.String("err", err.Error()))
-			lastErr = err
-			continue
-		}
-		defer resp.Body.Close()
-		cancelCtx()
+	if t.cfg.Publish.DryRun {
+		slog.Info("dry run, skipping publishing", slog.Any("files", filePaths))
+		return nil
+	}
 
-		if err := json.NewDecoder(resp.Body).Decode(&us); err != nil {
-			slog.Error("failed to decode response body", slog.String("err", err.Error()))
-			lastErr = err
-			continue
-		}
+	apiURL := fmt.Sprintf("%s/plugins/%s/bot", t.apiURL, pluginID)
 
-		ctx, cancelCtx = context.WithTimeout(context.Background(), httpUploadTimeout)
-		defer cancelCtx()
-		resp, err = t.apiClient.DoAPIRequestReader(ctx, http.MethodPost, apiURL+"/uploads/"+us.Id, textFile, nil)
-		if err != nil {
-			slog.Error("failed to upload data", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+	// Failing to read the record of a previous run should not prevent us from
+	// publishing, at worst files end up duplicated.
+	rec, err := t.loadPublishedRecord()
+	if err != nil {
+		slog.Error("failed to load published record", slog.String("err", err.Error()))
+	}
+	if rec == nil {
+		rec = &publishedRecord{}
+	} else {
+		slog.Info("job was published before, replacing previous files",
+			slog.Int("version", rec.Version),
+			slog.Any("fileIDs", rec.FileIDs))
+	}
+
+	// When object storage is configured files are uploaded there and the
+	// plugin only receives their keys.
+	var s3 *s3Client
+	if t.cfg.Publish.S3.IsEnabled() {
+		s3 = newS3Client(t.cfg.Publish.S3)
+	}
+
+	err = retry(context.Background(), "publishTranscription", defaultRetryPolicy, func(ctx context.Context) error {
+		t.reportProgress()
+
+		var transcriptions public.Transcriptions
+		var publishedFileIDs []string
+		var objectStorage *objectStorageInfo
+		if s3 != nil {
+			objectStorage = &objectStorageInfo{Bucket: t.cfg.Publish.S3.Bucket}
 		}
-		defer resp.Body.Close()
-		cancelCtx()
+		for j, out := range outputs {
+			var fileIDs, objectKeys []string
+			for _, path := range filePaths[j] {
+				if s3 != nil {
+					key := s3.objectKey(t.cfg.TranscriptionID, path)
+					if err := s3.uploadFile(key, path); err != nil {
+						return err
+					}
+					objectKeys = append(objectKeys, key)
+					continue
+				}
+
+				fileID, err := t.uploadFile(apiURL, path)
+				if err != nil {
+					return err
+				}
+				fileIDs = append(fileIDs, fileID)
+			}
+			publishedFileIDs = append(publishedFileIDs, fileIDs...)
+			if objectStorage != nil {
+				objectStorage.Keys = append(objectStorage.Keys, objectKeys)
+			}
 
-		var textFi model.FileInfo
-		if err := json.NewDecoder(resp.Body).Decode(&textFi); err != nil {
-			slog.Error("failed to decode response body", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+			transcription := public.Transcription{
+				Language: out.tr.Language(),
+				FileIDs:  fileIDs,
+			}
+			if out.label != "" {
+				transcription.Title = fmt.Sprintf("%s (%s)", transcription.Language, out.label)
+			}
+			transcriptions = append(transcriptions, transcription)
 		}
 
 		// attaching post VTT and text formatted files.
-		payload, err = json.Marshal(public.TranscribingJobInfo{
-			JobID:  t.cfg.TranscriptionID,
-			PostID: t.cfg.PostID,
-			Transcriptions: []public.Transcription{
-				{
-					Language: tr.Language(),
-					FileIDs:  []string{vttFi.Id, textFi.Id},
-				},
+		payload, err := json.Marshal(transcribingJobInfo{
+			TranscribingJobInfo: public.TranscribingJobInfo{
+				JobID:          t.cfg.TranscriptionID,
+				PostID:         t.cfg.PostID,
+				Transcriptions: transcriptions,
 			},
+			Keywords:        keywords,
+			Languages:       languages,
+			Truncated:       t.truncated.Load() != nil,
+			Version:         rec.Version + 1,
+			ReplacesFileIDs: rec.FileIDs,
+			ObjectStorage:   objectStorage,
 		})
 		if err != nil {
 			slog.Error("failed to encode payload", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+			return err
 		}
 
 		url := fmt.Sprintf("%s/calls/%s/transcriptions", apiURL, t.cfg.CallID)
-		ctx, cancelCtx = context.WithTimeout(context.Background(), httpRequestTimeout)
+		ctx, cancelCtx := context.WithTimeout(ctx, httpRequestTimeout)
 		defer cancelCtx()
-		resp, err = t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, url, payload, "")
+		resp, err := t.apiClient.DoAPIRequestBytes(ctx, http.MethodPost, url, payload, "")
 		if err != nil {
+			err = newAPIError(resp, err)
 			slog.Error("failed to post transcription", slog.String("err", err.Error()))
-			lastErr = err
-			continue
+			return err
 		}
 		defer resp.Body.Close()
 
+		if err := t.savePublishedRecord(publishedRecord{
+			Version: rec.Version + 1,
+			FileIDs: publishedFileIDs,
+		}); err != nil {
+			slog.Error("failed to save published record", slog.String("err", err.Error()))
+		}
+
+		res := &publishResult{
+			FileIDs:   publishedFileIDs,
+			Languages: languages,
+		}
+		if objectStorage != nil {
+			for _, keys := range objectStorage.Keys {
+				res.ObjectKeys = append(res.ObjectKeys, keys...)
+			}
+		}
+		t.published.Store(res)
+
 		return nil
-	}
+	})
 
-	return fmt.Errorf("maximum attempts reached : %w", lastErr)
+	return err
 }
 
 func newTimeP(t time.Time) *time.Time {
 	return &t
 }
 
+// newMonotonicClock returns a function reporting the time elapsed since its
+// creation. Since only durations are exposed, wall clock readings, which can
+// jump backwards or forwards (e.g. NTP steps), never take part in the result.
+func newMonotonicClock() func() time.Duration {
+	start := time.Now()
+	return func() time.Duration {
+		return time.Since(start)
+	}
+}
+
 func sanitizeFilename(name string) string {
 	return filenameSanitizationRE.ReplaceAllString(name, "_")
 }
@@ -304,16 +596,17 @@ func (t *Transcriber) getFilenameForCall() (string, error) {
 	url := fmt.Sprintf("%s/plugins/%s/bot/calls/%s/filename", t.cfg.SiteURL, pluginID, t.cfg.CallID)
 	resp, err := t.apiClient.DoAPIRequest(ctx, http.MethodGet, url, "", "")
 	if err != nil {
+		err = newAPIError(resp, err)
 		return "", fmt.Errorf("failed to get filename: %w", err)
 	}
 	defer resp.Body.Close()
 
-	var m map[string]string
-	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+	var r filenameResponse
+	if err := decodeAPIResponse("/bot/calls/{callID}/filename", resp.Body, &r); err != nil {
 		return "", fmt.Errorf("failed to unmarshal filename: %w", err)
 	}
 
-	filename := sanitizeFilename(m["filename"])
+	filename := sanitizeFilename(r.Filename)
 
 	if filename == "" {
 		return "", fmt.Errorf("invalid empty filename")
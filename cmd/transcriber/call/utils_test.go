@@ -13,6 +13,7 @@ import (
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
 
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
 	"github.com/mattermost/mattermost/server/public/model"
 
 	"github.com/stretchr/testify/require"
@@ -46,6 +47,79 @@ func TestSanitizeFilename(t *testing.T) {
 	}
 }
 
+func TestNewTranscriptionOutputs(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	trA := transcribe.Transcription{
+		{Speaker: "SpeakerA", Segments: []transcribe.Segment{{StartTS: 0, EndTS: 1000, Text: "A1"}}},
+	}
+	apis := []config.TranscribeAPI{config.TranscribeAPIWhisperCPP, config.TranscribeAPIAzure}
+	sessionTrs := make([]map[string][]transcribe.TrackTranscription, len(apis))
+
+	t.Run("single API", func(t *testing.T) {
+		outputs := tr.newTranscriptionOutputs(apis[:1], []transcribe.Transcription{trA}, sessionTrs[:1])
+		require.Equal(t, []transcriptionOutput{{tr: trA}}, outputs)
+
+		require.Empty(t, tr.newTranscriptionOutputs(apis[:1], []transcribe.Transcription{nil}, sessionTrs[:1]))
+	})
+
+	t.Run("comparison", func(t *testing.T) {
+		outputs := tr.newTranscriptionOutputs(apis, []transcribe.Transcription{trA, trA}, sessionTrs)
+		require.Equal(t, []transcriptionOutput{
+			{tr: trA, label: "whisper.cpp"},
+			{tr: trA, label: "azure"},
+		}, outputs)
+
+		// The comparison transcription is published even if the main one
+		// is empty.
+		outputs = tr.newTranscriptionOutputs(apis, []transcribe.Transcription{nil, trA}, sessionTrs)
+		require.Equal(t, []transcriptionOutput{{tr: trA, label: "azure"}}, outputs)
+
+		require.Empty(t, tr.newTranscriptionOutputs(apis, []transcribe.Transcription{nil, nil}, sessionTrs))
+	})
+}
+
+func TestSplitOutputsByLanguage(t *testing.T) {
+	monolingual := transcribe.Transcription{
+		{
+			Speaker:  "SpeakerA",
+			Language: "en",
+			Segments: []transcribe.Segment{{StartTS: 0, EndTS: 1000, Text: "A1"}},
+		},
+	}
+	multilingual := transcribe.Transcription{
+		{
+			Speaker: "SpeakerA",
+			Segments: []transcribe.Segment{
+				{StartTS: 0, EndTS: 1000, Text: "A1", Language: "en"},
+				{StartTS: 1000, EndTS: 3000, Text: "A2", Language: "fr"},
+			},
+		},
+	}
+	participants := []participant{{SessionID: "sessionA"}}
+	vadSegments := []trackVADSegments{{TrackID: "trackA", Segments: []vadSegment{{StartMs: 0, EndMs: 3000}}}}
+
+	outputs := splitOutputsByLanguage([]transcriptionOutput{
+		{tr: monolingual, label: "whisper.cpp"},
+		{tr: multilingual, label: "azure", participants: participants, vadSegments: vadSegments},
+	})
+	require.Len(t, outputs, 3)
+
+	require.Equal(t, transcriptionOutput{tr: monolingual, label: "whisper.cpp"}, outputs[0])
+
+	require.Equal(t, "azure", outputs[1].label)
+	require.Equal(t, "fr", outputs[1].language)
+	require.Equal(t, "fr", outputs[1].tr.Language())
+	require.Equal(t, participants, outputs[1].participants)
+	require.Equal(t, vadSegments, outputs[1].vadSegments)
+
+	require.Equal(t, "azure", outputs[2].label)
+	require.Equal(t, "en", outputs[2].language)
+	require.Equal(t, "en", outputs[2].tr.Language())
+	require.Nil(t, outputs[2].participants)
+	require.Nil(t, outputs[2].vadSegments)
+}
+
 func TestPublishTranscriptions(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		AddSource: true,
@@ -71,8 +145,10 @@ func TestPublishTranscriptions(t *testing.T) {
 		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
 		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
-		NumThreads:      1,
-		ModelSize:       config.ModelSizeTiny,
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
 	}
 	cfg.SetDefaults()
 	tr, err := NewTranscriber(cfg)
@@ -81,7 +157,7 @@ func TestPublishTranscriptions(t *testing.T) {
 
 	t.Run("failure to get filename", func(t *testing.T) {
 		err := tr.publishTranscription(transcribe.Transcription{})
-		require.EqualError(t, err, "failed to get filename for call: failed to get filename: AppErrorFromJSON: model.utils.decode_json.app_error, body: 404 page not found\n, json: cannot unmarshal number into Go value of type model.AppError")
+		require.EqualError(t, err, "failed to get filename for call: permanent failure: failed to get filename: AppErrorFromJSON: model.utils.decode_json.app_error, body: 404 page not found\n, json: cannot unmarshal number into Go value of type model.AppError (status 404)")
 	})
 
 	t.Run("missing file", func(t *testing.T) {
@@ -127,6 +203,8 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 	dataDir := os.Getenv("DATA_DIR")
 	os.Setenv("DATA_DIR", filepath.Dir(vttFile.Name()))
 	defer os.Setenv("DATA_DIR", dataDir)
+	os.Remove(tr.publishedRecordPath())
+	defer os.Remove(tr.publishedRecordPath())
 
 	maxAPIRetryAttempts = 2
 
@@ -135,7 +213,7 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 			middlewares[0],
 			func(w http.ResponseWriter, r *http.Request) bool {
 				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads" && r.Method == http.MethodPost {
-					w.WriteHeader(400)
+					w.WriteHeader(500)
 					fmt.Fprintln(w, `{"message": "upload session error"}`)
 					return true
 				}
@@ -145,7 +223,7 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 		}
 
 		err := tr.publishTranscription(transcribe.Transcription{})
-		require.EqualError(t, err, "maximum attempts reached : upload session error")
+		require.EqualError(t, err, "maximum attempts reached : upload session error (status 500)")
 	})
 
 	t.Run("upload failure", func(t *testing.T) {
@@ -171,7 +249,7 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 			},
 			func(w http.ResponseWriter, r *http.Request) bool {
 				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads/jpanyqdipffrpmxxst3kzdjaah" && r.Method == http.MethodPost {
-					w.WriteHeader(400)
+					w.WriteHeader(503)
 					fmt.Fprintln(w, `{"message": "upload error"}`)
 					return true
 				}
@@ -181,7 +259,28 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 		}
 
 		err := tr.publishTranscription(transcribe.Transcription{})
-		require.EqualError(t, err, "maximum attempts reached : upload error")
+		require.EqualError(t, err, "maximum attempts reached : upload error (status 503)")
+	})
+
+	t.Run("permanent upload failure", func(t *testing.T) {
+		var attempts int
+		middlewares = []middleware{
+			middlewares[0],
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads" && r.Method == http.MethodPost {
+					attempts++
+					w.WriteHeader(413)
+					fmt.Fprintln(w, `{"message": "file too large"}`)
+					return true
+				}
+
+				return false
+			},
+		}
+
+		err := tr.publishTranscription(transcribe.Transcription{})
+		require.EqualError(t, err, "permanent failure: file too large (status 413)")
+		require.Equal(t, 1, attempts)
 	})
 
 	t.Run("success after failure", func(t *testing.T) {
@@ -214,7 +313,7 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 						err = json.NewEncoder(w).Encode(&fi)
 						require.NoError(t, err)
 					} else {
-						w.WriteHeader(400)
+						w.WriteHeader(500)
 						fmt.Fprintln(w, `{"message": "upload error"}`)
 						failures++
 					}
@@ -291,7 +390,7 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 			func(w http.ResponseWriter, r *http.Request) bool {
 				if r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename" && r.Method == http.MethodGet {
 					if failures == 0 {
-						w.WriteHeader(400)
+						w.WriteHeader(502)
 						failures++
 					} else {
 						w.WriteHeader(200)
@@ -346,4 +445,303 @@ All right, we should be recording. Welcome everyone, developers meeting for Dece
 		err := tr.publishTranscription(transcribe.Transcription{})
 		require.NoError(t, err)
 	})
+
+	t.Run("labeled outputs", func(t *testing.T) {
+		var uploadedFilenames []string
+		var jobInfo public.TranscribingJobInfo
+		middlewares = []middleware{
+			middlewares[0],
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads" && r.Method == http.MethodPost {
+					var us model.UploadSession
+
+					err := json.NewDecoder(r.Body).Decode(&us)
+					require.NoError(t, err)
+
+					uploadedFilenames = append(uploadedFilenames, us.Filename)
+					us.Id = "jpanyqdipffrpmxxst3kzdjaah"
+
+					w.WriteHeader(200)
+					err = json.NewEncoder(w).Encode(&us)
+					require.NoError(t, err)
+
+					return true
+				}
+
+				return false
+			},
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads/jpanyqdipffrpmxxst3kzdjaah" && r.Method == http.MethodPost {
+					fi := model.FileInfo{Id: fmt.Sprintf("fileID%d", len(uploadedFilenames))}
+					w.WriteHeader(200)
+					err = json.NewEncoder(w).Encode(&fi)
+					require.NoError(t, err)
+
+					return true
+				}
+
+				return false
+			},
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/transcriptions" && r.Method == http.MethodPost {
+					err := json.NewDecoder(r.Body).Decode(&jobInfo)
+					require.NoError(t, err)
+					w.WriteHeader(200)
+					return true
+				}
+
+				return false
+			},
+		}
+
+		err := tr.publishTranscriptions([]transcriptionOutput{
+			{tr: transcribe.Transcription{}, label: config.TranscribeAPIWhisperCPP},
+			{tr: transcribe.Transcription{}, label: config.TranscribeAPIAzure},
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{
+			"Call_Test-whisper.cpp.vtt",
+			"Call_Test-whisper.cpp.txt",
+			"Call_Test-azure.vtt",
+			"Call_Test-azure.txt",
+		}, uploadedFilenames)
+
+		require.Equal(t, public.Transcriptions{
+			{
+				Title:    "en (whisper.cpp)",
+				Language: "en",
+				FileIDs:  []string{"fileID1", "fileID2"},
+			},
+			{
+				Title:    "en (azure)",
+				Language: "en",
+				FileIDs:  []string{"fileID3", "fileID4"},
+			},
+		}, jobInfo.Transcriptions)
+	})
+
+	t.Run("re-run replaces previous files", func(t *testing.T) {
+		var uploadedFilenames []string
+		var jobInfo transcribingJobInfo
+		middlewares = []middleware{
+			middlewares[0],
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads" && r.Method == http.MethodPost {
+					var us model.UploadSession
+
+					err := json.NewDecoder(r.Body).Decode(&us)
+					require.NoError(t, err)
+
+					uploadedFilenames = append(uploadedFilenames, us.Filename)
+					us.Id = "jpanyqdipffrpmxxst3kzdjaah"
+
+					w.WriteHeader(200)
+					err = json.NewEncoder(w).Encode(&us)
+					require.NoError(t, err)
+
+					return true
+				}
+
+				return false
+			},
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/uploads/jpanyqdipffrpmxxst3kzdjaah" && r.Method == http.MethodPost {
+					fi := model.FileInfo{Id: fmt.Sprintf("fileID%d", len(uploadedFilenames))}
+					w.WriteHeader(200)
+					err = json.NewEncoder(w).Encode(&fi)
+					require.NoError(t, err)
+
+					return true
+				}
+
+				return false
+			},
+			func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/transcriptions" && r.Method == http.MethodPost {
+					err := json.NewDecoder(r.Body).Decode(&jobInfo)
+					require.NoError(t, err)
+					w.WriteHeader(200)
+					return true
+				}
+
+				return false
+			},
+		}
+
+		rec, err := tr.loadPublishedRecord()
+		require.NoError(t, err)
+		require.NotNil(t, rec)
+		require.Equal(t, []string{"fileID1", "fileID2", "fileID3", "fileID4"}, rec.FileIDs)
+
+		err = tr.publishTranscriptions([]transcriptionOutput{{tr: transcribe.Transcription{}}})
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"Call_Test.vtt", "Call_Test.txt"}, uploadedFilenames)
+		require.Equal(t, rec.Version+1, jobInfo.Version)
+		require.Equal(t, rec.FileIDs, jobInfo.ReplacesFileIDs)
+
+		rec, err = tr.loadPublishedRecord()
+		require.NoError(t, err)
+		require.Equal(t, jobInfo.Version, rec.Version)
+		require.Equal(t, []string{"fileID1", "fileID2"}, rec.FileIDs)
+	})
+}
+
+func TestSiteURLPathPrefix(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/mattermost/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename":
+			fmt.Fprintln(w, `{"filename": "Call_Test"}`)
+		case "/mattermost/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile":
+			fmt.Fprintln(w, `{"id": "userID", "username": "testuser"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL + "/mattermost",
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	require.Equal(t, ts.URL+"/mattermost", tr.apiURL)
+
+	filename, err := tr.getFilenameForCall()
+	require.NoError(t, err)
+	require.Equal(t, "Call_Test", filename)
+
+	user, err := tr.getSessionProfile("sessionID")
+	require.NoError(t, err)
+	require.Equal(t, "testuser", user.Username)
+
+	require.Equal(t, []string{
+		"/mattermost/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename",
+		"/mattermost/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/sessions/sessionID/profile",
+	}, paths)
+}
+
+func TestPublishTranscriptionsDryRun(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path == "/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename" {
+			fmt.Fprintln(w, `{"filename": "Call_Test"}`)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	t.Setenv("DATA_DIR", t.TempDir())
+
+	cfg := config.CallTranscriberConfig{
+		SiteURL:         ts.URL,
+		CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+		PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+		TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+		AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+		Engine: config.EngineConfig{
+			NumThreads: 1,
+			ModelSize:  config.ModelSizeTiny,
+		},
+		Publish: config.PublishConfig{
+			DryRun: true,
+		},
+	}
+	cfg.SetDefaults()
+	tr, err := NewTranscriber(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tr)
+
+	err = tr.publishTranscription(transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello", StartTS: 0, EndTS: 1000},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Only the filename is fetched, nothing gets uploaded nor posted.
+	require.Equal(t, []string{
+		"/plugins/com.mattermost.calls/bot/calls/8w8jorhr7j83uqr6y1st894hqe/filename",
+	}, paths)
+
+	for _, name := range []string{"Call_Test.vtt", "Call_Test.txt"} {
+		_, err := os.Stat(filepath.Join(getDataDir(), name))
+		require.NoError(t, err)
+	}
+
+	rec, err := tr.loadPublishedRecord()
+	require.NoError(t, err)
+	require.Nil(t, rec)
+	require.Nil(t, tr.published.Load())
+
+	// The transcription is kept for corrections to be applied later on.
+	saved, err := tr.loadTranscriptionRecord()
+	require.NoError(t, err)
+	require.Equal(t, "Alice", saved[0].Speaker)
+}
+
+func TestWriteTranscriptionFilesFormats(t *testing.T) {
+	tr := transcribe.Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []transcribe.Segment{
+				{Text: "Hello", StartTS: 0, EndTS: 1000},
+			},
+		},
+	}
+
+	t.Run("subset", func(t *testing.T) {
+		dir := t.TempDir()
+		paths, err := writeTranscriptionFiles(dir, "call", tr, config.OutputFormat("srt,vtt").Formats(), config.OutputOptions{}, nil, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{filepath.Join(dir, "call.vtt"), filepath.Join(dir, "call.srt")}, paths)
+
+		srt, err := os.ReadFile(paths[1])
+		require.NoError(t, err)
+		require.Equal(t, "1\n00:00:00,000 --> 00:00:01,000\n(Alice) Hello\n", string(srt))
+
+		_, err = os.Stat(filepath.Join(dir, "call.txt"))
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("all", func(t *testing.T) {
+		dir := t.TempDir()
+		opts := config.OutputOptions{ASS: transcribe.ASSOptions{Enabled: true}}
+		paths, err := writeTranscriptionFiles(dir, "call", tr, config.OutputFormats, opts, nil, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			filepath.Join(dir, "call.vtt"),
+			filepath.Join(dir, "call.txt"),
+			filepath.Join(dir, "call.srt"),
+			filepath.Join(dir, "call.ttml"),
+			filepath.Join(dir, "call.csv"),
+			filepath.Join(dir, "call.json"),
+			filepath.Join(dir, "call.ass"),
+		}, paths)
+
+		data, err := os.ReadFile(paths[5])
+		require.NoError(t, err)
+		var segments []transcribe.NamedSegment
+		require.NoError(t, json.Unmarshal(data, &segments))
+		require.Equal(t, tr.Interleave(), segments)
+	})
 }
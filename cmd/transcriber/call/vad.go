@@ -0,0 +1,78 @@
+package call
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/vad"
+
+	"github.com/streamer45/silero-vad-go/speech"
+)
+
+// speechDetector finds the speech segments in audio samples.
+type speechDetector interface {
+	Detect(pcm []float32) ([]speech.Segment, error)
+	Reset() error
+	Destroy() error
+}
+
+// webrtcSpeechDetector adapts vad.Detector to speechDetector.
+type webrtcSpeechDetector struct {
+	*vad.Detector
+}
+
+func (sd webrtcSpeechDetector) Detect(pcm []float32) ([]speech.Segment, error) {
+	segments, err := sd.Detector.Detect(pcm)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]speech.Segment, len(segments))
+	for i, s := range segments {
+		ret[i] = speech.Segment(s)
+	}
+
+	return ret, nil
+}
+
+func (sd webrtcSpeechDetector) Reset() error {
+	sd.Detector.Reset()
+	return nil
+}
+
+func (sd webrtcSpeechDetector) Destroy() error {
+	return nil
+}
+
+// newSpeechDetector creates a speech detector running the configured engine.
+func newSpeechDetector(cfg config.VADConfig) (speechDetector, error) {
+	switch cfg.Engine {
+	case config.VADEngineWebRTC:
+		// The detector doesn't output probabilities so the threshold picks
+		// how aggressive it is instead.
+		sd, err := vad.NewDetector(vad.DetectorConfig{
+			Mode:                 min(vad.MaxMode, int(cfg.Threshold*(vad.MaxMode+1))),
+			MinSilenceDurationMs: cfg.MinSilenceDurationMs,
+			SpeechPadMs:          cfg.SpeechPadMs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return webrtcSpeechDetector{sd}, nil
+	case config.VADEngineSilero, "":
+		sd, err := speech.NewDetector(speech.DetectorConfig{
+			ModelPath:            filepath.Join(getModelsDir(), "silero_vad.onnx"),
+			SampleRate:           trackOutAudioRate,
+			Threshold:            float32(cfg.Threshold),
+			MinSilenceDurationMs: cfg.MinSilenceDurationMs,
+			SpeechPadMs:          cfg.SpeechPadMs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return sd, nil
+	default:
+		return nil, fmt.Errorf("unsupported VAD engine %q", cfg.Engine)
+	}
+}
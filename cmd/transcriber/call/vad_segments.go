@@ -0,0 +1,85 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// vadSegment is a portion of a track detected as speech, and so sent for
+// transcription. Times are in milliseconds since the start of the call.
+type vadSegment struct {
+	StartMs int64 `json:"start_ms"`
+	EndMs   int64 `json:"end_ms"`
+}
+
+type trackVADSegments struct {
+	TrackID   string       `json:"track_id"`
+	SessionID string       `json:"session_id"`
+	UserID    string       `json:"user_id,omitempty"`
+	Speaker   string       `json:"speaker"`
+	Segments  []vadSegment `json:"segments"`
+}
+
+// vadSegmentsArtifact is the JSON artifact holding the speech segmentation of
+// every track. Audio outside of segments was never transcribed.
+type vadSegmentsArtifact struct {
+	Tracks []trackVADSegments `json:"tracks"`
+}
+
+func (t *Transcriber) setTrackVADSegments(trackID string, segments []vadSegment) {
+	t.vadSegmentsMut.Lock()
+	defer t.vadSegmentsMut.Unlock()
+	if t.vadSegments == nil {
+		t.vadSegments = make(map[string][]vadSegment)
+	}
+	t.vadSegments[trackID] = segments
+}
+
+// getTrackVADSegments returns the speech segments of the given track, if
+// recorded.
+func (t *Transcriber) getTrackVADSegments(trackID string) []vadSegment {
+	t.vadSegmentsMut.Lock()
+	defer t.vadSegmentsMut.Unlock()
+	return t.vadSegments[trackID]
+}
+
+// getVADSegments returns the speech segments of the given tracks, in the same
+// order.
+func (t *Transcriber) getVADSegments(ctxs []trackContext) []trackVADSegments {
+	tracks := make([]trackVADSegments, 0, len(ctxs))
+	for _, ctx := range ctxs {
+		track := trackVADSegments{
+			TrackID:   ctx.trackID,
+			SessionID: ctx.sessionID,
+			Segments:  t.getTrackVADSegments(ctx.trackID),
+		}
+		if ctx.user != nil {
+			track.UserID = ctx.user.Id
+			track.Speaker = t.getSpeakerName(ctx.user)
+		}
+		if track.Segments == nil {
+			track.Segments = []vadSegment{}
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// writeVADSegmentsFile saves the VAD segments JSON artifact in dir and
+// returns its path.
+func writeVADSegmentsFile(dir, fname string, tracks []trackVADSegments) (string, error) {
+	path := filepath.Join(dir, fname+"-vad.json")
+
+	data, err := json.MarshalIndent(vadSegmentsArtifact{Tracks: tracks}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode VAD segments: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write VAD segments file: %w", err)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,68 @@
+package call
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVADSegments(t *testing.T) {
+	tr := setupTranscriberForTest(t)
+
+	ctxs := []trackContext{
+		{
+			trackID:   "trackA",
+			sessionID: "sessionA",
+			user:      &model.User{Id: "userA", Username: "usera", FirstName: "User", LastName: "A"},
+		},
+		{
+			trackID:   "trackB",
+			sessionID: "sessionB",
+			user:      &model.User{Id: "userB", Username: "userb"},
+		},
+	}
+
+	tr.setTrackVADSegments("trackA", []vadSegment{
+		{StartMs: 1000, EndMs: 2500},
+		{StartMs: 4000, EndMs: 4800},
+	})
+	require.Nil(t, tr.getTrackVADSegments("trackB"))
+
+	expected := []trackVADSegments{
+		{
+			TrackID:   "trackA",
+			SessionID: "sessionA",
+			UserID:    "userA",
+			Speaker:   "User A",
+			Segments: []vadSegment{
+				{StartMs: 1000, EndMs: 2500},
+				{StartMs: 4000, EndMs: 4800},
+			},
+		},
+		{
+			TrackID:   "trackB",
+			SessionID: "sessionB",
+			UserID:    "userB",
+			Speaker:   "userb",
+			Segments:  []vadSegment{},
+		},
+	}
+	tracks := tr.getVADSegments(ctxs)
+	require.Equal(t, expected, tracks)
+
+	path, err := writeVADSegmentsFile(getDataDir(), "Call_Test", tracks)
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(getDataDir(), "Call_Test-vad.json"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var artifact vadSegmentsArtifact
+	require.NoError(t, json.Unmarshal(data, &artifact))
+	require.Equal(t, expected, artifact.Tracks)
+}
@@ -0,0 +1,36 @@
+package call
+
+import (
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSpeechDetector(t *testing.T) {
+	t.Run("webrtc", func(t *testing.T) {
+		sd, err := newSpeechDetector(config.VADConfig{
+			Engine:               config.VADEngineWebRTC,
+			Threshold:            0.5,
+			MinSilenceDurationMs: 150,
+			SpeechPadMs:          60,
+		})
+		require.NoError(t, err)
+		require.IsType(t, webrtcSpeechDetector{}, sd)
+		defer func() {
+			require.NoError(t, sd.Destroy())
+		}()
+
+		segments, err := sd.Detect(make([]float32, trackOutAudioRate))
+		require.NoError(t, err)
+		require.Empty(t, segments)
+		require.NoError(t, sd.Reset())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		sd, err := newSpeechDetector(config.VADConfig{Engine: "invalid"})
+		require.EqualError(t, err, `unsupported VAD engine "invalid"`)
+		require.Nil(t, sd)
+	})
+}
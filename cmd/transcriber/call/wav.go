@@ -0,0 +1,201 @@
+package call
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+
+	wavMaxFmtChunkSize = 1024
+)
+
+// errUnsupportedWAVEncoding is returned for valid WAV files whose encoding
+// (e.g. A-law, ADPCM) can't be decoded natively.
+var errUnsupportedWAVEncoding = errors.New("unsupported WAV encoding")
+
+type wavFormat struct {
+	audioFormat   uint16
+	channels      uint16
+	sampleRate    uint32
+	bitsPerSample uint16
+}
+
+// wavHeaderSize is the size of the header written by pcm16WAVHeader.
+const wavHeaderSize = 44
+
+// pcm16WAVHeader returns the header of a WAV file holding dataSize bytes of
+// 16-bit mono PCM samples at trackOutAudioRate.
+func pcm16WAVHeader(dataSize uint32) []byte {
+	hdr := make([]byte, 0, wavHeaderSize)
+	hdr = append(hdr, "RIFF"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, wavHeaderSize-8+dataSize)
+	hdr = append(hdr, "WAVEfmt "...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, 16)
+	hdr = binary.LittleEndian.AppendUint16(hdr, wavFormatPCM)
+	hdr = binary.LittleEndian.AppendUint16(hdr, trackAudioChannels)
+	hdr = binary.LittleEndian.AppendUint32(hdr, trackOutAudioRate)
+	hdr = binary.LittleEndian.AppendUint32(hdr, trackOutAudioRate*trackAudioChannels*2)
+	hdr = binary.LittleEndian.AppendUint16(hdr, trackAudioChannels*2)
+	hdr = binary.LittleEndian.AppendUint16(hdr, 16)
+	hdr = append(hdr, "data"...)
+	hdr = binary.LittleEndian.AppendUint32(hdr, dataSize)
+	return hdr
+}
+
+// decodeWAV reads a WAV file and returns its audio as mono PCM samples
+// resampled to trackOutAudioRate. Only integer (8, 16, 24 and 32-bit) and
+// float (32 and 64-bit) encodings are supported.
+func decodeWAV(path string) ([]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	var riffHdr [12]byte
+	if _, err := io.ReadFull(f, riffHdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if string(riffHdr[0:4]) != "RIFF" || string(riffHdr[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	var format *wavFormat
+	for {
+		var chunkHdr [8]byte
+		if _, err := io.ReadFull(f, chunkHdr[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("data chunk not found")
+			}
+			return nil, fmt.Errorf("failed to read chunk header: %w", err)
+		}
+		chunkID := string(chunkHdr[0:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunkHdr[4:8]))
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 || chunkSize > wavMaxFmtChunkSize {
+				return nil, fmt.Errorf("invalid fmt chunk size %d", chunkSize)
+			}
+			buf := make([]byte, chunkSize+chunkSize%2)
+			if _, err := io.ReadFull(f, buf); err != nil {
+				return nil, fmt.Errorf("failed to read fmt chunk: %w", err)
+			}
+			format = &wavFormat{
+				audioFormat:   binary.LittleEndian.Uint16(buf[0:2]),
+				channels:      binary.LittleEndian.Uint16(buf[2:4]),
+				sampleRate:    binary.LittleEndian.Uint32(buf[4:8]),
+				bitsPerSample: binary.LittleEndian.Uint16(buf[14:16]),
+			}
+			// The actual format of extensible files is given by the first
+			// two bytes of the sub-format GUID.
+			if format.audioFormat == wavFormatExtensible && chunkSize >= 26 {
+				format.audioFormat = binary.LittleEndian.Uint16(buf[24:26])
+			}
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("data chunk found before fmt chunk")
+			}
+			samples, err := decodeWAVData(io.LimitReader(f, chunkSize), *format)
+			if err != nil {
+				return nil, err
+			}
+			return resamplePCM(samples, int(format.sampleRate), trackOutAudioRate), nil
+		default:
+			// Chunks are padded to an even size.
+			if _, err := f.Seek(chunkSize+chunkSize%2, io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("failed to skip chunk: %w", err)
+			}
+		}
+	}
+}
+
+// decodeWAVData decodes interleaved samples, downmixing them to mono.
+func decodeWAVData(r io.Reader, format wavFormat) ([]float32, error) {
+	if format.channels == 0 || format.sampleRate == 0 {
+		return nil, fmt.Errorf("invalid WAV format")
+	}
+
+	sampleSize := int(format.bitsPerSample / 8)
+	var decodeSample func(b []byte) float32
+	switch {
+	case format.audioFormat == wavFormatPCM && format.bitsPerSample == 8:
+		// 8-bit samples are unsigned.
+		decodeSample = func(b []byte) float32 {
+			return (float32(b[0]) - 128) / 128
+		}
+	case format.audioFormat == wavFormatPCM && format.bitsPerSample == 16:
+		decodeSample = func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+		}
+	case format.audioFormat == wavFormatPCM && format.bitsPerSample == 24:
+		decodeSample = func(b []byte) float32 {
+			// Shifting left then right sign-extends the value.
+			return float32(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case format.audioFormat == wavFormatPCM && format.bitsPerSample == 32:
+		decodeSample = func(b []byte) float32 {
+			return float32(float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31))
+		}
+	case format.audioFormat == wavFormatFloat && format.bitsPerSample == 32:
+		decodeSample = func(b []byte) float32 {
+			return math.Float32frombits(binary.LittleEndian.Uint32(b))
+		}
+	case format.audioFormat == wavFormatFloat && format.bitsPerSample == 64:
+		decodeSample = func(b []byte) float32 {
+			return float32(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+		}
+	default:
+		return nil, fmt.Errorf("%w (format=%d, bits=%d)", errUnsupportedWAVEncoding, format.audioFormat, format.bitsPerSample)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data chunk: %w", err)
+	}
+
+	channels := int(format.channels)
+	frameSize := sampleSize * channels
+	samples := make([]float32, len(data)/frameSize)
+	for i := range samples {
+		var sum float32
+		for c := 0; c < channels; c++ {
+			sum += decodeSample(data[i*frameSize+c*sampleSize:])
+		}
+		samples[i] = sum / float32(channels)
+	}
+
+	return samples, nil
+}
+
+// resamplePCM converts samples from inRate to outRate through linear
+// interpolation. This is good enough for speech recognition purposes.
+func resamplePCM(samples []float32, inRate, outRate int) []float32 {
+	if inRate == outRate || len(samples) == 0 {
+		return samples
+	}
+
+	outLen := int(int64(len(samples)) * int64(outRate) / int64(inRate))
+	out := make([]float32, outLen)
+	ratio := float64(inRate) / float64(outRate)
+	for i := range out {
+		pos := float64(i) * ratio
+		idx := int(pos)
+		if idx+1 >= len(samples) {
+			out[i] = samples[len(samples)-1]
+			continue
+		}
+		frac := float32(pos - float64(idx))
+		out[i] = samples[idx]*(1-frac) + samples[idx+1]*frac
+	}
+
+	return out
+}
@@ -0,0 +1,123 @@
+package call
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+)
+
+const (
+	webhookRequestTimeout = 10 * time.Second
+	// webhookSignatureHeader carries the hex encoded HMAC-SHA256 of the
+	// request body, keyed with the configured secret.
+	webhookSignatureHeader = "X-Calls-Transcriber-Signature"
+)
+
+// publishResult holds what was published for the job.
+type publishResult struct {
+	FileIDs    []string
+	ObjectKeys []string
+	Languages  []transcribe.LanguageShare
+}
+
+// completionPayload is sent to the completion webhook once the job finishes.
+type completionPayload struct {
+	CallID          string                     `json:"call_id"`
+	PostID          string                     `json:"post_id"`
+	TranscriptionID string                     `json:"transcription_id"`
+	Status          public.JobStatusType       `json:"status"`
+	DurationMs      int64                      `json:"duration_ms"`
+	Languages       []transcribe.LanguageShare `json:"languages,omitempty"`
+	FileIDs         []string                   `json:"file_ids,omitempty"`
+	ObjectKeys      []string                   `json:"object_keys,omitempty"`
+	Error           string                     `json:"error,omitempty"`
+	// Timestamp is the time the payload was generated, in milliseconds,
+	// letting receivers reject replayed requests.
+	Timestamp int64 `json:"timestamp"`
+}
+
+func signWebhookPayload(secret string, payload []byte) string {
+	return "sha256=" + hex.EncodeToString(hmacSHA256([]byte(secret), string(payload)))
+}
+
+// notifyCompletion sends the job outcome to the completion webhook, if
+// configured and not in dry run. It's best effort, never fails the job and
+// only happens once.
+func (t *Transcriber) notifyCompletion(callDurMs int64, jobErr error) {
+	if t.cfg.Publish.CompletionWebhookURL == "" || t.cfg.Publish.DryRun {
+		return
+	}
+
+	t.completionOnce.Do(func() {
+		payload := completionPayload{
+			CallID:          t.cfg.CallID,
+			PostID:          t.cfg.PostID,
+			TranscriptionID: t.cfg.TranscriptionID,
+			Status:          jobStatusTypeDone,
+			DurationMs:      callDurMs,
+			Timestamp:       time.Now().UnixMilli(),
+		}
+		if res := t.published.Load(); res != nil {
+			payload.Languages = res.Languages
+			payload.FileIDs = res.FileIDs
+			payload.ObjectKeys = res.ObjectKeys
+		}
+		if jobErr != nil {
+			payload.Status = public.JobStatusTypeFailed
+			payload.Error = jobErr.Error()
+		}
+
+		if err := t.postCompletionWebhook(payload); err != nil {
+			slog.Error("failed to notify completion webhook", slog.String("err", err.Error()))
+			return
+		}
+
+		slog.Debug("completion webhook notified", slog.String("status", string(payload.Status)))
+	})
+}
+
+func (t *Transcriber) postCompletionWebhook(payload completionPayload) error {
+	data, err := json.Marshal(&payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	signature := signWebhookPayload(t.cfg.Publish.CompletionWebhookSecret, data)
+
+	httpClient := &http.Client{}
+	err = retry(context.Background(), "postCompletionWebhook", defaultRetryPolicy, func(ctx context.Context) error {
+		ctx, cancelFn := context.WithTimeout(ctx, webhookRequestTimeout)
+		defer cancelFn()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.Publish.CompletionWebhookURL, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhookSignatureHeader, signature)
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("request failed: %w", err)
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		return nil
+	})
+
+	return err
+}
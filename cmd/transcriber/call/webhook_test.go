@@ -0,0 +1,100 @@
+package call
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+
+	"github.com/mattermost/mattermost-plugin-calls/server/public"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyCompletion(t *testing.T) {
+	var payloads []completionPayload
+	var failures int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, signWebhookPayload("secret", data), r.Header.Get(webhookSignatureHeader))
+
+		var p completionPayload
+		require.NoError(t, json.Unmarshal(data, &p))
+		payloads = append(payloads, p)
+	}))
+	defer ts.Close()
+
+	defer func(attempts int) {
+		maxAPIRetryAttempts = attempts
+	}(maxAPIRetryAttempts)
+	maxAPIRetryAttempts = 2
+
+	t.Run("not configured", func(t *testing.T) {
+		tr := setupTranscriberForTest(t)
+		tr.notifyCompletion(1000, nil)
+		require.Empty(t, payloads)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		defer func() {
+			payloads = nil
+		}()
+
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Publish.CompletionWebhookURL = ts.URL
+		tr.cfg.Publish.CompletionWebhookSecret = "secret"
+		tr.published.Store(&publishResult{
+			FileIDs:   []string{"fileA", "fileB"},
+			Languages: []transcribe.LanguageShare{{Language: "en", Share: 1}},
+		})
+
+		tr.notifyCompletion(60000, nil)
+		require.Len(t, payloads, 1)
+		require.NotZero(t, payloads[0].Timestamp)
+		payloads[0].Timestamp = 0
+		require.Equal(t, completionPayload{
+			CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+			PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+			TranscriptionID: "67t5u6cmtfbb7jug739d43xa9e",
+			Status:          jobStatusTypeDone,
+			DurationMs:      60000,
+			Languages:       []transcribe.LanguageShare{{Language: "en", Share: 1}},
+			FileIDs:         []string{"fileA", "fileB"},
+		}, payloads[0])
+
+		// Only sent once.
+		tr.notifyCompletion(60000, fmt.Errorf("some error"))
+		require.Len(t, payloads, 1)
+	})
+
+	t.Run("failure after retry", func(t *testing.T) {
+		defer func() {
+			payloads = nil
+		}()
+
+		tr := setupTranscriberForTest(t)
+		tr.cfg.Publish.CompletionWebhookURL = ts.URL
+		tr.cfg.Publish.CompletionWebhookSecret = "secret"
+
+		failures = 1
+		tr.notifyCompletion(0, fmt.Errorf("failed to transcribe track"))
+		require.Len(t, payloads, 1)
+		require.Equal(t, public.JobStatusType(public.JobStatusTypeFailed), payloads[0].Status)
+		require.Equal(t, "failed to transcribe track", payloads[0].Error)
+		require.Empty(t, payloads[0].FileIDs)
+	})
+}
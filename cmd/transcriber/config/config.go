@@ -3,15 +3,13 @@ package config
 import (
 	"encoding/json"
 	"fmt"
-	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"regexp"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
-
-	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
 )
 
 var (
@@ -29,14 +27,212 @@ const (
 	LiveCaptionsNumTranscribersDefault          = 1
 	LiveCaptionsNumThreadsPerTranscriberDefault = 2
 	LiveCaptionsLanguageDefault                 = "en"
+	LiveCaptionsWindowsBudgetMBDefault          = 32
+	LiveCaptionsDedupMaxDistanceDefault         = 0.1
+	VADEngineDefault                            = VADEngineSilero
+	VADThresholdDefault                         = 0.5
+	VADMinSilenceDurationMsDefault              = 2000
+	VADSpeechPadMsDefault                       = 100
+	LiveCaptionsVADThresholdDefault             = 0.5
+	LiveCaptionsVADMinSilenceDurationMsDefault  = 150
+	LiveCaptionsVADSpeechPadMsDefault           = 60
+	DuplicatePacketsDefault                     = DuplicatePacketsDrop
+	BotTracksDefault                            = BotTracksLabel
+	SummaryBackendDefault                       = SummaryBackendPlugin
+	ReorderBufferMsDefault                      = 100
+	S3RegionDefault                             = "us-east-1"
+)
+
+// DuplicatePackets defines what to do with retransmitted/duplicated RTP packets.
+type DuplicatePackets string
+
+// BotTracks defines what to do with the audio of bot participants.
+type BotTracks string
+
+// SummaryBackend defines what generates the meeting summary.
+type SummaryBackend string
+
+const (
+	// SummaryBackendPlugin has the AI plugin generate the summary.
+	SummaryBackendPlugin SummaryBackend = "plugin"
+	// SummaryBackendLocal generates the summary in the job, through a local
+	// LLM, for deployments without access to the AI plugin.
+	SummaryBackendLocal SummaryBackend = "local"
+)
+
+// ReorderBufferMsMax caps the reordering delay as it affects the latency of
+// live captions.
+const ReorderBufferMsMax = 1000
+
+// PreStartBufferMsMax caps the audio buffered per track before the recording
+// starts as it's held in memory.
+const PreStartBufferMsMax = 60000
+
+// WhisperRollingPromptMaxTokens caps the rolling prompt since whisper only
+// conditions on up to half of its text context.
+const WhisperRollingPromptMaxTokens = 224
+
+// AzurePhraseListMaxPhrases caps the phrases passed to Azure, which accepts
+// up to 500 of them per recognizer.
+const AzurePhraseListMaxPhrases = 500
+
+// LiveCaptionsMaxLanguages caps the languages live captions are generated in
+// as each of them is transcribed separately.
+const LiveCaptionsMaxLanguages = 4
+
+// FilenameTemplatePlaceholders are the values that can be used in
+// FilenameTemplate: the file name provided by the plugin, the ID of the
+// call's channel, the date the call started (YYYY-MM-DD), the language of
+// the transcription, the label of its transcription API (when comparing),
+// the ID of the job and the format of the file (e.g. vtt).
+var FilenameTemplatePlaceholders = []string{"{filename}", "{channel}", "{date}", "{lang}", "{label}", "{job}", "{format}"}
+
+var (
+	filenameTemplatePlaceholderRE = regexp.MustCompile(`\{[^}]*\}`)
+	filenameTemplateInvalidRE     = regexp.MustCompile(`[\\:*?"<>|\s/{}]`)
+)
+
+func validateFilenameTemplate(tmpl string) error {
+	var hasPlaceholder bool
+	for _, p := range filenameTemplatePlaceholderRE.FindAllString(tmpl, -1) {
+		if !slices.Contains(FilenameTemplatePlaceholders, p) {
+			return fmt.Errorf("unknown placeholder %s", p)
+		}
+		if p != "{format}" {
+			hasPlaceholder = true
+		}
+	}
+
+	if filenameTemplateInvalidRE.MatchString(filenameTemplatePlaceholderRE.ReplaceAllString(tmpl, "")) {
+		return fmt.Errorf("invalid characters")
+	}
+
+	// Files can't all be named the same.
+	if !hasPlaceholder {
+		return fmt.Errorf("should contain at least one placeholder other than {format}")
+	}
+
+	return nil
+}
+
+// CorrectionsMax caps the corrections applied at once since they're passed
+// through the environment.
+const CorrectionsMax = 1000
+
+const (
+	// DuplicatePacketsDrop drops packets whose sequence number was recently seen.
+	DuplicatePacketsDrop DuplicatePackets = "drop"
+	// DuplicatePacketsKeep writes every received packet.
+	DuplicatePacketsKeep DuplicatePackets = "keep"
+)
+
+const (
+	// BotTracksLabel transcribes bots, tagging them as such.
+	BotTracksLabel BotTracks = "label"
+	// BotTracksKeep transcribes bots like any other participant.
+	BotTracksKeep BotTracks = "keep"
+	// BotTracksExclude neither records nor captions bots.
+	BotTracksExclude BotTracks = "exclude"
+)
+
+// IPFamily restricts the IP version used to connect to the Mattermost
+// installation. An empty value means either can be used.
+type IPFamily string
+
+const (
+	IPFamilyIPv4 IPFamily = "ipv4"
+	IPFamilyIPv6 IPFamily = "ipv6"
+)
+
+// VADEngine is the implementation used to detect speech.
+type VADEngine string
+
+const (
+	// VADEngineSilero runs the Silero neural network model (through
+	// onnxruntime).
+	VADEngineSilero VADEngine = "silero"
+	// VADEngineWebRTC runs a port of the WebRTC GMM based detector. It's less
+	// accurate in noisy environments but much cheaper to run and doesn't
+	// need any model file.
+	VADEngineWebRTC VADEngine = "webrtc"
+)
+
+// ResultCache is where the transcriptions of speech chunks are cached, so
+// that re-runs of a job skip the chunks already transcribed. An empty value
+// disables caching.
+type ResultCache string
+
+const (
+	// ResultCacheDisk keeps results in the data directory.
+	ResultCacheDisk ResultCache = "disk"
+	// ResultCacheS3 keeps results in the S3 bucket transcriptions are
+	// published to.
+	ResultCacheS3 ResultCache = "s3"
 )
 
 type OutputFormat string
 
 const (
-	OutputFormatVTT OutputFormat = "vtt"
+	OutputFormatVTT  OutputFormat = "vtt"
+	OutputFormatTXT  OutputFormat = "txt"
+	OutputFormatSRT  OutputFormat = "srt"
+	OutputFormatTTML OutputFormat = "ttml"
+	OutputFormatCSV  OutputFormat = "csv"
+	OutputFormatJSON OutputFormat = "json"
 )
 
+// OutputFormats are the supported formats, in the order their files are
+// published. WebVTT comes first as the plugin renders captions out of the
+// first file.
+var OutputFormats = []OutputFormat{OutputFormatVTT, OutputFormatTXT, OutputFormatSRT, OutputFormatTTML, OutputFormatCSV, OutputFormatJSON}
+
+// Formats returns the formats listed in f, a comma separated list (e.g.
+// vtt,srt), in the order of OutputFormats. A lone vtt, the only value
+// supported before lists were and the one sent by the plugin, still yields the
+// text file too since the plugin expects at least two files.
+func (f OutputFormat) Formats() []OutputFormat {
+	listed := make(map[OutputFormat]bool)
+	for _, val := range strings.Split(string(f), ",") {
+		listed[OutputFormat(strings.TrimSpace(val))] = true
+	}
+	if len(listed) == 1 && listed[OutputFormatVTT] {
+		listed[OutputFormatTXT] = true
+	}
+
+	var formats []OutputFormat
+	for _, format := range OutputFormats {
+		if listed[format] {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+func validateOutputFormat(f OutputFormat) error {
+	if strings.TrimSpace(string(f)) == "" {
+		return fmt.Errorf("should not be empty")
+	}
+
+	seen := make(map[OutputFormat]bool)
+	for _, val := range strings.Split(string(f), ",") {
+		format := OutputFormat(strings.TrimSpace(val))
+		if !slices.Contains(OutputFormats, format) {
+			return fmt.Errorf("unknown format %q", format)
+		}
+		if seen[format] {
+			return fmt.Errorf("duplicate format %q", format)
+		}
+		seen[format] = true
+	}
+
+	// Captions are rendered out of the WebVTT file.
+	if !seen[OutputFormatVTT] {
+		return fmt.Errorf("should include %s", OutputFormatVTT)
+	}
+
+	return nil
+}
+
 type ModelSize string
 
 const (
@@ -53,13 +249,10 @@ const (
 	TranscribeAPIWhisperCPP    = "whisper.cpp"
 	TranscribeAPIOpenAIWhisper = "openai/whisper"
 	TranscribeAPIAzure         = "azure"
+	TranscribeAPIExternal      = "external"
+	TranscribeAPIFasterWhisper = "faster-whisper"
 )
 
-type OutputOptions struct {
-	WebVTT transcribe.WebVTTOptions
-	Text   transcribe.TextOptions
-}
-
 type CallTranscriberConfig struct {
 	// input config
 	SiteURL         string
@@ -67,21 +260,115 @@ type CallTranscriberConfig struct {
 	PostID          string
 	AuthToken       string
 	TranscriptionID string
-	NumThreads      int
-
-	// output config
-	TranscribeAPI        TranscribeAPI
-	TranscribeAPIOptions map[string]any
-	ModelSize            ModelSize
-	OutputFormat         OutputFormat
-	OutputOptions        OutputOptions
-
-	// live captions config
-	LiveCaptionsOn                       bool
-	LiveCaptionsModelSize                ModelSize
-	LiveCaptionsNumTranscribers          int
-	LiveCaptionsNumThreadsPerTranscriber int
-	LiveCaptionsLanguage                 string
+
+	Capture      CaptureConfig
+	Engine       EngineConfig
+	LiveCaptions LiveCaptionsConfig
+	Output       OutputConfig
+	Publish      PublishConfig
+	Network      NetworkConfig
+}
+
+// TranscribeRange identifies a portion of a captured track. Timestamps are in
+// milliseconds relative to the start of the call, as in the transcription.
+type TranscribeRange struct {
+	TrackID string
+	StartMs int64
+	EndMs   int64
+}
+
+// ParseTranscribeRange parses a range given as <trackID>:<startMs>-<endMs>.
+func ParseTranscribeRange(s string) (TranscribeRange, error) {
+	var r TranscribeRange
+
+	idx := strings.LastIndex(s, ":")
+	if idx <= 0 {
+		return r, fmt.Errorf("missing track ID")
+	}
+	r.TrackID = s[:idx]
+
+	start, end, ok := strings.Cut(s[idx+1:], "-")
+	if !ok {
+		return r, fmt.Errorf("missing range end")
+	}
+
+	var err error
+	if r.StartMs, err = strconv.ParseInt(start, 10, 64); err != nil {
+		return r, fmt.Errorf("failed to parse range start: %w", err)
+	}
+	if r.EndMs, err = strconv.ParseInt(end, 10, 64); err != nil {
+		return r, fmt.Errorf("failed to parse range end: %w", err)
+	}
+
+	if r.StartMs < 0 || r.EndMs <= r.StartMs {
+		return r, fmt.Errorf("invalid range %d-%d", r.StartMs, r.EndMs)
+	}
+
+	return r, nil
+}
+
+// Correction replaces the text of a published segment, identified by its
+// speaker and start timestamp (in milliseconds relative to the start of the
+// call, as in the transcription). An empty text removes the segment.
+type Correction struct {
+	Speaker string `json:"speaker"`
+	StartMs int64  `json:"start_ms"`
+	Text    string `json:"text"`
+}
+
+// ParseCorrections parses corrections given as a JSON array.
+func ParseCorrections(s string) ([]Correction, error) {
+	var corrections []Correction
+	if err := json.Unmarshal([]byte(s), &corrections); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal corrections: %w", err)
+	}
+
+	if len(corrections) == 0 {
+		return nil, fmt.Errorf("no corrections given")
+	}
+
+	if len(corrections) > CorrectionsMax {
+		return nil, fmt.Errorf("too many corrections: %d", len(corrections))
+	}
+
+	for i, c := range corrections {
+		if c.Speaker == "" {
+			return nil, fmt.Errorf("correction %d: missing speaker", i)
+		}
+		if c.StartMs < 0 {
+			return nil, fmt.Errorf("correction %d: invalid start %d", i, c.StartMs)
+		}
+	}
+
+	return corrections, nil
+}
+
+// StringListOption returns the items of a transcribe API option holding a
+// list of strings (decoded from JSON as []any), such as AZURE_PHRASE_LIST.
+// The boolean is false if the value isn't a list of non-empty strings.
+func StringListOption(val any) ([]string, bool) {
+	var items []any
+	switch v := val.(type) {
+	case []string:
+		for _, item := range v {
+			items = append(items, item)
+		}
+	case []any:
+		items = v
+	default:
+		return nil, false
+	}
+
+	phrases := make([]string, 0, len(items))
+	for _, item := range items {
+		phrase, ok := item.(string)
+		if !ok || strings.TrimSpace(phrase) == "" {
+			return nil, false
+		}
+		phrases = append(phrases, phrase)
+	}
+
+	return phrases, true
 }
 
 func (p ModelSize) IsValid() bool {
@@ -95,7 +382,61 @@ func (p ModelSize) IsValid() bool {
 
 func (a TranscribeAPI) IsValid() bool {
 	switch a {
-	case TranscribeAPIWhisperCPP, TranscribeAPIOpenAIWhisper, TranscribeAPIAzure:
+	case TranscribeAPIWhisperCPP, TranscribeAPIOpenAIWhisper, TranscribeAPIAzure, TranscribeAPIExternal, TranscribeAPIFasterWhisper:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d DuplicatePackets) IsValid() bool {
+	switch d {
+	case DuplicatePacketsDrop, DuplicatePacketsKeep:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b BotTracks) IsValid() bool {
+	switch b {
+	case BotTracksLabel, BotTracksKeep, BotTracksExclude:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b SummaryBackend) IsValid() bool {
+	switch b {
+	case SummaryBackendPlugin, SummaryBackendLocal:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c ResultCache) IsValid() bool {
+	switch c {
+	case "", ResultCacheDisk, ResultCacheS3:
+		return true
+	default:
+		return false
+	}
+}
+
+func (e VADEngine) IsValid() bool {
+	switch e {
+	case VADEngineSilero, VADEngineWebRTC:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f IPFamily) IsValid() bool {
+	switch f {
+	case IPFamilyIPv4, IPFamilyIPv6:
 		return true
 	default:
 		return false
@@ -107,13 +448,19 @@ func (cfg CallTranscriberConfig) IsValidURL() error {
 		return fmt.Errorf("SiteURL cannot be empty")
 	}
 
+	// Mattermost can be served under a path prefix (e.g. behind a proxy) in
+	// which case all API, plugin and WebSocket endpoints are relative to it.
 	u, err := url.Parse(cfg.SiteURL)
 	if err != nil {
 		return fmt.Errorf("SiteURL parsing failed: %w", err)
 	} else if u.Scheme != "http" && u.Scheme != "https" {
 		return fmt.Errorf("SiteURL parsing failed: invalid scheme %q", u.Scheme)
-	} else if u.Path != "" {
+	} else if u.Host == "" {
+		return fmt.Errorf("SiteURL parsing failed: invalid empty host")
+	} else if strings.HasSuffix(u.Path, "/") {
 		return fmt.Errorf("SiteURL parsing failed: invalid path %q", u.Path)
+	} else if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("SiteURL parsing failed: unexpected query or fragment")
 	}
 
 	return nil
@@ -148,88 +495,39 @@ func (cfg CallTranscriberConfig) IsValid() error {
 		return fmt.Errorf("PostID parsing failed")
 	}
 
-	if !cfg.TranscribeAPI.IsValid() {
-		return fmt.Errorf("TranscribeAPI value is not valid")
-	}
-	if !cfg.ModelSize.IsValid() {
-		return fmt.Errorf("ModelSize value is not valid")
-	}
-	if cfg.OutputFormat != OutputFormatVTT {
-		return fmt.Errorf("OutputFormat value is not valid")
-	}
-
-	if inTranscriber == "true" {
-		numCPU := runtime.NumCPU()
-		if cfg.NumThreads < 1 || cfg.NumThreads > numCPU {
-			return fmt.Errorf("NumThreads should be in the range [1, %d]", numCPU)
-		}
-
-		if cfg.LiveCaptionsOn {
-			if cfg.LiveCaptionsNumTranscribers < 1 || cfg.LiveCaptionsNumThreadsPerTranscriber < 1 ||
-				cfg.LiveCaptionsNumTranscribers*cfg.LiveCaptionsNumThreadsPerTranscriber > numCPU {
-				return fmt.Errorf("LiveCaptionsNumTranscribers * LiveCaptionsNumThreadsPerTranscriber should be in the range [1, %d]", numCPU)
-			}
-		}
-	}
-
-	if cfg.LiveCaptionsOn {
-		if !cfg.LiveCaptionsModelSize.IsValid() {
-			return fmt.Errorf("LiveCaptionsModelSize value is not valid")
-		}
-
-		if cfg.LiveCaptionsLanguage == "" {
-			return fmt.Errorf("LiveCaptionsLanguage cannot be empty")
-		}
-	}
-
-	if err := cfg.OutputOptions.Text.IsValid(); err != nil {
+	if err := cfg.Capture.IsValid(); err != nil {
 		return err
 	}
 
-	return cfg.OutputOptions.WebVTT.IsValid()
-}
-
-func (cfg *CallTranscriberConfig) SetDefaults() {
-	if cfg.TranscribeAPI == "" {
-		cfg.TranscribeAPI = TranscribeAPIDefault
+	if err := cfg.Engine.IsValid(); err != nil {
+		return err
 	}
 
-	if cfg.ModelSize == "" {
-		cfg.ModelSize = ModelSizeDefault
+	if cfg.Engine.ResultCache == ResultCacheS3 && !cfg.Publish.S3.IsEnabled() {
+		return fmt.Errorf("ResultCache %q requires S3 to be configured", ResultCacheS3)
 	}
 
-	if cfg.OutputFormat == "" {
-		cfg.OutputFormat = OutputFormatVTT
+	if err := cfg.LiveCaptions.IsValid(); err != nil {
+		return err
 	}
 
-	if cfg.NumThreads == 0 {
-		if cfg.LiveCaptionsOn {
-			cfg.NumThreads = min(NumThreadsDefault, runtime.NumCPU()/2)
-		} else {
-			cfg.NumThreads = max(1, runtime.NumCPU()/2)
-		}
+	if err := cfg.Output.IsValid(); err != nil {
+		return err
 	}
 
-	if cfg.OutputOptions.WebVTT.IsEmpty() {
-		cfg.OutputOptions.WebVTT.SetDefaults()
+	if err := cfg.Publish.IsValid(); err != nil {
+		return err
 	}
 
-	if cfg.OutputOptions.Text.IsEmpty() {
-		cfg.OutputOptions.Text.SetDefaults()
-	}
+	return cfg.Network.IsValid()
+}
 
-	if cfg.LiveCaptionsModelSize == "" {
-		cfg.LiveCaptionsModelSize = LiveCaptionsModelSizeDefault
-	}
-	if cfg.LiveCaptionsNumTranscribers == 0 {
-		cfg.LiveCaptionsNumTranscribers = LiveCaptionsNumTranscribersDefault
-	}
-	if cfg.LiveCaptionsNumThreadsPerTranscriber == 0 {
-		cfg.LiveCaptionsNumThreadsPerTranscriber = LiveCaptionsNumThreadsPerTranscriberDefault
-	}
-	if cfg.LiveCaptionsLanguage == "" {
-		cfg.LiveCaptionsLanguage = LiveCaptionsLanguageDefault
-	}
+func (cfg *CallTranscriberConfig) SetDefaults() {
+	cfg.Capture.SetDefaults()
+	cfg.Engine.SetDefaults(cfg.LiveCaptions.On)
+	cfg.LiveCaptions.SetDefaults()
+	cfg.Output.SetDefaults()
+	cfg.Publish.SetDefaults()
 }
 
 func (cfg CallTranscriberConfig) ToEnv() []string {
@@ -239,133 +537,94 @@ func (cfg CallTranscriberConfig) ToEnv() []string {
 		fmt.Sprintf("POST_ID=%s", cfg.PostID),
 		fmt.Sprintf("AUTH_TOKEN=%s", cfg.AuthToken),
 		fmt.Sprintf("TRANSCRIPTION_ID=%s", cfg.TranscriptionID),
-		fmt.Sprintf("TRANSCRIBE_API=%s", cfg.TranscribeAPI),
-		fmt.Sprintf("MODEL_SIZE=%s", cfg.ModelSize),
-		fmt.Sprintf("OUTPUT_FORMAT=%s", cfg.OutputFormat),
-		fmt.Sprintf("NUM_THREADS=%d", cfg.NumThreads),
-		fmt.Sprintf("LIVE_CAPTIONS_ON=%t", cfg.LiveCaptionsOn),
-		fmt.Sprintf("LIVE_CAPTIONS_MODEL_SIZE=%s", cfg.LiveCaptionsModelSize),
-		fmt.Sprintf("LIVE_CAPTIONS_NUM_TRANSCRIBERS=%d", cfg.LiveCaptionsNumTranscribers),
-		fmt.Sprintf("LIVE_CAPTIONS_NUM_THREADS_PER_TRANSCRIBER=%d", cfg.LiveCaptionsNumThreadsPerTranscriber),
-		fmt.Sprintf("LIVE_CAPTIONS_LANGUAGE=%s", cfg.LiveCaptionsLanguage),
-	}
-
-	if cfg.TranscribeAPIOptions != nil {
-		data, err := json.Marshal(cfg.TranscribeAPIOptions)
-		if err != nil {
-			vars = append(vars, fmt.Sprintf("TRANSCRIBE_API_OPTIONS='%s'", string(data)))
-		} else {
-			slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
-		}
 	}
 
-	vars = append(vars, cfg.OutputOptions.WebVTT.ToEnv()...)
-	vars = append(vars, cfg.OutputOptions.Text.ToEnv()...)
+	vars = append(vars, cfg.Capture.ToEnv()...)
+	vars = append(vars, cfg.Engine.ToEnv()...)
+	vars = append(vars, cfg.LiveCaptions.ToEnv()...)
+	vars = append(vars, cfg.Output.ToEnv()...)
+	vars = append(vars, cfg.Publish.ToEnv()...)
+	vars = append(vars, cfg.Network.ToEnv()...)
 
 	return vars
 }
 
+// ToMap returns the config as a flat map, keeping the same keys regardless of
+// which section a setting belongs to, as expected by the plugin.
 func (cfg CallTranscriberConfig) ToMap() map[string]any {
-	apiOptsJSON, err := json.Marshal(cfg.TranscribeAPIOptions)
-	if err != nil {
-		slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
-	}
-
 	m := map[string]any{
-		"site_url":                       cfg.SiteURL,
-		"call_id":                        cfg.CallID,
-		"post_id":                        cfg.PostID,
-		"auth_token":                     cfg.AuthToken,
-		"transcription_id":               cfg.TranscriptionID,
-		"transcribe_api":                 cfg.TranscribeAPI,
-		"transcribe_api_options":         string(apiOptsJSON),
-		"model_size":                     cfg.ModelSize,
-		"output_format":                  cfg.OutputFormat,
-		"num_threads":                    cfg.NumThreads,
-		"live_captions_on":               cfg.LiveCaptionsOn,
-		"live_captions_model_size":       cfg.LiveCaptionsModelSize,
-		"live_captions_num_transcribers": cfg.LiveCaptionsNumTranscribers,
-		"live_captions_language":         cfg.LiveCaptionsLanguage,
-		"live_captions_num_threads_per_transcriber": cfg.LiveCaptionsNumThreadsPerTranscriber,
-	}
-
-	for k, v := range cfg.OutputOptions.WebVTT.ToMap() {
-		m[k] = v
-	}
-	for k, v := range cfg.OutputOptions.Text.ToMap() {
-		m[k] = v
+		"site_url":         cfg.SiteURL,
+		"call_id":          cfg.CallID,
+		"post_id":          cfg.PostID,
+		"auth_token":       cfg.AuthToken,
+		"transcription_id": cfg.TranscriptionID,
+	}
+
+	for _, sm := range []map[string]any{
+		cfg.Capture.ToMap(),
+		cfg.Engine.ToMap(),
+		cfg.LiveCaptions.ToMap(),
+		cfg.Output.ToMap(),
+		cfg.Publish.ToMap(),
+		cfg.Network.ToMap(),
+	} {
+		for k, v := range sm {
+			m[k] = v
+		}
 	}
 
 	return m
 }
 
 func (cfg *CallTranscriberConfig) FromMap(m map[string]any) *CallTranscriberConfig {
-	cfg.SiteURL, _ = m["site_url"].(string)
+	if siteURL, ok := m["site_url"].(string); ok {
+		cfg.SiteURL = strings.TrimSuffix(siteURL, "/")
+	}
 	cfg.CallID, _ = m["call_id"].(string)
 	cfg.PostID, _ = m["post_id"].(string)
 	cfg.AuthToken, _ = m["auth_token"].(string)
 	cfg.TranscriptionID, _ = m["transcription_id"].(string)
 
-	// num_threads can either be int or float64 depending whether it's been
-	// previously marshaled or not.
-	switch m["num_threads"].(type) {
-	case int:
-		cfg.NumThreads = m["num_threads"].(int)
-	case float64:
-		cfg.NumThreads = int(m["num_threads"].(float64))
-	}
+	cfg.Capture.FromMap(m)
+	cfg.Engine.FromMap(m)
+	cfg.LiveCaptions.FromMap(m)
+	cfg.Output.FromMap(m)
+	cfg.Publish.FromMap(m)
+	cfg.Network.FromMap(m)
 
-	// likewise for live_captions_num_transcribers and live_captions_num_threads_per_transcriber
-	switch m["live_captions_num_transcribers"].(type) {
-	case int:
-		cfg.LiveCaptionsNumTranscribers = m["live_captions_num_transcribers"].(int)
-	case float64:
-		cfg.LiveCaptionsNumTranscribers = int(m["live_captions_num_transcribers"].(float64))
-	}
-	switch m["live_captions_num_threads_per_transcriber"].(type) {
-	case int:
-		cfg.LiveCaptionsNumThreadsPerTranscriber = m["live_captions_num_threads_per_transcriber"].(int)
-	case float64:
-		cfg.LiveCaptionsNumThreadsPerTranscriber = int(m["live_captions_num_threads_per_transcriber"].(float64))
-	}
+	return cfg
+}
 
-	cfg.LiveCaptionsOn, _ = m["live_captions_on"].(bool)
-	if liveCaptionsModelSize, ok := m["live_captions_model_size"].(string); ok {
-		cfg.LiveCaptionsModelSize = ModelSize(liveCaptionsModelSize)
-	} else {
-		cfg.LiveCaptionsModelSize, _ = m["live_captions_model_size"].(ModelSize)
-	}
-	if language, ok := m["live_captions_language"].(string); ok {
-		cfg.LiveCaptionsLanguage = language
+// loadTranscribeAPIDefaults reads the deployment wide TranscribeAPIOptions
+// from a JSON file (e.g. mounted from a secret), so that options common to
+// every job don't need to be passed along with each of them.
+func loadTranscribeAPIDefaults(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TranscribeAPIDefaultsFile: %w", err)
 	}
 
-	if api, ok := m["transcribe_api"].(string); ok {
-		cfg.TranscribeAPI = TranscribeAPI(api)
-	} else {
-		cfg.TranscribeAPI, _ = m["transcribe_api"].(TranscribeAPI)
+	var defaults map[string]any
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal TranscribeAPIDefaultsFile: %w", err)
 	}
 
-	if opts, ok := m["transcribe_api_options"].(string); ok {
-		if err := json.Unmarshal([]byte(opts), &cfg.TranscribeAPIOptions); err != nil {
-			slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
-		}
-	}
+	return defaults, nil
+}
 
-	if modelSize, ok := m["model_size"].(string); ok {
-		cfg.ModelSize = ModelSize(modelSize)
-	} else {
-		cfg.ModelSize, _ = m["model_size"].(ModelSize)
-	}
-	if outputFormat, ok := m["output_format"].(string); ok {
-		cfg.OutputFormat = OutputFormat(outputFormat)
-	} else {
-		cfg.OutputFormat, _ = m["output_format"].(OutputFormat)
+// mergeTranscribeAPIOptions returns the default options overridden by those
+// set for the job. Options are merged by key, a job option replacing the
+// default one as a whole.
+func mergeTranscribeAPIOptions(defaults, options map[string]any) map[string]any {
+	if len(defaults) == 0 {
+		return options
 	}
 
-	cfg.OutputOptions.WebVTT.FromMap(m)
-	cfg.OutputOptions.Text.FromMap(m)
+	merged := make(map[string]any, len(defaults)+len(options))
+	maps.Copy(merged, defaults)
+	maps.Copy(merged, options)
 
-	return cfg
+	return merged
 }
 
 func FromEnv() (CallTranscriberConfig, error) {
@@ -375,36 +634,24 @@ func FromEnv() (CallTranscriberConfig, error) {
 	cfg.PostID = os.Getenv("POST_ID")
 	cfg.AuthToken = os.Getenv("AUTH_TOKEN")
 	cfg.TranscriptionID = os.Getenv("TRANSCRIPTION_ID")
-	cfg.NumThreads, _ = strconv.Atoi(os.Getenv("NUM_THREADS"))
-	cfg.LiveCaptionsOn, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_ON"))
-	cfg.LiveCaptionsNumTranscribers, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_NUM_TRANSCRIBERS"))
-	cfg.LiveCaptionsNumThreadsPerTranscriber, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_NUM_THREADS_PER_TRANSCRIBER"))
-	cfg.LiveCaptionsLanguage = os.Getenv("LIVE_CAPTIONS_LANGUAGE")
-
-	if val := os.Getenv("TRANSCRIBE_API"); val != "" {
-		cfg.TranscribeAPI = TranscribeAPI(val)
-	}
 
-	if val := os.Getenv("MODEL_SIZE"); val != "" {
-		cfg.ModelSize = ModelSize(val)
+	if err := cfg.Capture.FromEnv(); err != nil {
+		return cfg, err
 	}
-
-	if val := os.Getenv("LIVE_CAPTIONS_MODEL_SIZE"); val != "" {
-		cfg.LiveCaptionsModelSize = ModelSize(val)
-	}
-
-	if val := os.Getenv("OUTPUT_FORMAT"); val != "" {
-		cfg.OutputFormat = OutputFormat(val)
+	if err := cfg.Engine.FromEnv(); err != nil {
+		return cfg, err
 	}
-
-	if val := os.Getenv("TRANSCRIBE_API_OPTIONS"); val != "" {
-		if err := json.Unmarshal([]byte(val), &cfg.TranscribeAPIOptions); err != nil {
-			return cfg, fmt.Errorf("failed to unmarshal TranscribeAPIOptions: %w", err)
+	if path := os.Getenv("TRANSCRIBE_API_DEFAULTS_FILE"); path != "" {
+		defaults, err := loadTranscribeAPIDefaults(path)
+		if err != nil {
+			return cfg, err
 		}
+		cfg.Engine.TranscribeAPIOptions = mergeTranscribeAPIOptions(defaults, cfg.Engine.TranscribeAPIOptions)
 	}
-
-	cfg.OutputOptions.WebVTT.FromEnv()
-	cfg.OutputOptions.Text.FromEnv()
+	cfg.LiveCaptions.FromEnv()
+	cfg.Output.FromEnv()
+	cfg.Publish.FromEnv()
+	cfg.Network.FromEnv()
 
 	return cfg, nil
 }
@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
 
@@ -31,6 +34,27 @@ func TestConfigIsValid(t *testing.T) {
 			},
 			expectedError: "SiteURL parsing failed: invalid scheme \"invalid\"",
 		},
+		{
+			name: "invalid SiteURL path",
+			cfg: CallTranscriberConfig{
+				SiteURL: "http://localhost:8065/mattermost/",
+			},
+			expectedError: "SiteURL parsing failed: invalid path \"/mattermost/\"",
+		},
+		{
+			name: "invalid SiteURL query",
+			cfg: CallTranscriberConfig{
+				SiteURL: "http://localhost:8065/mattermost?tenant=a",
+			},
+			expectedError: "SiteURL parsing failed: unexpected query or fragment",
+		},
+		{
+			name: "valid SiteURL with path prefix",
+			cfg: CallTranscriberConfig{
+				SiteURL: "http://localhost:8065/mattermost",
+			},
+			expectedError: "CallID cannot be empty",
+		},
 		{
 			name: "missing CallID",
 			cfg: CallTranscriberConfig{
@@ -84,8 +108,12 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				OutputFormat:    OutputFormatVTT,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
 			},
 			expectedError: "ModelSize value is not valid",
 		},
@@ -97,10 +125,142 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+			},
+			expectedError: "OutputFormat value is not valid: should not be empty",
+		},
+		{
+			name: "invalid TrimSilenceMs",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format:        OutputFormatVTT,
+					TrimSilenceMs: -1,
+				},
+			},
+			expectedError: "TrimSilenceMs should not be negative",
+		},
+		{
+			name: "unknown OutputFormat",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format: "vtt,docx",
+				},
+			},
+			expectedError: `OutputFormat value is not valid: unknown format "docx"`,
+		},
+		{
+			name: "duplicate OutputFormat",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format: "vtt,srt,vtt",
+				},
+			},
+			expectedError: `OutputFormat value is not valid: duplicate format "vtt"`,
+		},
+		{
+			name: "OutputFormat without vtt",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format: "txt,json",
+				},
+			},
+			expectedError: "OutputFormat value is not valid: should include vtt",
+		},
+		{
+			name: "unknown FilenameTemplate placeholder",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format:           OutputFormatVTT,
+					FilenameTemplate: "{channel}-{time}",
+				},
+			},
+			expectedError: "FilenameTemplate value is not valid: unknown placeholder {time}",
+		},
+		{
+			name: "invalid FilenameTemplate characters",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format:           OutputFormatVTT,
+					FilenameTemplate: "transcripts/{channel}",
+				},
+			},
+			expectedError: "FilenameTemplate value is not valid: invalid characters",
+		},
+		{
+			name: "FilenameTemplate without placeholders",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format:           OutputFormatVTT,
+					FilenameTemplate: "transcript-{format}",
+				},
 			},
-			expectedError: "OutputFormat value is not valid",
+			expectedError: "FilenameTemplate value is not valid: should contain at least one placeholder other than {format}",
 		},
 		{
 			name: "invalid NumThreads",
@@ -110,9 +270,13 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
 			},
 			inTranscriber: "true",
 			expectedError: fmt.Sprintf("NumThreads should be in the range [1, %d]", runtime.NumCPU()),
@@ -125,9 +289,13 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
 			},
 			inTranscriber: "false",
 			expectedError: "SilenceThresholdMs should be a positive number",
@@ -140,15 +308,19 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
-				NumThreads:      1,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   0,
-							MaxSegmentDurationMs: 10000,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   0,
+								MaxSegmentDurationMs: 10000,
+							},
 						},
 					},
 				},
@@ -163,145 +335,1334 @@ func TestConfigIsValid(t *testing.T) {
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
-				NumThreads:      1,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 0,
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 0,
+							},
+						},
+					},
+				},
+			},
+			expectedError: "MaxSegmentDurationMs should be a positive number",
+		},
+		{
+			name: "invalid LiveCaptionsNumTranscribers",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			inTranscriber: "true",
+			expectedError: fmt.Sprintf("LiveCaptionsNumTranscribers * LiveCaptionsNumThreadsPerTranscriber should be in the range [1, %d]", runtime.NumCPU()),
+		},
+		{
+			name: "valid LiveCaptionsNumTranscribers if not in a container",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			expectedError: "LiveCaptionsModelSize value is not valid",
+		},
+		{
+			name: "invalid LiveCaptionsNumTranscribers",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			inTranscriber: "false",
+			expectedError: "LiveCaptionsModelSize value is not valid",
+		},
+		{
+			name: "invalid LiveCaptionsLanguage",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          runtime.NumCPU() / 2,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 "",
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			expectedError: "LiveCaptionsLanguage cannot be empty",
+		},
+		{
+			name: "duplicate LiveCaptionsLanguage",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          runtime.NumCPU() / 2,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 "en,es,en",
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			expectedError: `LiveCaptionsLanguage has duplicate language "en"`,
+		},
+		{
+			name: "too many LiveCaptionsLanguage",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          runtime.NumCPU() / 2,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 "en,es,fr,de,it",
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			expectedError: "LiveCaptionsLanguage should list at most 4 languages",
+		},
+		{
+			name: "invalid LiveCaptionsQueueSize",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          1,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 "en",
+					QueueSize:                -1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+			},
+			expectedError: "LiveCaptionsQueueSize should be positive",
+		},
+		{
+			name: "invalid LiveCaptionsDedupMaxDistance",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          1,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 "en",
+					QueueSize:                1,
+					DedupMaxDistance:         1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
 						},
 					},
 				},
 			},
-			expectedError: "MaxSegmentDurationMs should be a positive number",
+			expectedError: "LiveCaptionsDedupMaxDistance should be less than 1",
+		},
+		{
+			name: "LiveCaptionsShadow without LiveCaptionsOn",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					Shadow: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "LiveCaptionsShadow requires LiveCaptionsOn",
+		},
+		{
+			name: "LiveCaptionsAck without LiveCaptionsOn",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					Ack: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "LiveCaptionsAck requires LiveCaptionsOn",
+		},
+		{
+			name: "LiveCaptionsArchive without LiveCaptionsOn",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					Archive: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "LiveCaptionsArchive requires LiveCaptionsOn",
+		},
+		{
+			name: "LiveCaptionsPartial without LiveCaptionsOn",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					Partial: true,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "LiveCaptionsPartial requires LiveCaptionsOn",
+		},
+		{
+			name: "invalid DuplicatePackets",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					DuplicatePackets: "ignore",
+				},
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "DuplicatePackets value is not valid",
+		},
+		{
+			name: "invalid BotTracks",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					BotTracks: "ignore",
+				},
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "BotTracks value is not valid",
+		},
+		{
+			name: "invalid MaxCallDuration",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					MaxCallDuration: -time.Hour,
+				},
+			},
+			expectedError: "MaxCallDuration should not be negative",
+		},
+		{
+			name: "invalid MaxTrackSizeBytes",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					MaxTrackSizeBytes: -1,
+				},
+			},
+			expectedError: "MaxTrackSizeBytes should not be negative",
+		},
+		{
+			name: "invalid ReorderBufferMs",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					ReorderBufferMs: 5000,
+				},
+			},
+			expectedError: "ReorderBufferMs should not be greater than 1000",
+		},
+		{
+			name: "invalid PreStartBufferMs",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					PreStartBufferMs: -1,
+				},
+			},
+			expectedError: "PreStartBufferMs should be in the range [0, 60000]",
+		},
+		{
+			name: "invalid VADThreshold",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					VAD: VADConfig{
+						Threshold: 1.5,
+					},
+				},
+			},
+			expectedError: "VADThreshold should be in the range [0, 1)",
+		},
+		{
+			name: "invalid VADEngine",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					VAD: VADConfig{
+						Engine: "invalid",
+					},
+				},
+			},
+			expectedError: "VADEngine value is not valid",
+		},
+		{
+			name: "invalid ReTranscribeRange",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Capture: CaptureConfig{
+					ReTranscribeRange: "trackA:5000-1000",
+				},
+			},
+			expectedError: "ReTranscribeRange value is not valid: invalid range 5000-1000",
+		},
+		{
+			name: "invalid Corrections",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					Corrections: `[{"speaker":"","start_ms":1000,"text":"hello"}]`,
+				},
+			},
+			expectedError: "Corrections value is not valid: correction 0: missing speaker",
+		},
+		{
+			name: "CorrectionsRegenerate without Corrections",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					CorrectionsRegenerate: true,
+				},
+			},
+			expectedError: "CorrectionsRegenerate requires Corrections to be set",
+		},
+		{
+			name: "invalid ArtifactsURL",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					ArtifactsURL: "ftp://offloader/artifacts",
+				},
+			},
+			expectedError: `ArtifactsURL parsing failed: invalid scheme "ftp"`,
+		},
+		{
+			name: "missing S3Endpoint",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					S3: S3Config{
+						Bucket: "transcripts",
+					},
+				},
+			},
+			expectedError: "S3Endpoint cannot be empty",
+		},
+		{
+			name: "invalid S3Endpoint",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					S3: S3Config{
+						Endpoint: "ftp://minio:9000",
+						Bucket:   "transcripts",
+					},
+				},
+			},
+			expectedError: `S3Endpoint parsing failed: invalid scheme "ftp"`,
+		},
+		{
+			name: "missing S3 credentials",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					S3: S3Config{
+						Endpoint: "http://minio:9000",
+						Bucket:   "transcripts",
+						Region:   "us-east-1",
+					},
+				},
+			},
+			expectedError: "S3AccessKeyID and S3SecretAccessKey cannot be empty",
+		},
+		{
+			name: "invalid IPFamily",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Network: NetworkConfig{
+					IPFamily: "ipv5",
+				},
+			},
+			expectedError: "IPFamily value is not valid",
+		},
+		{
+			name: "invalid DNSServers",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Network: NetworkConfig{
+					DNSServers: []string{"1.1.1.1", "dns.local:53"},
+				},
+			},
+			expectedError: `DNSServers parsing failed: invalid address "dns.local:53"`,
+		},
+		{
+			name: "invalid CompletionWebhookURL",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					CompletionWebhookURL:    "ftp://hooks/transcriptions",
+					CompletionWebhookSecret: "secret",
+				},
+			},
+			expectedError: `CompletionWebhookURL parsing failed: invalid scheme "ftp"`,
+		},
+		{
+			name: "missing CompletionWebhookSecret",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					CompletionWebhookURL: "https://hooks/transcriptions",
+				},
+			},
+			expectedError: "CompletionWebhookSecret cannot be empty",
+		},
+		{
+			name: "missing PostProcessWebhookSecret",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					PostProcessWebhookURL: "https://hooks/postprocess",
+				},
+			},
+			expectedError: "PostProcessWebhookSecret cannot be empty",
+		},
+		{
+			name: "both PostProcessCommand and PostProcessWebhookURL",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					PostProcessCommand:       "/usr/local/bin/redact",
+					PostProcessWebhookURL:    "https://hooks/postprocess",
+					PostProcessWebhookSecret: "secret",
+				},
+			},
+			expectedError: "PostProcessCommand and PostProcessWebhookURL cannot both be set",
+		},
+		{
+			name: "local SummaryBackend without SummaryModel",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
+						},
+					},
+				},
+				Publish: PublishConfig{
+					GenerateSummary: true,
+					SummaryBackend:  SummaryBackendLocal,
+				},
+			},
+			expectedError: "SummaryModel cannot be empty with the local SummaryBackend",
+		},
+		{
+			name: "invalid TranscribeAPICompare",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI:        TranscribeAPIDefault,
+					TranscribeAPICompare: "invalid",
+					ModelSize:            ModelSizeMedium,
+					NumThreads:           1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "TranscribeAPICompare value is not valid",
+		},
+		{
+			name: "same TranscribeAPICompare as TranscribeAPI",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI:        TranscribeAPIDefault,
+					TranscribeAPICompare: TranscribeAPIDefault,
+					ModelSize:            ModelSizeMedium,
+					NumThreads:           1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "TranscribeAPICompare should be different from TranscribeAPI",
+		},
+		{
+			name: "invalid OUTPUT_FILTER_REGEX",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					TranscribeAPIOptions: map[string]any{
+						"OUTPUT_FILTER_REGEX": "(?i)subtitles by(",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "OUTPUT_FILTER_REGEX value is not valid: error parsing regexp: missing closing ): `(?i)subtitles by(`",
+		},
+		{
+			name: "invalid WHISPER_SUPPRESS_NON_SPEECH_TOKENS",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					TranscribeAPIOptions: map[string]any{
+						"WHISPER_SUPPRESS_NON_SPEECH_TOKENS": "yes",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "WHISPER_SUPPRESS_NON_SPEECH_TOKENS value is not valid",
+		},
+		{
+			name: "invalid WHISPER_ROLLING_PROMPT_TOKENS",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					TranscribeAPIOptions: map[string]any{
+						"WHISPER_ROLLING_PROMPT_TOKENS": 500.0,
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "WHISPER_ROLLING_PROMPT_TOKENS should be an integer in the range [0, 224]",
+		},
+		{
+			name: "invalid AZURE_PHRASE_LIST",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_PHRASE_LIST": "Mattermost",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_PHRASE_LIST should be a list of non-empty strings",
+		},
+		{
+			name: "too many AZURE_PHRASE_LIST phrases",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_PHRASE_LIST": func() []any {
+							phrases := make([]any, AzurePhraseListMaxPhrases+1)
+							for i := range phrases {
+								phrases[i] = fmt.Sprintf("phrase%d", i)
+							}
+							return phrases
+						}(),
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_PHRASE_LIST should not have more than 500 phrases",
+		},
+		{
+			name: "invalid AZURE_SPEECH_ENDPOINT",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_SPEECH_ENDPOINT": "speech.example.com",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_SPEECH_ENDPOINT should be an absolute URL",
+		},
+		{
+			name: "AZURE_SPEECH_ENDPOINT and AZURE_SPEECH_HOST",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_SPEECH_ENDPOINT": "wss://speech.example.com/stt/speech/recognition/conversation/cognitiveservices/v1",
+						"AZURE_SPEECH_HOST":     "wss://speech.example.com",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_SPEECH_ENDPOINT and AZURE_SPEECH_HOST should not be both set",
+		},
+		{
+			name: "AZURE_BATCH without container",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_BATCH": true,
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_BATCH_CONTAINER_SAS_URL should be set with AZURE_BATCH",
+		},
+		{
+			name: "invalid AZURE_BATCH_LOCALE",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIAzure,
+					TranscribeAPIOptions: map[string]any{
+						"AZURE_BATCH":                   true,
+						"AZURE_BATCH_CONTAINER_SAS_URL": "https://account.blob.core.windows.net/audio?sig=signature",
+						"AZURE_BATCH_LOCALE":            "",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "AZURE_BATCH_LOCALE value is not valid",
+		},
+		{
+			name: "missing EXTERNAL_API_URL",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI:        TranscribeAPIExternal,
+					TranscribeAPIOptions: map[string]any{},
+					ModelSize:            ModelSizeMedium,
+					NumThreads:           1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "EXTERNAL_API_URL should be set with the external API",
+		},
+		{
+			name: "invalid EXTERNAL_API_URL",
+			cfg: CallTranscriberConfig{
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIExternal,
+					TranscribeAPIOptions: map[string]any{
+						"EXTERNAL_API_URL": "localhost:8080",
+					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+				},
+			},
+			expectedError: "EXTERNAL_API_URL should be an absolute URL",
 		},
 		{
-			name: "invalid LiveCaptionsNumTranscribers",
+			name: "invalid EXTERNAL_API_TOKEN",
 			cfg: CallTranscriberConfig{
 				SiteURL:         "http://localhost:8065",
 				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
-				NumThreads:      1,
-				LiveCaptionsOn:  true,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 10000,
-						},
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIExternal,
+					TranscribeAPIOptions: map[string]any{
+						"EXTERNAL_API_URL":   "http://localhost:8080",
+						"EXTERNAL_API_TOKEN": 45,
 					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
 				},
 			},
-			inTranscriber: "true",
-			expectedError: fmt.Sprintf("LiveCaptionsNumTranscribers * LiveCaptionsNumThreadsPerTranscriber should be in the range [1, %d]", runtime.NumCPU()),
+			expectedError: "EXTERNAL_API_TOKEN value is not valid",
 		},
 		{
-			name: "valid LiveCaptionsNumTranscribers if not in a container",
+			name: "invalid FASTER_WHISPER_COMMAND",
 			cfg: CallTranscriberConfig{
 				SiteURL:         "http://localhost:8065",
 				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
-				NumThreads:      1,
-				LiveCaptionsOn:  true,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 10000,
-						},
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIFasterWhisper,
+					TranscribeAPIOptions: map[string]any{
+						"FASTER_WHISPER_COMMAND": "faster-whisper-server",
 					},
+					ModelSize:  ModelSizeMedium,
+					NumThreads: 1,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
 				},
 			},
-			expectedError: "LiveCaptionsModelSize value is not valid",
+			expectedError: "FASTER_WHISPER_COMMAND should be set to a non-empty list of strings with the faster-whisper API",
 		},
 		{
-			name: "invalid LiveCaptionsNumTranscribers",
+			name: "invalid ResultCache",
 			cfg: CallTranscriberConfig{
 				SiteURL:         "http://localhost:8065",
 				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
 				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:   TranscribeAPIDefault,
-				ModelSize:       ModelSizeMedium,
-				OutputFormat:    OutputFormatVTT,
-				NumThreads:      1,
-				LiveCaptionsOn:  true,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 10000,
-						},
-					},
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIWhisperCPP,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+					ResultCache:   "memory",
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
 				},
 			},
-			inTranscriber: "false",
-			expectedError: "LiveCaptionsModelSize value is not valid",
+			expectedError: "ResultCache value is not valid",
 		},
 		{
-			name: "invalid LiveCaptionsLanguage",
+			name: "S3 ResultCache without S3",
 			cfg: CallTranscriberConfig{
-				SiteURL:                              "http://localhost:8065",
-				CallID:                               "8w8jorhr7j83uqr6y1st894hqe",
-				PostID:                               "udzdsg7dwidbzcidx5khrf8nee",
-				AuthToken:                            "qj75unbsef83ik9p7ueypb6iyw",
-				TranscriptionID:                      "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:                        TranscribeAPIDefault,
-				ModelSize:                            ModelSizeMedium,
-				OutputFormat:                         OutputFormatVTT,
-				NumThreads:                           1,
-				LiveCaptionsOn:                       true,
-				LiveCaptionsNumTranscribers:          runtime.NumCPU() / 2,
-				LiveCaptionsNumThreadsPerTranscriber: 1,
-				LiveCaptionsModelSize:                ModelSizeTiny,
-				LiveCaptionsLanguage:                 "",
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 10000,
-						},
-					},
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIWhisperCPP,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+					ResultCache:   ResultCacheS3,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
 				},
 			},
-			expectedError: "LiveCaptionsLanguage cannot be empty",
+			expectedError: `ResultCache "s3" requires S3 to be configured`,
 		},
 		{
 			name: "valid config",
 			cfg: CallTranscriberConfig{
-				SiteURL:                              "http://localhost:8065",
-				CallID:                               "8w8jorhr7j83uqr6y1st894hqe",
-				PostID:                               "udzdsg7dwidbzcidx5khrf8nee",
-				AuthToken:                            "qj75unbsef83ik9p7ueypb6iyw",
-				TranscriptionID:                      "on5yfih5etn5m8rfdidamc1oxa",
-				TranscribeAPI:                        TranscribeAPIDefault,
-				ModelSize:                            ModelSizeMedium,
-				OutputFormat:                         OutputFormatVTT,
-				NumThreads:                           1,
-				LiveCaptionsOn:                       true,
-				LiveCaptionsNumTranscribers:          runtime.NumCPU() / 2,
-				LiveCaptionsNumThreadsPerTranscriber: 1,
-				LiveCaptionsModelSize:                ModelSizeTiny,
-				LiveCaptionsLanguage:                 LiveCaptionsLanguageDefault,
-				OutputOptions: OutputOptions{
-					Text: transcribe.TextOptions{
-						CompactOptions: transcribe.TextCompactOptions{
-							SilenceThresholdMs:   2000,
-							MaxSegmentDurationMs: 10000,
+				SiteURL:         "http://localhost:8065",
+				CallID:          "8w8jorhr7j83uqr6y1st894hqe",
+				PostID:          "udzdsg7dwidbzcidx5khrf8nee",
+				AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
+				TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
+				Engine: EngineConfig{
+					TranscribeAPI: TranscribeAPIDefault,
+					ModelSize:     ModelSizeMedium,
+					NumThreads:    1,
+				},
+				LiveCaptions: LiveCaptionsConfig{
+					On:                       true,
+					NumTranscribers:          runtime.NumCPU() / 2,
+					NumThreadsPerTranscriber: 1,
+					ModelSize:                ModelSizeTiny,
+					Language:                 LiveCaptionsLanguageDefault,
+					QueueSize:                4,
+				},
+				Output: OutputConfig{
+					Format: OutputFormatVTT,
+					Options: OutputOptions{
+						Text: transcribe.TextOptions{
+							CompactOptions: transcribe.TextCompactOptions{
+								SilenceThresholdMs:   2000,
+								MaxSegmentDurationMs: 10000,
+							},
 						},
 					},
 				},
@@ -327,55 +1688,127 @@ func TestConfigSetDefaults(t *testing.T) {
 		var cfg CallTranscriberConfig
 		cfg.SetDefaults()
 		require.Equal(t, CallTranscriberConfig{
-			TranscribeAPI:                        TranscribeAPIDefault,
-			ModelSize:                            ModelSizeDefault,
-			OutputFormat:                         OutputFormatDefault,
-			NumThreads:                           max(1, runtime.NumCPU()/2),
-			LiveCaptionsNumTranscribers:          LiveCaptionsNumTranscribersDefault,
-			LiveCaptionsNumThreadsPerTranscriber: 2,
-			LiveCaptionsModelSize:                LiveCaptionsModelSizeDefault,
-			LiveCaptionsLanguage:                 LiveCaptionsLanguageDefault,
-			OutputOptions: OutputOptions{
-				WebVTT: transcribe.WebVTTOptions{
-					OmitSpeaker: false,
+			Capture: CaptureConfig{
+				DuplicatePackets: DuplicatePacketsDefault,
+				BotTracks:        BotTracksDefault,
+				ReorderBufferMs:  ReorderBufferMsDefault,
+			},
+			Engine: EngineConfig{
+				TranscribeAPI: TranscribeAPIDefault,
+				ModelSize:     ModelSizeDefault,
+				NumThreads:    max(1, runtime.NumCPU()/2),
+				VAD: VADConfig{
+					Engine:               VADEngineDefault,
+					Threshold:            VADThresholdDefault,
+					MinSilenceDurationMs: VADMinSilenceDurationMsDefault,
+					SpeechPadMs:          VADSpeechPadMsDefault,
 				},
-				Text: transcribe.TextOptions{
-					CompactOptions: transcribe.TextCompactOptions{
-						SilenceThresholdMs:   2000,
-						MaxSegmentDurationMs: 10000,
+			},
+			LiveCaptions: LiveCaptionsConfig{
+				NumTranscribers:          LiveCaptionsNumTranscribersDefault,
+				NumThreadsPerTranscriber: 2,
+				ModelSize:                LiveCaptionsModelSizeDefault,
+				Language:                 LiveCaptionsLanguageDefault,
+				QueueSize:                LiveCaptionsNumTranscribersDefault,
+				WindowsBudgetMB:          LiveCaptionsWindowsBudgetMBDefault,
+				DedupMaxDistance:         LiveCaptionsDedupMaxDistanceDefault,
+				VAD: VADConfig{
+					Engine:               VADEngineDefault,
+					Threshold:            LiveCaptionsVADThresholdDefault,
+					MinSilenceDurationMs: LiveCaptionsVADMinSilenceDurationMsDefault,
+					SpeechPadMs:          LiveCaptionsVADSpeechPadMsDefault,
+				},
+			},
+			Output: OutputConfig{
+				Format: OutputFormatDefault,
+				Options: OutputOptions{
+					WebVTT: transcribe.WebVTTOptions{
+						OmitSpeaker: false,
+					},
+					Text: transcribe.TextOptions{
+						CompactOptions: transcribe.TextCompactOptions{
+							SilenceThresholdMs:   2000,
+							MaxSegmentDurationMs: 10000,
+						},
 					},
 				},
 			},
+			Publish: PublishConfig{
+				SummaryBackend: SummaryBackendDefault,
+			},
 		}, cfg)
 	})
 
 	t.Run("no overrides", func(t *testing.T) {
 		cfg := CallTranscriberConfig{
-			ModelSize: ModelSizeMedium,
+			Engine: EngineConfig{
+				ModelSize: ModelSizeMedium,
+			},
 		}
 		cfg.SetDefaults()
 		require.Equal(t, CallTranscriberConfig{
-			TranscribeAPI:                        TranscribeAPIDefault,
-			ModelSize:                            ModelSizeMedium,
-			OutputFormat:                         OutputFormatDefault,
-			NumThreads:                           max(1, runtime.NumCPU()/2),
-			LiveCaptionsNumTranscribers:          LiveCaptionsNumTranscribersDefault,
-			LiveCaptionsNumThreadsPerTranscriber: 2,
-			LiveCaptionsModelSize:                LiveCaptionsModelSizeDefault,
-			LiveCaptionsLanguage:                 LiveCaptionsLanguageDefault,
-			OutputOptions: OutputOptions{
-				WebVTT: transcribe.WebVTTOptions{
-					OmitSpeaker: false,
+			Capture: CaptureConfig{
+				DuplicatePackets: DuplicatePacketsDefault,
+				BotTracks:        BotTracksDefault,
+				ReorderBufferMs:  ReorderBufferMsDefault,
+			},
+			Engine: EngineConfig{
+				TranscribeAPI: TranscribeAPIDefault,
+				ModelSize:     ModelSizeMedium,
+				NumThreads:    max(1, runtime.NumCPU()/2),
+				VAD: VADConfig{
+					Engine:               VADEngineDefault,
+					Threshold:            VADThresholdDefault,
+					MinSilenceDurationMs: VADMinSilenceDurationMsDefault,
+					SpeechPadMs:          VADSpeechPadMsDefault,
 				},
-				Text: transcribe.TextOptions{
-					CompactOptions: transcribe.TextCompactOptions{
-						SilenceThresholdMs:   2000,
-						MaxSegmentDurationMs: 10000,
+			},
+			LiveCaptions: LiveCaptionsConfig{
+				NumTranscribers:          LiveCaptionsNumTranscribersDefault,
+				NumThreadsPerTranscriber: 2,
+				ModelSize:                LiveCaptionsModelSizeDefault,
+				Language:                 LiveCaptionsLanguageDefault,
+				QueueSize:                LiveCaptionsNumTranscribersDefault,
+				WindowsBudgetMB:          LiveCaptionsWindowsBudgetMBDefault,
+				DedupMaxDistance:         LiveCaptionsDedupMaxDistanceDefault,
+				VAD: VADConfig{
+					Engine:               VADEngineDefault,
+					Threshold:            LiveCaptionsVADThresholdDefault,
+					MinSilenceDurationMs: LiveCaptionsVADMinSilenceDurationMsDefault,
+					SpeechPadMs:          LiveCaptionsVADSpeechPadMsDefault,
+				},
+			},
+			Output: OutputConfig{
+				Format: OutputFormatDefault,
+				Options: OutputOptions{
+					WebVTT: transcribe.WebVTTOptions{
+						OmitSpeaker: false,
+					},
+					Text: transcribe.TextOptions{
+						CompactOptions: transcribe.TextCompactOptions{
+							SilenceThresholdMs:   2000,
+							MaxSegmentDurationMs: 10000,
+						},
 					},
 				},
 			},
+			Publish: PublishConfig{
+				SummaryBackend: SummaryBackendDefault,
+			},
 		}, cfg)
 	})
+
+	t.Run("s3 region", func(t *testing.T) {
+		cfg := CallTranscriberConfig{
+			Publish: PublishConfig{
+				S3: S3Config{
+					Bucket: "transcripts",
+				},
+			},
+		}
+		cfg.SetDefaults()
+		require.Equal(t, S3RegionDefault, cfg.Publish.S3.Region)
+	})
 }
 
 func TestFromEnv(t *testing.T) {
@@ -418,22 +1851,281 @@ func TestFromEnv(t *testing.T) {
 			PostID:          "udzdsg7dwidbzcidx5khrf8nee",
 			AuthToken:       "qj75unbsef83ik9p7ueypb6iyw",
 			TranscriptionID: "on5yfih5etn5m8rfdidamc1oxa",
-			TranscribeAPI:   TranscribeAPIWhisperCPP,
-			ModelSize:       ModelSizeMedium,
-			NumThreads:      1,
-			OutputOptions: OutputOptions{
-				WebVTT: transcribe.WebVTTOptions{
-					OmitSpeaker: true,
-				},
-				Text: transcribe.TextOptions{
-					CompactOptions: transcribe.TextCompactOptions{
-						SilenceThresholdMs:   200,
-						MaxSegmentDurationMs: 1000,
+			Engine: EngineConfig{
+				TranscribeAPI: TranscribeAPIWhisperCPP,
+				ModelSize:     ModelSizeMedium,
+				NumThreads:    1,
+			},
+			Output: OutputConfig{
+				Options: OutputOptions{
+					WebVTT: transcribe.WebVTTOptions{
+						OmitSpeaker: true,
+					},
+					Text: transcribe.TextOptions{
+						CompactOptions: transcribe.TextCompactOptions{
+							SilenceThresholdMs:   200,
+							MaxSegmentDurationMs: 1000,
+						},
 					},
 				},
 			},
 		}, cfg)
 	})
+
+	t.Run("limits", func(t *testing.T) {
+		t.Setenv("MAX_CALL_DURATION", "2h30m")
+		t.Setenv("MAX_TRACK_SIZE_BYTES", "1073741824")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, CaptureConfig{
+			MaxCallDuration:   150 * time.Minute,
+			MaxTrackSizeBytes: 1073741824,
+		}, cfg.Capture)
+	})
+
+	t.Run("re-transcribe from data", func(t *testing.T) {
+		t.Setenv("RE_TRANSCRIBE_FROM_DATA", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Capture.ReTranscribeFromData)
+	})
+
+	t.Run("re-transcribe range", func(t *testing.T) {
+		t.Setenv("RE_TRANSCRIBE_RANGE", "voice_sessionA:1000-5000")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, "voice_sessionA:1000-5000", cfg.Capture.ReTranscribeRange)
+	})
+
+	t.Run("corrections", func(t *testing.T) {
+		t.Setenv("CORRECTIONS", `[{"speaker":"Alice","start_ms":1000,"text":"hello"}]`)
+		t.Setenv("CORRECTIONS_REGENERATE", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, `[{"speaker":"Alice","start_ms":1000,"text":"hello"}]`, cfg.Publish.Corrections)
+		require.True(t, cfg.Publish.CorrectionsRegenerate)
+	})
+
+	t.Run("record rtp", func(t *testing.T) {
+		t.Setenv("RECORD_RTP", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Capture.RecordRTP)
+	})
+
+	t.Run("reorder buffer", func(t *testing.T) {
+		t.Setenv("REORDER_BUFFER_MS", "-1")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, -1, cfg.Capture.ReorderBufferMs)
+		cfg.SetDefaults()
+		require.Equal(t, -1, cfg.Capture.ReorderBufferMs)
+	})
+
+	t.Run("pre-start buffer", func(t *testing.T) {
+		t.Setenv("PRE_START_BUFFER_MS", "5000")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, 5000, cfg.Capture.PreStartBufferMs)
+		require.Contains(t, cfg.ToEnv(), "PRE_START_BUFFER_MS=5000")
+	})
+
+	t.Run("denoise", func(t *testing.T) {
+		t.Setenv("DENOISE", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Engine.Denoise)
+	})
+
+	t.Run("vad", func(t *testing.T) {
+		t.Setenv("LIVE_CAPTIONS_VAD_ENGINE", "webrtc")
+		t.Setenv("VAD_THRESHOLD", "0.7")
+		t.Setenv("VAD_MIN_SILENCE_DURATION_MS", "1000")
+		t.Setenv("LIVE_CAPTIONS_VAD_SPEECH_PAD_MS", "100")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		cfg.SetDefaults()
+		require.Equal(t, VADConfig{
+			Engine:               VADEngineSilero,
+			Threshold:            0.7,
+			MinSilenceDurationMs: 1000,
+			SpeechPadMs:          VADSpeechPadMsDefault,
+		}, cfg.Engine.VAD)
+		require.Equal(t, VADConfig{
+			Engine:               VADEngineWebRTC,
+			Threshold:            LiveCaptionsVADThresholdDefault,
+			MinSilenceDurationMs: LiveCaptionsVADMinSilenceDurationMsDefault,
+			SpeechPadMs:          100,
+		}, cfg.LiveCaptions.VAD)
+	})
+
+	t.Run("filename template", func(t *testing.T) {
+		t.Setenv("OUTPUT_FILENAME_TEMPLATE", "{channel}-{date}-{lang}-{format}")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, "{channel}-{date}-{lang}-{format}", cfg.Output.FilenameTemplate)
+		require.Contains(t, cfg.Output.ToEnv(), "OUTPUT_FILENAME_TEMPLATE={channel}-{date}-{lang}-{format}")
+	})
+
+	t.Run("result cache", func(t *testing.T) {
+		t.Setenv("RESULT_CACHE", "disk")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, ResultCacheDisk, cfg.Engine.ResultCache)
+		require.Contains(t, cfg.Engine.ToEnv(), "RESULT_CACHE=disk")
+	})
+
+	t.Run("disable loudness normalization", func(t *testing.T) {
+		t.Setenv("DISABLE_LOUDNESS_NORMALIZATION", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Engine.DisableLoudnessNormalization)
+	})
+
+	t.Run("speaker embeddings", func(t *testing.T) {
+		t.Setenv("SPEAKER_EMBEDDINGS", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Output.SpeakerEmbeddings)
+	})
+
+	t.Run("vad segments", func(t *testing.T) {
+		t.Setenv("VAD_SEGMENTS", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Output.VADSegments)
+	})
+
+	t.Run("bot tracks", func(t *testing.T) {
+		t.Setenv("BOT_TRACKS", "exclude")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, BotTracksExclude, cfg.Capture.BotTracks)
+	})
+
+	t.Run("local summary backend", func(t *testing.T) {
+		t.Setenv("GENERATE_SUMMARY", "true")
+		t.Setenv("SUMMARY_BACKEND", "local")
+		t.Setenv("SUMMARY_MODEL", "qwen2.5-1.5b-instruct-q4_k_m.gguf")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, SummaryBackendLocal, cfg.Publish.SummaryBackend)
+		require.Equal(t, "qwen2.5-1.5b-instruct-q4_k_m.gguf", cfg.Publish.SummaryModel)
+	})
+
+	t.Run("trim silence", func(t *testing.T) {
+		t.Setenv("TRIM_SILENCE_MS", "5000")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, 5000, cfg.Output.TrimSilenceMs)
+	})
+
+	t.Run("metadata header", func(t *testing.T) {
+		t.Setenv("OUTPUT_METADATA_HEADER", "true")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.True(t, cfg.Output.MetadataHeader)
+	})
+
+	t.Run("live captions windows budget", func(t *testing.T) {
+		t.Setenv("LIVE_CAPTIONS_WINDOWS_BUDGET_MB", "-1")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, -1, cfg.LiveCaptions.WindowsBudgetMB)
+		cfg.SetDefaults()
+		require.Equal(t, -1, cfg.LiveCaptions.WindowsBudgetMB)
+	})
+
+	t.Run("live captions smoothing", func(t *testing.T) {
+		t.Setenv("LIVE_CAPTIONS_MIN_INTERVAL_MS", "3000")
+		t.Setenv("LIVE_CAPTIONS_DEDUP_MAX_DISTANCE", "-1")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, 3000, cfg.LiveCaptions.MinIntervalMs)
+		cfg.SetDefaults()
+		require.Equal(t, -1.0, cfg.LiveCaptions.DedupMaxDistance)
+	})
+
+	t.Run("network", func(t *testing.T) {
+		t.Setenv("IP_FAMILY", "ipv6")
+		t.Setenv("DNS_SERVERS", "10.0.0.2, [fd00::2]:53,")
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, NetworkConfig{
+			IPFamily:   IPFamilyIPv6,
+			DNSServers: []string{"10.0.0.2", "[fd00::2]:53"},
+		}, cfg.Network)
+		require.NoError(t, cfg.Network.IsValid())
+		require.Equal(t, []string{"IP_FAMILY=ipv6", "DNS_SERVERS=10.0.0.2,[fd00::2]:53"}, cfg.Network.ToEnv())
+	})
+
+	t.Run("transcribe API defaults file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "defaults.json")
+		err := os.WriteFile(path, []byte(`{"AZURE_SPEECH_REGION": "eastus", "AZURE_SPEECH_KEY": "default", "WHISPER_ROLLING_PROMPT_TOKENS": 50}`), 0600)
+		require.NoError(t, err)
+		t.Setenv("TRANSCRIBE_API_DEFAULTS_FILE", path)
+
+		cfg, err := FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"AZURE_SPEECH_REGION":           "eastus",
+			"AZURE_SPEECH_KEY":              "default",
+			"WHISPER_ROLLING_PROMPT_TOKENS": float64(50),
+		}, cfg.Engine.TranscribeAPIOptions)
+
+		// Options set for the job take precedence.
+		t.Setenv("TRANSCRIBE_API_OPTIONS", `{"AZURE_SPEECH_KEY": "job", "WHISPER_SUPPRESS_REGEX": "^$"}`)
+		cfg, err = FromEnv()
+		require.NoError(t, err)
+		require.Equal(t, map[string]any{
+			"AZURE_SPEECH_REGION":           "eastus",
+			"AZURE_SPEECH_KEY":              "job",
+			"WHISPER_ROLLING_PROMPT_TOKENS": float64(50),
+			"WHISPER_SUPPRESS_REGEX":        "^$",
+		}, cfg.Engine.TranscribeAPIOptions)
+	})
+
+	t.Run("invalid TRANSCRIBE_API_DEFAULTS_FILE", func(t *testing.T) {
+		t.Setenv("TRANSCRIBE_API_DEFAULTS_FILE", filepath.Join(t.TempDir(), "missing.json"))
+
+		_, err := FromEnv()
+		require.ErrorContains(t, err, "failed to read TranscribeAPIDefaultsFile")
+
+		path := filepath.Join(t.TempDir(), "defaults.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[]`), 0600))
+		t.Setenv("TRANSCRIBE_API_DEFAULTS_FILE", path)
+
+		_, err = FromEnv()
+		require.ErrorContains(t, err, "failed to unmarshal TranscribeAPIDefaultsFile")
+	})
+
+	t.Run("invalid MAX_CALL_DURATION", func(t *testing.T) {
+		t.Setenv("MAX_CALL_DURATION", "10")
+
+		_, err := FromEnv()
+		require.EqualError(t, err, `failed to parse MaxCallDuration: time: missing unit in duration "10"`)
+	})
 }
 
 func TestCallTranscriberConfigToEnv(t *testing.T) {
@@ -443,11 +2135,11 @@ func TestCallTranscriberConfigToEnv(t *testing.T) {
 	cfg.PostID = "udzdsg7dwidbzcidx5khrf8nee"
 	cfg.AuthToken = "qj75unbsef83ik9p7ueypb6iyw"
 	cfg.TranscriptionID = "on5yfih5etn5m8rfdidamc1oxa"
-	cfg.NumThreads = 1
-	cfg.LiveCaptionsOn = true
-	cfg.LiveCaptionsNumTranscribers = 1
-	cfg.LiveCaptionsNumThreadsPerTranscriber = 1
-	cfg.LiveCaptionsLanguage = "nl"
+	cfg.Engine.NumThreads = 1
+	cfg.LiveCaptions.On = true
+	cfg.LiveCaptions.NumTranscribers = 1
+	cfg.LiveCaptions.NumThreadsPerTranscriber = 1
+	cfg.LiveCaptions.Language = "nl"
 	cfg.SetDefaults()
 	require.Equal(t, []string{
 		"SITE_URL=http://localhost:8065",
@@ -455,21 +2147,88 @@ func TestCallTranscriberConfigToEnv(t *testing.T) {
 		"POST_ID=udzdsg7dwidbzcidx5khrf8nee",
 		"AUTH_TOKEN=qj75unbsef83ik9p7ueypb6iyw",
 		"TRANSCRIPTION_ID=on5yfih5etn5m8rfdidamc1oxa",
+		"DUPLICATE_PACKETS=drop",
+		"BOT_TRACKS=label",
+		"RE_TRANSCRIBE_FROM_DATA=false",
+		"RECORD_RTP=false",
+		"REORDER_BUFFER_MS=100",
 		"TRANSCRIBE_API=whisper.cpp",
 		"MODEL_SIZE=base",
-		"OUTPUT_FORMAT=vtt",
 		"NUM_THREADS=1",
+		"DENOISE=false",
+		"DISABLE_LOUDNESS_NORMALIZATION=false",
+		"VAD_ENGINE=silero",
+		"VAD_THRESHOLD=0.5",
+		"VAD_MIN_SILENCE_DURATION_MS=2000",
+		"VAD_SPEECH_PAD_MS=100",
 		"LIVE_CAPTIONS_ON=true",
 		"LIVE_CAPTIONS_MODEL_SIZE=tiny",
 		"LIVE_CAPTIONS_NUM_TRANSCRIBERS=1",
 		"LIVE_CAPTIONS_NUM_THREADS_PER_TRANSCRIBER=1",
 		"LIVE_CAPTIONS_LANGUAGE=nl",
+		"LIVE_CAPTIONS_SHADOW=false",
+		"LIVE_CAPTIONS_ARCHIVE=false",
+		"LIVE_CAPTIONS_ACK=false",
+		"LIVE_CAPTIONS_PARTIAL=false",
+		"LIVE_CAPTIONS_AUTO_SCALE=false",
+		"LIVE_CAPTIONS_QUEUE_SIZE=1",
+		"LIVE_CAPTIONS_WINDOWS_BUDGET_MB=32",
+		"LIVE_CAPTIONS_MIN_INTERVAL_MS=0",
+		"LIVE_CAPTIONS_DEDUP_MAX_DISTANCE=0.1",
+		"LIVE_CAPTIONS_VAD_ENGINE=silero",
+		"LIVE_CAPTIONS_VAD_THRESHOLD=0.5",
+		"LIVE_CAPTIONS_VAD_MIN_SILENCE_DURATION_MS=150",
+		"LIVE_CAPTIONS_VAD_SPEECH_PAD_MS=60",
+		"OUTPUT_FORMAT=vtt",
+		"INCLUDE_SILENT_PARTICIPANTS=false",
+		"EXTRACT_KEYWORDS=false",
+		"SPLIT_BY_LANGUAGE=false",
+		"SPEAKER_EMBEDDINGS=false",
+		"VAD_SEGMENTS=false",
+		"TRIM_SILENCE_MS=0",
+		"OUTPUT_METADATA_HEADER=false",
 		"WEBVTT_OMIT_SPEAKER=false",
+		"WEBVTT_SPEAKER_COLOR_CLASSES=false",
+		"WEBVTT_RTL_MARKERS=false",
+		"WEBVTT_SPEAKER_STYLES=false",
+		"WEBVTT_MAX_LINE_LENGTH=0",
+		"WEBVTT_MAX_LINES_PER_CUE=0",
+		"WEBVTT_MIN_CUE_DURATION_MS=0",
+		"WEBVTT_MIN_CUE_GAP_MS=0",
 		"TEXT_COMPACT_SILENCE_THRESHOLD_MS=2000",
 		"TEXT_COMPACT_MAX_SEGMENT_DURATION_MS=10000",
+		"TEXT_REMOVE_FILLERS=false",
+		"TEXT_RTL_MARKERS=false",
+		"ASS_SUBTITLES=false",
+		"GENERATE_SUMMARY=false",
+		"SUMMARY_BACKEND=plugin",
+		"DRY_RUN=false",
 	}, cfg.ToEnv())
 }
 
+func TestEngineConfigEnv(t *testing.T) {
+	var cfg EngineConfig
+	cfg.NumThreads = 2
+	cfg.TranscribeAPIOptions = map[string]any{
+		"WHISPER_SUPPRESS_NON_SPEECH_TOKENS": true,
+		"AZURE_PHRASE_LIST":                  []any{"Mattermost", "Calls"},
+	}
+	cfg.SetDefaults(false)
+
+	vars := cfg.ToEnv()
+	require.Contains(t, vars, `TRANSCRIBE_API_OPTIONS={"AZURE_PHRASE_LIST":["Mattermost","Calls"],"WHISPER_SUPPRESS_NON_SPEECH_TOKENS":true}`)
+
+	for _, v := range vars {
+		key, val, _ := strings.Cut(v, "=")
+		os.Setenv(key, val)
+		defer os.Unsetenv(key)
+	}
+
+	var parsed EngineConfig
+	require.NoError(t, parsed.FromEnv())
+	require.Equal(t, cfg, parsed)
+}
+
 func TestCallTranscriberConfigMap(t *testing.T) {
 	var cfg CallTranscriberConfig
 	cfg.SiteURL = "http://localhost:8065"
@@ -477,11 +2236,32 @@ func TestCallTranscriberConfigMap(t *testing.T) {
 	cfg.PostID = "udzdsg7dwidbzcidx5khrf8nee"
 	cfg.AuthToken = "qj75unbsef83ik9p7ueypb6iyw"
 	cfg.TranscriptionID = "on5yfih5etn5m8rfdidamc1oxa"
-	cfg.NumThreads = 1
-	cfg.LiveCaptionsOn = true
-	cfg.LiveCaptionsNumTranscribers = 1
-	cfg.LiveCaptionsNumThreadsPerTranscriber = 1
-	cfg.OutputOptions.WebVTT.OmitSpeaker = true
+	cfg.Engine.NumThreads = 1
+	cfg.LiveCaptions.On = true
+	cfg.LiveCaptions.NumTranscribers = 1
+	cfg.LiveCaptions.NumThreadsPerTranscriber = 1
+	cfg.Output.Options.WebVTT.OmitSpeaker = true
+	cfg.Output.Options.WebVTT.MaxLineLength = 42
+	cfg.Output.Options.WebVTT.MaxLinesPerCue = 2
+	cfg.Output.Options.WebVTT.MinCueDurationMs = 800
+	cfg.Output.Options.WebVTT.MinCueGapMs = 80
+	cfg.Capture.MaxCallDuration = 4 * time.Hour
+	cfg.Capture.MaxTrackSizeBytes = 1 << 30
+	cfg.Capture.ReTranscribeFromData = true
+	cfg.Capture.ReTranscribeRange = "trackA:1000-5000"
+	cfg.Capture.RecordRTP = true
+	cfg.Capture.ReorderBufferMs = -1
+	cfg.Engine.VAD.Threshold = 0.65
+	cfg.LiveCaptions.VAD.SpeechPadMs = 30
+	cfg.LiveCaptions.VAD.Engine = VADEngineWebRTC
+	cfg.Network.IPFamily = IPFamilyIPv4
+	cfg.Network.DNSServers = []string{"10.0.0.2", "10.0.0.3"}
+	cfg.Publish.PostProcessCommand = "/usr/local/bin/redact --strict"
+	cfg.Publish.Corrections = `[{"speaker":"Alice","start_ms":1000,"text":"hello"}]`
+	cfg.Publish.CorrectionsRegenerate = true
+	cfg.Engine.ResultCache = ResultCacheDisk
+	cfg.Output.FilenameTemplate = "{channel}-{date}"
+	cfg.Output.MetadataHeader = true
 	cfg.SetDefaults()
 
 	inTranscriber = "true"
@@ -490,6 +2270,37 @@ func TestCallTranscriberConfigMap(t *testing.T) {
 		var c CallTranscriberConfig
 		err := c.FromMap(cfg.ToMap()).IsValid()
 		require.NoError(t, err)
+		require.Equal(t, cfg.Capture, c.Capture)
+		require.Equal(t, cfg.Engine.VAD, c.Engine.VAD)
+		require.Equal(t, cfg.Engine.ResultCache, c.Engine.ResultCache)
+		require.Equal(t, cfg.Output.FilenameTemplate, c.Output.FilenameTemplate)
+		require.Equal(t, cfg.Output.MetadataHeader, c.Output.MetadataHeader)
+		require.Equal(t, cfg.Output.Options.WebVTT, c.Output.Options.WebVTT)
+		require.Equal(t, cfg.LiveCaptions.VAD, c.LiveCaptions.VAD)
+		require.Equal(t, cfg.Network, c.Network)
+		require.Equal(t, cfg.Publish, c.Publish)
+	})
+
+	t.Run("flat keys", func(t *testing.T) {
+		m := cfg.ToMap()
+		require.Equal(t, "http://localhost:8065", m["site_url"])
+		require.Equal(t, DuplicatePacketsDefault, m["duplicate_packets"])
+		require.Equal(t, 1, m["num_threads"])
+		require.Equal(t, false, m["denoise"])
+		require.Equal(t, ResultCacheDisk, m["result_cache"])
+		require.Equal(t, "{channel}-{date}", m["output_filename_template"])
+		require.Equal(t, true, m["output_metadata_header"])
+		require.Equal(t, 0.65, m["vad_threshold"])
+		require.Equal(t, 150, m["live_captions_vad_min_silence_duration_ms"])
+		require.Equal(t, VADEngineWebRTC, m["live_captions_vad_engine"])
+		require.Equal(t, true, m["live_captions_on"])
+		require.Equal(t, OutputFormatDefault, m["output_format"])
+		require.Equal(t, true, m["webvtt_omit_speaker"])
+		require.Equal(t, 42, m["webvtt_max_line_length"])
+		require.Equal(t, false, m["ass_subtitles"])
+		require.Equal(t, false, m["generate_summary"])
+		require.Equal(t, false, m["dry_run"])
+		require.Equal(t, "/usr/local/bin/redact --strict", m["post_process_command"])
 	})
 
 	t.Run("marshaling", func(t *testing.T) {
@@ -502,5 +2313,95 @@ func TestCallTranscriberConfigMap(t *testing.T) {
 		require.NoError(t, err)
 		err = c.FromMap(mm).IsValid()
 		require.NoError(t, err)
+		require.Equal(t, cfg.Capture, c.Capture)
+		require.Equal(t, cfg.Engine.VAD, c.Engine.VAD)
+		require.Equal(t, cfg.Engine.ResultCache, c.Engine.ResultCache)
+		require.Equal(t, cfg.Output.FilenameTemplate, c.Output.FilenameTemplate)
+		require.Equal(t, cfg.Output.MetadataHeader, c.Output.MetadataHeader)
+		require.Equal(t, cfg.Output.Options.WebVTT, c.Output.Options.WebVTT)
+		require.Equal(t, cfg.LiveCaptions.VAD, c.LiveCaptions.VAD)
+		require.Equal(t, cfg.Network, c.Network)
 	})
 }
+
+func TestParseTranscribeRange(t *testing.T) {
+	r, err := ParseTranscribeRange("voice:sessionA:1000-5000")
+	require.NoError(t, err)
+	require.Equal(t, TranscribeRange{TrackID: "voice:sessionA", StartMs: 1000, EndMs: 5000}, r)
+
+	for input, expectedError := range map[string]string{
+		"1000-5000":        "missing track ID",
+		":1000-5000":       "missing track ID",
+		"trackA:1000":      "missing range end",
+		"trackA:a-5000":    `failed to parse range start: strconv.ParseInt: parsing "a": invalid syntax`,
+		"trackA:1000-":     `failed to parse range end: strconv.ParseInt: parsing "": invalid syntax`,
+		"trackA:-1-5":      `failed to parse range start: strconv.ParseInt: parsing "": invalid syntax`,
+		"trackA:1000-1000": "invalid range 1000-1000",
+	} {
+		_, err := ParseTranscribeRange(input)
+		require.EqualError(t, err, expectedError, input)
+	}
+}
+
+func TestStringListOption(t *testing.T) {
+	tcs := []struct {
+		name     string
+		val      any
+		expected []string
+		ok       bool
+	}{
+		{"list", []any{"Mattermost", "Calls"}, []string{"Mattermost", "Calls"}, true},
+		{"strings", []string{"Mattermost"}, []string{"Mattermost"}, true},
+		{"empty list", []any{}, []string{}, true},
+		{"missing", nil, nil, false},
+		{"string", "Mattermost", nil, false},
+		{"not a string", []any{"Mattermost", 45.0}, nil, false},
+		{"empty phrase", []any{"Mattermost", " "}, nil, false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			phrases, ok := StringListOption(tc.val)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, phrases)
+		})
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	tcs := []struct {
+		name     string
+		format   OutputFormat
+		expected []OutputFormat
+	}{
+		{"default", OutputFormatDefault, []OutputFormat{OutputFormatVTT, OutputFormatTXT}},
+		{"subset", "vtt,srt", []OutputFormat{OutputFormatVTT, OutputFormatSRT}},
+		{"all", "json,csv, ttml,srt ,txt,vtt", []OutputFormat{OutputFormatVTT, OutputFormatTXT, OutputFormatSRT, OutputFormatTTML, OutputFormatCSV, OutputFormatJSON}},
+		{"empty", "", nil},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.format.Formats())
+		})
+	}
+}
+
+func TestParseCorrections(t *testing.T) {
+	corrections, err := ParseCorrections(`[{"speaker":"Alice","start_ms":1000,"text":"hello"},{"speaker":"Bob","start_ms":0,"text":""}]`)
+	require.NoError(t, err)
+	require.Equal(t, []Correction{
+		{Speaker: "Alice", StartMs: 1000, Text: "hello"},
+		{Speaker: "Bob", StartMs: 0, Text: ""},
+	}, corrections)
+
+	for input, expectedError := range map[string]string{
+		"":                                "failed to unmarshal corrections: unexpected end of JSON input",
+		"[]":                              "no corrections given",
+		`[{"start_ms":1000}]`:             "correction 0: missing speaker",
+		`[{"speaker":"A","start_ms":-1}]`: "correction 0: invalid start -1",
+	} {
+		_, err := ParseCorrections(input)
+		require.EqualError(t, err, expectedError, input)
+	}
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// secretOptionKeys are the substrings identifying TranscribeAPIOptions keys
+// whose values must not be logged (e.g. AZURE_SPEECH_KEY). Proxy and SAS
+// URLs hold credentials.
+var secretOptionKeys = []string{"KEY", "SECRET", "TOKEN", "PASSWORD", "PROXY", "SAS"}
+
+// LogValue implements slog.LogValuer so that the effective configuration can
+// be logged as a single structured record. Credentials are left out and URLs
+// are reduced to their scheme, host and path.
+func (cfg CallTranscriberConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("site_url", sanitizeURL(cfg.SiteURL)),
+		slog.String("call_id", cfg.CallID),
+		slog.String("post_id", cfg.PostID),
+		slog.String("transcription_id", cfg.TranscriptionID),
+		slog.Any("capture", cfg.Capture),
+		slog.Any("engine", cfg.Engine),
+		slog.Any("live_captions", cfg.LiveCaptions),
+		slog.Any("output", cfg.Output),
+		slog.Any("publish", cfg.Publish),
+		slog.Any("network", cfg.Network),
+	)
+}
+
+func (c CaptureConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("duplicate_packets", string(c.DuplicatePackets)),
+		slog.String("bot_tracks", string(c.BotTracks)),
+		slog.Duration("max_call_duration", c.MaxCallDuration),
+		slog.Int64("max_track_size_bytes", c.MaxTrackSizeBytes),
+		slog.Bool("re_transcribe_from_data", c.ReTranscribeFromData),
+		slog.String("re_transcribe_range", c.ReTranscribeRange),
+		slog.Bool("record_rtp", c.RecordRTP),
+		slog.Int("reorder_buffer_ms", c.ReorderBufferMs),
+		slog.Int("pre_start_buffer_ms", c.PreStartBufferMs),
+	)
+}
+
+func (c EngineConfig) LogValue() slog.Value {
+	opts := make([]string, 0, len(c.TranscribeAPIOptions))
+	for key, val := range c.TranscribeAPIOptions {
+		if isSecretOptionKey(key) {
+			opts = append(opts, key)
+			continue
+		}
+		opts = append(opts, key+"="+slog.AnyValue(val).String())
+	}
+	sort.Strings(opts)
+
+	return slog.GroupValue(
+		slog.String("transcribe_api", string(c.TranscribeAPI)),
+		slog.String("transcribe_api_compare", string(c.TranscribeAPICompare)),
+		slog.Any("transcribe_api_options", opts),
+		slog.String("model_size", string(c.ModelSize)),
+		slog.Int("num_threads", c.NumThreads),
+		slog.Bool("denoise", c.Denoise),
+		slog.Bool("disable_loudness_normalization", c.DisableLoudnessNormalization),
+		slog.String("result_cache", string(c.ResultCache)),
+		slog.Any("vad", c.VAD),
+	)
+}
+
+func (c LiveCaptionsConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("on", c.On),
+		slog.String("model_size", string(c.ModelSize)),
+		slog.Int("num_transcribers", c.NumTranscribers),
+		slog.Int("num_threads_per_transcriber", c.NumThreadsPerTranscriber),
+		slog.Int("queue_size", c.QueueSize),
+		slog.Int("windows_budget_mb", c.WindowsBudgetMB),
+		slog.Int("min_interval_ms", c.MinIntervalMs),
+		slog.Float64("dedup_max_distance", c.DedupMaxDistance),
+		slog.String("language", c.Language),
+		slog.Bool("shadow", c.Shadow),
+		slog.Bool("archive", c.Archive),
+		slog.Bool("ack", c.Ack),
+		slog.Bool("partial", c.Partial),
+		slog.Bool("auto_scale", c.AutoScale),
+		slog.Any("vad", c.VAD),
+	)
+}
+
+func (c VADConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("engine", string(c.Engine)),
+		slog.Float64("threshold", c.Threshold),
+		slog.Int("min_silence_duration_ms", c.MinSilenceDurationMs),
+		slog.Int("speech_pad_ms", c.SpeechPadMs),
+	)
+}
+
+func (c OutputConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("format", string(c.Format)),
+		slog.Bool("include_silent_participants", c.IncludeSilentParticipants),
+		slog.Bool("extract_keywords", c.ExtractKeywords),
+		slog.Bool("split_by_language", c.SplitByLanguage),
+		slog.Bool("speaker_embeddings", c.SpeakerEmbeddings),
+		slog.Bool("vad_segments", c.VADSegments),
+		slog.Int("trim_silence_ms", c.TrimSilenceMs),
+		slog.String("filename_template", c.FilenameTemplate),
+		slog.Bool("metadata_header", c.MetadataHeader),
+		slog.Bool("webvtt_omit_speaker", c.Options.WebVTT.OmitSpeaker),
+		slog.Bool("webvtt_speaker_color_classes", c.Options.WebVTT.SpeakerColorClasses),
+		slog.Bool("webvtt_rtl_markers", c.Options.WebVTT.RTLMarkers),
+		slog.Bool("webvtt_speaker_styles", c.Options.WebVTT.SpeakerStyles),
+		slog.Int("webvtt_max_line_length", c.Options.WebVTT.MaxLineLength),
+		slog.Int("webvtt_max_lines_per_cue", c.Options.WebVTT.MaxLinesPerCue),
+		slog.Int("webvtt_min_cue_duration_ms", c.Options.WebVTT.MinCueDurationMs),
+		slog.Int("webvtt_min_cue_gap_ms", c.Options.WebVTT.MinCueGapMs),
+		slog.Int("text_compact_silence_threshold_ms", c.Options.Text.CompactOptions.SilenceThresholdMs),
+		slog.Int("text_compact_max_segment_duration_ms", c.Options.Text.CompactOptions.MaxSegmentDurationMs),
+		slog.Bool("text_remove_fillers", c.Options.Text.RemoveFillers),
+		slog.Bool("text_rtl_markers", c.Options.Text.RTLMarkers),
+		slog.Bool("ass_subtitles", c.Options.ASS.Enabled),
+	)
+}
+
+func (c PublishConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Bool("generate_summary", c.GenerateSummary),
+		slog.String("summary_backend", string(c.SummaryBackend)),
+		slog.String("summary_model", c.SummaryModel),
+		slog.Bool("dry_run", c.DryRun),
+		slog.String("artifacts_url", sanitizeURL(c.ArtifactsURL)),
+		slog.Bool("s3_enabled", c.S3.IsEnabled()),
+		slog.String("s3_endpoint", sanitizeURL(c.S3.Endpoint)),
+		slog.String("s3_bucket", c.S3.Bucket),
+		slog.String("s3_region", c.S3.Region),
+		slog.String("completion_webhook_url", sanitizeURL(c.CompletionWebhookURL)),
+		slog.Bool("completion_webhook_signed", c.CompletionWebhookSecret != ""),
+		slog.Bool("post_process_command_set", c.PostProcessCommand != ""),
+		slog.String("post_process_webhook_url", sanitizeURL(c.PostProcessWebhookURL)),
+		slog.Bool("corrections_set", c.Corrections != ""),
+		slog.Bool("corrections_regenerate", c.CorrectionsRegenerate),
+	)
+}
+
+func (c NetworkConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("ip_family", string(c.IPFamily)),
+		slog.Any("dns_servers", c.DNSServers),
+	)
+}
+
+func isSecretOptionKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, s := range secretOptionKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeURL strips credentials, query and fragment, which could hold
+// tokens (e.g. pre-signed URLs), from the given URL.
+func sanitizeURL(u string) string {
+	if u == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "[invalid]"
+	}
+
+	parsed.User = nil
+	parsed.RawQuery = ""
+	parsed.ForceQuery = false
+	parsed.Fragment = ""
+
+	return parsed.String()
+}
@@ -0,0 +1,1447 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/transcribe"
+)
+
+// CaptureConfig holds the settings affecting how call audio is received and
+// saved to disk.
+type CaptureConfig struct {
+	// DuplicatePackets controls whether duplicated RTP packets are dropped.
+	DuplicatePackets DuplicatePackets
+	// BotTracks controls whether the audio of bot participants (e.g.
+	// integrations playing media) is transcribed, and how they are named.
+	BotTracks BotTracks
+	// MaxCallDuration, if set, is the maximum amount of call time captured.
+	// Once reached, capturing stops and what was recorded so far gets
+	// published, marked as truncated.
+	MaxCallDuration time.Duration
+	// MaxTrackSizeBytes, if set, is the maximum size of a saved track file.
+	// Once reached, capturing stops as with MaxCallDuration.
+	MaxTrackSizeBytes int64
+	// ReTranscribeFromData skips joining the call altogether, running
+	// post-processing and publishing over the tracks previously captured in
+	// the data directory by the same job (e.g. after fixing a model issue).
+	ReTranscribeFromData bool
+	// ReTranscribeRange, if set, also skips joining the call but only
+	// re-transcribes the given portion of a captured track
+	// (<trackID>:<startMs>-<endMs>), publishing the result as a patch of
+	// the existing transcription.
+	ReTranscribeRange string
+	// RecordRTP saves the raw RTP packets received for each voice track,
+	// along with their arrival times, so that the call can be replayed in
+	// tests to reproduce synchronization issues.
+	RecordRTP bool
+	// ReorderBufferMs is how long packets following a hole in the RTP
+	// sequence are held waiting for late packets to fill it. A negative
+	// value disables reordering, dropping out of order packets.
+	ReorderBufferMs int
+	// PreStartBufferMs, if set, is how much audio received before the
+	// recording started is kept, rather than discarded. Timestamps stay
+	// relative to the recording start, speech running into it being
+	// clipped to start with the recording.
+	PreStartBufferMs int
+}
+
+func (c CaptureConfig) IsValid() error {
+	if c.DuplicatePackets != "" && !c.DuplicatePackets.IsValid() {
+		return fmt.Errorf("DuplicatePackets value is not valid")
+	}
+
+	if c.BotTracks != "" && !c.BotTracks.IsValid() {
+		return fmt.Errorf("BotTracks value is not valid")
+	}
+
+	if c.MaxCallDuration < 0 {
+		return fmt.Errorf("MaxCallDuration should not be negative")
+	}
+
+	if c.MaxTrackSizeBytes < 0 {
+		return fmt.Errorf("MaxTrackSizeBytes should not be negative")
+	}
+
+	if c.ReorderBufferMs > ReorderBufferMsMax {
+		return fmt.Errorf("ReorderBufferMs should not be greater than %d", ReorderBufferMsMax)
+	}
+
+	if c.PreStartBufferMs < 0 || c.PreStartBufferMs > PreStartBufferMsMax {
+		return fmt.Errorf("PreStartBufferMs should be in the range [0, %d]", PreStartBufferMsMax)
+	}
+
+	if c.ReTranscribeRange != "" {
+		if _, err := ParseTranscribeRange(c.ReTranscribeRange); err != nil {
+			return fmt.Errorf("ReTranscribeRange value is not valid: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *CaptureConfig) SetDefaults() {
+	if c.DuplicatePackets == "" {
+		c.DuplicatePackets = DuplicatePacketsDefault
+	}
+
+	if c.BotTracks == "" {
+		c.BotTracks = BotTracksDefault
+	}
+
+	if c.ReorderBufferMs == 0 {
+		c.ReorderBufferMs = ReorderBufferMsDefault
+	}
+}
+
+func (c *CaptureConfig) FromEnv() error {
+	if val := os.Getenv("DUPLICATE_PACKETS"); val != "" {
+		c.DuplicatePackets = DuplicatePackets(val)
+	}
+
+	if val := os.Getenv("BOT_TRACKS"); val != "" {
+		c.BotTracks = BotTracks(val)
+	}
+
+	if val := os.Getenv("MAX_CALL_DURATION"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("failed to parse MaxCallDuration: %w", err)
+		}
+		c.MaxCallDuration = d
+	}
+
+	if val := os.Getenv("MAX_TRACK_SIZE_BYTES"); val != "" {
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse MaxTrackSizeBytes: %w", err)
+		}
+		c.MaxTrackSizeBytes = n
+	}
+
+	c.ReTranscribeFromData, _ = strconv.ParseBool(os.Getenv("RE_TRANSCRIBE_FROM_DATA"))
+	c.ReTranscribeRange = os.Getenv("RE_TRANSCRIBE_RANGE")
+	c.RecordRTP, _ = strconv.ParseBool(os.Getenv("RECORD_RTP"))
+
+	if val := os.Getenv("REORDER_BUFFER_MS"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("failed to parse ReorderBufferMs: %w", err)
+		}
+		c.ReorderBufferMs = n
+	}
+
+	if val := os.Getenv("PRE_START_BUFFER_MS"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return fmt.Errorf("failed to parse PreStartBufferMs: %w", err)
+		}
+		c.PreStartBufferMs = n
+	}
+
+	return nil
+}
+
+func (c CaptureConfig) ToEnv() []string {
+	vars := []string{
+		fmt.Sprintf("DUPLICATE_PACKETS=%s", c.DuplicatePackets),
+		fmt.Sprintf("BOT_TRACKS=%s", c.BotTracks),
+		fmt.Sprintf("RE_TRANSCRIBE_FROM_DATA=%t", c.ReTranscribeFromData),
+		fmt.Sprintf("RECORD_RTP=%t", c.RecordRTP),
+		fmt.Sprintf("REORDER_BUFFER_MS=%d", c.ReorderBufferMs),
+	}
+
+	if c.MaxCallDuration > 0 {
+		vars = append(vars, fmt.Sprintf("MAX_CALL_DURATION=%s", c.MaxCallDuration))
+	}
+
+	if c.MaxTrackSizeBytes > 0 {
+		vars = append(vars, fmt.Sprintf("MAX_TRACK_SIZE_BYTES=%d", c.MaxTrackSizeBytes))
+	}
+
+	if c.ReTranscribeRange != "" {
+		vars = append(vars, fmt.Sprintf("RE_TRANSCRIBE_RANGE=%s", c.ReTranscribeRange))
+	}
+
+	if c.PreStartBufferMs > 0 {
+		vars = append(vars, fmt.Sprintf("PRE_START_BUFFER_MS=%d", c.PreStartBufferMs))
+	}
+
+	return vars
+}
+
+func (c *CaptureConfig) FromMap(m map[string]any) {
+	if duplicatePackets, ok := m["duplicate_packets"].(string); ok {
+		c.DuplicatePackets = DuplicatePackets(duplicatePackets)
+	} else {
+		c.DuplicatePackets, _ = m["duplicate_packets"].(DuplicatePackets)
+	}
+
+	if botTracks, ok := m["bot_tracks"].(string); ok {
+		c.BotTracks = BotTracks(botTracks)
+	} else {
+		c.BotTracks, _ = m["bot_tracks"].(BotTracks)
+	}
+
+	// The duration is passed as a string (e.g. "2h30m") so that it's
+	// unaffected by marshaling.
+	if val, ok := m["max_call_duration"].(string); ok && val != "" {
+		if d, err := time.ParseDuration(val); err != nil {
+			slog.Error("failed to parse MaxCallDuration", slog.String("err", err.Error()))
+		} else {
+			c.MaxCallDuration = d
+		}
+	}
+
+	// max_track_size_bytes can either be int64 or float64 depending whether
+	// it's been previously marshaled or not.
+	switch m["max_track_size_bytes"].(type) {
+	case int64:
+		c.MaxTrackSizeBytes = m["max_track_size_bytes"].(int64)
+	case int:
+		c.MaxTrackSizeBytes = int64(m["max_track_size_bytes"].(int))
+	case float64:
+		c.MaxTrackSizeBytes = int64(m["max_track_size_bytes"].(float64))
+	}
+
+	c.ReTranscribeFromData, _ = m["re_transcribe_from_data"].(bool)
+	c.ReTranscribeRange, _ = m["re_transcribe_range"].(string)
+	c.RecordRTP, _ = m["record_rtp"].(bool)
+
+	switch m["reorder_buffer_ms"].(type) {
+	case int:
+		c.ReorderBufferMs = m["reorder_buffer_ms"].(int)
+	case float64:
+		c.ReorderBufferMs = int(m["reorder_buffer_ms"].(float64))
+	}
+
+	switch m["pre_start_buffer_ms"].(type) {
+	case int:
+		c.PreStartBufferMs = m["pre_start_buffer_ms"].(int)
+	case float64:
+		c.PreStartBufferMs = int(m["pre_start_buffer_ms"].(float64))
+	}
+}
+
+func (c CaptureConfig) ToMap() map[string]any {
+	var maxCallDuration string
+	if c.MaxCallDuration > 0 {
+		maxCallDuration = c.MaxCallDuration.String()
+	}
+
+	return map[string]any{
+		"duplicate_packets":       c.DuplicatePackets,
+		"bot_tracks":              c.BotTracks,
+		"max_call_duration":       maxCallDuration,
+		"max_track_size_bytes":    c.MaxTrackSizeBytes,
+		"re_transcribe_from_data": c.ReTranscribeFromData,
+		"re_transcribe_range":     c.ReTranscribeRange,
+		"record_rtp":              c.RecordRTP,
+		"reorder_buffer_ms":       c.ReorderBufferMs,
+		"pre_start_buffer_ms":     c.PreStartBufferMs,
+	}
+}
+
+// EngineConfig holds the settings of the speech-to-text backend used to
+// transcribe the recorded tracks.
+type EngineConfig struct {
+	TranscribeAPI        TranscribeAPI
+	TranscribeAPIOptions map[string]any
+	// When set, tracks are also transcribed through this API and the resulting
+	// transcription is published alongside the main one, labeled by backend.
+	TranscribeAPICompare TranscribeAPI
+	ModelSize            ModelSize
+	NumThreads           int
+	// Denoise attenuates background noise (e.g. fans, hum) before speech
+	// detection and transcription, both for live captions and the final
+	// transcription.
+	Denoise bool
+	// DisableLoudnessNormalization turns off normalizing the loudness of
+	// each track before transcribing it, which otherwise helps with quiet
+	// speakers.
+	DisableLoudnessNormalization bool
+	// VAD holds the settings of the speech detection run over the tracks
+	// before transcribing them.
+	VAD VADConfig
+	// ResultCache, if set, caches the transcription of each speech chunk,
+	// keyed by its audio and the transcription settings.
+	ResultCache ResultCache
+}
+
+func (c EngineConfig) IsValid() error {
+	if !c.TranscribeAPI.IsValid() {
+		return fmt.Errorf("TranscribeAPI value is not valid")
+	}
+	if c.TranscribeAPICompare != "" {
+		if !c.TranscribeAPICompare.IsValid() {
+			return fmt.Errorf("TranscribeAPICompare value is not valid")
+		}
+		if c.TranscribeAPICompare == c.TranscribeAPI {
+			return fmt.Errorf("TranscribeAPICompare should be different from TranscribeAPI")
+		}
+	}
+	if !c.ModelSize.IsValid() {
+		return fmt.Errorf("ModelSize value is not valid")
+	}
+	if !c.ResultCache.IsValid() {
+		return fmt.Errorf("ResultCache value is not valid")
+	}
+	for _, key := range []string{"WHISPER_SUPPRESS_REGEX", "OUTPUT_FILTER_REGEX"} {
+		if val, ok := c.TranscribeAPIOptions[key]; ok {
+			if re, ok := val.(string); !ok {
+				return fmt.Errorf("%s value is not valid", key)
+			} else if _, err := regexp.Compile(re); err != nil {
+				return fmt.Errorf("%s value is not valid: %w", key, err)
+			}
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["WHISPER_SUPPRESS_NON_SPEECH_TOKENS"]; ok {
+		if _, ok := val.(bool); !ok {
+			return fmt.Errorf("WHISPER_SUPPRESS_NON_SPEECH_TOKENS value is not valid")
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["WHISPER_ROLLING_PROMPT_TOKENS"]; ok {
+		// Options unmarshaled from JSON hold numbers as float64.
+		if tokens, ok := val.(float64); !ok || tokens < 0 || tokens > WhisperRollingPromptMaxTokens || tokens != math.Trunc(tokens) {
+			return fmt.Errorf("WHISPER_ROLLING_PROMPT_TOKENS should be an integer in the range [0, %d]", WhisperRollingPromptMaxTokens)
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["AZURE_PHRASE_LIST"]; ok {
+		phrases, ok := StringListOption(val)
+		if !ok {
+			return fmt.Errorf("AZURE_PHRASE_LIST should be a list of non-empty strings")
+		}
+		if len(phrases) > AzurePhraseListMaxPhrases {
+			return fmt.Errorf("AZURE_PHRASE_LIST should not have more than %d phrases", AzurePhraseListMaxPhrases)
+		}
+	}
+	for _, key := range []string{"AZURE_SPEECH_ENDPOINT", "AZURE_SPEECH_HOST", "AZURE_SPEECH_PROXY", "AZURE_BATCH_API_URL", "AZURE_BATCH_CONTAINER_SAS_URL", "EXTERNAL_API_URL"} {
+		if val, ok := c.TranscribeAPIOptions[key]; ok {
+			if u, ok := val.(string); !ok {
+				return fmt.Errorf("%s value is not valid", key)
+			} else if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+				return fmt.Errorf("%s should be an absolute URL", key)
+			}
+		}
+	}
+	if _, ok := c.TranscribeAPIOptions["AZURE_SPEECH_ENDPOINT"]; ok {
+		if _, ok := c.TranscribeAPIOptions["AZURE_SPEECH_HOST"]; ok {
+			return fmt.Errorf("AZURE_SPEECH_ENDPOINT and AZURE_SPEECH_HOST should not be both set")
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["AZURE_BATCH"]; ok {
+		if batch, ok := val.(bool); !ok {
+			return fmt.Errorf("AZURE_BATCH value is not valid")
+		} else if _, ok := c.TranscribeAPIOptions["AZURE_BATCH_CONTAINER_SAS_URL"]; batch && !ok {
+			return fmt.Errorf("AZURE_BATCH_CONTAINER_SAS_URL should be set with AZURE_BATCH")
+		}
+	}
+	if c.TranscribeAPI == TranscribeAPIExternal || c.TranscribeAPICompare == TranscribeAPIExternal {
+		if _, ok := c.TranscribeAPIOptions["EXTERNAL_API_URL"]; !ok {
+			return fmt.Errorf("EXTERNAL_API_URL should be set with the external API")
+		}
+	}
+	if c.TranscribeAPI == TranscribeAPIFasterWhisper || c.TranscribeAPICompare == TranscribeAPIFasterWhisper {
+		if command, ok := StringListOption(c.TranscribeAPIOptions["FASTER_WHISPER_COMMAND"]); !ok || len(command) == 0 {
+			return fmt.Errorf("FASTER_WHISPER_COMMAND should be set to a non-empty list of strings with the faster-whisper API")
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["EXTERNAL_API_TOKEN"]; ok {
+		if _, ok := val.(string); !ok {
+			return fmt.Errorf("EXTERNAL_API_TOKEN value is not valid")
+		}
+	}
+	if val, ok := c.TranscribeAPIOptions["AZURE_BATCH_LOCALE"]; ok {
+		if locale, ok := val.(string); !ok || locale == "" {
+			return fmt.Errorf("AZURE_BATCH_LOCALE value is not valid")
+		}
+	}
+
+	if inTranscriber == "true" {
+		numCPU := runtime.NumCPU()
+		if c.NumThreads < 1 || c.NumThreads > numCPU {
+			return fmt.Errorf("NumThreads should be in the range [1, %d]", numCPU)
+		}
+	}
+
+	return c.VAD.IsValid("VAD")
+}
+
+// SetDefaults sets the engine defaults. Fewer threads are used by default
+// when live captions are on since these need CPU time of their own.
+func (c *EngineConfig) SetDefaults(liveCaptionsOn bool) {
+	if c.TranscribeAPI == "" {
+		c.TranscribeAPI = TranscribeAPIDefault
+	}
+
+	if c.ModelSize == "" {
+		c.ModelSize = ModelSizeDefault
+	}
+
+	if c.NumThreads == 0 {
+		if liveCaptionsOn {
+			c.NumThreads = min(NumThreadsDefault, runtime.NumCPU()/2)
+		} else {
+			c.NumThreads = max(1, runtime.NumCPU()/2)
+		}
+	}
+
+	// 2 seconds of silence is a good threshold that allows us not to split
+	// speech portions excessively which in turn improves the transcribing
+	// performance as there is less overhead.
+	c.VAD.SetDefaults(VADConfig{
+		Engine:               VADEngineDefault,
+		Threshold:            VADThresholdDefault,
+		MinSilenceDurationMs: VADMinSilenceDurationMsDefault,
+		SpeechPadMs:          VADSpeechPadMsDefault,
+	})
+}
+
+func (c *EngineConfig) FromEnv() error {
+	c.NumThreads, _ = strconv.Atoi(os.Getenv("NUM_THREADS"))
+	c.Denoise, _ = strconv.ParseBool(os.Getenv("DENOISE"))
+	c.DisableLoudnessNormalization, _ = strconv.ParseBool(os.Getenv("DISABLE_LOUDNESS_NORMALIZATION"))
+	c.VAD.FromEnv("VAD_")
+
+	if val := os.Getenv("TRANSCRIBE_API"); val != "" {
+		c.TranscribeAPI = TranscribeAPI(val)
+	}
+
+	if val := os.Getenv("TRANSCRIBE_API_COMPARE"); val != "" {
+		c.TranscribeAPICompare = TranscribeAPI(val)
+	}
+
+	if val := os.Getenv("MODEL_SIZE"); val != "" {
+		c.ModelSize = ModelSize(val)
+	}
+
+	if val := os.Getenv("RESULT_CACHE"); val != "" {
+		c.ResultCache = ResultCache(val)
+	}
+
+	if val := os.Getenv("TRANSCRIBE_API_OPTIONS"); val != "" {
+		if err := json.Unmarshal([]byte(val), &c.TranscribeAPIOptions); err != nil {
+			return fmt.Errorf("failed to unmarshal TranscribeAPIOptions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c EngineConfig) ToEnv() []string {
+	vars := []string{
+		fmt.Sprintf("TRANSCRIBE_API=%s", c.TranscribeAPI),
+		fmt.Sprintf("MODEL_SIZE=%s", c.ModelSize),
+		fmt.Sprintf("NUM_THREADS=%d", c.NumThreads),
+		fmt.Sprintf("DENOISE=%t", c.Denoise),
+		fmt.Sprintf("DISABLE_LOUDNESS_NORMALIZATION=%t", c.DisableLoudnessNormalization),
+	}
+
+	vars = append(vars, c.VAD.ToEnv("VAD_")...)
+
+	if c.TranscribeAPIOptions != nil {
+		data, err := json.Marshal(c.TranscribeAPIOptions)
+		if err == nil {
+			vars = append(vars, fmt.Sprintf("TRANSCRIBE_API_OPTIONS=%s", string(data)))
+		} else {
+			slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
+		}
+	}
+
+	if c.TranscribeAPICompare != "" {
+		vars = append(vars, fmt.Sprintf("TRANSCRIBE_API_COMPARE=%s", c.TranscribeAPICompare))
+	}
+
+	if c.ResultCache != "" {
+		vars = append(vars, fmt.Sprintf("RESULT_CACHE=%s", c.ResultCache))
+	}
+
+	return vars
+}
+
+func (c *EngineConfig) FromMap(m map[string]any) {
+	// num_threads can either be int or float64 depending whether it's been
+	// previously marshaled or not.
+	switch m["num_threads"].(type) {
+	case int:
+		c.NumThreads = m["num_threads"].(int)
+	case float64:
+		c.NumThreads = int(m["num_threads"].(float64))
+	}
+
+	if api, ok := m["transcribe_api"].(string); ok {
+		c.TranscribeAPI = TranscribeAPI(api)
+	} else {
+		c.TranscribeAPI, _ = m["transcribe_api"].(TranscribeAPI)
+	}
+
+	if api, ok := m["transcribe_api_compare"].(string); ok {
+		c.TranscribeAPICompare = TranscribeAPI(api)
+	} else {
+		c.TranscribeAPICompare, _ = m["transcribe_api_compare"].(TranscribeAPI)
+	}
+
+	if opts, ok := m["transcribe_api_options"].(string); ok {
+		if err := json.Unmarshal([]byte(opts), &c.TranscribeAPIOptions); err != nil {
+			slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
+		}
+	}
+
+	if modelSize, ok := m["model_size"].(string); ok {
+		c.ModelSize = ModelSize(modelSize)
+	} else {
+		c.ModelSize, _ = m["model_size"].(ModelSize)
+	}
+
+	if cache, ok := m["result_cache"].(string); ok {
+		c.ResultCache = ResultCache(cache)
+	} else {
+		c.ResultCache, _ = m["result_cache"].(ResultCache)
+	}
+
+	c.Denoise, _ = m["denoise"].(bool)
+	c.DisableLoudnessNormalization, _ = m["disable_loudness_normalization"].(bool)
+	c.VAD.FromMap(m, "vad_")
+}
+
+func (c EngineConfig) ToMap() map[string]any {
+	apiOptsJSON, err := json.Marshal(c.TranscribeAPIOptions)
+	if err != nil {
+		slog.Error("failed to marshal TranscribeAPIOptions", slog.String("err", err.Error()))
+	}
+
+	m := map[string]any{
+		"transcribe_api":                 c.TranscribeAPI,
+		"transcribe_api_options":         string(apiOptsJSON),
+		"transcribe_api_compare":         c.TranscribeAPICompare,
+		"model_size":                     c.ModelSize,
+		"num_threads":                    c.NumThreads,
+		"denoise":                        c.Denoise,
+		"disable_loudness_normalization": c.DisableLoudnessNormalization,
+		"result_cache":                   c.ResultCache,
+	}
+
+	for k, v := range c.VAD.ToMap("vad_") {
+		m[k] = v
+	}
+
+	return m
+}
+
+// VADConfig holds the settings of a speech detector. The same settings are
+// used for the final transcription and live captions, with different
+// prefixes and defaults as the latter need to react faster.
+type VADConfig struct {
+	// Engine is the speech detector implementation.
+	Engine VADEngine
+	// Threshold is the speech probability above which audio is considered
+	// speech. Noisy environments may need it raised. The WebRTC engine, which
+	// doesn't output probabilities, maps it to one of its four aggressiveness
+	// modes (e.g. 0.5 to mode 2).
+	Threshold float64
+	// MinSilenceDurationMs is how long the silence separating two speech
+	// segments should be.
+	MinSilenceDurationMs int
+	// SpeechPadMs is the padding added around speech segments so that they
+	// aren't cut too aggressively.
+	SpeechPadMs int
+}
+
+func (c VADConfig) IsValid(name string) error {
+	// Zero values are replaced by the defaults.
+	if c.Engine != "" && !c.Engine.IsValid() {
+		return fmt.Errorf("%sEngine value is not valid", name)
+	}
+
+	if c.Threshold < 0 || c.Threshold >= 1 {
+		return fmt.Errorf("%sThreshold should be in the range [0, 1)", name)
+	}
+
+	if c.MinSilenceDurationMs < 0 {
+		return fmt.Errorf("%sMinSilenceDurationMs should not be negative", name)
+	}
+
+	if c.SpeechPadMs < 0 {
+		return fmt.Errorf("%sSpeechPadMs should not be negative", name)
+	}
+
+	return nil
+}
+
+func (c *VADConfig) SetDefaults(defaults VADConfig) {
+	if c.Engine == "" {
+		c.Engine = defaults.Engine
+	}
+	if c.Threshold == 0 {
+		c.Threshold = defaults.Threshold
+	}
+	if c.MinSilenceDurationMs == 0 {
+		c.MinSilenceDurationMs = defaults.MinSilenceDurationMs
+	}
+	if c.SpeechPadMs == 0 {
+		c.SpeechPadMs = defaults.SpeechPadMs
+	}
+}
+
+func (c *VADConfig) FromEnv(prefix string) {
+	c.Engine = VADEngine(os.Getenv(prefix + "ENGINE"))
+	c.Threshold, _ = strconv.ParseFloat(os.Getenv(prefix+"THRESHOLD"), 64)
+	c.MinSilenceDurationMs, _ = strconv.Atoi(os.Getenv(prefix + "MIN_SILENCE_DURATION_MS"))
+	c.SpeechPadMs, _ = strconv.Atoi(os.Getenv(prefix + "SPEECH_PAD_MS"))
+}
+
+func (c VADConfig) ToEnv(prefix string) []string {
+	return []string{
+		fmt.Sprintf("%sENGINE=%s", prefix, c.Engine),
+		fmt.Sprintf("%sTHRESHOLD=%g", prefix, c.Threshold),
+		fmt.Sprintf("%sMIN_SILENCE_DURATION_MS=%d", prefix, c.MinSilenceDurationMs),
+		fmt.Sprintf("%sSPEECH_PAD_MS=%d", prefix, c.SpeechPadMs),
+	}
+}
+
+func (c *VADConfig) FromMap(m map[string]any, prefix string) {
+	if engine, ok := m[prefix+"engine"].(string); ok {
+		c.Engine = VADEngine(engine)
+	} else {
+		c.Engine, _ = m[prefix+"engine"].(VADEngine)
+	}
+	c.Threshold, _ = m[prefix+"threshold"].(float64)
+
+	// These can either be int or float64 depending whether they have been
+	// previously marshaled or not.
+	switch m[prefix+"min_silence_duration_ms"].(type) {
+	case int:
+		c.MinSilenceDurationMs = m[prefix+"min_silence_duration_ms"].(int)
+	case float64:
+		c.MinSilenceDurationMs = int(m[prefix+"min_silence_duration_ms"].(float64))
+	}
+	switch m[prefix+"speech_pad_ms"].(type) {
+	case int:
+		c.SpeechPadMs = m[prefix+"speech_pad_ms"].(int)
+	case float64:
+		c.SpeechPadMs = int(m[prefix+"speech_pad_ms"].(float64))
+	}
+}
+
+func (c VADConfig) ToMap(prefix string) map[string]any {
+	return map[string]any{
+		prefix + "engine":                  c.Engine,
+		prefix + "threshold":               c.Threshold,
+		prefix + "min_silence_duration_ms": c.MinSilenceDurationMs,
+		prefix + "speech_pad_ms":           c.SpeechPadMs,
+	}
+}
+
+// LiveCaptionsConfig holds the settings of the captions generated while the
+// call is ongoing.
+type LiveCaptionsConfig struct {
+	On                       bool
+	ModelSize                ModelSize
+	NumTranscribers          int
+	NumThreadsPerTranscriber int
+	// Language is the language captions are generated in. It can be a comma
+	// separated list to caption the call in several languages at once, each
+	// requiring its own whisper context per transcriber.
+	Language string
+	// Shadow runs live captions without broadcasting them to clients.
+	// Captions are persisted in the data directory instead.
+	Shadow bool
+	// Archive keeps every caption sent to clients in a file in the data
+	// directory, pushed as an artifact at the end of the call, so that live
+	// captions can be compared to the final transcription.
+	Archive bool
+	// Ack sends captions through the plugin's API rather than the WebSocket
+	// connection so that the server acknowledges every caption.
+	Ack bool
+	// Partial sends provisional captions, marked as such, while speech is
+	// ongoing, followed by a final one once it stops, so that captions show up
+	// sooner.
+	Partial bool
+	// AutoScale adjusts the live captions transcribers to the load during the
+	// call: transcribers are added, up to the available CPUs, and then the
+	// model downsized when captions fall behind, and restored once they keep
+	// up again.
+	AutoScale bool
+	// QueueSize is the number of caption requests that can wait for a free
+	// transcriber. It defaults to NumTranscribers so that bursts of speakers
+	// don't get dropped while every worker is busy.
+	QueueSize int
+	// WindowsBudgetMB caps the memory taken by the audio windows of all
+	// tracks combined. Once over budget, the largest windows are trimmed. A
+	// negative value removes the cap.
+	WindowsBudgetMB int
+	// MinIntervalMs is the minimum time between captions sent for the same
+	// session. Captions transcribed sooner are held, and replaced by newer
+	// ones, until then. Zero disables rate limiting.
+	MinIntervalMs int
+	// DedupMaxDistance is the edit distance, relative to the text length,
+	// under which a caption is considered a repeat of the previous one and
+	// suppressed. A negative value disables deduplication.
+	DedupMaxDistance float64
+	// VAD holds the settings of the speech detection run over the audio
+	// windows before transcribing them.
+	VAD VADConfig
+}
+
+func (c LiveCaptionsConfig) IsValid() error {
+	if !c.On {
+		if c.Shadow {
+			return fmt.Errorf("LiveCaptionsShadow requires LiveCaptionsOn")
+		}
+		if c.Ack {
+			return fmt.Errorf("LiveCaptionsAck requires LiveCaptionsOn")
+		}
+		if c.Archive {
+			return fmt.Errorf("LiveCaptionsArchive requires LiveCaptionsOn")
+		}
+		if c.Partial {
+			return fmt.Errorf("LiveCaptionsPartial requires LiveCaptionsOn")
+		}
+		return nil
+	}
+
+	if inTranscriber == "true" {
+		numCPU := runtime.NumCPU()
+		if c.NumTranscribers < 1 || c.NumThreadsPerTranscriber < 1 ||
+			c.NumTranscribers*c.NumThreadsPerTranscriber > numCPU {
+			return fmt.Errorf("LiveCaptionsNumTranscribers * LiveCaptionsNumThreadsPerTranscriber should be in the range [1, %d]", numCPU)
+		}
+	}
+
+	if !c.ModelSize.IsValid() {
+		return fmt.Errorf("LiveCaptionsModelSize value is not valid")
+	}
+
+	languages := c.Languages()
+	if len(languages) == 0 {
+		return fmt.Errorf("LiveCaptionsLanguage cannot be empty")
+	}
+	if len(languages) > LiveCaptionsMaxLanguages {
+		return fmt.Errorf("LiveCaptionsLanguage should list at most %d languages", LiveCaptionsMaxLanguages)
+	}
+	for i, lang := range languages {
+		if slices.Contains(languages[:i], lang) {
+			return fmt.Errorf("LiveCaptionsLanguage has duplicate language %q", lang)
+		}
+	}
+
+	if c.QueueSize < 1 {
+		return fmt.Errorf("LiveCaptionsQueueSize should be positive")
+	}
+
+	if c.MinIntervalMs < 0 {
+		return fmt.Errorf("LiveCaptionsMinIntervalMs should not be negative")
+	}
+
+	if c.DedupMaxDistance >= 1 {
+		return fmt.Errorf("LiveCaptionsDedupMaxDistance should be less than 1")
+	}
+
+	return c.VAD.IsValid("LiveCaptionsVAD")
+}
+
+// Languages returns the languages captions are generated in.
+func (c LiveCaptionsConfig) Languages() []string {
+	return splitList(c.Language)
+}
+
+func (c *LiveCaptionsConfig) SetDefaults() {
+	if c.ModelSize == "" {
+		c.ModelSize = LiveCaptionsModelSizeDefault
+	}
+	if c.NumTranscribers == 0 {
+		c.NumTranscribers = LiveCaptionsNumTranscribersDefault
+	}
+	if c.NumThreadsPerTranscriber == 0 {
+		c.NumThreadsPerTranscriber = LiveCaptionsNumThreadsPerTranscriberDefault
+	}
+	if c.Language == "" {
+		c.Language = LiveCaptionsLanguageDefault
+	}
+	if c.QueueSize == 0 {
+		c.QueueSize = c.NumTranscribers
+	}
+	if c.WindowsBudgetMB == 0 {
+		c.WindowsBudgetMB = LiveCaptionsWindowsBudgetMBDefault
+	}
+	if c.DedupMaxDistance == 0 {
+		c.DedupMaxDistance = LiveCaptionsDedupMaxDistanceDefault
+	}
+	c.VAD.SetDefaults(VADConfig{
+		Engine:               VADEngineDefault,
+		Threshold:            LiveCaptionsVADThresholdDefault,
+		MinSilenceDurationMs: LiveCaptionsVADMinSilenceDurationMsDefault,
+		SpeechPadMs:          LiveCaptionsVADSpeechPadMsDefault,
+	})
+}
+
+func (c *LiveCaptionsConfig) FromEnv() {
+	c.On, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_ON"))
+	c.NumTranscribers, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_NUM_TRANSCRIBERS"))
+	c.NumThreadsPerTranscriber, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_NUM_THREADS_PER_TRANSCRIBER"))
+	c.Language = os.Getenv("LIVE_CAPTIONS_LANGUAGE")
+	c.Shadow, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_SHADOW"))
+	c.Archive, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_ARCHIVE"))
+	c.Ack, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_ACK"))
+	c.Partial, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_PARTIAL"))
+	c.AutoScale, _ = strconv.ParseBool(os.Getenv("LIVE_CAPTIONS_AUTO_SCALE"))
+	c.QueueSize, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_QUEUE_SIZE"))
+	c.WindowsBudgetMB, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_WINDOWS_BUDGET_MB"))
+	c.MinIntervalMs, _ = strconv.Atoi(os.Getenv("LIVE_CAPTIONS_MIN_INTERVAL_MS"))
+	c.DedupMaxDistance, _ = strconv.ParseFloat(os.Getenv("LIVE_CAPTIONS_DEDUP_MAX_DISTANCE"), 64)
+	c.VAD.FromEnv("LIVE_CAPTIONS_VAD_")
+
+	if val := os.Getenv("LIVE_CAPTIONS_MODEL_SIZE"); val != "" {
+		c.ModelSize = ModelSize(val)
+	}
+}
+
+func (c LiveCaptionsConfig) ToEnv() []string {
+	vars := []string{
+		fmt.Sprintf("LIVE_CAPTIONS_ON=%t", c.On),
+		fmt.Sprintf("LIVE_CAPTIONS_MODEL_SIZE=%s", c.ModelSize),
+		fmt.Sprintf("LIVE_CAPTIONS_NUM_TRANSCRIBERS=%d", c.NumTranscribers),
+		fmt.Sprintf("LIVE_CAPTIONS_NUM_THREADS_PER_TRANSCRIBER=%d", c.NumThreadsPerTranscriber),
+		fmt.Sprintf("LIVE_CAPTIONS_LANGUAGE=%s", c.Language),
+		fmt.Sprintf("LIVE_CAPTIONS_SHADOW=%t", c.Shadow),
+		fmt.Sprintf("LIVE_CAPTIONS_ARCHIVE=%t", c.Archive),
+		fmt.Sprintf("LIVE_CAPTIONS_ACK=%t", c.Ack),
+		fmt.Sprintf("LIVE_CAPTIONS_PARTIAL=%t", c.Partial),
+		fmt.Sprintf("LIVE_CAPTIONS_AUTO_SCALE=%t", c.AutoScale),
+		fmt.Sprintf("LIVE_CAPTIONS_QUEUE_SIZE=%d", c.QueueSize),
+		fmt.Sprintf("LIVE_CAPTIONS_WINDOWS_BUDGET_MB=%d", c.WindowsBudgetMB),
+		fmt.Sprintf("LIVE_CAPTIONS_MIN_INTERVAL_MS=%d", c.MinIntervalMs),
+		fmt.Sprintf("LIVE_CAPTIONS_DEDUP_MAX_DISTANCE=%g", c.DedupMaxDistance),
+	}
+
+	return append(vars, c.VAD.ToEnv("LIVE_CAPTIONS_VAD_")...)
+}
+
+func (c *LiveCaptionsConfig) FromMap(m map[string]any) {
+	// These can either be int or float64 depending whether they have been
+	// previously marshaled or not.
+	switch m["live_captions_num_transcribers"].(type) {
+	case int:
+		c.NumTranscribers = m["live_captions_num_transcribers"].(int)
+	case float64:
+		c.NumTranscribers = int(m["live_captions_num_transcribers"].(float64))
+	}
+	switch m["live_captions_num_threads_per_transcriber"].(type) {
+	case int:
+		c.NumThreadsPerTranscriber = m["live_captions_num_threads_per_transcriber"].(int)
+	case float64:
+		c.NumThreadsPerTranscriber = int(m["live_captions_num_threads_per_transcriber"].(float64))
+	}
+	switch m["live_captions_queue_size"].(type) {
+	case int:
+		c.QueueSize = m["live_captions_queue_size"].(int)
+	case float64:
+		c.QueueSize = int(m["live_captions_queue_size"].(float64))
+	}
+	switch m["live_captions_windows_budget_mb"].(type) {
+	case int:
+		c.WindowsBudgetMB = m["live_captions_windows_budget_mb"].(int)
+	case float64:
+		c.WindowsBudgetMB = int(m["live_captions_windows_budget_mb"].(float64))
+	}
+	switch m["live_captions_min_interval_ms"].(type) {
+	case int:
+		c.MinIntervalMs = m["live_captions_min_interval_ms"].(int)
+	case float64:
+		c.MinIntervalMs = int(m["live_captions_min_interval_ms"].(float64))
+	}
+	c.DedupMaxDistance, _ = m["live_captions_dedup_max_distance"].(float64)
+
+	c.On, _ = m["live_captions_on"].(bool)
+	c.Shadow, _ = m["live_captions_shadow"].(bool)
+	c.Archive, _ = m["live_captions_archive"].(bool)
+	c.Ack, _ = m["live_captions_ack"].(bool)
+	c.Partial, _ = m["live_captions_partial"].(bool)
+	c.AutoScale, _ = m["live_captions_auto_scale"].(bool)
+	if modelSize, ok := m["live_captions_model_size"].(string); ok {
+		c.ModelSize = ModelSize(modelSize)
+	} else {
+		c.ModelSize, _ = m["live_captions_model_size"].(ModelSize)
+	}
+	if language, ok := m["live_captions_language"].(string); ok {
+		c.Language = language
+	}
+	c.VAD.FromMap(m, "live_captions_vad_")
+}
+
+func (c LiveCaptionsConfig) ToMap() map[string]any {
+	m := map[string]any{
+		"live_captions_on":                          c.On,
+		"live_captions_model_size":                  c.ModelSize,
+		"live_captions_num_transcribers":            c.NumTranscribers,
+		"live_captions_num_threads_per_transcriber": c.NumThreadsPerTranscriber,
+		"live_captions_language":                    c.Language,
+		"live_captions_shadow":                      c.Shadow,
+		"live_captions_archive":                     c.Archive,
+		"live_captions_ack":                         c.Ack,
+		"live_captions_partial":                     c.Partial,
+		"live_captions_auto_scale":                  c.AutoScale,
+		"live_captions_queue_size":                  c.QueueSize,
+		"live_captions_windows_budget_mb":           c.WindowsBudgetMB,
+		"live_captions_min_interval_ms":             c.MinIntervalMs,
+		"live_captions_dedup_max_distance":          c.DedupMaxDistance,
+	}
+
+	for k, v := range c.VAD.ToMap("live_captions_vad_") {
+		m[k] = v
+	}
+
+	return m
+}
+
+type OutputOptions struct {
+	WebVTT transcribe.WebVTTOptions
+	Text   transcribe.TextOptions
+	ASS    transcribe.ASSOptions
+}
+
+// OutputConfig holds the settings of the generated transcription files.
+type OutputConfig struct {
+	// Format is the comma separated list of formats to publish (e.g.
+	// vtt,txt,srt). See OutputFormats.
+	Format  OutputFormat
+	Options OutputOptions
+	// IncludeSilentParticipants adds a participants JSON artifact listing
+	// every session observed on the call, including those that never spoke.
+	IncludeSilentParticipants bool
+	// ExtractKeywords attaches the most relevant terms of the call, and of each
+	// speaker, to the job info sent to the plugin.
+	ExtractKeywords bool
+	// SplitByLanguage publishes a separate transcription for each language
+	// detected in a multilingual call.
+	SplitByLanguage bool
+	// SpeakerEmbeddings adds an opaque voice-print of each participant to the
+	// participants JSON artifact so that the same speaker can be linked
+	// across calls. Being biometric data, it's only computed when explicitly
+	// enabled.
+	SpeakerEmbeddings bool
+	// VADSegments adds a JSON artifact listing, for every track, the speech
+	// segments found by the speech detector so that it's possible to tell
+	// why some audio wasn't transcribed.
+	VADSegments bool
+	// TrimSilenceMs, if set, shortens any silence across all speakers
+	// longer than this down to this in the transcription files, which then
+	// come with a JSON time map to get back to the recording timestamps.
+	TrimSilenceMs int
+	// FilenameTemplate, if set, names the published files after it rather
+	// than after the call (e.g. {channel}-{date}-{lang}-{format}). See
+	// FilenameTemplatePlaceholders.
+	FilenameTemplate string
+	// MetadataHeader prepends a block describing the call (channel, start
+	// and end time, participants, model and language) to the transcription
+	// files so that they are self-describing once exported.
+	MetadataHeader bool
+}
+
+func (c OutputConfig) IsValid() error {
+	if err := validateOutputFormat(c.Format); err != nil {
+		return fmt.Errorf("OutputFormat value is not valid: %w", err)
+	}
+
+	if c.TrimSilenceMs < 0 {
+		return fmt.Errorf("TrimSilenceMs should not be negative")
+	}
+
+	if c.FilenameTemplate != "" {
+		if err := validateFilenameTemplate(c.FilenameTemplate); err != nil {
+			return fmt.Errorf("FilenameTemplate value is not valid: %w", err)
+		}
+	}
+
+	if err := c.Options.Text.IsValid(); err != nil {
+		return err
+	}
+
+	return c.Options.WebVTT.IsValid()
+}
+
+func (c *OutputConfig) SetDefaults() {
+	if c.Format == "" {
+		c.Format = OutputFormatDefault
+	}
+
+	if c.Options.WebVTT.IsEmpty() {
+		c.Options.WebVTT.SetDefaults()
+	}
+
+	if c.Options.Text.IsEmpty() {
+		c.Options.Text.SetDefaults()
+	}
+}
+
+func (c *OutputConfig) FromEnv() {
+	c.IncludeSilentParticipants, _ = strconv.ParseBool(os.Getenv("INCLUDE_SILENT_PARTICIPANTS"))
+	c.ExtractKeywords, _ = strconv.ParseBool(os.Getenv("EXTRACT_KEYWORDS"))
+	c.SplitByLanguage, _ = strconv.ParseBool(os.Getenv("SPLIT_BY_LANGUAGE"))
+	c.SpeakerEmbeddings, _ = strconv.ParseBool(os.Getenv("SPEAKER_EMBEDDINGS"))
+	c.VADSegments, _ = strconv.ParseBool(os.Getenv("VAD_SEGMENTS"))
+	c.TrimSilenceMs, _ = strconv.Atoi(os.Getenv("TRIM_SILENCE_MS"))
+	c.MetadataHeader, _ = strconv.ParseBool(os.Getenv("OUTPUT_METADATA_HEADER"))
+
+	if val := os.Getenv("OUTPUT_FORMAT"); val != "" {
+		c.Format = OutputFormat(val)
+	}
+
+	c.FilenameTemplate = os.Getenv("OUTPUT_FILENAME_TEMPLATE")
+
+	c.Options.WebVTT.FromEnv()
+	c.Options.Text.FromEnv()
+	c.Options.ASS.FromEnv()
+}
+
+func (c OutputConfig) ToEnv() []string {
+	vars := []string{
+		fmt.Sprintf("OUTPUT_FORMAT=%s", c.Format),
+		fmt.Sprintf("INCLUDE_SILENT_PARTICIPANTS=%t", c.IncludeSilentParticipants),
+		fmt.Sprintf("EXTRACT_KEYWORDS=%t", c.ExtractKeywords),
+		fmt.Sprintf("SPLIT_BY_LANGUAGE=%t", c.SplitByLanguage),
+		fmt.Sprintf("SPEAKER_EMBEDDINGS=%t", c.SpeakerEmbeddings),
+		fmt.Sprintf("VAD_SEGMENTS=%t", c.VADSegments),
+		fmt.Sprintf("TRIM_SILENCE_MS=%d", c.TrimSilenceMs),
+		fmt.Sprintf("OUTPUT_METADATA_HEADER=%t", c.MetadataHeader),
+	}
+
+	if c.FilenameTemplate != "" {
+		vars = append(vars, fmt.Sprintf("OUTPUT_FILENAME_TEMPLATE=%s", c.FilenameTemplate))
+	}
+
+	vars = append(vars, c.Options.WebVTT.ToEnv()...)
+	vars = append(vars, c.Options.Text.ToEnv()...)
+	vars = append(vars, c.Options.ASS.ToEnv()...)
+
+	return vars
+}
+
+func (c *OutputConfig) FromMap(m map[string]any) {
+	c.IncludeSilentParticipants, _ = m["include_silent_participants"].(bool)
+	c.ExtractKeywords, _ = m["extract_keywords"].(bool)
+	c.SplitByLanguage, _ = m["split_by_language"].(bool)
+	c.SpeakerEmbeddings, _ = m["speaker_embeddings"].(bool)
+	c.VADSegments, _ = m["vad_segments"].(bool)
+	switch v := m["trim_silence_ms"].(type) {
+	case int:
+		c.TrimSilenceMs = v
+	case float64:
+		c.TrimSilenceMs = int(v)
+	}
+
+	c.FilenameTemplate, _ = m["output_filename_template"].(string)
+	c.MetadataHeader, _ = m["output_metadata_header"].(bool)
+
+	if outputFormat, ok := m["output_format"].(string); ok {
+		c.Format = OutputFormat(outputFormat)
+	} else {
+		c.Format, _ = m["output_format"].(OutputFormat)
+	}
+
+	c.Options.WebVTT.FromMap(m)
+	c.Options.Text.FromMap(m)
+	c.Options.ASS.FromMap(m)
+}
+
+func (c OutputConfig) ToMap() map[string]any {
+	m := map[string]any{
+		"output_format":               c.Format,
+		"include_silent_participants": c.IncludeSilentParticipants,
+		"extract_keywords":            c.ExtractKeywords,
+		"split_by_language":           c.SplitByLanguage,
+		"speaker_embeddings":          c.SpeakerEmbeddings,
+		"vad_segments":                c.VADSegments,
+		"trim_silence_ms":             c.TrimSilenceMs,
+		"output_filename_template":    c.FilenameTemplate,
+		"output_metadata_header":      c.MetadataHeader,
+	}
+
+	for k, v := range c.Options.WebVTT.ToMap() {
+		m[k] = v
+	}
+	for k, v := range c.Options.Text.ToMap() {
+		m[k] = v
+	}
+	for k, v := range c.Options.ASS.ToMap() {
+		m[k] = v
+	}
+
+	return m
+}
+
+// PublishConfig holds the settings affecting what gets delivered once the
+// transcription is complete.
+type PublishConfig struct {
+	// When set, the published transcription is also sent to the AI plugin to
+	// generate a meeting summary which gets attached to the call post.
+	GenerateSummary bool
+	// SummaryBackend selects what generates the summary. With the local
+	// backend, the summary is published as an additional file along with the
+	// transcription instead.
+	SummaryBackend SummaryBackend
+	// SummaryModel is the file name, in the models directory, of the GGUF
+	// model used by the local summary backend.
+	SummaryModel string
+	// ArtifactsURL is the job artifacts endpoint exposed by the offloader, if
+	// available. Logs, metrics and diagnostics are pushed to it once the job
+	// completes. Credentials, if needed, can be passed as part of the URL.
+	ArtifactsURL string
+	// S3 configures an S3-compatible bucket transcription files are uploaded
+	// to instead of going through the plugin's upload API.
+	S3 S3Config
+	// CompletionWebhookURL, if set, receives a JSON payload describing the
+	// outcome once the job finishes, either successfully or not.
+	CompletionWebhookURL string
+	// CompletionWebhookSecret is the key the webhook payload is signed
+	// with (HMAC-SHA256) so that receivers can verify its origin.
+	CompletionWebhookSecret string
+	// PostProcessCommand, if set, is a command (with space separated
+	// arguments) run before publishing. It receives the JSON transcription on
+	// its standard input and writes the, possibly modified (e.g. redacted),
+	// transcription to its standard output.
+	PostProcessCommand string
+	// PostProcessWebhookURL is an alternative to PostProcessCommand: the JSON
+	// transcription is POSTed to it and the response body is used in its
+	// place.
+	PostProcessWebhookURL string
+	// PostProcessWebhookSecret is the key the post-processing webhook
+	// payload is signed with (HMAC-SHA256).
+	PostProcessWebhookSecret string
+	// DryRun captures and transcribes calls as usual but skips uploading and
+	// publishing anything, leaving the transcription files in the data
+	// directory. Useful to validate a deployment or benchmark settings
+	// against real traffic.
+	DryRun bool
+	// Corrections, if set, skips joining the call and applies the given user
+	// corrections (a JSON array, see ParseCorrections) to the transcription
+	// previously published by the same job, saving them as an artifact.
+	Corrections string
+	// CorrectionsRegenerate also publishes the corrected transcription,
+	// replacing the previous files.
+	CorrectionsRegenerate bool
+}
+
+// S3Config holds the settings of an S3-compatible (e.g. AWS, MinIO) object
+// storage target. It's enabled when Bucket is set.
+type S3Config struct {
+	// Endpoint is the base URL of the service (e.g. https://s3.amazonaws.com).
+	// Objects are addressed path-style so that any compatible service works.
+	Endpoint        string
+	Bucket          string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Prefix, if set, is prepended to every object key.
+	Prefix string
+}
+
+func (c S3Config) IsEnabled() bool {
+	return c.Bucket != ""
+}
+
+func (c S3Config) IsValid() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+
+	if c.Endpoint == "" {
+		return fmt.Errorf("S3Endpoint cannot be empty")
+	} else if u, err := url.Parse(c.Endpoint); err != nil {
+		return fmt.Errorf("S3Endpoint parsing failed: %w", err)
+	} else if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("S3Endpoint parsing failed: invalid scheme %q", u.Scheme)
+	}
+
+	if c.Region == "" {
+		return fmt.Errorf("S3Region cannot be empty")
+	}
+
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("S3AccessKeyID and S3SecretAccessKey cannot be empty")
+	}
+
+	return nil
+}
+
+func (c PublishConfig) IsValid() error {
+	if c.ArtifactsURL != "" {
+		if u, err := url.Parse(c.ArtifactsURL); err != nil {
+			return fmt.Errorf("ArtifactsURL parsing failed: %w", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("ArtifactsURL parsing failed: invalid scheme %q", u.Scheme)
+		}
+	}
+
+	if c.CompletionWebhookURL != "" {
+		if u, err := url.Parse(c.CompletionWebhookURL); err != nil {
+			return fmt.Errorf("CompletionWebhookURL parsing failed: %w", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("CompletionWebhookURL parsing failed: invalid scheme %q", u.Scheme)
+		}
+
+		if c.CompletionWebhookSecret == "" {
+			return fmt.Errorf("CompletionWebhookSecret cannot be empty")
+		}
+	}
+
+	if c.PostProcessWebhookURL != "" {
+		if c.PostProcessCommand != "" {
+			return fmt.Errorf("PostProcessCommand and PostProcessWebhookURL cannot both be set")
+		}
+
+		if u, err := url.Parse(c.PostProcessWebhookURL); err != nil {
+			return fmt.Errorf("PostProcessWebhookURL parsing failed: %w", err)
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("PostProcessWebhookURL parsing failed: invalid scheme %q", u.Scheme)
+		}
+
+		if c.PostProcessWebhookSecret == "" {
+			return fmt.Errorf("PostProcessWebhookSecret cannot be empty")
+		}
+	}
+
+	if c.SummaryBackend != "" && !c.SummaryBackend.IsValid() {
+		return fmt.Errorf("SummaryBackend value is not valid")
+	}
+
+	if c.SummaryBackend == SummaryBackendLocal && c.SummaryModel == "" {
+		return fmt.Errorf("SummaryModel cannot be empty with the local SummaryBackend")
+	}
+
+	if c.Corrections != "" {
+		if _, err := ParseCorrections(c.Corrections); err != nil {
+			return fmt.Errorf("Corrections value is not valid: %w", err)
+		}
+	} else if c.CorrectionsRegenerate {
+		return fmt.Errorf("CorrectionsRegenerate requires Corrections to be set")
+	}
+
+	return c.S3.IsValid()
+}
+
+func (c *PublishConfig) SetDefaults() {
+	if c.SummaryBackend == "" {
+		c.SummaryBackend = SummaryBackendDefault
+	}
+
+	if c.S3.IsEnabled() && c.S3.Region == "" {
+		c.S3.Region = S3RegionDefault
+	}
+}
+
+func (c *PublishConfig) FromEnv() {
+	c.GenerateSummary, _ = strconv.ParseBool(os.Getenv("GENERATE_SUMMARY"))
+	if val := os.Getenv("SUMMARY_BACKEND"); val != "" {
+		c.SummaryBackend = SummaryBackend(val)
+	}
+	c.SummaryModel = os.Getenv("SUMMARY_MODEL")
+	c.ArtifactsURL = os.Getenv("ARTIFACTS_URL")
+	c.S3.Endpoint = os.Getenv("S3_ENDPOINT")
+	c.S3.Bucket = os.Getenv("S3_BUCKET")
+	c.S3.Region = os.Getenv("S3_REGION")
+	c.S3.AccessKeyID = os.Getenv("S3_ACCESS_KEY_ID")
+	c.S3.SecretAccessKey = os.Getenv("S3_SECRET_ACCESS_KEY")
+	c.S3.Prefix = os.Getenv("S3_PREFIX")
+	c.CompletionWebhookURL = os.Getenv("COMPLETION_WEBHOOK_URL")
+	c.CompletionWebhookSecret = os.Getenv("COMPLETION_WEBHOOK_SECRET")
+	c.PostProcessCommand = os.Getenv("POST_PROCESS_COMMAND")
+	c.PostProcessWebhookURL = os.Getenv("POST_PROCESS_WEBHOOK_URL")
+	c.PostProcessWebhookSecret = os.Getenv("POST_PROCESS_WEBHOOK_SECRET")
+	c.DryRun, _ = strconv.ParseBool(os.Getenv("DRY_RUN"))
+	c.Corrections = os.Getenv("CORRECTIONS")
+	c.CorrectionsRegenerate, _ = strconv.ParseBool(os.Getenv("CORRECTIONS_REGENERATE"))
+}
+
+func (c PublishConfig) ToEnv() []string {
+	vars := []string{
+		fmt.Sprintf("GENERATE_SUMMARY=%t", c.GenerateSummary),
+		fmt.Sprintf("SUMMARY_BACKEND=%s", c.SummaryBackend),
+		fmt.Sprintf("DRY_RUN=%t", c.DryRun),
+	}
+
+	if c.SummaryModel != "" {
+		vars = append(vars, fmt.Sprintf("SUMMARY_MODEL=%s", c.SummaryModel))
+	}
+
+	if c.ArtifactsURL != "" {
+		vars = append(vars, fmt.Sprintf("ARTIFACTS_URL=%s", c.ArtifactsURL))
+	}
+
+	if c.S3.IsEnabled() {
+		vars = append(vars,
+			fmt.Sprintf("S3_ENDPOINT=%s", c.S3.Endpoint),
+			fmt.Sprintf("S3_BUCKET=%s", c.S3.Bucket),
+			fmt.Sprintf("S3_REGION=%s", c.S3.Region),
+			fmt.Sprintf("S3_ACCESS_KEY_ID=%s", c.S3.AccessKeyID),
+			fmt.Sprintf("S3_SECRET_ACCESS_KEY=%s", c.S3.SecretAccessKey),
+		)
+		if c.S3.Prefix != "" {
+			vars = append(vars, fmt.Sprintf("S3_PREFIX=%s", c.S3.Prefix))
+		}
+	}
+
+	if c.CompletionWebhookURL != "" {
+		vars = append(vars,
+			fmt.Sprintf("COMPLETION_WEBHOOK_URL=%s", c.CompletionWebhookURL),
+			fmt.Sprintf("COMPLETION_WEBHOOK_SECRET=%s", c.CompletionWebhookSecret),
+		)
+	}
+
+	if c.PostProcessCommand != "" {
+		vars = append(vars, fmt.Sprintf("POST_PROCESS_COMMAND=%s", c.PostProcessCommand))
+	}
+
+	if c.PostProcessWebhookURL != "" {
+		vars = append(vars,
+			fmt.Sprintf("POST_PROCESS_WEBHOOK_URL=%s", c.PostProcessWebhookURL),
+			fmt.Sprintf("POST_PROCESS_WEBHOOK_SECRET=%s", c.PostProcessWebhookSecret),
+		)
+	}
+
+	if c.Corrections != "" {
+		vars = append(vars,
+			fmt.Sprintf("CORRECTIONS=%s", c.Corrections),
+			fmt.Sprintf("CORRECTIONS_REGENERATE=%t", c.CorrectionsRegenerate),
+		)
+	}
+
+	return vars
+}
+
+func (c *PublishConfig) FromMap(m map[string]any) {
+	c.GenerateSummary, _ = m["generate_summary"].(bool)
+	if summaryBackend, ok := m["summary_backend"].(string); ok {
+		c.SummaryBackend = SummaryBackend(summaryBackend)
+	} else {
+		c.SummaryBackend, _ = m["summary_backend"].(SummaryBackend)
+	}
+	c.SummaryModel, _ = m["summary_model"].(string)
+	c.ArtifactsURL, _ = m["artifacts_url"].(string)
+	c.S3.Endpoint, _ = m["s3_endpoint"].(string)
+	c.S3.Bucket, _ = m["s3_bucket"].(string)
+	c.S3.Region, _ = m["s3_region"].(string)
+	c.S3.AccessKeyID, _ = m["s3_access_key_id"].(string)
+	c.S3.SecretAccessKey, _ = m["s3_secret_access_key"].(string)
+	c.S3.Prefix, _ = m["s3_prefix"].(string)
+	c.CompletionWebhookURL, _ = m["completion_webhook_url"].(string)
+	c.CompletionWebhookSecret, _ = m["completion_webhook_secret"].(string)
+	c.PostProcessCommand, _ = m["post_process_command"].(string)
+	c.PostProcessWebhookURL, _ = m["post_process_webhook_url"].(string)
+	c.PostProcessWebhookSecret, _ = m["post_process_webhook_secret"].(string)
+	c.DryRun, _ = m["dry_run"].(bool)
+	c.Corrections, _ = m["corrections"].(string)
+	c.CorrectionsRegenerate, _ = m["corrections_regenerate"].(bool)
+}
+
+func (c PublishConfig) ToMap() map[string]any {
+	return map[string]any{
+		"generate_summary":            c.GenerateSummary,
+		"summary_backend":             c.SummaryBackend,
+		"summary_model":               c.SummaryModel,
+		"artifacts_url":               c.ArtifactsURL,
+		"s3_endpoint":                 c.S3.Endpoint,
+		"s3_bucket":                   c.S3.Bucket,
+		"s3_region":                   c.S3.Region,
+		"s3_access_key_id":            c.S3.AccessKeyID,
+		"s3_secret_access_key":        c.S3.SecretAccessKey,
+		"s3_prefix":                   c.S3.Prefix,
+		"completion_webhook_url":      c.CompletionWebhookURL,
+		"completion_webhook_secret":   c.CompletionWebhookSecret,
+		"post_process_command":        c.PostProcessCommand,
+		"post_process_webhook_url":    c.PostProcessWebhookURL,
+		"post_process_webhook_secret": c.PostProcessWebhookSecret,
+		"dry_run":                     c.DryRun,
+		"corrections":                 c.Corrections,
+		"corrections_regenerate":      c.CorrectionsRegenerate,
+	}
+}
+
+// NetworkConfig holds the settings affecting how connections to the
+// Mattermost installation (API and rtcd signaling) are established.
+type NetworkConfig struct {
+	// IPFamily, if set, forces connecting over either IPv4 or IPv6.
+	IPFamily IPFamily
+	// DNSServers, if set, is a list of resolvers (host[:port]) to use in
+	// place of the system ones.
+	DNSServers []string
+}
+
+func (c NetworkConfig) IsValid() error {
+	if c.IPFamily != "" && !c.IPFamily.IsValid() {
+		return fmt.Errorf("IPFamily value is not valid")
+	}
+
+	for _, server := range c.DNSServers {
+		host := server
+		if h, _, err := net.SplitHostPort(server); err == nil {
+			host = h
+		}
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("DNSServers parsing failed: invalid address %q", server)
+		}
+	}
+
+	return nil
+}
+
+func (c *NetworkConfig) FromEnv() {
+	c.IPFamily = IPFamily(os.Getenv("IP_FAMILY"))
+	c.DNSServers = splitList(os.Getenv("DNS_SERVERS"))
+}
+
+func (c NetworkConfig) ToEnv() []string {
+	var vars []string
+
+	if c.IPFamily != "" {
+		vars = append(vars, fmt.Sprintf("IP_FAMILY=%s", c.IPFamily))
+	}
+
+	if len(c.DNSServers) > 0 {
+		vars = append(vars, fmt.Sprintf("DNS_SERVERS=%s", strings.Join(c.DNSServers, ",")))
+	}
+
+	return vars
+}
+
+func (c *NetworkConfig) FromMap(m map[string]any) {
+	if ipFamily, ok := m["ip_family"].(string); ok {
+		c.IPFamily = IPFamily(ipFamily)
+	} else {
+		c.IPFamily, _ = m["ip_family"].(IPFamily)
+	}
+
+	// The list is passed as a comma separated string, same as through env.
+	if val, ok := m["dns_servers"].(string); ok {
+		c.DNSServers = splitList(val)
+	}
+}
+
+func (c NetworkConfig) ToMap() map[string]any {
+	return map[string]any{
+		"ip_family":   c.IPFamily,
+		"dns_servers": strings.Join(c.DNSServers, ","),
+	}
+}
+
+// splitList parses a comma separated list, ignoring empty entries.
+func splitList(val string) []string {
+	var list []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,136 @@
+// Package denoise reduces stationary background noise (e.g. fans, hum or
+// hiss) in speech audio so that it doesn't get picked up as speech.
+package denoise
+
+import (
+	"math"
+	"sort"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/dsp"
+)
+
+const (
+	// frameSize is the analysis frame in samples (32ms at 16KHz).
+	frameSize = 512
+	hopSize   = frameSize / 2
+	// noiseFramesRatio is the share of the quietest frames the noise profile
+	// is estimated from.
+	noiseFramesRatio = 0.1
+	// overSubtraction scales the noise profile, which is underestimated by
+	// only considering the quietest frames, so that noise fluctuating above
+	// it is still removed.
+	overSubtraction = 2
+	// gainFloor bounds the attenuation (-20dB) as fully muting bins produces
+	// audible artifacts which the speech detector and whisper handle worse
+	// than some residual noise.
+	gainFloor = 0.1
+	// gainSmoothing is the weight of the previous frame's gains, limiting
+	// fluctuations between frames (i.e. "musical noise").
+	gainSmoothing = 0.5
+)
+
+// window is a periodic square root Hann window, applied both when analyzing
+// and synthesizing so that overlapping frames add back to the input.
+var window = func() []float64 {
+	w := make([]float64, frameSize)
+	for i := range w {
+		w[i] = math.Sqrt(0.5 * (1 - math.Cos(2*math.Pi*float64(i)/frameSize)))
+	}
+	return w
+}()
+
+// Reduce returns a copy of the given mono samples with the background noise
+// attenuated through spectral gating: the noise spectrum is estimated from the
+// quietest portions of the audio and subtracted from every frame. Audio too
+// short to estimate the noise from is returned as is.
+func Reduce(pcm []float32) []float32 {
+	out := make([]float32, len(pcm))
+	if len(pcm) < 2*frameSize {
+		copy(out, pcm)
+		return out
+	}
+
+	// Padding so that every sample is covered by two frames.
+	padded := make([]float64, hopSize+len(pcm)+frameSize)
+	for i, s := range pcm {
+		padded[hopSize+i] = float64(s)
+	}
+
+	numFrames := (len(padded)-frameSize)/hopSize + 1
+	spectra := make([][]complex128, numFrames)
+	powers := make([][]float64, numFrames)
+	energies := make([]float64, numFrames)
+	for f := range spectra {
+		spectrum := make([]complex128, frameSize)
+		for i := range spectrum {
+			spectrum[i] = complex(padded[f*hopSize+i]*window[i], 0)
+		}
+		dsp.FFT(spectrum, false)
+
+		power := make([]float64, frameSize/2+1)
+		for k := range power {
+			re, im := real(spectrum[k]), imag(spectrum[k])
+			power[k] = re*re + im*im
+			energies[f] += power[k]
+		}
+		spectra[f] = spectrum
+		powers[f] = power
+	}
+
+	noise := noiseProfile(powers, energies)
+
+	result := make([]float64, len(padded))
+	prevGains := make([]float64, frameSize/2+1)
+	for k := range prevGains {
+		prevGains[k] = 1
+	}
+	for f, spectrum := range spectra {
+		for k := 0; k <= frameSize/2; k++ {
+			gain := gainFloor
+			if powers[f][k] > 0 {
+				gain = max(gainFloor, 1-overSubtraction*noise[k]/powers[f][k])
+			}
+			gain = gainSmoothing*prevGains[k] + (1-gainSmoothing)*gain
+			prevGains[k] = gain
+
+			spectrum[k] *= complex(gain, 0)
+			// Keeping the spectrum conjugate symmetric so that the output
+			// is real.
+			if k > 0 && k < frameSize/2 {
+				spectrum[frameSize-k] *= complex(gain, 0)
+			}
+		}
+		dsp.FFT(spectrum, true)
+
+		for i := range spectrum {
+			result[f*hopSize+i] += real(spectrum[i]) * window[i]
+		}
+	}
+
+	for i := range out {
+		out[i] = float32(result[hopSize+i])
+	}
+
+	return out
+}
+
+// noiseProfile returns the average power spectrum of the quietest frames.
+func noiseProfile(powers [][]float64, energies []float64) []float64 {
+	idxs := make([]int, len(powers))
+	for i := range idxs {
+		idxs[i] = i
+	}
+	sort.Slice(idxs, func(i, j int) bool {
+		return energies[idxs[i]] < energies[idxs[j]]
+	})
+
+	n := max(1, int(float64(len(idxs))*noiseFramesRatio))
+	noise := make([]float64, len(powers[0]))
+	for _, idx := range idxs[:n] {
+		for k, p := range powers[idx] {
+			noise[k] += p / float64(n)
+		}
+	}
+
+	return noise
+}
@@ -0,0 +1,56 @@
+package denoise
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rms(pcm []float32) float64 {
+	var sum float64
+	for _, s := range pcm {
+		sum += float64(s) * float64(s)
+	}
+	return math.Sqrt(sum / float64(len(pcm)))
+}
+
+func TestReduce(t *testing.T) {
+	const rate = 16000
+
+	t.Run("short", func(t *testing.T) {
+		pcm := []float32{0.1, 0.2, 0.3}
+		require.Equal(t, pcm, Reduce(pcm))
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		require.Equal(t, make([]float32, rate), Reduce(make([]float32, rate)))
+	})
+
+	t.Run("noise", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+
+		// Two seconds of noise with a one second tone in the middle.
+		pcm := make([]float32, 2*rate)
+		for i := range pcm {
+			pcm[i] = float32(rnd.NormFloat64() * 0.01)
+		}
+		tone := pcm[rate/2 : rate+rate/2]
+		for i := range tone {
+			tone[i] += float32(0.3 * math.Sin(2*math.Pi*440*float64(i)/rate))
+		}
+
+		out := Reduce(pcm)
+		require.Len(t, out, len(pcm))
+
+		// Noise gets attenuated by at least 10dB.
+		require.Less(t, rms(out[:rate/4]), rms(pcm[:rate/4])/math.Sqrt(10))
+		require.Less(t, rms(out[len(out)-rate/4:]), rms(pcm[len(pcm)-rate/4:])/math.Sqrt(10))
+
+		// The tone is preserved.
+		toneOut := out[rate/2+rate/8 : rate+rate/2-rate/8]
+		toneIn := pcm[rate/2+rate/8 : rate+rate/2-rate/8]
+		require.InDelta(t, 1, rms(toneOut)/rms(toneIn), 0.05)
+	})
+}
@@ -0,0 +1,49 @@
+// Package dsp implements the signal processing primitives shared by the audio
+// analysis packages.
+package dsp
+
+import (
+	"math"
+	"math/bits"
+)
+
+// FFT computes in place the discrete Fourier transform of x, whose length
+// must be a power of two. The inverse transform is computed if inverse is
+// set, including the 1/N scaling.
+func FFT(x []complex128, inverse bool) {
+	n := len(x)
+	if n <= 1 {
+		return
+	}
+
+	// Bit reversal permutation.
+	shift := bits.UintSize - bits.TrailingZeros(uint(n))
+	for i := 0; i < n; i++ {
+		if j := int(bits.Reverse(uint(i)) >> shift); j > i {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1.0
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := complex(math.Cos(2*math.Pi/float64(size)), sign*math.Sin(2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := x[start+k], w*x[start+k+size/2]
+				x[start+k], x[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+
+	if inverse {
+		for i := range x {
+			x[i] /= complex(float64(n), 0)
+		}
+	}
+}
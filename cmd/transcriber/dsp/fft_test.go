@@ -0,0 +1,25 @@
+package dsp
+
+import (
+	"math/cmplx"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFFT(t *testing.T) {
+	x := make([]complex128, 8)
+	for i := range x {
+		x[i] = complex(float64(i), 0)
+	}
+	orig := append([]complex128(nil), x...)
+
+	FFT(x, false)
+	// DC component is the sum of the samples.
+	require.InDelta(t, 28, real(x[0]), 1e-9)
+
+	FFT(x, true)
+	for i := range x {
+		require.InDelta(t, 0, cmplx.Abs(x[i]-orig[i]), 1e-9)
+	}
+}
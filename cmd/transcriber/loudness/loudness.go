@@ -0,0 +1,210 @@
+// Package loudness measures and normalizes the loudness of speech audio
+// following EBU R128 (ITU-R BS.1770) so that quiet speakers are transcribed
+// as accurately as loud ones.
+package loudness
+
+import (
+	"math"
+)
+
+const (
+	sampleRate = 16000
+	// blockSize is the gating block (400ms), overlapping by 75%.
+	blockSize = sampleRate * 400 / 1000
+	blockStep = blockSize / 4
+	// absoluteGate and relativeGate exclude silence and quiet passages from
+	// the measurement, so that pauses don't lower the loudness.
+	absoluteGate = -70
+	relativeGate = -10
+
+	// Target is the loudness, in LUFS, audio is normalized to.
+	Target = -23
+	// maxGainDB bounds the amplification so that tracks with barely any
+	// signal (e.g. a muted microphone picking up the room) aren't turned
+	// into loud noise.
+	maxGainDB = 30
+	// peakCeiling is the highest sample value normalized audio can reach
+	// (-1dBFS), lowering the gain if needed so that it doesn't clip.
+	peakCeiling = 0.89
+)
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// kWeighting returns the two stages of the BS.1770 K-weighting filter (a
+// high shelf modeling the head, followed by a high pass) for the package's
+// sample rate, as the reference coefficients are for 48KHz only.
+func kWeighting() (*biquad, *biquad) {
+	k := math.Tan(math.Pi * 1681.974450955533 / sampleRate)
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := &biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * 38.13547087602444 / sampleRate)
+	q = 0.5003270373238773
+	highPass := &biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / (1 + k/q + k*k),
+		a2: (1 - k/q + k*k) / (1 + k/q + k*k),
+	}
+
+	return shelf, highPass
+}
+
+// blockPowers returns the mean square of the K-weighted signal over every
+// gating block of the given mono samples, at 16KHz.
+func blockPowers(pcm []float32) []float64 {
+	if len(pcm) < blockSize {
+		return nil
+	}
+
+	shelf, highPass := kWeighting()
+	squares := make([]float64, len(pcm))
+	for i, s := range pcm {
+		y := highPass.process(shelf.process(float64(s)))
+		squares[i] = y * y
+	}
+
+	powers := make([]float64, 0, (len(pcm)-blockSize)/blockStep+1)
+	var sum float64
+	for i := 0; i < blockSize; i++ {
+		sum += squares[i]
+	}
+	powers = append(powers, sum/blockSize)
+	for off := blockStep; off+blockSize <= len(pcm); off += blockStep {
+		for i := off - blockStep; i < off; i++ {
+			sum -= squares[i]
+		}
+		for i := off + blockSize - blockStep; i < off+blockSize; i++ {
+			sum += squares[i]
+		}
+		powers = append(powers, max(0, sum)/blockSize)
+	}
+
+	return powers
+}
+
+func powerToLUFS(p float64) float64 {
+	return -0.691 + 10*math.Log10(p)
+}
+
+// Meter measures the loudness of audio passed to it in chunks, so that long
+// tracks don't need to be held in memory as a whole to be normalized.
+type Meter struct {
+	powers []float64
+	peak   float64
+}
+
+// Add measures the given chunk of mono samples, at 16KHz.
+func (m *Meter) Add(pcm []float32) {
+	m.powers = append(m.powers, blockPowers(pcm)...)
+	for _, s := range pcm {
+		m.peak = max(m.peak, math.Abs(float64(s)))
+	}
+}
+
+// Integrated returns the gated integrated loudness, in LUFS, of the audio
+// measured so far. Negative infinity is returned if no block is above the
+// absolute gate.
+func (m *Meter) Integrated() float64 {
+	gatedMean := func(threshold float64) (float64, int) {
+		var sum float64
+		var n int
+		for _, p := range m.powers {
+			if p > 0 && powerToLUFS(p) > threshold {
+				sum += p
+				n++
+			}
+		}
+		if n == 0 {
+			return 0, 0
+		}
+		return sum / float64(n), n
+	}
+
+	mean, n := gatedMean(absoluteGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	mean, n = gatedMean(powerToLUFS(mean) + relativeGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+
+	return powerToLUFS(mean)
+}
+
+// Gain returns the gain, in dB, bringing the audio measured so far to
+// Target.
+func (m *Meter) Gain() float64 {
+	lufs := m.Integrated()
+	if math.IsInf(lufs, -1) {
+		return 0
+	}
+
+	gainDB := min(maxGainDB, Target-lufs)
+	if m.peak > 0 {
+		// Peaks only limit amplification, audio isn't attenuated because of
+		// them.
+		gainDB = min(gainDB, max(0, 20*math.Log10(peakCeiling/m.peak)))
+	}
+
+	return gainDB
+}
+
+// Apply scales the given samples in place by gainDB.
+func Apply(pcm []float32, gainDB float64) {
+	gain := float32(math.Pow(10, gainDB/20))
+	for i := range pcm {
+		pcm[i] *= gain
+	}
+}
+
+// Integrated returns the gated integrated loudness, in LUFS, of the given
+// chunks of mono samples, at 16KHz, taken as a whole. Negative infinity is
+// returned if no block is above the absolute gate.
+func Integrated(chunks ...[]float32) float64 {
+	var m Meter
+	for _, pcm := range chunks {
+		m.Add(pcm)
+	}
+	return m.Integrated()
+}
+
+// Normalize scales in place the given chunks of mono samples, at 16KHz, so
+// that their integrated loudness matches Target, and returns the applied
+// gain in dB. The same gain is applied to all chunks.
+func Normalize(chunks ...[]float32) float64 {
+	var m Meter
+	for _, pcm := range chunks {
+		m.Add(pcm)
+	}
+
+	gainDB := m.Gain()
+	for _, pcm := range chunks {
+		Apply(pcm, gainDB)
+	}
+
+	return gainDB
+}
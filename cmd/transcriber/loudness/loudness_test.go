@@ -0,0 +1,92 @@
+package loudness
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func genSine(freq, amp float64, dur float64) []float32 {
+	pcm := make([]float32, int(dur*sampleRate))
+	for i := range pcm {
+		pcm[i] = float32(amp * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+	}
+	return pcm
+}
+
+func TestIntegrated(t *testing.T) {
+	t.Run("reference", func(t *testing.T) {
+		// A full scale 997Hz sine measures -3.01 LUFS.
+		require.InDelta(t, -3.01, Integrated(genSine(997, 1, 5)), 0.1)
+		require.InDelta(t, -23.01, Integrated(genSine(997, 0.1, 5)), 0.1)
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		require.True(t, math.IsInf(Integrated(make([]float32, 5*sampleRate)), -1))
+		require.True(t, math.IsInf(Integrated(), -1))
+		// Too short to measure.
+		require.True(t, math.IsInf(Integrated(genSine(997, 1, 0.1)), -1))
+	})
+
+	t.Run("gating", func(t *testing.T) {
+		// Pauses shouldn't affect the measured loudness.
+		pcm := append(genSine(997, 0.1, 5), make([]float32, 10*sampleRate)...)
+		require.InDelta(t, -23.01, Integrated(pcm), 0.1)
+	})
+
+	t.Run("chunks", func(t *testing.T) {
+		require.InDelta(t, Integrated(genSine(997, 0.1, 2)), Integrated(genSine(997, 0.1, 1), genSine(997, 0.1, 1)), 0.1)
+	})
+}
+
+func TestNormalize(t *testing.T) {
+	t.Run("quiet", func(t *testing.T) {
+		a, b := genSine(997, 0.01, 3), genSine(500, 0.01, 3)
+		gain := Normalize(a, b)
+		require.Greater(t, gain, 15.0)
+		require.InDelta(t, Target, Integrated(a, b), 0.1)
+	})
+
+	t.Run("loud", func(t *testing.T) {
+		pcm := genSine(997, 0.8, 3)
+		require.Less(t, Normalize(pcm), 0.0)
+		require.InDelta(t, Target, Integrated(pcm), 0.1)
+	})
+
+	t.Run("peak limited", func(t *testing.T) {
+		pcm := genSine(997, 0.01, 3)
+		pcm[100] = 0.5
+		gain := Normalize(pcm)
+		require.InDelta(t, 20*math.Log10(peakCeiling/0.5), gain, 0.01)
+		require.LessOrEqual(t, float64(pcm[100]), peakCeiling)
+	})
+
+	t.Run("gain bounded", func(t *testing.T) {
+		pcm := genSine(997, 0.001, 3)
+		require.Equal(t, float64(maxGainDB), Normalize(pcm))
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		pcm := make([]float32, 3*sampleRate)
+		require.Zero(t, Normalize(pcm))
+	})
+}
+
+func TestMeter(t *testing.T) {
+	a, b := genSine(997, 0.01, 3), genSine(500, 0.01, 3)
+
+	var m Meter
+	m.Add(a)
+	m.Add(b)
+	require.Equal(t, Integrated(a, b), m.Integrated())
+
+	gain := m.Gain()
+	Apply(a, gain)
+	Apply(b, gain)
+	require.InDelta(t, Target, Integrated(a, b), 0.1)
+
+	var empty Meter
+	require.True(t, math.IsInf(empty.Integrated(), -1))
+	require.Zero(t, empty.Gain())
+}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -18,8 +22,21 @@ import (
 const (
 	startTimeout = 30 * time.Second
 	stopTimeout  = 10 * time.Second
+
+	httpReadHeaderTimeout = 5 * time.Second
+	// The debug server exposes sensitive information (e.g. pprof) so it
+	// defaults to listening on the loopback interface only.
+	debugServerAddressDefault = "localhost:6060"
 )
 
+// contentLogKeys are the attribute keys under which transcribed content
+// (e.g. recognized text, captions) is logged.
+var contentLogKeys = map[string]bool{
+	"text": true,
+}
+
+const redactedLogValue = "[redacted]"
+
 func slogReplaceAttr(_ []string, a slog.Attr) slog.Attr {
 	if a.Key == slog.SourceKey {
 		source := a.Value.Any().(*slog.Source)
@@ -38,13 +55,41 @@ func slogReplaceAttr(_ []string, a slog.Attr) slog.Attr {
 	return a
 }
 
-func main() {
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+// slogRedactContentAttr masks transcribed content while keeping everything
+// else (e.g. timings, IDs) intact.
+func slogRedactContentAttr(groups []string, a slog.Attr) slog.Attr {
+	if contentLogKeys[a.Key] {
+		return slog.String(a.Key, redactedLogValue)
+	}
+	return slogReplaceAttr(groups, a)
+}
+
+func newLogger(w io.Writer) *slog.Logger {
+	replaceAttr := slogReplaceAttr
+	if redact, _ := strconv.ParseBool(os.Getenv("LOG_REDACT_CONTENT")); redact {
+		replaceAttr = slogRedactContentAttr
+	}
+
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
 		AddSource:   true,
 		Level:       slog.LevelDebug,
-		ReplaceAttr: slogReplaceAttr,
+		ReplaceAttr: replaceAttr,
 	})).With("trID", os.Getenv("TRANSCRIPTION_ID"))
-	slog.SetDefault(logger)
+}
+
+func main() {
+	slog.SetDefault(newLogger(os.Stdout))
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case transcribeFileCmd:
+			os.Exit(runTranscribeFile(os.Args[2:]))
+		case transcribeBatchCmd:
+			os.Exit(runTranscribeBatch(os.Args[2:]))
+		case publishCmd:
+			os.Exit(runPublish(os.Args[2:]))
+		}
+	}
 
 	pid := os.Getpid()
 	if err := os.WriteFile("/tmp/transcriber.pid", []byte(fmt.Sprintf("%d", pid)), 0666); err != nil {
@@ -65,32 +110,132 @@ func main() {
 		os.Exit(1)
 	}
 
-	slog.Info("starting transcriber")
+	// Logs are copied to a file so that they can be pushed as a job artifact
+	// once done.
+	if cfg.Publish.ArtifactsURL != "" {
+		if logFile, err := transcriber.OpenLogFile(); err != nil {
+			slog.Error("failed to open log file", slog.String("err", err.Error()))
+		} else {
+			defer logFile.Close()
+			slog.SetDefault(newLogger(io.MultiWriter(os.Stdout, logFile)))
+		}
+	}
+
+	// Logged once the log file is set up so that it's also part of the
+	// artifacts.
+	slog.Info("effective config", slog.Any("config", cfg), slog.Int("numCPU", runtime.NumCPU()))
 
-	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
-	defer cancel()
-	if err := transcriber.Start(ctx); err != nil {
-		if err := transcriber.ReportJobFailure(err.Error()); err != nil {
-			slog.Error("failed to report job failure", slog.String("err", err.Error()))
+	if addr := os.Getenv("HEALTH_LISTEN_ADDRESS"); addr != "" {
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           transcriber.HealthHandler(),
+			ReadHeaderTimeout: httpReadHeaderTimeout,
 		}
+		go func() {
+			slog.Info("starting health server", slog.String("addr", addr))
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("health server failed", slog.String("err", err.Error()))
+			}
+		}()
+		defer srv.Close()
+	}
 
-		// cleaning up
-		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
-		defer cancel()
-		if err := transcriber.Stop(stopCtx); err != nil {
-			slog.Error("failed to stop transcriber", slog.String("err", err.Error()))
+	if debugServer, _ := strconv.ParseBool(os.Getenv("DEBUG_SERVER")); debugServer {
+		addr := os.Getenv("DEBUG_SERVER_ADDRESS")
+		if addr == "" {
+			addr = debugServerAddressDefault
+		}
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           transcriber.DebugHandler(),
+			ReadHeaderTimeout: httpReadHeaderTimeout,
 		}
+		go func() {
+			slog.Info("starting debug server", slog.String("addr", addr))
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("debug server failed", slog.String("err", err.Error()))
+			}
+		}()
+		defer srv.Close()
+	}
 
-		slog.Error("failed to start transcriber", slog.String("err", err.Error()))
+	if cfg.Capture.ReTranscribeRange != "" {
+		// Only a portion of a captured track is transcribed again and
+		// published as a patch, so there's nothing else to run.
+		if err := transcriber.ReTranscribeRange(); err != nil {
+			slog.Error("failed to re-transcribe range", slog.String("err", err.Error()))
+			if err := transcriber.ReportJobFailure(err.Error()); err != nil {
+				slog.Error("failed to report job failure", slog.String("err", err.Error()))
+			}
+			os.Exit(1)
+		}
+		slog.Info("range re-transcribed, exiting")
+		return
+	}
 
-		// Although an error case, if we fail to start we are not losing any
-		// transcript data so the associated resources (e.g. container, volume) can be safely deleted.
-		// This is signaled to the calling layer (calls-offloader) by exiting with
-		// a success code.
-		os.Exit(0)
+	if cfg.Publish.Corrections != "" {
+		// User corrections are applied to the transcription previously
+		// published, so there's nothing to capture nor transcribe.
+		if err := transcriber.ApplyCorrections(); err != nil {
+			slog.Error("failed to apply corrections", slog.String("err", err.Error()))
+			if err := transcriber.ReportJobFailure(err.Error()); err != nil {
+				slog.Error("failed to report job failure", slog.String("err", err.Error()))
+			}
+			os.Exit(1)
+		}
+		slog.Info("corrections applied, exiting")
+		return
 	}
 
-	slog.Info("transcriber has started")
+	var resumed bool
+	if cfg.Capture.ReTranscribeFromData {
+		// The tracks captured by a previous run of the job are transcribed
+		// again, without joining the call.
+		if err := transcriber.ReTranscribe(); err != nil {
+			slog.Error("failed to re-transcribe from data", slog.String("err", err.Error()))
+			if err := transcriber.ReportJobFailure(err.Error()); err != nil {
+				slog.Error("failed to report job failure", slog.String("err", err.Error()))
+			}
+			os.Exit(1)
+		}
+		resumed = true
+	} else {
+		// If a previous run crashed during post-processing we pick up from where
+		// it left off rather than joining the call again.
+		resumed, err = transcriber.Resume()
+		if err != nil {
+			slog.Error("failed to resume from checkpoint", slog.String("err", err.Error()))
+		}
+	}
+
+	if !resumed {
+		slog.Info("starting transcriber")
+
+		ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+		defer cancel()
+		if err := transcriber.Start(ctx); err != nil {
+			if err := transcriber.ReportJobFailure(err.Error()); err != nil {
+				slog.Error("failed to report job failure", slog.String("err", err.Error()))
+			}
+
+			// cleaning up
+			stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout)
+			defer cancel()
+			if err := transcriber.Stop(stopCtx); err != nil {
+				slog.Error("failed to stop transcriber", slog.String("err", err.Error()))
+			}
+
+			slog.Error("failed to start transcriber", slog.String("err", err.Error()))
+
+			// Although an error case, if we fail to start we are not losing any
+			// transcript data so the associated resources (e.g. container, volume) can be safely deleted.
+			// This is signaled to the calling layer (calls-offloader) by exiting with
+			// a success code.
+			os.Exit(0)
+		}
+
+		slog.Info("transcriber has started")
+	}
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLoggerRedactContent(t *testing.T) {
+	logCaption := func() string {
+		var buf bytes.Buffer
+		newLogger(&buf).Info("caption", slog.String("text", "secret words"), slog.Int64("startTS", 1000))
+		return buf.String()
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		out := logCaption()
+		require.Contains(t, out, `text="secret words"`)
+		require.Contains(t, out, "startTS=1000")
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Setenv("LOG_REDACT_CONTENT", "true")
+		out := logCaption()
+		require.NotContains(t, out, "secret words")
+		require.Contains(t, out, "text="+redactedLogValue)
+		require.Contains(t, out, "startTS=1000")
+		require.Contains(t, out, "source=main_test.go")
+	})
+}
@@ -18,6 +18,7 @@ import (
 var (
 	errFileNotOpened    = errors.New("file not opened")
 	errInvalidNilPacket = errors.New("invalid nil packet")
+	errAudioWritten     = errors.New("audio already written")
 )
 
 // Writer is used to take RTP packets and write them to an OGG on disk
@@ -32,15 +33,23 @@ type Writer struct {
 	previousGranulePosition uint64
 	previousTimestamp       uint32
 	lastPayloadSize         int
+	comments                []string
+	size                    int64
 }
 
 // NewWriter builds a new OGG Opus writer
 func NewWriter(fileName string, sampleRate uint32, channelCount uint16) (*Writer, error) {
+	return NewWriterWithComments(fileName, sampleRate, channelCount, nil)
+}
+
+// NewWriterWithComments builds a new OGG Opus writer which stores the given
+// user comments (e.g. "KEY=value") in the comment header.
+func NewWriterWithComments(fileName string, sampleRate uint32, channelCount uint16, comments []string) (*Writer, error) {
 	f, err := os.Create(fileName) //nolint:gosec
 	if err != nil {
 		return nil, err
 	}
-	writer, err := NewWith(f, sampleRate, channelCount)
+	writer, err := newWriterWith(f, sampleRate, channelCount, comments)
 	if err != nil {
 		return nil, f.Close()
 	}
@@ -50,6 +59,10 @@ func NewWriter(fileName string, sampleRate uint32, channelCount uint16) (*Writer
 
 // NewWith initialize a new OGG Opus writer with an io.Writer output
 func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*Writer, error) {
+	return newWriterWith(out, sampleRate, channelCount, nil)
+}
+
+func newWriterWith(out io.Writer, sampleRate uint32, channelCount uint16, comments []string) (*Writer, error) {
 	if out == nil {
 		return nil, errFileNotOpened
 	}
@@ -60,6 +73,7 @@ func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*Writer, er
 		channelCount:  channelCount,
 		serial:        randutil.NewMathRandomGenerator().Uint32(),
 		checksumTable: generateChecksumTable(),
+		comments:      comments,
 
 		// Timestamp and Granule MUST start from 1
 		// Only headers can have 0 values
@@ -96,8 +110,7 @@ func NewWith(out io.Writer, sampleRate uint32, channelCount uint16) (*Writer, er
    Figure 1: Example Packet Organization for a Logical Ogg Opus Stream
 */
 
-func (i *Writer) writeHeaders() error {
-	// ID Header
+func (i *Writer) idHeader() []byte {
 	oggIDHeader := make([]byte, 19)
 
 	copy(oggIDHeader[0:], idPageSignature)                          // Magic Signature 'OpusHead'
@@ -108,6 +121,13 @@ func (i *Writer) writeHeaders() error {
 	binary.LittleEndian.PutUint16(oggIDHeader[16:], 0)              // output gain
 	oggIDHeader[18] = 0                                             // channel map 0 = one stream: mono or stereo
 
+	return oggIDHeader
+}
+
+func (i *Writer) writeHeaders() error {
+	// ID Header
+	oggIDHeader := i.idHeader()
+
 	// Reference: https://tools.ietf.org/html/rfc7845.html#page-6
 	// RFC specifies that the ID Header page should have a granule position of 0 and a Header Type set to 2 (StartOfStream)
 	data := i.createPage(oggIDHeader, pageHeaderTypeBeginningOfStream, 0, i.pageIndex)
@@ -117,11 +137,21 @@ func (i *Writer) writeHeaders() error {
 	i.pageIndex++
 
 	// Comment Header
-	oggCommentHeader := make([]byte, 21)
-	copy(oggCommentHeader[0:], commentPageSignature)        // Magic Signature 'OpusTags'
-	binary.LittleEndian.PutUint32(oggCommentHeader[8:], 5)  // Vendor Length
-	copy(oggCommentHeader[12:], "pion")                     // Vendor name 'pion'
-	binary.LittleEndian.PutUint32(oggCommentHeader[17:], 0) // User Comment List Length
+	commentsLen := 0
+	for _, c := range i.comments {
+		commentsLen += 4 + len(c)
+	}
+	oggCommentHeader := make([]byte, 21+commentsLen)
+	copy(oggCommentHeader[0:], commentPageSignature)                              // Magic Signature 'OpusTags'
+	binary.LittleEndian.PutUint32(oggCommentHeader[8:], 5)                        // Vendor Length
+	copy(oggCommentHeader[12:], "pion")                                           // Vendor name 'pion'
+	binary.LittleEndian.PutUint32(oggCommentHeader[17:], uint32(len(i.comments))) // User Comment List Length
+	offset := 21
+	for _, c := range i.comments {
+		binary.LittleEndian.PutUint32(oggCommentHeader[offset:], uint32(len(c))) // User Comment Length
+		copy(oggCommentHeader[offset+4:], c)                                     // User Comment
+		offset += 4 + len(c)
+	}
 
 	// RFC specifies that the page where the CommentHeader completes should have a granule position of 0
 	data = i.createPage(oggCommentHeader, pageHeaderTypeContinuationOfStream, 0, i.pageIndex)
@@ -133,6 +163,33 @@ func (i *Writer) writeHeaders() error {
 	return nil
 }
 
+// SetChannelCount updates the channel count stored in the ID header of the
+// file. It's meant for streams whose channel count is only known once their
+// first packet is received, so it must be called before any audio is written.
+func (i *Writer) SetChannelCount(channelCount uint16) error {
+	if i.fd == nil {
+		return errFileNotOpened
+	}
+	// The ID and comment headers are the only pages written so far.
+	if i.pageIndex > 2 {
+		return errAudioWritten
+	}
+	if channelCount == i.channelCount {
+		return nil
+	}
+
+	i.channelCount = channelCount
+
+	// The ID header has a fixed size so its page can be rewritten in place.
+	// The size of the last written page is needed when closing.
+	lastPayloadSize := i.lastPayloadSize
+	data := i.createPage(i.idHeader(), pageHeaderTypeBeginningOfStream, 0, 0)
+	i.lastPayloadSize = lastPayloadSize
+
+	_, err := i.fd.WriteAt(data, 0)
+	return err
+}
+
 const (
 	pageHeaderSize = 27
 )
@@ -249,6 +306,12 @@ func (i *Writer) writeToStream(p []byte) error {
 		return errFileNotOpened
 	}
 
-	_, err := i.stream.Write(p)
+	n, err := i.stream.Write(p)
+	i.size += int64(n)
 	return err
 }
+
+// Size returns the number of bytes written so far.
+func (i *Writer) Size() int64 {
+	return i.size
+}
@@ -56,6 +56,61 @@ func (d *Decoder) Decode(data []byte, samples []float32) (int, error) {
 	return ret, nil
 }
 
+// DecodeFEC recovers the packet lost right before data using the forward
+// error correction information data may carry. If it has none, the loss is
+// concealed as with DecodeLost. The capacity of samples must match the
+// duration of the lost packet.
+func (d *Decoder) DecodeFEC(data []byte, samples []float32) (int, error) {
+	if d.dec == nil {
+		return 0, fmt.Errorf("decoder is not initialized")
+	}
+
+	if len(data) == 0 {
+		return 0, fmt.Errorf("data should not be empty")
+	}
+
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("samples should not be empty")
+	}
+
+	if cap(samples)%d.channels != 0 {
+		return 0, fmt.Errorf("invalid samples capacity")
+	}
+
+	ret := int(C.opus_decode_float(d.dec, (*C.uchar)(&data[0]), C.int(len(data)),
+		(*C.float)(&samples[0]), C.int(cap(samples)/d.channels), 1))
+	if ret < 0 {
+		return 0, fmt.Errorf("decode failed with code %d", ret)
+	}
+
+	return ret, nil
+}
+
+// DecodeLost runs packet loss concealment, synthesizing audio for a lost
+// packet from the previously decoded ones. The capacity of samples must
+// match the duration of the lost packet.
+func (d *Decoder) DecodeLost(samples []float32) (int, error) {
+	if d.dec == nil {
+		return 0, fmt.Errorf("decoder is not initialized")
+	}
+
+	if len(samples) == 0 {
+		return 0, fmt.Errorf("samples should not be empty")
+	}
+
+	if cap(samples)%d.channels != 0 {
+		return 0, fmt.Errorf("invalid samples capacity")
+	}
+
+	ret := int(C.opus_decode_float(d.dec, nil, 0,
+		(*C.float)(&samples[0]), C.int(cap(samples)/d.channels), 0))
+	if ret < 0 {
+		return 0, fmt.Errorf("decode failed with code %d", ret)
+	}
+
+	return ret, nil
+}
+
 func (d *Decoder) Destroy() error {
 	if d.dec == nil {
 		return fmt.Errorf("decoder is not initialized")
@@ -46,6 +46,63 @@ func TestOpusDecode(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestOpusDecodeLoss(t *testing.T) {
+	f, err := os.Open("../../../testfiles/sample.opus")
+	require.NoError(t, err)
+	defer f.Close()
+
+	ogg, _, err := oggreader.NewWith(f)
+	require.NoError(t, err)
+
+	rate := 16000
+	frameSize := 20 * rate / 1000
+	samples := make([]float32, frameSize)
+
+	dec, err := NewDecoder(rate, 1)
+	require.NoError(t, err)
+	require.NotNil(t, dec)
+	defer func() {
+		require.NoError(t, dec.Destroy())
+	}()
+
+	var i int
+	for {
+		data, hdr, err := ogg.ParseNextPage()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		if hdr.GranulePosition == 0 {
+			continue
+		}
+
+		// Simulating the two packets before every third one getting lost.
+		i++
+		if i%3 == 0 {
+			n, err := dec.DecodeLost(samples)
+			require.NoError(t, err)
+			require.Equal(t, frameSize, n)
+
+			n, err = dec.DecodeFEC(data, samples)
+			require.NoError(t, err)
+			require.Equal(t, frameSize, n)
+		}
+
+		n, err := dec.Decode(data, samples)
+		require.NoError(t, err)
+		require.Equal(t, frameSize, n)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := dec.DecodeLost(nil)
+		require.EqualError(t, err, "samples should not be empty")
+
+		_, err = dec.DecodeFEC(nil, samples)
+		require.EqualError(t, err, "data should not be empty")
+	})
+}
+
 func BenchmarkOpusDecode(b *testing.B) {
 	f, err := os.Open("../../../testfiles/sample.opus")
 	require.NoError(b, err)
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const publishCmd = "publish"
+
+// runPublish lists the jobs found in a data directory or publishes one of
+// them, skipping capture and transcription. Settings not given through flags
+// are read from the environment as usual. It returns the process exit code.
+func runPublish(args []string) int {
+	fs := flag.NewFlagSet(publishCmd, flag.ContinueOnError)
+	dataDir := fs.String("data-dir", "", "data directory of the job (required)")
+	list := fs.Bool("list", false, "list the jobs found in the data directory and exit")
+	trID := fs.String("transcription-id", os.Getenv("TRANSCRIPTION_ID"), "ID of the job to publish, can be omitted if the data directory holds a single job")
+	siteURL := fs.String("site-url", os.Getenv("SITE_URL"), "URL of the Mattermost installation")
+	authToken := fs.String("auth-token", os.Getenv("AUTH_TOKEN"), "token to authenticate against the Mattermost installation")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s -data-dir DIR [flags]\n\n", os.Args[0], publishCmd)
+		fmt.Fprintf(fs.Output(), "Publishes a job which transcribed all of its tracks but failed to publish.\n")
+		fmt.Fprintf(fs.Output(), "CALL_ID and POST_ID are read from the environment.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dataDir == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	jobs, err := call.ListDataDir(*dataDir)
+	if err != nil {
+		slog.Error("failed to list data directory", slog.String("err", err.Error()))
+		return 1
+	}
+
+	if *list {
+		for _, job := range jobs {
+			slog.Info("job found",
+				slog.String("transcriptionID", job.TranscriptionID),
+				slog.Int("numTracks", job.NumTracks),
+				slog.Int("numTranscribed", job.NumTranscribed),
+				slog.Bool("pending", job.Pending),
+				slog.Int("publishedVersion", job.PublishedVersion),
+				slog.Bool("canPublish", job.CanPublish()))
+		}
+		return 0
+	}
+
+	if *trID == "" {
+		if len(jobs) != 1 {
+			slog.Error("transcription ID is required", slog.Int("numJobs", len(jobs)))
+			return 2
+		}
+		*trID = jobs[0].TranscriptionID
+	}
+
+	// Files are looked up in the data directory through the environment.
+	if err := os.Setenv("DATA_DIR", *dataDir); err != nil {
+		slog.Error("failed to set data directory", slog.String("err", err.Error()))
+		return 1
+	}
+
+	cfg, err := config.FromEnv()
+	if err != nil {
+		slog.Error("failed to load config", slog.String("err", err.Error()))
+		return 1
+	}
+	cfg.SiteURL = strings.TrimSuffix(*siteURL, "/")
+	cfg.AuthToken = *authToken
+	cfg.TranscriptionID = *trID
+	cfg.SetDefaults()
+
+	transcriber, err := call.NewTranscriber(cfg)
+	if err != nil {
+		slog.Error("failed to create call transcriber", slog.String("err", err.Error()))
+		return 1
+	}
+
+	if err := transcriber.PublishFromData(); err != nil {
+		slog.Error("failed to publish from data", slog.String("err", err.Error()))
+		return 1
+	}
+
+	<-transcriber.Done()
+	if err := transcriber.Err(); err != nil {
+		slog.Error("failed to publish", slog.String("err", err.Error()))
+		return 1
+	}
+
+	slog.Info("job published", slog.String("transcriptionID", cfg.TranscriptionID))
+
+	return 0
+}
@@ -0,0 +1,115 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ASSOptions controls the generation of an ASS (Advanced SubStation Alpha)
+// subtitles file, which video tools such as FFmpeg can burn into recordings.
+type ASSOptions struct {
+	Enabled bool
+}
+
+func (o *ASSOptions) FromEnv() {
+	o.Enabled, _ = strconv.ParseBool(os.Getenv("ASS_SUBTITLES"))
+}
+
+func (o *ASSOptions) ToEnv() []string {
+	return []string{
+		fmt.Sprintf("ASS_SUBTITLES=%t", o.Enabled),
+	}
+}
+
+func (o *ASSOptions) FromMap(m map[string]any) {
+	o.Enabled, _ = m["ass_subtitles"].(bool)
+}
+
+func (o *ASSOptions) ToMap() map[string]any {
+	return map[string]any{
+		"ass_subtitles": o.Enabled,
+	}
+}
+
+// assColors are the primary colors speakers are styled with, picked by color
+// index. ASS colors are in &HAABBGGRR format.
+var assColors = []string{
+	"&H00FFFFFF", // white
+	"&H0000FFFF", // yellow
+	"&H00FFFF00", // cyan
+	"&H0000FF00", // green
+	"&H00FF80FF", // pink
+	"&H000080FF", // orange
+	"&H00FF8080", // light blue
+	"&H008080FF", // light red
+}
+
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 1920
+PlayResY: 1080
+WrapStyle: 0
+ScaledBorderAndShadow: yes
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+`
+
+const assStyleTmpl = "Style: %s,Arial,48,%s,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,60,60,40,1\n"
+
+// assTS converts ts milliseconds in the 0:00:00.00 format.
+func assTS(ts int64) string {
+	cs := ts / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, (cs/6000)%60, (cs/100)%60, cs%100)
+}
+
+// assText escapes text so that it's rendered as is: braces would otherwise
+// start override blocks and backslashes escape sequences.
+func assText(text string) string {
+	return strings.NewReplacer("{", "(", "}", ")", `\`, "/", "\n", `\N`).Replace(text)
+}
+
+// ASS writes the transcription as ASS subtitles, with a style per speaker so
+// that they can be told apart (and restyled) when burnt into a video.
+func (t Transcription) ASS(w io.Writer) error {
+	if _, err := io.WriteString(w, assHeader); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, assStyleTmpl, "Default", assColors[0]); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	// Style names can't hold arbitrary speaker names (e.g. commas) so
+	// they're numbered in order of appearance in the transcription.
+	styles := make(map[string]string)
+	for _, trackTr := range t {
+		if _, ok := styles[trackTr.Speaker]; ok {
+			continue
+		}
+		style := fmt.Sprintf("Speaker%d", len(styles)+1)
+		styles[trackTr.Speaker] = style
+		if _, err := fmt.Fprintf(w, assStyleTmpl, style, assColors[trackTr.ColorIndex%len(assColors)]); err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+	}
+
+	if _, err := io.WriteString(w, "\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	for _, s := range t.Interleave() {
+		style := styles[s.Speaker]
+		s.sanitize(assText)
+		_, err := fmt.Fprintf(w, "Dialogue: 0,%s,%s,%s,%s,0,0,0,,(%s) %s\n",
+			assTS(s.StartTS), assTS(s.EndTS), style, s.Speaker, s.Speaker, s.Text)
+		if err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package transcribe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestASSTS(t *testing.T) {
+	require.Equal(t, "0:00:00.00", assTS(0))
+	require.Equal(t, "0:00:00.99", assTS(999))
+	require.Equal(t, "0:01:02.20", assTS(62200))
+	require.Equal(t, "1:45:45.04", assTS(6345045))
+}
+
+func TestASS(t *testing.T) {
+	header := assHeader +
+		"Style: Default,Arial,48,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,60,60,40,1\n"
+	events := "\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n"
+
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		var b strings.Builder
+		require.NoError(t, tr.ASS(&b))
+		require.Equal(t, header+events, b.String())
+	})
+
+	t.Run("speakers", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:    "Alice",
+				ColorIndex: 1,
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1000, Text: "Hello {there}"},
+					{StartTS: 3000, EndTS: 4500, Text: "How are you?"},
+				},
+			},
+			{
+				Speaker:    "Bob, Jr.",
+				ColorIndex: 10,
+				Segments: []Segment{
+					{StartTS: 1500, EndTS: 2500, Text: `Hi, C:\ drive`},
+				},
+			},
+		}
+
+		var b strings.Builder
+		require.NoError(t, tr.ASS(&b))
+		require.Equal(t, header+
+			"Style: Speaker1,Arial,48,&H0000FFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,60,60,40,1\n"+
+			"Style: Speaker2,Arial,48,&H00FFFF00,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,2,1,2,60,60,40,1\n"+
+			events+
+			"Dialogue: 0,0:00:00.00,0:00:01.00,Speaker1,Alice,0,0,0,,(Alice) Hello (there)\n"+
+			"Dialogue: 0,0:00:01.50,0:00:02.50,Speaker2,Bob Jr.,0,0,0,,(Bob Jr.) Hi, C:/ drive\n"+
+			"Dialogue: 0,0:00:03.00,0:00:04.50,Speaker1,Alice,0,0,0,,(Alice) How are you?\n",
+			b.String())
+	})
+}
@@ -0,0 +1,94 @@
+package transcribe
+
+import (
+	"strings"
+	"unicode"
+)
+
+type TextDirection string
+
+const (
+	DirectionLTR TextDirection = "ltr"
+	DirectionRTL TextDirection = "rtl"
+)
+
+const (
+	leftToRightMark = "\u200e"
+	rightToLeftMark = "\u200f"
+)
+
+// rtlLanguages are the languages written right-to-left.
+var rtlLanguages = map[string]bool{
+	"ar":  true,
+	"arc": true,
+	"ckb": true,
+	"dv":  true,
+	"fa":  true,
+	"he":  true,
+	"iw":  true,
+	"ps":  true,
+	"sd":  true,
+	"ug":  true,
+	"ur":  true,
+	"yi":  true,
+}
+
+// isRTLRune returns whether r belongs to a right-to-left script.
+func isRTLRune(r rune) bool {
+	return unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// detectDirection returns the direction of the first strongly directional
+// character in text, defaulting to left-to-right.
+func detectDirection(text string) TextDirection {
+	for _, r := range text {
+		if isRTLRune(r) {
+			return DirectionRTL
+		}
+		if unicode.IsLetter(r) {
+			return DirectionLTR
+		}
+	}
+	return DirectionLTR
+}
+
+// textDirection returns the direction of text in the given language. When
+// the language isn't known the direction is detected from the text itself.
+func textDirection(text, language string) TextDirection {
+	if language == "" {
+		return detectDirection(text)
+	}
+
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	if rtlLanguages[base] {
+		return DirectionRTL
+	}
+
+	return DirectionLTR
+}
+
+// mark returns the Unicode mark for the direction.
+func (d TextDirection) mark() string {
+	if d == DirectionRTL {
+		return rightToLeftMark
+	}
+	return leftToRightMark
+}
+
+// markSpeaker returns the directional marks to place before and after a
+// speaker name rendered inline with text in the given direction. Names in
+// the opposite direction are followed by a mark so that the surrounding
+// punctuation isn't reordered along with them, and lines that either are
+// right-to-left or start with a right-to-left name get a leading mark so
+// that viewers pick the right base direction.
+func markSpeaker(speaker string, dir TextDirection) (string, string) {
+	var before, after string
+	speakerDir := detectDirection(speaker)
+	if dir == DirectionRTL || speakerDir != dir {
+		before = dir.mark()
+	}
+	if speakerDir != dir {
+		after = dir.mark()
+	}
+	return before, after
+}
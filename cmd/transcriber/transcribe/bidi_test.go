@@ -0,0 +1,54 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextDirection(t *testing.T) {
+	tcs := []struct {
+		name     string
+		text     string
+		language string
+		expected TextDirection
+	}{
+		{"latin", "Hello", "en", DirectionLTR},
+		{"arabic", "مرحبا", "ar", DirectionRTL},
+		{"hebrew with region", "שלום", "he-IL", DirectionRTL},
+		{"language wins over text", "Mattermost", "fa", DirectionRTL},
+		{"detected rtl", "123 مرحبا", "", DirectionRTL},
+		{"detected ltr", "123 Hello مرحبا", "", DirectionLTR},
+		{"no strong characters", "123", "", DirectionLTR},
+		{"empty", "", "", DirectionLTR},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, textDirection(tc.text, tc.language))
+		})
+	}
+}
+
+func TestMarkSpeaker(t *testing.T) {
+	tcs := []struct {
+		name           string
+		speaker        string
+		dir            TextDirection
+		expectedBefore string
+		expectedAfter  string
+	}{
+		{"ltr name in ltr line", "Alex", DirectionLTR, "", ""},
+		{"rtl name in rtl line", "עמית", DirectionRTL, rightToLeftMark, ""},
+		{"ltr name in rtl line", "Alex", DirectionRTL, rightToLeftMark, rightToLeftMark},
+		{"rtl name in ltr line", "عمر", DirectionLTR, leftToRightMark, leftToRightMark},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			before, after := markSpeaker(tc.speaker, tc.dir)
+			require.Equal(t, tc.expectedBefore, before)
+			require.Equal(t, tc.expectedAfter, after)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package transcribe
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// csvHeader names the columns of the CSV output.
+var csvHeader = []string{"speaker", "start_ms", "end_ms", "duration_ms", "text", "language", "confidence"}
+
+// CSV writes the segments of the transcription as CSV, one per row sorted by
+// start time, so that they can be loaded into databases and analytics tools.
+// The language falls back to the track's one, and both it and the confidence
+// are left empty if unknown.
+func (t Transcription) CSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	for _, s := range t.Interleave() {
+		s.sanitize()
+
+		var confidence string
+		if s.Confidence > 0 {
+			confidence = strconv.FormatFloat(s.Confidence, 'f', -1, 64)
+		}
+
+		err := cw.Write([]string{
+			s.Speaker,
+			strconv.FormatInt(s.StartTS, 10),
+			strconv.FormatInt(s.EndTS, 10),
+			strconv.FormatInt(s.EndTS-s.StartTS, 10),
+			s.Text,
+			s.segmentLanguage(),
+			confidence,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package transcribe
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSV(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		var b strings.Builder
+		require.NoError(t, tr.CSV(&b))
+		require.Equal(t, "speaker,start_ms,end_ms,duration_ms,text,language,confidence\n", b.String())
+	})
+
+	t.Run("segments", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:  "Alice",
+				Language: "en",
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1200, Text: ` Hello, "everyone"  `, Confidence: 0.92},
+					{StartTS: 3000, EndTS: 4500, Text: "Ciao", Language: "it"},
+				},
+			},
+			{
+				Speaker: "Bob",
+				Segments: []Segment{
+					{StartTS: 1500, EndTS: 2500, Text: "Hi\nthere", Confidence: 0.5},
+				},
+			},
+		}
+
+		var b strings.Builder
+		require.NoError(t, tr.CSV(&b))
+		require.Equal(t, `speaker,start_ms,end_ms,duration_ms,text,language,confidence
+Alice,0,1200,1200,"Hello, ""everyone""",en,0.92
+Bob,1500,2500,1000,Hi there,,0.5
+Alice,3000,4500,1500,Ciao,it,
+`, b.String())
+
+		// It can be read back.
+		records, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		require.Equal(t, `Hello, "everyone"`, records[1][4])
+	})
+}
@@ -0,0 +1,191 @@
+package transcribe
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// KeywordsMaxDefault is the default number of keywords extracted for the
+	// call and for each speaker.
+	KeywordsMaxDefault = 10
+	keywordMinLength   = 3
+)
+
+// Common English words that carry no meaning on their own. Transcriptions in
+// other languages will still work, relying on IDF only to discount frequent
+// terms.
+var keywordStopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		about above after again against all also and any are aren around because been before
+		being below between both but can cannot could couldn did didn does doesn doing don down
+		during each even few for from further get gets getting going gonna got had hadn has hasn
+		have haven having her here hers herself him himself his how into isn its itself just
+		know let like lot maybe more most much must mustn myself need not now off once one only
+		other our ours ourselves out over own really right said same say says see shan she should
+		shouldn so some such sure than that thats the their theirs them themselves then there
+		these they thing things think this those through too under until very want was wasn way
+		well were weren what when where which while who whom why will with won would wouldn yeah
+		yes yet you your yours yourself yourselves okay actually kind
+	`) {
+		keywordStopWords[w] = true
+	}
+}
+
+// Keywords holds the most relevant terms for the whole call and for each
+// speaker, sorted by relevance.
+type Keywords struct {
+	Call     []string            `json:"call"`
+	Speakers map[string][]string `json:"speakers"`
+}
+
+func tokenizeKeywords(text string) []string {
+	var tokens []string
+	for _, tok := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '\''
+	}) {
+		tok = strings.Trim(tok, "'")
+		if i := strings.IndexRune(tok, '\''); i > 0 {
+			// Drop contractions and possessives (e.g. "team's" -> "team").
+			tok = tok[:i]
+		}
+		if len([]rune(tok)) < keywordMinLength || keywordStopWords[tok] || isNumeric(tok) {
+			continue
+		}
+		tokens = append(tokens, tok)
+	}
+	return tokens
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if !unicode.IsNumber(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// tfidf scores the terms of each document and returns the top n terms for each.
+// IDF is smoothed so that terms still get a positive score when there's a
+// single document.
+func tfidf(docs [][]string, n int) [][]string {
+	df := make(map[string]int)
+	tfs := make([]map[string]int, len(docs))
+	for i, doc := range docs {
+		tfs[i] = make(map[string]int)
+		for _, term := range doc {
+			if tfs[i][term] == 0 {
+				df[term]++
+			}
+			tfs[i][term]++
+		}
+	}
+
+	top := make([][]string, len(docs))
+	for i, doc := range docs {
+		if len(doc) == 0 {
+			continue
+		}
+
+		type scoredTerm struct {
+			term  string
+			score float64
+		}
+		terms := make([]scoredTerm, 0, len(tfs[i]))
+		for term, count := range tfs[i] {
+			idf := math.Log(1 + float64(len(docs))/float64(df[term]))
+			terms = append(terms, scoredTerm{
+				term:  term,
+				score: float64(count) / float64(len(doc)) * idf,
+			})
+		}
+
+		sort.Slice(terms, func(a, b int) bool {
+			if terms[a].score == terms[b].score {
+				return terms[a].term < terms[b].term
+			}
+			return terms[a].score > terms[b].score
+		})
+
+		for j := 0; j < len(terms) && j < n; j++ {
+			top[i] = append(top[i], terms[j].term)
+		}
+	}
+
+	return top
+}
+
+// Keywords extracts up to n keywords for the whole call and for each speaker
+// using TF-IDF. Per speaker, each speaker's speech is a document so that terms
+// used by everyone are discounted. For the call, each segment is a document.
+func (t Transcription) Keywords(n int) Keywords {
+	kw := Keywords{
+		Speakers: make(map[string][]string),
+	}
+
+	var speakers []string
+	var speakerDocs [][]string
+	speakerIdx := make(map[string]int)
+	var segmentDocs [][]string
+	for _, trackTr := range t {
+		idx, ok := speakerIdx[trackTr.Speaker]
+		if !ok {
+			idx = len(speakers)
+			speakerIdx[trackTr.Speaker] = idx
+			speakers = append(speakers, trackTr.Speaker)
+			speakerDocs = append(speakerDocs, nil)
+		}
+
+		for _, s := range trackTr.Segments {
+			tokens := tokenizeKeywords(s.Text)
+			speakerDocs[idx] = append(speakerDocs[idx], tokens...)
+			segmentDocs = append(segmentDocs, tokens)
+		}
+	}
+
+	for i, terms := range tfidf(speakerDocs, n) {
+		if len(terms) > 0 {
+			kw.Speakers[speakers[i]] = terms
+		}
+	}
+
+	// For the call we merge the per-segment scores by summing them.
+	scores := make(map[string]float64)
+	df := make(map[string]int)
+	for _, doc := range segmentDocs {
+		seen := make(map[string]bool)
+		for _, term := range doc {
+			if !seen[term] {
+				seen[term] = true
+				df[term]++
+			}
+		}
+	}
+	for _, doc := range segmentDocs {
+		for _, term := range doc {
+			scores[term] += math.Log(1+float64(len(segmentDocs))/float64(df[term])) / float64(len(doc))
+		}
+	}
+
+	terms := make([]string, 0, len(scores))
+	for term := range scores {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(a, b int) bool {
+		if scores[terms[a]] == scores[terms[b]] {
+			return terms[a] < terms[b]
+		}
+		return scores[terms[a]] > scores[terms[b]]
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	kw.Call = terms
+
+	return kw
+}
@@ -0,0 +1,88 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizeKeywords(t *testing.T) {
+	tcs := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "stop words and short tokens",
+			input:    "So I think we should go with the new database.",
+			expected: []string{"new", "database"},
+		},
+		{
+			name:     "punctuation, numbers and contractions",
+			input:    "The team's release, v10, ships in 2024! Don't worry.",
+			expected: []string{"team", "release", "v10", "ships", "worry"},
+		},
+		{
+			name:     "non ASCII",
+			input:    "Réunion über Straße",
+			expected: []string{"réunion", "über", "straße"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tokenizeKeywords(tc.input))
+		})
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		kw := tr.Keywords(KeywordsMaxDefault)
+		require.Empty(t, kw.Call)
+		require.Empty(t, kw.Speakers)
+	})
+
+	t.Run("full", func(t *testing.T) {
+		tr := Transcription{
+			TrackTranscription{
+				Speaker: "SpeakerA",
+				Segments: []Segment{
+					{
+						Text: "The database migration is blocking the release.",
+					},
+					{
+						Text: "We need the database migration done first.",
+					},
+				},
+			},
+			TrackTranscription{
+				Speaker: "SpeakerB",
+				Segments: []Segment{
+					{
+						Text: "I can review the release notes and the changelog.",
+					},
+				},
+			},
+			TrackTranscription{
+				Speaker: "SpeakerA",
+				Segments: []Segment{
+					{
+						Text: "Database indexes too.",
+					},
+				},
+			},
+		}
+
+		kw := tr.Keywords(3)
+		require.Equal(t, []string{"database", "indexes", "migration"}, kw.Call)
+		require.Equal(t, map[string][]string{
+			"SpeakerA": {"database", "migration", "blocking"},
+			"SpeakerB": {"changelog", "notes", "review"},
+		}, kw.Speakers)
+	})
+}
@@ -0,0 +1,60 @@
+package transcribe
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Metadata describes the call a transcription belongs to, so that exported
+// files are self-describing.
+type Metadata struct {
+	Channel      string     `json:"channel,omitempty"`
+	StartTime    *time.Time `json:"start_time,omitempty"`
+	EndTime      *time.Time `json:"end_time,omitempty"`
+	Participants []string   `json:"participants,omitempty"`
+	Model        string     `json:"model,omitempty"`
+	Language     string     `json:"language,omitempty"`
+}
+
+// Lines returns the metadata as "Key: value" lines, skipping the fields that
+// aren't set.
+func (m Metadata) Lines() []string {
+	var lines []string
+	add := func(key, value string) {
+		// Values are single line so that they can't break out of the block
+		// they are written in.
+		value = strings.Join(strings.Fields(value), " ")
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", key, value))
+		}
+	}
+
+	add("Channel", m.Channel)
+	if m.StartTime != nil {
+		add("Start", m.StartTime.UTC().Format(time.RFC3339))
+	}
+	if m.EndTime != nil {
+		add("End", m.EndTime.UTC().Format(time.RFC3339))
+	}
+	add("Participants", strings.Join(m.Participants, ", "))
+	add("Model", m.Model)
+	add("Language", m.Language)
+
+	return lines
+}
+
+// Speakers returns the speakers of the transcription, in the order they
+// first appear in.
+func (t Transcription) Speakers() []string {
+	var speakers []string
+	seen := make(map[string]bool)
+	for _, trackTr := range t {
+		if trackTr.Speaker == "" || seen[trackTr.Speaker] {
+			continue
+		}
+		seen[trackTr.Speaker] = true
+		speakers = append(speakers, trackTr.Speaker)
+	}
+	return speakers
+}
@@ -0,0 +1,68 @@
+package transcribe
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataLines(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		require.Empty(t, Metadata{}.Lines())
+	})
+
+	t.Run("full", func(t *testing.T) {
+		m := Metadata{
+			Channel:      "channelID",
+			StartTime:    newTimeP(time.Date(2024, 5, 2, 10, 0, 0, 0, time.FixedZone("CEST", 2*3600))),
+			EndTime:      newTimeP(time.Date(2024, 5, 2, 8, 45, 30, 0, time.UTC)),
+			Participants: []string{"Alice", "Bob"},
+			Model:        "whisper.cpp (medium)",
+			Language:     "en",
+		}
+		require.Equal(t, []string{
+			"Channel: channelID",
+			"Start: 2024-05-02T08:00:00Z",
+			"End: 2024-05-02T08:45:30Z",
+			"Participants: Alice, Bob",
+			"Model: whisper.cpp (medium)",
+			"Language: en",
+		}, m.Lines())
+	})
+
+	t.Run("multiline values", func(t *testing.T) {
+		m := Metadata{Channel: "first\n\nsecond"}
+		require.Equal(t, []string{"Channel: first second"}, m.Lines())
+	})
+}
+
+func TestMetadataJSON(t *testing.T) {
+	data, err := json.Marshal(Metadata{
+		Channel:      "channelID",
+		StartTime:    newTimeP(time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)),
+		Participants: []string{"Alice"},
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"channel": "channelID",
+		"start_time": "2024-05-02T08:00:00Z",
+		"participants": ["Alice"]
+	}`, string(data))
+}
+
+func TestSpeakers(t *testing.T) {
+	tr := Transcription{
+		{Speaker: "Bob"},
+		{Speaker: "Alice"},
+		{Speaker: "Bob"},
+		{Speaker: ""},
+	}
+	require.Equal(t, []string{"Bob", "Alice"}, tr.Speakers())
+	require.Empty(t, Transcription{}.Speakers())
+}
+
+func newTimeP(t time.Time) *time.Time {
+	return &t
+}
@@ -8,16 +8,27 @@ import (
 
 var (
 	segmentSanitizationSpacesRE = regexp.MustCompile(`\s+`)
-	// We allow spaces, dots, dashes, underscores, digits and letters in both ASCII and foreign alphabets.
-	segmentSanitizationSpecialRE = regexp.MustCompile(`[^\s\d\pL\pN.\-_]`)
+	// We allow spaces, dots, dashes, underscores, square brackets (e.g. "[bot]"), digits and letters in both ASCII and foreign alphabets.
+	segmentSanitizationSpecialRE = regexp.MustCompile(`[^\s\d\pL\pN.\-_\[\]]`)
 )
 
-type namedSegment struct {
+// NamedSegment is a transcribed segment attributed to its speaker.
+type NamedSegment struct {
 	Segment
-	Speaker string
+	Speaker    string
+	ColorIndex int
+	// SpeakerIndex is the position of the speaker among those of the
+	// transcription, as returned by Speakers. Unlike ColorIndex, it's unique
+	// to the speaker.
+	SpeakerIndex int
+	// Direction is the direction the text is written in.
+	Direction TextDirection
+	// language is the language of the segment, falling back to the track's
+	// one. Unlike Segment.Language, it's only used to render text.
+	language string
 }
 
-func (ns *namedSegment) sanitize(escapers ...func(string) string) {
+func (ns *NamedSegment) sanitize(escapers ...func(string) string) {
 	// Remove unwanted special characters
 	ns.Speaker = segmentSanitizationSpecialRE.ReplaceAllString(ns.Speaker, "")
 
@@ -33,14 +44,33 @@ func (ns *namedSegment) sanitize(escapers ...func(string) string) {
 	}
 }
 
-func (t Transcription) interleave() []namedSegment {
-	var nss []namedSegment
+// segmentLanguage returns the language of the segment, if known.
+func (ns NamedSegment) segmentLanguage() string {
+	if ns.Language != "" {
+		return ns.Language
+	}
+	return ns.language
+}
+
+// Interleave merges the segments of all tracks into a single list, sorted by
+// start time.
+func (t Transcription) Interleave() []NamedSegment {
+	var nss []NamedSegment
+
+	speakerIndexes := make(map[string]int)
+	for i, speaker := range t.Speakers() {
+		speakerIndexes[speaker] = i
+	}
 
 	for _, trackTr := range t {
 		for _, s := range trackTr.Segments {
-			var ns namedSegment
+			var ns NamedSegment
 			ns.Segment = s
 			ns.Speaker = trackTr.Speaker
+			ns.ColorIndex = trackTr.ColorIndex
+			ns.SpeakerIndex = speakerIndexes[trackTr.Speaker]
+			ns.language = segmentLanguage(s, trackTr)
+			ns.Direction = textDirection(s.Text, ns.language)
 			nss = append(nss, ns)
 		}
 	}
@@ -51,3 +81,23 @@ func (t Transcription) interleave() []namedSegment {
 
 	return nss
 }
+
+// FilterSegments removes any text matching re from the segments. This is
+// meant to clean up recurrent junk outputs (e.g. "Subtitles by ...") that
+// models tend to hallucinate. Segments left with no text are dropped.
+func FilterSegments(segments []Segment, re *regexp.Regexp) []Segment {
+	if re == nil {
+		return segments
+	}
+
+	filtered := make([]Segment, 0, len(segments))
+	for _, s := range segments {
+		s.Text = re.ReplaceAllString(s.Text, "")
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+
+	return filtered
+}
@@ -1,6 +1,7 @@
 package transcribe
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 
@@ -36,8 +37,8 @@ func TestVTTTS(t *testing.T) {
 func TestInterleave(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		var tr Transcription
-		var ns []namedSegment
-		require.Equal(t, ns, tr.interleave())
+		var ns []NamedSegment
+		require.Equal(t, ns, tr.Interleave())
 	})
 
 	t.Run("ordered", func(t *testing.T) {
@@ -73,9 +74,10 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 		}
-		ns := []namedSegment{
+		ns := []NamedSegment{
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 0,
 					EndTS:   1,
@@ -83,7 +85,8 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 2,
 					EndTS:   3,
@@ -91,7 +94,9 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerB",
+				Speaker:      "SpeakerB",
+				SpeakerIndex: 1,
+				Direction:    DirectionLTR,
 				Segment: Segment{
 					StartTS: 4,
 					EndTS:   5,
@@ -99,7 +104,9 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerB",
+				Speaker:      "SpeakerB",
+				SpeakerIndex: 1,
+				Direction:    DirectionLTR,
 				Segment: Segment{
 					StartTS: 5,
 					EndTS:   6,
@@ -107,7 +114,7 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, ns, tr.interleave())
+		require.Equal(t, ns, tr.Interleave())
 	})
 
 	t.Run("unordered", func(t *testing.T) {
@@ -158,9 +165,10 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 		}
-		ns := []namedSegment{
+		ns := []NamedSegment{
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 0,
 					EndTS:   1,
@@ -168,7 +176,8 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 2,
 					EndTS:   3,
@@ -176,7 +185,9 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerB",
+				Speaker:      "SpeakerB",
+				SpeakerIndex: 1,
+				Direction:    DirectionLTR,
 				Segment: Segment{
 					StartTS: 3,
 					EndTS:   4,
@@ -184,7 +195,8 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 4,
 					EndTS:   5,
@@ -192,7 +204,8 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerA",
+				Speaker:   "SpeakerA",
+				Direction: DirectionLTR,
 				Segment: Segment{
 					StartTS: 5,
 					EndTS:   6,
@@ -200,7 +213,9 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 			{
-				Speaker: "SpeakerB",
+				Speaker:      "SpeakerB",
+				SpeakerIndex: 1,
+				Direction:    DirectionLTR,
 				Segment: Segment{
 					StartTS: 6,
 					EndTS:   7,
@@ -208,7 +223,7 @@ func TestInterleave(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, ns, tr.interleave())
+		require.Equal(t, ns, tr.Interleave())
 	})
 }
 
@@ -216,7 +231,7 @@ func TestWebVTT(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		var tr Transcription
 		var b strings.Builder
-		err := tr.WebVTT(&b, WebVTTOptions{})
+		err := tr.WebVTT(&b, WebVTTOptions{}, nil)
 		require.NoError(t, err)
 		require.Equal(t, "WEBVTT\n", b.String())
 	})
@@ -293,7 +308,7 @@ func TestWebVTT(t *testing.T) {
 `
 		err := tr.WebVTT(&b, WebVTTOptions{
 			OmitSpeaker: false,
-		})
+		}, nil)
 		require.NoError(t, err)
 		require.Equal(t, expected, b.String())
 	})
@@ -370,7 +385,7 @@ B2
 `
 		err := tr.WebVTT(&b, WebVTTOptions{
 			OmitSpeaker: true,
-		})
+		}, nil)
 		require.NoError(t, err)
 		require.Equal(t, expected, b.String())
 	})
@@ -397,17 +412,420 @@ B2
 `
 		err := tr.WebVTT(&b, WebVTTOptions{
 			OmitSpeaker: false,
-		})
+		}, nil)
 		require.NoError(t, err)
 		require.Equal(t, expected, b.String())
 	})
+
+	t.Run("speaker color classes", func(t *testing.T) {
+		tr := Transcription{
+			TrackTranscription{
+				Speaker:    "SpeakerA",
+				ColorIndex: 0,
+				Segments: []Segment{
+					{
+						StartTS: 0,
+						EndTS:   1000,
+						Text:    "A1",
+					},
+				},
+			},
+			TrackTranscription{
+				Speaker:    "SpeakerB",
+				ColorIndex: 1,
+				Segments: []Segment{
+					{
+						StartTS: 1000,
+						EndTS:   2000,
+						Text:    "B1",
+					},
+				},
+			},
+		}
+
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{
+			SpeakerColorClasses: true,
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:01.000
+<v.color0 SpeakerA>(SpeakerA) A1
+
+00:00:01.000 --> 00:00:02.000
+<v.color1 SpeakerB>(SpeakerB) B1
+`, b.String())
+
+		b.Reset()
+		err = tr.WebVTT(&b, WebVTTOptions{
+			OmitSpeaker:         true,
+			SpeakerColorClasses: true,
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:01.000
+<c.color0>A1</c>
+
+00:00:01.000 --> 00:00:02.000
+<c.color1>B1</c>
+`, b.String())
+	})
+
+	t.Run("rtl markers", func(t *testing.T) {
+		tr := newMixedDirectionTranscription()
+
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:01.000
+<v Dana>(Dana) مرحبا Mattermost
+
+00:00:01.000 --> 00:00:02.000
+<v عمر>(عمر) Hello
+
+00:00:02.000 --> 00:00:03.000
+<v נועה>(נועה) שלום
+
+00:00:03.000 --> 00:00:04.000
+<v Alex>(Alex) Hi
+`, b.String())
+
+		b.Reset()
+		err = tr.WebVTT(&b, WebVTTOptions{
+			RTLMarkers: true,
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\n00:00:00.000 --> 00:00:01.000\n<v Dana>\u200f(Dana)\u200f مرحبا Mattermost\n"+
+			"\n00:00:01.000 --> 00:00:02.000\n<v عمر>\u200e(عمر)\u200e Hello\n"+
+			"\n00:00:02.000 --> 00:00:03.000\n<v נועה>\u200f(נועה) שלום\n"+
+			"\n00:00:03.000 --> 00:00:04.000\n<v Alex>(Alex) Hi\n", b.String())
+
+		b.Reset()
+		err = tr.WebVTT(&b, WebVTTOptions{
+			OmitSpeaker: true,
+			RTLMarkers:  true,
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\n00:00:00.000 --> 00:00:01.000\n\u200fمرحبا Mattermost\n"+
+			"\n00:00:01.000 --> 00:00:02.000\nHello\n"+
+			"\n00:00:02.000 --> 00:00:03.000\n\u200fשלום\n"+
+			"\n00:00:03.000 --> 00:00:04.000\nHi\n", b.String())
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:  "Alice",
+				Segments: []Segment{{StartTS: 0, EndTS: 1000, Text: "Hello"}},
+			},
+		}
+
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{}, &Metadata{
+			Channel:      "channelID",
+			Participants: []string{"Alice", "Bob --> Carl"},
+			Language:     "en",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\nNOTE\nChannel: channelID\nParticipants: Alice, Bob -> Carl\nLanguage: en\n"+
+			"\n00:00:00.000 --> 00:00:01.000\n<v Alice>(Alice) Hello\n", b.String())
+
+		b.Reset()
+		err = tr.WebVTT(&b, WebVTTOptions{}, &Metadata{})
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n"+
+			"\n00:00:00.000 --> 00:00:01.000\n<v Alice>(Alice) Hello\n", b.String())
+	})
+}
+
+func TestWebVTTLineWrapping(t *testing.T) {
+	tr := Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []Segment{
+				{StartTS: 0, EndTS: 6000, Text: "This is a rather long sentence that doesn't fit & needs wrapping"},
+			},
+		},
+	}
+
+	t.Run("lines", func(t *testing.T) {
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{MaxLineLength: 32}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:06.000
+<v Alice>(Alice) This is a rather long
+sentence that doesn&#39;t fit &amp;
+needs wrapping
+`, b.String())
+	})
+
+	t.Run("cues", func(t *testing.T) {
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{MaxLineLength: 32, MaxLinesPerCue: 1}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:02.032
+<v Alice>(Alice) This is a rather long
+
+00:00:02.032 --> 00:00:04.064
+<v Alice>(Alice) sentence that doesn&#39;t
+
+00:00:04.064 --> 00:00:06.000
+<v Alice>(Alice) fit &amp; needs wrapping
+`, b.String())
+	})
+
+	t.Run("omit speaker", func(t *testing.T) {
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{OmitSpeaker: true, MaxLineLength: 32, MaxLinesPerCue: 2}, nil)
+		require.NoError(t, err)
+		require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:05.225
+This is a rather long sentence
+that doesn&#39;t fit &amp; needs
+
+00:00:05.225 --> 00:00:06.000
+wrapping
+`, b.String())
+	})
+}
+
+func TestWebVTTSpeakerStyles(t *testing.T) {
+	tr := Transcription{
+		{
+			Speaker:    "Alice",
+			ColorIndex: 1,
+			Segments:   []Segment{{StartTS: 0, EndTS: 1000, Text: "Hello"}},
+		},
+		{
+			Speaker:    "Bob \"the builder\"",
+			ColorIndex: 10,
+			Segments:   []Segment{{StartTS: 1000, EndTS: 2000, Text: "Hi"}},
+		},
+		{
+			Speaker:    "Alice",
+			ColorIndex: 1,
+			Segments:   []Segment{{StartTS: 2000, EndTS: 3000, Text: "Bye"}},
+		},
+	}
+
+	style := `STYLE
+::cue(v[voice="Alice"]) {
+  color: #FFFF00;
+}
+::cue(v[voice="Bob the builder"]) {
+  color: #00FFFF;
+}
+`
+
+	t.Run("speaker", func(t *testing.T) {
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{SpeakerStyles: true}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n"+style+`
+00:00:00.000 --> 00:00:01.000
+<v Alice>(Alice) Hello
+
+00:00:01.000 --> 00:00:02.000
+<v Bob the builder>(Bob the builder) Hi
+
+00:00:02.000 --> 00:00:03.000
+<v Alice>(Alice) Bye
+`, b.String())
+	})
+
+	t.Run("omit speaker", func(t *testing.T) {
+		var b strings.Builder
+		err := tr.WebVTT(&b, WebVTTOptions{SpeakerStyles: true, OmitSpeaker: true, SpeakerColorClasses: true}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n\n"+style+`
+00:00:00.000 --> 00:00:01.000
+<v.color1 Alice>Hello
+
+00:00:01.000 --> 00:00:02.000
+<v.color10 Bob the builder>Hi
+
+00:00:02.000 --> 00:00:03.000
+<v.color1 Alice>Bye
+`, b.String())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var b strings.Builder
+		err := Transcription{}.WebVTT(&b, WebVTTOptions{SpeakerStyles: true}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "WEBVTT\n", b.String())
+	})
+}
+
+func TestInterleaveSpeakerIndex(t *testing.T) {
+	tr := Transcription{
+		{Speaker: "Bob", ColorIndex: 3, Segments: []Segment{{StartTS: 1000, Text: "B1"}}},
+		{Speaker: "Alice", ColorIndex: 3, Segments: []Segment{{StartTS: 0, Text: "A1"}}},
+		{Speaker: "Bob", ColorIndex: 3, Segments: []Segment{{StartTS: 2000, Text: "B2"}}},
+	}
+
+	var indexes []int
+	for _, s := range tr.Interleave() {
+		indexes = append(indexes, s.SpeakerIndex)
+	}
+	require.Equal(t, []int{1, 0, 0}, indexes)
+}
+
+func TestWrapText(t *testing.T) {
+	width := func(int) int { return 10 }
+
+	require.Empty(t, wrapText("", width))
+	require.Equal(t, []string{"short"}, wrapText("short", width))
+	require.Equal(t, []string{"one two", "three four"}, wrapText("one two three four", width))
+	require.Equal(t, []string{"a", "verylongwor", "d b"}, wrapText("a verylongword b", func(line int) int {
+		return 10 + line%2
+	}))
+	require.Equal(t, []string{"これは日本語の文章で", "す"}, wrapText("これは日本語の文章です", width))
+}
+
+func TestWebVTTOptionsIsValid(t *testing.T) {
+	require.NoError(t, (&WebVTTOptions{}).IsValid())
+	require.NoError(t, (&WebVTTOptions{MaxLineLength: 42, MaxLinesPerCue: 2}).IsValid())
+	require.EqualError(t, (&WebVTTOptions{MaxLineLength: -1}).IsValid(), "MaxLineLength should not be negative")
+	require.EqualError(t, (&WebVTTOptions{MaxLineLength: 42, MaxLinesPerCue: -1}).IsValid(), "MaxLinesPerCue should not be negative")
+	require.EqualError(t, (&WebVTTOptions{MaxLinesPerCue: 2}).IsValid(), "MaxLinesPerCue requires MaxLineLength to be set")
+	require.EqualError(t, (&WebVTTOptions{MinCueDurationMs: -1}).IsValid(), "MinCueDurationMs should not be negative")
+	require.EqualError(t, (&WebVTTOptions{MinCueGapMs: -1}).IsValid(), "MinCueGapMs should not be negative")
+}
+
+func TestEnforceCueTimings(t *testing.T) {
+	seg := func(speaker string, startTS, endTS int64, text string) NamedSegment {
+		return NamedSegment{Segment: Segment{StartTS: startTS, EndTS: endTS, Text: text}, Speaker: speaker}
+	}
+
+	tcs := []struct {
+		name          string
+		segments      []NamedSegment
+		minDurationMs int64
+		minGapMs      int64
+		expected      []NamedSegment
+	}{
+		{
+			name:          "empty",
+			minDurationMs: 1000,
+			expected:      []NamedSegment{},
+		},
+		{
+			name: "merge with same speaker",
+			segments: []NamedSegment{
+				seg("Alice", 0, 100, "Yes."),
+				seg("Alice", 200, 300, "Sure."),
+				seg("Alice", 400, 2000, "Let's go."),
+				seg("Bob", 2500, 4000, "Ok."),
+			},
+			minDurationMs: 1000,
+			expected: []NamedSegment{
+				seg("Alice", 0, 2000, "Yes. Sure. Let's go."),
+				seg("Bob", 2500, 4000, "Ok."),
+			},
+		},
+		{
+			name: "extend up to next",
+			segments: []NamedSegment{
+				seg("Alice", 0, 100, "Yes."),
+				seg("Bob", 500, 700, "No."),
+				seg("Alice", 5000, 6000, "Why?"),
+			},
+			minDurationMs: 1000,
+			minGapMs:      100,
+			expected: []NamedSegment{
+				seg("Alice", 0, 400, "Yes."),
+				seg("Bob", 500, 1500, "No."),
+				seg("Alice", 5000, 6000, "Why?"),
+			},
+		},
+		{
+			name: "gap",
+			segments: []NamedSegment{
+				seg("Alice", 0, 1000, "Hello."),
+				seg("Bob", 1000, 2000, "Hi."),
+				seg("Alice", 1500, 3000, "How are you?"),
+				seg("Bob", 3050, 3080, "Good."),
+			},
+			minGapMs: 100,
+			expected: []NamedSegment{
+				seg("Alice", 0, 900, "Hello."),
+				seg("Bob", 1000, 2000, "Hi."),
+				seg("Alice", 1500, 2950, "How are you?"),
+				seg("Bob", 3050, 3080, "Good."),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, enforceCueTimings(tc.segments, tc.minDurationMs, tc.minGapMs))
+		})
+	}
+}
+
+func TestWebVTTMinCueDuration(t *testing.T) {
+	tr := Transcription{
+		{
+			Speaker: "Alice",
+			Segments: []Segment{
+				{StartTS: 0, EndTS: 150, Text: "Yes"},
+				{StartTS: 200, EndTS: 1500, Text: "that works"},
+			},
+		},
+	}
+
+	var b strings.Builder
+	err := tr.WebVTT(&b, WebVTTOptions{MinCueDurationMs: 200, MinCueGapMs: 50}, nil)
+	require.NoError(t, err)
+	require.Equal(t, `WEBVTT
+
+00:00:00.000 --> 00:00:01.500
+<v Alice>(Alice) Yes that works
+`, b.String())
+}
+
+func newMixedDirectionTranscription() Transcription {
+	return Transcription{
+		{
+			Speaker:  "Dana",
+			Language: "ar",
+			Segments: []Segment{{StartTS: 0, EndTS: 1000, Text: "مرحبا Mattermost"}},
+		},
+		{
+			Speaker:  "عمر",
+			Language: "en",
+			Segments: []Segment{{StartTS: 1000, EndTS: 2000, Text: "Hello"}},
+		},
+		{
+			Speaker:  "נועה",
+			Language: "he",
+			Segments: []Segment{{StartTS: 2000, EndTS: 3000, Text: "שלום"}},
+		},
+		{
+			Speaker:  "Alex",
+			Segments: []Segment{{StartTS: 3000, EndTS: 4000, Text: "Hi"}},
+		},
+	}
 }
 
 func TestText(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
 		var tr Transcription
 		var b strings.Builder
-		err := tr.Text(&b, TextOptions{})
+		err := tr.Text(&b, TextOptions{}, nil)
 		require.NoError(t, err)
 		require.Empty(t, b.String())
 	})
@@ -486,7 +904,7 @@ A4
 SpeakerB
 B2
 `
-		err := tr.Text(&b, TextOptions{})
+		err := tr.Text(&b, TextOptions{}, nil)
 		require.NoError(t, err)
 		require.Equal(t, expected, b.String())
 	})
@@ -562,30 +980,165 @@ B2
 				SilenceThresholdMs:   2000,
 				MaxSegmentDurationMs: 10000,
 			},
-		})
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, b.String())
+	})
+
+	t.Run("remove fillers", func(t *testing.T) {
+		tr := Transcription{
+			TrackTranscription{
+				Speaker: "SpeakerA",
+				Segments: []Segment{
+					{
+						StartTS: 0,
+						EndTS:   1000,
+						Text:    "Um, so I think, uh, we should ship it.",
+					},
+					{
+						StartTS: 1000,
+						EndTS:   2000,
+						Text:    "Uh...",
+					},
+				},
+			},
+			TrackTranscription{
+				Speaker: "SpeakerB",
+				Segments: []Segment{
+					{
+						StartTS: 2000,
+						EndTS:   3000,
+						Text:    "It's, you know, umbrella season.",
+					},
+				},
+			},
+		}
+
+		var b strings.Builder
+		expected := `00:00:00 -> 00:00:01
+SpeakerA
+so I think we should ship it.
+
+00:00:02 -> 00:00:03
+SpeakerB
+It's umbrella season.
+`
+		err := tr.Text(&b, TextOptions{
+			RemoveFillers: true,
+		}, nil)
 		require.NoError(t, err)
 		require.Equal(t, expected, b.String())
 	})
+
+	t.Run("rtl markers", func(t *testing.T) {
+		tr := newMixedDirectionTranscription()
+
+		var b strings.Builder
+		err := tr.Text(&b, TextOptions{
+			RTLMarkers: true,
+		}, nil)
+		require.NoError(t, err)
+		require.Equal(t, "00:00:00 -> 00:00:01\nDana\n\u200fمرحبا Mattermost\n"+
+			"\n00:00:01 -> 00:00:02\nعمر\nHello\n"+
+			"\n00:00:02 -> 00:00:03\nנועה\n\u200fשלום\n"+
+			"\n00:00:03 -> 00:00:04\nAlex\nHi\n", b.String())
+	})
+
+	t.Run("metadata", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:  "Alice",
+				Segments: []Segment{{StartTS: 0, EndTS: 1000, Text: "Hello"}},
+			},
+		}
+
+		var b strings.Builder
+		err := tr.Text(&b, TextOptions{}, &Metadata{
+			Channel: "channelID",
+			Model:   "whisper.cpp (medium)",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Channel: channelID\nModel: whisper.cpp (medium)\n"+
+			"\n00:00:00 -> 00:00:01\nAlice\nHello\n", b.String())
+	})
+}
+
+func TestRemoveFillers(t *testing.T) {
+	tcs := []struct {
+		name     string
+		fillers  []string
+		input    string
+		expected string
+	}{
+		{
+			name:     "no fillers",
+			input:    "Hello world.",
+			expected: "Hello world.",
+		},
+		{
+			name:     "leading filler",
+			input:    "Um, hello world.",
+			expected: "hello world.",
+		},
+		{
+			name:     "mid sentence",
+			input:    "Hello, uh, world.",
+			expected: "Hello world.",
+		},
+		{
+			name:     "multi word filler",
+			input:    "It is, you  know, fine.",
+			expected: "It is fine.",
+		},
+		{
+			name:     "partial word match",
+			input:    "The umpire was hmmm.",
+			expected: "The umpire was hmmm.",
+		},
+		{
+			name:     "custom fillers",
+			fillers:  []string{"like"},
+			input:    "It was, like, um, great.",
+			expected: "It was um, great.",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			segments := removeFillers([]NamedSegment{{Segment: Segment{Text: tc.input}}}, tc.fillers)
+			require.Len(t, segments, 1)
+			require.Equal(t, tc.expected, strings.TrimSpace(segments[0].Text))
+		})
+	}
+
+	t.Run("empty segments dropped", func(t *testing.T) {
+		segments := removeFillers([]NamedSegment{
+			{Segment: Segment{Text: "Um."}},
+			{Segment: Segment{Text: "Hello"}},
+		}, nil)
+		require.Len(t, segments, 1)
+		require.Equal(t, "Hello", segments[0].Text)
+	})
 }
 
 func TestSanitizeSegment(t *testing.T) {
 	tcs := []struct {
 		name     string
-		input    namedSegment
-		expected namedSegment
+		input    NamedSegment
+		expected NamedSegment
 	}{
 		{
 			name: "empty",
 		},
 		{
 			name: "plaintext",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "some sentence.",
 				},
 				Speaker: "Firstname Lastname",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "some sentence.",
 				},
@@ -594,13 +1147,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "multiple spaces",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "   some   sentence with  multiple spaces.  ",
 				},
 				Speaker: "Firstname   Lastname",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "some sentence with multiple spaces.",
 				},
@@ -609,13 +1162,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "new lines",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "sentence with new\r \n\r\n lines\n\n.\n\n\n",
 				},
 				Speaker: "Firstname\n\nLastname",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "sentence with new lines .",
 				},
@@ -624,13 +1177,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "dots",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence.",
 				},
 				Speaker: "Firstname Lastname Jr.",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence.",
 				},
@@ -639,13 +1192,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "dashes and underscores",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
 				Speaker: "Firstname_Last-name",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
@@ -654,13 +1207,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "parentheses",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "(test sentence)",
 				},
 				Speaker: "(Firstname Lastname)",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "(test sentence)",
 				},
@@ -669,13 +1222,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "digits",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test 45",
 				},
 				Speaker: "Firstname45 Lastname",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test 45",
 				},
@@ -684,13 +1237,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "unicode",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
 				Speaker: "Firstname 🦄 Lastname",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
@@ -699,13 +1252,13 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "foreign alphabet characters",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
 				Speaker: "うずまき ナルト",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
@@ -714,19 +1267,34 @@ func TestSanitizeSegment(t *testing.T) {
 		},
 		{
 			name: "foreign alphabet digits",
-			input: namedSegment{
+			input: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
 				Speaker: "٣ ٢",
 			},
-			expected: namedSegment{
+			expected: NamedSegment{
 				Segment: Segment{
 					Text: "test sentence",
 				},
 				Speaker: "٣ ٢",
 			},
 		},
+		{
+			name: "bot label",
+			input: NamedSegment{
+				Segment: Segment{
+					Text: "test sentence",
+				},
+				Speaker: "Recorder <Bot> [bot]",
+			},
+			expected: NamedSegment{
+				Segment: Segment{
+					Text: "test sentence",
+				},
+				Speaker: "Recorder Bot [bot]",
+			},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -739,18 +1307,18 @@ func TestSanitizeSegment(t *testing.T) {
 
 func TestCompact(t *testing.T) {
 	t.Run("empty", func(t *testing.T) {
-		require.Empty(t, compactSegments(nil, TextCompactOptions{
+		require.Empty(t, Compact(nil, TextCompactOptions{
 			SilenceThresholdMs:   1000,
 			MaxSegmentDurationMs: 1000,
 		}))
-		require.Empty(t, compactSegments([]namedSegment{}, TextCompactOptions{
+		require.Empty(t, Compact([]NamedSegment{}, TextCompactOptions{
 			SilenceThresholdMs:   1000,
 			MaxSegmentDurationMs: 1000,
 		}))
 	})
 
 	t.Run("single segment", func(t *testing.T) {
-		segments := []namedSegment{
+		segments := []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -760,14 +1328,14 @@ func TestCompact(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, segments, compactSegments(segments, TextCompactOptions{
+		require.Equal(t, segments, Compact(segments, TextCompactOptions{
 			SilenceThresholdMs:   1000,
 			MaxSegmentDurationMs: 1000,
 		}))
 	})
 
 	t.Run("single speaker", func(t *testing.T) {
-		segments := []namedSegment{
+		segments := []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -793,7 +1361,7 @@ func TestCompact(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, []namedSegment{
+		require.Equal(t, []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -802,14 +1370,14 @@ func TestCompact(t *testing.T) {
 					Text:    "test1 test2 test3",
 				},
 			},
-		}, compactSegments(segments, TextCompactOptions{
+		}, Compact(segments, TextCompactOptions{
 			SilenceThresholdMs:   1000,
 			MaxSegmentDurationMs: 1000,
 		}))
 	})
 
 	t.Run("silence threshold", func(t *testing.T) {
-		segments := []namedSegment{
+		segments := []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -835,7 +1403,7 @@ func TestCompact(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, []namedSegment{
+		require.Equal(t, []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -852,14 +1420,14 @@ func TestCompact(t *testing.T) {
 					Text:    "test3",
 				},
 			},
-		}, compactSegments(segments, TextCompactOptions{
+		}, Compact(segments, TextCompactOptions{
 			SilenceThresholdMs:   100,
 			MaxSegmentDurationMs: 200,
 		}))
 	})
 
 	t.Run("max duration", func(t *testing.T) {
-		segments := []namedSegment{
+		segments := []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -885,7 +1453,7 @@ func TestCompact(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, []namedSegment{
+		require.Equal(t, []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -902,14 +1470,14 @@ func TestCompact(t *testing.T) {
 					Text:    "test3",
 				},
 			},
-		}, compactSegments(segments, TextCompactOptions{
+		}, Compact(segments, TextCompactOptions{
 			SilenceThresholdMs:   100,
 			MaxSegmentDurationMs: 200,
 		}))
 	})
 
 	t.Run("speaker change", func(t *testing.T) {
-		segments := []namedSegment{
+		segments := []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -951,7 +1519,7 @@ func TestCompact(t *testing.T) {
 				},
 			},
 		}
-		require.Equal(t, []namedSegment{
+		require.Equal(t, []NamedSegment{
 			{
 				Speaker: "A",
 				Segment: Segment{
@@ -976,9 +1544,108 @@ func TestCompact(t *testing.T) {
 					Text:    "testA3",
 				},
 			},
-		}, compactSegments(segments, TextCompactOptions{
+		}, Compact(segments, TextCompactOptions{
 			SilenceThresholdMs:   1000,
 			MaxSegmentDurationMs: 1000,
 		}))
 	})
+
+	t.Run("unspaced language", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:  "A",
+				Language: "ja",
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 100, Text: "こんにちは。"},
+					{StartTS: 100, EndTS: 200, Text: "今日はいい天気ですね。"},
+					{StartTS: 200, EndTS: 300, Text: "Mattermost"},
+					{StartTS: 300, EndTS: 400, Text: "を使っています。"},
+				},
+			},
+			{
+				Speaker:  "B",
+				Language: "en",
+				Segments: []Segment{
+					{StartTS: 1000, EndTS: 1100, Text: "Hello."},
+					{StartTS: 1100, EndTS: 1200, Text: "How are you?"},
+				},
+			},
+		}
+
+		out := Compact(tr.Interleave(), TextCompactOptions{
+			SilenceThresholdMs:   1000,
+			MaxSegmentDurationMs: 1000,
+		})
+		require.Len(t, out, 2)
+		require.Equal(t, "こんにちは。今日はいい天気ですね。Mattermostを使っています。", out[0].Text)
+		require.Equal(t, "Hello. How are you?", out[1].Text)
+	})
+}
+
+func TestJoinText(t *testing.T) {
+	tcs := []struct {
+		name     string
+		prev     string
+		next     string
+		language string
+		expected string
+	}{
+		{"latin", "Hello.", "How are you?", "en", "Hello. How are you?"},
+		{"chinese", "你好。", "今天天气很好。", "zh", "你好。今天天气很好。"},
+		{"chinese with region", "你好", "世界", "zh-TW", "你好世界"},
+		{"japanese with latin word", "これは", "Mattermost", "ja", "これはMattermost"},
+		{"thai", "สวัสดี", "ครับ", "th", "สวัสดีครับ"},
+		{"latin words in unspaced language", "Mattermost", "Calls", "ja", "Mattermost Calls"},
+		{"unspaced script in spaced language", "東京", "大阪", "en", "東京 大阪"},
+		{"unknown language", "東京", "大阪", "", "東京大阪"},
+		{"unknown language mixed script", "東京", "Tokyo", "", "東京 Tokyo"},
+		{"surrounding spaces", "你好 ", " 世界", "zh", "你好世界"},
+		{"empty", "", "test", "en", "test"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, joinText(tc.prev, tc.next, tc.language))
+		})
+	}
+}
+
+func TestFilterSegments(t *testing.T) {
+	segments := []Segment{
+		{
+			StartTS: 0,
+			EndTS:   1000,
+			Text:    " Hello everyone.",
+		},
+		{
+			StartTS: 1000,
+			EndTS:   2000,
+			Text:    " Subtitles by the Amara.org community",
+		},
+		{
+			StartTS: 2000,
+			EndTS:   3000,
+			Text:    " Let's start. Thanks for watching!",
+		},
+	}
+
+	t.Run("nil regexp", func(t *testing.T) {
+		require.Equal(t, segments, FilterSegments(segments, nil))
+	})
+
+	t.Run("filtering", func(t *testing.T) {
+		re := regexp.MustCompile(`(?i)\s*(subtitles by .*|thanks for watching!?)`)
+		require.Equal(t, []Segment{
+			{
+				StartTS: 0,
+				EndTS:   1000,
+				Text:    " Hello everyone.",
+			},
+			{
+				StartTS: 2000,
+				EndTS:   3000,
+				Text:    " Let's start.",
+			},
+		}, FilterSegments(segments, re))
+	})
 }
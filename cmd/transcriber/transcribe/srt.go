@@ -0,0 +1,36 @@
+package transcribe
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// srtTS converts ts milliseconds in the 00:00:00,000 format.
+func srtTS(ts int64) string {
+	return strings.Replace(vttTS(ts, true), ".", ",", 1)
+}
+
+// srtText escapes text so that it can't be mistaken for cue timings.
+func srtText(text string) string {
+	return strings.ReplaceAll(text, "-->", "->")
+}
+
+// SRT writes the transcription as SubRip subtitles, the format most widely
+// supported by video players and editors.
+func (t Transcription) SRT(w io.Writer) error {
+	for i, s := range t.Interleave() {
+		s.sanitize(srtText)
+
+		nl := "\n"
+		if i == 0 {
+			nl = ""
+		}
+		_, err := fmt.Fprintf(w, "%s%d\n%s --> %s\n(%s) %s\n", nl, i+1, srtTS(s.StartTS), srtTS(s.EndTS), s.Speaker, s.Text)
+		if err != nil {
+			return fmt.Errorf("failed to write: %w", err)
+		}
+	}
+
+	return nil
+}
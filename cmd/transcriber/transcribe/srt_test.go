@@ -0,0 +1,57 @@
+package transcribe
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSRTTS(t *testing.T) {
+	require.Equal(t, "00:00:00,000", srtTS(0))
+	require.Equal(t, "00:00:00,999", srtTS(999))
+	require.Equal(t, "00:01:02,200", srtTS(62200))
+	require.Equal(t, "01:45:45,045", srtTS(6345045))
+}
+
+func TestSRT(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		var b strings.Builder
+		require.NoError(t, tr.SRT(&b))
+		require.Empty(t, b.String())
+	})
+
+	t.Run("speakers", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker: "Alice",
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1000, Text: " Hello  there "},
+					{StartTS: 3000, EndTS: 4500, Text: "A --> B"},
+				},
+			},
+			{
+				Speaker: "Bob",
+				Segments: []Segment{
+					{StartTS: 1500, EndTS: 2500, Text: "Hi"},
+				},
+			},
+		}
+
+		var b strings.Builder
+		require.NoError(t, tr.SRT(&b))
+		require.Equal(t, `1
+00:00:00,000 --> 00:00:01,000
+(Alice) Hello there
+
+2
+00:00:01,500 --> 00:00:02,500
+(Bob) Hi
+
+3
+00:00:03,000 --> 00:00:04,500
+(Alice) A -> B
+`, b.String())
+	})
+}
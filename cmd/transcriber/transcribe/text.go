@@ -5,7 +5,21 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultFillers are the filler tokens removed from the text output when
+// RemoveFillers is set and no custom list is given.
+var DefaultFillers = []string{"um", "uh", "erm", "hmm", "you know"}
+
+var (
+	fillerSpaceBeforePunctRE = regexp.MustCompile(`\s+([,.;:!?])`)
+	fillerLeadingPunctRE     = regexp.MustCompile(`^[\s,.;:!?]+`)
+	fillerMultiSpaceRE       = regexp.MustCompile(`\s{2,}`)
 )
 
 type TextCompactOptions struct {
@@ -24,6 +38,14 @@ func (o *TextCompactOptions) IsEmpty() bool {
 
 type TextOptions struct {
 	CompactOptions TextCompactOptions
+	// RemoveFillers strips filler tokens (e.g. "um", "uh") from the text
+	// after compacting.
+	RemoveFillers bool
+	// Fillers overrides DefaultFillers.
+	Fillers []string
+	// RTLMarkers prefixes right-to-left text with a Unicode directional mark
+	// so that it renders correctly regardless of what it starts with.
+	RTLMarkers bool
 }
 
 func (o *TextOptions) SetDefaults() {
@@ -39,6 +61,12 @@ func (o *TextOptions) IsValid() error {
 		return fmt.Errorf("MaxSegmentDurationMs should be a positive number")
 	}
 
+	for _, filler := range o.Fillers {
+		if strings.TrimSpace(filler) == "" {
+			return fmt.Errorf("Fillers should not contain empty values")
+		}
+	}
+
 	return nil
 }
 
@@ -47,21 +75,35 @@ func (o *TextOptions) IsEmpty() bool {
 }
 
 func (o *TextOptions) ToEnv() []string {
-	return []string{
+	vars := []string{
 		fmt.Sprintf("TEXT_COMPACT_SILENCE_THRESHOLD_MS=%d", o.CompactOptions.SilenceThresholdMs),
 		fmt.Sprintf("TEXT_COMPACT_MAX_SEGMENT_DURATION_MS=%d", o.CompactOptions.MaxSegmentDurationMs),
+		fmt.Sprintf("TEXT_REMOVE_FILLERS=%t", o.RemoveFillers),
+		fmt.Sprintf("TEXT_RTL_MARKERS=%t", o.RTLMarkers),
 	}
+
+	if len(o.Fillers) > 0 {
+		vars = append(vars, fmt.Sprintf("TEXT_FILLERS=%s", strings.Join(o.Fillers, ",")))
+	}
+
+	return vars
 }
 
 func (o *TextOptions) FromEnv() {
 	o.CompactOptions.SilenceThresholdMs, _ = strconv.Atoi(os.Getenv("TEXT_COMPACT_SILENCE_THRESHOLD_MS"))
 	o.CompactOptions.MaxSegmentDurationMs, _ = strconv.Atoi(os.Getenv("TEXT_COMPACT_MAX_SEGMENT_DURATION_MS"))
+	o.RemoveFillers, _ = strconv.ParseBool(os.Getenv("TEXT_REMOVE_FILLERS"))
+	o.Fillers = parseFillers(os.Getenv("TEXT_FILLERS"))
+	o.RTLMarkers, _ = strconv.ParseBool(os.Getenv("TEXT_RTL_MARKERS"))
 }
 
 func (o *TextOptions) ToMap() map[string]any {
 	return map[string]any{
 		"text_compact_silence_threshold_ms":    o.CompactOptions.SilenceThresholdMs,
 		"text_compact_max_segment_duration_ms": o.CompactOptions.MaxSegmentDurationMs,
+		"text_remove_fillers":                  o.RemoveFillers,
+		"text_fillers":                         strings.Join(o.Fillers, ","),
+		"text_rtl_markers":                     o.RTLMarkers,
 	}
 }
 
@@ -81,14 +123,120 @@ func (o *TextOptions) FromMap(m map[string]any) {
 	case float64:
 		o.CompactOptions.MaxSegmentDurationMs = int(m["text_compact_max_segment_duration_ms"].(float64))
 	}
+
+	o.RemoveFillers, _ = m["text_remove_fillers"].(bool)
+	fillers, _ := m["text_fillers"].(string)
+	o.Fillers = parseFillers(fillers)
+	o.RTLMarkers, _ = m["text_rtl_markers"].(bool)
+}
+
+// parseFillers parses a comma separated list of filler tokens.
+func parseFillers(val string) []string {
+	var fillers []string
+	for _, filler := range strings.Split(val, ",") {
+		if filler = strings.TrimSpace(filler); filler != "" {
+			fillers = append(fillers, filler)
+		}
+	}
+	return fillers
+}
+
+// newFillersRE returns a regular expression matching any of the given filler
+// tokens as whole words (case insensitive), along with any surrounding commas.
+func newFillersRE(fillers []string) *regexp.Regexp {
+	alts := make([]string, 0, len(fillers))
+	for _, filler := range fillers {
+		// Multi-word fillers (e.g. "you know") can be separated by any whitespace.
+		words := strings.Fields(regexp.QuoteMeta(filler))
+		if len(words) == 0 {
+			continue
+		}
+		alts = append(alts, strings.Join(words, `\s+`))
+	}
+	return regexp.MustCompile(`(?i),?\s*\b(?:` + strings.Join(alts, "|") + `)\b,?`)
+}
+
+// removeFillers strips filler tokens from the segments' text. Segments that are
+// left with no text are dropped.
+func removeFillers(segments []NamedSegment, fillers []string) []NamedSegment {
+	if len(fillers) == 0 {
+		fillers = DefaultFillers
+	}
+	re := newFillersRE(fillers)
+
+	out := make([]NamedSegment, 0, len(segments))
+	for _, s := range segments {
+		s.Text = re.ReplaceAllString(s.Text, " ")
+		s.Text = fillerMultiSpaceRE.ReplaceAllString(s.Text, " ")
+		s.Text = fillerSpaceBeforePunctRE.ReplaceAllString(s.Text, "$1")
+		s.Text = fillerLeadingPunctRE.ReplaceAllString(s.Text, "")
+		if strings.TrimSpace(s.Text) == "" {
+			continue
+		}
+		out = append(out, s)
+	}
+
+	return out
+}
+
+// unspacedLanguages are the languages whose words aren't separated by
+// spaces.
+var unspacedLanguages = map[string]bool{
+	"zh":  true,
+	"yue": true,
+	"ja":  true,
+	"th":  true,
+	"lo":  true,
+	"my":  true,
+	"km":  true,
+	"bo":  true,
+}
+
+// isUnspacedRune returns whether r belongs to a script written without
+// spaces between words, including CJK punctuation.
+func isUnspacedRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai,
+		unicode.Lao, unicode.Myanmar, unicode.Khmer, unicode.Tibetan) ||
+		// CJK symbols and punctuation, and full-width forms.
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// joinText joins the text of two consecutive segments in the given
+// language. Languages written without spaces (e.g. Japanese, Chinese) are
+// joined without one, unless the text on either side of the join isn't in
+// their script (e.g. an English word). If the language isn't known the
+// script alone decides.
+func joinText(prev, next, language string) string {
+	prev = strings.TrimRightFunc(prev, unicode.IsSpace)
+	next = strings.TrimLeftFunc(next, unicode.IsSpace)
+	if prev == "" || next == "" {
+		return prev + next
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	if unspacedLanguages[base] && (isUnspacedRune(last) || isUnspacedRune(first)) {
+		return prev + next
+	}
+	if base == "" && isUnspacedRune(last) && isUnspacedRune(first) {
+		return prev + next
+	}
+
+	return prev + " " + next
 }
 
-func compactSegments(segments []namedSegment, opts TextCompactOptions) []namedSegment {
+// Compact joins consecutive segments from the same speaker that are separated
+// by less than opts.SilenceThresholdMs, as long as the joined segment spans
+// less than opts.MaxSegmentDurationMs. The input is expected to be sorted by
+// start time, as returned by Interleave.
+func Compact(segments []NamedSegment, opts TextCompactOptions) []NamedSegment {
 	if len(segments) < 2 {
 		return segments
 	}
 
-	out := []namedSegment{segments[0]}
+	out := []NamedSegment{segments[0]}
 
 	for i := 1; i < len(segments); i++ {
 		currSeg := segments[i]
@@ -103,7 +251,7 @@ func compactSegments(segments []namedSegment, opts TextCompactOptions) []namedSe
 			int(currSeg.StartTS-out[len(out)-1].StartTS) < opts.MaxSegmentDurationMs {
 
 			slog.Debug(fmt.Sprintf("%d and %d can be joined", i-1, i))
-			out[len(out)-1].Text += " " + currSeg.Text
+			out[len(out)-1].Text = joinText(out[len(out)-1].Text, currSeg.Text, currSeg.segmentLanguage())
 			out[len(out)-1].EndTS = currSeg.EndTS
 		} else {
 			out = append(out, currSeg)
@@ -115,25 +263,45 @@ func compactSegments(segments []namedSegment, opts TextCompactOptions) []namedSe
 	return out
 }
 
-func (t Transcription) Text(w io.Writer, opts TextOptions) error {
-	segments := t.interleave()
+// Text writes the transcription as plain text. The call metadata, if given,
+// is written as a header block before the text.
+func (t Transcription) Text(w io.Writer, opts TextOptions, meta *Metadata) error {
+	segments := t.Interleave()
 
 	if !opts.CompactOptions.IsEmpty() {
-		segments = compactSegments(segments, opts.CompactOptions)
+		segments = Compact(segments, opts.CompactOptions)
+	}
+
+	if opts.RemoveFillers {
+		segments = removeFillers(segments, opts.Fillers)
+	}
+
+	var header bool
+	if meta != nil {
+		if lines := meta.Lines(); len(lines) > 0 {
+			if _, err := fmt.Fprintf(w, "%s\n", strings.Join(lines, "\n")); err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+			header = true
+		}
 	}
 
 	for i, s := range segments {
 		s.sanitize()
 
 		nl := "\n"
-		if i == 0 {
+		if i == 0 && !header {
 			nl = ""
 		}
 		_, err := fmt.Fprintf(w, "%s%v -> %v\n", nl, vttTS(s.StartTS, false), vttTS(s.EndTS, false))
 		if err != nil {
 			return fmt.Errorf("failed to write: %w", err)
 		}
-		_, err = fmt.Fprintf(w, "%s\n%s\n", s.Speaker, s.Text)
+		text := s.Text
+		if opts.RTLMarkers && s.Direction == DirectionRTL {
+			text = s.Direction.mark() + text
+		}
+		_, err = fmt.Fprintf(w, "%s\n%s\n", s.Speaker, text)
 		if err != nil {
 			return fmt.Errorf("failed to write: %w", err)
 		}
@@ -1,5 +1,9 @@
 package transcribe
 
+import (
+	"sort"
+)
+
 const DefaultLanguage = "en"
 
 type Transcriber interface {
@@ -11,18 +15,31 @@ type Segment struct {
 	Text    string
 	StartTS int64
 	EndTS   int64
+	// Language is the language detected for the segment, if known.
+	Language string
+	// Confidence is the average probability, in the range (0, 1], of the
+	// segment's tokens. Zero if the API doesn't provide it.
+	Confidence float64
 }
 
 type TrackTranscription struct {
 	Speaker  string
 	Language string
 	Segments []Segment
+	// ColorIndex is a stable per-speaker index clients can use to
+	// consistently style the speaker's captions.
+	ColorIndex int
 }
 
 type Transcription []TrackTranscription
 
 func (tr Transcription) Language() string {
-	// Here we make a reasonable assumption. That the language of the
+	// When languages are known per segment we go with the dominant one.
+	if langs := tr.Languages(); len(langs) > 0 {
+		return langs[0].Language
+	}
+
+	// Otherwise we make a reasonable assumption. That the language of the
 	// transcription is equal to the first detected language. We default to
 	// English if none is found.
 	for _, t := range tr {
@@ -32,3 +49,91 @@ func (tr Transcription) Language() string {
 	}
 	return DefaultLanguage
 }
+
+// LanguageShare is the proportion of speech in a given language.
+type LanguageShare struct {
+	Language string  `json:"language"`
+	Share    float64 `json:"share"`
+}
+
+// segmentLanguage returns the language of the segment, falling back to the
+// language of the track it belongs to.
+func segmentLanguage(s Segment, trackTr TrackTranscription) string {
+	if s.Language != "" {
+		return s.Language
+	}
+	return trackTr.Language
+}
+
+// Languages returns the languages detected in the transcription along with
+// their proportion of the total speech duration, sorted by share in
+// descending order. Segments with no known language are not accounted for.
+func (tr Transcription) Languages() []LanguageShare {
+	durs := make(map[string]int64)
+	var total int64
+	for _, trackTr := range tr {
+		for _, s := range trackTr.Segments {
+			lang := segmentLanguage(s, trackTr)
+			if lang == "" {
+				continue
+			}
+			// Zero-length segments still count so that a language is never lost.
+			dur := max(1, s.EndTS-s.StartTS)
+			durs[lang] += dur
+			total += dur
+		}
+	}
+
+	shares := make([]LanguageShare, 0, len(durs))
+	for lang, dur := range durs {
+		shares = append(shares, LanguageShare{
+			Language: lang,
+			Share:    float64(dur) / float64(total),
+		})
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		if shares[i].Share == shares[j].Share {
+			return shares[i].Language < shares[j].Language
+		}
+		return shares[i].Share > shares[j].Share
+	})
+
+	return shares
+}
+
+// SplitByLanguage splits the transcription into one transcription per
+// detected language, in the same order returned by Languages. Segments with
+// no known language go with the dominant language.
+func (tr Transcription) SplitByLanguage() []Transcription {
+	langs := tr.Languages()
+	if len(langs) < 2 {
+		return []Transcription{tr}
+	}
+
+	idx := make(map[string]int, len(langs))
+	for i, l := range langs {
+		idx[l.Language] = i
+	}
+
+	splits := make([]Transcription, len(langs))
+	for _, trackTr := range tr {
+		trackTrs := make([]TrackTranscription, len(langs))
+		for _, s := range trackTr.Segments {
+			i := idx[segmentLanguage(s, trackTr)]
+			trackTrs[i].Segments = append(trackTrs[i].Segments, s)
+		}
+
+		for i := range trackTrs {
+			if len(trackTrs[i].Segments) == 0 {
+				continue
+			}
+			trackTrs[i].Speaker = trackTr.Speaker
+			trackTrs[i].ColorIndex = trackTr.ColorIndex
+			trackTrs[i].Language = langs[i].Language
+			splits[i] = append(splits[i], trackTrs[i])
+		}
+	}
+
+	return splits
+}
@@ -0,0 +1,92 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscriptionLanguages(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		require.Empty(t, tr.Languages())
+		require.Equal(t, DefaultLanguage, tr.Language())
+		require.Equal(t, []Transcription{tr}, tr.SplitByLanguage())
+	})
+
+	t.Run("track language only", func(t *testing.T) {
+		tr := Transcription{
+			TrackTranscription{
+				Speaker:  "SpeakerA",
+				Language: "it",
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1000, Text: "A1"},
+				},
+			},
+		}
+		require.Equal(t, []LanguageShare{{Language: "it", Share: 1}}, tr.Languages())
+		require.Equal(t, "it", tr.Language())
+	})
+
+	t.Run("multilingual", func(t *testing.T) {
+		tr := Transcription{
+			TrackTranscription{
+				Speaker:    "SpeakerA",
+				Language:   "en",
+				ColorIndex: 1,
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1000, Text: "A1", Language: "en"},
+					{StartTS: 1000, EndTS: 4000, Text: "A2", Language: "es"},
+				},
+			},
+			TrackTranscription{
+				Speaker: "SpeakerB",
+				Segments: []Segment{
+					{StartTS: 4000, EndTS: 6000, Text: "B1", Language: "es"},
+					{StartTS: 6000, EndTS: 8000, Text: "B2"},
+				},
+			},
+		}
+
+		// B2 has no known language so it's not accounted for.
+		langs := tr.Languages()
+		require.Len(t, langs, 2)
+		require.Equal(t, "es", langs[0].Language)
+		require.InDelta(t, 5.0/6.0, langs[0].Share, 0.0001)
+		require.Equal(t, "en", langs[1].Language)
+		require.InDelta(t, 1.0/6.0, langs[1].Share, 0.0001)
+		require.Equal(t, "es", tr.Language())
+
+		// Segments with no known language go with the dominant language.
+		require.Equal(t, []Transcription{
+			{
+				TrackTranscription{
+					Speaker:    "SpeakerA",
+					Language:   "es",
+					ColorIndex: 1,
+					Segments: []Segment{
+						{StartTS: 1000, EndTS: 4000, Text: "A2", Language: "es"},
+					},
+				},
+				TrackTranscription{
+					Speaker:  "SpeakerB",
+					Language: "es",
+					Segments: []Segment{
+						{StartTS: 4000, EndTS: 6000, Text: "B1", Language: "es"},
+						{StartTS: 6000, EndTS: 8000, Text: "B2"},
+					},
+				},
+			},
+			{
+				TrackTranscription{
+					Speaker:    "SpeakerA",
+					Language:   "en",
+					ColorIndex: 1,
+					Segments: []Segment{
+						{StartTS: 0, EndTS: 1000, Text: "A1", Language: "en"},
+					},
+				},
+			},
+		}, tr.SplitByLanguage())
+	})
+}
@@ -0,0 +1,93 @@
+package transcribe
+
+import (
+	"sort"
+)
+
+// TimeSpan maps a portion of a trimmed transcription back to the recording.
+// A trimmed timestamp ts, falling in the span, is at
+// ts - StartMs + OriginalStartMs in the recording.
+type TimeSpan struct {
+	StartMs         int64 `json:"start_ms"`
+	OriginalStartMs int64 `json:"original_start_ms"`
+}
+
+// TimeMap is the list of spans, sorted by start time, a trimmed transcription
+// is made of. Each span runs until the next one.
+type TimeMap []TimeSpan
+
+// ToOriginal maps a timestamp of the trimmed transcription back to the
+// recording.
+func (m TimeMap) ToOriginal(ts int64) int64 {
+	idx := sort.Search(len(m), func(i int) bool {
+		return m[i].StartMs > ts
+	})
+	if idx == 0 {
+		return ts
+	}
+	span := m[idx-1]
+	return ts - span.StartMs + span.OriginalStartMs
+}
+
+// fromOriginal maps a timestamp of the recording to the trimmed
+// transcription. It's only meaningful outside of trimmed silences.
+func (m TimeMap) fromOriginal(ts int64) int64 {
+	idx := sort.Search(len(m), func(i int) bool {
+		return m[i].OriginalStartMs > ts
+	})
+	if idx == 0 {
+		return ts
+	}
+	span := m[idx-1]
+	return ts - span.OriginalStartMs + span.StartMs
+}
+
+// TrimSilences shortens every silence across all speakers, including the one
+// before anyone speaks, that lasts longer than thresholdMs down to
+// thresholdMs. It returns the trimmed transcription along with the map needed
+// to get back to the recording timestamps.
+func (tr Transcription) TrimSilences(thresholdMs int64) (Transcription, TimeMap) {
+	type interval struct {
+		start, end int64
+	}
+
+	var intervals []interval
+	for _, trackTr := range tr {
+		for _, s := range trackTr.Segments {
+			intervals = append(intervals, interval{s.StartTS, s.EndTS})
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start < intervals[j].start
+	})
+
+	tm := TimeMap{{StartMs: 0, OriginalStartMs: 0}}
+	var end, removed int64
+	for _, iv := range intervals {
+		if gap := iv.start - end; gap > thresholdMs {
+			removed += gap - thresholdMs
+			tm = append(tm, TimeSpan{
+				StartMs:         iv.start - removed,
+				OriginalStartMs: iv.start,
+			})
+		}
+		end = max(end, iv.end)
+	}
+
+	trimmed := make(Transcription, 0, len(tr))
+	for _, trackTr := range tr {
+		segments := make([]Segment, 0, len(trackTr.Segments))
+		for _, s := range trackTr.Segments {
+			// Segments never span a trimmed silence so they keep their
+			// duration.
+			shift := s.StartTS - tm.fromOriginal(s.StartTS)
+			s.StartTS -= shift
+			s.EndTS -= shift
+			segments = append(segments, s)
+		}
+		trackTr.Segments = segments
+		trimmed = append(trimmed, trackTr)
+	}
+
+	return trimmed, tm
+}
@@ -0,0 +1,76 @@
+package transcribe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimSilences(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		tr, tm := Transcription(nil).TrimSilences(1000)
+		require.Empty(t, tr)
+		require.Equal(t, TimeMap{{StartMs: 0, OriginalStartMs: 0}}, tm)
+	})
+
+	t.Run("short silences are kept", func(t *testing.T) {
+		in := Transcription{
+			{Speaker: "A", Segments: []Segment{{Text: "a", StartTS: 500, EndTS: 1000}}},
+			{Speaker: "B", Segments: []Segment{{Text: "b", StartTS: 1500, EndTS: 2000}}},
+		}
+		tr, tm := in.TrimSilences(1000)
+		require.Equal(t, in, tr)
+		require.Len(t, tm, 1)
+	})
+
+	t.Run("across speakers", func(t *testing.T) {
+		in := Transcription{
+			{
+				Speaker: "A",
+				Segments: []Segment{
+					{Text: "a1", StartTS: 5000, EndTS: 8000},
+					{Text: "a2", StartTS: 60000, EndTS: 61000},
+				},
+			},
+			{
+				Speaker: "B",
+				Segments: []Segment{
+					// Overlapping with A, the silence is what's left after both.
+					{Text: "b1", StartTS: 7000, EndTS: 10000},
+					{Text: "b2", StartTS: 10500, EndTS: 11000},
+				},
+			},
+		}
+
+		tr, tm := in.TrimSilences(1000)
+		require.Equal(t, TimeMap{
+			{StartMs: 0, OriginalStartMs: 0},
+			{StartMs: 1000, OriginalStartMs: 5000},
+			{StartMs: 8000, OriginalStartMs: 60000},
+		}, tm)
+		require.Equal(t, Transcription{
+			{
+				Speaker: "A",
+				Segments: []Segment{
+					{Text: "a1", StartTS: 1000, EndTS: 4000},
+					{Text: "a2", StartTS: 8000, EndTS: 9000},
+				},
+			},
+			{
+				Speaker: "B",
+				Segments: []Segment{
+					{Text: "b1", StartTS: 3000, EndTS: 6000},
+					{Text: "b2", StartTS: 6500, EndTS: 7000},
+				},
+			},
+		}, tr)
+
+		// The input is left untouched.
+		require.Equal(t, int64(5000), in[0].Segments[0].StartTS)
+
+		require.Equal(t, int64(500), tm.ToOriginal(500))
+		require.Equal(t, int64(5000), tm.ToOriginal(1000))
+		require.Equal(t, int64(10500), tm.ToOriginal(6500))
+		require.Equal(t, int64(60500), tm.ToOriginal(8500))
+	})
+}
@@ -0,0 +1,81 @@
+package transcribe
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ttmlHeader opens a TTML document conforming to the IMSC1 Text Profile, as
+// ingested by broadcast and archival workflows. Captions are shown at the
+// bottom of the picture.
+const ttmlHeader = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttp:profile="http://www.w3.org/ns/ttml/profile/imsc1/text" ttp:timeBase="media" xml:lang="%s">
+`
+
+const ttmlLayout = `    <layout>
+      <region xml:id="bottom" tts:origin="10% 80%" tts:extent="80% 15%" tts:displayAlign="after" tts:textAlign="center"/>
+    </layout>
+`
+
+// xmlText escapes text for use in XML content and attributes.
+func xmlText(text string) string {
+	var b strings.Builder
+	// Writing to a strings.Builder can't fail.
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// TTML writes the transcription as TTML captions, following the IMSC1 Text
+// Profile. Speakers are declared as agents, each with a style colored after
+// their color index.
+func (t Transcription) TTML(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ttmlHeader, xmlText(t.Language()))
+	b.WriteString("  <head>\n")
+
+	// Identifiers can't hold arbitrary speaker names so they're numbered
+	// after the speaker's index.
+	var agents, styles strings.Builder
+	colors := make(map[string]int)
+	for _, trackTr := range t {
+		if _, ok := colors[trackTr.Speaker]; !ok {
+			colors[trackTr.Speaker] = trackTr.ColorIndex
+		}
+	}
+	for i, speaker := range t.Speakers() {
+		ns := NamedSegment{Speaker: speaker}
+		ns.sanitize()
+		fmt.Fprintf(&agents, "      <ttm:agent xml:id=\"speaker%d\" type=\"person\">\n        <ttm:name type=\"full\">%s</ttm:name>\n      </ttm:agent>\n",
+			i, xmlText(ns.Speaker))
+		fmt.Fprintf(&styles, "      <style xml:id=\"speaker%d\" tts:color=\"%s\"/>\n", i, vttColors[colors[speaker]%len(vttColors)])
+	}
+	if agents.Len() > 0 {
+		fmt.Fprintf(&b, "    <metadata>\n%s    </metadata>\n", agents.String())
+	}
+	fmt.Fprintf(&b, "    <styling>\n      <style xml:id=\"default\" tts:color=\"%s\" tts:backgroundColor=\"#000000C0\" tts:fontFamily=\"proportionalSansSerif\"/>\n%s    </styling>\n",
+		vttColors[0], styles.String())
+	b.WriteString(ttmlLayout)
+	b.WriteString("  </head>\n")
+
+	b.WriteString("  <body region=\"bottom\" style=\"default\">\n    <div>\n")
+	for _, s := range t.Interleave() {
+		// Speakers without a name aren't declared as agents.
+		hasAgent := s.Speaker != ""
+		s.sanitize()
+		fmt.Fprintf(&b, "      <p begin=\"%s\" end=\"%s\"", vttTS(s.StartTS, true), vttTS(s.EndTS, true))
+		if hasAgent {
+			fmt.Fprintf(&b, " ttm:agent=\"speaker%d\" style=\"speaker%d\"", s.SpeakerIndex, s.SpeakerIndex)
+		}
+		fmt.Fprintf(&b, "><span>(%s) %s</span></p>\n", xmlText(s.Speaker), xmlText(s.Text))
+	}
+	b.WriteString("    </div>\n  </body>\n</tt>\n")
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+
+	return nil
+}
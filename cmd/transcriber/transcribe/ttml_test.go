@@ -0,0 +1,89 @@
+package transcribe
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTTML(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		var tr Transcription
+		var b strings.Builder
+		require.NoError(t, tr.TTML(&b))
+		require.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttp:profile="http://www.w3.org/ns/ttml/profile/imsc1/text" ttp:timeBase="media" xml:lang="en">
+  <head>
+    <styling>
+      <style xml:id="default" tts:color="#FFFFFF" tts:backgroundColor="#000000C0" tts:fontFamily="proportionalSansSerif"/>
+    </styling>
+    <layout>
+      <region xml:id="bottom" tts:origin="10% 80%" tts:extent="80% 15%" tts:displayAlign="after" tts:textAlign="center"/>
+    </layout>
+  </head>
+  <body region="bottom" style="default">
+    <div>
+    </div>
+  </body>
+</tt>
+`, b.String())
+	})
+
+	t.Run("speakers", func(t *testing.T) {
+		tr := Transcription{
+			{
+				Speaker:    "Alice",
+				Language:   "it",
+				ColorIndex: 1,
+				Segments: []Segment{
+					{StartTS: 0, EndTS: 1000, Text: "Ciao <tutti> & benvenuti"},
+					{StartTS: 3000, EndTS: 4500, Text: "Come va?"},
+				},
+			},
+			{
+				Speaker:    "Bob",
+				ColorIndex: 2,
+				Segments: []Segment{
+					{StartTS: 1500, EndTS: 2500, Text: "Bene"},
+				},
+			},
+		}
+
+		var b strings.Builder
+		require.NoError(t, tr.TTML(&b))
+		out := b.String()
+
+		require.Contains(t, out, ` xml:lang="it">`)
+		require.Contains(t, out, `    <metadata>
+      <ttm:agent xml:id="speaker0" type="person">
+        <ttm:name type="full">Alice</ttm:name>
+      </ttm:agent>
+      <ttm:agent xml:id="speaker1" type="person">
+        <ttm:name type="full">Bob</ttm:name>
+      </ttm:agent>
+    </metadata>
+`)
+		require.Contains(t, out, `      <style xml:id="speaker0" tts:color="#FFFF00"/>
+      <style xml:id="speaker1" tts:color="#00FFFF"/>
+`)
+		require.Contains(t, out, `    <div>
+      <p begin="00:00:00.000" end="00:00:01.000" ttm:agent="speaker0" style="speaker0"><span>(Alice) Ciao &lt;tutti&gt; &amp; benvenuti</span></p>
+      <p begin="00:00:01.500" end="00:00:02.500" ttm:agent="speaker1" style="speaker1"><span>(Bob) Bene</span></p>
+      <p begin="00:00:03.000" end="00:00:04.500" ttm:agent="speaker0" style="speaker0"><span>(Alice) Come va?</span></p>
+    </div>
+`)
+
+		// The document is well formed.
+		dec := xml.NewDecoder(strings.NewReader(out))
+		for {
+			_, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+		}
+	})
+}
@@ -7,13 +7,59 @@ import (
 	"math"
 	"os"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 type WebVTTOptions struct {
 	OmitSpeaker bool
+	// SpeakerColorClasses adds a per-speaker color class (e.g. <v.color2 Name>)
+	// to cues so that players can style speakers differently.
+	SpeakerColorClasses bool
+	// RTLMarkers adds Unicode directional marks to cues so that right-to-left
+	// text and speaker names render correctly when mixed with left-to-right
+	// ones.
+	RTLMarkers bool
+	// SpeakerStyles adds a STYLE block coloring the cues of each speaker,
+	// matched by their voice tag, so that players color-code speakers
+	// without custom CSS. Cues keep their voice tag when OmitSpeaker is set.
+	SpeakerStyles bool
+	// MaxLineLength, if set, wraps the text of cues in lines of at most this
+	// many characters, the speaker included.
+	MaxLineLength int
+	// MaxLinesPerCue, if set along with MaxLineLength, splits segments
+	// wrapping in more lines than this into consecutive cues.
+	MaxLinesPerCue int
+	// MinCueDurationMs, if set, merges cues lasting less than this with the
+	// next one when from the same speaker, as done to compact the text
+	// output, or extends them otherwise, so that captions don't flash.
+	MinCueDurationMs int
+	// MinCueGapMs, if set, shortens cues ending less than this before the
+	// next one starts.
+	MinCueGapMs int
 }
 
 func (o *WebVTTOptions) IsValid() error {
+	if o.MaxLineLength < 0 {
+		return fmt.Errorf("MaxLineLength should not be negative")
+	}
+
+	if o.MaxLinesPerCue < 0 {
+		return fmt.Errorf("MaxLinesPerCue should not be negative")
+	}
+
+	if o.MaxLinesPerCue > 0 && o.MaxLineLength == 0 {
+		return fmt.Errorf("MaxLinesPerCue requires MaxLineLength to be set")
+	}
+
+	if o.MinCueDurationMs < 0 {
+		return fmt.Errorf("MinCueDurationMs should not be negative")
+	}
+
+	if o.MinCueGapMs < 0 {
+		return fmt.Errorf("MinCueGapMs should not be negative")
+	}
+
 	return nil
 }
 
@@ -23,25 +69,72 @@ func (o *WebVTTOptions) IsEmpty() bool {
 
 func (o *WebVTTOptions) SetDefaults() {
 	o.OmitSpeaker = false
+	o.SpeakerColorClasses = false
+	o.RTLMarkers = false
+	o.SpeakerStyles = false
+	o.MaxLineLength = 0
+	o.MaxLinesPerCue = 0
+	o.MinCueDurationMs = 0
+	o.MinCueGapMs = 0
 }
 
 func (o *WebVTTOptions) FromEnv() {
 	o.OmitSpeaker, _ = strconv.ParseBool(os.Getenv("WEBVTT_OMIT_SPEAKER"))
+	o.SpeakerColorClasses, _ = strconv.ParseBool(os.Getenv("WEBVTT_SPEAKER_COLOR_CLASSES"))
+	o.RTLMarkers, _ = strconv.ParseBool(os.Getenv("WEBVTT_RTL_MARKERS"))
+	o.SpeakerStyles, _ = strconv.ParseBool(os.Getenv("WEBVTT_SPEAKER_STYLES"))
+	o.MaxLineLength, _ = strconv.Atoi(os.Getenv("WEBVTT_MAX_LINE_LENGTH"))
+	o.MaxLinesPerCue, _ = strconv.Atoi(os.Getenv("WEBVTT_MAX_LINES_PER_CUE"))
+	o.MinCueDurationMs, _ = strconv.Atoi(os.Getenv("WEBVTT_MIN_CUE_DURATION_MS"))
+	o.MinCueGapMs, _ = strconv.Atoi(os.Getenv("WEBVTT_MIN_CUE_GAP_MS"))
 }
 
 func (o *WebVTTOptions) ToEnv() []string {
 	return []string{
 		fmt.Sprintf("WEBVTT_OMIT_SPEAKER=%t", o.OmitSpeaker),
+		fmt.Sprintf("WEBVTT_SPEAKER_COLOR_CLASSES=%t", o.SpeakerColorClasses),
+		fmt.Sprintf("WEBVTT_RTL_MARKERS=%t", o.RTLMarkers),
+		fmt.Sprintf("WEBVTT_SPEAKER_STYLES=%t", o.SpeakerStyles),
+		fmt.Sprintf("WEBVTT_MAX_LINE_LENGTH=%d", o.MaxLineLength),
+		fmt.Sprintf("WEBVTT_MAX_LINES_PER_CUE=%d", o.MaxLinesPerCue),
+		fmt.Sprintf("WEBVTT_MIN_CUE_DURATION_MS=%d", o.MinCueDurationMs),
+		fmt.Sprintf("WEBVTT_MIN_CUE_GAP_MS=%d", o.MinCueGapMs),
 	}
 }
 
 func (o *WebVTTOptions) FromMap(m map[string]any) {
 	o.OmitSpeaker, _ = m["webvtt_omit_speaker"].(bool)
+	o.SpeakerColorClasses, _ = m["webvtt_speaker_color_classes"].(bool)
+	o.RTLMarkers, _ = m["webvtt_rtl_markers"].(bool)
+	o.SpeakerStyles, _ = m["webvtt_speaker_styles"].(bool)
+
+	setIntFromMap(&o.MaxLineLength, m, "webvtt_max_line_length")
+	setIntFromMap(&o.MaxLinesPerCue, m, "webvtt_max_lines_per_cue")
+	setIntFromMap(&o.MinCueDurationMs, m, "webvtt_min_cue_duration_ms")
+	setIntFromMap(&o.MinCueGapMs, m, "webvtt_min_cue_gap_ms")
+}
+
+// setIntFromMap sets dst to the value of key in m, if any. It can either be
+// int or float64 depending whether m has been previously marshaled or not.
+func setIntFromMap(dst *int, m map[string]any, key string) {
+	switch v := m[key].(type) {
+	case int:
+		*dst = v
+	case float64:
+		*dst = int(v)
+	}
 }
 
 func (o *WebVTTOptions) ToMap() map[string]any {
 	return map[string]any{
-		"webvtt_omit_speaker": o.OmitSpeaker,
+		"webvtt_omit_speaker":          o.OmitSpeaker,
+		"webvtt_speaker_color_classes": o.SpeakerColorClasses,
+		"webvtt_rtl_markers":           o.RTLMarkers,
+		"webvtt_speaker_styles":        o.SpeakerStyles,
+		"webvtt_max_line_length":       o.MaxLineLength,
+		"webvtt_max_lines_per_cue":     o.MaxLinesPerCue,
+		"webvtt_min_cue_duration_ms":   o.MinCueDurationMs,
+		"webvtt_min_cue_gap_ms":        o.MinCueGapMs,
 	}
 }
 
@@ -64,27 +157,256 @@ func vttTS(ts int64, withMs bool) string {
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
-func (t Transcription) WebVTT(w io.Writer, opts WebVTTOptions) error {
+// WebVTT writes the transcription in WebVTT format. The call metadata, if
+// given, is written as a NOTE block right after the header.
+func (t Transcription) WebVTT(w io.Writer, opts WebVTTOptions, meta *Metadata) error {
 	_, err := fmt.Fprintf(w, "WEBVTT\n")
 	if err != nil {
 		return fmt.Errorf("failed to write: %w", err)
 	}
-	for _, s := range t.interleave() {
-		s.sanitize(html.EscapeString)
+	// Style blocks must come before any cue.
+	if opts.SpeakerStyles {
+		if style := t.vttStyle(); style != "" {
+			if _, err := fmt.Fprintf(w, "\nSTYLE\n%s", style); err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+		}
+	}
+	if meta != nil {
+		if lines := meta.Lines(); len(lines) > 0 {
+			// Notes can't hold the cue timings separator.
+			note := strings.ReplaceAll(strings.Join(lines, "\n"), "-->", "->")
+			if _, err := fmt.Fprintf(w, "\nNOTE\n%s\n", note); err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+		}
+	}
+	segments := t.Interleave()
+	if opts.MinCueDurationMs > 0 || opts.MinCueGapMs > 0 {
+		segments = enforceCueTimings(segments, int64(opts.MinCueDurationMs), int64(opts.MinCueGapMs))
+	}
+
+	for _, s := range segments {
+		// Text is escaped once wrapped so that entities count as a single
+		// character.
+		s.sanitize()
+		var prefixLen int
+		if !opts.OmitSpeaker {
+			// The speaker is shown as "(Speaker) " before the text.
+			prefixLen = utf8.RuneCountInString(s.Speaker) + 3
+		}
+		cues := splitCues(s, prefixLen, opts)
+		s.Speaker = html.EscapeString(s.Speaker)
 
-		_, err = fmt.Fprintf(w, "\n%s --> %s\n", vttTS(s.StartTS, true), vttTS(s.EndTS, true))
-		if err != nil {
-			return fmt.Errorf("failed to write: %w", err)
+		var before, after string
+		if opts.RTLMarkers {
+			if opts.OmitSpeaker {
+				if s.Direction == DirectionRTL {
+					before = s.Direction.mark()
+				}
+			} else {
+				before, after = markSpeaker(s.Speaker, s.Direction)
+			}
+		}
+
+		tmpl := "<v %[1]s>%[4]s(%[1]s)%[5]s %[2]s\n"
+		if opts.SpeakerColorClasses {
+			tmpl = "<v.color%[3]d %[1]s>%[4]s(%[1]s)%[5]s %[2]s\n"
 		}
-		tmpl := "<v %[1]s>(%[1]s) %[2]s\n"
 		if opts.OmitSpeaker {
-			tmpl = "%[2]s\n"
+			tmpl = "%[4]s%[2]s\n"
+			if opts.SpeakerColorClasses {
+				tmpl = "<c.color%[3]d>%[4]s%[2]s</c>\n"
+			}
+			// Styles match cues by voice.
+			if opts.SpeakerStyles {
+				tmpl = "<v %[1]s>%[4]s%[2]s\n"
+				if opts.SpeakerColorClasses {
+					tmpl = "<v.color%[3]d %[1]s>%[4]s%[2]s\n"
+				}
+			}
 		}
-		_, err = fmt.Fprintf(w, tmpl, s.Speaker, s.Text)
-		if err != nil {
-			return fmt.Errorf("failed to write: %w", err)
+		for _, cue := range cues {
+			_, err = fmt.Fprintf(w, "\n%s --> %s\n", vttTS(cue.startTS, true), vttTS(cue.endTS, true))
+			if err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
+			for i := range cue.lines {
+				cue.lines[i] = html.EscapeString(cue.lines[i])
+			}
+			_, err = fmt.Fprintf(w, tmpl, s.Speaker, strings.Join(cue.lines, "\n"), s.ColorIndex, before, after)
+			if err != nil {
+				return fmt.Errorf("failed to write: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
+
+// vttColors are the colors speakers are styled with, picked by color index.
+// They match those of the ASS subtitles.
+var vttColors = []string{
+	"#FFFFFF", // white
+	"#FFFF00", // yellow
+	"#00FFFF", // cyan
+	"#00FF00", // green
+	"#FF80FF", // pink
+	"#FF8000", // orange
+	"#8080FF", // light blue
+	"#FF8080", // light red
+}
+
+// vttStyle returns the rules of a STYLE block coloring the cues of each
+// speaker after their color index.
+func (t Transcription) vttStyle() string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, trackTr := range t {
+		// Names are sanitized as in cues so that selectors match voices.
+		// This also leaves no character needing escaping in CSS strings.
+		ns := NamedSegment{Speaker: trackTr.Speaker}
+		ns.sanitize()
+		if ns.Speaker == "" || seen[ns.Speaker] {
+			continue
+		}
+		seen[ns.Speaker] = true
+		fmt.Fprintf(&b, "::cue(v[voice=\"%s\"]) {\n  color: %s;\n}\n", ns.Speaker, vttColors[trackTr.ColorIndex%len(vttColors)])
+	}
+	return b.String()
+}
+
+// enforceCueTimings merges segments lasting less than minDurationMs with the
+// next one if it's from the same speaker, or else extends them up to
+// minDurationMs as long as they keep ending minGapMs before the next one
+// starts. Segments ending less than minGapMs before the next one starts are
+// then shortened, unless they overlap it. The input is expected to be sorted
+// by start time, as returned by Interleave.
+func enforceCueTimings(segments []NamedSegment, minDurationMs, minGapMs int64) []NamedSegment {
+	out := make([]NamedSegment, 0, len(segments))
+	for i := 0; i < len(segments); i++ {
+		s := segments[i]
+		for s.EndTS-s.StartTS < minDurationMs && i+1 < len(segments) && segments[i+1].Speaker == s.Speaker {
+			i++
+			s.Text = joinText(s.Text, segments[i].Text, segments[i].segmentLanguage())
+			s.EndTS = max(s.EndTS, segments[i].EndTS)
+		}
+
+		if s.EndTS-s.StartTS < minDurationMs {
+			endTS := s.StartTS + minDurationMs
+			if i+1 < len(segments) {
+				endTS = min(endTS, segments[i+1].StartTS-minGapMs)
+			}
+			s.EndTS = max(s.EndTS, endTS)
+		}
+
+		out = append(out, s)
+	}
+
+	for i := 0; i+1 < len(out); i++ {
+		nextStartTS := out[i+1].StartTS
+		if nextStartTS < out[i].EndTS {
+			// Overlapping speech is shown at the same time.
+			continue
+		}
+		if endTS := nextStartTS - minGapMs; endTS < out[i].EndTS && endTS > out[i].StartTS {
+			out[i].EndTS = endTS
+		}
+	}
+
+	return out
+}
+
+// vttCue is one of the cues a segment is written as.
+type vttCue struct {
+	startTS int64
+	endTS   int64
+	lines   []string
+}
+
+// splitCues wraps the text of s in lines of at most opts.MaxLineLength
+// characters, leaving room for a prefix of prefixLen characters at the start
+// of every cue, and splits them in cues of at most opts.MaxLinesPerCue lines.
+// The segment's time is shared among cues in proportion to their length.
+func splitCues(s NamedSegment, prefixLen int, opts WebVTTOptions) []vttCue {
+	if opts.MaxLineLength <= 0 {
+		return []vttCue{{startTS: s.StartTS, endTS: s.EndTS, lines: []string{s.Text}}}
+	}
+
+	// Long speaker names can't leave too little room for the text.
+	firstWidth := max(opts.MaxLineLength-prefixLen, (opts.MaxLineLength+1)/2)
+	lines := wrapText(s.Text, func(line int) int {
+		if line == 0 || (opts.MaxLinesPerCue > 0 && line%opts.MaxLinesPerCue == 0) {
+			return firstWidth
+		}
+		return opts.MaxLineLength
+	})
+	if len(lines) == 0 {
+		return []vttCue{{startTS: s.StartTS, endTS: s.EndTS, lines: []string{s.Text}}}
+	}
+
+	perCue := len(lines)
+	if opts.MaxLinesPerCue > 0 {
+		perCue = opts.MaxLinesPerCue
+	}
+
+	var total int
+	for _, line := range lines {
+		total += utf8.RuneCountInString(line)
+	}
+
+	var cues []vttCue
+	var done int
+	startTS := s.StartTS
+	for i := 0; i < len(lines); i += perCue {
+		cue := vttCue{
+			startTS: startTS,
+			lines:   lines[i:min(i+perCue, len(lines))],
+		}
+		for _, line := range cue.lines {
+			done += utf8.RuneCountInString(line)
+		}
+		cue.endTS = s.StartTS + (s.EndTS-s.StartTS)*int64(done)/int64(total)
+		startTS = cue.endTS
+		cues = append(cues, cue)
+	}
+
+	return cues
+}
+
+// wrapText breaks text on spaces in lines no longer than width returns for
+// each of them, in characters. Words longer than a line are broken, which is
+// also how text written without spaces (e.g. Japanese) gets wrapped.
+func wrapText(text string, width func(line int) int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > 0 {
+			maxLen := max(width(len(lines)), 1)
+			if len(line) > 0 {
+				if len(line)+1+len(w) <= maxLen {
+					line = append(append(line, ' '), w...)
+					w = nil
+				} else {
+					lines = append(lines, string(line))
+					line = nil
+				}
+				continue
+			}
+
+			n := min(len(w), maxLen)
+			line = append(line, w[:n]...)
+			w = w[n:]
+			if len(w) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+
+	return lines
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const transcribeBatchCmd = "transcribe-batch"
+
+// runTranscribeBatch transcribes the recordings listed in a manifest, e.g. to
+// backfill calls which were never transcribed. Settings are read from the
+// environment and can be overridden per job. It returns the process exit
+// code.
+func runTranscribeBatch(args []string) int {
+	fs := flag.NewFlagSet(transcribeBatchCmd, flag.ContinueOnError)
+	manifestPath := fs.String("manifest", "", "path of the JSON manifest listing the jobs (required)")
+	outDir := fs.String("output-dir", ".", "directory holding the output directory of each job")
+	statusPath := fs.String("status", "", "path of the JSON file the job statuses are saved to (defaults to batch_status.json in the output directory)")
+	parallel := fs.Int("parallel", 1, "number of jobs run at once")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s -manifest FILE [flags]\n\n", os.Args[0], transcribeBatchCmd)
+		fmt.Fprintf(fs.Output(), "Transcribes the jobs listed in a manifest. Jobs already done according to the status file are skipped.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *manifestPath == "" || *parallel < 1 || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if *statusPath == "" {
+		*statusPath = filepath.Join(*outDir, "batch_status.json")
+	}
+
+	manifest, err := call.LoadBatchManifest(*manifestPath)
+	if err != nil {
+		slog.Error("failed to load manifest", slog.String("err", err.Error()))
+		return 2
+	}
+
+	cfg, err := config.FromEnv()
+	if err != nil {
+		slog.Error("failed to load config", slog.String("err", err.Error()))
+		return 1
+	}
+	cfg.SetDefaults()
+
+	if err := os.MkdirAll(*outDir, 0700); err != nil {
+		slog.Error("failed to create output directory", slog.String("err", err.Error()))
+		return 1
+	}
+
+	statuses, err := call.RunBatch(cfg, manifest, call.BatchOptions{
+		OutputDir:  *outDir,
+		StatusPath: *statusPath,
+		Parallel:   *parallel,
+	})
+	if err != nil {
+		slog.Error("failed to run batch", slog.String("err", err.Error()))
+		return 1
+	}
+
+	var failed int
+	for _, status := range statuses {
+		if status.State == call.BatchJobStateFailed {
+			failed++
+		}
+	}
+
+	slog.Info("batch completed",
+		slog.Int("numJobs", len(statuses)),
+		slog.Int("numFailed", failed),
+		slog.String("status", *statusPath))
+
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/call"
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/config"
+)
+
+const transcribeFileCmd = "transcribe-file"
+
+// runTranscribeFile transcribes pre-recorded track files locally, without
+// joining a call. Engine and output settings are read from the environment
+// as usual. It returns the process exit code.
+func runTranscribeFile(args []string) int {
+	fs := flag.NewFlagSet(transcribeFileCmd, flag.ContinueOnError)
+	outDir := fs.String("output-dir", ".", "directory the transcription files are written to")
+	name := fs.String("name", "transcription", "name of the transcription files, without extension")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags] path[:speaker[:offset]]...\n\n", os.Args[0], transcribeFileCmd)
+		fmt.Fprintf(fs.Output(), "Transcribes audio tracks, one per speaker: OGG/Opus, WAV, or MP3/M4A/MKA/MP4 through ffmpeg.\n")
+		fmt.Fprintf(fs.Output(), "The offset is a duration (e.g. 1m30s) at which the track starts relative to the others.\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	tracks := make([]call.OfflineTrack, 0, fs.NArg())
+	for _, arg := range fs.Args() {
+		track, err := call.ParseOfflineTrack(arg)
+		if err != nil {
+			slog.Error("invalid track argument", slog.String("arg", arg), slog.String("err", err.Error()))
+			return 2
+		}
+		tracks = append(tracks, track)
+	}
+
+	cfg, err := config.FromEnv()
+	if err != nil {
+		slog.Error("failed to load config", slog.String("err", err.Error()))
+		return 1
+	}
+	cfg.SetDefaults()
+
+	paths, err := call.TranscribeFiles(cfg, tracks, *outDir, *name)
+	if err != nil {
+		slog.Error("failed to transcribe files", slog.String("err", err.Error()))
+		return 1
+	}
+
+	for _, path := range paths {
+		slog.Info("transcription file written", slog.String("path", path))
+	}
+
+	return 0
+}
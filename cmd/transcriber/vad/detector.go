@@ -0,0 +1,124 @@
+package vad
+
+import (
+	"fmt"
+)
+
+// DetectorConfig holds the settings of a Detector.
+type DetectorConfig struct {
+	// Mode is the aggressiveness of the detection, from 0 to MaxMode.
+	Mode int
+	// MinSilenceDurationMs is how long the silence separating two speech
+	// segments should be.
+	MinSilenceDurationMs int
+	// SpeechPadMs is the padding added around speech segments so that they
+	// aren't cut too aggressively.
+	SpeechPadMs int
+}
+
+func (c DetectorConfig) IsValid() error {
+	if c.Mode < 0 || c.Mode > MaxMode {
+		return fmt.Errorf("invalid Mode: should be in range [0, %d]", MaxMode)
+	}
+
+	if c.MinSilenceDurationMs < 0 {
+		return fmt.Errorf("invalid MinSilenceDurationMs: should not be negative")
+	}
+
+	if c.SpeechPadMs < 0 {
+		return fmt.Errorf("invalid SpeechPadMs: should not be negative")
+	}
+
+	return nil
+}
+
+// Segment is a portion of audio holding speech.
+type Segment struct {
+	// The relative timestamp in seconds of when a speech segment begins.
+	SpeechStartAt float64
+	// The relative timestamp in seconds of when a speech segment ends. It's
+	// zero if speech continues up to the end of the samples.
+	SpeechEndAt float64
+}
+
+// Detector finds speech segments in audio samples by grouping the frames
+// classified as speech by a WebRTC detector.
+type Detector struct {
+	cfg DetectorConfig
+	vad *WebRTC
+
+	currSample int
+	triggered  bool
+	tempEnd    int
+}
+
+func NewDetector(cfg DetectorConfig) (*Detector, error) {
+	if err := cfg.IsValid(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return &Detector{
+		cfg: cfg,
+		vad: NewWebRTC(cfg.Mode),
+	}, nil
+}
+
+// Detect returns the speech segments found in the given samples, at
+// SampleRate. Detection continues from the previous call unless Reset is
+// called in between, timestamps being relative to the samples passed since.
+func (d *Detector) Detect(pcm []float32) ([]Segment, error) {
+	if len(pcm) < FrameSize {
+		return nil, fmt.Errorf("not enough samples")
+	}
+
+	minSilenceSamples := d.cfg.MinSilenceDurationMs * SampleRate / 1000
+	speechPadSamples := d.cfg.SpeechPadMs * SampleRate / 1000
+
+	var segments []Segment
+	for i := 0; i+FrameSize <= len(pcm); i += FrameSize {
+		isSpeech := d.vad.Process(pcm[i : i+FrameSize])
+		d.currSample += FrameSize
+
+		if isSpeech {
+			d.tempEnd = 0
+			if !d.triggered {
+				d.triggered = true
+				segments = append(segments, Segment{
+					SpeechStartAt: float64(max(0, d.currSample-FrameSize-speechPadSamples)) / SampleRate,
+				})
+			}
+			continue
+		}
+
+		if !d.triggered {
+			continue
+		}
+
+		if d.tempEnd == 0 {
+			d.tempEnd = d.currSample - FrameSize
+		}
+
+		// Not enough silence yet to split.
+		if d.currSample-d.tempEnd < minSilenceSamples {
+			continue
+		}
+
+		endAt := min(d.tempEnd+speechPadSamples, d.currSample)
+		d.tempEnd = 0
+		d.triggered = false
+		// Speech may have started, and been returned, in a previous call.
+		if len(segments) > 0 {
+			segments[len(segments)-1].SpeechEndAt = float64(endAt) / SampleRate
+		}
+	}
+
+	return segments, nil
+}
+
+// Reset restores the detector to its initial state.
+func (d *Detector) Reset() {
+	d.vad.Reset()
+	d.currSample = 0
+	d.triggered = false
+	d.tempEnd = 0
+}
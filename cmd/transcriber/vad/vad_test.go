@@ -0,0 +1,123 @@
+package vad
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// genVoiced generates a harmonic signal, amplitude modulated at a syllabic
+// rate, resembling voiced speech, over some background noise.
+func genVoiced(amp, dur float64) []float32 {
+	pcm := genNoise(amp/30, dur)
+	for i := range pcm {
+		ts := float64(i) / SampleRate
+		f0 := 140 + 20*math.Sin(2*math.Pi*0.5*ts)
+		var v float64
+		for h := 1; h <= 20; h++ {
+			v += math.Sin(2*math.Pi*f0*float64(h)*ts) / float64(h)
+		}
+		env := 0.5 + 0.5*math.Sin(2*math.Pi*4*ts)
+		pcm[i] += float32(amp * env * v / 3)
+	}
+	return pcm
+}
+
+func genNoise(amp, dur float64) []float32 {
+	r := rand.New(rand.NewSource(1))
+	pcm := make([]float32, int(dur*SampleRate))
+	for i := range pcm {
+		pcm[i] = float32(amp * r.NormFloat64())
+	}
+	return pcm
+}
+
+func countSpeechFrames(v *WebRTC, pcm []float32) int {
+	var n int
+	for i := 0; i+FrameSize <= len(pcm); i += FrameSize {
+		if v.Process(pcm[i : i+FrameSize]) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWebRTC(t *testing.T) {
+	t.Run("silence", func(t *testing.T) {
+		require.Zero(t, countSpeechFrames(NewWebRTC(0), make([]float32, SampleRate)))
+	})
+
+	t.Run("noise", func(t *testing.T) {
+		require.Zero(t, countSpeechFrames(NewWebRTC(2), genNoise(0.001, 3)))
+	})
+
+	t.Run("speech", func(t *testing.T) {
+		v := NewWebRTC(2)
+		require.Zero(t, countSpeechFrames(v, genNoise(0.001, 1)))
+		require.Greater(t, countSpeechFrames(v, genVoiced(0.03, 2)), 160)
+	})
+
+	t.Run("aggressiveness", func(t *testing.T) {
+		pcm := genVoiced(0.03, 2)
+		require.Less(t, countSpeechFrames(NewWebRTC(MaxMode), pcm), countSpeechFrames(NewWebRTC(0), pcm))
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		pcm := append(genNoise(0.001, 1), genVoiced(0.03, 1)...)
+		v := NewWebRTC(2)
+		n := countSpeechFrames(v, pcm)
+		v.Reset()
+		require.Equal(t, n, countSpeechFrames(v, pcm))
+	})
+}
+
+func TestDetector(t *testing.T) {
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := NewDetector(DetectorConfig{Mode: 4})
+		require.EqualError(t, err, "invalid config: invalid Mode: should be in range [0, 3]")
+		_, err = NewDetector(DetectorConfig{SpeechPadMs: -1})
+		require.EqualError(t, err, "invalid config: invalid SpeechPadMs: should not be negative")
+	})
+
+	sd, err := NewDetector(DetectorConfig{
+		Mode:                 2,
+		MinSilenceDurationMs: 500,
+		SpeechPadMs:          50,
+	})
+	require.NoError(t, err)
+
+	t.Run("not enough samples", func(t *testing.T) {
+		_, err := sd.Detect(make([]float32, FrameSize-1))
+		require.EqualError(t, err, "not enough samples")
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		defer sd.Reset()
+		segments, err := sd.Detect(make([]float32, SampleRate))
+		require.NoError(t, err)
+		require.Empty(t, segments)
+	})
+
+	t.Run("speech", func(t *testing.T) {
+		defer sd.Reset()
+
+		var pcm []float32
+		pcm = append(pcm, genNoise(0.001, 1)...)
+		pcm = append(pcm, genVoiced(0.03, 2)...)
+		pcm = append(pcm, genNoise(0.001, 1)...)
+		pcm = append(pcm, genVoiced(0.03, 1)...)
+
+		segments, err := sd.Detect(pcm)
+		require.NoError(t, err)
+		require.Len(t, segments, 2)
+
+		require.InDelta(t, 0.95, segments[0].SpeechStartAt, 0.05)
+		require.InDelta(t, 3.05, segments[0].SpeechEndAt, 0.2)
+
+		// Speech continues up to the end.
+		require.InDelta(t, 3.95, segments[1].SpeechStartAt, 0.05)
+		require.Zero(t, segments[1].SpeechEndAt)
+	})
+}
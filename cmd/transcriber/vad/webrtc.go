@@ -0,0 +1,496 @@
+// Package vad implements a lightweight speech detector, a floating point port
+// of the WebRTC voice activity detector. It models the energy of six
+// frequency bands with Gaussian mixtures for both speech and noise, adapting
+// them as audio is processed, which makes it far cheaper to run than a neural
+// network and free of any native dependency or model file.
+package vad
+
+import (
+	"math"
+)
+
+const (
+	// SampleRate is the rate of the audio samples the detector expects.
+	SampleRate = 16000
+	// FrameSize is the number of samples classified at once (10ms).
+	FrameSize = SampleRate / 100
+	// MaxMode is the most aggressive mode, the least likely to report noise
+	// as speech.
+	MaxMode = 3
+
+	numChannels  = 6
+	numGaussians = 2
+	numTables    = numChannels * numGaussians
+
+	// minEnergy is the frame energy below which audio is considered silence
+	// without further processing.
+	minEnergy = 10
+	// maxSpeechFrames is the number of consecutive speech frames after which
+	// the longer hangover applies.
+	maxSpeechFrames = 6
+	// minProbability is the lowest a mixture probability can be, as in the
+	// fixed point reference implementation (Q27).
+	minProbability = 1.0 / (1 << 27)
+	// minUpdateProbability is the mixture probability above which the
+	// Gaussians are updated proportionally to their own.
+	minUpdateProbability = 1.0 / (1 << 15)
+
+	noiseUpdateConst     = 0.02
+	speechUpdateConst    = 0.2
+	noiseStdUpdateConst  = 1.0 / 1024
+	speechStdUpdateConst = 0.025
+	// backEta pulls the noise model towards the tracked noise floor.
+	backEta = 0.6
+	minStd  = 3
+	// minimumWindow is the number of frames over which the noise floor is
+	// tracked.
+	minimumWindow    = 100
+	numLowValues     = 16
+	lowValueInit     = 625
+	meanValueInit    = 100
+	smoothingDown    = 0.2
+	smoothingUp      = 0.99
+	initialMaxSpeech = 100
+)
+
+// The model parameters below are those of the reference implementation,
+// converted from fixed point. Energies are in dB. The first numChannels
+// values are those of the first Gaussian of each band, followed by those of
+// the second one.
+var (
+	noiseDataWeights  = q7(34, 62, 72, 66, 53, 25, 94, 66, 56, 62, 75, 103)
+	speechDataWeights = q7(48, 82, 45, 87, 50, 47, 80, 46, 83, 41, 78, 81)
+	noiseDataMeans    = q7(6738, 4892, 7065, 6715, 6771, 3369, 7646, 3863, 7820, 7266, 5020, 4362)
+	speechDataMeans   = q7(8306, 10085, 10078, 11823, 11843, 6309, 9473, 9571, 10879, 7581, 8180, 7483)
+	noiseDataStds     = q7(378, 1064, 493, 582, 688, 593, 474, 697, 475, 688, 421, 455)
+	speechDataStds    = q7(555, 505, 567, 524, 585, 1231, 509, 828, 492, 1540, 1079, 850)
+
+	spectrumWeights   = []float64{6, 8, 10, 12, 14, 16}
+	minimumDifference = []float64{17, 17, 18, 18, 18, 18}
+	maximumSpeech     = q7(11392, 11392, 11520, 11520, 11520, 11520)
+	maximumNoise      = q7(9216, 9088, 8960, 8832, 8704, 8576)
+	minimumMean       = q7(640, 768)
+	// energyOffsets compensate for the bands being decimated to different
+	// rates.
+	energyOffsets = []float64{23, 23, 17, 11, 11, 11}
+)
+
+func q7(values ...int) []float64 {
+	ret := make([]float64, len(values))
+	for i, v := range values {
+		ret[i] = float64(v) / 128
+	}
+	return ret
+}
+
+// modeParams hold the decision thresholds of a mode, for 10ms frames.
+type modeParams struct {
+	// overHangMax1 and overHangMax2 are the number of frames still reported
+	// as speech once it stops, after short and long speech respectively.
+	overHangMax1 int
+	overHangMax2 int
+	// localThreshold applies to the likelihood ratio of every band while
+	// globalThreshold applies to their weighted sum.
+	localThreshold  float64
+	globalThreshold float64
+}
+
+var modes = [MaxMode + 1]modeParams{
+	{8, 14, 24, 57},
+	{8, 14, 37, 100},
+	{6, 9, 82, 285},
+	{6, 9, 94, 1100},
+}
+
+// allPass is a first order all-pass filter.
+type allPass struct {
+	coef  float64
+	state float64
+}
+
+func (f *allPass) process(x float64) float64 {
+	y := f.coef*x + f.state
+	f.state = x - f.coef*y
+	return y
+}
+
+// splitter splits a signal in its lower and upper halves of the spectrum,
+// decimating both by two.
+type splitter struct {
+	upper allPass
+	lower allPass
+}
+
+func newSplitter() splitter {
+	return splitter{
+		upper: allPass{coef: 20972.0 / 32768},
+		lower: allPass{coef: 5571.0 / 32768},
+	}
+}
+
+func (s *splitter) split(in, hp, lp []float64) {
+	for i := range lp {
+		u := s.upper.process(in[2*i]) / 2
+		l := s.lower.process(in[2*i+1]) / 2
+		hp[i] = u - l
+		lp[i] = u + l
+	}
+}
+
+// highPass removes frequencies below 80Hz from a signal sampled at 500Hz.
+type highPass struct {
+	x1, x2, y1, y2 float64
+}
+
+func (f *highPass) process(data []float64) {
+	const (
+		b0 = 6631.0 / 16384
+		b1 = -13262.0 / 16384
+		b2 = 6631.0 / 16384
+		a1 = -7756.0 / 16384
+		a2 = 5620.0 / 16384
+	)
+	for i, x := range data {
+		y := b0*x + b1*f.x1 + b2*f.x2 - a1*f.y1 - a2*f.y2
+		f.x2, f.x1 = f.x1, x
+		f.y2, f.y1 = f.y1, y
+		data[i] = y
+	}
+}
+
+// logEnergy returns the energy of a band, in dB, and adds it to total.
+func logEnergy(data []float64, offset float64, total *float64) float64 {
+	var energy float64
+	for _, x := range data {
+		energy += x * x
+	}
+	*total += energy
+
+	if energy <= 1 {
+		return offset
+	}
+	return 10*math.Log10(energy) + offset
+}
+
+// WebRTC classifies 10ms frames of audio as speech or not. It isn't safe for
+// concurrent use.
+type WebRTC struct {
+	mode modeParams
+
+	downsampler splitter
+	splitters   [5]splitter
+	highPass    highPass
+
+	noiseMeans  [numTables]float64
+	noiseStds   [numTables]float64
+	speechMeans [numTables]float64
+	speechStds  [numTables]float64
+
+	// The lowest band energies over the last minimumWindow frames, sorted,
+	// along with their age, used to track the noise floor.
+	lowValues  [numChannels][numLowValues]float64
+	ages       [numChannels][numLowValues]int
+	meanValues [numChannels]float64
+
+	frameCounter int
+	overHang     int
+	numOfSpeech  int
+}
+
+// NewWebRTC returns a detector running in the given mode, from 0 (least
+// aggressive, the most likely to report noise as speech) to MaxMode.
+func NewWebRTC(mode int) *WebRTC {
+	v := &WebRTC{
+		mode: modes[max(0, min(MaxMode, mode))],
+	}
+	v.Reset()
+	return v
+}
+
+// Reset restores the detector to its initial state, forgetting what it
+// learned about the audio processed so far.
+func (v *WebRTC) Reset() {
+	v.downsampler = newSplitter()
+	for i := range v.splitters {
+		v.splitters[i] = newSplitter()
+	}
+	v.highPass = highPass{}
+
+	copy(v.noiseMeans[:], noiseDataMeans)
+	copy(v.noiseStds[:], noiseDataStds)
+	copy(v.speechMeans[:], speechDataMeans)
+	copy(v.speechStds[:], speechDataStds)
+
+	for c := range numChannels {
+		for i := range numLowValues {
+			v.lowValues[c][i] = lowValueInit
+			v.ages[c][i] = 0
+		}
+		v.meanValues[c] = meanValueInit
+	}
+
+	v.frameCounter = 0
+	v.overHang = 0
+	v.numOfSpeech = 0
+}
+
+// Process returns whether the given frame of FrameSize samples, at
+// SampleRate, holds speech.
+func (v *WebRTC) Process(frame []float32) bool {
+	// Processing happens at 8KHz, on samples scaled to 16 bits as the model
+	// is for.
+	var in [FrameSize / 2]float64
+	var scratch [FrameSize / 2]float64
+	var pcm [FrameSize]float64
+	for i, s := range frame[:FrameSize] {
+		pcm[i] = float64(s) * 32768
+	}
+	v.downsampler.split(pcm[:], scratch[:], in[:])
+
+	features, totalEnergy := v.features(in[:])
+	return v.decide(features, totalEnergy)
+}
+
+// features returns the log energy of each band along with the total energy
+// of the frame.
+func (v *WebRTC) features(in []float64) ([numChannels]float64, float64) {
+	var features [numChannels]float64
+	var total float64
+
+	// 0-2000Hz and 2000-4000Hz.
+	var hp1, lp1 [FrameSize / 4]float64
+	v.splitters[0].split(in, hp1[:], lp1[:])
+
+	// 2000-3000Hz and 3000-4000Hz.
+	var hp2, lp2 [FrameSize / 8]float64
+	v.splitters[1].split(hp1[:], hp2[:], lp2[:])
+	features[5] = logEnergy(hp2[:], energyOffsets[5], &total)
+	features[4] = logEnergy(lp2[:], energyOffsets[4], &total)
+
+	// 0-1000Hz and 1000-2000Hz.
+	v.splitters[2].split(lp1[:], hp2[:], lp2[:])
+	features[3] = logEnergy(hp2[:], energyOffsets[3], &total)
+
+	// 0-500Hz and 500-1000Hz.
+	var hp3, lp3 [FrameSize / 16]float64
+	v.splitters[3].split(lp2[:], hp3[:], lp3[:])
+	features[2] = logEnergy(hp3[:], energyOffsets[2], &total)
+
+	// 0-250Hz and 250-500Hz.
+	var hp4, lp4 [FrameSize / 32]float64
+	v.splitters[4].split(lp3[:], hp4[:], lp4[:])
+	features[1] = logEnergy(hp4[:], energyOffsets[1], &total)
+
+	// 80-250Hz.
+	v.highPass.process(lp4[:])
+	features[0] = logEnergy(lp4[:], energyOffsets[0], &total)
+
+	return features, total
+}
+
+// gaussianProbability returns the density of x under a normal distribution,
+// without its constant factor, along with (x-mean)/std², used to update the
+// model.
+func gaussianProbability(x, mean, std float64) (float64, float64) {
+	delta := (x - mean) / (std * std)
+	return math.Exp(-(x-mean)*delta/2) / std, delta
+}
+
+// decide makes the speech decision for a frame through a likelihood ratio
+// test, both per band and globally, and updates the models accordingly.
+func (v *WebRTC) decide(features [numChannels]float64, totalEnergy float64) bool {
+	var speech bool
+
+	if totalEnergy > minEnergy {
+		var deltaN, deltaS, ngp, sgp [numTables]float64
+		var sumLLR float64
+		for c := range numChannels {
+			var h0, h1 float64
+			var noiseProbs, speechProbs [numGaussians]float64
+			for k := range numGaussians {
+				g := c + k*numChannels
+
+				var p float64
+				p, deltaN[g] = gaussianProbability(features[c], v.noiseMeans[g], v.noiseStds[g])
+				noiseProbs[k] = noiseDataWeights[g] * p
+				h0 += noiseProbs[k]
+
+				p, deltaS[g] = gaussianProbability(features[c], v.speechMeans[g], v.speechStds[g])
+				speechProbs[k] = speechDataWeights[g] * p
+				h1 += speechProbs[k]
+			}
+
+			llr := math.Log2(max(h1, minProbability)) - math.Log2(max(h0, minProbability))
+			sumLLR += llr * spectrumWeights[c]
+			if llr*4 > v.mode.localThreshold {
+				speech = true
+			}
+
+			// Share of each Gaussian in the mixture, used to update them.
+			if h0 >= minUpdateProbability {
+				ngp[c] = noiseProbs[0] / h0
+				ngp[c+numChannels] = 1 - ngp[c]
+			} else {
+				ngp[c] = 1
+			}
+			if h1 >= minUpdateProbability {
+				sgp[c] = speechProbs[0] / h1
+				sgp[c+numChannels] = 1 - sgp[c]
+			}
+		}
+
+		if sumLLR >= v.mode.globalThreshold {
+			speech = true
+		}
+
+		v.update(features, speech, deltaN, deltaS, ngp, sgp)
+		v.frameCounter++
+	}
+
+	// Speech is extended a few frames past its end so that trailing quiet
+	// sounds aren't cut.
+	if !speech {
+		if v.overHang > 0 {
+			speech = true
+			v.overHang--
+		}
+		v.numOfSpeech = 0
+	} else {
+		v.numOfSpeech++
+		if v.numOfSpeech > maxSpeechFrames {
+			v.numOfSpeech = maxSpeechFrames
+			v.overHang = v.mode.overHangMax2
+		} else {
+			v.overHang = v.mode.overHangMax1
+		}
+	}
+
+	return speech
+}
+
+// weightedAverage shifts the means of a band by offset and returns their
+// weighted average.
+func weightedAverage(means *[numTables]float64, c int, offset float64, weights []float64) float64 {
+	var avg float64
+	for k := range numGaussians {
+		g := c + k*numChannels
+		means[g] += offset
+		avg += means[g] * weights[g]
+	}
+	return avg
+}
+
+// update adapts the noise model, or the speech one, to the frame depending
+// on the decision made.
+func (v *WebRTC) update(features [numChannels]float64, speech bool, deltaN, deltaS, ngp, sgp [numTables]float64) {
+	// As in the reference implementation, the speech means of a band are
+	// capped according to the limit of the previous band.
+	maxSpeech := float64(initialMaxSpeech)
+
+	for c := range numChannels {
+		featureMin := v.findMinimum(features[c], c)
+		noiseGlobalMean := weightedAverage(&v.noiseMeans, c, 0, noiseDataWeights)
+
+		for k := range numGaussians {
+			g := c + k*numChannels
+			nmk, smk := v.noiseMeans[g], v.speechMeans[g]
+			nsk, ssk := v.noiseStds[g], v.speechStds[g]
+
+			nmk2 := nmk
+			if !speech {
+				nmk2 += noiseUpdateConst * ngp[g] * deltaN[g]
+			}
+			// Long term correction towards the noise floor, making sure the
+			// noise mean doesn't drift too much.
+			nmk3 := nmk2 + backEta*(featureMin-noiseGlobalMean)
+			v.noiseMeans[g] = max(float64(k+5), min(float64(72+k-c), nmk3))
+
+			if speech {
+				smk2 := smk + speechUpdateConst*sgp[g]*deltaS[g]
+				v.speechMeans[g] = max(minimumMean[k], min(maxSpeech+5, smk2))
+
+				z := deltaS[g] * (features[c] - smk)
+				ssk += speechStdUpdateConst * sgp[g] * (z - 1) / ssk
+				v.speechStds[g] = max(minStd, ssk)
+			} else {
+				z := deltaN[g] * (features[c] - nmk)
+				nsk += noiseStdUpdateConst * ngp[g] * (z - 1) / nsk
+				v.noiseStds[g] = max(minStd, nsk)
+			}
+		}
+
+		// The models are kept apart.
+		noiseGlobalMean = weightedAverage(&v.noiseMeans, c, 0, noiseDataWeights)
+		speechGlobalMean := weightedAverage(&v.speechMeans, c, 0, speechDataWeights)
+		if diff := speechGlobalMean - noiseGlobalMean; diff < minimumDifference[c] {
+			shift := minimumDifference[c] - diff
+			speechGlobalMean = weightedAverage(&v.speechMeans, c, 0.8*shift, speechDataWeights)
+			noiseGlobalMean = weightedAverage(&v.noiseMeans, c, -0.2*shift, noiseDataWeights)
+		}
+
+		// Neither model can drift above its limit.
+		maxSpeech = maximumSpeech[c]
+		if speechGlobalMean > maxSpeech {
+			for k := range numGaussians {
+				v.speechMeans[c+k*numChannels] -= speechGlobalMean - maxSpeech
+			}
+		}
+		if noiseGlobalMean > maximumNoise[c] {
+			for k := range numGaussians {
+				v.noiseMeans[c+k*numChannels] -= noiseGlobalMean - maximumNoise[c]
+			}
+		}
+	}
+}
+
+// findMinimum tracks the lowest energies of a band over the last
+// minimumWindow frames and returns a smoothed estimate of the noise floor.
+func (v *WebRTC) findMinimum(feature float64, c int) float64 {
+	values := &v.lowValues[c]
+	ages := &v.ages[c]
+
+	// Values get older and are forgotten once out of the window.
+	n := 0
+	for i := range numLowValues {
+		if ages[i] >= minimumWindow {
+			continue
+		}
+		values[n] = values[i]
+		ages[n] = ages[i] + 1
+		n++
+	}
+	for ; n < numLowValues; n++ {
+		values[n] = lowValueInit
+		ages[n] = minimumWindow
+	}
+
+	for i := range numLowValues {
+		if feature < values[i] {
+			copy(values[i+1:], values[i:numLowValues-1])
+			copy(ages[i+1:], ages[i:numLowValues-1])
+			values[i] = feature
+			ages[i] = 1
+			break
+		}
+	}
+
+	median := float64(meanValueInit)
+	if v.frameCounter > 2 {
+		median = values[2]
+	} else if v.frameCounter > 0 {
+		median = values[0]
+	}
+
+	// The estimate follows drops quickly but rises slowly.
+	var alpha float64
+	if v.frameCounter > 0 {
+		if median < v.meanValues[c] {
+			alpha = smoothingDown
+		} else {
+			alpha = smoothingUp
+		}
+	}
+	v.meanValues[c] = alpha*v.meanValues[c] + (1-alpha)*median
+
+	return v.meanValues[c]
+}
@@ -0,0 +1,214 @@
+// Package voiceprint computes compact speaker embeddings from speech audio so
+// that the same speaker can be linked across calls without keeping any of
+// their audio.
+package voiceprint
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/mattermost/calls-transcriber/cmd/transcriber/dsp"
+)
+
+const (
+	sampleRate = 16000
+	// frameSize is the analysis frame in samples (32ms at 16KHz).
+	frameSize = 512
+	hopSize   = frameSize / 2
+	numBands  = 24
+	minFreq   = 100
+	maxFreq   = 7600
+	// minFrames is the amount of speech (~1.6s) below which the embedding
+	// isn't considered reliable enough to be reported.
+	minFrames = 100
+	// minFrameEnergy excludes the (near) silent frames that speech
+	// segments are padded with.
+	minFrameEnergy = 1e-4
+	// version prefixes encoded embeddings so that consumers don't compare
+	// embeddings computed differently.
+	version = "v1"
+)
+
+// bands holds the FFT bin ranges of the mel spaced frequency bands.
+var bands = func() [numBands][2]int {
+	mel := func(f float64) float64 { return 2595 * math.Log10(1+f/700) }
+	hz := func(m float64) float64 { return 700 * (math.Pow(10, m/2595) - 1) }
+	bin := func(f float64) int { return int(math.Round(f * frameSize / sampleRate)) }
+
+	var b [numBands][2]int
+	lo, hi := mel(minFreq), mel(maxFreq)
+	for i := range b {
+		b[i][0] = bin(hz(lo + (hi-lo)*float64(i)/numBands))
+		b[i][1] = max(b[i][0]+1, bin(hz(lo+(hi-lo)*float64(i+1)/numBands)))
+	}
+	return b
+}()
+
+var window = func() []float64 {
+	w := make([]float64, frameSize)
+	for i := range w {
+		w[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/frameSize))
+	}
+	return w
+}()
+
+// Stats accumulates the spectral envelope of a speaker's speech. Stats,
+// rather than embeddings, are kept so that multiple tracks of the same
+// speaker can be combined.
+type Stats struct {
+	Frames int       `json:"frames"`
+	Sum    []float64 `json:"sum"`
+	SumSq  []float64 `json:"sum_sq"`
+}
+
+// Add accumulates the given mono speech samples, at 16KHz.
+func (s *Stats) Add(pcm []float32) {
+	if s.Sum == nil {
+		s.Sum = make([]float64, numBands)
+		s.SumSq = make([]float64, numBands)
+	}
+
+	spectrum := make([]complex128, frameSize)
+	var feats [numBands]float64
+	for off := 0; off+frameSize <= len(pcm); off += hopSize {
+		var energy float64
+		for i := range spectrum {
+			spectrum[i] = complex(float64(pcm[off+i])*window[i], 0)
+			energy += float64(pcm[off+i]) * float64(pcm[off+i])
+		}
+		if energy/frameSize < minFrameEnergy*minFrameEnergy {
+			continue
+		}
+		dsp.FFT(spectrum, false)
+
+		// Log band energies are normalized by their average across bands so
+		// that the speaker's volume, and the gain of their microphone, don't
+		// affect the result.
+		var avg float64
+		for b, r := range bands {
+			var p float64
+			for k := r[0]; k < r[1]; k++ {
+				re, im := real(spectrum[k]), imag(spectrum[k])
+				p += re*re + im*im
+			}
+			feats[b] = math.Log(p + 1e-10)
+			avg += feats[b] / numBands
+		}
+
+		for b, f := range feats {
+			s.Sum[b] += f - avg
+			s.SumSq[b] += (f - avg) * (f - avg)
+		}
+		s.Frames++
+	}
+}
+
+// Merge accumulates the given stats.
+func (s *Stats) Merge(o *Stats) {
+	if o == nil || o.Frames == 0 {
+		return
+	}
+	if s.Sum == nil {
+		s.Sum = make([]float64, numBands)
+		s.SumSq = make([]float64, numBands)
+	}
+	for b := range s.Sum {
+		s.Sum[b] += o.Sum[b]
+		s.SumSq[b] += o.SumSq[b]
+	}
+	s.Frames += o.Frames
+}
+
+// Embedding returns the unit length speaker embedding, made of the mean and
+// standard deviation of the spectral envelope, or nil if there isn't enough
+// speech.
+func (s *Stats) Embedding() []float64 {
+	if s == nil || s.Frames < minFrames {
+		return nil
+	}
+
+	emb := make([]float64, 2*numBands)
+	var norm float64
+	for b := range s.Sum {
+		mean := s.Sum[b] / float64(s.Frames)
+		emb[b] = mean
+		emb[numBands+b] = math.Sqrt(max(0, s.SumSq[b]/float64(s.Frames)-mean*mean))
+	}
+	for _, v := range emb {
+		norm += v * v
+	}
+	if norm == 0 {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	for i := range emb {
+		emb[i] /= norm
+	}
+
+	return emb
+}
+
+// Encode returns the embedding in its opaque, quantized, form or an empty
+// string if there isn't enough speech.
+func (s *Stats) Encode() string {
+	emb := s.Embedding()
+	if emb == nil {
+		return ""
+	}
+
+	data := make([]byte, len(emb))
+	for i, v := range emb {
+		data[i] = byte(int8(math.Round(max(-1, min(1, v)) * 127)))
+	}
+
+	return version + "." + base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Similarity returns the cosine similarity, in [-1, 1], between two encoded
+// embeddings.
+func Similarity(a, b string) (float64, error) {
+	va, err := decode(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := decode(b)
+	if err != nil {
+		return 0, err
+	}
+
+	var dot, na, nb float64
+	for i := range va {
+		dot += va[i] * vb[i]
+		na += va[i] * va[i]
+		nb += vb[i] * vb[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0, nil
+	}
+
+	return dot / math.Sqrt(na*nb), nil
+}
+
+func decode(s string) ([]float64, error) {
+	v, data, ok := strings.Cut(s, ".")
+	if !ok || v != version {
+		return nil, fmt.Errorf("unsupported embedding version")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode embedding: %w", err)
+	}
+	if len(raw) != 2*numBands {
+		return nil, fmt.Errorf("invalid embedding length %d", len(raw))
+	}
+
+	emb := make([]float64, len(raw))
+	for i, b := range raw {
+		emb[i] = float64(int8(b)) / 127
+	}
+
+	return emb, nil
+}
@@ -0,0 +1,91 @@
+package voiceprint
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// genVoice generates a crude voiced signal: harmonics of f0 shaped by a
+// single formant, plus some noise.
+func genVoice(rnd *rand.Rand, f0, formant, gain float64, dur int) []float32 {
+	pcm := make([]float32, dur*sampleRate)
+	for h := 1; float64(h)*f0 < maxFreq; h++ {
+		f := float64(h) * f0
+		amp := math.Exp(-math.Abs(f-formant) / 500)
+		phase := rnd.Float64() * 2 * math.Pi
+		for i := range pcm {
+			pcm[i] += float32(gain * amp * math.Sin(2*math.Pi*f*float64(i)/sampleRate+phase))
+		}
+	}
+	for i := range pcm {
+		pcm[i] += float32(gain * 0.01 * rnd.NormFloat64())
+	}
+	return pcm
+}
+
+func TestEncode(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	t.Run("not enough speech", func(t *testing.T) {
+		var s Stats
+		s.Add(genVoice(rnd, 120, 700, 0.1, 1))
+		require.Empty(t, s.Encode())
+
+		require.Empty(t, (&Stats{}).Encode())
+	})
+
+	t.Run("silence", func(t *testing.T) {
+		var s Stats
+		s.Add(make([]float32, 5*sampleRate))
+		require.Zero(t, s.Frames)
+		require.Empty(t, s.Encode())
+	})
+
+	t.Run("same speaker", func(t *testing.T) {
+		var a, b, c Stats
+		a.Add(genVoice(rnd, 120, 700, 0.1, 5))
+		// Volume shouldn't matter.
+		b.Add(genVoice(rnd, 120, 700, 0.5, 5))
+		c.Add(genVoice(rnd, 220, 2000, 0.1, 5))
+
+		ea, eb, ec := a.Encode(), b.Encode(), c.Encode()
+		require.NotEmpty(t, ea)
+		require.NotEqual(t, ea, ec)
+
+		same, err := Similarity(ea, eb)
+		require.NoError(t, err)
+		other, err := Similarity(ea, ec)
+		require.NoError(t, err)
+		require.Greater(t, same, 0.95)
+		require.Greater(t, same, other)
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		pcm := genVoice(rnd, 120, 700, 0.1, 4)
+
+		var whole, merged, part Stats
+		whole.Add(pcm[:2*sampleRate])
+		whole.Add(pcm[2*sampleRate:])
+		merged.Add(pcm[:2*sampleRate])
+		part.Add(pcm[2*sampleRate:])
+		merged.Merge(&part)
+		merged.Merge(nil)
+
+		require.Equal(t, whole.Frames, merged.Frames)
+		require.Equal(t, whole.Encode(), merged.Encode())
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	_, err := Similarity("v0.AAAA", "v0.AAAA")
+	require.EqualError(t, err, "unsupported embedding version")
+
+	_, err = Similarity("v1.AAAA", "v1.AAAA")
+	require.EqualError(t, err, "invalid embedding length 3")
+
+	_, err = Similarity("v1.!", "v1.AAAA")
+	require.Error(t, err)
+}